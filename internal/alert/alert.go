@@ -0,0 +1,169 @@
+// Package alert sends security events, such as denied commands or changed
+// host keys, to HTTP webhooks as they happen.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+const (
+	// queueSize bounds the events waiting to be sent; more are dropped so
+	// alerting never slows down tool calls.
+	queueSize = 256
+	// dedupWindow suppresses repeats of the same event (type, host and
+	// subject), so a burst of rate-limited calls is one alert, not hundreds.
+	dedupWindow = time.Minute
+	// sendTimeout bounds one webhook request.
+	sendTimeout = 10 * time.Second
+)
+
+// Event is a security event. It is posted as JSON to the generic webhook.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"event"` // one of config.AlertEvents
+	Message   string    `json:"message"`
+	Host      string    `json:"host,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	Command   string    `json:"command,omitempty"`
+}
+
+// Notifier delivers events to the configured webhooks in the background.
+type Notifier struct {
+	webhook string
+	slack   string
+	events  []string // empty = all
+	client  *http.Client
+	queue   chan Event
+
+	mu   sync.Mutex
+	sent map[string]time.Time // dedup key -> last queued
+}
+
+// New creates a Notifier for cfg, or returns nil if no webhook is set. A nil
+// Notifier drops every event.
+func New(cfg config.AlertConfig) *Notifier {
+	if cfg.Webhook == "" && cfg.Slack == "" {
+		return nil
+	}
+	return &Notifier{
+		webhook: cfg.Webhook,
+		slack:   cfg.Slack,
+		events:  cfg.Events,
+		client:  &http.Client{Timeout: sendTimeout},
+		queue:   make(chan Event, queueSize),
+		sent:    make(map[string]time.Time),
+	}
+}
+
+// Notify queues e for delivery without waiting. Events not selected by
+// --alert-events, repeats within dedupWindow, and events arriving while the
+// queue is full are dropped.
+func (n *Notifier) Notify(e Event) {
+	if n == nil || (len(n.events) > 0 && !slices.Contains(n.events, e.Type)) {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	key := e.Type + "\x00" + e.Host + "\x00" + e.Command
+	n.mu.Lock()
+	if last, ok := n.sent[key]; ok && e.Time.Sub(last) < dedupWindow {
+		n.mu.Unlock()
+		return
+	}
+	n.sent[key] = e.Time
+	if len(n.sent) > queueSize {
+		for k, t := range n.sent {
+			if e.Time.Sub(t) >= dedupWindow {
+				delete(n.sent, k)
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	select {
+	case n.queue <- e:
+	default:
+		log.Printf("Alert queue full, dropping %s event", e.Type)
+	}
+}
+
+// Start sends queued events until ctx is done.
+func (n *Notifier) Start(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-n.queue:
+				n.send(ctx, e)
+			}
+		}
+	}()
+}
+
+func (n *Notifier) send(ctx context.Context, e Event) {
+	if n.webhook != "" {
+		if err := n.post(ctx, n.webhook, e); err != nil {
+			log.Printf("Alert webhook: %v", err)
+		}
+	}
+	if n.slack != "" {
+		if err := n.post(ctx, n.slack, map[string]string{"text": slackText(e)}); err != nil {
+			log.Printf("Slack alert: %v", err)
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// slackText formats e as a Slack message.
+func slackText(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ":rotating_light: *ssh-mcp %s*: %s", e.Type, e.Message)
+	for _, f := range [][2]string{
+		{"host", e.Host}, {"session", e.SessionID}, {"tool", e.Tool}, {"command", e.Command},
+	} {
+		if f[1] != "" {
+			fmt.Fprintf(&b, "\n• %s: `%s`", f[0], strings.ReplaceAll(f[1], "`", "'"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// receiver collects the JSON bodies posted to it.
+func receiver(t *testing.T) (*httptest.Server, chan map[string]any) {
+	t.Helper()
+	got := make(chan map[string]any, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		got <- body
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func next(t *testing.T, ch chan map[string]any) map[string]any {
+	t.Helper()
+	select {
+	case body := <-ch:
+		return body
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert received")
+		return nil
+	}
+}
+
+func TestNotifier_WebhookAndSlack(t *testing.T) {
+	hook, hookGot := receiver(t)
+	slack, slackGot := receiver(t)
+	n := New(config.AlertConfig{Webhook: hook.URL, Slack: slack.URL})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n.Start(ctx)
+
+	n.Notify(Event{Type: config.AlertCommandDenied, Message: "command rejected", Host: "web1", Command: "rm -rf /"})
+
+	body := next(t, hookGot)
+	if body["event"] != config.AlertCommandDenied || body["command"] != "rm -rf /" || body["host"] != "web1" {
+		t.Errorf("webhook body = %v", body)
+	}
+	if body["time"] == "" {
+		t.Error("webhook body has no time")
+	}
+	text, _ := next(t, slackGot)["text"].(string)
+	if !strings.Contains(text, "command_denied") || !strings.Contains(text, "`rm -rf /`") {
+		t.Errorf("slack text = %q", text)
+	}
+}
+
+func TestNotifier_FilterAndDedup(t *testing.T) {
+	n := New(config.AlertConfig{Webhook: "http://localhost:1", Events: []string{config.AlertSudo, config.AlertRateLimited}})
+
+	n.Notify(Event{Type: config.AlertHostDenied, Host: "a"})
+	n.Notify(Event{Type: config.AlertRateLimited, Host: "a"})
+	n.Notify(Event{Type: config.AlertRateLimited, Host: "a"}) // repeat
+	n.Notify(Event{Type: config.AlertRateLimited, Host: "b"})
+	n.Notify(Event{Type: config.AlertRateLimited, Host: "a", Time: time.Now().Add(2 * dedupWindow)})
+
+	if got := len(n.queue); got != 3 {
+		t.Errorf("queued %d events, want 3 (filtered, deduplicated)", got)
+	}
+}
+
+func TestNotifier_Nil(t *testing.T) {
+	n := New(config.AlertConfig{})
+	if n != nil {
+		t.Fatal("expected nil notifier without webhooks")
+	}
+	n.Notify(Event{Type: config.AlertSudo}) // must not panic
+	n.Start(context.Background())
+}
@@ -0,0 +1,142 @@
+// Package charset converts remote command output and file content from
+// legacy character encodings to UTF-8.
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	// Auto keeps valid UTF-8, decodes UTF-16 with a byte order mark, and
+	// decodes anything else with the fallback encoding.
+	Auto = "auto"
+	// UTF8 is the name reported for content that needed no conversion.
+	UTF8 = "utf-8"
+	// DefaultFallback is used by Auto when no fallback is configured. It is
+	// a superset of Latin-1, so it never fails and is right for most
+	// Western European syslogs and Windows hosts.
+	DefaultFallback = "windows-1252"
+)
+
+// codePages holds Windows and DOS code page names that the WHATWG encoding
+// index does not know. cp1250-cp1258 are WHATWG labels already.
+var codePages = map[string]encoding.Encoding{
+	"cp437":   charmap.CodePage437,
+	"cp850":   charmap.CodePage850,
+	"cp866":   charmap.CodePage866,
+	"cp874":   charmap.Windows874,
+	"cp932":   japanese.ShiftJIS,
+	"cp936":   simplifiedchinese.GBK,
+	"cp949":   korean.EUCKR,
+	"cp950":   traditionalchinese.Big5,
+	"cp65001": unicode.UTF8,
+}
+
+// Lookup returns the encoding for name, which may be any WHATWG label
+// (e.g. "latin1", "windows-1251", "shift_jis", "koi8-r", "utf-16le") or a
+// Windows code page ("cp1251", "cp932"). Matching is case-insensitive.
+func Lookup(name string) (encoding.Encoding, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if enc, ok := codePages[key]; ok {
+		return enc, nil
+	}
+	enc, err := htmlindex.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// Validate reports whether name is Auto, empty, or a known encoding.
+func Validate(name string) error {
+	if name == "" || strings.EqualFold(name, Auto) {
+		return nil
+	}
+	_, err := Lookup(name)
+	return err
+}
+
+// Decoder converts bytes in one encoding, or detected ones, to UTF-8.
+type Decoder struct {
+	name         string // canonical name of enc, or Auto
+	enc          encoding.Encoding
+	fallbackName string
+	fallback     encoding.Encoding
+}
+
+// NewDecoder returns a decoder for name (Auto if empty). fallback is the
+// encoding Auto uses for content that is not valid UTF-8 (DefaultFallback
+// if empty).
+func NewDecoder(name, fallback string) (*Decoder, error) {
+	if fallback == "" {
+		fallback = DefaultFallback
+	}
+	fb, err := Lookup(fallback)
+	if err != nil {
+		return nil, fmt.Errorf("fallback encoding: %w", err)
+	}
+	d := &Decoder{fallback: fb, fallbackName: canonical(fb, fallback)}
+	if name == "" || strings.EqualFold(name, Auto) {
+		d.name = Auto
+		return d, nil
+	}
+	if d.enc, err = Lookup(name); err != nil {
+		return nil, err
+	}
+	d.name = canonical(d.enc, name)
+	return d, nil
+}
+
+// Decode converts data to UTF-8 and returns it with the name of the encoding
+// it was decoded from. Bytes that are invalid in that encoding become U+FFFD.
+// A nil Decoder behaves like Auto with the default fallback.
+func (d *Decoder) Decode(data []byte) (string, string) {
+	if d == nil {
+		d, _ = NewDecoder(Auto, "")
+	}
+	if d.name != Auto {
+		return decodeWith(d.enc, d.name, data)
+	}
+	switch {
+	case utf8.Valid(data):
+		return string(data), UTF8
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), "utf-16le", data)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), "utf-16be", data)
+	}
+	return decodeWith(d.fallback, d.fallbackName, data)
+}
+
+func decodeWith(enc encoding.Encoding, name string, data []byte) (string, string) {
+	if name == UTF8 {
+		return strings.ToValidUTF8(string(data), "�"), UTF8
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		// Decoders replace invalid input themselves; an error here means
+		// the transform itself failed, so keep the bytes as they came.
+		return strings.ToValidUTF8(string(data), "�"), UTF8
+	}
+	return string(out), name
+}
+
+// canonical returns the WHATWG name of enc, or fallback if it has none.
+func canonical(enc encoding.Encoding, fallback string) string {
+	if name, err := htmlindex.Name(enc); err == nil {
+		return name
+	}
+	return strings.ToLower(fallback)
+}
@@ -0,0 +1,95 @@
+package charset
+
+import (
+	"testing"
+)
+
+func TestDecoder_Auto(t *testing.T) {
+	d, err := NewDecoder(Auto, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		in       []byte
+		want     string
+		wantName string
+	}{
+		{"ascii", []byte("hello"), "hello", UTF8},
+		{"utf-8", []byte("grüße"), "grüße", UTF8},
+		{"latin-1 falls back", []byte("gr\xfc\xdfe"), "grüße", "windows-1252"},
+		{"utf-16le bom", []byte("\xff\xfeh\x00i\x00"), "hi", "utf-16le"},
+		{"utf-16be bom", []byte("\xfe\xff\x00h\x00i"), "hi", "utf-16be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, used := d.Decode(tt.in)
+			if got != tt.want || used != tt.wantName {
+				t.Errorf("Decode(%q) = %q, %q; want %q, %q", tt.in, got, used, tt.want, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDecoder_AutoWithFallback(t *testing.T) {
+	d, err := NewDecoder("", "cp1251")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "Привет" in CP1251.
+	got, used := d.Decode([]byte("\xcf\xf0\xe8\xe2\xe5\xf2"))
+	if got != "Привет" || used != "windows-1251" {
+		t.Errorf("got %q, %q", got, used)
+	}
+}
+
+func TestDecoder_Named(t *testing.T) {
+	tests := []struct {
+		enc      string
+		in       []byte
+		want     string
+		wantName string
+	}{
+		{"windows-1251", []byte("\xcf\xf0\xe8\xe2\xe5\xf2"), "Привет", "windows-1251"},
+		{"CP932", []byte("\x93\xfa\x96\x7b"), "日本", "shift_jis"},
+		{"latin1", []byte("caf\xe9"), "café", "windows-1252"},
+		{"cp866", []byte("\x8f\xe0\xa8\xa2\xa5\xe2"), "Привет", "ibm866"},
+		{"utf-8", []byte("ok\xff"), "ok�", UTF8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enc, func(t *testing.T) {
+			d, err := NewDecoder(tt.enc, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, used := d.Decode(tt.in)
+			if got != tt.want || used != tt.wantName {
+				t.Errorf("Decode(%q) = %q, %q; want %q, %q", tt.in, got, used, tt.want, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestDecoder_Nil(t *testing.T) {
+	var d *Decoder
+	if got, used := d.Decode([]byte("caf\xe9")); got != "café" || used != "windows-1252" {
+		t.Errorf("got %q, %q", got, used)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, name := range []string{"", "auto", "AUTO", "utf-8", "latin1", "cp1251", "cp932", "Shift_JIS", "koi8-r"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("Validate(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"klingon", "cp99999"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("Validate(%q): expected error", name)
+		}
+	}
+	if _, err := NewDecoder("auto", "klingon"); err == nil {
+		t.Error("expected error for unknown fallback")
+	}
+}
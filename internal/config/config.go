@@ -1,18 +1,46 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alexflint/go-arg"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/charset"
 )
 
 // Version is set at build time via ldflags.
 var Version = "dev"
 
+// defaultMaxIdleTime is used when no idle timeout is configured.
+const defaultMaxIdleTime = 5 * time.Minute
+
+// UserNamePattern matches a POSIX-style remote user name.
+var UserNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// EnvNamePattern matches an environment variable name.
+var EnvNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ShellNamePattern limits a shell to a plain name or absolute path.
+var ShellNamePattern = regexp.MustCompile(`^([A-Za-z0-9_.+-]+|(/[A-Za-z0-9_.+-]+)+)$`)
+
+// LocalePattern matches a locale name such as C.UTF-8 or de_DE.UTF-8@euro.
+var LocalePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.@-]*$`)
+
+// TermPattern matches a terminal type such as dumb or xterm-256color.
+var TermPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.+-]*$`)
+
 // commaSeparated is a custom type for parsing comma-separated lists.
 // Supports both repeated flags (--flag val1 --flag val2) and
 // comma-separated env vars (VAR="val1,val2,val3").
@@ -33,31 +61,100 @@ func (c *commaSeparated) UnmarshalText(b []byte) error {
 
 // Args holds CLI arguments parsed by go-arg.
 type Args struct {
-	EnableHTTP       bool           `arg:"--enable-http,env:MCP_SSH_ENABLE_HTTP" help:"enable HTTP transport"`
-	HTTPPort         int            `arg:"--http-port,env:MCP_SSH_HTTP_PORT" default:"8081" placeholder:"PORT" help:"HTTP transport port"`
-	DisableStdio     bool           `arg:"--disable-stdio,env:MCP_SSH_DISABLE_STDIO" help:"disable stdio transport"`
-	NoVerifyHost     bool           `arg:"--no-verify-host-key,env:MCP_SSH_NO_VERIFY_HOST_KEY" help:"disable host key verification"`
-	KnownHosts       string         `arg:"--known-hosts,env:MCP_SSH_KNOWN_HOSTS" placeholder:"PATH" help:"path to known_hosts file"`
-	SSHConfigPath    string         `arg:"--ssh-config,env:MCP_SSH_CONFIG" placeholder:"PATH" help:"path to SSH config file"`
-	EnableSudo       bool           `arg:"--enable-sudo,env:MCP_SSH_ENABLE_SUDO" help:"allow sudo execution"`
-	CommandTimeout   time.Duration  `arg:"--command-timeout,env:MCP_SSH_COMMAND_TIMEOUT" default:"60s" placeholder:"DURATION" help:"command execution timeout"`
-	HostAllowlist    commaSeparated `arg:"--host-allowlist,separate,env:MCP_SSH_HOST_ALLOWLIST" placeholder:"PATTERN" help:"host allowlist (can be specified multiple times or comma-separated)"`
-	HostDenylist     commaSeparated `arg:"--host-denylist,separate,env:MCP_SSH_HOST_DENYLIST" placeholder:"PATTERN" help:"host denylist (can be specified multiple times or comma-separated)"`
-	CommandAllowlist commaSeparated `arg:"--command-allowlist,separate,env:MCP_SSH_COMMAND_ALLOWLIST" placeholder:"REGEX" help:"command allowlist regex (can be specified multiple times or comma-separated)"`
-	CommandDenylist  commaSeparated `arg:"--command-denylist,separate,env:MCP_SSH_COMMAND_DENYLIST" placeholder:"REGEX" help:"command denylist regex (can be specified multiple times or comma-separated)"`
-	RateLimit        int            `arg:"--rate-limit,env:MCP_SSH_RATE_LIMIT" default:"60" placeholder:"NUM" help:"rate limit (requests per minute)"`
-	RateLimitFileOps bool           `arg:"--rate-limit-file-ops,env:MCP_SSH_RATE_LIMIT_FILE_OPS" help:"apply rate limiting to SFTP file operations"`
-	LocalBaseDir     string         `arg:"--local-base-dir,env:MCP_SSH_LOCAL_BASE_DIR" placeholder:"PATH" help:"restrict local file operations to this directory"`
-	MaxFileSize      int64          `arg:"--max-file-size,env:MCP_SSH_MAX_FILE_SIZE" default:"0" placeholder:"BYTES" help:"maximum file size for read operations (0=unlimited)"`
-	MaxConnections   int            `arg:"--max-connections,env:MCP_SSH_MAX_CONNECTIONS" default:"0" placeholder:"NUM" help:"maximum number of concurrent SSH connections (0=unlimited)"`
-	HTTPToken        string         `arg:"--http-token,env:MCP_SSH_HTTP_TOKEN" placeholder:"TOKEN" help:"bearer token for HTTP transport authentication"`
-	DisableTools     commaSeparated `arg:"--disable-tools,separate,env:MCP_SSH_DISABLE_TOOLS" placeholder:"TOOL" help:"disable specific tools (can be specified multiple times or comma-separated)"`
-	EnableTerminal   bool           `arg:"--enable-terminal,env:MCP_SSH_ENABLE_TERMINAL" help:"allow interactive PTY terminal sessions (ssh_open_terminal)"`
-	MaxTerminals     int            `arg:"--max-terminals,env:MCP_SSH_MAX_TERMINALS" default:"0" placeholder:"NUM" help:"maximum number of concurrent PTY terminal sessions (0=unlimited)"`
-	MaxOutputSize    int            `arg:"--max-output-size,env:MCP_SSH_MAX_OUTPUT_SIZE" default:"0" placeholder:"BYTES" help:"maximum output size per stream in bytes for execute/terminal results (0=unlimited)"`
-	MaxTunnels       int            `arg:"--max-tunnels,env:MCP_SSH_MAX_TUNNELS" default:"0" placeholder:"NUM" help:"maximum number of concurrent SSH tunnels (0=unlimited)"`
-	EnableTunnels    bool           `arg:"--enable-tunnels,env:MCP_SSH_ENABLE_TUNNELS" help:"allow SSH tunnel creation (ssh_tunnel_create)"`
-	ShowVersion      bool           `arg:"--version" help:"show version and exit"`
+	EnableHTTP         bool           `arg:"--enable-http,env:MCP_SSH_ENABLE_HTTP" help:"enable HTTP transport"`
+	HTTPPort           int            `arg:"--http-port,env:MCP_SSH_HTTP_PORT" default:"8081" placeholder:"PORT" help:"HTTP transport port"`
+	DisableStdio       bool           `arg:"--disable-stdio,env:MCP_SSH_DISABLE_STDIO" help:"disable stdio transport"`
+	NoVerifyHost       bool           `arg:"--no-verify-host-key,env:MCP_SSH_NO_VERIFY_HOST_KEY" help:"disable host key verification"`
+	HostKeyPrompt      string         `arg:"--host-key-prompt,env:MCP_SSH_HOST_KEY_PROMPT" default:"off" placeholder:"MODE" help:"ask the user through MCP elicitation whether to trust a host key known_hosts rejects, and on approval save it and retry: off, unknown (new hosts only) or all (changed keys too)"`
+	KnownHosts         string         `arg:"--known-hosts,env:MCP_SSH_KNOWN_HOSTS" placeholder:"PATH" help:"path to known_hosts file"`
+	SSHConfigPath      string         `arg:"--ssh-config,env:MCP_SSH_CONFIG" placeholder:"PATH" help:"path to SSH config file"`
+	EnableSudo         bool           `arg:"--enable-sudo,env:MCP_SSH_ENABLE_SUDO" help:"allow sudo execution"`
+	EnableAgentForward bool           `arg:"--enable-agent-forwarding,env:MCP_SSH_ENABLE_AGENT_FORWARDING" help:"allow ssh_git to forward the local ssh-agent (SSH_AUTH_SOCK) to the remote host for clones and pulls of private repositories"`
+	RunAsUsers         commaSeparated `arg:"--run-as-users,separate,env:MCP_SSH_RUN_AS_USERS" placeholder:"USER" help:"users ssh_execute may run commands as with run_as (sudo -u, which also needs --enable-sudo, or su); '*' allows any user (can be specified multiple times or comma-separated)"`
+	CommandTimeout     time.Duration  `arg:"--command-timeout,env:MCP_SSH_COMMAND_TIMEOUT" default:"60s" placeholder:"DURATION" help:"command execution timeout"`
+	ExecuteRetries     int            `arg:"--execute-retries,env:MCP_SSH_EXECUTE_RETRIES" default:"0" placeholder:"NUM" help:"retry ssh_execute this many times when the connection fails before the command reports an exit status; exit codes, timeouts and cancellations are never retried (0=off, at most 10)"`
+	Locale             string         `arg:"--locale,env:MCP_SSH_LOCALE" placeholder:"LOCALE" help:"set LANG and LC_ALL to this locale (e.g. C.UTF-8) for ssh_execute and ssh_run_script commands on POSIX hosts"`
+	Term               string         `arg:"--term,env:MCP_SSH_TERM" placeholder:"TERM" help:"set TERM (e.g. dumb) for ssh_execute and ssh_run_script commands on POSIX hosts"`
+	KillGrace          time.Duration  `arg:"--kill-grace,env:MCP_SSH_KILL_GRACE" default:"7s" placeholder:"DURATION" help:"on timeout or cancellation, time a command gets after SIGINT and SIGTERM before SIGKILL (0=SIGKILL at once, at most 5m)"`
+	RetryBackoff       time.Duration  `arg:"--execute-retry-backoff,env:MCP_SSH_EXECUTE_RETRY_BACKOFF" default:"1s" placeholder:"DURATION" help:"wait before the first ssh_execute retry, doubled for each further retry"`
+	DialAttemptTimeout time.Duration  `arg:"--dial-attempt-timeout,env:MCP_SSH_DIAL_ATTEMPT_TIMEOUT" default:"10s" placeholder:"DURATION" help:"give up on one resolved address of a host after this long and try the next; attempts are staggered by 250ms, happy eyeballs style (0=use the whole connection timeout)"`
+	MaxIdleTime        time.Duration  `arg:"--max-idle-time,env:MCP_SSH_MAX_IDLE_TIME" default:"5m" placeholder:"DURATION" help:"close connections idle for longer than this (reconnected on next use)"`
+	MaxLifetime        time.Duration  `arg:"--max-session-lifetime,env:MCP_SSH_MAX_SESSION_LIFETIME" default:"0" placeholder:"DURATION" help:"close sessions this long after they connect, regardless of activity; they are not reconnected (0=unlimited)"`
+	HostMap            commaSeparated `arg:"--host-map,separate,env:MCP_SSH_HOST_MAP" placeholder:"NAME=IP[:PORT]" help:"connect to NAME at this address instead of resolving it through DNS; host filters see the address (can be specified multiple times or comma-separated)"`
+	HostIdleTimeouts   commaSeparated `arg:"--host-idle-time,separate,env:MCP_SSH_HOST_IDLE_TIME" placeholder:"PATTERN=DURATION" help:"per-host idle timeout override, host pattern is a regex (can be specified multiple times or comma-separated)"`
+	HostDefaults       []string       `arg:"--host-defaults,separate,env:MCP_SSH_HOST_DEFAULTS" placeholder:"PATTERN=SPEC" help:"ssh_execute defaults for sessions on matching hosts; SPEC is ';'-separated: dir=PATH, shell=NAME and env=NAME=VALUE, repeatable (e.g. 'app-.*=dir=/srv/app;env=RAILS_ENV=production')"`
+	VaultAddr          string         `arg:"--vault-addr,env:MCP_SSH_VAULT_ADDR" placeholder:"URL" help:"HashiCorp Vault address (defaults to VAULT_ADDR)"`
+	VaultToken         string         `arg:"--vault-token,env:MCP_SSH_VAULT_TOKEN" placeholder:"TOKEN" help:"HashiCorp Vault token (defaults to VAULT_TOKEN)"`
+	HostVault          commaSeparated `arg:"--host-vault,separate,env:MCP_SSH_HOST_VAULT" placeholder:"PATTERN=KIND:PATH" help:"fetch credentials from Vault for matching hosts; KIND is kv (secret with password/private_key) or ssh-ca (signing endpoint, e.g. ssh/sign/role)"`
+	HostIAP            commaSeparated `arg:"--host-iap,separate,env:MCP_SSH_HOST_IAP" placeholder:"PATTERN=[PROJECT/]ZONE" help:"reach matching GCE instances through an Identity-Aware Proxy TCP tunnel (requires gcloud); the host is the instance name"`
+	HostProxyCommand   []string       `arg:"--host-proxy-command,separate,env:MCP_SSH_HOST_PROXY_COMMAND" placeholder:"PATTERN=COMMAND" help:"connect to matching hosts through a ProxyCommand-style helper (e.g. 'tsh proxy ssh %r@%h:%p'); %h, %p, %r expand to host, port, user"`
+	SSHProxy           string         `arg:"--ssh-proxy,env:MCP_SSH_PROXY" placeholder:"URL" help:"dial SSH connections through this proxy: http://[USER:PASS@]HOST:PORT (HTTP CONNECT), socks5://... (resolve locally) or socks5h://... (proxy resolves)"`
+	HostProxy          []string       `arg:"--host-proxy,separate,env:MCP_SSH_HOST_PROXY" placeholder:"PATTERN=URL" help:"proxy for matching hosts, overriding --ssh-proxy; URL 'none' connects directly"`
+	SSHAlgorithms      string         `arg:"--ssh-algorithms,env:MCP_SSH_ALGORITHMS" default:"default" placeholder:"MODE" help:"SSH algorithm policy: default, legacy (adds ssh-rsa, SHA-1 key exchanges, CBC ciphers for old devices) or strict (AEAD ciphers, ETM MACs, no SHA-1)"`
+	SSHCiphers         commaSeparated `arg:"--ssh-ciphers,separate,env:MCP_SSH_CIPHERS" placeholder:"NAME" help:"ciphers to offer, in order, replacing those of --ssh-algorithms (can be specified multiple times or comma-separated)"`
+	SSHMACs            commaSeparated `arg:"--ssh-macs,separate,env:MCP_SSH_MACS" placeholder:"NAME" help:"MACs to offer, in order, replacing those of --ssh-algorithms"`
+	SSHKex             commaSeparated `arg:"--ssh-kex,separate,env:MCP_SSH_KEX" placeholder:"NAME" help:"key exchanges to offer, in order, replacing those of --ssh-algorithms"`
+	SSHHostKeyAlgos    commaSeparated `arg:"--ssh-host-key-algorithms,separate,env:MCP_SSH_HOST_KEY_ALGORITHMS" placeholder:"NAME" help:"host key algorithms to accept, in order, replacing those of --ssh-algorithms"`
+	HostAlgorithms     []string       `arg:"--host-algorithms,separate,env:MCP_SSH_HOST_ALGORITHMS" placeholder:"PATTERN=SPEC" help:"algorithm policy for matching hosts; SPEC is ';'-separated: a mode and/or ciphers=, macs=, kex=, host-keys= with '+'-separated names (e.g. 'switch-.*=legacy')"`
+	HostAllowlist      commaSeparated `arg:"--host-allowlist,separate,env:MCP_SSH_HOST_ALLOWLIST" placeholder:"PATTERN" help:"host allowlist (can be specified multiple times or comma-separated)"`
+	HostDenylist       commaSeparated `arg:"--host-denylist,separate,env:MCP_SSH_HOST_DENYLIST" placeholder:"PATTERN" help:"host denylist (can be specified multiple times or comma-separated)"`
+	CommandAllowlist   commaSeparated `arg:"--command-allowlist,separate,env:MCP_SSH_COMMAND_ALLOWLIST" placeholder:"REGEX" help:"command allowlist regex (can be specified multiple times or comma-separated)"`
+	CommandDenylist    commaSeparated `arg:"--command-denylist,separate,env:MCP_SSH_COMMAND_DENYLIST" placeholder:"REGEX" help:"command denylist regex (can be specified multiple times or comma-separated)"`
+	ProtectProcesses   commaSeparated `arg:"--protect-process,separate,env:MCP_SSH_PROTECT_PROCESSES" placeholder:"REGEX" help:"process names ssh_signal refuses to signal, e.g. sshd (can be specified multiple times or comma-separated)"`
+	ParseCommands      bool           `arg:"--parse-commands,env:MCP_SSH_PARSE_COMMANDS" help:"parse commands as shell code and apply the command allowlist/denylist to every command they run (pipes, ; chains, $(...), sh -c, sudo/env wrappers); commands that can't be checked are rejected"`
+	ResolveHosts       bool           `arg:"--resolve-hosts,env:MCP_SSH_RESOLVE_HOSTS" help:"resolve host names before the host allowlist/denylist so CIDR rules apply to every address they resolve to; connections then use the checked addresses"`
+	RateLimit          int            `arg:"--rate-limit,env:MCP_SSH_RATE_LIMIT" default:"60" placeholder:"NUM" help:"rate limit (requests per minute)"`
+	RateLimitFileOps   bool           `arg:"--rate-limit-file-ops,env:MCP_SSH_RATE_LIMIT_FILE_OPS" help:"apply rate limiting to SFTP file operations"`
+	RateLimitWait      time.Duration  `arg:"--rate-limit-wait,env:MCP_SSH_RATE_LIMIT_WAIT" default:"0s" placeholder:"DURATION" help:"queue tool calls over the rate limit for up to this long instead of failing them (0=fail immediately)"`
+	LocalBaseDir       commaSeparated `arg:"--local-base-dir,separate,env:MCP_SSH_LOCAL_BASE_DIR" placeholder:"PATH[:ro]" help:"restrict local file operations to these directories; append :ro to allow only reads from a directory (can be specified multiple times or comma-separated)"`
+	CredentialStore    string         `arg:"--credential-store,env:MCP_SSH_CREDENTIAL_STORE" placeholder:"BACKEND" help:"save passwords for reuse: keychain (macOS Keychain / libsecret / Windows Credential Manager) or file (encrypted)"`
+	CredentialFile     string         `arg:"--credential-file,env:MCP_SSH_CREDENTIAL_FILE" placeholder:"PATH" help:"encrypted credential file for --credential-store file (default: <user config dir>/ssh-mcp/credentials)"`
+	CredentialKey      string         `arg:"--credential-key,env:MCP_SSH_CREDENTIAL_KEY" placeholder:"PASSPHRASE" help:"passphrase for the encrypted credential file"`
+	BackupStyle        string         `arg:"--backup-style,env:MCP_SSH_BACKUP_STYLE" default:"simple" placeholder:"STYLE" help:"ssh_edit_file backup naming: simple (single .bak, overwritten) or timestamped (.<UTC time>.bak per edit)"`
+	BackupKeep         int            `arg:"--backup-keep,env:MCP_SSH_BACKUP_KEEP" default:"0" placeholder:"NUM" help:"keep at most NUM timestamped backups per file, removing the oldest (0=unlimited)"`
+	BackupDir          string         `arg:"--backup-dir,env:MCP_SSH_BACKUP_DIR" placeholder:"PATH" help:"remote directory for edit backups, mirroring each file's absolute path (default: next to the file)"`
+	TranscriptDir      string         `arg:"--transcript-dir,env:MCP_SSH_TRANSCRIPT_DIR" placeholder:"PATH" help:"record every tool call (arguments, output, errors) to one file per SSH session in this local directory, readable with ssh_get_transcript"`
+	TranscriptKeep     time.Duration  `arg:"--transcript-retention,env:MCP_SSH_TRANSCRIPT_RETENTION" default:"0" placeholder:"DURATION" help:"delete transcript files not written to for this long, e.g. 720h (0=keep forever)"`
+	FetchDomains       commaSeparated `arg:"--fetch-allow-domain,separate,env:MCP_SSH_FETCH_ALLOW_DOMAINS" placeholder:"DOMAIN" help:"enable ssh_fetch_url for HTTPS URLs on this domain; *.example.com allows its subdomains (can be specified multiple times or comma-separated)"`
+	FetchMaxSize       int64          `arg:"--fetch-max-size,env:MCP_SSH_FETCH_MAX_SIZE" default:"104857600" placeholder:"BYTES" help:"largest artifact ssh_fetch_url downloads (0=unlimited)"`
+	MaxFileSize        int64          `arg:"--max-file-size,env:MCP_SSH_MAX_FILE_SIZE" default:"0" placeholder:"BYTES" help:"maximum file size for read operations (0=unlimited)"`
+	MaxConnections     int            `arg:"--max-connections,env:MCP_SSH_MAX_CONNECTIONS" default:"0" placeholder:"NUM" help:"maximum number of concurrent SSH connections (0=unlimited)"`
+	HTTPToken          string         `arg:"--http-token,env:MCP_SSH_HTTP_TOKEN" placeholder:"TOKEN" help:"bearer token for HTTP transport authentication"`
+	TenantsFile        string         `arg:"--tenants,env:MCP_SSH_TENANTS" placeholder:"PATH" help:"JSON file of tenants, each with its own HTTP bearer token, host and command allow/denylists, disabled tools and preset, served by this process"`
+	CommandTemplates   string         `arg:"--command-templates,env:MCP_SSH_COMMAND_TEMPLATES" placeholder:"PATH" help:"JSON file of command templates with typed {{param}} placeholders, each offered as its own tool (e.g. restart_service), so agents can run those operations without general ssh_execute"`
+	Plugins            commaSeparated `arg:"--plugin,separate,env:MCP_SSH_PLUGINS" placeholder:"PATH" help:"executable that adds tools over a JSON protocol on stdin/stdout, running remote commands through this server's sessions and filters (can be specified multiple times or comma-separated)"`
+	HTTPAccessLog      bool           `arg:"--http-access-log,env:MCP_SSH_HTTP_ACCESS_LOG" help:"log every HTTP request (method, path, client IP, auth result, status, duration)"`
+	HTTPMaxBody        int64          `arg:"--http-max-body,env:MCP_SSH_HTTP_MAX_BODY" default:"0" placeholder:"BYTES" help:"maximum HTTP request body size (0=unlimited)"`
+	HTTPRateLimit      int            `arg:"--http-rate-limit,env:MCP_SSH_HTTP_RATE_LIMIT" default:"0" placeholder:"NUM" help:"maximum HTTP requests per minute per client IP (0=unlimited)"`
+	HTTPMaxConns       int            `arg:"--http-max-conns-per-ip,env:MCP_SSH_HTTP_MAX_CONNS_PER_IP" default:"0" placeholder:"NUM" help:"maximum concurrent HTTP connections per client IP (0=unlimited)"`
+	MaxConcurrent      int            `arg:"--max-concurrent-tools,env:MCP_SSH_MAX_CONCURRENT_TOOLS" default:"0" placeholder:"NUM" help:"maximum number of tool calls executing at once; further calls wait (0=unlimited)"`
+	SerializeCalls     bool           `arg:"--serialize-sessions,env:MCP_SSH_SERIALIZE_SESSIONS" help:"run tool calls on the same SSH session one at a time"`
+	PolicyWebhook      string         `arg:"--policy-webhook,env:MCP_SSH_POLICY_WEBHOOK" placeholder:"URL" help:"POST every tool call (tool, host, commands, paths, client) to this URL before running it; the JSON answer allows, denies or modifies the call"`
+	PolicyTimeout      time.Duration  `arg:"--policy-timeout,env:MCP_SSH_POLICY_TIMEOUT" default:"5s" placeholder:"DURATION" help:"how long to wait for the policy webhook"`
+	PolicyFailOpen     bool           `arg:"--policy-fail-open,env:MCP_SSH_POLICY_FAIL_OPEN" help:"run tool calls when the policy webhook fails or is unreachable (default: reject them)"`
+	AlertWebhook       string         `arg:"--alert-webhook,env:MCP_SSH_ALERT_WEBHOOK" placeholder:"URL" help:"POST security events (denied hosts/commands, policy denials, sudo use, rate limiting, changed host keys) as JSON to this URL"`
+	AlertSlack         string         `arg:"--alert-slack,env:MCP_SSH_ALERT_SLACK" placeholder:"URL" help:"post security events to this Slack incoming webhook"`
+	AlertEvents        commaSeparated `arg:"--alert-events,separate,env:MCP_SSH_ALERT_EVENTS" placeholder:"EVENT" help:"only alert on these events: host_denied, command_denied, policy_denied, sudo, rate_limited, host_key_changed (default: all)"`
+	ShutdownGrace      time.Duration  `arg:"--shutdown-grace,env:MCP_SSH_SHUTDOWN_GRACE" default:"30s" placeholder:"DURATION" help:"on SIGINT/SIGTERM, stop accepting tool calls and wait this long for running ones before closing connections (0=close immediately)"`
+	SharedSessions     bool           `arg:"--shared-sessions,env:MCP_SSH_SHARED_SESSIONS" help:"let all HTTP clients see and use every SSH session, terminal and tunnel (default: each MCP client session only sees its own)"`
+	DisableTools       commaSeparated `arg:"--disable-tools,separate,env:MCP_SSH_DISABLE_TOOLS" placeholder:"TOOL" help:"disable specific tools (can be specified multiple times or comma-separated)"`
+	ToolPreset         string         `arg:"--preset,env:MCP_SSH_PRESET" default:"full" placeholder:"PRESET" help:"tool preset: full, diagnostics (no file changes) or readonly (no commands, file changes or tunnels)"`
+	DisableExec        bool           `arg:"--disable-exec,env:MCP_SSH_DISABLE_EXEC" help:"disable every tool that runs remote commands (execute, scripts, terminals, docker/kubectl exec and restart)"`
+	DisableFileWrite   bool           `arg:"--disable-file-write,env:MCP_SSH_DISABLE_FILE_WRITE" help:"disable every tool that creates, changes or moves files (uploads, edits, copies, archives, keys)"`
+	EnableTerminal     bool           `arg:"--enable-terminal,env:MCP_SSH_ENABLE_TERMINAL" help:"allow interactive PTY terminal sessions (ssh_open_terminal)"`
+	MaxTerminals       int            `arg:"--max-terminals,env:MCP_SSH_MAX_TERMINALS" default:"0" placeholder:"NUM" help:"maximum number of concurrent PTY terminal sessions (0=unlimited)"`
+	OutputEncoding     string         `arg:"--output-encoding,env:MCP_SSH_OUTPUT_ENCODING" default:"auto" placeholder:"NAME" help:"encoding of remote command output and files, converted to UTF-8: auto (UTF-8, UTF-16 with BOM, else --fallback-encoding) or a name like latin1, windows-1251, cp932"`
+	FallbackEncoding   string         `arg:"--fallback-encoding,env:MCP_SSH_FALLBACK_ENCODING" default:"windows-1252" placeholder:"NAME" help:"encoding assumed by --output-encoding auto for output that is not valid UTF-8"`
+	SFTPTimeout        time.Duration  `arg:"--sftp-timeout,env:MCP_SSH_SFTP_TIMEOUT" default:"30s" placeholder:"DURATION" help:"fail SFTP operations (stat, readdir, open, each read or write) when the server sends no reply for this long, independent of --command-timeout (0=disabled)"`
+	TransferWorkers    int            `arg:"--transfer-workers,env:MCP_SSH_TRANSFER_WORKERS" default:"4" placeholder:"NUM" help:"files copied at once by directory uploads and downloads over SFTP (1=sequential, at most 32); the parallel input overrides it per call"`
+	TransferProtocol   string         `arg:"--transfer-protocol,env:MCP_SSH_TRANSFER_PROTOCOL" default:"auto" placeholder:"PROTO" help:"protocol for ssh_upload/ssh_download: sftp, scp, or auto (sftp, falling back to scp when the server has no SFTP subsystem)"`
+	UploadUmask        string         `arg:"--upload-umask,env:MCP_SSH_UPLOAD_UMASK" placeholder:"MODE" help:"octal permission bits cleared on every uploaded file and directory, e.g. 022 (no group/world write) or 077 (owner only)"`
+	UploadFileMode     string         `arg:"--upload-file-mode,env:MCP_SSH_UPLOAD_FILE_MODE" placeholder:"MODE" help:"octal mode given to every uploaded file instead of the local file's mode (--upload-umask still applies)"`
+	UploadDirMode      string         `arg:"--upload-dir-mode,env:MCP_SSH_UPLOAD_DIR_MODE" placeholder:"MODE" help:"octal mode given to every directory created by an upload instead of the local directory's mode (--upload-umask still applies)"`
+	MaxOutputSize      int            `arg:"--max-output-size,env:MCP_SSH_MAX_OUTPUT_SIZE" default:"0" placeholder:"BYTES" help:"maximum output size per stream in bytes for execute/terminal results (0=unlimited)"`
+	MaxTunnels         int            `arg:"--max-tunnels,env:MCP_SSH_MAX_TUNNELS" default:"0" placeholder:"NUM" help:"maximum number of concurrent SSH tunnels (0=unlimited)"`
+	EnableTunnels      bool           `arg:"--enable-tunnels,env:MCP_SSH_ENABLE_TUNNELS" help:"allow SSH tunnel creation (ssh_tunnel_create)"`
+	ShowVersion        bool           `arg:"--version" help:"show version and exit"`
 }
 
 // Description returns the program description for go-arg.
@@ -75,26 +172,266 @@ type Config struct {
 	SSH           SSHConfig
 	Security      SecurityConfig
 	Transport     TransportConfig
+	Backup        BackupConfig
+	Transcript    TranscriptConfig
+	Alert         AlertConfig
 	DisabledTools []string
+	Preset        string // PresetFull, PresetDiagnostics or PresetReadOnly
+	// DisabledCategories are the tool categories turned off by Preset,
+	// --disable-exec and --disable-file-write.
+	DisabledCategories []string
+	// Tenants are served over HTTP next to the main server, each selected
+	// by its own bearer token (see --tenants).
+	Tenants []Tenant
+	// CommandTemplates are registered as tools of their own (see
+	// --command-templates).
+	CommandTemplates []CommandTemplate
+	// Plugins are executables whose tools are registered at startup (see
+	// --plugin).
+	Plugins []string
+}
+
+// Tenant is one entry of the --tenants file. Its rules narrow the global
+// ones: a host or command must pass both, and its disabled tools and preset
+// add to the global ones.
+type Tenant struct {
+	Name             string   `json:"name"`
+	Token            string   `json:"token"`
+	HostAllowlist    []string `json:"host_allowlist,omitempty"`
+	HostDenylist     []string `json:"host_denylist,omitempty"`
+	CommandAllowlist []string `json:"command_allowlist,omitempty"`
+	CommandDenylist  []string `json:"command_denylist,omitempty"`
+	DisableTools     []string `json:"disable_tools,omitempty"`
+	Preset           string   `json:"preset,omitempty"`
 }
 
 // SSHConfig holds SSH-related configuration.
 type SSHConfig struct {
-	KnownHostsPath    string
-	VerifyHostKey     bool
-	ConfigPath        string
-	KeySearchPaths    []string
-	CommandTimeout    time.Duration
-	ConnectionTimeout time.Duration
-	MaxIdleTime       time.Duration
-	AllowSudo         bool
-	AllowTerminal     bool
-	StripANSI         bool
-	MaxConnections    int
-	MaxTerminals      int
-	MaxOutputSize     int
-	MaxTunnels        int
-	AllowTunnels      bool
+	KnownHostsPath     string
+	VerifyHostKey      bool
+	HostKeyPrompt      string // HostKeyPromptOff, HostKeyPromptUnknown or HostKeyPromptAll
+	ConfigPath         string
+	SSHDir             string // local ~/.ssh directory
+	KeySearchPaths     []string
+	CommandTimeout     time.Duration
+	ExecuteRetries     int           // ssh_execute retries on connection errors (0 = off)
+	RetryBackoff       time.Duration // first ssh_execute retry delay, doubled per retry
+	KillGrace          time.Duration // SIGINT/SIGTERM grace before SIGKILL on timeout
+	Locale             string        // LANG/LC_ALL for commands ("" = remote default)
+	Term               string        // TERM for commands ("" = remote default)
+	ConnectionTimeout  time.Duration
+	DialAttemptTimeout time.Duration // per-address TCP connect timeout (0 = ConnectionTimeout)
+	MaxIdleTime        time.Duration
+	MaxLifetime        time.Duration // 0 = unlimited
+	HostMap            []HostMapping
+	HostIdleTimeouts   []HostIdleTimeout
+	HostDefaults       []HostDefaults
+	Vault              VaultConfig
+	HostIAP            []HostIAP
+	HostProxyCommands  []HostProxyCommand
+	Proxy              string // outbound proxy URL for SSH dials ("" = direct)
+	HostProxies        []HostProxy
+	Algorithms         AlgorithmPolicy
+	HostAlgorithms     []HostAlgorithms
+	AllowSudo          bool
+	AllowAgentForward  bool     // ssh_git may forward the local ssh-agent
+	RunAsUsers         []string // users allowed as run_as targets; "*" = any, empty = run_as disabled
+	AllowTerminal      bool
+	StripANSI          bool
+	OutputEncoding     string        // charset.Auto or an encoding name
+	FallbackEncoding   string        // what charset.Auto decodes non-UTF-8 output as
+	TransferProtocol   string        // TransferAuto, TransferSFTP or TransferSCP
+	SFTPTimeout        time.Duration // max wait for any one SFTP reply (0 = disabled)
+	TransferWorkers    int           // default parallel file copies in directory transfers
+	UploadFileMode     os.FileMode   // mode of uploaded files (0 = the local mode)
+	UploadDirMode      os.FileMode   // mode of directories created by uploads (0 = the local mode)
+	UploadUmask        os.FileMode   // bits cleared from uploaded files' and directories' modes
+	MaxConnections     int
+	MaxTerminals       int
+	MaxOutputSize      int
+	MaxTunnels         int
+	AllowTunnels       bool
+}
+
+// MaxExecuteRetries caps --execute-retries and the ssh_execute retries input.
+const MaxExecuteRetries = 10
+
+// MaxKillGrace caps --kill-grace and the kill_grace input.
+const MaxKillGrace = 5 * time.Minute
+
+// HostMapping resolves the host name Alias (case-insensitive) to Address,
+// an IP literal, before DNS. A non-zero Port replaces the port as well.
+type HostMapping struct {
+	Alias   string
+	Address string
+	Port    int
+}
+
+// HostIdleTimeout overrides MaxIdleTime for hosts matching Pattern.
+// Pattern is a case-insensitive, auto-anchored regex like the host filters.
+type HostIdleTimeout struct {
+	Pattern string
+	Timeout time.Duration
+}
+
+// SessionDefaults are what ssh_execute on a session uses when a call leaves
+// them unset: a working directory, a shell, and environment variables
+// exported in front of the command.
+type SessionDefaults struct {
+	WorkingDir string            `json:"working_dir,omitempty"`
+	Shell      string            `json:"shell,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+// IsZero reports whether d sets nothing.
+func (d SessionDefaults) IsZero() bool {
+	return d.WorkingDir == "" && d.Shell == "" && len(d.Env) == 0
+}
+
+// Merge returns d with the values o sets: o's working directory and shell
+// replace d's, and its variables are added to d's, replacing any with the
+// same name.
+func (d SessionDefaults) Merge(o SessionDefaults) SessionDefaults {
+	if o.WorkingDir != "" {
+		d.WorkingDir = o.WorkingDir
+	}
+	if o.Shell != "" {
+		d.Shell = o.Shell
+	}
+	if len(o.Env) > 0 {
+		env := make(map[string]string, len(d.Env)+len(o.Env))
+		maps.Copy(env, d.Env)
+		maps.Copy(env, o.Env)
+		d.Env = env
+	}
+	return d
+}
+
+// Validate checks variable names and that no value holds a NUL or line
+// break. The shell is checked when a command runs, like ssh_execute's.
+func (d SessionDefaults) Validate() error {
+	if strings.ContainsAny(d.WorkingDir, "\x00\n\r") {
+		return fmt.Errorf("working directory must not contain NUL or line breaks")
+	}
+	if d.Shell != "" && !ShellNamePattern.MatchString(d.Shell) {
+		return fmt.Errorf("invalid shell %q: must be a shell name or absolute path", d.Shell)
+	}
+	for name, value := range d.Env {
+		if !EnvNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if strings.ContainsAny(value, "\x00\n\r") {
+			return fmt.Errorf("environment variable %s must not contain NUL or line breaks", name)
+		}
+	}
+	return nil
+}
+
+// HostDefaults gives sessions on hosts matching Pattern these defaults.
+// Pattern is a case-insensitive, auto-anchored regex like the host filters.
+type HostDefaults struct {
+	Pattern  string
+	Defaults SessionDefaults
+}
+
+// Vault credential kinds for HostVault entries.
+const (
+	VaultKindKV    = "kv"     // KV secret with "password" and/or "private_key" fields
+	VaultKindSSHCA = "ssh-ca" // SSH secrets engine signing endpoint (<mount>/sign/<role>)
+)
+
+// VaultConfig configures the optional HashiCorp Vault credential provider.
+type VaultConfig struct {
+	Addr  string
+	Token string
+	Hosts []HostVault
+}
+
+// HostVault maps hosts matching Pattern to a Vault credential source.
+// Pattern is a case-insensitive, auto-anchored regex like the host filters.
+type HostVault struct {
+	Pattern string
+	Kind    string // VaultKindKV or VaultKindSSHCA
+	Path    string // secret path or signing endpoint, relative to /v1/
+}
+
+// HostIAP routes hosts matching Pattern through a GCP Identity-Aware Proxy
+// TCP tunnel. Project may be empty to use the gcloud default project.
+type HostIAP struct {
+	Pattern string
+	Project string
+	Zone    string
+}
+
+// HostProxyCommand connects hosts matching Pattern through Command, which
+// speaks SSH over its stdin/stdout like OpenSSH's ProxyCommand.
+type HostProxyCommand struct {
+	Pattern string
+	Command string
+}
+
+// ProxyNone as a HostProxy URL makes matching hosts bypass SSHConfig.Proxy.
+const ProxyNone = "none"
+
+// Outbound proxy URL schemes.
+const (
+	ProxySchemeHTTP    = "http"    // HTTP CONNECT
+	ProxySchemeSOCKS5  = "socks5"  // SOCKS5, target resolved locally
+	ProxySchemeSOCKS5H = "socks5h" // SOCKS5, target resolved by the proxy
+)
+
+// LocalDir is a --local-base-dir entry: a local directory that file
+// operations may use, read-only when tagged ":ro".
+type LocalDir struct {
+	Path     string
+	ReadOnly bool
+}
+
+// String formats d like the flag value.
+func (d LocalDir) String() string {
+	if d.ReadOnly {
+		return d.Path + LocalDirReadOnly
+	}
+	return d.Path
+}
+
+// Suffixes of --local-base-dir entries.
+const (
+	LocalDirReadOnly  = ":ro"
+	LocalDirReadWrite = ":rw"
+)
+
+// HostProxy dials hosts matching Pattern through the proxy at URL, or
+// directly when URL is ProxyNone.
+type HostProxy struct {
+	Pattern string
+	URL     string
+}
+
+// SSH algorithm policy modes for AlgorithmPolicy.Mode.
+const (
+	AlgorithmsDefault = "default" // golang.org/x/crypto/ssh defaults
+	AlgorithmsLegacy  = "legacy"  // every implemented algorithm, for ancient devices
+	AlgorithmsStrict  = "strict"  // AEAD ciphers, ETM MACs, no SHA-1 or ssh-rsa
+)
+
+// AlgorithmPolicy selects the algorithms offered in the SSH handshake. A
+// non-empty list replaces the mode's list for that kind.
+type AlgorithmPolicy struct {
+	Mode              string // AlgorithmsDefault, AlgorithmsLegacy or AlgorithmsStrict
+	Ciphers           []string
+	MACs              []string
+	KeyExchanges      []string
+	HostKeyAlgorithms []string
+}
+
+// HostAlgorithms applies Policy to hosts matching Pattern instead of the
+// global policy. Pattern is a case-insensitive, auto-anchored regex like the
+// host filters. A rule without a mode keeps the global mode and lists for
+// the kinds it doesn't set.
+type HostAlgorithms struct {
+	Pattern string
+	Policy  AlgorithmPolicy
 }
 
 // SecurityConfig holds security-related configuration.
@@ -103,10 +440,112 @@ type SecurityConfig struct {
 	HostDenylist     []string
 	CommandAllowlist []string
 	CommandDenylist  []string
-	RateLimit        int // requests per minute
+	ProtectProcesses []string // process name regexes ssh_signal refuses
+	ParseCommands    bool     // check each command of a shell command line
+	ResolveHosts     bool     // check and pin the resolved addresses of host names
+	RateLimit        int      // requests per minute
 	RateLimitFileOps bool
-	LocalBaseDir     string
+	RateLimitWait    time.Duration // longest a tool call waits for the rate limit, 0 = fail at once
+	LocalDirs        []LocalDir    // allowed local roots, empty = unrestricted
 	MaxFileSize      int64
+	FetchDomains     []string // domains ssh_fetch_url may download from, empty = tool disabled
+	FetchMaxSize     int64    // largest ssh_fetch_url download, 0 = unlimited
+	CredentialStore  string   // "", CredentialStoreKeychain or CredentialStoreFile
+	CredentialFile   string
+	CredentialKey    string
+	MaxConcurrent    int  // tool calls running at once, 0 = unlimited
+	SerializeCalls   bool // run calls on the same SSH session one at a time
+	PolicyWebhook    string
+	PolicyTimeout    time.Duration
+	PolicyFailOpen   bool // allow calls when the webhook fails
+}
+
+// DefaultPolicyTimeout is SecurityConfig.PolicyTimeout when unset.
+const DefaultPolicyTimeout = 5 * time.Second
+
+// Credential store backends.
+const (
+	CredentialStoreKeychain = "keychain"
+	CredentialStoreFile     = "file"
+)
+
+// DefaultTransferWorkers is SSHConfig.TransferWorkers when unset.
+const DefaultTransferWorkers = 4
+
+// File transfer protocols for SSHConfig.TransferProtocol.
+const (
+	TransferAuto = "auto" // SFTP, or scp when the SFTP subsystem is unavailable
+	TransferSFTP = "sftp"
+	TransferSCP  = "scp"
+)
+
+// Host key prompt modes for SSHConfig.HostKeyPrompt.
+const (
+	HostKeyPromptOff     = "off"
+	HostKeyPromptUnknown = "unknown" // hosts missing from known_hosts
+	HostKeyPromptAll     = "all"     // changed keys too
+)
+
+// Backup styles for BackupConfig.Style.
+const (
+	BackupStyleSimple      = "simple"      // <file>.bak, overwritten on every edit
+	BackupStyleTimestamped = "timestamped" // <file>.<UTC time>.bak, one per edit
+)
+
+// BackupConfig controls the backups ssh_edit_file writes before changing a file.
+type BackupConfig struct {
+	Style string // BackupStyleSimple or BackupStyleTimestamped
+	Keep  int    // max timestamped backups per file, 0 = unlimited
+	Dir   string // remote backup directory; empty = next to the file
+}
+
+// TranscriptConfig controls session transcript recording.
+type TranscriptConfig struct {
+	Dir       string        // local directory; empty = recording disabled
+	Retention time.Duration // delete files idle this long, 0 = keep forever
+}
+
+// AlertConfig controls the webhooks notified of security events.
+type AlertConfig struct {
+	Webhook string   // generic JSON webhook
+	Slack   string   // Slack incoming webhook
+	Events  []string // events to send; empty = all of AlertEvents
+}
+
+// Security events sent to alert webhooks.
+const (
+	AlertHostDenied     = "host_denied"      // host rejected by the host filter
+	AlertCommandDenied  = "command_denied"   // command rejected by the command filter
+	AlertPolicyDenied   = "policy_denied"    // tool call denied by the policy webhook
+	AlertSudo           = "sudo"             // tool call using sudo or run_as
+	AlertRateLimited    = "rate_limited"     // request over the per-host rate limit
+	AlertHostKeyChanged = "host_key_changed" // host key differs from known_hosts
+)
+
+// AlertEvents lists every alert event.
+var AlertEvents = []string{
+	AlertHostDenied, AlertCommandDenied, AlertPolicyDenied, AlertSudo, AlertRateLimited, AlertHostKeyChanged,
+}
+
+// Tool categories that can be disabled as a group.
+const (
+	ToolCategoryExec      = "exec"       // tools that run remote commands
+	ToolCategoryFileWrite = "file-write" // tools that create, change or move files
+	ToolCategoryTunnels   = "tunnels"    // port forwarding tools
+)
+
+// Tool presets for --preset.
+const (
+	PresetFull        = "full"
+	PresetDiagnostics = "diagnostics"
+	PresetReadOnly    = "readonly"
+)
+
+// PresetCategories lists the tool categories each preset disables.
+var PresetCategories = map[string][]string{
+	PresetFull:        nil,
+	PresetDiagnostics: {ToolCategoryFileWrite},
+	PresetReadOnly:    {ToolCategoryExec, ToolCategoryFileWrite, ToolCategoryTunnels},
 }
 
 // TransportConfig holds transport-related configuration.
@@ -117,6 +556,32 @@ type TransportConfig struct {
 	HTTPPath     string
 	HTTPHost     string // always "localhost", not configurable
 	HTTPToken    string
+	// SharedSessions puts all HTTP clients in one namespace instead of
+	// binding SSH sessions to the MCP session that created them.
+	SharedSessions bool
+	// AccessLog logs one line per HTTP request.
+	AccessLog bool
+	// MaxBodySize caps HTTP request bodies in bytes (0 = unlimited).
+	MaxBodySize int64
+	// RateLimit is the per-IP request budget per minute (0 = unlimited).
+	RateLimit int
+	// MaxConnsPerIP caps open TCP connections per client IP (0 = unlimited).
+	MaxConnsPerIP int
+	// ShutdownGrace is how long shutdown waits for running tool calls
+	// before closing connections (0 = close immediately).
+	ShutdownGrace time.Duration
+}
+
+// validateWebhookURL checks that raw, if set, is an absolute http(s) URL.
+func validateWebhookURL(name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL, got %q", name, raw)
+	}
+	return nil
 }
 
 // Validate checks the configuration for errors.
@@ -127,40 +592,294 @@ func (c *Config) Validate() error {
 	if !c.Transport.StdioEnabled && !c.Transport.HTTPEnabled {
 		return fmt.Errorf("at least one transport (stdio or HTTP) must be enabled")
 	}
+	if _, ok := PresetCategories[c.Preset]; !ok && c.Preset != "" {
+		return fmt.Errorf("invalid preset %q (must be %s, %s or %s)", c.Preset, PresetFull, PresetDiagnostics, PresetReadOnly)
+	}
+	if err := c.validateTenants(); err != nil {
+		return err
+	}
+	if err := c.validateCommandTemplates(); err != nil {
+		return err
+	}
+	for _, p := range c.Plugins {
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("plugin: %w", err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("plugin %s is a directory", p)
+		}
+	}
+	if c.SSH.ExecuteRetries < 0 || c.SSH.ExecuteRetries > MaxExecuteRetries {
+		return fmt.Errorf("execute retries must be between 0 and %d", MaxExecuteRetries)
+	}
+	if c.SSH.RetryBackoff < 0 {
+		return fmt.Errorf("execute retry backoff must not be negative")
+	}
+	if c.SSH.Locale != "" && !LocalePattern.MatchString(c.SSH.Locale) {
+		return fmt.Errorf("invalid locale %q", c.SSH.Locale)
+	}
+	if c.SSH.Term != "" && !TermPattern.MatchString(c.SSH.Term) {
+		return fmt.Errorf("invalid term %q", c.SSH.Term)
+	}
+	if c.SSH.KillGrace < 0 || c.SSH.KillGrace > MaxKillGrace {
+		return fmt.Errorf("kill grace must be between 0 and %s", MaxKillGrace)
+	}
 	if c.SSH.CommandTimeout <= 0 {
 		return fmt.Errorf("command timeout must be positive")
 	}
+	if c.SSH.DialAttemptTimeout < 0 {
+		return fmt.Errorf("dial attempt timeout must not be negative")
+	}
 	if c.SSH.ConnectionTimeout <= 0 {
 		return fmt.Errorf("connection timeout must be positive")
 	}
+	if c.SSH.MaxIdleTime <= 0 {
+		return fmt.Errorf("max idle time must be positive")
+	}
+	if c.SSH.SFTPTimeout < 0 {
+		return fmt.Errorf("SFTP timeout must be non-negative")
+	}
+	if c.SSH.TransferWorkers < 1 {
+		return fmt.Errorf("transfer workers must be at least 1")
+	}
+	if c.Security.MaxConcurrent < 0 {
+		return fmt.Errorf("max concurrent tools must be non-negative")
+	}
+	if err := validateWebhookURL("policy webhook", c.Security.PolicyWebhook); err != nil {
+		return err
+	}
+	if err := validateWebhookURL("alert webhook", c.Alert.Webhook); err != nil {
+		return err
+	}
+	if err := validateWebhookURL("Slack alert webhook", c.Alert.Slack); err != nil {
+		return err
+	}
+	if c.Transcript.Retention < 0 {
+		return fmt.Errorf("transcript retention must be non-negative")
+	}
+	if c.Transcript.Retention > 0 && c.Transcript.Dir == "" {
+		return fmt.Errorf("--transcript-retention requires --transcript-dir")
+	}
+	for _, e := range c.Alert.Events {
+		if !slices.Contains(AlertEvents, e) {
+			return fmt.Errorf("invalid alert event %q (must be one of %s)", e, strings.Join(AlertEvents, ", "))
+		}
+	}
+	if len(c.Alert.Events) > 0 && c.Alert.Webhook == "" && c.Alert.Slack == "" {
+		return fmt.Errorf("--alert-events requires --alert-webhook or --alert-slack")
+	}
+	if c.Security.PolicyTimeout < 0 {
+		return fmt.Errorf("policy timeout must be non-negative")
+	}
+	if c.Transport.ShutdownGrace < 0 {
+		return fmt.Errorf("shutdown grace period must be non-negative")
+	}
+	if c.Transport.MaxBodySize < 0 {
+		return fmt.Errorf("HTTP max body size must be non-negative")
+	}
+	if c.Transport.RateLimit < 0 {
+		return fmt.Errorf("HTTP rate limit must be non-negative")
+	}
+	if c.Transport.MaxConnsPerIP < 0 {
+		return fmt.Errorf("HTTP max connections per IP must be non-negative")
+	}
+	if c.SSH.MaxLifetime < 0 {
+		return fmt.Errorf("max session lifetime must be non-negative")
+	}
+	for _, o := range c.SSH.HostIdleTimeouts {
+		if o.Timeout <= 0 {
+			return fmt.Errorf("idle time for host pattern %q must be positive", o.Pattern)
+		}
+		if _, err := regexp.Compile(o.Pattern); err != nil {
+			return fmt.Errorf("invalid host idle time pattern %q: %w", o.Pattern, err)
+		}
+	}
+	for _, h := range c.SSH.HostDefaults {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("invalid host defaults pattern %q: %w", h.Pattern, err)
+		}
+		if err := h.Defaults.Validate(); err != nil {
+			return fmt.Errorf("host defaults %q: %w", h.Pattern, err)
+		}
+	}
+	if len(c.SSH.Vault.Hosts) > 0 {
+		if c.SSH.Vault.Addr == "" || c.SSH.Vault.Token == "" {
+			return fmt.Errorf("--host-vault requires a Vault address and token (--vault-addr/--vault-token or VAULT_ADDR/VAULT_TOKEN)")
+		}
+		for _, h := range c.SSH.Vault.Hosts {
+			if _, err := regexp.Compile(h.Pattern); err != nil {
+				return fmt.Errorf("invalid host vault pattern %q: %w", h.Pattern, err)
+			}
+		}
+	}
+	for _, h := range c.SSH.HostIAP {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("invalid host IAP pattern %q: %w", h.Pattern, err)
+		}
+	}
+	for _, h := range c.SSH.HostProxyCommands {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("invalid host proxy command pattern %q: %w", h.Pattern, err)
+		}
+	}
+	if c.SSH.Proxy != "" {
+		if err := validateProxyURL(c.SSH.Proxy); err != nil {
+			return fmt.Errorf("invalid --ssh-proxy: %w", err)
+		}
+	}
+	for _, h := range c.SSH.HostProxies {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("invalid host proxy pattern %q: %w", h.Pattern, err)
+		}
+		if h.URL == ProxyNone {
+			continue
+		}
+		if err := validateProxyURL(h.URL); err != nil {
+			return fmt.Errorf("invalid host proxy %q: %w", h.Pattern, err)
+		}
+	}
+	if err := c.SSH.Algorithms.validate(); err != nil {
+		return err
+	}
+	for _, h := range c.SSH.HostAlgorithms {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			return fmt.Errorf("invalid host algorithms pattern %q: %w", h.Pattern, err)
+		}
+		if err := h.Policy.validate(); err != nil {
+			return fmt.Errorf("host algorithms %q: %w", h.Pattern, err)
+		}
+	}
+	for _, p := range c.Security.ProtectProcesses {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid protected process pattern %q: %w", p, err)
+		}
+	}
+	for _, u := range c.SSH.RunAsUsers {
+		if u != "*" && !UserNamePattern.MatchString(u) {
+			return fmt.Errorf("invalid run-as user %q", u)
+		}
+	}
 	if c.Security.RateLimit <= 0 {
 		return fmt.Errorf("rate limit must be positive")
 	}
-	if c.Security.LocalBaseDir != "" {
-		absPath, err := filepath.Abs(c.Security.LocalBaseDir)
+	if c.Security.RateLimitWait < 0 {
+		return fmt.Errorf("rate limit wait must not be negative")
+	}
+	for i, d := range c.Security.LocalDirs {
+		absPath, err := filepath.Abs(d.Path)
 		if err != nil {
 			return fmt.Errorf("invalid local base dir: %w", err)
 		}
 		if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
-			return fmt.Errorf("local base dir %q does not exist or is not a directory", c.Security.LocalBaseDir)
+			return fmt.Errorf("local base dir %q does not exist or is not a directory", d.Path)
 		}
-		c.Security.LocalBaseDir = absPath
+		c.Security.LocalDirs[i].Path = absPath
+	}
+	switch c.Security.CredentialStore {
+	case "", CredentialStoreKeychain:
+	case CredentialStoreFile:
+		if c.Security.CredentialKey == "" {
+			return fmt.Errorf("--credential-store file requires --credential-key (or MCP_SSH_CREDENTIAL_KEY)")
+		}
+		if c.Security.CredentialFile == "" {
+			return fmt.Errorf("--credential-store file requires --credential-file")
+		}
+	default:
+		return fmt.Errorf("invalid credential store %q (must be %s or %s)",
+			c.Security.CredentialStore, CredentialStoreKeychain, CredentialStoreFile)
 	}
 	if c.Security.MaxFileSize < 0 {
 		return fmt.Errorf("max file size must be non-negative")
 	}
+	for _, d := range c.Security.FetchDomains {
+		if name := strings.TrimPrefix(d, "*."); name == "" || strings.ContainsAny(name, "/:*@ ") {
+			return fmt.Errorf("invalid fetch domain %q (expected a host name like example.com or *.example.com)", d)
+		}
+	}
+	if c.Security.FetchMaxSize < 0 {
+		return fmt.Errorf("fetch max size must be non-negative")
+	}
 	if c.SSH.MaxConnections < 0 {
 		return fmt.Errorf("max connections must be non-negative")
 	}
 	if c.SSH.MaxTerminals < 0 {
 		return fmt.Errorf("max terminals must be non-negative")
 	}
+	if err := charset.Validate(c.SSH.OutputEncoding); err != nil {
+		return fmt.Errorf("output encoding: %w", err)
+	}
+	if c.SSH.FallbackEncoding != "" {
+		if _, err := charset.Lookup(c.SSH.FallbackEncoding); err != nil {
+			return fmt.Errorf("fallback encoding: %w", err)
+		}
+	}
+	switch c.SSH.HostKeyPrompt {
+	case HostKeyPromptOff, HostKeyPromptUnknown, HostKeyPromptAll:
+	default:
+		return fmt.Errorf("invalid host key prompt %q (must be %s, %s or %s)",
+			c.SSH.HostKeyPrompt, HostKeyPromptOff, HostKeyPromptUnknown, HostKeyPromptAll)
+	}
+	if c.SSH.HostKeyPrompt != HostKeyPromptOff && !c.SSH.VerifyHostKey {
+		return fmt.Errorf("--host-key-prompt needs host key verification (drop --no-verify-host-key)")
+	}
+	switch c.SSH.TransferProtocol {
+	case TransferAuto, TransferSFTP, TransferSCP:
+	default:
+		return fmt.Errorf("invalid transfer protocol %q (must be %s, %s or %s)",
+			c.SSH.TransferProtocol, TransferAuto, TransferSFTP, TransferSCP)
+	}
 	if c.SSH.MaxOutputSize < 0 {
 		return fmt.Errorf("max output size must be non-negative")
 	}
 	if c.SSH.MaxTunnels < 0 {
 		return fmt.Errorf("max tunnels must be non-negative")
 	}
+	switch c.Backup.Style {
+	case BackupStyleSimple, BackupStyleTimestamped:
+	default:
+		return fmt.Errorf("invalid backup style %q (must be %s or %s)",
+			c.Backup.Style, BackupStyleSimple, BackupStyleTimestamped)
+	}
+	if c.Backup.Keep < 0 {
+		return fmt.Errorf("backup keep must be non-negative")
+	}
+	if c.Backup.Keep > 0 && c.Backup.Style != BackupStyleTimestamped {
+		return fmt.Errorf("--backup-keep requires --backup-style %s", BackupStyleTimestamped)
+	}
+	if c.Backup.Dir != "" && !strings.HasPrefix(c.Backup.Dir, "/") && !strings.HasPrefix(c.Backup.Dir, "~") {
+		return fmt.Errorf("backup dir %q must be an absolute remote path or start with ~", c.Backup.Dir)
+	}
+	return nil
+}
+
+// validateTenants checks that every tenant has a unique name and token and
+// that tenants can be reached over HTTP.
+func (c *Config) validateTenants() error {
+	if len(c.Tenants) == 0 {
+		return nil
+	}
+	if !c.Transport.HTTPEnabled {
+		return fmt.Errorf("--tenants requires --enable-http")
+	}
+	names := make(map[string]bool, len(c.Tenants))
+	tokens := make(map[string]bool, len(c.Tenants))
+	for i, t := range c.Tenants {
+		switch {
+		case t.Name == "":
+			return fmt.Errorf("tenant %d: name is required", i+1)
+		case names[t.Name]:
+			return fmt.Errorf("tenant %q is defined more than once", t.Name)
+		case t.Token == "":
+			return fmt.Errorf("tenant %q: token is required", t.Name)
+		case tokens[t.Token] || t.Token == c.Transport.HTTPToken:
+			return fmt.Errorf("tenant %q: token is already in use", t.Name)
+		}
+		if _, ok := PresetCategories[t.Preset]; !ok && t.Preset != "" {
+			return fmt.Errorf("tenant %q: invalid preset %q", t.Name, t.Preset)
+		}
+		names[t.Name], tokens[t.Token] = true, true
+	}
 	return nil
 }
 
@@ -216,18 +935,164 @@ func buildConfig(args Args) (*Config, error) {
 		sshConfigPath = filepath.Join(sshDir, "config")
 	}
 
+	maxIdleTime := args.MaxIdleTime
+	if maxIdleTime == 0 {
+		maxIdleTime = defaultMaxIdleTime
+	}
+
+	hostIdleTimeouts, err := parseHostIdleTimeouts(args.HostIdleTimeouts)
+	if err != nil {
+		return nil, err
+	}
+
+	hostMap, err := parseHostMap(args.HostMap)
+	if err != nil {
+		return nil, err
+	}
+
+	hostVault, err := parseHostVault(args.HostVault)
+	if err != nil {
+		return nil, err
+	}
+
+	hostIAP, err := parseHostIAP(args.HostIAP)
+	if err != nil {
+		return nil, err
+	}
+
+	hostProxyCommands, err := parseHostProxyCommands(args.HostProxyCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	hostProxies, err := parseHostProxies(args.HostProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	hostDefaults, err := parseHostDefaults(args.HostDefaults)
+	if err != nil {
+		return nil, err
+	}
+
+	hostAlgorithms, err := parseHostAlgorithms(args.HostAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	algorithmMode := args.SSHAlgorithms
+	if algorithmMode == "" {
+		algorithmMode = AlgorithmsDefault
+	}
+	vaultAddr := args.VaultAddr
+	if vaultAddr == "" {
+		vaultAddr = os.Getenv("VAULT_ADDR")
+	}
+	vaultToken := args.VaultToken
+	if vaultToken == "" {
+		vaultToken = os.Getenv("VAULT_TOKEN")
+	}
+
+	backupStyle := args.BackupStyle
+	if backupStyle == "" {
+		backupStyle = BackupStyleSimple
+	}
+
+	hostKeyPrompt := args.HostKeyPrompt
+	if hostKeyPrompt == "" {
+		hostKeyPrompt = HostKeyPromptOff
+	}
+
+	transferProtocol := args.TransferProtocol
+	if transferProtocol == "" {
+		transferProtocol = TransferAuto
+	}
+
+	transferWorkers := args.TransferWorkers
+	if transferWorkers == 0 {
+		transferWorkers = DefaultTransferWorkers
+	}
+
+	policyTimeout := args.PolicyTimeout
+	if policyTimeout == 0 {
+		policyTimeout = DefaultPolicyTimeout
+	}
+
+	tenants, err := loadTenants(args.TenantsFile)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := loadCommandTemplates(args.CommandTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadUmask, err := parseFileMode("upload umask", args.UploadUmask)
+	if err != nil {
+		return nil, err
+	}
+	uploadFileMode, err := parseFileMode("upload file mode", args.UploadFileMode)
+	if err != nil {
+		return nil, err
+	}
+	uploadDirMode, err := parseFileMode("upload dir mode", args.UploadDirMode)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialFile := args.CredentialFile
+	if credentialFile == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			credentialFile = filepath.Join(configDir, "ssh-mcp", "credentials")
+		}
+	}
+
 	return &Config{
 		SSH: SSHConfig{
-			KnownHostsPath:    knownHosts,
-			VerifyHostKey:     !args.NoVerifyHost,
-			ConfigPath:        sshConfigPath,
-			KeySearchPaths:    defaultKeyPaths(sshDir),
-			CommandTimeout:    args.CommandTimeout,
-			ConnectionTimeout: 30 * time.Second,
-			MaxIdleTime:       5 * time.Minute,
+			KnownHostsPath:     knownHosts,
+			VerifyHostKey:      !args.NoVerifyHost,
+			HostKeyPrompt:      hostKeyPrompt,
+			ConfigPath:         sshConfigPath,
+			SSHDir:             sshDir,
+			KeySearchPaths:     defaultKeyPaths(sshDir),
+			CommandTimeout:     args.CommandTimeout,
+			ExecuteRetries:     args.ExecuteRetries,
+			RetryBackoff:       args.RetryBackoff,
+			KillGrace:          args.KillGrace,
+			Locale:             args.Locale,
+			Term:               args.Term,
+			ConnectionTimeout:  30 * time.Second,
+			DialAttemptTimeout: args.DialAttemptTimeout,
+			MaxIdleTime:        maxIdleTime,
+			MaxLifetime:        args.MaxLifetime,
+			HostMap:            hostMap,
+			HostIdleTimeouts:   hostIdleTimeouts,
+			HostDefaults:       hostDefaults,
+			Vault:              VaultConfig{Addr: vaultAddr, Token: vaultToken, Hosts: hostVault},
+			HostIAP:            hostIAP,
+			HostProxyCommands:  hostProxyCommands,
+			Proxy:              args.SSHProxy,
+			HostProxies:        hostProxies,
+			Algorithms: AlgorithmPolicy{
+				Mode:              algorithmMode,
+				Ciphers:           []string(args.SSHCiphers),
+				MACs:              []string(args.SSHMACs),
+				KeyExchanges:      []string(args.SSHKex),
+				HostKeyAlgorithms: []string(args.SSHHostKeyAlgos),
+			},
+			HostAlgorithms:    hostAlgorithms,
 			AllowSudo:         args.EnableSudo,
+			AllowAgentForward: args.EnableAgentForward,
+			RunAsUsers:        []string(args.RunAsUsers),
 			AllowTerminal:     args.EnableTerminal,
 			StripANSI:         true,
+			OutputEncoding:    args.OutputEncoding,
+			FallbackEncoding:  args.FallbackEncoding,
+			TransferProtocol:  transferProtocol,
+			SFTPTimeout:       args.SFTPTimeout,
+			TransferWorkers:   transferWorkers,
+			UploadFileMode:    uploadFileMode,
+			UploadDirMode:     uploadDirMode,
+			UploadUmask:       uploadUmask,
 			MaxConnections:    args.MaxConnections,
 			MaxTerminals:      args.MaxTerminals,
 			MaxOutputSize:     args.MaxOutputSize,
@@ -239,28 +1104,395 @@ func buildConfig(args Args) (*Config, error) {
 			HostDenylist:     []string(args.HostDenylist),
 			CommandAllowlist: []string(args.CommandAllowlist),
 			CommandDenylist:  []string(args.CommandDenylist),
+			ProtectProcesses: []string(args.ProtectProcesses),
+			ParseCommands:    args.ParseCommands,
+			ResolveHosts:     args.ResolveHosts,
 			RateLimit:        args.RateLimit,
 			RateLimitFileOps: args.RateLimitFileOps,
-			LocalBaseDir:     args.LocalBaseDir,
+			RateLimitWait:    args.RateLimitWait,
+			LocalDirs:        parseLocalDirs(args.LocalBaseDir),
 			MaxFileSize:      args.MaxFileSize,
+			FetchDomains:     []string(args.FetchDomains),
+			FetchMaxSize:     args.FetchMaxSize,
+			CredentialStore:  args.CredentialStore,
+			CredentialFile:   credentialFile,
+			CredentialKey:    args.CredentialKey,
+			MaxConcurrent:    args.MaxConcurrent,
+			SerializeCalls:   args.SerializeCalls,
+			PolicyWebhook:    args.PolicyWebhook,
+			PolicyTimeout:    policyTimeout,
+			PolicyFailOpen:   args.PolicyFailOpen,
 		},
 		Transport: TransportConfig{
-			StdioEnabled: !args.DisableStdio,
-			HTTPEnabled:  args.EnableHTTP,
-			HTTPPort:     args.HTTPPort,
-			HTTPPath:     "/mcp",
-			HTTPHost:     "localhost", // hardcoded, not configurable
-			HTTPToken:    args.HTTPToken,
+			StdioEnabled:   !args.DisableStdio,
+			HTTPEnabled:    args.EnableHTTP,
+			HTTPPort:       args.HTTPPort,
+			HTTPPath:       "/mcp",
+			HTTPHost:       "localhost", // hardcoded, not configurable
+			HTTPToken:      args.HTTPToken,
+			SharedSessions: args.SharedSessions,
+			AccessLog:      args.HTTPAccessLog,
+			MaxBodySize:    args.HTTPMaxBody,
+			RateLimit:      args.HTTPRateLimit,
+			MaxConnsPerIP:  args.HTTPMaxConns,
+			ShutdownGrace:  args.ShutdownGrace,
 		},
-		DisabledTools: []string(args.DisableTools),
+		Backup: BackupConfig{
+			Style: backupStyle,
+			Keep:  args.BackupKeep,
+			Dir:   args.BackupDir,
+		},
+		Transcript: TranscriptConfig{
+			Dir:       args.TranscriptDir,
+			Retention: args.TranscriptKeep,
+		},
+		Alert: AlertConfig{
+			Webhook: args.AlertWebhook,
+			Slack:   args.AlertSlack,
+			Events:  []string(args.AlertEvents),
+		},
+		DisabledTools:      []string(args.DisableTools),
+		Preset:             args.ToolPreset,
+		DisabledCategories: disabledCategories(args),
+		Tenants:            tenants,
+		CommandTemplates:   templates,
+		Plugins:            []string(args.Plugins),
 	}, nil
 }
 
+// loadTenants reads the --tenants file: {"tenants": [{...}, ...]}. Unknown
+// fields are rejected so a misspelled rule can't silently allow everything.
+func loadTenants(path string) ([]Tenant, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("tenants: %w", err)
+	}
+	defer f.Close()
+	var file struct {
+		Tenants []Tenant `json:"tenants"`
+	}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("tenants %s: %w", path, err)
+	}
+	if len(file.Tenants) == 0 {
+		return nil, fmt.Errorf("tenants %s: no tenants defined", path)
+	}
+	return file.Tenants, nil
+}
+
+// disabledCategories merges the categories of --preset with the
+// --disable-exec and --disable-file-write switches.
+func disabledCategories(args Args) []string {
+	cats := slices.Clone(PresetCategories[args.ToolPreset])
+	if args.DisableExec && !slices.Contains(cats, ToolCategoryExec) {
+		cats = append(cats, ToolCategoryExec)
+	}
+	if args.DisableFileWrite && !slices.Contains(cats, ToolCategoryFileWrite) {
+		cats = append(cats, ToolCategoryFileWrite)
+	}
+	return cats
+}
+
+// parseLocalDirs parses "PATH[:ro|:rw]" entries; without a suffix the
+// directory is read-write.
+func parseLocalDirs(entries []string) []LocalDir {
+	var dirs []LocalDir
+	for _, e := range entries {
+		if e == "" {
+			continue
+		}
+		if p, ok := strings.CutSuffix(e, LocalDirReadOnly); ok {
+			dirs = append(dirs, LocalDir{Path: p, ReadOnly: true})
+			continue
+		}
+		dirs = append(dirs, LocalDir{Path: strings.TrimSuffix(e, LocalDirReadWrite)})
+	}
+	return dirs
+}
+
+// parseFileMode parses an octal permission mode such as 644 or 0750; empty
+// means 0. Setuid, setgid and sticky bits are rejected.
+func parseFileMode(name, s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid %s %q (expected octal permission bits, 000-777)", name, s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// parseHostIdleTimeouts parses "PATTERN=DURATION" entries. The last '=' is
+// used as the separator so patterns may contain '=' themselves.
+func parseHostIdleTimeouts(entries []string) ([]HostIdleTimeout, error) {
+	result := make([]HostIdleTimeout, 0, len(entries))
+	for _, e := range entries {
+		idx := strings.LastIndex(e, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid host idle time %q (expected PATTERN=DURATION)", e)
+		}
+		d, err := time.ParseDuration(e[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid host idle time %q: %w", e, err)
+		}
+		result = append(result, HostIdleTimeout{Pattern: e[:idx], Timeout: d})
+	}
+	return result, nil
+}
+
+// parseHostVault parses "PATTERN=KIND:PATH" entries.
+func parseHostVault(entries []string) ([]HostVault, error) {
+	result := make([]HostVault, 0, len(entries))
+	for _, e := range entries {
+		idx := strings.LastIndex(e, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid host vault %q (expected PATTERN=KIND:PATH)", e)
+		}
+		kind, path, ok := strings.Cut(e[idx+1:], ":")
+		path = strings.Trim(path, "/")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid host vault %q (expected PATTERN=KIND:PATH)", e)
+		}
+		if kind != VaultKindKV && kind != VaultKindSSHCA {
+			return nil, fmt.Errorf("invalid host vault %q: unknown kind %q (must be %s or %s)", e, kind, VaultKindKV, VaultKindSSHCA)
+		}
+		result = append(result, HostVault{Pattern: e[:idx], Kind: kind, Path: path})
+	}
+	return result, nil
+}
+
+// parseHostMap parses "NAME=IP[:PORT]" entries. IPv6 addresses with a port
+// must be bracketed ([2001:db8::1]:22).
+func parseHostMap(entries []string) ([]HostMapping, error) {
+	result := make([]HostMapping, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		alias, addr, ok := strings.Cut(e, "=")
+		if !ok || alias == "" || addr == "" {
+			return nil, fmt.Errorf("invalid host map %q (expected NAME=IP[:PORT])", e)
+		}
+		m := HostMapping{Alias: alias, Address: addr}
+		if host, portStr, err := net.SplitHostPort(addr); err == nil {
+			port, err := strconv.Atoi(portStr)
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid host map %q: bad port %q", e, portStr)
+			}
+			m.Address, m.Port = host, port
+		}
+		if net.ParseIP(m.Address) == nil {
+			return nil, fmt.Errorf("invalid host map %q: %q is not an IP address", e, m.Address)
+		}
+		if key := strings.ToLower(alias); seen[key] {
+			return nil, fmt.Errorf("duplicate host map entry for %q", alias)
+		} else {
+			seen[key] = true
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// parseHostIAP parses "PATTERN=[PROJECT/]ZONE" entries.
+func parseHostIAP(entries []string) ([]HostIAP, error) {
+	result := make([]HostIAP, 0, len(entries))
+	for _, e := range entries {
+		idx := strings.LastIndex(e, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid host IAP %q (expected PATTERN=[PROJECT/]ZONE)", e)
+		}
+		h := HostIAP{Pattern: e[:idx], Zone: e[idx+1:]}
+		if project, zone, ok := strings.Cut(h.Zone, "/"); ok {
+			h.Project, h.Zone = project, zone
+			if project == "" {
+				return nil, fmt.Errorf("invalid host IAP %q: empty project", e)
+			}
+		}
+		if h.Zone == "" || strings.Contains(h.Zone, "/") {
+			return nil, fmt.Errorf("invalid host IAP %q (expected PATTERN=[PROJECT/]ZONE)", e)
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// parseHostProxyCommands parses "PATTERN=COMMAND" entries. Unlike the other
+// per-host flags the first '=' is the separator, since commands commonly
+// contain '=' (e.g. --cluster=prod).
+func parseHostProxyCommands(entries []string) ([]HostProxyCommand, error) {
+	result := make([]HostProxyCommand, 0, len(entries))
+	for _, e := range entries {
+		pattern, command, ok := strings.Cut(e, "=")
+		command = strings.TrimSpace(command)
+		if !ok || pattern == "" || command == "" {
+			return nil, fmt.Errorf("invalid host proxy command %q (expected PATTERN=COMMAND)", e)
+		}
+		result = append(result, HostProxyCommand{Pattern: pattern, Command: command})
+	}
+	return result, nil
+}
+
+// parseHostProxies parses "PATTERN=URL" entries. The first '=' is the
+// separator, since proxy URLs may contain '=' in credentials.
+func parseHostProxies(entries []string) ([]HostProxy, error) {
+	result := make([]HostProxy, 0, len(entries))
+	for _, e := range entries {
+		pattern, u, ok := strings.Cut(e, "=")
+		if !ok || pattern == "" || u == "" {
+			return nil, fmt.Errorf("invalid host proxy %q (expected PATTERN=URL)", e)
+		}
+		result = append(result, HostProxy{Pattern: pattern, URL: u})
+	}
+	return result, nil
+}
+
+// validateProxyURL checks that s is an http, socks5 or socks5h URL with an
+// explicit host and port and nothing else.
+func validateProxyURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case ProxySchemeHTTP, ProxySchemeSOCKS5, ProxySchemeSOCKS5H:
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (must be %s, %s or %s)", u.Scheme, ProxySchemeHTTP, ProxySchemeSOCKS5, ProxySchemeSOCKS5H)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return fmt.Errorf("proxy %q must include host and port", u.Redacted())
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("proxy %q must not have a path or query", u.Redacted())
+	}
+	return nil
+}
+
+// parseHostDefaults parses "PATTERN=SPEC" entries, where SPEC is a
+// ';'-separated list of dir=PATH, shell=NAME and env=NAME=VALUE items. The
+// first '=' is the separator; env may be given more than once.
+func parseHostDefaults(entries []string) ([]HostDefaults, error) {
+	result := make([]HostDefaults, 0, len(entries))
+	for _, e := range entries {
+		pattern, spec, ok := strings.Cut(e, "=")
+		if !ok || pattern == "" || strings.TrimSpace(spec) == "" {
+			return nil, fmt.Errorf("invalid host defaults %q (expected PATTERN=SPEC)", e)
+		}
+		h := HostDefaults{Pattern: pattern}
+		for _, item := range strings.Split(spec, ";") {
+			kind, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok || value == "" {
+				return nil, fmt.Errorf("invalid host defaults %q: %q is not KIND=VALUE", e, item)
+			}
+			switch kind {
+			case "dir":
+				h.Defaults.WorkingDir = value
+			case "shell":
+				h.Defaults.Shell = value
+			case "env":
+				name, v, ok := strings.Cut(value, "=")
+				if !ok {
+					return nil, fmt.Errorf("invalid host defaults %q: env needs NAME=VALUE", e)
+				}
+				if h.Defaults.Env == nil {
+					h.Defaults.Env = map[string]string{}
+				}
+				h.Defaults.Env[name] = v
+			default:
+				return nil, fmt.Errorf("invalid host defaults %q: unknown kind %q (must be dir, shell or env)", e, kind)
+			}
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// parseHostAlgorithms parses "PATTERN=SPEC" entries, where SPEC is a
+// ';'-separated list of a mode and KIND=NAME+NAME items. The first '=' is the
+// separator. A missing mode is left empty, meaning "as configured globally".
+func parseHostAlgorithms(entries []string) ([]HostAlgorithms, error) {
+	result := make([]HostAlgorithms, 0, len(entries))
+	for _, e := range entries {
+		pattern, spec, ok := strings.Cut(e, "=")
+		if !ok || pattern == "" || strings.TrimSpace(spec) == "" {
+			return nil, fmt.Errorf("invalid host algorithms %q (expected PATTERN=SPEC)", e)
+		}
+		h := HostAlgorithms{Pattern: pattern}
+		for _, item := range strings.Split(spec, ";") {
+			item = strings.TrimSpace(item)
+			kind, names, isList := strings.Cut(item, "=")
+			if !isList {
+				if h.Policy.Mode != "" {
+					return nil, fmt.Errorf("invalid host algorithms %q: more than one mode", e)
+				}
+				h.Policy.Mode = item
+				continue
+			}
+			var list []string
+			for _, n := range strings.Split(names, "+") {
+				if n = strings.TrimSpace(n); n != "" {
+					list = append(list, n)
+				}
+			}
+			switch kind {
+			case "ciphers":
+				h.Policy.Ciphers = list
+			case "macs":
+				h.Policy.MACs = list
+			case "kex":
+				h.Policy.KeyExchanges = list
+			case "host-keys":
+				h.Policy.HostKeyAlgorithms = list
+			default:
+				return nil, fmt.Errorf("invalid host algorithms %q: unknown kind %q (must be ciphers, macs, kex or host-keys)", e, kind)
+			}
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// validate checks the mode and that every algorithm name is one
+// golang.org/x/crypto/ssh implements. An empty mode is allowed for host
+// rules, which inherit the global one.
+func (p AlgorithmPolicy) validate() error {
+	switch p.Mode {
+	case "", AlgorithmsDefault, AlgorithmsLegacy, AlgorithmsStrict:
+	default:
+		return fmt.Errorf("invalid SSH algorithm mode %q (must be %s, %s or %s)",
+			p.Mode, AlgorithmsDefault, AlgorithmsLegacy, AlgorithmsStrict)
+	}
+	known, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+	for _, k := range []struct {
+		kind  string
+		names []string
+		known []string
+	}{
+		{"cipher", p.Ciphers, append(known.Ciphers, insecure.Ciphers...)},
+		{"MAC", p.MACs, append(known.MACs, insecure.MACs...)},
+		{"key exchange", p.KeyExchanges, append(known.KeyExchanges, insecure.KeyExchanges...)},
+		{"host key algorithm", p.HostKeyAlgorithms, append(known.HostKeys, insecure.HostKeys...)},
+	} {
+		for _, n := range k.names {
+			if !slices.Contains(k.known, n) {
+				return fmt.Errorf("unsupported %s %q", k.kind, n)
+			}
+		}
+	}
+	return nil
+}
+
 func defaultKeyPaths(sshDir string) []string {
 	return []string{
 		filepath.Join(sshDir, "id_rsa"),
 		filepath.Join(sshDir, "id_ed25519"),
 		filepath.Join(sshDir, "id_ecdsa"),
 		filepath.Join(sshDir, "id_dsa"),
+		filepath.Join(sshDir, "id_ed25519_sk"),
+		filepath.Join(sshDir, "id_ecdsa_sk"),
 	}
 }
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Types of a command template parameter.
+const (
+	ParamString  = "string"
+	ParamInteger = "integer"
+	ParamBoolean = "boolean"
+)
+
+// MaxTemplateTimeout caps the timeout of a command template, in seconds.
+const MaxTemplateTimeout = 3600
+
+var (
+	// TemplateNamePattern is what a command template's tool name must
+	// match. Names starting with ssh_ are kept for the built-in tools.
+	TemplateNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// TemplatePlaceholder matches a {{param}} placeholder in a template
+	// command.
+	TemplatePlaceholder  = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]*)\s*\}\}`)
+	templateParamPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+)
+
+// scriptInterpreters are the interpreter names ssh_run_script accepts.
+var scriptInterpreters = []string{"bash", "sh", "python", "python3", "powershell", "pwsh"}
+
+// CommandTemplate is one entry of the --command-templates file: a command
+// with typed {{param}} placeholders, or a script that gets the parameters
+// as arguments, offered as a tool of its own so an agent can run exactly
+// that operation without general ssh_execute.
+type CommandTemplate struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Command     string               `json:"command,omitempty"`
+	Script      string               `json:"script,omitempty"`      // instead of command, run like ssh_run_script
+	Interpreter string               `json:"interpreter,omitempty"` // of script; default bash (powershell on Windows)
+	Params      []TemplateParam      `json:"params,omitempty"`
+	SessionID   string               `json:"session_id,omitempty"` // run on this session; the tool takes no session_id
+	Hosts       []string             `json:"hosts,omitempty"`      // host patterns (regex or CIDR) the session must match
+	Sudo        bool                 `json:"sudo,omitempty"`
+	Timeout     int                  `json:"timeout,omitempty"` // seconds; 0 = --command-timeout
+	Annotations *TemplateAnnotations `json:"annotations,omitempty"`
+}
+
+// TemplateAnnotations are the MCP tool hints of a command template. Without
+// them a template is a destructive, non-idempotent tool titled by its name.
+type TemplateAnnotations struct {
+	Title       string `json:"title,omitempty"`
+	ReadOnly    bool   `json:"read_only,omitempty"`
+	Destructive *bool  `json:"destructive,omitempty"` // default: !read_only
+	Idempotent  bool   `json:"idempotent,omitempty"`
+}
+
+// TemplateParam is a placeholder of a command template and the input
+// property its tool takes for it. Strings are shell-quoted into the
+// command; a boolean becomes Flag when true and nothing when false.
+type TemplateParam struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"` // ParamString (default), ParamInteger or ParamBoolean
+	Description string   `json:"description,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"` // regex a string must match in full
+	Enum        []string `json:"enum,omitempty"`    // the only values a string may take
+	Min         *int64   `json:"min,omitempty"`     // integer bounds
+	Max         *int64   `json:"max,omitempty"`
+	Flag        string   `json:"flag,omitempty"`    // booleans: text for true, e.g. --force
+	Default     any      `json:"default,omitempty"` // makes the parameter optional
+}
+
+// ParamType returns the type of p, defaulting to ParamString.
+func (p TemplateParam) ParamType() string {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+// loadCommandTemplates reads the --command-templates file:
+// {"templates": [{...}, ...], "tools": [{...}, ...]}. Both lists hold
+// CommandTemplate entries; "tools" reads better for scripts and fixed
+// sessions. Unknown fields are rejected, as in the --tenants file.
+func loadCommandTemplates(path string) ([]CommandTemplate, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("command templates: %w", err)
+	}
+	defer f.Close()
+	var file struct {
+		Templates []CommandTemplate `json:"templates"`
+		Tools     []CommandTemplate `json:"tools"`
+	}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("command templates %s: %w", path, err)
+	}
+	templates := append(file.Templates, file.Tools...)
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("command templates %s: no templates defined", path)
+	}
+	return templates, nil
+}
+
+// validateCommandTemplates checks every template and that no two share a
+// name.
+func (c *Config) validateCommandTemplates() error {
+	names := make(map[string]bool, len(c.CommandTemplates))
+	for i, t := range c.CommandTemplates {
+		if t.Name == "" {
+			return fmt.Errorf("command template %d: name is required", i+1)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("command template %q is defined more than once", t.Name)
+		}
+		names[t.Name] = true
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("command template %q: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+func (t CommandTemplate) validate() error {
+	switch {
+	case !TemplateNamePattern.MatchString(t.Name):
+		return fmt.Errorf("name must be lowercase letters, digits and underscores, starting with a letter")
+	case strings.HasPrefix(t.Name, "ssh_"):
+		return fmt.Errorf("names starting with ssh_ are reserved for built-in tools")
+	case t.Description == "":
+		return fmt.Errorf("description is required")
+	case (strings.TrimSpace(t.Command) == "") == (strings.TrimSpace(t.Script) == ""):
+		return fmt.Errorf("exactly one of command and script is required")
+	case t.Interpreter != "" && t.Script == "":
+		return fmt.Errorf("interpreter needs script")
+	case t.Interpreter != "" && !slices.Contains(scriptInterpreters, strings.ToLower(t.Interpreter)):
+		return fmt.Errorf("unsupported interpreter %q (must be bash, sh, python, or powershell)", t.Interpreter)
+	case t.Timeout < 0 || t.Timeout > MaxTemplateTimeout:
+		return fmt.Errorf("timeout must be between 0 and %d seconds", MaxTemplateTimeout)
+	}
+	for _, h := range t.Hosts {
+		if err := checkHostPattern(h); err != nil {
+			return err
+		}
+	}
+	params := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		if params[p.Name] {
+			return fmt.Errorf("parameter %q is defined more than once", p.Name)
+		}
+		params[p.Name] = true
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+	}
+	// Scripts get the parameters as arguments; {{...}} in them is left alone.
+	for _, m := range TemplatePlaceholder.FindAllStringSubmatch(t.Command, -1) {
+		if !params[m[1]] {
+			return fmt.Errorf("command uses undefined parameter %q", m[1])
+		}
+	}
+	return nil
+}
+
+// checkHostPattern checks a host pattern the way the host filter compiles
+// it: a CIDR, or else a case-insensitive regex.
+func checkHostPattern(p string) error {
+	if _, _, err := net.ParseCIDR(p); err == nil && strings.Contains(p, "/") {
+		return nil
+	}
+	if _, err := regexp.Compile("(?i)" + p); err != nil {
+		return fmt.Errorf("invalid host pattern %q: %w", p, err)
+	}
+	return nil
+}
+
+func (p TemplateParam) validate() error {
+	switch {
+	case !templateParamPattern.MatchString(p.Name):
+		return fmt.Errorf("name must be lowercase letters, digits and underscores, starting with a letter")
+	case p.Name == "session_id":
+		return fmt.Errorf("session_id is reserved")
+	}
+	switch p.ParamType() {
+	case ParamString:
+		if p.Min != nil || p.Max != nil || p.Flag != "" {
+			return fmt.Errorf("min, max and flag don't apply to strings")
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern: %w", err)
+			}
+		}
+		if p.Default != nil {
+			s, ok := p.Default.(string)
+			if !ok {
+				return fmt.Errorf("default must be a string")
+			}
+			if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+				return fmt.Errorf("default %q is not in enum", s)
+			}
+		}
+	case ParamInteger:
+		if p.Pattern != "" || len(p.Enum) > 0 || p.Flag != "" {
+			return fmt.Errorf("pattern, enum and flag don't apply to integers")
+		}
+		if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+			return fmt.Errorf("min is greater than max")
+		}
+		if p.Default != nil {
+			f, ok := p.Default.(float64)
+			if !ok || f != math.Trunc(f) {
+				return fmt.Errorf("default must be an integer")
+			}
+		}
+	case ParamBoolean:
+		if p.Pattern != "" || len(p.Enum) > 0 || p.Min != nil || p.Max != nil {
+			return fmt.Errorf("pattern, enum, min and max don't apply to booleans")
+		}
+		if _, ok := p.Default.(bool); p.Default != nil && !ok {
+			return fmt.Errorf("default must be true or false")
+		}
+	default:
+		return fmt.Errorf("invalid type %q (must be %s, %s or %s)", p.Type, ParamString, ParamInteger, ParamBoolean)
+	}
+	return nil
+}
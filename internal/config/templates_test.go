@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildConfig_CommandTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	data := `{"templates": [
+		{"name": "restart_service", "description": "Restart a systemd unit.", "command": "systemctl restart {{name}}",
+		 "sudo": true, "params": [{"name": "name", "pattern": "[a-z0-9@._-]+"}]},
+		{"name": "tail_log", "description": "Show the end of a log.", "command": "tail -n {{ lines }} {{follow}} /var/log/{{file}}",
+		 "params": [
+			{"name": "file", "enum": ["syslog", "auth.log"]},
+			{"name": "lines", "type": "integer", "min": 1, "max": 1000, "default": 100},
+			{"name": "follow", "type": "boolean", "flag": "-F", "default": false}
+		 ]}
+	], "tools": [
+		{"name": "rotate_logs", "description": "Rotate logs.", "script": "logrotate -f \"$1\"", "interpreter": "sh",
+		 "session_id": "root@web1:22", "hosts": ["web\\d+", "10.0.0.0/8"],
+		 "annotations": {"title": "Rotate logs", "idempotent": true}, "params": [{"name": "config"}]}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	args := Args{HTTPPort: 8081, CommandTimeout: 60 * time.Second, RateLimit: 60, CommandTemplates: path}
+	cfg, err := buildConfig(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.CommandTemplates) != 3 || !cfg.CommandTemplates[0].Sudo || len(cfg.CommandTemplates[1].Params) != 3 {
+		t.Fatalf("templates = %+v", cfg.CommandTemplates)
+	}
+	if tool := cfg.CommandTemplates[2]; tool.Name != "rotate_logs" || tool.SessionID != "root@web1:22" || len(tool.Hosts) != 2 || !tool.Annotations.Idempotent {
+		t.Errorf("tool = %+v", tool)
+	}
+	if p := cfg.CommandTemplates[1].Params[1]; p.ParamType() != ParamInteger || *p.Max != 1000 || p.Default != 100.0 {
+		t.Errorf("lines param = %+v", p)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	for name, mutate := range map[string]func(*Config){
+		"empty name":          func(c *Config) { c.CommandTemplates[0].Name = "" },
+		"duplicate name":      func(c *Config) { c.CommandTemplates[1].Name = "restart_service" },
+		"bad name":            func(c *Config) { c.CommandTemplates[0].Name = "Restart-Service" },
+		"ssh_ prefix":         func(c *Config) { c.CommandTemplates[0].Name = "ssh_restart" },
+		"no description":      func(c *Config) { c.CommandTemplates[0].Description = "" },
+		"no command":          func(c *Config) { c.CommandTemplates[0].Command = " " },
+		"bad timeout":         func(c *Config) { c.CommandTemplates[0].Timeout = -1 },
+		"undefined parameter": func(c *Config) { c.CommandTemplates[0].Command = "systemctl {{action}} {{name}}" },
+		"session_id param":    func(c *Config) { c.CommandTemplates[0].Params[0].Name = "session_id" },
+		"duplicate param":     func(c *Config) { c.CommandTemplates[1].Params[1].Name = "file" },
+		"bad type":            func(c *Config) { c.CommandTemplates[0].Params[0].Type = "float" },
+		"bad pattern":         func(c *Config) { c.CommandTemplates[0].Params[0].Pattern = "[a-z" },
+		"enum default":        func(c *Config) { c.CommandTemplates[1].Params[0].Default = "kern.log" },
+		"integer default":     func(c *Config) { c.CommandTemplates[1].Params[1].Default = 1.5 },
+		"integer enum":        func(c *Config) { c.CommandTemplates[1].Params[1].Enum = []string{"1"} },
+		"min above max":       func(c *Config) { *c.CommandTemplates[1].Params[1].Min = 2000 },
+		"boolean default":     func(c *Config) { c.CommandTemplates[1].Params[2].Default = "yes" },
+		"string flag":         func(c *Config) { c.CommandTemplates[0].Params[0].Flag = "-n" },
+		"command and script":  func(c *Config) { c.CommandTemplates[2].Command = "logrotate" },
+		"neither":             func(c *Config) { c.CommandTemplates[2].Script = "" },
+		"command interpreter": func(c *Config) { c.CommandTemplates[0].Interpreter = "bash" },
+		"bad interpreter":     func(c *Config) { c.CommandTemplates[2].Interpreter = "ruby" },
+		"bad host pattern":    func(c *Config) { c.CommandTemplates[2].Hosts = []string{"web("} },
+	} {
+		c, _ := buildConfig(args)
+		mutate(c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	os.WriteFile(path, []byte(`{"templates": [{"name": "x", "description": "y", "command": "z", "sudo_password": "p"}]}`), 0o600)
+	if _, err := buildConfig(args); err == nil {
+		t.Error("expected error for unknown template field")
+	}
+	os.WriteFile(path, []byte(`{"templates": []}`), 0o600)
+	if _, err := buildConfig(args); err == nil {
+		t.Error("expected error for empty templates file")
+	}
+}
@@ -0,0 +1,31 @@
+package connection
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ForwardAgent lets sessions on client that request agent forwarding
+// (agent.RequestAgentForwarding) reach the local ssh-agent at SSH_AUTH_SOCK.
+// Every channel the host opens gets its own connection to the agent. The
+// handler can only be registered once per client, so it is remembered and
+// registered again only after a reconnect replaced the client.
+func (c *Connection) ForwardAgent(client *ssh.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.agentClient == client {
+		return nil
+	}
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return fmt.Errorf("agent forwarding needs a local ssh-agent: SSH_AUTH_SOCK is not set")
+	}
+	if err := agent.ForwardToRemote(client, socket); err != nil {
+		return fmt.Errorf("forward ssh-agent: %w", err)
+	}
+	c.agentClient = client
+	return nil
+}
@@ -0,0 +1,147 @@
+package connection
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startAgentCountServer accepts SSH connections without authentication. An
+// exec on a session that requested agent forwarding opens an agent channel
+// back to the client and exits with the number of keys the agent lists; any
+// other exec exits with 255.
+func startAgentCountServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{NoClientAuth: true}
+	srvCfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(nc, srvCfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nch := range chans {
+					ch, chReqs, err := nch.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						defer ch.Close()
+						forwarded := false
+						for req := range chReqs {
+							switch req.Type {
+							case "auth-agent-req@openssh.com":
+								forwarded = true
+								req.Reply(true, nil)
+							case "exec":
+								req.Reply(true, nil)
+								status := uint32(255)
+								if forwarded {
+									if status, err = countAgentKeys(sconn); err != nil {
+										status = 254
+									}
+								}
+								ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+								return
+							default:
+								req.Reply(false, nil)
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// countAgentKeys opens an agent channel to the client and counts its keys.
+func countAgentKeys(sconn *ssh.ServerConn) (uint32, error) {
+	ch, reqs, err := sconn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+	keys, err := agent.NewClient(ch).List()
+	return uint32(len(keys)), err
+}
+
+func TestConnection_ForwardAgent(t *testing.T) {
+	_, k1, _ := ed25519.GenerateKey(rand.Reader)
+	_, k2, _ := ed25519.GenerateKey(rand.Reader)
+	serveAgent(t, k1, k2)
+	addr := startAgentCountServer(t)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	run := func(forward bool) int {
+		t.Helper()
+		session, err := client.NewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer session.Close()
+		if forward {
+			if err := agent.RequestAgentForwarding(session); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = session.Run("true")
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			return exitErr.ExitStatus()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return 0
+	}
+
+	c := &Connection{}
+	for range 2 { // the second call must not try to register the handler again
+		if err := c.ForwardAgent(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := run(true); got != 2 {
+		t.Errorf("keys seen through the forwarded agent = %d, want 2", got)
+	}
+	if got := run(false); got != 255 {
+		t.Errorf("session without forwarding exited %d, want 255", got)
+	}
+}
+
+func TestConnection_ForwardAgentNoSocket(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	c := &Connection{}
+	if err := c.ForwardAgent(&ssh.Client{}); err == nil {
+		t.Error("ForwardAgent succeeded without SSH_AUTH_SOCK")
+	}
+}
@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"log"
+	"regexp"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// strictAlgorithms is the --ssh-algorithms strict set: authenticated
+// encryption, encrypt-then-MAC, and no SHA-1 or ssh-rsa anywhere.
+var strictAlgorithms = ssh.Algorithms{
+	Ciphers: []string{ssh.CipherAES128GCM, ssh.CipherAES256GCM, ssh.CipherChaCha20Poly1305},
+	MACs:    []string{ssh.HMACSHA256ETM, ssh.HMACSHA512ETM},
+	KeyExchanges: []string{
+		ssh.KeyExchangeMLKEM768X25519, ssh.KeyExchangeCurve25519,
+		ssh.KeyExchangeECDHP256, ssh.KeyExchangeECDHP384, ssh.KeyExchangeECDHP521,
+		ssh.KeyExchangeDH16SHA512,
+	},
+	HostKeys: []string{
+		ssh.CertAlgoED25519v01, ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+		ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSASHA512v01,
+		ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+		ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512,
+	},
+}
+
+// legacyAlgorithms is the --ssh-algorithms legacy set: everything the ssh
+// package implements, secure algorithms first so modern servers still
+// negotiate them.
+func legacyAlgorithms() ssh.Algorithms {
+	s, i := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+	return ssh.Algorithms{
+		Ciphers:      append(s.Ciphers, i.Ciphers...),
+		MACs:         append(s.MACs, i.MACs...),
+		KeyExchanges: append(s.KeyExchanges, i.KeyExchanges...),
+		HostKeys:     append(s.HostKeys, i.HostKeys...),
+	}
+}
+
+// algorithmRule is a compiled --host-algorithms entry.
+type algorithmRule struct {
+	re     *regexp.Regexp
+	policy config.AlgorithmPolicy
+}
+
+func compileAlgorithmRules(hosts []config.HostAlgorithms) []algorithmRule {
+	var rules []algorithmRule
+	for _, h := range hosts {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host algorithms pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		rules = append(rules, algorithmRule{re: re, policy: h.Policy})
+	}
+	return rules
+}
+
+// algorithmsFor returns the policy for host: the first matching
+// --host-algorithms rule, or the global policy. A rule without a mode
+// keeps the global mode and the global lists it doesn't replace.
+func (a *AuthDiscovery) algorithmsFor(host string) config.AlgorithmPolicy {
+	global := a.cfg.Algorithms
+	for _, r := range a.algoRules {
+		if !r.re.MatchString(host) {
+			continue
+		}
+		p := r.policy
+		if p.Mode != "" {
+			return p
+		}
+		p.Mode = global.Mode
+		p.Ciphers = listOr(p.Ciphers, global.Ciphers)
+		p.MACs = listOr(p.MACs, global.MACs)
+		p.KeyExchanges = listOr(p.KeyExchanges, global.KeyExchanges)
+		p.HostKeyAlgorithms = listOr(p.HostKeyAlgorithms, global.HostKeyAlgorithms)
+		return p
+	}
+	return global
+}
+
+// applyAlgorithms sets the algorithm lists of cfg from p. Lists left nil
+// use the ssh package defaults.
+func applyAlgorithms(cfg *ssh.ClientConfig, p config.AlgorithmPolicy) {
+	var base ssh.Algorithms
+	switch p.Mode {
+	case config.AlgorithmsLegacy:
+		base = legacyAlgorithms()
+	case config.AlgorithmsStrict:
+		base = strictAlgorithms
+	}
+	cfg.Ciphers = slices.Clone(listOr(p.Ciphers, base.Ciphers))
+	cfg.MACs = slices.Clone(listOr(p.MACs, base.MACs))
+	cfg.KeyExchanges = slices.Clone(listOr(p.KeyExchanges, base.KeyExchanges))
+	cfg.HostKeyAlgorithms = slices.Clone(listOr(p.HostKeyAlgorithms, base.HostKeys))
+}
+
+// listOr returns list, or fallback when list is empty.
+func listOr(list, fallback []string) []string {
+	if len(list) > 0 {
+		return list
+	}
+	return fallback
+}
@@ -0,0 +1,61 @@
+package connection
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestApplyAlgorithms(t *testing.T) {
+	var cfg ssh.ClientConfig
+	applyAlgorithms(&cfg, config.AlgorithmPolicy{Mode: config.AlgorithmsDefault})
+	if cfg.Ciphers != nil || cfg.KeyExchanges != nil || cfg.HostKeyAlgorithms != nil {
+		t.Errorf("default mode set lists: %+v", cfg.Config)
+	}
+
+	applyAlgorithms(&cfg, config.AlgorithmPolicy{Mode: config.AlgorithmsLegacy})
+	if !slices.Contains(cfg.KeyExchanges, ssh.InsecureKeyExchangeDH14SHA1) || !slices.Contains(cfg.HostKeyAlgorithms, ssh.KeyAlgoRSA) ||
+		!slices.Contains(cfg.Ciphers, ssh.InsecureCipherAES128CBC) {
+		t.Errorf("legacy mode lacks old algorithms: kex=%v hostkeys=%v ciphers=%v", cfg.KeyExchanges, cfg.HostKeyAlgorithms, cfg.Ciphers)
+	}
+	if cfg.Ciphers[0] != ssh.SupportedAlgorithms().Ciphers[0] {
+		t.Errorf("legacy mode should prefer secure ciphers, got %v first", cfg.Ciphers[0])
+	}
+
+	applyAlgorithms(&cfg, config.AlgorithmPolicy{Mode: config.AlgorithmsStrict, MACs: []string{ssh.HMACSHA512ETM}})
+	for _, insecure := range []string{ssh.InsecureKeyExchangeDH14SHA1, ssh.KeyAlgoRSA, ssh.CipherAES128CTR, ssh.HMACSHA1} {
+		if slices.Contains(cfg.KeyExchanges, insecure) || slices.Contains(cfg.HostKeyAlgorithms, insecure) ||
+			slices.Contains(cfg.Ciphers, insecure) || slices.Contains(cfg.MACs, insecure) {
+			t.Errorf("strict mode offers %s", insecure)
+		}
+	}
+	if !slices.Equal(cfg.MACs, []string{ssh.HMACSHA512ETM}) {
+		t.Errorf("explicit MACs not applied: %v", cfg.MACs)
+	}
+}
+
+func TestAlgorithmsFor(t *testing.T) {
+	auth := NewAuthDiscovery(&config.SSHConfig{
+		ConnectionTimeout: 30 * time.Second,
+		Algorithms:        config.AlgorithmPolicy{Mode: config.AlgorithmsStrict, Ciphers: []string{ssh.CipherAES256GCM}},
+		HostAlgorithms: []config.HostAlgorithms{
+			{Pattern: "switch-.*", Policy: config.AlgorithmPolicy{Mode: config.AlgorithmsLegacy}},
+			{Pattern: "db1", Policy: config.AlgorithmPolicy{MACs: []string{ssh.HMACSHA256ETM}}},
+		},
+	})
+
+	if p := auth.algorithmsFor("SWITCH-3"); p.Mode != config.AlgorithmsLegacy || p.Ciphers != nil {
+		t.Errorf("switch-3 = %+v, want legacy without the global ciphers", p)
+	}
+	p := auth.algorithmsFor("db1")
+	if p.Mode != config.AlgorithmsStrict || !slices.Equal(p.Ciphers, []string{ssh.CipherAES256GCM}) || !slices.Equal(p.MACs, []string{ssh.HMACSHA256ETM}) {
+		t.Errorf("db1 = %+v, want global strict with its own MACs", p)
+	}
+	if p := auth.algorithmsFor("web"); p.Mode != config.AlgorithmsStrict {
+		t.Errorf("web = %+v, want the global policy", p)
+	}
+}
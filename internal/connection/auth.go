@@ -9,10 +9,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/kevinburke/ssh_config"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
 
 	"github.com/n0madic/ssh-mcp/internal/config"
@@ -26,6 +27,12 @@ type ConnectParams struct {
 	Password     string
 	KeyPath      string
 	UseSSHConfig bool
+	ProxyCommand string                 // OpenSSH-style ProxyCommand from ssh_config ("" = dial directly)
+	Addresses    []net.IP               // checked addresses of Host to dial instead of resolving it again (nil = resolve)
+	ViaSession   SessionID              // tunnel through this existing session of the same owner ("" = dial from here)
+	IdleTimeout  time.Duration          // 0 = use the configured per-host or global idle timeout
+	MaxLifetime  time.Duration          // 0 = use the global max session lifetime; can only shorten it
+	Defaults     config.SessionDefaults // ssh_execute defaults, merged over those of the matching --host-defaults rule
 }
 
 // ResolvedHost holds resolved SSH connection details from ssh_config.
@@ -34,20 +41,46 @@ type ResolvedHost struct {
 	Port         int
 	User         string
 	IdentityFile string
+	ProxyCommand string
 }
 
 // AuthDiscovery handles SSH authentication method discovery.
 type AuthDiscovery struct {
-	cfg *config.SSHConfig
+	cfg        *config.SSHConfig
+	vault      *VaultClient // nil unless per-host Vault credentials are configured
+	vaultRules []vaultRule
+	algoRules  []algorithmRule
+	keyChanged func(host string, key ssh.PublicKey)
+	knownMu    sync.Mutex // serializes AddKnownHost
 }
 
 // NewAuthDiscovery creates a new AuthDiscovery.
 func NewAuthDiscovery(cfg *config.SSHConfig) *AuthDiscovery {
-	return &AuthDiscovery{cfg: cfg}
+	a := &AuthDiscovery{cfg: cfg, algoRules: compileAlgorithmRules(cfg.HostAlgorithms)}
+	if len(cfg.Vault.Hosts) > 0 {
+		a.vault = NewVaultClient(cfg.Vault.Addr, cfg.Vault.Token, cfg.ConnectionTimeout)
+		a.vaultRules = compileVaultRules(cfg.Vault.Hosts)
+	}
+	return a
 }
 
-// ResolveHost resolves an SSH alias from ssh_config to actual connection details.
+// ResolveHost resolves an SSH alias from ssh_config to actual connection
+// details, then maps the host name through --host-map.
 func (a *AuthDiscovery) ResolveHost(alias string) *ResolvedHost {
+	resolved := a.resolveSSHConfig(alias)
+	for _, m := range a.cfg.HostMap {
+		if strings.EqualFold(m.Alias, resolved.HostName) {
+			resolved.HostName = m.Address
+			if m.Port != 0 {
+				resolved.Port = m.Port
+			}
+			break
+		}
+	}
+	return resolved
+}
+
+func (a *AuthDiscovery) resolveSSHConfig(alias string) *ResolvedHost {
 	resolved := &ResolvedHost{
 		HostName: alias,
 		Port:     22,
@@ -78,12 +111,46 @@ func (a *AuthDiscovery) ResolveHost(alias string) *ResolvedHost {
 	if identityFile, err := sshCfg.Get(alias, "IdentityFile"); err == nil && identityFile != "" {
 		resolved.IdentityFile = expandPath(identityFile)
 	}
+	if proxyCommand, err := sshCfg.Get(alias, "ProxyCommand"); err == nil && proxyCommand != "" && !strings.EqualFold(proxyCommand, "none") {
+		resolved.ProxyCommand = proxyCommand
+	}
 
 	return resolved
 }
 
+// ConfigHosts returns the host aliases defined in ssh_config, in file order,
+// skipping wildcard and negated patterns that cannot be connected to.
+func (a *AuthDiscovery) ConfigHosts() []string {
+	f, err := os.Open(a.cfg.ConfigPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	sshCfg, err := ssh_config.Decode(f)
+	if err != nil {
+		return nil
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, h := range sshCfg.Hosts {
+		for _, p := range h.Patterns {
+			alias := p.String()
+			// String drops the "!" of negated patterns, which Matches rejects.
+			if alias == "" || strings.ContainsAny(alias, "*?") || seen[alias] || !h.Matches(alias) {
+				continue
+			}
+			seen[alias] = true
+			hosts = append(hosts, alias)
+		}
+	}
+	return hosts
+}
+
 // BuildAuthMethods constructs SSH authentication methods from the given parameters.
-// Explicit key is tried first, then ssh-agent, then default key files (only when no agent).
+// Explicit key is tried first, then Vault credentials for the host, then ssh-agent,
+// then default key files (only when no agent).
 func (a *AuthDiscovery) BuildAuthMethods(params ConnectParams) []ssh.AuthMethod {
 	var methods []ssh.AuthMethod
 	// Try explicit key path first.
@@ -93,6 +160,9 @@ func (a *AuthDiscovery) BuildAuthMethods(params ConnectParams) []ssh.AuthMethod
 		}
 	}
 
+	// Host-specific Vault credentials take precedence over generic keys.
+	methods = append(methods, a.vaultAuthMethods(params)...)
+
 	// Try ssh-agent next (handles passphrase-protected keys loaded into agent).
 	agentAvailable := false
 	if method := a.agentAuth(); method != nil {
@@ -129,12 +199,14 @@ func (a *AuthDiscovery) BuildClientConfig(params ConnectParams) (*ssh.ClientConf
 		return nil, fmt.Errorf("host key callback: %w", err)
 	}
 
-	return &ssh.ClientConfig{
+	clientConfig := &ssh.ClientConfig{
 		User:            params.User,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         a.cfg.ConnectionTimeout,
-	}, nil
+	}
+	applyAlgorithms(clientConfig, a.algorithmsFor(params.Host))
+	return clientConfig, nil
 }
 
 // ParseHostString parses "user:password@host:port" format into ConnectParams.
@@ -169,16 +241,14 @@ func ParseHostString(s string) ConnectParams {
 }
 
 func (a *AuthDiscovery) agentAuth() ssh.AuthMethod {
-	socket := os.Getenv("SSH_AUTH_SOCK")
-	if socket == "" {
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
 		return nil
 	}
-	conn, err := net.Dial("unix", socket)
+	agentClient, conn, err := dialAgent()
 	if err != nil {
 		log.Printf("SSH agent connection failed: %v", err)
 		return nil
 	}
-	agentClient := agent.NewClient(conn)
 
 	// Verify agent is reachable by listing keys.
 	if _, err := agentClient.List(); err != nil {
@@ -198,6 +268,10 @@ func (a *AuthDiscovery) loadKeyAuth(keyPath string) ssh.AuthMethod {
 
 	signer, err := ssh.ParsePrivateKey(keyData)
 	if err != nil {
+		// Security keys can't sign here; use the agent's copy.
+		if pub, perr := privateKeyPublic(keyData); perr == nil && isSecurityKey(pub) {
+			return a.securityKeyAuth(keyPath, pub)
+		}
 		var missingErr *ssh.PassphraseMissingError
 		if errors.As(err, &missingErr) {
 			log.Printf("SSH key %s is passphrase-protected (not supported)", keyPath)
@@ -213,17 +287,38 @@ func (a *AuthDiscovery) buildHostKeyCallback() (ssh.HostKeyCallback, error) {
 		return ssh.InsecureIgnoreHostKey(), nil
 	}
 
+	var callback ssh.HostKeyCallback
 	if _, err := os.Stat(a.cfg.KnownHostsPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("host key verification is enabled but known_hosts file %q does not exist; "+
-			"use --no-verify-host-key to disable verification or create the file with ssh-keyscan", a.cfg.KnownHostsPath)
-	}
-
-	callback, err := knownhosts.New(a.cfg.KnownHostsPath)
-	if err != nil {
+		if a.cfg.HostKeyPrompt == "" || a.cfg.HostKeyPrompt == config.HostKeyPromptOff {
+			return nil, fmt.Errorf("host key verification is enabled but known_hosts file %q does not exist; "+
+				"use --no-verify-host-key to disable verification or create the file with ssh-keyscan", a.cfg.KnownHostsPath)
+		}
+		// Every host is unknown until the user trusts one and the file
+		// is created.
+		callback = func(string, net.Addr, ssh.PublicKey) error { return &knownhosts.KeyError{} }
+	} else if callback, err = knownhosts.New(a.cfg.KnownHostsPath); err != nil {
 		return nil, fmt.Errorf("failed to parse known_hosts %s: %w", a.cfg.KnownHostsPath, err)
 	}
 
-	return callback, nil
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		// Want lists the known keys; empty means the host is just unknown.
+		if len(keyErr.Want) > 0 && a.keyChanged != nil {
+			a.keyChanged(hostname, key)
+		}
+		return newHostKeyError(hostname, key, keyErr)
+	}, nil
+}
+
+// OnHostKeyChanged registers f to be called when a host presents a key
+// that differs from the one in known_hosts. It must be set before
+// connections are made.
+func (a *AuthDiscovery) OnHostKeyChanged(f func(host string, key ssh.PublicKey)) {
+	a.keyChanged = f
 }
 
 func expandPath(path string) string {
@@ -1,10 +1,18 @@
 package connection
 
 import (
+	"crypto/ed25519"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
 	"github.com/n0madic/ssh-mcp/internal/config"
 )
 
@@ -169,6 +177,26 @@ func TestAuthDiscovery_ResolveHost_NoConfig(t *testing.T) {
 	}
 }
 
+func TestAuthDiscovery_ConfigHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := "Host web1 web2\n  User deploy\nHost *.internal !bad\n  Port 2222\nHost db web1\n  HostName 10.0.0.5\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthDiscovery(&config.SSHConfig{ConfigPath: path})
+
+	got := auth.ConfigHosts()
+	want := []string{"web1", "web2", "db"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ConfigHosts = %v, want %v", got, want)
+	}
+
+	missing := NewAuthDiscovery(&config.SSHConfig{ConfigPath: "/nonexistent/ssh/config"})
+	if hosts := missing.ConfigHosts(); len(hosts) != 0 {
+		t.Errorf("ConfigHosts without config = %v", hosts)
+	}
+}
+
 func TestBuildHostKeyCallback_MissingKnownHosts(t *testing.T) {
 	cfg := &config.SSHConfig{
 		KnownHostsPath:    "/nonexistent/known_hosts",
@@ -189,3 +217,80 @@ func TestBuildHostKeyCallback_MissingKnownHosts(t *testing.T) {
 		t.Errorf("expected error message to mention known_hosts, got: %v", err)
 	}
 }
+
+func TestBuildHostKeyCallback_KeyChanged(t *testing.T) {
+	newKey := func() ssh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	known, other := newKey(), newKey()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize("web1:22")}, known)
+	if err := os.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewAuthDiscovery(&config.SSHConfig{KnownHostsPath: path, VerifyHostKey: true})
+	var changed []string
+	auth.OnHostKeyChanged(func(host string, key ssh.PublicKey) {
+		changed = append(changed, host)
+	})
+	cb, err := auth.buildHostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+
+	if err := cb("web1:22", addr, known); err != nil {
+		t.Errorf("known key: %v", err)
+	}
+	var keyErr *HostKeyError
+	if err := cb("web2:22", addr, other); !errors.As(err, &keyErr) || keyErr.Changed() {
+		t.Errorf("unknown host: got %v, want an unchanged HostKeyError", err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("hook called for a known key or unknown host: %v", changed)
+	}
+	if err := cb("web1:22", addr, other); !errors.As(err, &keyErr) || !keyErr.Changed() {
+		t.Errorf("changed key: got %v, want a changed HostKeyError", err)
+	} else if keyErr.Fingerprint != ssh.FingerprintSHA256(other) || keyErr.Known[0].Fingerprint != ssh.FingerprintSHA256(known) || keyErr.Known[0].Line != 1 {
+		t.Errorf("changed key: %+v", keyErr)
+	}
+	if len(changed) != 1 || changed[0] != "web1:22" {
+		t.Errorf("hook calls = %v, want [web1:22]", changed)
+	}
+}
+
+func TestAuthDiscovery_ResolveHost_HostMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("Host sw\n  HostName lab-sw1\n  User admin\n  Port 2200\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthDiscovery(&config.SSHConfig{
+		ConfigPath: path,
+		HostMap: []config.HostMapping{
+			{Alias: "lab-sw1", Address: "10.0.0.5"},
+			{Alias: "lab-sw2", Address: "10.0.0.6", Port: 2222},
+		},
+	})
+
+	// ssh_config HostName is mapped; its Port stays without a mapped port.
+	r := auth.ResolveHost("sw")
+	if r.HostName != "10.0.0.5" || r.Port != 2200 || r.User != "admin" {
+		t.Errorf("ResolveHost(sw) = %+v", r)
+	}
+	r = auth.ResolveHost("LAB-SW2")
+	if r.HostName != "10.0.0.6" || r.Port != 2222 {
+		t.Errorf("ResolveHost(LAB-SW2) = %+v", r)
+	}
+	if r := auth.ResolveHost("other"); r.HostName != "other" || r.Port != 22 {
+		t.Errorf("ResolveHost(other) = %+v", r)
+	}
+}
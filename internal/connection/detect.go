@@ -3,7 +3,9 @@ package connection
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,17 +17,37 @@ type RemoteInfo struct {
 	OS                 string // "Linux", "Darwin", "FreeBSD", "Windows"
 	Arch               string // "x86_64", "aarch64", "arm64", "AMD64"
 	Shell              string // "/bin/bash", "/bin/zsh", "C:\Windows\system32\cmd.exe"
-	PackageManager     string // "apt", "dnf", "yum", "apk", "pacman", "brew", or ""
+	PackageManager     string // "apt", "dnf", "yum", "zypper", "apk", "pacman", "brew", "pkg", or ""
 	SudoNoninteractive bool   // true if `sudo -n true` succeeds (passwordless sudo available)
+
+	// From /etc/os-release (sw_vers on macOS); empty if neither exists.
+	Distro        string // ID: "ubuntu", "debian", "alpine", "rhel", "macos"
+	DistroLike    string // ID_LIKE, space-separated: "debian", "rhel fedora"
+	DistroVersion string // VERSION_ID: "22.04", "3.19", "14.4"
+	DistroName    string // PRETTY_NAME: "Ubuntu 22.04.4 LTS"
+
+	InitSystem string // "systemd", "openrc", "runit", "launchd", "sysvinit", "rc", or ""
+	Sudo       bool   // sudo is installed
+	Doas       bool   // doas is installed
 }
 
 const detectTimeout = 5 * time.Second
 
-// posixProbeCommand collects OS, arch, shell, package manager, and sudo-noninteractive
-// status on POSIX hosts. Always produces 5 lines; lines 4 and 5 may be empty / "no".
+// initSystems are the init systems posixProbeCommand reports.
+var initSystems = []string{"systemd", "openrc", "runit", "launchd", "sysvinit", "rc"}
+
+// posixProbeCommand collects OS, arch, shell, package manager, sudo-noninteractive
+// status, init system, installed privilege tools and os-release on POSIX hosts.
+// Lines 1-7 are always printed (4, 6 and 7 may be empty); os-release follows.
 const posixProbeCommand = `uname -s; uname -m; echo "$SHELL"; ` +
-	`pm=""; for c in apt dnf yum apk pacman brew; do command -v "$c" >/dev/null 2>&1 && { pm="$c"; break; }; done; echo "$pm"; ` +
-	`if command -v sudo >/dev/null 2>&1 && sudo -n true >/dev/null 2>&1; then echo yes; else echo no; fi`
+	`pm=""; for c in apt dnf yum zypper apk pacman brew pkg; do command -v "$c" >/dev/null 2>&1 && { pm="$c"; break; }; done; echo "$pm"; ` +
+	`if command -v sudo >/dev/null 2>&1 && sudo -n true >/dev/null 2>&1; then echo yes; else echo no; fi; ` +
+	`if [ -d /run/systemd/system ]; then echo systemd; elif [ -d /run/openrc ]; then echo openrc; ` +
+	`elif [ -d /run/runit ] || [ -d /etc/runit/runsvdir ]; then echo runit; elif command -v launchctl >/dev/null 2>&1; then echo launchd; ` +
+	`elif [ -f /etc/inittab ]; then echo sysvinit; elif [ -f /etc/rc ]; then echo rc; else echo; fi; ` +
+	`for c in sudo doas; do command -v "$c" >/dev/null 2>&1 && printf '%s ' "$c"; done; echo; ` +
+	`if [ -r /etc/os-release ]; then cat /etc/os-release; elif command -v sw_vers >/dev/null 2>&1; then ` +
+	`v=$(sw_vers -productVersion); echo ID=macos; echo "VERSION_ID=$v"; echo "PRETTY_NAME=\"macOS $v\""; fi`
 
 // detectRemoteInfo runs lightweight probe commands to detect the remote OS,
 // architecture, and shell. Best-effort: failures are logged but never block
@@ -59,6 +81,24 @@ func detectRemoteInfo(ctx context.Context, client *ssh.Client) RemoteInfo {
 	return RemoteInfo{}
 }
 
+// RefreshRemoteInfo probes the host again and replaces the cached
+// information, for example after a package manager was installed or the
+// host was upgraded. The cached info is kept if the probe fails.
+func (c *Connection) RefreshRemoteInfo(ctx context.Context) (RemoteInfo, error) {
+	client, err := c.GetClient()
+	if err != nil {
+		return RemoteInfo{}, err
+	}
+	info := detectRemoteInfo(ctx, client)
+	if info.OS == "" {
+		return c.GetRemoteInfo(), fmt.Errorf("could not detect the remote OS")
+	}
+	c.mu.Lock()
+	c.RemoteInfo = info
+	c.mu.Unlock()
+	return info, nil
+}
+
 // runProbeCommand executes a command on the SSH client and returns trimmed output.
 func runProbeCommand(ctx context.Context, client *ssh.Client, command string) (string, error) {
 	session, err := client.NewSession()
@@ -87,9 +127,9 @@ func runProbeCommand(ctx context.Context, client *ssh.Client, command string) (s
 	}
 }
 
-// parseDetectionOutput parses POSIX probe output (5 lines: OS, arch, shell,
-// package manager, sudo-n). Earlier 3-line outputs remain compatible: trailing
-// fields stay empty / false.
+// parseDetectionOutput parses POSIX probe output (OS, arch, shell, package
+// manager, sudo-n, init system, privilege tools, then os-release lines).
+// Shorter outputs remain compatible: trailing fields stay empty / false.
 func parseDetectionOutput(output string) RemoteInfo {
 	lines := strings.Split(output, "\n")
 	var info RemoteInfo
@@ -109,10 +149,69 @@ func parseDetectionOutput(output string) RemoteInfo {
 	if len(lines) >= 5 {
 		info.SudoNoninteractive = strings.TrimSpace(lines[4]) == "yes"
 	}
+	if len(lines) >= 6 {
+		if init := strings.TrimSpace(lines[5]); slices.Contains(initSystems, init) {
+			info.InitSystem = init
+		}
+	}
+	if len(lines) >= 7 {
+		for _, tool := range strings.Fields(lines[6]) {
+			switch tool {
+			case "sudo":
+				info.Sudo = true
+			case "doas":
+				info.Doas = true
+			}
+		}
+	}
+	if len(lines) >= 8 {
+		parseOSRelease(&info, lines[7:])
+	}
 
 	return info
 }
 
+// parseOSRelease fills the distro fields of info from os-release(5) lines.
+func parseOSRelease(info *RemoteInfo, lines []string) {
+	for _, line := range lines {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		value = unquoteOSRelease(value)
+		switch key {
+		case "ID":
+			info.Distro = value
+		case "ID_LIKE":
+			info.DistroLike = value
+		case "VERSION_ID":
+			info.DistroVersion = value
+		case "PRETTY_NAME":
+			info.DistroName = value
+		}
+	}
+}
+
+// unquoteOSRelease strips the shell-style quotes of an os-release value and
+// undoes backslash escapes inside double quotes.
+func unquoteOSRelease(v string) string {
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return v[1 : len(v)-1]
+	}
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return v
+	}
+	v = v[1 : len(v)-1]
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) && strings.IndexByte("\"\\$`", v[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
 // parseWindowsDetectionOutput parses Windows probe output
 // (echo %OS%; echo %PROCESSOR_ARCHITECTURE%; echo %COMSPEC%).
 // Normalizes "Windows_NT" to "Windows".
@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"strings"
 	"sync"
 	"testing"
 )
@@ -139,6 +140,47 @@ func TestParseDetectionOutput(t *testing.T) {
 	}
 }
 
+func TestParseDetectionOutput_OSRelease(t *testing.T) {
+	output := strings.Join([]string{
+		"Linux", "x86_64", "/bin/bash", "apt", "no", "systemd", "sudo doas ",
+		`PRETTY_NAME="Ubuntu 22.04.4 LTS"`,
+		"NAME=\"Ubuntu\"",
+		"# comment",
+		"ID=ubuntu",
+		"ID_LIKE=debian",
+		`VERSION_ID="22.04"`,
+		`HOME_URL="https://www.ubuntu.com/"`,
+	}, "\n")
+	want := RemoteInfo{
+		OS: "Linux", Arch: "x86_64", Shell: "/bin/bash", PackageManager: "apt",
+		InitSystem: "systemd", Sudo: true, Doas: true,
+		Distro: "ubuntu", DistroLike: "debian", DistroVersion: "22.04", DistroName: "Ubuntu 22.04.4 LTS",
+	}
+	if got := parseDetectionOutput(output); got != want {
+		t.Errorf("parseDetectionOutput = %+v, want %+v", got, want)
+	}
+
+	// Alpine without an init system line entry and without privilege tools.
+	got := parseDetectionOutput("Linux\naarch64\n/bin/ash\napk\nno\n\n\nID=alpine\nVERSION_ID=3.19.1\nPRETTY_NAME='Alpine Linux v3.19'")
+	if got.Distro != "alpine" || got.DistroVersion != "3.19.1" || got.DistroName != "Alpine Linux v3.19" || got.InitSystem != "" || got.Sudo || got.Doas {
+		t.Errorf("alpine = %+v", got)
+	}
+}
+
+func TestUnquoteOSRelease(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:             "plain",
+		`"quoted"`:          "quoted",
+		`'single'`:          "single",
+		`"a \"b\" \$c \\d"`: `a "b" $c \d`,
+		`"`:                 `"`,
+	} {
+		if got := unquoteOSRelease(in); got != want {
+			t.Errorf("unquoteOSRelease(%s) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 func TestParseWindowsDetectionOutput(t *testing.T) {
 	tests := []struct {
 		name     string
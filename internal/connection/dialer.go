@@ -0,0 +1,164 @@
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialFunc establishes an SSH client connection to addr. It is stored on each
+// Connection so auto-reconnect uses the same transport as the initial dial.
+type dialFunc func(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error)
+
+// commandDialer returns a dialFunc that runs argv and speaks SSH over its
+// stdin/stdout, like OpenSSH's ProxyCommand.
+func commandDialer(argv []string) dialFunc {
+	return func(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+		conn, err := startCommandConn(argv, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bound the handshake: a hung proxy would otherwise block forever.
+		var timer *time.Timer
+		if cfg.Timeout > 0 {
+			timer = time.AfterFunc(cfg.Timeout, func() { conn.Close() })
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if timer != nil && !timer.Stop() {
+			if err == nil {
+				c.Close()
+			}
+			err = fmt.Errorf("handshake via %s timed out after %s", argv[0], cfg.Timeout)
+		}
+		if err != nil {
+			// Let the process be reaped so its stderr (which usually explains
+			// the failure) has been fully collected.
+			conn.Close()
+			select {
+			case <-conn.exited:
+			case <-time.After(time.Second):
+			}
+			if msg := conn.stderrText(); msg != "" {
+				return nil, fmt.Errorf("%w (%s: %s)", err, argv[0], msg)
+			}
+			return nil, err
+		}
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+}
+
+// commandConn adapts a child process's stdio to net.Conn. The pipes are
+// owned here rather than by exec.Cmd so that reaping the process never races
+// with reads of its final output.
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *limitedBuffer
+	addr   string
+	exited chan struct{} // closed once the process has been reaped
+
+	closeOnce sync.Once
+}
+
+func startCommandConn(argv []string, addr string) (*commandConn, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty proxy command")
+	}
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("proxy command stdin: %w", err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		stdinR.Close()
+		stdinW.Close()
+		return nil, fmt.Errorf("proxy command stdout: %w", err)
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+	stderr := &limitedBuffer{max: 4096}
+	cmd.Stderr = stderr
+	err = cmd.Start()
+	// The child holds its own copies of these ends.
+	stdinR.Close()
+	stdoutW.Close()
+	if err != nil {
+		stdinW.Close()
+		stdoutR.Close()
+		return nil, fmt.Errorf("start proxy command %s: %w", argv[0], err)
+	}
+
+	c := &commandConn{
+		cmd: cmd, stdin: stdinW, stdout: stdoutR, stderr: stderr, addr: addr,
+		exited: make(chan struct{}),
+	}
+	go func() {
+		cmd.Wait()
+		close(c.exited)
+	}()
+	return c, nil
+}
+
+func (c *commandConn) Read(b []byte) (int, error)  { return c.stdout.Read(b) }
+func (c *commandConn) Write(b []byte) (int, error) { return c.stdin.Write(b) }
+
+// Close closes the pipes and terminates the process.
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.cmd.Process.Kill()
+		c.stdout.Close()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return commandAddr("proxy-command") }
+func (c *commandConn) RemoteAddr() net.Addr               { return commandAddr(c.addr) }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *commandConn) stderrText() string {
+	return strings.TrimSpace(c.stderr.String())
+}
+
+// commandAddr is a net.Addr for connections tunneled through a command.
+type commandAddr string
+
+func (a commandAddr) Network() string { return "proxy-command" }
+func (a commandAddr) String() string  { return string(a) }
+
+// limitedBuffer keeps the last max bytes written, for error reporting.
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - b.max; over > 0 {
+		b.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,161 @@
+package connection
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// TestHelperProxyProcess is not a real test: it is run as a child process by
+// the dialer tests and pipes stdio to the TCP address in SSH_MCP_TEST_PROXY,
+// like `nc` in a ProxyCommand.
+func TestHelperProxyProcess(t *testing.T) {
+	addr := os.Getenv("SSH_MCP_TEST_PROXY")
+	if addr == "" {
+		return
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		os.Stderr.WriteString("helper dial: " + err.Error())
+		os.Exit(1)
+	}
+	go io.Copy(conn, os.Stdin)
+	io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+// startTestSSHServer accepts SSH connections without authentication.
+func startTestSSHServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{NoClientAuth: true}
+	srvCfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, srvCfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "test server")
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func testClientConfig() *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+}
+
+func TestCommandDialer_Handshake(t *testing.T) {
+	addr := startTestSSHServer(t)
+	t.Setenv("SSH_MCP_TEST_PROXY", addr)
+
+	dial := commandDialer([]string{os.Args[0], "-test.run=^TestHelperProxyProcess$"})
+	client, err := dial("instance-1:22", testClientConfig())
+	if err != nil {
+		t.Fatalf("dial via command: %v", err)
+	}
+	defer client.Close()
+
+	if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		t.Errorf("request over proxied connection failed: %v", err)
+	}
+	if got := client.RemoteAddr().String(); got != "instance-1:22" {
+		t.Errorf("RemoteAddr = %q, want instance-1:22", got)
+	}
+}
+
+func TestCommandDialer_ReportsStderr(t *testing.T) {
+	dial := commandDialer([]string{"sh", "-c", "echo 'ERROR: instance not found' >&2; exit 1"})
+	_, err := dial("missing:22", testClientConfig())
+	if err == nil {
+		t.Fatal("expected error from failing proxy command")
+	}
+	if !strings.Contains(err.Error(), "instance not found") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+}
+
+func TestCommandDialer_HandshakeTimeout(t *testing.T) {
+	dial := commandDialer([]string{"sh", "-c", "sleep 10"})
+	cfg := testClientConfig()
+	cfg.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	_, err := dial("hung:22", cfg)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected handshake timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout took too long: %v", elapsed)
+	}
+}
+
+func TestPool_IAPCommand(t *testing.T) {
+	cfg := &config.SSHConfig{
+		KeySearchPaths:    []string{"/nonexistent"},
+		ConnectionTimeout: 5 * time.Second,
+		MaxIdleTime:       5 * time.Minute,
+		HostIAP: []config.HostIAP{
+			{Pattern: "gce-.*", Project: "my-proj", Zone: "europe-west1-b"},
+			{Pattern: "vm-.*", Zone: "us-central1-a"},
+		},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+
+	got := pool.iapCommand(ConnectParams{Host: "gce-web", Port: 22})
+	want := []string{
+		"gcloud", "compute", "start-iap-tunnel", "gce-web", "22",
+		"--listen-on-stdin", "--zone=europe-west1-b", "--verbosity=warning", "--project=my-proj",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("iapCommand = %v, want %v", got, want)
+	}
+
+	got = pool.iapCommand(ConnectParams{Host: "VM-1", Port: 2222})
+	if !slices.Contains(got, "--zone=us-central1-a") || slices.ContainsFunc(got, func(s string) bool {
+		return strings.HasPrefix(s, "--project")
+	}) {
+		t.Errorf("unexpected command for project-less rule: %v", got)
+	}
+
+	if got := pool.iapCommand(ConnectParams{Host: "web.example.com", Port: 22}); got != nil {
+		t.Errorf("expected no IAP command for unmatched host, got %v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialAttemptDelay is how long to wait for one address before also trying
+// the next (RFC 8305 "Connection Attempt Delay").
+const dialAttemptDelay = 250 * time.Millisecond
+
+// tcpDialer returns a dialFunc that connects over TCP with dialMulti, giving
+// each address at most attemptTimeout (0 = the whole connection timeout),
+// and bounds the SSH handshake with ClientConfig.Timeout. Non-nil pinned
+// addresses are dialed instead of resolving the host.
+func tcpDialer(attemptTimeout time.Duration, pinned []net.IP) dialFunc {
+	return func(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+		conn, err := dialMulti(addr, pinned, cfg.Timeout, attemptTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Timeout > 0 {
+			conn.SetDeadline(time.Now().Add(cfg.Timeout))
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+}
+
+// dialMulti resolves addr and races connections to its addresses, happy
+// eyeballs style: addresses alternate between IPv6 and IPv4, a new attempt
+// starts every dialAttemptDelay or as soon as the previous one fails, and the
+// first connection to succeed wins. timeout bounds the whole dial (0 = none).
+// Non-nil pinned addresses replace the DNS lookup.
+func dialMulti(addr string, pinned []net.IP, timeout, attemptTimeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var d net.Dialer
+	var ips []net.IPAddr
+	switch {
+	case pinned != nil:
+		for _, ip := range pinned {
+			ips = append(ips, net.IPAddr{IP: ip})
+		}
+	case net.ParseIP(host) != nil:
+		return d.DialContext(ctx, "tcp", addr)
+	default:
+		if ips, err = net.DefaultResolver.LookupIPAddr(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	if len(ips) == 1 {
+		return d.DialContext(ctx, "tcp", net.JoinHostPort(ips[0].IP.String(), port))
+	}
+	targets := interleaveFamilies(ips)
+
+	type result struct {
+		conn net.Conn
+		addr string
+		err  error
+	}
+	results := make(chan result, len(targets))
+	next, pending := 0, 0
+	start := func() {
+		target := net.JoinHostPort(targets[next].IP.String(), port)
+		next++
+		pending++
+		go func() {
+			actx := ctx
+			if attemptTimeout > 0 {
+				var acancel context.CancelFunc
+				actx, acancel = context.WithTimeout(ctx, attemptTimeout)
+				defer acancel()
+			}
+			c, err := d.DialContext(actx, "tcp", target)
+			results <- result{conn: c, addr: target, err: err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(dialAttemptDelay)
+	defer timer.Stop()
+	var errs []error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Close connections that lose the race once they complete.
+				go func(n int) {
+					for ; n > 0; n-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				log.Printf("Connected to %s via %s (%d addresses, %d failed)", addr, r.addr, len(targets), len(errs))
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+			if next < len(targets) {
+				start()
+				timer.Reset(dialAttemptDelay)
+			}
+		case <-timer.C:
+			if next < len(targets) {
+				start()
+				timer.Reset(dialAttemptDelay)
+			}
+		}
+	}
+	return nil, fmt.Errorf("all %d addresses of %s failed: %w", len(targets), host, errors.Join(errs...))
+}
+
+// interleaveFamilies reorders ips to alternate address families, starting
+// with the family the resolver preferred, and otherwise keeps their order.
+func interleaveFamilies(ips []net.IPAddr) []net.IPAddr {
+	var first, second []net.IPAddr
+	firstIs4 := ips[0].IP.To4() != nil
+	for _, ip := range ips {
+		if (ip.IP.To4() != nil) == firstIs4 {
+			first = append(first, ip)
+		} else {
+			second = append(second, ip)
+		}
+	}
+	out := make([]net.IPAddr, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			out = append(out, first[i])
+		}
+		if i < len(second) {
+			out = append(out, second[i])
+		}
+	}
+	return out
+}
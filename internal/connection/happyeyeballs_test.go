@@ -0,0 +1,79 @@
+package connection
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterleaveFamilies(t *testing.T) {
+	ips := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("2001:db8::3")},
+		{IP: net.ParseIP("192.0.2.1")},
+	}
+	got := interleaveFamilies(ips)
+	want := []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "2001:db8::3"}
+	for i, ip := range got {
+		if ip.IP.String() != want[i] {
+			t.Fatalf("interleaveFamilies = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDialMulti(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// localhost may also resolve to ::1, where nothing listens; the IPv4
+	// address must still win.
+	conn, err := dialMulti(net.JoinHostPort("localhost", port), nil, 5*time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("dialMulti(localhost): %v", err)
+	}
+	conn.Close()
+
+	conn, err = dialMulti(ln.Addr().String(), nil, 5*time.Second, 0)
+	if err != nil {
+		t.Fatalf("dialMulti(IP literal): %v", err)
+	}
+	conn.Close()
+
+	// Pinned addresses are dialed without resolving the name.
+	conn, err = dialMulti(net.JoinHostPort("pinned.invalid", port), []net.IP{net.ParseIP("127.0.0.1")}, 5*time.Second, 0)
+	if err != nil {
+		t.Fatalf("dialMulti(pinned): %v", err)
+	}
+	conn.Close()
+
+	ln.Close()
+	if _, err := dialMulti(net.JoinHostPort("localhost", port), nil, 5*time.Second, time.Second); err == nil {
+		t.Fatal("expected error with nothing listening")
+	} else if !strings.Contains(err.Error(), "refused") {
+		t.Errorf("error = %v, want connection refused", err)
+	}
+}
+
+func TestTCPDialer(t *testing.T) {
+	addr := startTestSSHServer(t)
+	client, err := tcpDialer(time.Second, nil)(addr, testClientConfig())
+	if err != nil {
+		t.Fatalf("tcpDialer: %v", err)
+	}
+	client.Close()
+}
@@ -0,0 +1,61 @@
+package connection
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHistorySize is how many commands a pool's history keeps.
+const defaultHistorySize = 200
+
+// CommandRecord is one remote command run on a connection.
+type CommandRecord struct {
+	Time      time.Time // when the command finished
+	Owner     string    // client namespace of the session ("" = shared)
+	SessionID SessionID
+	Command   string
+	Duration  time.Duration
+	Failure   string // empty if the command succeeded
+}
+
+// CommandHistory keeps the most recent commands run across a pool's
+// connections, oldest first. It is in memory only.
+type CommandHistory struct {
+	mu      sync.Mutex
+	records []CommandRecord
+	size    int
+	total   int
+}
+
+// NewCommandHistory creates a history holding at most size records.
+func NewCommandHistory(size int) *CommandHistory {
+	return &CommandHistory{size: size}
+}
+
+// Add appends r, dropping the oldest record once the history is full.
+func (h *CommandHistory) Add(r CommandRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	if len(h.records) > h.size {
+		h.records = append(h.records[:0], h.records[len(h.records)-h.size:]...)
+	}
+	h.total++
+}
+
+// Recent returns a copy of the kept records, oldest first.
+func (h *CommandHistory) Recent() []CommandRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]CommandRecord, len(h.records))
+	copy(out, h.records)
+	return out
+}
+
+// Total returns how many records were ever added, so callers can tell
+// whether the history changed.
+func (h *CommandHistory) Total() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
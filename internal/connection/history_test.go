@@ -0,0 +1,50 @@
+package connection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommandHistory_KeepsMostRecent(t *testing.T) {
+	h := NewCommandHistory(3)
+	for _, cmd := range []string{"a", "b", "c", "d", "e"} {
+		h.Add(CommandRecord{Command: cmd})
+	}
+
+	got := h.Recent()
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3", len(got))
+	}
+	for i, want := range []string{"c", "d", "e"} {
+		if got[i].Command != want {
+			t.Errorf("record %d = %q, want %q", i, got[i].Command, want)
+		}
+	}
+	if h.Total() != 5 {
+		t.Errorf("Total = %d, want 5", h.Total())
+	}
+
+	// Recent returns a copy.
+	got[0].Command = "changed"
+	if h.Recent()[0].Command != "c" {
+		t.Error("Recent must not expose the internal slice")
+	}
+}
+
+func TestConnection_RecordCommandResultAddsHistory(t *testing.T) {
+	h := NewCommandHistory(10)
+	conn := &Connection{ID: "user@host:22", history: h}
+	conn.RecordCommandResult("uptime", time.Second, "")
+	conn.RecordCommandResult("false", time.Second, "command exited with code 1")
+
+	got := h.Recent()
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2", len(got))
+	}
+	if got[0].SessionID != "user@host:22" || got[0].Command != "uptime" || got[0].Failure != "" {
+		t.Errorf("first record = %+v", got[0])
+	}
+	if got[1].Failure != "command exited with code 1" {
+		t.Errorf("second record failure = %q", got[1].Failure)
+	}
+}
@@ -0,0 +1,165 @@
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyError is returned when a host presents a key that known_hosts
+// does not have, or one that differs from the keys recorded there.
+type HostKeyError struct {
+	Host        string         // host as known_hosts names it (host, or [host]:port)
+	Key         ssh.PublicKey  // the key the host presented
+	KeyType     string         // its type, e.g. ssh-ed25519
+	Fingerprint string         // its SHA256 fingerprint
+	Known       []KnownHostKey // keys known_hosts has for the host (empty = unknown host)
+}
+
+// KnownHostKey is a known_hosts entry a presented key was checked against.
+type KnownHostKey struct {
+	KeyType     string `json:"key_type"`
+	Fingerprint string `json:"fingerprint"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+}
+
+// newHostKeyError describes a knownhosts.KeyError for hostname ("host:port").
+func newHostKeyError(hostname string, key ssh.PublicKey, keyErr *knownhosts.KeyError) *HostKeyError {
+	e := &HostKeyError{
+		Host:        knownhosts.Normalize(hostname),
+		Key:         key,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+	}
+	for _, k := range keyErr.Want {
+		e.Known = append(e.Known, KnownHostKey{
+			KeyType:     k.Key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(k.Key),
+			File:        k.Filename,
+			Line:        k.Line,
+		})
+	}
+	return e
+}
+
+// Changed reports whether known_hosts has other keys for the host, rather
+// than none at all.
+func (e *HostKeyError) Changed() bool {
+	return len(e.Known) > 0
+}
+
+func (e *HostKeyError) Error() string {
+	var b strings.Builder
+	if e.Changed() {
+		fmt.Fprintf(&b, "HOST KEY MISMATCH for %s, connection refused\n", e.Host)
+	} else {
+		fmt.Fprintf(&b, "host key for %s is not in known_hosts, connection refused\n", e.Host)
+	}
+	fmt.Fprintf(&b, "  presented: %s %s", e.KeyType, e.Fingerprint)
+	for _, k := range e.Known {
+		fmt.Fprintf(&b, "\n  known:     %s %s (%s:%d)", k.KeyType, k.Fingerprint, k.File, k.Line)
+	}
+	if e.Changed() {
+		fmt.Fprintf(&b, "\nThe host may be impersonated (man-in-the-middle). If its key was replaced legitimately, remove the old entry with 'ssh-keygen -R %s' and add the new one.", e.Host)
+	} else {
+		b.WriteString("\nCheck the fingerprint out of band, then add the key to known_hosts (e.g. with ssh-keyscan).")
+	}
+	return b.String()
+}
+
+// AddKnownHost trusts the key of e: it appends a known_hosts line for
+// e.Host with e.Key and, for a changed key, drops the lines of e.Known that
+// are in --known-hosts and still hold the old key. The file and its
+// directory are created if missing, and the file is replaced atomically.
+func (a *AuthDiscovery) AddKnownHost(e *HostKeyError) error {
+	a.knownMu.Lock()
+	defer a.knownMu.Unlock()
+
+	path := a.cfg.KnownHostsPath
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read known_hosts: %w", err)
+	}
+	mode := os.FileMode(0o600)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	drop := map[int]string{} // line number -> fingerprint it must hold
+	for _, k := range e.Known {
+		if filepath.Clean(k.File) == filepath.Clean(path) {
+			drop[k.Line] = k.Fingerprint
+		}
+	}
+	var out bytes.Buffer
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if fp, ok := drop[i+1]; ok && lineHasKey(line, fp) {
+			continue
+		}
+		out.Write(line)
+	}
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	out.WriteString(knownhosts.Line([]string{e.Host}, e.Key) + "\n")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create known_hosts directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".known_hosts-*")
+	if err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// lineHasKey reports whether a known_hosts line holds the key with the
+// given SHA256 fingerprint.
+func lineHasKey(line []byte, fingerprint string) bool {
+	_, _, key, _, _, err := ssh.ParseKnownHosts(line)
+	return err == nil && ssh.FingerprintSHA256(key) == fingerprint
+}
+
+// recordHostKey wraps cb so the key of every host that passes it is stored
+// on c.
+func (c *Connection) recordHostKey(cb ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := cb(hostname, remote, key); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.HostKeyType = key.Type()
+		c.HostKeyFingerprint = ssh.FingerprintSHA256(key)
+		c.mu.Unlock()
+		return nil
+	}
+}
+
+// GetHostKey returns the type and SHA256 fingerprint of the key the host
+// presented on the last connect ("" before the first one).
+func (c *Connection) GetHostKey() (keyType, fingerprint string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.HostKeyType, c.HostKeyFingerprint
+}
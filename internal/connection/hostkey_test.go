@@ -0,0 +1,138 @@
+package connection
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyError_Text(t *testing.T) {
+	known, presented := testHostKey(t), testHostKey(t)
+
+	changed := newHostKeyError("web1:2222", presented, &knownhosts.KeyError{
+		Want: []knownhosts.KnownKey{{Key: known, Filename: "/home/me/.ssh/known_hosts", Line: 7}},
+	})
+	if changed.Host != "[web1]:2222" || !changed.Changed() {
+		t.Errorf("changed: %+v", changed)
+	}
+	msg := changed.Error()
+	for _, want := range []string{
+		"HOST KEY MISMATCH for [web1]:2222",
+		"presented: ssh-ed25519 " + ssh.FingerprintSHA256(presented),
+		"known:     ssh-ed25519 " + ssh.FingerprintSHA256(known) + " (/home/me/.ssh/known_hosts:7)",
+		"ssh-keygen -R [web1]:2222",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("changed message lacks %q:\n%s", want, msg)
+		}
+	}
+
+	unknown := newHostKeyError("web2:22", presented, &knownhosts.KeyError{})
+	if unknown.Host != "web2" || unknown.Changed() {
+		t.Errorf("unknown: %+v", unknown)
+	}
+	if msg := unknown.Error(); !strings.Contains(msg, "not in known_hosts") || strings.Contains(msg, "known:") {
+		t.Errorf("unknown message:\n%s", msg)
+	}
+}
+
+func TestRecordHostKey(t *testing.T) {
+	key := testHostKey(t)
+	c := &Connection{}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+
+	reject := c.recordHostKey(func(string, net.Addr, ssh.PublicKey) error { return &knownhosts.KeyError{} })
+	if err := reject("web1:22", addr, key); err == nil {
+		t.Error("expected the callback's error")
+	}
+	if _, fp := c.GetHostKey(); fp != "" {
+		t.Errorf("rejected key recorded: %s", fp)
+	}
+
+	accept := c.recordHostKey(ssh.InsecureIgnoreHostKey())
+	if err := accept("web1:22", addr, key); err != nil {
+		t.Fatal(err)
+	}
+	if typ, fp := c.GetHostKey(); typ != ssh.KeyAlgoED25519 || fp != ssh.FingerprintSHA256(key) {
+		t.Errorf("GetHostKey() = %s %s", typ, fp)
+	}
+}
+
+func TestAddKnownHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	auth := NewAuthDiscovery(&config.SSHConfig{KnownHostsPath: path, VerifyHostKey: true, HostKeyPrompt: config.HostKeyPromptAll})
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+	first, second := testHostKey(t), testHostKey(t)
+
+	// Without known_hosts every host is unknown.
+	cb, err := auth.buildHostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyErr *HostKeyError
+	if err := cb("web1:22", addr, first); !errors.As(err, &keyErr) || keyErr.Changed() {
+		t.Fatalf("missing known_hosts: got %v, want an unknown-host HostKeyError", err)
+	}
+	if err := auth.AddKnownHost(keyErr); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("known_hosts not created 0600: %v", err)
+	}
+	other := knownhosts.Line([]string{"web2"}, second)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(other + "\n")
+	f.Close()
+
+	// A changed key replaces the old entry and leaves other hosts alone.
+	if cb, err = auth.buildHostKeyCallback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("web1:22", addr, first); err != nil {
+		t.Fatalf("trusted key rejected: %v", err)
+	}
+	if err := cb("web1:22", addr, second); !errors.As(err, &keyErr) || !keyErr.Changed() {
+		t.Fatalf("changed key: got %v", err)
+	}
+	if err := auth.AddKnownHost(keyErr); err != nil {
+		t.Fatal(err)
+	}
+	if cb, err = auth.buildHostKeyCallback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("web1:22", addr, second); err != nil {
+		t.Errorf("new key rejected: %v", err)
+	}
+	if err := cb("web1:22", addr, first); err == nil {
+		t.Error("old key still accepted")
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || lines[0] != other {
+		t.Errorf("known_hosts:\n%s", data)
+	}
+}
@@ -0,0 +1,50 @@
+package connection
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// iapRule is a compiled --host-iap entry.
+type iapRule struct {
+	re      *regexp.Regexp
+	project string
+	zone    string
+}
+
+func compileIAPRules(hosts []config.HostIAP) []iapRule {
+	var rules []iapRule
+	for _, h := range hosts {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host IAP pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		rules = append(rules, iapRule{re: re, project: h.Project, zone: h.Zone})
+	}
+	return rules
+}
+
+// iapCommand returns the gcloud invocation that opens an Identity-Aware Proxy
+// TCP tunnel to the instance named params.Host on params.Port, speaking over
+// stdio (the same mechanism as `gcloud compute ssh --tunnel-through-iap`).
+// It returns nil if no --host-iap rule matches.
+func (p *Pool) iapCommand(params ConnectParams) []string {
+	for _, r := range p.iapRules {
+		if !r.re.MatchString(params.Host) {
+			continue
+		}
+		argv := []string{
+			"gcloud", "compute", "start-iap-tunnel", params.Host, strconv.Itoa(params.Port),
+			"--listen-on-stdin", "--zone=" + r.zone, "--verbosity=warning",
+		}
+		if r.project != "" {
+			argv = append(argv, "--project="+r.project)
+		}
+		return argv
+	}
+	return nil
+}
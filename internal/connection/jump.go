@@ -0,0 +1,55 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// jumpDialer returns a dialFunc that opens the TCP connection from the
+// remote side of another of owner's sessions (a direct-tcpip channel, like
+// OpenSSH's ProxyJump) and speaks SSH over it. The jump session is looked up
+// on every dial, so auto-reconnect of either session goes through its current
+// client, and fails once the jump session is gone.
+func (p *Pool) jumpDialer(owner string, via SessionID) dialFunc {
+	return func(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+		ctx := WithOwner(context.Background(), owner)
+		if cfg.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+			defer cancel()
+		}
+		jump, err := p.GetConnection(ctx, via)
+		if err != nil {
+			return nil, fmt.Errorf("jump session: %w", err)
+		}
+		jumpClient, err := jump.GetClient()
+		if err != nil {
+			return nil, fmt.Errorf("jump session: %w", err)
+		}
+		conn, err := jumpClient.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("dial %s from %s: %w", addr, via, err)
+		}
+
+		// SSH channels have no deadlines; bound the handshake with a timer.
+		var timer *time.Timer
+		if cfg.Timeout > 0 {
+			timer = time.AfterFunc(cfg.Timeout, func() { conn.Close() })
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if timer != nil && !timer.Stop() {
+			if err == nil {
+				c.Close()
+			}
+			err = fmt.Errorf("handshake via %s timed out after %s", via, cfg.Timeout)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+}
@@ -0,0 +1,105 @@
+package connection
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startJumpSSHServer accepts SSH connections without authentication and
+// forwards direct-tcpip channels, like sshd with AllowTcpForwarding.
+func startJumpSSHServer(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{NoClientAuth: true}
+	srvCfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, srvCfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nch := range chans {
+					var target struct {
+						Host       string
+						Port       uint32
+						OriginHost string
+						OriginPort uint32
+					}
+					if nch.ChannelType() != "direct-tcpip" || ssh.Unmarshal(nch.ExtraData(), &target) != nil {
+						nch.Reject(ssh.UnknownChannelType, "direct-tcpip only")
+						continue
+					}
+					ch, chReqs, err := nch.Accept()
+					if err != nil {
+						continue
+					}
+					go ssh.DiscardRequests(chReqs)
+					go splice(ch, net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestPool_JumpDialer(t *testing.T) {
+	jumpAddr := startJumpSSHServer(t)
+	targetAddr := startTestSSHServer(t)
+
+	jumpClient, err := ssh.Dial("tcp", jumpAddr, testClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := newTestPool()
+	jumpID := SessionID("test@jump:22")
+	jump := &Connection{ID: jumpID, Owner: "alice", Client: jumpClient, Connected: true, ready: make(chan struct{})}
+	close(jump.ready)
+	pool.conns[poolKey{owner: "alice", id: jumpID}] = jump
+	defer pool.CloseAll()
+
+	client, err := pool.jumpDialer("alice", jumpID)(targetAddr, testClientConfig())
+	if err != nil {
+		t.Fatalf("dial through jump session: %v", err)
+	}
+	client.Close()
+
+	// Another owner can't use alice's session as a jump host.
+	if _, err := pool.jumpDialer("bob", jumpID)(targetAddr, testClientConfig()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("dial through another owner's session: err = %v, want not found", err)
+	}
+
+	// The target must be reachable from the jump host.
+	if _, err := pool.jumpDialer("alice", jumpID)("127.0.0.1:1", testClientConfig()); err == nil {
+		t.Error("expected error for an unreachable target")
+	}
+
+	if ips, err := pool.LookupTarget(context.Background(), ConnectParams{Host: "behind-jump.invalid", ViaSession: jumpID}); ips != nil || err != nil {
+		t.Errorf("LookupTarget via jump = %v, %v; want nil, nil", ips, err)
+	}
+}
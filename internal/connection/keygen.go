@@ -0,0 +1,138 @@
+package connection
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Supported key types for GenerateKeyPair.
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeRSA     = "rsa"
+)
+
+// GeneratedKey describes a keypair written to disk by GenerateKeyPair.
+type GeneratedKey struct {
+	PrivateKeyPath string
+	PublicKeyPath  string
+	PublicKey      string // authorized_keys line, including comment
+	Fingerprint    string // SHA256 fingerprint
+	Type           string
+	Bits           int
+}
+
+// GenerateKeyPair generates a new keypair and writes the private key to
+// privPath (OpenSSH format, mode 0600) and the public key to privPath+".pub"
+// (mode 0644). bits selects the RSA modulus size (default 4096) or ECDSA curve
+// (256, 384, 521; default 256) and is ignored for Ed25519. Existing files are
+// never overwritten unless overwrite is true.
+func GenerateKeyPair(keyType string, bits int, comment, privPath string, overwrite bool) (*GeneratedKey, error) {
+	if keyType == "" {
+		keyType = KeyTypeEd25519
+	}
+	keyType = strings.ToLower(keyType)
+
+	var priv crypto.PrivateKey
+	var pub crypto.PublicKey
+	switch keyType {
+	case KeyTypeEd25519:
+		edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ed25519 key: %w", err)
+		}
+		priv, pub, bits = edPriv, edPub, 256
+	case KeyTypeECDSA:
+		if bits == 0 {
+			bits = 256
+		}
+		var curve elliptic.Curve
+		switch bits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("invalid ECDSA key size %d (must be 256, 384, or 521)", bits)
+		}
+		ecKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("generate ecdsa key: %w", err)
+		}
+		priv, pub = ecKey, &ecKey.PublicKey
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = 4096
+		}
+		if bits < 2048 || bits > 8192 {
+			return nil, fmt.Errorf("invalid RSA key size %d (must be 2048-8192)", bits)
+		}
+		rsaKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, fmt.Errorf("generate rsa key: %w", err)
+		}
+		priv, pub = rsaKey, &rsaKey.PublicKey
+	default:
+		return nil, fmt.Errorf("unknown key type %q (must be ed25519, ecdsa, or rsa)", keyType)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("convert public key: %w", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return nil, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	pubLine := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if comment != "" {
+		pubLine += " " + comment
+	}
+
+	pubPath := privPath + ".pub"
+	if !overwrite {
+		for _, p := range []string{privPath, pubPath} {
+			if _, err := os.Lstat(p); err == nil {
+				return nil, fmt.Errorf("%s already exists (set overwrite to replace it)", p)
+			}
+		}
+	}
+
+	if err := writeKeyFile(privPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("write private key: %w", err)
+	}
+	if err := writeKeyFile(pubPath, []byte(pubLine+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("write public key: %w", err)
+	}
+
+	return &GeneratedKey{
+		PrivateKeyPath: privPath,
+		PublicKeyPath:  pubPath,
+		PublicKey:      pubLine,
+		Fingerprint:    ssh.FingerprintSHA256(sshPub),
+		Type:           keyType,
+		Bits:           bits,
+	}, nil
+}
+
+// writeKeyFile writes data with the given permissions, tightening the mode of
+// a pre-existing file (os.WriteFile only applies perm on creation).
+func writeKeyFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,109 @@
+package connection
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKeyPair_Types(t *testing.T) {
+	tests := []struct {
+		keyType  string
+		bits     int
+		wantBits int
+		wantAlgo string
+	}{
+		{"", 0, 256, ssh.KeyAlgoED25519},
+		{"ed25519", 0, 256, ssh.KeyAlgoED25519},
+		{"ecdsa", 384, 384, ssh.KeyAlgoECDSA384},
+		{"RSA", 2048, 2048, ssh.KeyAlgoRSA},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		privPath := filepath.Join(dir, "id_test")
+
+		key, err := GenerateKeyPair(tt.keyType, tt.bits, "test@mcp", privPath, false)
+		if err != nil {
+			t.Fatalf("GenerateKeyPair(%q, %d): %v", tt.keyType, tt.bits, err)
+		}
+		if key.Bits != tt.wantBits {
+			t.Errorf("%s: Bits = %d, want %d", tt.keyType, key.Bits, tt.wantBits)
+		}
+		if !strings.HasSuffix(key.PublicKey, " test@mcp") {
+			t.Errorf("%s: public key missing comment: %q", tt.keyType, key.PublicKey)
+		}
+		if !strings.HasPrefix(key.Fingerprint, "SHA256:") {
+			t.Errorf("%s: unexpected fingerprint %q", tt.keyType, key.Fingerprint)
+		}
+
+		privData, err := os.ReadFile(privPath)
+		if err != nil {
+			t.Fatalf("read private key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(privData)
+		if err != nil {
+			t.Fatalf("%s: parse generated private key: %v", tt.keyType, err)
+		}
+		if signer.PublicKey().Type() != tt.wantAlgo {
+			t.Errorf("%s: key algo = %s, want %s", tt.keyType, signer.PublicKey().Type(), tt.wantAlgo)
+		}
+
+		info, err := os.Stat(privPath)
+		if err != nil {
+			t.Fatalf("stat private key: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s: private key mode = %o, want 600", tt.keyType, info.Mode().Perm())
+		}
+
+		pubData, err := os.ReadFile(privPath + ".pub")
+		if err != nil {
+			t.Fatalf("read public key: %v", err)
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(pubData)
+		if err != nil {
+			t.Fatalf("%s: parse public key: %v", tt.keyType, err)
+		}
+		if ssh.FingerprintSHA256(pub) != key.Fingerprint {
+			t.Errorf("%s: public key file does not match returned fingerprint", tt.keyType)
+		}
+	}
+}
+
+func TestGenerateKeyPair_InvalidParams(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		keyType string
+		bits    int
+	}{
+		{"dsa", 0},
+		{"rsa", 1024},
+		{"ecdsa", 512},
+	} {
+		if _, err := GenerateKeyPair(tc.keyType, tc.bits, "", filepath.Join(dir, "k"), false); err == nil {
+			t.Errorf("expected error for %s/%d", tc.keyType, tc.bits)
+		}
+	}
+}
+
+func TestGenerateKeyPair_NoOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	privPath := filepath.Join(dir, "id_test")
+	if err := os.WriteFile(privPath+".pub", []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GenerateKeyPair("ed25519", 0, "", privPath, false); err == nil {
+		t.Fatal("expected error when key file already exists")
+	}
+	if data, _ := os.ReadFile(privPath + ".pub"); string(data) != "existing" {
+		t.Error("existing public key was modified")
+	}
+
+	if _, err := GenerateKeyPair("ed25519", 0, "", privPath, true); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+}
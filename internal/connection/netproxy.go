@@ -0,0 +1,277 @@
+package connection
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// netProxyRule is a compiled --host-proxy entry. A nil proxy means direct.
+type netProxyRule struct {
+	re    *regexp.Regexp
+	proxy *url.URL
+}
+
+func compileNetProxyRules(hosts []config.HostProxy) []netProxyRule {
+	var rules []netProxyRule
+	for _, h := range hosts {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host proxy pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		rule := netProxyRule{re: re}
+		if h.URL != config.ProxyNone {
+			if rule.proxy, err = url.Parse(h.URL); err != nil {
+				log.Printf("Ignoring invalid host proxy %q: %v", h.Pattern, err)
+				continue
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// netProxyFor returns the outbound proxy for host: the first matching
+// --host-proxy rule, else --ssh-proxy. It returns nil for a direct dial.
+func (p *Pool) netProxyFor(host string) *url.URL {
+	for _, r := range p.netProxyRules {
+		if r.re.MatchString(host) {
+			return r.proxy
+		}
+	}
+	return p.netProxy
+}
+
+// proxyDialer returns a dialFunc that tunnels the SSH connection through the
+// HTTP CONNECT or SOCKS5 proxy at u. With pinned addresses the proxy is asked
+// for the first of them instead of the host name.
+func proxyDialer(u *url.URL, pinned []net.IP) dialFunc {
+	return func(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+		target := addr
+		if len(pinned) > 0 {
+			_, port, _ := net.SplitHostPort(addr)
+			target = net.JoinHostPort(pinned[0].String(), port)
+		}
+		conn, err := dialMulti(u.Host, nil, cfg.Timeout, 0)
+		if err != nil {
+			return nil, fmt.Errorf("connect to proxy %s: %w", u.Host, err)
+		}
+		// One deadline covers the proxy negotiation and the SSH handshake.
+		if cfg.Timeout > 0 {
+			conn.SetDeadline(time.Now().Add(cfg.Timeout))
+		}
+		tunnel := conn
+		if u.Scheme == config.ProxySchemeHTTP {
+			tunnel, err = httpConnect(conn, u, target)
+		} else {
+			err = socks5Connect(conn, u, target)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %w", u.Redacted(), err)
+		}
+		conn = tunnel
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+}
+
+// httpConnect asks an HTTP proxy to open a tunnel to addr. The returned conn
+// replays anything the proxy sent after its response headers.
+func httpConnect(conn net.Conn, u *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		cred := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+cred)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+	if br.Buffered() == 0 {
+		return conn, nil
+	}
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn is a net.Conn whose reads go through r first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// SOCKS5 protocol constants (RFC 1928, RFC 1929).
+const (
+	socks5Version      = 0x05
+	socks5AuthNone     = 0x00
+	socks5AuthPassword = 0x02
+	socks5AuthNoAccept = 0xff
+	socks5CmdConnect   = 0x01
+	socks5AtypIPv4     = 0x01
+	socks5AtypDomain   = 0x03
+	socks5AtypIPv6     = 0x04
+)
+
+var socks5Replies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect asks a SOCKS5 proxy to connect to addr. With the socks5
+// scheme the host is resolved locally first; socks5h sends the name.
+func socks5Connect(conn net.Conn, u *url.URL, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{socks5AuthNone}
+	if u.User != nil {
+		methods = append(methods, socks5AuthPassword)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return err
+	}
+	if resp[0] != socks5Version {
+		return fmt.Errorf("not a SOCKS5 proxy (version %d)", resp[0])
+	}
+	switch resp[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if u.User == nil {
+			return fmt.Errorf("proxy requires a username and password")
+		}
+		if err := socks5Auth(conn, u.User); err != nil {
+			return err
+		}
+	case socks5AuthNoAccept:
+		return fmt.Errorf("proxy accepted none of the offered authentication methods")
+	default:
+		return fmt.Errorf("proxy chose unsupported authentication method %d", resp[1])
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	ip := net.ParseIP(host)
+	if ip == nil && u.Scheme == config.ProxySchemeSOCKS5 {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return err
+		}
+		ip = ips[0]
+	}
+	switch {
+	case ip.To4() != nil:
+		req = append(append(req, socks5AtypIPv4), ip.To4()...)
+	case ip != nil:
+		req = append(append(req, socks5AtypIPv6), ip.To16()...)
+	default:
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long for SOCKS5")
+		}
+		req = append(append(req, socks5AtypDomain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Reply: VER REP RSV ATYP BND.ADDR BND.PORT.
+	var hdr [4]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return err
+	}
+	if hdr[1] != 0 {
+		if msg, ok := socks5Replies[hdr[1]]; ok {
+			return fmt.Errorf("connect %s: %s", addr, msg)
+		}
+		return fmt.Errorf("connect %s: SOCKS5 error %d", addr, hdr[1])
+	}
+	var skip int
+	switch hdr[3] {
+	case socks5AtypIPv4:
+		skip = net.IPv4len
+	case socks5AtypIPv6:
+		skip = net.IPv6len
+	case socks5AtypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("proxy sent unknown address type %d", hdr[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// socks5Auth performs RFC 1929 username/password authentication.
+func socks5Auth(conn net.Conn, user *url.Userinfo) error {
+	name := user.Username()
+	pass, _ := user.Password()
+	if len(name) > 255 || len(pass) > 255 {
+		return fmt.Errorf("proxy username or password too long")
+	}
+	req := append([]byte{0x01, byte(len(name))}, name...)
+	req = append(append(req, byte(len(pass))), pass...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return err
+	}
+	if resp[1] != 0 {
+		return fmt.Errorf("proxy rejected the username or password")
+	}
+	return nil
+}
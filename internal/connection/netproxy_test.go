@@ -0,0 +1,180 @@
+package connection
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// startFakeProxy accepts connections on a local port and runs handle on each.
+func startFakeProxy(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(c)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// splice copies between the client and a new connection to target.
+func splice(client io.ReadWriteCloser, target string) {
+	defer client.Close()
+	up, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer up.Close()
+	go io.Copy(up, client)
+	io.Copy(client, up)
+}
+
+func TestProxyDialer_HTTPConnect(t *testing.T) {
+	sshAddr := startTestSSHServer(t)
+	var gotAuth, gotTarget string
+	proxyAddr := startFakeProxy(t, func(c net.Conn) {
+		br := bufio.NewReader(c)
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			c.Close()
+			return
+		}
+		gotAuth, gotTarget = req.Header.Get("Proxy-Authorization"), req.Host
+		io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+		splice(struct {
+			io.Reader
+			io.WriteCloser
+		}{br, c}, sshAddr)
+	})
+
+	u, _ := url.Parse("http://alice:s3cret@" + proxyAddr)
+	client, err := proxyDialer(u, nil)("target.example:22", testClientConfig())
+	if err != nil {
+		t.Fatalf("dial through HTTP proxy: %v", err)
+	}
+	client.Close()
+	if gotTarget != "target.example:22" {
+		t.Errorf("CONNECT target = %q", gotTarget)
+	}
+	if gotAuth != "Basic YWxpY2U6czNjcmV0" {
+		t.Errorf("Proxy-Authorization = %q", gotAuth)
+	}
+}
+
+func TestProxyDialer_HTTPConnectRefused(t *testing.T) {
+	proxyAddr := startFakeProxy(t, func(c net.Conn) {
+		defer c.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(c)); err == nil {
+			io.WriteString(c, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n")
+		}
+	})
+	u, _ := url.Parse("http://" + proxyAddr)
+	if _, err := proxyDialer(u, nil)("target.example:22", testClientConfig()); err == nil {
+		t.Fatal("expected error for 403 from proxy")
+	}
+}
+
+func TestProxyDialer_SOCKS5(t *testing.T) {
+	sshAddr := startTestSSHServer(t)
+	var gotUser, gotPass, gotHost string
+	var gotPort uint16
+	proxyAddr := startFakeProxy(t, func(c net.Conn) {
+		buf := make([]byte, 512)
+		// Greeting: pick username/password.
+		if _, err := io.ReadFull(c, buf[:2]); err != nil {
+			c.Close()
+			return
+		}
+		io.ReadFull(c, buf[:buf[1]])
+		c.Write([]byte{5, socks5AuthPassword})
+		// RFC 1929: VER ULEN UNAME PLEN PASSWD.
+		io.ReadFull(c, buf[:2])
+		n := int(buf[1])
+		io.ReadFull(c, buf[:n])
+		gotUser = string(buf[:n])
+		io.ReadFull(c, buf[:1])
+		n = int(buf[0])
+		io.ReadFull(c, buf[:n])
+		gotPass = string(buf[:n])
+		c.Write([]byte{1, 0})
+		// CONNECT request with a domain name.
+		io.ReadFull(c, buf[:4])
+		if buf[3] != socks5AtypDomain {
+			c.Close()
+			return
+		}
+		io.ReadFull(c, buf[:1])
+		n = int(buf[0])
+		io.ReadFull(c, buf[:n+2])
+		gotHost = string(buf[:n])
+		gotPort = binary.BigEndian.Uint16(buf[n : n+2])
+		c.Write([]byte{5, 0, 0, socks5AtypIPv4, 127, 0, 0, 1, 0, 0})
+		splice(c, sshAddr)
+	})
+
+	u, _ := url.Parse("socks5h://bob:pw@" + proxyAddr)
+	client, err := proxyDialer(u, nil)("target.example:2222", testClientConfig())
+	if err != nil {
+		t.Fatalf("dial through SOCKS5 proxy: %v", err)
+	}
+	client.Close()
+	if gotHost != "target.example" || gotPort != 2222 {
+		t.Errorf("SOCKS5 target = %s:%d", gotHost, gotPort)
+	}
+	if gotUser != "bob" || gotPass != "pw" {
+		t.Errorf("SOCKS5 credentials = %q/%q", gotUser, gotPass)
+	}
+}
+
+func TestProxyDialer_SOCKS5Failure(t *testing.T) {
+	proxyAddr := startFakeProxy(t, func(c net.Conn) {
+		defer c.Close()
+		buf := make([]byte, 512)
+		io.ReadFull(c, buf[:3])
+		c.Write([]byte{5, socks5AuthNone})
+		io.ReadFull(c, buf[:10]) // IPv4 CONNECT
+		c.Write([]byte{5, 5, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0})
+	})
+	u, _ := url.Parse("socks5://" + proxyAddr)
+	if _, err := proxyDialer(u, nil)("127.0.0.1:22", testClientConfig()); err == nil {
+		t.Fatal("expected error for refused SOCKS5 connect")
+	}
+}
+
+func TestPool_NetProxyFor(t *testing.T) {
+	cfg := &config.SSHConfig{
+		ConnectionTimeout: 5 * time.Second,
+		Proxy:             "http://proxy.corp:3128",
+		HostProxies: []config.HostProxy{
+			{Pattern: "lab-.*", URL: "socks5h://jump:1080"},
+			{Pattern: ".*\\.internal", URL: config.ProxyNone},
+		},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+
+	if u := pool.netProxyFor("LAB-1"); u == nil || u.Host != "jump:1080" {
+		t.Errorf("lab-1 proxy = %v, want socks5h://jump:1080", u)
+	}
+	if u := pool.netProxyFor("db.internal"); u != nil {
+		t.Errorf("db.internal proxy = %v, want direct", u)
+	}
+	if u := pool.netProxyFor("example.com"); u == nil || u.Host != "proxy.corp:3128" {
+		t.Errorf("example.com proxy = %v, want --ssh-proxy", u)
+	}
+}
@@ -0,0 +1,21 @@
+package connection
+
+import "context"
+
+// ownerKey is the context key for the client owning the sessions a request
+// may see and use.
+type ownerKey struct{}
+
+// WithOwner returns ctx tagged with owner, the client on whose behalf pool,
+// terminal and tunnel operations run. Sessions created by one owner are
+// invisible to every other owner. The empty owner is the shared namespace
+// used by stdio and by HTTP with --shared-sessions.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the owner set by WithOwner, or "" (shared) if none.
+func OwnerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
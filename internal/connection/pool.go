@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"regexp"
 	"sync"
 	"time"
 
@@ -17,25 +20,42 @@ type SessionID string
 
 // ConnectionInfo provides metadata about a connection.
 type ConnectionInfo struct {
-	SessionID          SessionID `json:"session_id"`
-	Host               string    `json:"host"`
-	Port               int       `json:"port"`
-	User               string    `json:"user"`
-	ConnectedAt        time.Time `json:"connected_at"`
-	LastUsed           time.Time `json:"last_used"`
-	CommandCount       int       `json:"command_count"`
-	Connected          bool      `json:"connected"`
-	OS                 string    `json:"os,omitempty"`
-	Arch               string    `json:"arch,omitempty"`
-	Shell              string    `json:"shell,omitempty"`
-	PackageManager     string    `json:"package_manager,omitempty"`
-	SudoNoninteractive bool      `json:"sudo_noninteractive,omitempty"`
+	SessionID          SessionID              `json:"session_id"`
+	Host               string                 `json:"host"`
+	Port               int                    `json:"port"`
+	User               string                 `json:"user"`
+	ConnectedAt        time.Time              `json:"connected_at"`
+	LastUsed           time.Time              `json:"last_used"`
+	CommandCount       int                    `json:"command_count"`
+	FailedCommands     int                    `json:"failed_commands"`
+	ActiveCommands     int                    `json:"active_commands"`
+	CommandTime        time.Duration          `json:"command_time"`
+	BytesUploaded      int64                  `json:"bytes_uploaded"`
+	BytesDownloaded    int64                  `json:"bytes_downloaded"`
+	LastError          string                 `json:"last_error,omitempty"`
+	IdleTimeout        time.Duration          `json:"idle_timeout"`
+	ExpiresAt          time.Time              `json:"expires_at"` // zero = no max lifetime
+	Connected          bool                   `json:"connected"`
+	OS                 string                 `json:"os,omitempty"`
+	Arch               string                 `json:"arch,omitempty"`
+	Shell              string                 `json:"shell,omitempty"`
+	PackageManager     string                 `json:"package_manager,omitempty"`
+	SudoNoninteractive bool                   `json:"sudo_noninteractive,omitempty"`
+	Distro             string                 `json:"distro,omitempty"`
+	DistroVersion      string                 `json:"distro_version,omitempty"`
+	InitSystem         string                 `json:"init_system,omitempty"`
+	ViaSession         SessionID              `json:"via_session,omitempty"`
+	HostKeyType        string                 `json:"host_key_type,omitempty"`
+	HostKeyFingerprint string                 `json:"host_key_fingerprint,omitempty"`
+	Rebooting          bool                   `json:"rebooting,omitempty"`
+	Defaults           config.SessionDefaults `json:"-"` // ssh_execute defaults; holds variable values
 }
 
 // Connection wraps an SSH client with metadata.
 type Connection struct {
 	mu           sync.RWMutex
 	ID           SessionID
+	Owner        string // client namespace the session belongs to ("" = shared)
 	Client       *ssh.Client
 	Host         string
 	Port         int
@@ -45,28 +65,203 @@ type Connection struct {
 	CommandCount int
 	Connected    bool
 	RemoteInfo   RemoteInfo
+	IdleTimeout  time.Duration          // idle period after which the client is closed (0 = pool default)
+	SFTPTimeout  time.Duration          // max wait for any one SFTP reply (0 = none)
+	ExpiresAt    time.Time              // when the session is closed for good (zero = no max lifetime)
+	Defaults     config.SessionDefaults // ssh_execute defaults for an unset working_dir, shell and environment
+
+	// Host key presented on the last connect.
+	HostKeyType        string
+	HostKeyFingerprint string
+
+	// Usage statistics, updated by tool handlers.
+	FailedCommands  int
+	ActiveCommands  int           // commands started but not yet recorded
+	CommandTime     time.Duration // total wall time spent in executed commands
+	BytesUploaded   int64
+	BytesDownloaded int64
+	LastError       string
+
 	clientConfig *ssh.ClientConfig // stored for auto-reconnect (no raw password)
 	addr         string            // stored for auto-reconnect
+	dial         dialFunc          // stored for auto-reconnect (nil = direct TCP)
+	via          SessionID         // jump session the connection is tunneled through ("" = none)
 	ready        chan struct{}     // closed when connection attempt completes
 	connectErr   error             // non-nil if the connection attempt failed
 	reconnectMu  sync.Mutex        // serializes auto-reconnect attempts
+	history      *CommandHistory   // pool-wide command history (nil = not recorded)
+	workspaces   []string          // scratch directories removed when the session closes
+	agentClient  *ssh.Client       // client the local ssh-agent is forwarded over (nil = none yet)
+	rebooting    time.Time         // when a reboot was started (zero = not rebooting)
 }
 
 // Pool manages a thread-safe pool of SSH connections.
 type Pool struct {
-	mu    sync.RWMutex
-	conns map[SessionID]*Connection
-	auth  *AuthDiscovery
-	cfg   *config.SSHConfig
+	mu            sync.RWMutex
+	conns         map[poolKey]*Connection
+	auth          *AuthDiscovery
+	cfg           *config.SSHConfig
+	idleOverrides []idleOverride
+	defaultRules  []defaultsRule
+	iapRules      []iapRule
+	proxyRules    []proxyRule
+	netProxy      *url.URL // --ssh-proxy (nil = direct)
+	netProxyRules []netProxyRule
+	history       *CommandHistory
+	onIdleClose   func()
+	onExpire      func(owner string, id SessionID)
+}
+
+// poolKey identifies a connection in the pool. The same user@host:port has a
+// separate connection per owner, so one client can never reuse another
+// client's authenticated connection.
+type poolKey struct {
+	owner string
+	id    SessionID
+}
+
+// idleOverride is a compiled per-host idle timeout override.
+type idleOverride struct {
+	re      *regexp.Regexp
+	timeout time.Duration
+}
+
+// defaultsRule is a compiled --host-defaults entry.
+type defaultsRule struct {
+	re       *regexp.Regexp
+	defaults config.SessionDefaults
 }
 
 // NewPool creates a new connection pool.
 func NewPool(cfg *config.SSHConfig, auth *AuthDiscovery) *Pool {
-	return &Pool{
-		conns: make(map[SessionID]*Connection),
-		auth:  auth,
-		cfg:   cfg,
+	p := &Pool{
+		conns:   make(map[poolKey]*Connection),
+		auth:    auth,
+		cfg:     cfg,
+		history: NewCommandHistory(defaultHistorySize),
+	}
+	for _, o := range cfg.HostIdleTimeouts {
+		re, err := regexp.Compile("(?i)^(?:" + o.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host idle time pattern %q: %v", o.Pattern, err)
+			continue
+		}
+		p.idleOverrides = append(p.idleOverrides, idleOverride{re: re, timeout: o.Timeout})
 	}
+	for _, h := range cfg.HostDefaults {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host defaults pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		p.defaultRules = append(p.defaultRules, defaultsRule{re: re, defaults: h.Defaults})
+	}
+	p.iapRules = compileIAPRules(cfg.HostIAP)
+	p.proxyRules = compileProxyRules(cfg.HostProxyCommands)
+	p.netProxyRules = compileNetProxyRules(cfg.HostProxies)
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			log.Printf("Ignoring invalid SSH proxy: %v", err)
+		} else {
+			p.netProxy = u
+		}
+	}
+	return p
+}
+
+// dialerFor returns how to reach params.Host, in order of precedence: a
+// matching --host-proxy-command, a matching --host-iap rule, ProxyCommand
+// from ssh_config, a matching --host-proxy rule or --ssh-proxy, or directly
+// over TCP.
+func (p *Pool) dialerFor(params ConnectParams) dialFunc {
+	for _, r := range p.proxyRules {
+		if r.re.MatchString(params.Host) {
+			return commandDialer(proxyCommandArgv(expandProxyCommand(r.command, params)))
+		}
+	}
+	if argv := p.iapCommand(params); argv != nil {
+		return commandDialer(argv)
+	}
+	if params.ProxyCommand != "" {
+		return commandDialer(proxyCommandArgv(expandProxyCommand(params.ProxyCommand, params)))
+	}
+	if u := p.netProxyFor(params.Host); u != nil {
+		return proxyDialer(u, params.Addresses)
+	}
+	return tcpDialer(p.cfg.DialAttemptTimeout, params.Addresses)
+}
+
+// LookupTarget resolves params.Host for host filtering. It returns nil for
+// IP literals and for hosts reached through a jump session or a helper
+// command, which resolve the name themselves. A name that does not resolve is an error for direct
+// dials, but not behind an outbound proxy, which may know names this host
+// doesn't. Callers pass the result as ConnectParams.Addresses so the checked
+// addresses are the ones dialed.
+func (p *Pool) LookupTarget(ctx context.Context, params ConnectParams) ([]net.IP, error) {
+	if net.ParseIP(params.Host) != nil || params.ViaSession != "" || params.ProxyCommand != "" || p.iapCommand(params) != nil {
+		return nil, nil
+	}
+	for _, r := range p.proxyRules {
+		if r.re.MatchString(params.Host) {
+			return nil, nil
+		}
+	}
+	if p.cfg.ConnectionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.ConnectionTimeout)
+		defer cancel()
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, params.Host)
+	if err != nil {
+		if p.netProxyFor(params.Host) != nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("resolve %s: %w", params.Host, err)
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// idleTimeoutFor returns the idle timeout for a new connection: an explicit
+// request wins, then the first matching per-host override, then MaxIdleTime.
+func (p *Pool) idleTimeoutFor(params ConnectParams) time.Duration {
+	if params.IdleTimeout > 0 {
+		return params.IdleTimeout
+	}
+	for _, o := range p.idleOverrides {
+		if o.re.MatchString(params.Host) {
+			return o.timeout
+		}
+	}
+	return p.cfg.MaxIdleTime
+}
+
+// defaultsFor returns the ssh_execute defaults for a new connection: those
+// of the first matching --host-defaults rule, with the requested ones
+// merged over them.
+func (p *Pool) defaultsFor(params ConnectParams) config.SessionDefaults {
+	var d config.SessionDefaults
+	for _, r := range p.defaultRules {
+		if r.re.MatchString(params.Host) {
+			d = r.defaults
+			break
+		}
+	}
+	return d.Merge(params.Defaults)
+}
+
+// lifetimeFor returns the max lifetime for a new connection: the global
+// MaxLifetime, shortened by an explicit request (0 = unlimited).
+func (p *Pool) lifetimeFor(params ConnectParams) time.Duration {
+	global := p.cfg.MaxLifetime
+	if params.MaxLifetime > 0 && (global <= 0 || params.MaxLifetime < global) {
+		return params.MaxLifetime
+	}
+	return global
 }
 
 // StartIdleCleanup starts a background goroutine that checks for idle connections.
@@ -80,6 +275,7 @@ func (p *Pool) StartIdleCleanup(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
+				p.expireSessions()
 				p.cleanupIdle()
 			}
 		}
@@ -90,7 +286,7 @@ func (p *Pool) cleanupIdle() {
 	p.mu.RLock()
 	var toClose []*Connection
 	var toCloseIDs []SessionID
-	for id, conn := range p.conns {
+	for key, conn := range p.conns {
 		// Skip pending connections (not yet ready).
 		select {
 		case <-conn.ready:
@@ -98,9 +294,13 @@ func (p *Pool) cleanupIdle() {
 			continue
 		}
 		conn.mu.RLock()
-		if conn.Connected && time.Since(conn.LastUsed) > p.cfg.MaxIdleTime {
+		idle := conn.IdleTimeout
+		if idle <= 0 {
+			idle = p.cfg.MaxIdleTime
+		}
+		if conn.Connected && time.Since(conn.LastUsed) > idle {
 			toClose = append(toClose, conn)
-			toCloseIDs = append(toCloseIDs, id)
+			toCloseIDs = append(toCloseIDs, key.id)
 		}
 		conn.mu.RUnlock()
 	}
@@ -116,6 +316,65 @@ func (p *Pool) cleanupIdle() {
 		}
 		conn.mu.Unlock()
 	}
+	if len(toClose) > 0 && p.onIdleClose != nil {
+		p.onIdleClose()
+	}
+}
+
+// expireSessions removes connections past their max lifetime. Unlike idle
+// connections they are not reconnected: the session is gone and the caller
+// has to ssh_connect again.
+func (p *Pool) expireSessions() {
+	now := time.Now()
+	p.mu.RLock()
+	var expired []poolKey
+	for key, conn := range p.conns {
+		select {
+		case <-conn.ready:
+		default:
+			continue
+		}
+		if conn.expired(now) {
+			expired = append(expired, key)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, key := range expired {
+		p.expire(key)
+	}
+	if len(expired) > 0 && p.onIdleClose != nil {
+		p.onIdleClose()
+	}
+}
+
+// expire closes and removes a session that reached its max lifetime.
+func (p *Pool) expire(key poolKey) {
+	if err := p.remove(key); err != nil {
+		return // already removed
+	}
+	log.Printf("Session %s reached its max lifetime, closed", key.id)
+	if p.onExpire != nil {
+		p.onExpire(key.owner, key.id)
+	}
+}
+
+// History returns the pool's recent command history.
+func (p *Pool) History() *CommandHistory {
+	return p.history
+}
+
+// OnIdleClose sets a function called after the idle cleanup closes
+// connections. It must be set before StartIdleCleanup.
+func (p *Pool) OnIdleClose(f func()) {
+	p.onIdleClose = f
+}
+
+// OnExpire sets a function called with each session closed for reaching
+// its max lifetime, e.g. to close its terminals and tunnels. It must be set
+// before StartIdleCleanup.
+func (p *Pool) OnExpire(f func(owner string, id SessionID)) {
+	p.onExpire = f
 }
 
 // MakeSessionID constructs a SessionID from user, host, and port.
@@ -129,12 +388,14 @@ func MakeSessionID(user, host string, port int) SessionID {
 // to become ready instead of returning "session not found".
 func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, error) {
 	id := MakeSessionID(params.User, params.Host, params.Port)
+	key := poolKey{owner: OwnerFrom(ctx), id: id}
 
 	// Check for existing connection (alive, dead, or pending).
 	p.mu.RLock()
-	existing, exists := p.conns[id]
+	existing, exists := p.conns[key]
 	p.mu.RUnlock()
 
+	var workspaces []string // of a dead connection this one replaces
 	if exists {
 		// Wait for any pending connection attempt to complete first.
 		select {
@@ -146,10 +407,14 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 		if existing.connectErr != nil {
 			// Previous attempt failed; remove and retry below.
 			p.mu.Lock()
-			if cur, ok := p.conns[id]; ok && cur == existing {
-				delete(p.conns, id)
+			if cur, ok := p.conns[key]; ok && cur == existing {
+				delete(p.conns, key)
 			}
 			p.mu.Unlock()
+		} else if existing.expired(time.Now()) {
+			// Past its max lifetime: close it (and its terminals and
+			// tunnels) and connect afresh below.
+			p.expire(key)
 		} else {
 			existing.mu.RLock()
 			alive := existing.Connected && p.isAlive(existing.Client)
@@ -157,13 +422,18 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 			if alive {
 				existing.mu.Lock()
 				existing.LastUsed = time.Now()
+				if params.IdleTimeout > 0 {
+					existing.IdleTimeout = params.IdleTimeout
+				}
+				existing.Defaults = existing.Defaults.Merge(params.Defaults)
+				existing.shortenLifetime(params.MaxLifetime)
 				existing.mu.Unlock()
 				return id, nil
 			}
 			// Dead connection, remove and reconnect.
 			p.mu.Lock()
-			if cur, ok := p.conns[id]; ok && cur == existing {
-				delete(p.conns, id)
+			if cur, ok := p.conns[key]; ok && cur == existing {
+				delete(p.conns, key)
 			}
 			p.mu.Unlock()
 			existing.mu.Lock()
@@ -171,6 +441,7 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 				existing.Client.Close()
 				existing.Client = nil
 			}
+			workspaces = existing.workspaces
 			existing.mu.Unlock()
 		}
 	}
@@ -184,18 +455,25 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 
 	// Create a pending connection reservation before dialing.
 	pending := &Connection{
-		ID:    id,
-		Host:  params.Host,
-		Port:  params.Port,
-		User:  params.User,
-		ready: make(chan struct{}),
+		ID:          id,
+		Owner:       key.owner,
+		Host:        params.Host,
+		Port:        params.Port,
+		User:        params.User,
+		IdleTimeout: p.idleTimeoutFor(params),
+		Defaults:    p.defaultsFor(params),
+		SFTPTimeout: p.cfg.SFTPTimeout,
+		via:         params.ViaSession,
+		ready:       make(chan struct{}),
+		history:     p.history,
+		workspaces:  workspaces,
 	}
 
 	p.mu.Lock()
 
 	// Enforce max connections limit (count only active connections).
 	if p.cfg.MaxConnections > 0 {
-		if _, replacing := p.conns[id]; !replacing {
+		if _, replacing := p.conns[key]; !replacing {
 			activeCount := 0
 			for _, c := range p.conns {
 				c.mu.RLock()
@@ -213,7 +491,7 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 	}
 
 	// Check if another goroutine placed a reservation while we were building config.
-	if existing, exists := p.conns[id]; exists {
+	if existing, exists := p.conns[key]; exists {
 		p.mu.Unlock()
 
 		// Wait for the other attempt to finish.
@@ -227,9 +505,14 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 			existing.mu.RLock()
 			alive := existing.Connected && p.isAlive(existing.Client)
 			existing.mu.RUnlock()
-			if alive {
+			if alive && !existing.expired(time.Now()) {
 				existing.mu.Lock()
 				existing.LastUsed = time.Now()
+				if params.IdleTimeout > 0 {
+					existing.IdleTimeout = params.IdleTimeout
+				}
+				existing.Defaults = existing.Defaults.Merge(params.Defaults)
+				existing.shortenLifetime(params.MaxLifetime)
 				existing.mu.Unlock()
 				return id, nil
 			}
@@ -237,15 +520,15 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 
 		// Failed or dead — remove and re-acquire lock to place our reservation.
 		p.mu.Lock()
-		if cur, ok := p.conns[id]; ok && cur == existing {
-			delete(p.conns, id)
+		if cur, ok := p.conns[key]; ok && cur == existing {
+			delete(p.conns, key)
 			existing.mu.Lock()
 			if existing.Client != nil {
 				existing.Client.Close()
 				existing.Client = nil
 			}
 			existing.mu.Unlock()
-		} else if cur, ok := p.conns[id]; ok && cur != pending {
+		} else if cur, ok := p.conns[key]; ok && cur != pending {
 			// Yet another goroutine beat us; give up and let caller retry.
 			p.mu.Unlock()
 			close(pending.ready)
@@ -254,17 +537,24 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 	}
 
 	// Place our pending reservation in the pool.
-	p.conns[id] = pending
+	p.conns[key] = pending
 	p.mu.Unlock()
 
+	// Reconnects reuse clientConfig, so they update the recorded key too.
+	clientConfig.HostKeyCallback = pending.recordHostKey(clientConfig.HostKeyCallback)
+
 	// Dial without holding the pool lock.
-	client, err := ssh.Dial("tcp", addr, clientConfig)
+	dial := p.dialerFor(params)
+	if params.ViaSession != "" {
+		dial = p.jumpDialer(key.owner, params.ViaSession)
+	}
+	client, err := dial(addr, clientConfig)
 	if err != nil {
 		pending.connectErr = fmt.Errorf("SSH dial %s: %w", addr, err)
 		// Remove the failed reservation from the pool.
 		p.mu.Lock()
-		if cur, ok := p.conns[id]; ok && cur == pending {
-			delete(p.conns, id)
+		if cur, ok := p.conns[key]; ok && cur == pending {
+			delete(p.conns, key)
 		}
 		p.mu.Unlock()
 		close(pending.ready)
@@ -277,8 +567,12 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 	pending.Connected = true
 	pending.ConnectedAt = now
 	pending.LastUsed = now
+	if lifetime := p.lifetimeFor(params); lifetime > 0 {
+		pending.ExpiresAt = now.Add(lifetime)
+	}
 	pending.clientConfig = clientConfig
 	pending.addr = addr
+	pending.dial = dial
 	pending.mu.Unlock()
 
 	// Detect remote OS, architecture, and shell (best-effort, never blocks connection).
@@ -294,25 +588,53 @@ func (p *Pool) Connect(ctx context.Context, params ConnectParams) (SessionID, er
 // GetConnection retrieves a connection by ID, attempting auto-reconnect if dead.
 // If a connection attempt is in progress, it waits for it to complete.
 func (p *Pool) GetConnection(ctx context.Context, id SessionID) (*Connection, error) {
+	conn, _, err := p.GetConnectionStatus(ctx, id)
+	return conn, err
+}
+
+// GetConnectionStatus is GetConnection that also reports whether this call
+// had to re-establish the connection.
+func (p *Pool) GetConnectionStatus(ctx context.Context, id SessionID) (*Connection, bool, error) {
+	key := poolKey{owner: OwnerFrom(ctx), id: id}
 	p.mu.RLock()
-	conn, exists := p.conns[id]
+	conn, exists := p.conns[key]
 	p.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("session %s not found", id)
+		return nil, false, fmt.Errorf("session %s not found", id)
 	}
 
 	// Wait for pending connection to complete.
 	select {
 	case <-conn.ready:
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, false, ctx.Err()
 	}
 
 	if conn.connectErr != nil {
-		return nil, fmt.Errorf("session %s connection failed: %w", id, conn.connectErr)
+		return nil, false, fmt.Errorf("session %s connection failed: %w", id, conn.connectErr)
+	}
+
+	if conn.expired(time.Now()) {
+		p.expire(key)
+		return nil, false, fmt.Errorf("session %s expired (max session lifetime reached); connect again with ssh_connect", id)
+	}
+
+	if since := conn.RebootingSince(); !since.IsZero() {
+		return nil, false, fmt.Errorf("session %s is rebooting (since %s); try again once ssh_reboot returns", id, since.Format(time.RFC3339))
+	}
+
+	reconnected, err := p.revive(conn)
+	if err != nil {
+		return nil, false, err
 	}
+	return conn, reconnected, nil
+}
 
+// revive checks that conn's client is alive and, if not, dials the host
+// again with the saved client config. It reports whether it reconnected.
+func (p *Pool) revive(conn *Connection) (bool, error) {
+	id := conn.ID
 	conn.mu.RLock()
 	alive := conn.Connected && p.isAlive(conn.Client)
 	conn.mu.RUnlock()
@@ -321,7 +643,7 @@ func (p *Pool) GetConnection(ctx context.Context, id SessionID) (*Connection, er
 		conn.mu.Lock()
 		conn.LastUsed = time.Now()
 		conn.mu.Unlock()
-		return conn, nil
+		return false, nil
 	}
 
 	// Serialize auto-reconnect attempts for this connection.
@@ -337,7 +659,7 @@ func (p *Pool) GetConnection(ctx context.Context, id SessionID) (*Connection, er
 		conn.mu.Lock()
 		conn.LastUsed = time.Now()
 		conn.mu.Unlock()
-		return conn, nil
+		return false, nil
 	}
 
 	// Auto-reconnect using stored clientConfig (no raw credentials needed).
@@ -351,15 +673,19 @@ func (p *Pool) GetConnection(ctx context.Context, id SessionID) (*Connection, er
 	conn.Connected = false
 	savedConfig := conn.clientConfig
 	savedAddr := conn.addr
+	dial := conn.dial
 	conn.mu.Unlock()
 
 	if savedConfig == nil {
-		return nil, fmt.Errorf("cannot reconnect %s: no saved client config", id)
+		return false, fmt.Errorf("cannot reconnect %s: no saved client config", id)
+	}
+	if dial == nil {
+		dial = tcpDialer(p.cfg.DialAttemptTimeout, nil)
 	}
 
-	client, err := ssh.Dial("tcp", savedAddr, savedConfig)
+	client, err := dial(savedAddr, savedConfig)
 	if err != nil {
-		return nil, fmt.Errorf("reconnect SSH dial %s: %w", savedAddr, err)
+		return false, fmt.Errorf("reconnect SSH dial %s: %w", savedAddr, err)
 	}
 
 	conn.mu.Lock()
@@ -369,28 +695,52 @@ func (p *Pool) GetConnection(ctx context.Context, id SessionID) (*Connection, er
 	conn.mu.Unlock()
 
 	log.Printf("Reconnected to %s", id)
-	return conn, nil
+	return true, nil
+}
+
+// Disconnect closes and removes one of the caller's connections.
+func (p *Pool) Disconnect(ctx context.Context, id SessionID) error {
+	return p.remove(poolKey{owner: OwnerFrom(ctx), id: id})
 }
 
-// Disconnect closes and removes a connection.
+// CloseOwner closes and removes all connections of owner, e.g. when its
+// client goes away, and returns their session IDs.
+func (p *Pool) CloseOwner(owner string) []SessionID {
+	p.mu.RLock()
+	var ids []SessionID
+	for key := range p.conns {
+		if key.owner == owner {
+			ids = append(ids, key.id)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, id := range ids {
+		p.remove(poolKey{owner: owner, id: id})
+	}
+	return ids
+}
+
+// remove closes and removes a connection.
 // If a connection attempt is still pending, it waits for it to complete first.
-func (p *Pool) Disconnect(id SessionID) error {
+func (p *Pool) remove(key poolKey) error {
 	p.mu.Lock()
-	conn, exists := p.conns[id]
+	conn, exists := p.conns[key]
 	if !exists {
 		p.mu.Unlock()
-		return fmt.Errorf("session %s not found", id)
+		return fmt.Errorf("session %s not found", key.id)
 	}
-	delete(p.conns, id)
+	delete(p.conns, key)
 	p.mu.Unlock()
 
 	// Wait for pending connection to complete before closing (with timeout).
 	select {
 	case <-conn.ready:
 	case <-time.After(10 * time.Second):
-		log.Printf("Timeout waiting for pending connection %s during disconnect", id)
+		log.Printf("Timeout waiting for pending connection %s during disconnect", key.id)
 	}
 
+	p.removeWorkspaces(conn)
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
 
@@ -401,14 +751,18 @@ func (p *Pool) Disconnect(id SessionID) error {
 	return nil
 }
 
-// ListConnections returns info about all connections.
+// ListConnections returns info about the caller's connections.
 // Pending connections (still being established) are included with Connected=false.
-func (p *Pool) ListConnections() []ConnectionInfo {
+func (p *Pool) ListConnections(ctx context.Context) []ConnectionInfo {
+	owner := OwnerFrom(ctx)
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	infos := make([]ConnectionInfo, 0, len(p.conns))
-	for _, conn := range p.conns {
+	for key, conn := range p.conns {
+		if key.owner != owner {
+			continue
+		}
 		// Check if connection is still pending.
 		select {
 		case <-conn.ready:
@@ -422,12 +776,28 @@ func (p *Pool) ListConnections() []ConnectionInfo {
 				ConnectedAt:        conn.ConnectedAt,
 				LastUsed:           conn.LastUsed,
 				CommandCount:       conn.CommandCount,
+				FailedCommands:     conn.FailedCommands,
+				ActiveCommands:     conn.ActiveCommands,
+				CommandTime:        conn.CommandTime,
+				BytesUploaded:      conn.BytesUploaded,
+				BytesDownloaded:    conn.BytesDownloaded,
+				LastError:          conn.LastError,
+				IdleTimeout:        conn.IdleTimeout,
+				Defaults:           conn.Defaults,
+				ExpiresAt:          conn.ExpiresAt,
 				Connected:          conn.Connected,
 				OS:                 conn.RemoteInfo.OS,
 				Arch:               conn.RemoteInfo.Arch,
 				Shell:              conn.RemoteInfo.Shell,
 				PackageManager:     conn.RemoteInfo.PackageManager,
 				SudoNoninteractive: conn.RemoteInfo.SudoNoninteractive,
+				Distro:             conn.RemoteInfo.Distro,
+				DistroVersion:      conn.RemoteInfo.DistroVersion,
+				InitSystem:         conn.RemoteInfo.InitSystem,
+				ViaSession:         conn.via,
+				HostKeyType:        conn.HostKeyType,
+				HostKeyFingerprint: conn.HostKeyFingerprint,
+				Rebooting:          !conn.rebooting.IsZero(),
 			})
 			conn.mu.RUnlock()
 		default:
@@ -447,19 +817,20 @@ func (p *Pool) ListConnections() []ConnectionInfo {
 // CloseAll closes all connections (for graceful shutdown).
 func (p *Pool) CloseAll() {
 	p.mu.Lock()
-	conns := make(map[SessionID]*Connection, len(p.conns))
-	for id, conn := range p.conns {
-		conns[id] = conn
-		delete(p.conns, id)
+	conns := make(map[poolKey]*Connection, len(p.conns))
+	for key, conn := range p.conns {
+		conns[key] = conn
+		delete(p.conns, key)
 	}
 	p.mu.Unlock()
 
-	for id, conn := range conns {
+	for key, conn := range conns {
 		select {
 		case <-conn.ready:
 		case <-time.After(10 * time.Second):
-			log.Printf("Timeout waiting for pending connection %s during shutdown", id)
+			log.Printf("Timeout waiting for pending connection %s during shutdown", key.id)
 		}
+		p.removeWorkspaces(conn)
 		conn.mu.Lock()
 		conn.Connected = false
 		if conn.Client != nil {
@@ -486,11 +857,91 @@ func (c *Connection) GetRemoteInfo() RemoteInfo {
 	return c.RemoteInfo
 }
 
+// GetExpiresAt returns when the session reaches its max lifetime (zero if
+// it has none).
+func (c *Connection) GetExpiresAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ExpiresAt
+}
+
+// GetDefaults returns the session's ssh_execute defaults.
+func (c *Connection) GetDefaults() config.SessionDefaults {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Defaults
+}
+
+// expired reports whether the session is past its max lifetime at now.
+func (c *Connection) expired(now time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
+// shortenLifetime limits the session to d from when it first connected,
+// keeping an earlier expiry. The caller must hold c.mu.
+func (c *Connection) shortenLifetime(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if exp := c.ConnectedAt.Add(d); c.ExpiresAt.IsZero() || exp.Before(c.ExpiresAt) {
+		c.ExpiresAt = exp
+	}
+}
+
 // IncrementCommandCount increments the command counter for a connection.
+// The command counts as running until its RecordCommandResult.
 func (c *Connection) IncrementCommandCount() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.CommandCount++
+	c.ActiveCommands++
+}
+
+// RecordCommandResult adds d to the total command wall time and adds command
+// to the pool's history. A non-empty failure marks the command as failed and
+// becomes the connection's last error.
+func (c *Connection) RecordCommandResult(command string, d time.Duration, failure string) {
+	c.mu.Lock()
+	if c.ActiveCommands > 0 {
+		c.ActiveCommands--
+	}
+	c.CommandTime += d
+	if failure != "" {
+		c.FailedCommands++
+		c.LastError = failure
+	}
+	id, owner, history := c.ID, c.Owner, c.history
+	c.mu.Unlock()
+
+	if history != nil {
+		history.Add(CommandRecord{Time: time.Now(), Owner: owner, SessionID: id, Command: command, Duration: d, Failure: failure})
+	}
+}
+
+// AddBytesUploaded adds n to the uploaded byte counter.
+func (c *Connection) AddBytesUploaded(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.BytesUploaded += n
+}
+
+// AddBytesDownloaded adds n to the downloaded byte counter.
+func (c *Connection) AddBytesDownloaded(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.BytesDownloaded += n
+}
+
+// SetLastError records err as the most recent error seen on this connection.
+func (c *Connection) SetLastError(err error) {
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.LastError = err.Error()
 }
 
 func (p *Pool) isAlive(client *ssh.Client) bool {
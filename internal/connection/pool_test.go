@@ -3,6 +3,7 @@ package connection
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -26,7 +27,7 @@ func newTestPool() *Pool {
 func TestPool_ListConnections_Empty(t *testing.T) {
 	pool := newTestPool()
 
-	conns := pool.ListConnections()
+	conns := pool.ListConnections(context.Background())
 	if len(conns) != 0 {
 		t.Errorf("expected empty pool, got %d connections", len(conns))
 	}
@@ -35,7 +36,7 @@ func TestPool_ListConnections_Empty(t *testing.T) {
 func TestPool_Disconnect_NotFound(t *testing.T) {
 	pool := newTestPool()
 
-	err := pool.Disconnect(SessionID("nonexistent"))
+	err := pool.Disconnect(context.Background(), SessionID("nonexistent"))
 	if err == nil {
 		t.Error("expected error for non-existent session")
 	}
@@ -57,6 +58,14 @@ func TestConnection_IncrementCommandCount(t *testing.T) {
 	if conn.CommandCount != 3 {
 		t.Errorf("expected command count 3, got %d", conn.CommandCount)
 	}
+	if conn.ActiveCommands != 3 {
+		t.Errorf("expected 3 active commands, got %d", conn.ActiveCommands)
+	}
+	conn.RecordCommandResult("true", time.Millisecond, "")
+	conn.RecordCommandResult("false", time.Millisecond, "command exited with code 1")
+	if conn.ActiveCommands != 1 {
+		t.Errorf("expected 1 active command after two results, got %d", conn.ActiveCommands)
+	}
 }
 
 func TestPool_GetConnection_WaitsPendingConnect(t *testing.T) {
@@ -73,7 +82,7 @@ func TestPool_GetConnection_WaitsPendingConnect(t *testing.T) {
 	}
 
 	pool.mu.Lock()
-	pool.conns[id] = pending
+	pool.conns[poolKey{id: id}] = pending
 	pool.mu.Unlock()
 
 	ctx := context.Background()
@@ -126,7 +135,7 @@ func TestPool_GetConnection_PendingConnectFails(t *testing.T) {
 	}
 
 	pool.mu.Lock()
-	pool.conns[id] = pending
+	pool.conns[poolKey{id: id}] = pending
 	pool.mu.Unlock()
 
 	ctx := context.Background()
@@ -167,7 +176,7 @@ func TestPool_GetConnection_ContextCancelled(t *testing.T) {
 	}
 
 	pool.mu.Lock()
-	pool.conns[id] = pending
+	pool.conns[poolKey{id: id}] = pending
 	pool.mu.Unlock()
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -206,10 +215,10 @@ func TestPool_ListConnections_PendingShownAsDisconnected(t *testing.T) {
 		ready: make(chan struct{}),
 	}
 	pool.mu.Lock()
-	pool.conns[pending.ID] = pending
+	pool.conns[poolKey{id: pending.ID}] = pending
 	pool.mu.Unlock()
 
-	infos := pool.ListConnections()
+	infos := pool.ListConnections(context.Background())
 	if len(infos) != 1 {
 		t.Fatalf("expected 1 connection, got %d", len(infos))
 	}
@@ -271,12 +280,12 @@ func TestPool_Disconnect_WaitsPending(t *testing.T) {
 		ready: make(chan struct{}),
 	}
 	pool.mu.Lock()
-	pool.conns[id] = pending
+	pool.conns[poolKey{id: id}] = pending
 	pool.mu.Unlock()
 
 	done := make(chan error, 1)
 	go func() {
-		done <- pool.Disconnect(id)
+		done <- pool.Disconnect(context.Background(), id)
 	}()
 
 	// Verify Disconnect blocks while pending.
@@ -298,3 +307,314 @@ func TestPool_Disconnect_WaitsPending(t *testing.T) {
 		t.Fatal("Disconnect timed out after ready was signaled")
 	}
 }
+
+func TestConnection_UsageStatistics(t *testing.T) {
+	conn := &Connection{}
+	conn.RecordCommandResult("true", 2*time.Second, "")
+	conn.RecordCommandResult("false", 3*time.Second, "command exited with code 1")
+	conn.AddBytesUploaded(100)
+	conn.AddBytesUploaded(50)
+	conn.AddBytesDownloaded(42)
+
+	if conn.CommandTime != 5*time.Second {
+		t.Errorf("CommandTime = %v, want 5s", conn.CommandTime)
+	}
+	if conn.FailedCommands != 1 {
+		t.Errorf("FailedCommands = %d, want 1", conn.FailedCommands)
+	}
+	if conn.LastError != "command exited with code 1" {
+		t.Errorf("LastError = %q", conn.LastError)
+	}
+	if conn.BytesUploaded != 150 {
+		t.Errorf("BytesUploaded = %d, want 150", conn.BytesUploaded)
+	}
+	if conn.BytesDownloaded != 42 {
+		t.Errorf("BytesDownloaded = %d, want 42", conn.BytesDownloaded)
+	}
+
+	conn.SetLastError(nil)
+	if conn.LastError != "command exited with code 1" {
+		t.Errorf("SetLastError(nil) should not clear LastError, got %q", conn.LastError)
+	}
+	conn.SetLastError(fmt.Errorf("sftp failure"))
+	if conn.LastError != "sftp failure" {
+		t.Errorf("LastError = %q, want %q", conn.LastError, "sftp failure")
+	}
+}
+
+func TestPool_IdleTimeoutFor(t *testing.T) {
+	cfg := &config.SSHConfig{
+		KeySearchPaths:    []string{"/nonexistent"},
+		ConnectionTimeout: 5 * time.Second,
+		MaxIdleTime:       5 * time.Minute,
+		HostIdleTimeouts: []config.HostIdleTimeout{
+			{Pattern: `db\d+\.example\.com`, Timeout: 2 * time.Hour},
+		},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+
+	tests := []struct {
+		params ConnectParams
+		want   time.Duration
+	}{
+		{ConnectParams{Host: "web.example.com"}, 5 * time.Minute},
+		{ConnectParams{Host: "DB1.example.com"}, 2 * time.Hour},
+		{ConnectParams{Host: "db1.example.com.evil"}, 5 * time.Minute},
+		{ConnectParams{Host: "db1.example.com", IdleTimeout: time.Minute}, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := pool.idleTimeoutFor(tt.params); got != tt.want {
+			t.Errorf("idleTimeoutFor(%+v) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestPool_DefaultsFor(t *testing.T) {
+	cfg := &config.SSHConfig{
+		KeySearchPaths:    []string{"/nonexistent"},
+		ConnectionTimeout: 5 * time.Second,
+		HostDefaults: []config.HostDefaults{
+			{Pattern: `app-\d+`, Defaults: config.SessionDefaults{WorkingDir: "/srv/app", Env: map[string]string{"RAILS_ENV": "production"}}},
+			{Pattern: `app-.*`, Defaults: config.SessionDefaults{Shell: "zsh"}},
+		},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+
+	tests := []struct {
+		params ConnectParams
+		want   config.SessionDefaults
+	}{
+		{ConnectParams{Host: "web"}, config.SessionDefaults{}},
+		{ConnectParams{Host: "APP-1"}, config.SessionDefaults{WorkingDir: "/srv/app", Env: map[string]string{"RAILS_ENV": "production"}}},
+		{ConnectParams{Host: "app-x"}, config.SessionDefaults{Shell: "zsh"}},
+		{
+			ConnectParams{Host: "app-1", Defaults: config.SessionDefaults{WorkingDir: "/tmp", Env: map[string]string{"DEBUG": "1"}}},
+			config.SessionDefaults{WorkingDir: "/tmp", Env: map[string]string{"RAILS_ENV": "production", "DEBUG": "1"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := pool.defaultsFor(tt.params); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("defaultsFor(%+v) = %+v, want %+v", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestPool_ConnectExistingMergesDefaults(t *testing.T) {
+	addr, _ := startExecSSHServer(t)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	pool := newTestPool()
+	conn := &Connection{
+		ID:        "root@app:22",
+		Client:    client,
+		Connected: true,
+		Defaults:  config.SessionDefaults{WorkingDir: "/srv/app", Env: map[string]string{"A": "1"}},
+		ready:     make(chan struct{}),
+	}
+	close(conn.ready)
+	pool.conns[poolKey{id: conn.ID}] = conn
+
+	params := ConnectParams{Host: "app", Port: 22, User: "root", Defaults: config.SessionDefaults{Shell: "bash", Env: map[string]string{"B": "2"}}}
+	if _, err := pool.Connect(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	want := config.SessionDefaults{WorkingDir: "/srv/app", Shell: "bash", Env: map[string]string{"A": "1", "B": "2"}}
+	if got := conn.GetDefaults(); !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults after reconnect = %+v, want %+v", got, want)
+	}
+}
+
+func TestPool_CleanupIdle_PerConnectionTimeout(t *testing.T) {
+	pool := newTestPool()
+
+	mk := func(id SessionID, idle time.Duration, lastUsed time.Time) *Connection {
+		c := &Connection{
+			ID:          id,
+			Connected:   true,
+			LastUsed:    lastUsed,
+			IdleTimeout: idle,
+			ready:       make(chan struct{}),
+		}
+		close(c.ready)
+		pool.conns[poolKey{id: id}] = c
+		return c
+	}
+	tenMinAgo := time.Now().Add(-10 * time.Minute)
+	short := mk("short@host:22", 0, tenMinAgo)       // falls back to 5m pool default
+	long := mk("long@host:22", time.Hour, tenMinAgo) // custom keep-alive
+
+	pool.cleanupIdle()
+
+	if short.Connected {
+		t.Error("expected connection using default idle timeout to be closed")
+	}
+	if !long.Connected {
+		t.Error("expected connection with 1h idle timeout to stay connected")
+	}
+}
+
+func TestPool_LifetimeFor(t *testing.T) {
+	pool := newTestPool()
+	if got := pool.lifetimeFor(ConnectParams{}); got != 0 {
+		t.Errorf("no limits: got %v, want 0", got)
+	}
+	if got := pool.lifetimeFor(ConnectParams{MaxLifetime: time.Hour}); got != time.Hour {
+		t.Errorf("request only: got %v, want 1h", got)
+	}
+
+	pool.cfg.MaxLifetime = 8 * time.Hour
+	tests := []struct {
+		request, want time.Duration
+	}{
+		{0, 8 * time.Hour},
+		{time.Hour, time.Hour},
+		{24 * time.Hour, 8 * time.Hour}, // cannot extend the global limit
+	}
+	for _, tt := range tests {
+		if got := pool.lifetimeFor(ConnectParams{MaxLifetime: tt.request}); got != tt.want {
+			t.Errorf("lifetimeFor(%v) = %v, want %v", tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestConnection_ShortenLifetime(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	c := &Connection{ConnectedAt: start}
+	c.shortenLifetime(0)
+	if !c.ExpiresAt.IsZero() {
+		t.Fatalf("shortenLifetime(0) set ExpiresAt to %v", c.ExpiresAt)
+	}
+	c.shortenLifetime(3 * time.Hour)
+	if want := start.Add(3 * time.Hour); !c.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", c.ExpiresAt, want)
+	}
+	c.shortenLifetime(5 * time.Hour) // longer: keeps the earlier expiry
+	if want := start.Add(3 * time.Hour); !c.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", c.ExpiresAt, want)
+	}
+	if c.expired(time.Now()) {
+		t.Error("expected session not to be expired yet")
+	}
+	if !c.expired(start.Add(3 * time.Hour)) {
+		t.Error("expected session to be expired at ExpiresAt")
+	}
+}
+
+func TestPool_ExpireSessions(t *testing.T) {
+	pool := newTestPool()
+	var closed []SessionID
+	pool.OnExpire(func(_ string, id SessionID) { closed = append(closed, id) })
+
+	mk := func(id SessionID, expiresAt time.Time) {
+		c := &Connection{ID: id, Connected: true, LastUsed: time.Now(), ExpiresAt: expiresAt, ready: make(chan struct{})}
+		close(c.ready)
+		pool.conns[poolKey{id: id}] = c
+	}
+	mk("old@host:22", time.Now().Add(-time.Second))
+	mk("young@host:22", time.Now().Add(time.Hour))
+	mk("forever@host:22", time.Time{})
+
+	pool.expireSessions()
+
+	if len(closed) != 1 || closed[0] != "old@host:22" {
+		t.Fatalf("expired sessions = %v, want [old@host:22]", closed)
+	}
+	if _, ok := pool.conns[poolKey{id: "old@host:22"}]; ok {
+		t.Error("expected expired session to be removed from the pool")
+	}
+	if len(pool.conns) != 2 {
+		t.Errorf("pool has %d sessions, want 2", len(pool.conns))
+	}
+}
+
+func TestPool_GetConnection_Expired(t *testing.T) {
+	pool := newTestPool()
+	c := &Connection{ID: "old@host:22", Connected: true, ExpiresAt: time.Now().Add(-time.Minute), ready: make(chan struct{})}
+	close(c.ready)
+	pool.conns[poolKey{id: c.ID}] = c
+
+	_, err := pool.GetConnection(context.Background(), c.ID)
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("GetConnection error = %v, want expired error", err)
+	}
+	if _, ok := pool.conns[poolKey{id: c.ID}]; ok {
+		t.Error("expected expired session to be removed from the pool")
+	}
+}
+
+func TestPool_OwnersIsolated(t *testing.T) {
+	pool := newTestPool()
+	id := SessionID("user@host:22")
+	for _, owner := range []string{"client-a", "client-b"} {
+		c := &Connection{ID: id, Owner: owner, Connected: true, ready: make(chan struct{})}
+		close(c.ready)
+		pool.conns[poolKey{owner: owner, id: id}] = c
+	}
+	ctxA := WithOwner(context.Background(), "client-a")
+	ctxC := WithOwner(context.Background(), "client-c")
+
+	if got := pool.ListConnections(ctxA); len(got) != 1 || got[0].SessionID != id {
+		t.Errorf("ListConnections(client-a) = %+v, want only %s", got, id)
+	}
+	if got := pool.ListConnections(ctxC); len(got) != 0 {
+		t.Errorf("ListConnections(client-c) = %+v, want none", got)
+	}
+	if got := pool.ListConnections(context.Background()); len(got) != 0 {
+		t.Errorf("ListConnections(shared) = %+v, want none", got)
+	}
+	if _, err := pool.GetConnection(ctxC, id); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetConnection(client-c) error = %v, want not found", err)
+	}
+	if err := pool.Disconnect(ctxC, id); err == nil {
+		t.Error("expected Disconnect by another owner to fail")
+	}
+
+	if ids := pool.CloseOwner("client-a"); len(ids) != 1 || ids[0] != id {
+		t.Errorf("CloseOwner(client-a) = %v, want [%s]", ids, id)
+	}
+	if _, ok := pool.conns[poolKey{owner: "client-b", id: id}]; !ok {
+		t.Error("expected client-b's session to survive CloseOwner(client-a)")
+	}
+	if len(pool.conns) != 1 {
+		t.Errorf("pool has %d sessions, want 1", len(pool.conns))
+	}
+}
+
+func TestPool_LookupTarget(t *testing.T) {
+	cfg := &config.SSHConfig{
+		ConnectionTimeout: 5 * time.Second,
+		HostIAP:           []config.HostIAP{{Pattern: "gce-.*", Zone: "us-central1-a"}},
+		HostProxies:       []config.HostProxy{{Pattern: ".*\\.invalid", URL: "socks5h://jump:1080"}},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+	ctx := context.Background()
+
+	ips, err := pool.LookupTarget(ctx, ConnectParams{Host: "localhost"})
+	if err != nil || len(ips) == 0 {
+		t.Fatalf("LookupTarget(localhost) = %v, %v", ips, err)
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			t.Errorf("localhost resolved to %s", ip)
+		}
+	}
+
+	for _, params := range []ConnectParams{
+		{Host: "10.0.0.1"},
+		{Host: "gce-1"},
+		{Host: "web", ProxyCommand: "nc %h %p"},
+		{Host: "internal-only.invalid"}, // the proxy resolves it
+	} {
+		if ips, err := pool.LookupTarget(ctx, params); ips != nil || err != nil {
+			t.Errorf("LookupTarget(%+v) = %v, %v; want nil, nil", params, ips, err)
+		}
+	}
+
+	direct := NewPool(&config.SSHConfig{ConnectionTimeout: 5 * time.Second}, nil)
+	if _, err := direct.LookupTarget(ctx, ConnectParams{Host: "no-such-host.invalid"}); err == nil {
+		t.Error("expected error for an unresolvable direct host")
+	}
+}
@@ -0,0 +1,82 @@
+package connection
+
+import (
+	"log"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// proxyRule is a compiled --host-proxy-command entry.
+type proxyRule struct {
+	re      *regexp.Regexp
+	command string
+}
+
+func compileProxyRules(hosts []config.HostProxyCommand) []proxyRule {
+	var rules []proxyRule
+	for _, h := range hosts {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host proxy command pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		rules = append(rules, proxyRule{re: re, command: h.Command})
+	}
+	return rules
+}
+
+// expandProxyCommand substitutes the OpenSSH tokens %h (host), %p (port),
+// %r (user) and %% in command. Substituted values are shell-quoted because
+// the host and user come from tool input.
+func expandProxyCommand(command string, params ConnectParams) string {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		if command[i] != '%' || i+1 == len(command) {
+			b.WriteByte(command[i])
+			continue
+		}
+		i++
+		switch command[i] {
+		case 'h':
+			b.WriteString(shellQuoteArg(params.Host))
+		case 'p':
+			b.WriteString(strconv.Itoa(params.Port))
+		case 'r':
+			b.WriteString(shellQuoteArg(params.User))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(command[i])
+		}
+	}
+	return b.String()
+}
+
+// proxyCommandArgv runs command through the platform shell, as OpenSSH does.
+func proxyCommandArgv(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// shellQuoteArg quotes s for sh unless it only contains characters that are
+// safe unquoted, keeping common commands readable in logs and errors.
+func shellQuoteArg(s string) string {
+	safe := s != ""
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-:@/[]", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,80 @@
+package connection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestExpandProxyCommand(t *testing.T) {
+	params := ConnectParams{Host: "node-1.example.com", Port: 2222, User: "deploy"}
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"tsh proxy ssh %r@%h:%p", "tsh proxy ssh deploy@node-1.example.com:2222"},
+		{"nc %h %p", "nc node-1.example.com 2222"},
+		{"echo 100%% %x", "echo 100% %x"},
+		{"trailing %", "trailing %"},
+	}
+	for _, tt := range tests {
+		if got := expandProxyCommand(tt.command, params); got != tt.want {
+			t.Errorf("expandProxyCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	evil := ConnectParams{Host: "x; rm -rf ~", Port: 22, User: "o'neil"}
+	if got, want := expandProxyCommand("nc %h %p # %r", evil), `nc 'x; rm -rf ~' 22 # 'o'\''neil'`; got != want {
+		t.Errorf("unsafe values not quoted: got %q, want %q", got, want)
+	}
+}
+
+func TestAuthDiscovery_ResolveHost_ProxyCommand(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	content := "Host teleport-*\n  ProxyCommand tsh proxy ssh %r@%h:%p\n\nHost direct\n  ProxyCommand none\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthDiscovery(&config.SSHConfig{ConfigPath: configPath, ConnectionTimeout: 30 * time.Second})
+
+	if got := auth.ResolveHost("teleport-db").ProxyCommand; got != "tsh proxy ssh %r@%h:%p" {
+		t.Errorf("unexpected ProxyCommand %q", got)
+	}
+	if got := auth.ResolveHost("direct").ProxyCommand; got != "" {
+		t.Errorf("expected ProxyCommand none to disable the proxy, got %q", got)
+	}
+}
+
+func TestPool_DialerFor_Precedence(t *testing.T) {
+	addr := startTestSSHServer(t)
+	t.Setenv("SSH_MCP_TEST_PROXY", addr)
+	helper := os.Args[0] + " -test.run=^TestHelperProxyProcess$"
+
+	cfg := &config.SSHConfig{
+		KeySearchPaths:    []string{"/nonexistent"},
+		ConnectionTimeout: 5 * time.Second,
+		MaxIdleTime:       5 * time.Minute,
+		HostProxyCommands: []config.HostProxyCommand{{Pattern: "via-flag", Command: helper}},
+		HostIAP:           []config.HostIAP{{Pattern: "via-.*", Zone: "us-central1-a"}},
+	}
+	pool := NewPool(cfg, NewAuthDiscovery(cfg))
+
+	// The flag rule beats both the IAP rule and ssh_config's ProxyCommand.
+	dial := pool.dialerFor(ConnectParams{Host: "via-flag", Port: 22, ProxyCommand: "false"})
+	client, err := dial("via-flag:22", testClientConfig())
+	if err != nil {
+		t.Fatalf("dial through --host-proxy-command: %v", err)
+	}
+	client.Close()
+
+	// ssh_config ProxyCommand applies when no flag rule matches.
+	dial = pool.dialerFor(ConnectParams{Host: "from-config", Port: 22, ProxyCommand: helper})
+	client, err = dial("from-config:22", testClientConfig())
+	if err != nil {
+		t.Fatalf("dial through ssh_config ProxyCommand: %v", err)
+	}
+	client.Close()
+}
@@ -0,0 +1,57 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BeginReboot marks the session as rebooting. Until EndReboot, GetConnection
+// fails at once for it instead of trying to reconnect to a host that is
+// going down; the caller watches the host with Revive.
+func (c *Connection) BeginReboot() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebooting = time.Now()
+}
+
+// EndReboot clears the mark set by BeginReboot.
+func (c *Connection) EndReboot() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rebooting = time.Time{}
+}
+
+// RebootingSince returns when BeginReboot was called, or the zero time if
+// the session is not rebooting.
+func (c *Connection) RebootingSince() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rebooting
+}
+
+// Revive is GetConnectionStatus for a session that is rebooting: it
+// checks the client and, if it is dead, dials the host once. A failed dial
+// is returned as an error and leaves the session in the pool, so the
+// caller can try again.
+func (p *Pool) Revive(ctx context.Context, id SessionID) (*Connection, bool, error) {
+	p.mu.RLock()
+	conn, exists := p.conns[poolKey{owner: OwnerFrom(ctx), id: id}]
+	p.mu.RUnlock()
+	if !exists {
+		return nil, false, fmt.Errorf("session %s not found", id)
+	}
+	select {
+	case <-conn.ready:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+	if conn.connectErr != nil {
+		return nil, false, fmt.Errorf("session %s connection failed: %w", id, conn.connectErr)
+	}
+	reconnected, err := p.revive(conn)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, reconnected, nil
+}
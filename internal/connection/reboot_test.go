@@ -0,0 +1,62 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPool_Rebooting(t *testing.T) {
+	addr, _ := startExecSSHServer(t)
+	cfg := &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	pool := newTestPool()
+	// A session whose client is gone, as after the host went down.
+	c := &Connection{ID: "root@host:22", ready: make(chan struct{}), addr: addr, clientConfig: cfg}
+	close(c.ready)
+	pool.conns[poolKey{id: c.ID}] = c
+
+	c.BeginReboot()
+	if _, err := pool.GetConnection(context.Background(), c.ID); err == nil || !strings.Contains(err.Error(), "is rebooting") {
+		t.Fatalf("GetConnection while rebooting: err = %v", err)
+	}
+	if infos := pool.ListConnections(context.Background()); len(infos) != 1 || !infos[0].Rebooting {
+		t.Errorf("ListConnections = %+v, want the session marked rebooting", infos)
+	}
+
+	got, reconnected, err := pool.Revive(context.Background(), c.ID)
+	if err != nil || got != c || !reconnected {
+		t.Fatalf("Revive = %v, %v, %v; want the session reconnected", got, reconnected, err)
+	}
+	if _, reconnected, err := pool.Revive(context.Background(), c.ID); err != nil || reconnected {
+		t.Errorf("Revive of a live session = %v, %v; want no reconnect", reconnected, err)
+	}
+
+	c.EndReboot()
+	if !c.RebootingSince().IsZero() {
+		t.Error("RebootingSince not cleared by EndReboot")
+	}
+	if _, err := pool.GetConnection(context.Background(), c.ID); err != nil {
+		t.Errorf("GetConnection after reboot: %v", err)
+	}
+	if _, _, err := pool.Revive(context.Background(), "other@host:22"); err == nil {
+		t.Error("Revive of an unknown session should fail")
+	}
+	c.Client.Close()
+}
+
+func TestPool_ReviveDialFails(t *testing.T) {
+	pool := newTestPool()
+	c := &Connection{ID: "root@host:22", ready: make(chan struct{}), addr: "127.0.0.1:1",
+		clientConfig: &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()}}
+	close(c.ready)
+	pool.conns[poolKey{id: c.ID}] = c
+	c.BeginReboot()
+	if _, _, err := pool.Revive(context.Background(), c.ID); err == nil || !strings.Contains(err.Error(), "reconnect SSH dial") {
+		t.Fatalf("Revive of a down host: err = %v", err)
+	}
+	if _, ok := pool.conns[poolKey{id: c.ID}]; !ok {
+		t.Error("a failed Revive must leave the session in the pool")
+	}
+}
@@ -0,0 +1,104 @@
+package connection
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// openSSHKeyMagic starts the body of an "OPENSSH PRIVATE KEY" PEM block.
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+// isSecurityKey reports whether pub is a FIDO2 security key
+// (sk-ssh-ed25519@openssh.com or sk-ecdsa-sha2-nistp256@openssh.com). Their
+// private key files only hold a handle for the authenticator, so signing
+// has to go through ssh-agent.
+func isSecurityKey(pub ssh.PublicKey) bool {
+	switch pub.Type() {
+	case ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256:
+		return true
+	}
+	return false
+}
+
+// privateKeyPublic returns the public key stored in the clear in an OpenSSH
+// private key file, which is readable even when ssh.ParsePrivateKey cannot
+// use the key (security keys) or it is encrypted.
+func privateKeyPublic(keyData []byte) (ssh.PublicKey, error) {
+	block, _ := pem.Decode(keyData)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, fmt.Errorf("not an OpenSSH private key")
+	}
+	body, ok := bytes.CutPrefix(block.Bytes, []byte(openSSHKeyMagic))
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenSSH private key header")
+	}
+	var w struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}
+	if err := ssh.Unmarshal(body, &w); err != nil {
+		return nil, err
+	}
+	if w.NumKeys != 1 {
+		return nil, fmt.Errorf("unsupported number of keys: %d", w.NumKeys)
+	}
+	return ssh.ParsePublicKey(w.PubKey)
+}
+
+// dialAgent connects to the ssh-agent at SSH_AUTH_SOCK. The connection must
+// stay open while the agent's signers are in use.
+func dialAgent() (agent.ExtendedAgent, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// securityKeyAuth authenticates with the security key whose private key
+// file is keyPath, by finding the same public key in ssh-agent (added with
+// ssh-add, which talks to the authenticator). Nil if the agent lacks it.
+func (a *AuthDiscovery) securityKeyAuth(keyPath string, pub ssh.PublicKey) ssh.AuthMethod {
+	signer, err := agentSigner(pub)
+	if err != nil {
+		log.Printf("SSH key %s is a security key (%s) and must be loaded into ssh-agent with ssh-add: %v", keyPath, pub.Type(), err)
+		return nil
+	}
+	return ssh.PublicKeys(signer)
+}
+
+// agentSigner returns the ssh-agent signer for pub.
+func agentSigner(pub ssh.PublicKey) (ssh.Signer, error) {
+	ag, conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	signers, err := ag.Signers()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	want := pub.Marshal()
+	for _, s := range signers {
+		if bytes.Equal(s.PublicKey().Marshal(), want) {
+			return s, nil
+		}
+	}
+	conn.Close()
+	return nil, fmt.Errorf("key %s is not in the agent", ssh.FingerprintSHA256(pub))
+}
@@ -0,0 +1,130 @@
+package connection
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// skKeyFile builds an OpenSSH private key file for an sk-ssh-ed25519 key,
+// as ssh-keygen -t ed25519-sk writes it (the private part is not needed).
+func skKeyFile(t *testing.T) ([]byte, ssh.PublicKey) {
+	t.Helper()
+	pubBytes, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wire := ssh.Marshal(struct {
+		Type        string
+		Key         []byte
+		Application string
+	}{ssh.KeyAlgoSKED25519, pubBytes, "ssh:"})
+	pub, err := ssh.ParsePublicKey(wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := ssh.Marshal(struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{"none", "none", "", 1, wire, []byte("handle")})
+	data := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: append([]byte(openSSHKeyMagic), body...)})
+	return data, pub
+}
+
+func TestPrivateKeyPublic_SecurityKey(t *testing.T) {
+	data, want := skKeyFile(t)
+	if _, err := ssh.ParsePrivateKey(data); err == nil {
+		t.Fatal("ssh.ParsePrivateKey accepted an sk key; the agent fallback would be unused")
+	}
+	pub, err := privateKeyPublic(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSecurityKey(pub) || string(pub.Marshal()) != string(want.Marshal()) {
+		t.Errorf("privateKeyPublic = %s %s, want %s", pub.Type(), ssh.FingerprintSHA256(pub), ssh.FingerprintSHA256(want))
+	}
+	if _, err := privateKeyPublic([]byte("not a key")); err == nil {
+		t.Error("privateKeyPublic accepted garbage")
+	}
+}
+
+// serveAgent runs an in-memory ssh-agent on a socket and points
+// SSH_AUTH_SOCK at it.
+func serveAgent(t *testing.T, keys ...any) {
+	t.Helper()
+	keyring := agent.NewKeyring()
+	for _, k := range keys {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_ = agent.ServeAgent(keyring, c)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+}
+
+func TestAgentSigner(t *testing.T) {
+	_, inAgent, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	serveAgent(t, inAgent)
+
+	signer, err := ssh.NewSignerFromKey(inAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := agentSigner(signer.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := got.Sign(rand.Reader, []byte("data")); err != nil {
+		t.Errorf("agent signer: %v", err)
+	}
+
+	missing, _ := ssh.NewSignerFromKey(other)
+	if _, err := agentSigner(missing.PublicKey()); err == nil {
+		t.Error("agentSigner found a key the agent doesn't have")
+	}
+}
+
+func TestLoadKeyAuth_SecurityKeyNotInAgent(t *testing.T) {
+	serveAgent(t)
+	data, _ := skKeyFile(t)
+	path := filepath.Join(t.TempDir(), "id_ed25519_sk")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthDiscovery(&config.SSHConfig{ConnectionTimeout: 30 * time.Second})
+	if m := auth.loadKeyAuth(path); m != nil {
+		t.Error("loadKeyAuth returned a method for a security key missing from the agent")
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/vt"
 )
 
 // bufferCompactThreshold is the read position beyond which the output buffer
@@ -26,12 +28,14 @@ type TerminalID string
 type TerminalSession struct {
 	ID         TerminalID
 	SessionID  SessionID
+	Owner      string // client namespace, as in Connection.Owner
 	sshSession *ssh.Session
 	stdin      io.WriteCloser
 
 	outputMu  sync.Mutex
-	outputBuf []byte // accumulates all output since open
-	readPos   int    // position up to which output has been returned
+	outputBuf []byte     // accumulates all output since open
+	readPos   int        // position up to which output has been returned
+	screen    *vt.Screen // all output rendered onto a virtual screen
 
 	outputNew chan struct{} // closed and recreated when new data arrives
 	newMu     sync.Mutex
@@ -80,7 +84,8 @@ const exitWrapCommand = "exit() { echo '[ssh-mcp: exit blocked; use ssh_close_te
 // cols and rows default to 120×50; termType defaults to "xterm-256color".
 // When protectExit is true, a POSIX exit-wrapping shell function is injected after
 // shell start to prevent accidental session termination via `exit`.
-func (tp *TerminalPool) Open(sessionID SessionID, client *ssh.Client, cols, rows int, termType string, protectExit bool) (*TerminalSession, error) {
+// The terminal is only visible to owner.
+func (tp *TerminalPool) Open(owner string, sessionID SessionID, client *ssh.Client, cols, rows int, termType string, protectExit bool) (*TerminalSession, error) {
 	if cols <= 0 {
 		cols = 120
 	}
@@ -136,8 +141,10 @@ func (tp *TerminalPool) Open(sessionID SessionID, client *ssh.Client, cols, rows
 	ts := &TerminalSession{
 		ID:         id,
 		SessionID:  sessionID,
+		Owner:      owner,
 		sshSession: sshSess,
 		stdin:      stdin,
+		screen:     vt.New(cols, rows),
 		outputNew:  make(chan struct{}),
 		done:       make(chan struct{}),
 		createdAt:  now,
@@ -184,27 +191,7 @@ func (ts *TerminalSession) readLoop(r io.Reader) {
 	for {
 		n, err := r.Read(buf)
 		if n > 0 {
-			ts.outputMu.Lock()
-			ts.outputBuf = append(ts.outputBuf, buf[:n]...)
-			// Cap the buffer to prevent unbounded memory growth.
-			if len(ts.outputBuf) > maxBufferSize {
-				excess := len(ts.outputBuf) - maxBufferSize
-				if ts.readPos < excess {
-					ts.readPos = 0
-				} else {
-					ts.readPos -= excess
-				}
-				copy(ts.outputBuf, ts.outputBuf[excess:])
-				ts.outputBuf = ts.outputBuf[:maxBufferSize]
-			}
-			ts.outputMu.Unlock()
-
-			// Signal waiting readers by closing and replacing the channel.
-			ts.newMu.Lock()
-			ch := ts.outputNew
-			ts.outputNew = make(chan struct{})
-			close(ch)
-			ts.newMu.Unlock()
+			ts.appendOutput(buf[:n])
 		}
 		if err != nil {
 			if err != io.EOF {
@@ -219,6 +206,51 @@ func (ts *TerminalSession) readLoop(r io.Reader) {
 	}
 }
 
+// appendOutput adds data to the output buffer and the screen, and wakes
+// waiting readers.
+func (ts *TerminalSession) appendOutput(data []byte) {
+	ts.outputMu.Lock()
+	ts.outputBuf = append(ts.outputBuf, data...)
+	// Cap the buffer to prevent unbounded memory growth.
+	if len(ts.outputBuf) > maxBufferSize {
+		excess := len(ts.outputBuf) - maxBufferSize
+		if ts.readPos < excess {
+			ts.readPos = 0
+		} else {
+			ts.readPos -= excess
+		}
+		copy(ts.outputBuf, ts.outputBuf[excess:])
+		ts.outputBuf = ts.outputBuf[:maxBufferSize]
+	}
+	if ts.screen != nil {
+		ts.screen.Write(data)
+	}
+	ts.outputMu.Unlock()
+
+	// Signal waiting readers by closing and replacing the channel.
+	ts.newMu.Lock()
+	ch := ts.outputNew
+	ts.outputNew = make(chan struct{})
+	close(ch)
+	ts.newMu.Unlock()
+}
+
+// Screen returns the terminal's current screen, rendered from all output
+// received so far. Unlike ReadNew it consumes nothing, so it shows what a
+// full-screen program has drawn however the output was read.
+func (ts *TerminalSession) Screen() vt.Snapshot {
+	ts.mu.Lock()
+	ts.lastUsed = time.Now()
+	ts.mu.Unlock()
+
+	ts.outputMu.Lock()
+	defer ts.outputMu.Unlock()
+	if ts.screen == nil {
+		return vt.Snapshot{}
+	}
+	return ts.screen.Snapshot()
+}
+
 // Write sends data to the PTY stdin.
 func (ts *TerminalSession) Write(data []byte) error {
 	ts.mu.Lock()
@@ -472,15 +504,15 @@ type TerminalInfo struct {
 	LastUsed  time.Time
 }
 
-// List returns metadata for all active terminals. If sessionID is non-empty,
-// only terminals belonging to that session are included.
-func (tp *TerminalPool) List(sessionID SessionID) []TerminalInfo {
+// List returns metadata for all active terminals of owner. If sessionID is
+// non-empty, only terminals belonging to that session are included.
+func (tp *TerminalPool) List(owner string, sessionID SessionID) []TerminalInfo {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
 	result := make([]TerminalInfo, 0, len(tp.sessions))
 	for _, ts := range tp.sessions {
-		if sessionID != "" && ts.SessionID != sessionID {
+		if ts.Owner != owner || (sessionID != "" && ts.SessionID != sessionID) {
 			continue
 		}
 		ts.mu.Lock()
@@ -496,12 +528,12 @@ func (tp *TerminalPool) List(sessionID SessionID) []TerminalInfo {
 	return result
 }
 
-// Get retrieves a TerminalSession by ID.
-func (tp *TerminalPool) Get(id TerminalID) (*TerminalSession, error) {
+// Get retrieves one of owner's TerminalSessions by ID.
+func (tp *TerminalPool) Get(owner string, id TerminalID) (*TerminalSession, error) {
 	tp.mu.RLock()
 	ts, ok := tp.sessions[id]
 	tp.mu.RUnlock()
-	if !ok {
+	if !ok || ts.Owner != owner {
 		return nil, fmt.Errorf("terminal %s not found", id)
 	}
 	return ts, nil
@@ -529,10 +561,11 @@ func closeSession(ts *TerminalSession) {
 	ts.signalDone()
 }
 
-// Close terminates a TerminalSession and removes it from the pool.
-func (tp *TerminalPool) Close(id TerminalID) error {
+// Close terminates one of owner's TerminalSessions and removes it from the pool.
+func (tp *TerminalPool) Close(owner string, id TerminalID) error {
 	tp.mu.Lock()
 	ts, ok := tp.sessions[id]
+	ok = ok && ts.Owner == owner
 	if ok {
 		delete(tp.sessions, id)
 	}
@@ -546,12 +579,13 @@ func (tp *TerminalPool) Close(id TerminalID) error {
 	return nil
 }
 
-// CloseBySession closes all terminals associated with the given SSH session ID.
-func (tp *TerminalPool) CloseBySession(sessionID SessionID) {
+// CloseBySession closes all terminals of owner associated with the given SSH
+// session ID.
+func (tp *TerminalPool) CloseBySession(owner string, sessionID SessionID) {
 	tp.mu.Lock()
 	var toClose []*TerminalSession
 	for id, ts := range tp.sessions {
-		if ts.SessionID == sessionID {
+		if ts.Owner == owner && ts.SessionID == sessionID {
 			toClose = append(toClose, ts)
 			delete(tp.sessions, id)
 		}
@@ -569,6 +603,9 @@ func (tp *TerminalPool) InsertForTest(ts *TerminalSession) {
 	now := time.Now()
 	ts.done = make(chan struct{})
 	ts.outputNew = make(chan struct{})
+	if ts.screen == nil {
+		ts.screen = vt.New(120, 50)
+	}
 	ts.createdAt = now
 	ts.lastUsed = now
 	tp.mu.Lock()
@@ -1,10 +1,13 @@
 package connection
 
 import (
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/vt"
 )
 
 // TestTerminalPoolOpenClose verifies that Open creates a session with the expected ID
@@ -28,7 +31,7 @@ func TestTerminalPoolOpenClose(t *testing.T) {
 	tp.sessions[ts.ID] = ts
 	tp.mu.Unlock()
 
-	if _, err := tp.Get(ts.ID); err != nil {
+	if _, err := tp.Get("", ts.ID); err != nil {
 		t.Fatalf("Get after manual insert: %v", err)
 	}
 
@@ -47,7 +50,7 @@ func TestTerminalPoolOpenClose(t *testing.T) {
 	delete(tp.sessions, ts.ID)
 	tp.mu.Unlock()
 
-	if _, err := tp.Get(ts.ID); err == nil {
+	if _, err := tp.Get("", ts.ID); err == nil {
 		t.Error("expected error after removal, got nil")
 	}
 }
@@ -56,7 +59,7 @@ func TestTerminalPoolOpenClose(t *testing.T) {
 func TestTerminalPoolGet(t *testing.T) {
 	tp := NewTerminalPool(0)
 
-	_, err := tp.Get(TerminalID("does-not-exist"))
+	_, err := tp.Get("", TerminalID("does-not-exist"))
 	if err == nil {
 		t.Fatal("expected error for missing terminal, got nil")
 	}
@@ -67,7 +70,7 @@ func TestTerminalPoolGet(t *testing.T) {
 	tp.sessions[ts.ID] = ts
 	tp.mu.Unlock()
 
-	got, err := tp.Get(ts.ID)
+	got, err := tp.Get("", ts.ID)
 	if err != nil {
 		t.Fatalf("Get existing: %v", err)
 	}
@@ -97,7 +100,7 @@ func TestTerminalPoolCloseBySession(t *testing.T) {
 		tp.mu.Unlock()
 	}
 
-	tp.CloseBySession(target)
+	tp.CloseBySession("", target)
 
 	tp.mu.RLock()
 	remaining := len(tp.sessions)
@@ -504,7 +507,7 @@ func TestTerminalPoolList(t *testing.T) {
 	tp := NewTerminalPool(0)
 
 	// Empty pool should return empty slice.
-	result := tp.List("")
+	result := tp.List("", "")
 	if len(result) != 0 {
 		t.Fatalf("expected 0 terminals from empty pool, got %d", len(result))
 	}
@@ -525,13 +528,13 @@ func TestTerminalPoolList(t *testing.T) {
 	}
 
 	// List all terminals.
-	all := tp.List("")
+	all := tp.List("", "")
 	if len(all) != 3 {
 		t.Errorf("expected 3 terminals, got %d", len(all))
 	}
 
 	// Filter by sessionA.
-	filtered := tp.List(sessionA)
+	filtered := tp.List("", sessionA)
 	if len(filtered) != 2 {
 		t.Errorf("expected 2 terminals for sessionA, got %d", len(filtered))
 	}
@@ -542,13 +545,13 @@ func TestTerminalPoolList(t *testing.T) {
 	}
 
 	// Filter by sessionB.
-	filteredB := tp.List(sessionB)
+	filteredB := tp.List("", sessionB)
 	if len(filteredB) != 1 {
 		t.Errorf("expected 1 terminal for sessionB, got %d", len(filteredB))
 	}
 
 	// Filter by non-existent session.
-	filteredNone := tp.List(SessionID("nobody@host:22"))
+	filteredNone := tp.List("", SessionID("nobody@host:22"))
 	if len(filteredNone) != 0 {
 		t.Errorf("expected 0 terminals for unknown session, got %d", len(filteredNone))
 	}
@@ -566,3 +569,55 @@ func TestTerminalPoolMaxTerminals(t *testing.T) {
 		t.Errorf("expected maxTerminals=0, got %d", tp0.maxTerminals)
 	}
 }
+
+func TestTerminalPool_OwnersIsolated(t *testing.T) {
+	tp := NewTerminalPool(0)
+	ts := &TerminalSession{ID: "term-1", SessionID: "user@host:22", Owner: "client-a"}
+	tp.InsertForTest(ts)
+	ts.mu.Lock()
+	ts.closed = true // no SSH session to close
+	ts.mu.Unlock()
+
+	if _, err := tp.Get("client-a", ts.ID); err != nil {
+		t.Fatalf("Get by owner: %v", err)
+	}
+	if _, err := tp.Get("client-b", ts.ID); err == nil {
+		t.Error("expected Get by another owner to fail")
+	}
+	if got := tp.List("client-b", ""); len(got) != 0 {
+		t.Errorf("List(client-b) = %+v, want none", got)
+	}
+	if err := tp.Close("client-b", ts.ID); err == nil {
+		t.Error("expected Close by another owner to fail")
+	}
+	tp.CloseBySession("client-b", ts.SessionID)
+	if got := tp.List("client-a", ts.SessionID); len(got) != 1 {
+		t.Errorf("List(client-a) = %+v, want the terminal to survive", got)
+	}
+	if err := tp.Close("client-a", ts.ID); err != nil {
+		t.Errorf("Close by owner: %v", err)
+	}
+}
+
+// TestTerminalSessionScreen verifies that output is rendered onto the screen
+// and that reading output does not change it.
+func TestTerminalSessionScreen(t *testing.T) {
+	ts := &TerminalSession{
+		outputNew: make(chan struct{}),
+		done:      make(chan struct{}),
+		screen:    vt.New(20, 3),
+	}
+	ts.appendOutput([]byte("$ top\r\n\x1b[?1049h\x1b[H\x1b[2J"))
+	ts.appendOutput([]byte("load 0.42\x1b[3;1Hq quit"))
+	if got := ts.ReadNew(0); !strings.HasPrefix(got, "$ top") {
+		t.Errorf("ReadNew = %q", got)
+	}
+
+	snap := ts.Screen()
+	if want := []string{"load 0.42", "", "q quit"}; !slices.Equal(snap.Lines, want) || !snap.AltScreen {
+		t.Errorf("screen = %q (alt %v), want %q", snap.Lines, snap.AltScreen, want)
+	}
+	if (&TerminalSession{}).Screen().Lines != nil {
+		t.Error("session without a screen should return an empty snapshot")
+	}
+}
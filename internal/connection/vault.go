@@ -0,0 +1,248 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// VaultClient is a minimal HashiCorp Vault HTTP API client covering the two
+// endpoints needed for SSH credentials: KV secret reads and SSH CA signing.
+type VaultClient struct {
+	addr       string
+	token      string
+	httpClient *http.Client
+}
+
+// NewVaultClient creates a Vault client for the given address and token.
+func NewVaultClient(addr, token string, timeout time.Duration) *VaultClient {
+	return &VaultClient{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// ReadSecret reads a KV secret and returns its data. Both KV v1 and KV v2
+// responses are supported (for v2 the path must include "data/").
+func (v *VaultClient) ReadSecret(ctx context.Context, path string) (map[string]any, error) {
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	// KV v2 nests the secret under data.data alongside data.metadata.
+	if inner, ok := resp.Data["data"].(map[string]any); ok {
+		if _, hasMeta := resp.Data["metadata"]; hasMeta {
+			return inner, nil
+		}
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("vault: secret %s has no data", path)
+	}
+	return resp.Data, nil
+}
+
+// SignPublicKey asks the SSH secrets engine signing endpoint (e.g.
+// "ssh-client-signer/sign/my-role") to sign pub as a user certificate for principal.
+func (v *VaultClient) SignPublicKey(ctx context.Context, path string, pub ssh.PublicKey, principal string) (*ssh.Certificate, error) {
+	req := map[string]string{
+		"public_key": strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))),
+		"cert_type":  "user",
+	}
+	if principal != "" {
+		req["valid_principals"] = principal
+	}
+	var resp struct {
+		Data struct {
+			SignedKey string `json:"signed_key"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return nil, err
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.Data.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("vault: parse signed key from %s: %w", path, err)
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("vault: %s did not return a certificate", path)
+	}
+	return cert, nil
+}
+
+func (v *VaultClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("vault: encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	url := v.addr + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("vault: read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &errResp) == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("vault: %s %s: %s (HTTP %d)", method, path, strings.Join(errResp.Errors, "; "), resp.StatusCode)
+		}
+		return fmt.Errorf("vault: %s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("vault: decode response: %w", err)
+	}
+	return nil
+}
+
+// vaultRule is a compiled per-host Vault credential source.
+type vaultRule struct {
+	re   *regexp.Regexp
+	kind string
+	path string
+}
+
+func compileVaultRules(hosts []config.HostVault) []vaultRule {
+	var rules []vaultRule
+	for _, h := range hosts {
+		re, err := regexp.Compile("(?i)^(?:" + h.Pattern + ")$")
+		if err != nil {
+			log.Printf("Ignoring invalid host vault pattern %q: %v", h.Pattern, err)
+			continue
+		}
+		rules = append(rules, vaultRule{re: re, kind: h.Kind, path: h.Path})
+	}
+	return rules
+}
+
+// vaultAuthMethods returns auth methods backed by the first Vault rule
+// matching params.Host. Credentials are fetched lazily during each handshake,
+// so nothing is cached on the MCP host and auto-reconnect picks up rotated
+// secrets and freshly signed certificates.
+func (a *AuthDiscovery) vaultAuthMethods(params ConnectParams) []ssh.AuthMethod {
+	if a.vault == nil {
+		return nil
+	}
+	for _, r := range a.vaultRules {
+		if !r.re.MatchString(params.Host) {
+			continue
+		}
+		switch r.kind {
+		case config.VaultKindSSHCA:
+			return []ssh.AuthMethod{ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				return a.vaultCertSigners(r.path, params.User)
+			})}
+		case config.VaultKindKV:
+			return []ssh.AuthMethod{
+				ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+					return a.vaultKeySigners(r.path)
+				}),
+				ssh.PasswordCallback(func() (string, error) {
+					return a.vaultPassword(r.path)
+				}),
+			}
+		}
+	}
+	return nil
+}
+
+func (a *AuthDiscovery) vaultContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), a.cfg.ConnectionTimeout)
+}
+
+// vaultCertSigners generates an ephemeral key and has Vault sign it.
+func (a *AuthDiscovery) vaultCertSigners(path, principal string) ([]ssh.Signer, error) {
+	ctx, cancel := a.vaultContext()
+	defer cancel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ephemeral key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("ephemeral signer: %w", err)
+	}
+	cert, err := a.vault.SignPublicKey(ctx, path, signer.PublicKey(), principal)
+	if err != nil {
+		return nil, err
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("vault: certificate signer: %w", err)
+	}
+	return []ssh.Signer{certSigner}, nil
+}
+
+// vaultKeySigners returns the secret's "private_key" field as a signer, or no
+// signers if the secret only holds a password.
+func (a *AuthDiscovery) vaultKeySigners(path string) ([]ssh.Signer, error) {
+	ctx, cancel := a.vaultContext()
+	defer cancel()
+
+	data, err := a.vault.ReadSecret(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := data["private_key"].(string)
+	if key == "" {
+		return nil, nil
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("vault: parse private_key from %s: %w", path, err)
+	}
+	return []ssh.Signer{signer}, nil
+}
+
+// vaultPassword returns the secret's "password" field.
+func (a *AuthDiscovery) vaultPassword(path string) (string, error) {
+	ctx, cancel := a.vaultContext()
+	defer cancel()
+
+	data, err := a.vault.ReadSecret(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	password, _ := data["password"].(string)
+	if password == "" {
+		return "", fmt.Errorf("vault: secret %s has no password field", path)
+	}
+	return password, nil
+}
@@ -0,0 +1,180 @@
+package connection
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// newFakeVault starts a test Vault server with a KV v2 secret at
+// secret/data/web, a KV v1 secret at kv/db, and an SSH CA at ssh/sign/ops.
+func newFakeVault(t *testing.T, token string) (*httptest.Server, ssh.PublicKey) {
+	t.Helper()
+	_, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caSigner, err := ssh.NewSignerFromKey(caPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/secret/data/web", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"data":     map[string]any{"password": "v2-secret"},
+			"metadata": map[string]any{"version": 3},
+		}})
+	})
+	mux.HandleFunc("GET /v1/kv/db", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"password": "v1-secret"}})
+	})
+	mux.HandleFunc("POST /v1/ssh/sign/ops", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req["public_key"]))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cert := &ssh.Certificate{
+			Key:             pub,
+			CertType:        ssh.UserCert,
+			ValidPrincipals: []string{req["valid_principals"]},
+			ValidBefore:     ssh.CertTimeInfinity,
+		}
+		if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"signed_key": string(ssh.MarshalAuthorizedKey(cert)),
+		}})
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, caSigner.PublicKey()
+}
+
+func TestVaultClient_ReadSecret(t *testing.T) {
+	srv, _ := newFakeVault(t, "s.token")
+	v := NewVaultClient(srv.URL+"/", "s.token", 5*time.Second)
+
+	data, err := v.ReadSecret(context.Background(), "secret/data/web")
+	if err != nil {
+		t.Fatalf("ReadSecret v2: %v", err)
+	}
+	if data["password"] != "v2-secret" {
+		t.Errorf("expected KV v2 data to be unwrapped, got %v", data)
+	}
+
+	data, err = v.ReadSecret(context.Background(), "/kv/db")
+	if err != nil {
+		t.Fatalf("ReadSecret v1: %v", err)
+	}
+	if data["password"] != "v1-secret" {
+		t.Errorf("unexpected KV v1 data: %v", data)
+	}
+}
+
+func TestVaultClient_ErrorResponse(t *testing.T) {
+	srv, _ := newFakeVault(t, "s.token")
+	v := NewVaultClient(srv.URL, "wrong", 5*time.Second)
+
+	_, err := v.ReadSecret(context.Background(), "kv/db")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected permission denied error, got %v", err)
+	}
+}
+
+func TestVaultClient_SignPublicKey(t *testing.T) {
+	srv, caPub := newFakeVault(t, "s.token")
+	v := NewVaultClient(srv.URL, "s.token", 5*time.Second)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, _ := ssh.NewPublicKey(pub)
+
+	cert, err := v.SignPublicKey(context.Background(), "ssh/sign/ops", sshPub, "deploy")
+	if err != nil {
+		t.Fatalf("SignPublicKey: %v", err)
+	}
+	if string(cert.SignatureKey.Marshal()) != string(caPub.Marshal()) {
+		t.Error("certificate not signed by the CA")
+	}
+	if len(cert.ValidPrincipals) != 1 || cert.ValidPrincipals[0] != "deploy" {
+		t.Errorf("unexpected principals %v", cert.ValidPrincipals)
+	}
+}
+
+func TestAuthDiscovery_VaultAuthMethods(t *testing.T) {
+	srv, _ := newFakeVault(t, "s.token")
+	cfg := &config.SSHConfig{
+		ConnectionTimeout: 5 * time.Second,
+		Vault: config.VaultConfig{
+			Addr:  srv.URL,
+			Token: "s.token",
+			Hosts: []config.HostVault{
+				{Pattern: `web\d+\.example\.com`, Kind: config.VaultKindKV, Path: "secret/data/web"},
+				{Pattern: "ops.*", Kind: config.VaultKindSSHCA, Path: "ssh/sign/ops"},
+			},
+		},
+	}
+	a := NewAuthDiscovery(cfg)
+
+	if m := a.vaultAuthMethods(ConnectParams{Host: "db.example.com"}); len(m) != 0 {
+		t.Errorf("expected no Vault methods for unmatched host, got %d", len(m))
+	}
+	if m := a.vaultAuthMethods(ConnectParams{Host: "WEB1.example.com"}); len(m) != 2 {
+		t.Errorf("expected key and password methods for KV host, got %d", len(m))
+	}
+	if m := a.vaultAuthMethods(ConnectParams{Host: "ops-1", User: "deploy"}); len(m) != 1 {
+		t.Errorf("expected one certificate method for SSH CA host, got %d", len(m))
+	}
+
+	password, err := a.vaultPassword("secret/data/web")
+	if err != nil || password != "v2-secret" {
+		t.Errorf("vaultPassword = %q, %v", password, err)
+	}
+	signers, err := a.vaultKeySigners("secret/data/web")
+	if err != nil || len(signers) != 0 {
+		t.Errorf("expected no key signers for password-only secret, got %d, %v", len(signers), err)
+	}
+	signers, err = a.vaultCertSigners("ssh/sign/ops", "deploy")
+	if err != nil {
+		t.Fatalf("vaultCertSigners: %v", err)
+	}
+	if _, ok := signers[0].PublicKey().(*ssh.Certificate); !ok {
+		t.Error("expected certificate signer")
+	}
+}
+
+func TestAuthDiscovery_NoVaultWithoutHosts(t *testing.T) {
+	a := NewAuthDiscovery(&config.SSHConfig{})
+	if a.vault != nil {
+		t.Error("expected no Vault client when no hosts are configured")
+	}
+}
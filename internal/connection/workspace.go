@@ -0,0 +1,88 @@
+package connection
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// workspaceCleanupTimeout bounds the rm that removes a session's
+// workspaces when it is closed.
+const workspaceCleanupTimeout = 10 * time.Second
+
+// AddWorkspace records dir as a scratch directory of the session, removed
+// when the session is disconnected, expires or the server shuts down.
+func (c *Connection) AddWorkspace(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !slices.Contains(c.workspaces, dir) {
+		c.workspaces = append(c.workspaces, dir)
+	}
+}
+
+// RemoveWorkspace stops tracking dir and reports whether it was a
+// workspace of the session.
+func (c *Connection) RemoveWorkspace(dir string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.Index(c.workspaces, dir)
+	if i < 0 {
+		return false
+	}
+	c.workspaces = slices.Delete(c.workspaces, i, i+1)
+	return true
+}
+
+// Workspaces returns the session's workspaces, oldest first.
+func (c *Connection) Workspaces() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.workspaces)
+}
+
+// removeWorkspaces deletes the session's workspaces on the host before its
+// client is closed. A session whose client was closed while idle is dialed
+// once more for it. Failures are logged: the session is closing anyway.
+func (p *Pool) removeWorkspaces(c *Connection) {
+	c.mu.Lock()
+	dirs, client, connected := c.workspaces, c.Client, c.Connected
+	cfg, addr, dial := c.clientConfig, c.addr, c.dial
+	c.workspaces = nil
+	c.mu.Unlock()
+	if len(dirs) == 0 {
+		return
+	}
+	if !connected || client == nil {
+		if cfg == nil {
+			log.Printf("Session %s closed without a connection; workspaces left on the host: %s", c.ID, strings.Join(dirs, ", "))
+			return
+		}
+		if dial == nil {
+			dial = tcpDialer(p.cfg.DialAttemptTimeout, nil)
+		}
+		var err error
+		if client, err = dial(addr, cfg); err != nil {
+			log.Printf("Failed to reconnect %s to remove its workspaces (%s): %v", c.ID, strings.Join(dirs, ", "), err)
+			return
+		}
+		defer client.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), workspaceCleanupTimeout)
+	defer cancel()
+	if _, err := runProbeCommand(ctx, client, RemoveWorkspacesCommand(dirs)); err != nil {
+		log.Printf("Failed to remove workspaces of %s (%s): %v", c.ID, strings.Join(dirs, ", "), err)
+		return
+	}
+	log.Printf("Removed workspaces of %s: %s", c.ID, strings.Join(dirs, ", "))
+}
+
+// RemoveWorkspacesCommand returns the shell command that deletes dirs.
+func RemoveWorkspacesCommand(dirs []string) string {
+	args := []string{"rm", "-rf", "--"}
+	for _, d := range dirs {
+		args = append(args, shellQuoteArg(d))
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,145 @@
+package connection
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startExecSSHServer accepts SSH connections without authentication and
+// records the command of every exec request, which exits with status 0.
+func startExecSSHServer(t *testing.T) (addr string, commands func() []string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{NoClientAuth: true}
+	srvCfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	var cmds []string
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, srvCfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nch := range chans {
+					ch, chReqs, err := nch.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						defer ch.Close()
+						for req := range chReqs {
+							var exec struct{ Command string }
+							if req.Type != "exec" || ssh.Unmarshal(req.Payload, &exec) != nil {
+								req.Reply(false, nil)
+								continue
+							}
+							mu.Lock()
+							cmds = append(cmds, exec.Command)
+							mu.Unlock()
+							req.Reply(true, nil)
+							ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(cmds)
+	}
+}
+
+func TestConnection_Workspaces(t *testing.T) {
+	c := &Connection{}
+	c.AddWorkspace("/tmp/ssh-mcp.a")
+	c.AddWorkspace("/tmp/ssh-mcp.b")
+	c.AddWorkspace("/tmp/ssh-mcp.a")
+	if got := c.Workspaces(); !slices.Equal(got, []string{"/tmp/ssh-mcp.a", "/tmp/ssh-mcp.b"}) {
+		t.Fatalf("Workspaces = %v", got)
+	}
+	if !c.RemoveWorkspace("/tmp/ssh-mcp.a") || c.RemoveWorkspace("/tmp/other") {
+		t.Error("RemoveWorkspace should only remove tracked directories")
+	}
+	if got := c.Workspaces(); !slices.Equal(got, []string{"/tmp/ssh-mcp.b"}) {
+		t.Errorf("Workspaces after remove = %v", got)
+	}
+}
+
+func TestRemoveWorkspacesCommand(t *testing.T) {
+	got := RemoveWorkspacesCommand([]string{"/tmp/ssh-mcp.a", "/tmp/it's here"})
+	if want := `rm -rf -- /tmp/ssh-mcp.a '/tmp/it'\''s here'`; got != want {
+		t.Errorf("RemoveWorkspacesCommand = %q, want %q", got, want)
+	}
+}
+
+func TestPool_RemovesWorkspacesOnClose(t *testing.T) {
+	addr, commands := startExecSSHServer(t)
+	dial := func(id SessionID) *Connection {
+		client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &Connection{ID: id, Client: client, Connected: true, ready: make(chan struct{})}
+		close(c.ready)
+		return c
+	}
+
+	pool := newTestPool()
+	a, b := dial("a@host:22"), dial("b@host:22")
+	a.AddWorkspace("/tmp/ssh-mcp.a1")
+	a.AddWorkspace("/tmp/ssh-mcp.a2")
+	pool.conns[poolKey{id: a.ID}] = a
+	pool.conns[poolKey{id: b.ID}] = b
+	// A session closed while idle is dialed again to clean up.
+	idle := &Connection{ID: "c@host:22", ready: make(chan struct{}), workspaces: []string{"/tmp/ssh-mcp.c"},
+		addr: addr, clientConfig: &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()}}
+	close(idle.ready)
+	pool.conns[poolKey{id: idle.ID}] = idle
+
+	if err := pool.Disconnect(context.Background(), a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := commands(); !slices.Equal(got, []string{"rm -rf -- /tmp/ssh-mcp.a1 /tmp/ssh-mcp.a2"}) {
+		t.Fatalf("commands after disconnect = %q", got)
+	}
+	if len(a.Workspaces()) != 0 {
+		t.Error("workspaces still tracked after disconnect")
+	}
+
+	b.AddWorkspace("/tmp/ssh-mcp.b1")
+	pool.CloseAll()
+	got := commands()[1:]
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"rm -rf -- /tmp/ssh-mcp.b1", "rm -rf -- /tmp/ssh-mcp.c"}) {
+		t.Errorf("commands after CloseAll = %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters for deriving the file encryption key from the passphrase.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	fileKeyLen   = 32
+	fileSaltLen  = 16
+	fileVersion1 = 1
+)
+
+// fileEnvelope is the on-disk format: AES-256-GCM ciphertext of the JSON
+// credential map, with the scrypt salt and GCM nonce stored alongside.
+type fileEnvelope struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// FileStore is an encrypted credential file protected by a passphrase.
+type FileStore struct {
+	mu         sync.Mutex
+	path       string
+	passphrase string
+}
+
+// NewFileStore creates a file store at path encrypted with passphrase.
+// The file is created on first Set.
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+// Name implements Store.
+func (s *FileStore) Name() string {
+	return "encrypted file " + s.path
+}
+
+// Get implements Store.
+func (s *FileStore) Get(key string) (Credential, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds, err := s.load()
+	if err != nil {
+		return Credential{}, false, err
+	}
+	cred, ok := creds[key]
+	return cred, ok, nil
+}
+
+// Set implements Store.
+func (s *FileStore) Set(key string, cred Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds, err := s.load()
+	if err != nil {
+		return err
+	}
+	creds[key] = cred
+	return s.save(creds)
+}
+
+func (s *FileStore) load() (map[string]Credential, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(map[string]Credential), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read credential file: %w", err)
+	}
+
+	var env fileEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("parse credential file: %w", err)
+	}
+	if env.Version != fileVersion1 {
+		return nil, fmt.Errorf("unsupported credential file version %d", env.Version)
+	}
+	gcm, err := s.cipher(env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt credential file (wrong passphrase?): %w", err)
+	}
+
+	creds := make(map[string]Credential)
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return nil, fmt.Errorf("parse credential file: %w", err)
+	}
+	return creds, nil
+}
+
+func (s *FileStore) save(creds map[string]Credential) error {
+	plain, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("encode credentials: %w", err)
+	}
+
+	env := fileEnvelope{Version: fileVersion1, Salt: make([]byte, fileSaltLen)}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return fmt.Errorf("generate salt: %w", err)
+	}
+	gcm, err := s.cipher(env.Salt)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	env.Data = gcm.Seal(nil, env.Nonce, plain, nil)
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encode credential file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("create credential directory: %w", err)
+	}
+	// Write to a temp file and rename so a crash never leaves a truncated store.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".credentials-*")
+	if err != nil {
+		return fmt.Errorf("create credential file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod credential file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write credential file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write credential file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace credential file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, fileKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive credential key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("credential cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,55 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "credentials")
+	s := NewFileStore(path, "correct horse")
+
+	if _, ok, err := s.Get("root@web1:22"); err != nil || ok {
+		t.Fatalf("expected empty store, got ok=%v err=%v", ok, err)
+	}
+	if err := s.Set("root@web1:22", Credential{Password: "hunter2"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := Update(s, "root@web1:22", func(c *Credential) { c.SudoPassword = "sudo-pw" }); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// A fresh store instance must decrypt what the first one wrote.
+	cred, ok, err := NewFileStore(path, "correct horse").Get("root@web1:22")
+	if err != nil || !ok {
+		t.Fatalf("Get: ok=%v err=%v", ok, err)
+	}
+	if cred.Password != "hunter2" || cred.SudoPassword != "sudo-pw" {
+		t.Errorf("unexpected credential %+v", cred)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "hunter2") {
+		t.Error("credential file contains plaintext password")
+	}
+}
+
+func TestFileStore_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := NewFileStore(path, "right").Set("a@b:22", Credential{Password: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := NewFileStore(path, "wrong").Get("a@b:22")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected decrypt error, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainService is the service name entries are stored under.
+const keychainService = "ssh-mcp"
+
+// runFunc runs a helper command with optional stdin and returns its stdout.
+type runFunc func(stdin string, name string, args ...string) (string, error)
+
+// winCred reads and writes generic credentials in the Windows Credential
+// Manager (wincred_windows.go).
+type winCred interface {
+	read(target string) ([]byte, bool, error)
+	write(target, user string, blob []byte) error
+}
+
+// KeychainStore saves credentials in the OS keychain: through the
+// platform's command-line helper, security(1) on macOS and secret-tool
+// (libsecret) on Linux, or the Credential Manager API on Windows.
+type KeychainStore struct {
+	goos string
+	run  runFunc
+	win  winCred
+}
+
+// NewKeychainStore creates a keychain store for the given GOOS.
+func NewKeychainStore(goos string) (*KeychainStore, error) {
+	var helper string
+	switch goos {
+	case "windows":
+		win, err := newWinCred()
+		if err != nil {
+			return nil, err
+		}
+		return &KeychainStore{goos: goos, win: win}, nil
+	case "darwin":
+		helper = "security"
+	case "linux", "freebsd", "openbsd", "netbsd":
+		helper = "secret-tool"
+	default:
+		return nil, fmt.Errorf("keychain credential store is not supported on %s; use the file store", goos)
+	}
+	if _, err := exec.LookPath(helper); err != nil {
+		return nil, fmt.Errorf("keychain credential store requires %s: %w", helper, err)
+	}
+	return &KeychainStore{goos: goos, run: runCommand}, nil
+}
+
+// Name implements Store.
+func (s *KeychainStore) Name() string {
+	switch s.goos {
+	case "darwin":
+		return "macOS keychain"
+	case "windows":
+		return "Windows Credential Manager"
+	}
+	return "libsecret keyring"
+}
+
+// Get implements Store.
+func (s *KeychainStore) Get(key string) (Credential, bool, error) {
+	if s.win != nil {
+		data, ok, err := s.win.read(winCredTarget(key))
+		if err != nil {
+			return Credential{}, false, fmt.Errorf("keychain lookup: %w", err)
+		}
+		if !ok {
+			return Credential{}, false, nil
+		}
+		return decodeKeychainEntry(key, string(data))
+	}
+
+	var out string
+	var err error
+	if s.goos == "darwin" {
+		out, err = s.run("", "security", "find-generic-password", "-s", keychainService, "-a", key, "-w")
+	} else {
+		out, err = s.run("", "secret-tool", "lookup", "service", keychainService, "account", key)
+	}
+	out = strings.TrimSpace(out)
+	if err != nil || out == "" {
+		// Both helpers exit non-zero when the item does not exist.
+		var exitErr *exec.ExitError
+		if err == nil || errors.As(err, &exitErr) {
+			return Credential{}, false, nil
+		}
+		return Credential{}, false, fmt.Errorf("keychain lookup: %w", err)
+	}
+
+	return decodeKeychainEntry(key, out)
+}
+
+// decodeKeychainEntry parses the JSON secret saved for key.
+func decodeKeychainEntry(key, data string) (Credential, bool, error) {
+	var cred Credential
+	if err := json.Unmarshal([]byte(data), &cred); err != nil {
+		return Credential{}, false, fmt.Errorf("parse keychain entry for %s: %w", key, err)
+	}
+	return cred, true, nil
+}
+
+// winCredTarget is the Credential Manager target name of key.
+func winCredTarget(key string) string {
+	return keychainService + ":" + key
+}
+
+// Set implements Store.
+func (s *KeychainStore) Set(key string, cred Credential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("encode credential: %w", err)
+	}
+	switch {
+	case s.win != nil:
+		err = s.win.write(winCredTarget(key), key, data)
+	case s.goos == "darwin":
+		// A trailing -w without a value makes security(1) prompt for the
+		// secret, which it then reads (twice, to confirm) from stdin, so it
+		// never appears in argv. -U updates in place.
+		secret := string(data) + "\n"
+		_, err = s.run(secret+secret, "security", "add-generic-password", "-U", "-s", keychainService, "-a", key, "-w")
+	default:
+		_, err = s.run(string(data), "secret-tool", "store", "--label", keychainService+" "+key,
+			"service", keychainService, "account", key)
+	}
+	if err != nil {
+		return fmt.Errorf("keychain store: %w", err)
+	}
+	return nil
+}
+
+func runCommand(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return stdout.String(), fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
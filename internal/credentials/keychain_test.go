@@ -0,0 +1,108 @@
+package credentials
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// fakeKeychain records helper invocations and emulates a keyring in memory.
+type fakeKeychain struct {
+	items map[string]string
+	calls []string
+}
+
+func (f *fakeKeychain) run(stdin string, name string, args ...string) (string, error) {
+	f.calls = append(f.calls, name+" "+strings.Join(args, " "))
+	account := args[len(args)-1]
+	switch {
+	case name == "security" && args[0] == "add-generic-password":
+		// The secret comes on stdin, typed twice as at the prompt.
+		secret, confirm, _ := strings.Cut(strings.TrimSuffix(stdin, "\n"), "\n")
+		if secret != confirm {
+			return "", &exec.ExitError{}
+		}
+		f.items[args[5]] = secret
+		return "", nil
+	case name == "security" && args[0] == "find-generic-password":
+		account = args[4]
+	case name == "secret-tool" && args[0] == "store":
+		f.items[account] = stdin
+		return "", nil
+	}
+	if v, ok := f.items[account]; ok {
+		return v + "\n", nil
+	}
+	// Emulate the helper's non-zero exit for a missing item.
+	return "", &exec.ExitError{}
+}
+
+func TestKeychainStore_Backends(t *testing.T) {
+	for _, goos := range []string{"darwin", "linux"} {
+		t.Run(goos, func(t *testing.T) {
+			fake := &fakeKeychain{items: map[string]string{}}
+			s := &KeychainStore{goos: goos, run: fake.run}
+
+			if _, ok, err := s.Get("root@web1:22"); err != nil || ok {
+				t.Fatalf("expected missing item, got ok=%v err=%v", ok, err)
+			}
+			if err := s.Set("root@web1:22", Credential{Password: "hunter2"}); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			cred, ok, err := s.Get("root@web1:22")
+			if err != nil || !ok || cred.Password != "hunter2" {
+				t.Errorf("Get = %+v, %v, %v", cred, ok, err)
+			}
+			for _, c := range fake.calls {
+				if !strings.Contains(c, keychainService) {
+					t.Errorf("helper call %q does not use service %q", c, keychainService)
+				}
+				if strings.Contains(c, "hunter2") {
+					t.Errorf("helper call %q has the secret in its arguments", c)
+				}
+			}
+		})
+	}
+}
+
+// fakeWinCred emulates the Credential Manager in memory.
+type fakeWinCred struct {
+	blobs map[string][]byte
+	users map[string]string
+}
+
+func (f *fakeWinCred) read(target string) ([]byte, bool, error) {
+	b, ok := f.blobs[target]
+	return b, ok, nil
+}
+
+func (f *fakeWinCred) write(target, user string, blob []byte) error {
+	f.blobs[target] = blob
+	f.users[target] = user
+	return nil
+}
+
+func TestKeychainStore_WindowsBackend(t *testing.T) {
+	fake := &fakeWinCred{blobs: map[string][]byte{}, users: map[string]string{}}
+	s := &KeychainStore{goos: "windows", win: fake}
+
+	if _, ok, err := s.Get("root@web1:22"); err != nil || ok {
+		t.Fatalf("expected missing item, got ok=%v err=%v", ok, err)
+	}
+	if err := s.Set("root@web1:22", Credential{Password: "hunter2"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	cred, ok, err := s.Get("root@web1:22")
+	if err != nil || !ok || cred.Password != "hunter2" {
+		t.Errorf("Get = %+v, %v, %v", cred, ok, err)
+	}
+	if user := fake.users["ssh-mcp:root@web1:22"]; user != "root@web1:22" {
+		t.Errorf("target ssh-mcp:root@web1:22 has user %q, want root@web1:22", user)
+	}
+}
+
+func TestNewKeychainStore_UnsupportedOS(t *testing.T) {
+	if _, err := NewKeychainStore("plan9"); err == nil {
+		t.Error("expected error for unsupported OS")
+	}
+}
@@ -0,0 +1,55 @@
+// Package credentials provides optional persistent storage for SSH and sudo
+// passwords so they only need to pass through the model once per host.
+package credentials
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// Credential holds the secrets saved for one user@host:port.
+type Credential struct {
+	Password     string `json:"password,omitempty"`
+	SudoPassword string `json:"sudo_password,omitempty"`
+}
+
+// Store persists credentials keyed by "user@host:port".
+type Store interface {
+	// Get returns the saved credential for key; the bool is false if none exists.
+	Get(key string) (Credential, bool, error)
+	// Set saves the credential for key, replacing any existing entry.
+	Set(key string, cred Credential) error
+	// Name describes the backend for log messages.
+	Name() string
+}
+
+// New creates the store selected by cfg, or returns nil if storage is disabled.
+func New(cfg config.SecurityConfig) (Store, error) {
+	switch cfg.CredentialStore {
+	case "":
+		return nil, nil
+	case config.CredentialStoreFile:
+		return NewFileStore(cfg.CredentialFile, cfg.CredentialKey), nil
+	case config.CredentialStoreKeychain:
+		ks, err := NewKeychainStore(runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+		return ks, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q", cfg.CredentialStore)
+	}
+}
+
+// Update applies fn to the credential saved for key (zero value if none) and
+// saves the result, so saving a sudo password keeps the SSH password and vice versa.
+func Update(s Store, key string, fn func(*Credential)) error {
+	cred, _, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	fn(&cred)
+	return s.Set(key, cred)
+}
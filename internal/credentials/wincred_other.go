@@ -0,0 +1,11 @@
+//go:build !windows
+
+package credentials
+
+import "errors"
+
+// newWinCred reports that the Windows Credential Manager is only available
+// on Windows.
+func newWinCred() (winCred, error) {
+	return nil, errors.New("keychain credential store for windows requires running on Windows")
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1       // CRED_TYPE_GENERIC
+	credPersistLocalMachine = 2       // CRED_PERSIST_LOCAL_MACHINE
+	credMaxBlobSize         = 5 * 512 // CRED_MAX_CREDENTIAL_BLOB_SIZE
+)
+
+// credential mirrors the Win32 CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// sysWinCred is the Credential Manager of the current Windows user.
+type sysWinCred struct{}
+
+func newWinCred() (winCred, error) {
+	if err := procCredWriteW.Find(); err != nil {
+		return nil, fmt.Errorf("keychain credential store requires the Windows Credential Manager: %w", err)
+	}
+	return sysWinCred{}, nil
+}
+
+func (sysWinCred) read(target string) ([]byte, bool, error) {
+	name, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return nil, false, err
+	}
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("CredReadW: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return bytes.Clone(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), true, nil
+}
+
+func (sysWinCred) write(target, user string, blob []byte) error {
+	if len(blob) > credMaxBlobSize {
+		return fmt.Errorf("credential is %d bytes, over the Credential Manager limit of %d", len(blob), credMaxBlobSize)
+	}
+	name, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	userName, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         name,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return fmt.Errorf("CredWriteW: %w", err)
+	}
+	return nil
+}
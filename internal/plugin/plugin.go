@@ -0,0 +1,281 @@
+// Package plugin runs tool plugins: local executables that describe their
+// tools and handle calls over line-delimited JSON on stdin and stdout. A
+// plugin never holds SSH connections; it asks the server to run remote
+// commands, which go through the server's pool and security checks.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+const (
+	// describeTimeout bounds the describe exchange at startup.
+	describeTimeout = 10 * time.Second
+	// waitDelay is how long a plugin gets to exit after its answer, and
+	// for its output to drain after it is killed on cancellation.
+	waitDelay = 2 * time.Second
+	// maxStderr is how much of a plugin's stderr is kept for errors.
+	maxStderr = 4096
+	// maxExecs bounds the remote commands one call may run.
+	maxExecs = 100
+)
+
+// Message types of the protocol. The server sends describe or call as the
+// first line and exec_result in reply to each exec; the plugin answers
+// describe with tools, and a call with any number of exec requests and
+// then one result or error.
+const (
+	TypeDescribe   = "describe"
+	TypeTools      = "tools"
+	TypeCall       = "call"
+	TypeExec       = "exec"
+	TypeExecResult = "exec_result"
+	TypeResult     = "result"
+	TypeError      = "error"
+)
+
+// Tool is a tool a plugin offers.
+type Tool struct {
+	Name        string                      `json:"name"`
+	Description string                      `json:"description"`
+	InputSchema map[string]any              `json:"input_schema,omitempty"` // JSON schema of the arguments; default: any object
+	Annotations *config.TemplateAnnotations `json:"annotations,omitempty"`
+}
+
+// ExecRequest is a remote command a plugin asks the server to run.
+type ExecRequest struct {
+	SessionID string `json:"session_id"`
+	Command   string `json:"command"`
+	Timeout   int    `json:"timeout,omitempty"` // seconds; 0 = --command-timeout
+	Sudo      bool   `json:"sudo,omitempty"`
+}
+
+// ExecResult is the server's reply to an ExecRequest. Error is set when
+// the command could not run at all (unknown session, command denied).
+type ExecResult struct {
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exit_code"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ExecFunc runs an ExecRequest for the call whose ctx it gets.
+type ExecFunc func(ctx context.Context, req ExecRequest) ExecResult
+
+// Result is the outcome of a call. Structured, if set, is a JSON object
+// returned as structured content. IsError marks a tool-level failure the
+// plugin reported, as opposed to a plugin that failed to answer.
+type Result struct {
+	Text       string          `json:"text"`
+	Structured json.RawMessage `json:"structured,omitempty"`
+	IsError    bool            `json:"is_error,omitempty"`
+}
+
+// message is one line from a plugin.
+type message struct {
+	Type  string `json:"type"`
+	ID    int    `json:"id,omitempty"`
+	Tools []Tool `json:"tools,omitempty"`
+	ExecRequest
+	Result
+	Error string `json:"error,omitempty"`
+}
+
+// Plugin is a loaded plugin executable and the tools it described.
+type Plugin struct {
+	Path  string
+	Args  []string
+	Tools []Tool
+}
+
+// Load runs path to describe its tools and checks them.
+func Load(ctx context.Context, path string, args ...string) (*Plugin, error) {
+	p := &Plugin{Path: path, Args: args}
+	ctx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+	err := p.run(ctx, map[string]any{"type": TypeDescribe}, func(m message) (any, bool, error) {
+		if m.Type != TypeTools {
+			return nil, false, fmt.Errorf("expected %s, got %q", TypeTools, m.Type)
+		}
+		p.Tools = m.Tools
+		return nil, true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: describe: %w", path, err)
+	}
+	if err := checkTools(p.Tools); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// checkTools validates the tools a plugin described.
+func checkTools(tools []Tool) error {
+	if len(tools) == 0 {
+		return fmt.Errorf("no tools described")
+	}
+	seen := map[string]bool{}
+	for _, t := range tools {
+		switch {
+		case !config.TemplateNamePattern.MatchString(t.Name) || strings.HasPrefix(t.Name, "ssh_"):
+			return fmt.Errorf("invalid tool name %q: must be lowercase letters, digits and underscores, not starting with ssh_", t.Name)
+		case seen[t.Name]:
+			return fmt.Errorf("tool %q is described more than once", t.Name)
+		case t.Description == "":
+			return fmt.Errorf("tool %q: description is required", t.Name)
+		case t.InputSchema != nil && t.InputSchema["type"] != "object":
+			return fmt.Errorf("tool %q: input_schema must have type object", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return nil
+}
+
+// Call runs tool with args in a new plugin process. Every exec request the
+// plugin sends is passed to execute with ctx and answered, until the plugin
+// sends its result. Cancelling ctx kills the process.
+func (p *Plugin) Call(ctx context.Context, tool string, args json.RawMessage, execute ExecFunc) (*Result, error) {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	var res *Result
+	execs := 0
+	err := p.run(ctx, map[string]any{"type": TypeCall, "tool": tool, "arguments": args}, func(m message) (any, bool, error) {
+		switch m.Type {
+		case TypeExec:
+			if execs++; execs > maxExecs {
+				return nil, false, fmt.Errorf("more than %d exec requests", maxExecs)
+			}
+			r := execute(ctx, m.ExecRequest)
+			return struct {
+				Type string `json:"type"`
+				ID   int    `json:"id"`
+				ExecResult
+			}{TypeExecResult, m.ID, r}, false, nil
+		case TypeResult:
+			if s := bytes.TrimSpace(m.Structured); len(s) > 0 && s[0] != '{' {
+				return nil, false, fmt.Errorf("structured must be a JSON object")
+			}
+			r := m.Result
+			res = &r
+			return nil, true, nil
+		case TypeError:
+			if m.Error == "" {
+				m.Error = "unknown error"
+			}
+			res = &Result{Text: m.Error, IsError: true}
+			return nil, true, nil
+		default:
+			return nil, false, fmt.Errorf("unexpected message type %q", m.Type)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", tool, err)
+	}
+	return res, nil
+}
+
+// run starts the plugin, sends first and passes every message it prints
+// to handle, writing back handle's reply (if not nil), until handle says
+// it is done. The plugin's stdin is then closed and it must exit; on an
+// error it is killed.
+func (p *Plugin) run(ctx context.Context, first any, handle func(message) (reply any, done bool, err error)) error {
+	cmd := exec.CommandContext(ctx, p.Path, p.Args...)
+	cmd.WaitDelay = waitDelay
+	stderr := &tailBuffer{max: maxStderr}
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	err = converse(json.NewEncoder(stdin), json.NewDecoder(stdout), first, handle)
+	stdin.Close()
+	if err != nil {
+		cmd.Process.Kill()
+	}
+	// After its answer the plugin should exit on EOF; one that lingers is
+	// killed, but its answer stands.
+	timer := time.AfterFunc(waitDelay, func() { cmd.Process.Kill() })
+	cmd.Wait()
+	timer.Stop()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// converse runs the exchange of run over enc and dec.
+func converse(enc *json.Encoder, dec *json.Decoder, first any, handle func(message) (any, bool, error)) error {
+	if err := enc.Encode(first); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	for {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("exited without an answer")
+			}
+			return fmt.Errorf("read: %w", err)
+		}
+		reply, done, err := handle(m)
+		if err != nil || done {
+			return err
+		}
+		if reply != nil {
+			if err := enc.Encode(reply); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+	}
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf bytes.Buffer
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - b.max; over > 0 {
+		b.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
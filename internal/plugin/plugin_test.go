@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperPlugin is not a real test: it is run as a plugin by the tests
+// below, behaving as SSH_MCP_TEST_PLUGIN says.
+func TestHelperPlugin(t *testing.T) {
+	mode := os.Getenv("SSH_MCP_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+	in := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	read := func() map[string]any {
+		if !in.Scan() {
+			os.Exit(3)
+		}
+		var m map[string]any
+		json.Unmarshal(in.Bytes(), &m)
+		return m
+	}
+	req := read()
+	switch mode {
+	case "crash":
+		fmt.Fprint(os.Stderr, "boom")
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	case "bad-name":
+		out.Encode(map[string]any{"type": "tools", "tools": []map[string]any{{"name": "ssh_mine", "description": "x"}}})
+		os.Exit(0)
+	}
+	if req["type"] == "describe" {
+		out.Encode(map[string]any{"type": "tools", "tools": []map[string]any{
+			{"name": "appliance_status", "description": "Show appliance status.", "annotations": map[string]any{"read_only": true}},
+			{"name": "appliance_fail", "description": "Always fails."},
+		}})
+		os.Exit(0)
+	}
+	args := req["arguments"].(map[string]any)
+	switch req["tool"] {
+	case "appliance_status":
+		out.Encode(map[string]any{"type": "exec", "id": 7, "session_id": args["session_id"], "command": "show status"})
+		res := read()
+		if res["type"] != "exec_result" || res["id"] != 7.0 {
+			out.Encode(map[string]any{"type": "error", "error": "bad reply"})
+			os.Exit(0)
+		}
+		out.Encode(map[string]any{
+			"type":       "result",
+			"text":       fmt.Sprintf("status: %v (exit %v)", res["stdout"], res["exit_code"]),
+			"structured": map[string]any{"stdout": res["stdout"]},
+		})
+	default:
+		out.Encode(map[string]any{"type": "error", "error": "appliance unreachable"})
+	}
+	os.Exit(0)
+}
+
+// loadHelper loads the test binary as a plugin in the given mode.
+func loadHelper(t *testing.T, mode string) (*Plugin, error) {
+	t.Helper()
+	t.Setenv("SSH_MCP_TEST_PLUGIN", mode)
+	return Load(context.Background(), os.Args[0], "-test.run=^TestHelperPlugin$")
+}
+
+func TestLoad(t *testing.T) {
+	p, err := loadHelper(t, "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tools) != 2 || p.Tools[0].Name != "appliance_status" || !p.Tools[0].Annotations.ReadOnly {
+		t.Errorf("tools = %+v", p.Tools)
+	}
+
+	if _, err := loadHelper(t, "bad-name"); err == nil || !strings.Contains(err.Error(), "ssh_mine") {
+		t.Errorf("bad name: %v", err)
+	}
+	if _, err := loadHelper(t, "crash"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("crash: %v", err)
+	}
+	if _, err := Load(context.Background(), "/nonexistent/plugin"); err == nil {
+		t.Error("expected error for a missing executable")
+	}
+}
+
+func TestCall(t *testing.T) {
+	p, err := loadHelper(t, "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ExecRequest
+	execute := func(_ context.Context, req ExecRequest) ExecResult {
+		got = req
+		return ExecResult{Stdout: "green", ExitCode: 0}
+	}
+	res, err := p.Call(context.Background(), "appliance_status", json.RawMessage(`{"session_id":"admin@fw1:22"}`), execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SessionID != "admin@fw1:22" || got.Command != "show status" {
+		t.Errorf("exec request = %+v", got)
+	}
+	if res.Text != "status: green (exit 0)" || res.IsError || string(res.Structured) != `{"stdout":"green"}` {
+		t.Errorf("result = %+v (%s)", res, res.Structured)
+	}
+
+	res, err = p.Call(context.Background(), "appliance_fail", nil, execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError || res.Text != "appliance unreachable" {
+		t.Errorf("error result = %+v", res)
+	}
+}
+
+func TestCall_Cancelled(t *testing.T) {
+	p, err := loadHelper(t, "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSH_MCP_TEST_PLUGIN", "hang")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.Call(ctx, "appliance_status", nil, nil); err == nil {
+		t.Fatal("expected an error from a cancelled call")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("cancelled call took %s", time.Since(start))
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 4}
+	b.Write([]byte("abc"))
+	b.Write([]byte("def"))
+	if b.String() != "cdef" {
+		t.Errorf("tail = %q", b.String())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -50,6 +51,10 @@ type Filter struct {
 	hostDenylist  []hostMatcher
 	cmdAllowlist  []*regexp.Regexp
 	cmdDenylist   []*regexp.Regexp
+	shellParsing  bool
+	resolveHosts  bool
+	onDeny        func(kind, subject string)
+	parent        *Filter // rules that must pass first (nil = none)
 }
 
 // NewFilter creates a new Filter from string patterns.
@@ -73,9 +78,60 @@ func NewFilter(hostAllow, hostDeny, cmdAllow, cmdDeny []string) (*Filter, error)
 	return f, nil
 }
 
+// Child returns a filter that checks the given rules on top of f's: a host
+// or command must pass f first. The child follows f's shell parsing, host
+// resolution and OnDeny settings, including ones made later.
+func (f *Filter) Child(hostAllow, hostDeny, cmdAllow, cmdDeny []string) (*Filter, error) {
+	c, err := NewFilter(hostAllow, hostDeny, cmdAllow, cmdDeny)
+	if err != nil {
+		return nil, err
+	}
+	c.parent = f
+	return c, nil
+}
+
+// root returns the filter at the top of a Child chain.
+func (f *Filter) root() *Filter {
+	for f.parent != nil {
+		f = f.parent
+	}
+	return f
+}
+
+// OnDeny registers f to be called whenever a host ("host") or command
+// ("command") is rejected. It must be set before the filter is used.
+func (f *Filter) OnDeny(fn func(kind, subject string)) {
+	f.onDeny = fn
+}
+
+// denied reports a rejection to the OnDeny hook of the root filter.
+func (f *Filter) denied(kind, subject string) {
+	if hook := f.root().onDeny; hook != nil {
+		hook(kind, subject)
+	}
+}
+
 // AllowHost checks if a host is allowed.
 // Denylist has priority; empty allowlist means allow all.
 func (f *Filter) AllowHost(host string) error {
+	err := f.allowHost(host)
+	if err != nil {
+		f.denied("host", host)
+	}
+	return err
+}
+
+func (f *Filter) allowHost(host string) error {
+	if f.parent != nil {
+		if err := f.parent.allowHost(host); err != nil {
+			return err
+		}
+	}
+	return f.ownHost(host)
+}
+
+// ownHost checks host against f's rules only, ignoring the parent.
+func (f *Filter) ownHost(host string) error {
 	host = strings.ToLower(host)
 
 	for _, m := range f.hostDenylist {
@@ -96,6 +152,58 @@ func (f *Filter) AllowHost(host string) error {
 	return nil
 }
 
+// EnableHostResolution makes callers resolve host names and check the
+// addresses with AllowHostAddrs, so CIDR rules also apply to host names.
+func (f *Filter) EnableHostResolution() {
+	f.resolveHosts = true
+}
+
+// ResolvesHosts reports whether host names are resolved before filtering.
+func (f *Filter) ResolvesHosts() bool {
+	return f.root().resolveHosts
+}
+
+// AllowHostAddrs checks host and the addresses it resolved to. The host is
+// denied if its name or any address matches the denylist. With an allowlist,
+// it is allowed if its name matches or every address does. With no
+// addresses this is AllowHost.
+func (f *Filter) AllowHostAddrs(host string, addrs []net.IP) error {
+	err := f.allowHostAddrs(host, addrs)
+	if err != nil {
+		f.denied("host", host)
+	}
+	return err
+}
+
+func (f *Filter) allowHostAddrs(host string, addrs []net.IP) error {
+	if f.parent != nil {
+		if err := f.parent.allowHostAddrs(host, addrs); err != nil {
+			return err
+		}
+	}
+	host = strings.ToLower(host)
+	for _, ip := range addrs {
+		if slices.ContainsFunc(f.hostDenylist, matchesHost(ip.String())) {
+			return fmt.Errorf("host %q (%s) is denied by security policy", host, ip)
+		}
+	}
+	err := f.ownHost(host)
+	if err == nil || len(addrs) == 0 || slices.ContainsFunc(f.hostDenylist, matchesHost(host)) {
+		return err
+	}
+	// The name is not in the allowlist, so every address has to be.
+	for _, ip := range addrs {
+		if !slices.ContainsFunc(f.hostAllowlist, matchesHost(ip.String())) {
+			return fmt.Errorf("host %q (%s) is not in the allowlist", host, ip)
+		}
+	}
+	return nil
+}
+
+func matchesHost(host string) func(hostMatcher) bool {
+	return func(m hostMatcher) bool { return m.match(host) }
+}
+
 // compileHostPatterns compiles host patterns as either CIDR matchers or regex matchers.
 func compileHostPatterns(patterns []string) ([]hostMatcher, error) {
 	matchers := make([]hostMatcher, 0, len(patterns))
@@ -118,14 +226,47 @@ func compileHostPatterns(patterns []string) ([]hostMatcher, error) {
 	return matchers, nil
 }
 
+// EnableShellParsing makes AllowCommand parse commands as shell code and
+// check every command they run, not just the command line as a whole.
+func (f *Filter) EnableShellParsing() {
+	f.shellParsing = true
+}
+
+// ShellParsing reports whether commands are parsed as shell code.
+func (f *Filter) ShellParsing() bool {
+	return f.root().shellParsing
+}
+
 // AllowCommand checks if a command is allowed.
 // Denylist has priority; empty allowlist means allow all.
+//
+// With shell parsing, the command line is also split into the simple
+// commands it runs (see splitShell). Each of them, and each command it runs
+// through a wrapper such as sudo, env or xargs, is checked against the
+// denylist, and with an allowlist each must match it as a whole. Command
+// lines that can't be split are rejected.
 func (f *Filter) AllowCommand(cmd string) error {
+	err := f.allowCommand(cmd)
+	if err != nil {
+		f.denied("command", cmd)
+	}
+	return err
+}
+
+func (f *Filter) allowCommand(cmd string) error {
+	if f.parent != nil {
+		if err := f.parent.allowCommand(cmd); err != nil {
+			return err
+		}
+	}
 	for _, re := range f.cmdDenylist {
 		if re.MatchString(cmd) {
 			return fmt.Errorf("command is denied by security policy")
 		}
 	}
+	if f.ShellParsing() {
+		return f.allowShellCommand(cmd)
+	}
 
 	if len(f.cmdAllowlist) > 0 {
 		for _, re := range f.cmdAllowlist {
@@ -139,6 +280,43 @@ func (f *Filter) AllowCommand(cmd string) error {
 	return nil
 }
 
+func (f *Filter) allowShellCommand(cmd string) error {
+	cmds, err := splitShell(cmd, 0)
+	if err != nil {
+		return fmt.Errorf("command rejected by security policy: %w", err)
+	}
+	for _, c := range cmds {
+		for _, start := range c.starts {
+			for _, re := range f.cmdDenylist {
+				if re.MatchString(c.tail(start)) {
+					return fmt.Errorf("command is denied by security policy")
+				}
+			}
+		}
+	}
+	if len(f.cmdAllowlist) == 0 {
+		return nil
+	}
+	for _, c := range cmds {
+		if !f.matchAllowlist(c.String()) {
+			return fmt.Errorf("command %q is not in the allowlist", c.args[0])
+		}
+	}
+	if len(cmds) == 0 && !f.matchAllowlist(cmd) {
+		return fmt.Errorf("command is not in the allowlist")
+	}
+	return nil
+}
+
+func (f *Filter) matchAllowlist(cmd string) bool {
+	for _, re := range f.cmdAllowlist {
+		if re.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
 func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
 	compiled := make([]*regexp.Regexp, 0, len(patterns))
 	for _, p := range patterns {
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"net"
 	"strings"
 	"testing"
 )
@@ -100,6 +101,54 @@ func TestFilter_AutoAnchor_Wildcard(t *testing.T) {
 	}
 }
 
+func TestFilter_AllowHostAddrs_Denylist(t *testing.T) {
+	f, err := NewFilter(nil, []string{"10.0.0.0/8", "evil\\..*"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var denied []string
+	f.OnDeny(func(kind, subject string) { denied = append(denied, subject) })
+
+	// A name alone slips past CIDR rules; its addresses don't.
+	if err := f.AllowHostAddrs("server.internal", nil); err != nil {
+		t.Errorf("expected unresolved name allowed: %v", err)
+	}
+	err = f.AllowHostAddrs("server.internal", []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("10.1.2.3")})
+	if err == nil || !strings.Contains(err.Error(), "10.1.2.3") {
+		t.Errorf("expected denial naming 10.1.2.3, got %v", err)
+	}
+	if err := f.AllowHostAddrs("evil.example", []net.IP{net.ParseIP("192.0.2.1")}); err == nil {
+		t.Error("expected name denylist to still apply")
+	}
+	if err := f.AllowHostAddrs("web", []net.IP{net.ParseIP("192.0.2.1")}); err != nil {
+		t.Errorf("expected web allowed: %v", err)
+	}
+	if len(denied) != 2 {
+		t.Errorf("OnDeny called for %v, want two denials", denied)
+	}
+}
+
+func TestFilter_AllowHostAddrs_Allowlist(t *testing.T) {
+	f, err := NewFilter([]string{"192.168.0.0/16", "bastion"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lan := []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("192.168.1.11")}
+	if err := f.AllowHostAddrs("nas.lan", lan); err != nil {
+		t.Errorf("expected name with all addresses in range allowed: %v", err)
+	}
+	if err := f.AllowHostAddrs("nas.lan", append(lan, net.ParseIP("203.0.113.5"))); err == nil {
+		t.Error("expected denial when one address is outside the allowlist")
+	}
+	if err := f.AllowHostAddrs("bastion", []net.IP{net.ParseIP("203.0.113.5")}); err != nil {
+		t.Errorf("expected allowlisted name allowed regardless of address: %v", err)
+	}
+	if err := f.AllowHostAddrs("nas.lan", nil); err == nil {
+		t.Error("expected unresolved name outside the allowlist denied")
+	}
+}
+
 func TestFilter_AllowCommand_EmptyLists(t *testing.T) {
 	f, err := NewFilter(nil, nil, nil, nil)
 	if err != nil {
@@ -319,3 +368,68 @@ func TestFilter_CIDR_HostnameNotMatchedByCIDR(t *testing.T) {
 		t.Error("expected hostname denied (CIDR only matches IPs)")
 	}
 }
+
+func TestFilter_OnDeny(t *testing.T) {
+	f, err := NewFilter(nil, []string{"prod-.*"}, nil, []string{"reboot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var denied []string
+	f.OnDeny(func(kind, subject string) {
+		denied = append(denied, kind+":"+subject)
+	})
+	_ = f.AllowHost("dev-1")
+	_ = f.AllowHost("prod-1")
+	_ = f.AllowCommand("uptime")
+	_ = f.AllowCommand("reboot")
+	if want := []string{"host:prod-1", "command:reboot"}; strings.Join(denied, ",") != strings.Join(want, ",") {
+		t.Errorf("denials = %v, want %v", denied, want)
+	}
+}
+
+func TestFilter_Child(t *testing.T) {
+	parent, err := NewFilter(nil, []string{"prod-.*"}, nil, []string{"reboot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var denied []string
+	child, err := parent.Child([]string{"dev-.*", "prod-1"}, nil, []string{"uptime", "reboot"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Set after Child: the child must still report to it.
+	parent.OnDeny(func(kind, subject string) {
+		denied = append(denied, kind+":"+subject)
+	})
+
+	if err := child.AllowHost("dev-1"); err != nil {
+		t.Errorf("dev-1: %v", err)
+	}
+	if err := child.AllowHost("prod-1"); err == nil {
+		t.Error("prod-1 should be denied by the parent")
+	}
+	if err := child.AllowHost("stage-1"); err == nil {
+		t.Error("stage-1 should be outside the child allowlist")
+	}
+	if err := parent.AllowHost("stage-1"); err != nil {
+		t.Errorf("parent must not see child rules: %v", err)
+	}
+	if err := child.AllowCommand("uptime"); err != nil {
+		t.Errorf("uptime: %v", err)
+	}
+	if err := child.AllowCommand("reboot"); err == nil {
+		t.Error("reboot should be denied by the parent")
+	}
+	if err := child.AllowHostAddrs("db", []net.IP{net.ParseIP("10.0.0.1")}); err == nil {
+		t.Error("db should be outside the child allowlist")
+	}
+	want := []string{"host:prod-1", "host:stage-1", "command:reboot", "host:db"}
+	if strings.Join(denied, ",") != strings.Join(want, ",") {
+		t.Errorf("denials = %v, want %v", denied, want)
+	}
+
+	parent.EnableShellParsing()
+	if !child.ShellParsing() {
+		t.Error("child should follow the parent's shell parsing")
+	}
+}
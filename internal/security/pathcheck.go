@@ -8,6 +8,8 @@ import (
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
 )
 
 // MaxFilenameLength is the maximum allowed filename length (standard filesystem limit).
@@ -57,7 +59,8 @@ func ValidateFilename(name string) error {
 	return nil
 }
 
-// ValidatePath rejects paths with traversal attempts.
+// ValidatePath rejects paths with traversal attempts. Backslashes count as
+// separators too, so Windows paths such as C:\Users\me\file.txt pass.
 func ValidatePath(p string) error {
 	if p == "" {
 		return fmt.Errorf("path is empty")
@@ -74,7 +77,7 @@ func ValidatePath(p string) error {
 	}
 
 	// Validate the filename component.
-	base := path.Base(p)
+	base := path.Base(strings.ReplaceAll(p, `\`, "/"))
 	if base != "." && base != "/" {
 		if err := ValidateFilename(base); err != nil {
 			return fmt.Errorf("invalid filename in path: %w", err)
@@ -171,3 +174,42 @@ func SanitizePath(base, requested string) (string, error) {
 
 	return cleaned, nil
 }
+
+// ValidateLocalAccess checks localPath against the allowed local directories
+// (--local-base-dir). With none configured only the basic checks of
+// ValidateLocalPath apply. Otherwise the path must be inside one of them,
+// and for a write inside one that is not read-only. The most specific
+// directory decides, so a writable directory can sit inside a read-only one.
+func ValidateLocalAccess(localPath string, dirs []config.LocalDir, write bool) error {
+	if err := ValidateLocalPath(localPath, ""); err != nil {
+		return err
+	}
+	var match *config.LocalDir
+	var firstErr error
+	for i, d := range dirs {
+		if err := ValidateLocalPath(localPath, d.Path); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if match == nil || len(d.Path) > len(match.Path) {
+			match = &dirs[i]
+		}
+	}
+	switch {
+	case len(dirs) == 0:
+		return nil
+	case match == nil && len(dirs) == 1:
+		return firstErr
+	case match == nil:
+		var names []string
+		for _, d := range dirs {
+			names = append(names, d.String())
+		}
+		return fmt.Errorf("path %q is outside the allowed local directories (%s)", localPath, strings.Join(names, ", "))
+	case write && match.ReadOnly:
+		return fmt.Errorf("path %q is in read-only local directory %q; writes are not allowed there", localPath, match.Path)
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
 )
 
 func TestValidatePath_Valid(t *testing.T) {
@@ -22,6 +24,29 @@ func TestValidatePath_Valid(t *testing.T) {
 	}
 }
 
+func TestValidatePath_Windows(t *testing.T) {
+	for _, p := range []string{
+		`C:\Users\me\file.txt`,
+		`C:\Users\me\`,
+		"C:/Users/me/file.txt",
+		"/C:/Users/me",
+		`D:\`,
+		`C:`,
+	} {
+		if err := ValidatePath(p); err != nil {
+			t.Errorf("expected %q to be valid, got: %v", p, err)
+		}
+	}
+	for _, p := range []string{
+		`C:\Users\..\Windows\system32`,
+		"C:\\Users\\me\\bad\x01name",
+	} {
+		if err := ValidatePath(p); err == nil {
+			t.Errorf("expected %q to be rejected", p)
+		}
+	}
+}
+
 func TestValidatePath_Traversal(t *testing.T) {
 	tests := []string{
 		"../etc/passwd",
@@ -365,3 +390,42 @@ func TestContainsTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateLocalAccess(t *testing.T) {
+	src := t.TempDir()
+	out := filepath.Join(src, "out") // writable directory inside a read-only one
+	other := t.TempDir()
+	if err := os.Mkdir(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dirs := []config.LocalDir{{Path: src, ReadOnly: true}, {Path: out}}
+
+	for _, tt := range []struct {
+		path    string
+		write   bool
+		wantErr string
+	}{
+		{filepath.Join(src, "main.go"), false, ""},
+		{filepath.Join(src, "main.go"), true, "read-only local directory"},
+		{filepath.Join(out, "report.txt"), true, ""},
+		{filepath.Join(other, "x"), false, "outside the allowed local directories"},
+		{src + "/../x", false, "traversal"},
+	} {
+		err := ValidateLocalAccess(tt.path, dirs, tt.write)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s (write=%v): %v", tt.path, tt.write, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s (write=%v): error %v, want %q", tt.path, tt.write, err, tt.wantErr)
+		}
+	}
+
+	// A single directory keeps the detailed error of ValidateLocalPath.
+	err := ValidateLocalAccess(filepath.Join(other, "x"), dirs[:1], false)
+	if err == nil || !strings.Contains(err.Error(), "outside allowed base directory") {
+		t.Errorf("single dir error = %v", err)
+	}
+	if err := ValidateLocalAccess(filepath.Join(other, "x"), nil, true); err != nil {
+		t.Errorf("no dirs: %v", err)
+	}
+}
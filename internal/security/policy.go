@@ -0,0 +1,225 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Policy decisions a webhook may return.
+const (
+	PolicyAllow  = "allow"
+	PolicyDeny   = "deny"
+	PolicyModify = "modify"
+)
+
+// redacted replaces secret argument values sent to the policy webhook.
+const redacted = "[REDACTED]"
+
+// maxPolicyResponse bounds the webhook response body read.
+const maxPolicyResponse = 1 << 20
+
+// secretArgs are argument names whose values never leave the server.
+var secretArgs = map[string]bool{
+	"password":      true,
+	"sudo_password": true,
+	"passphrase":    true,
+	"token":         true,
+}
+
+// PolicyRequest describes a tool call to the policy webhook.
+type PolicyRequest struct {
+	Tool      string         `json:"tool"`
+	SessionID string         `json:"session_id,omitempty"`
+	Host      string         `json:"host,omitempty"`
+	Port      int            `json:"port,omitempty"`
+	User      string         `json:"user,omitempty"`
+	Commands  []string       `json:"commands,omitempty"`
+	Paths     []string       `json:"paths,omitempty"`
+	Arguments map[string]any `json:"arguments"`
+	Client    PolicyClient   `json:"client"`
+}
+
+// PolicyClient identifies the MCP client making a call.
+type PolicyClient struct {
+	SessionID string `json:"session_id,omitempty"` // MCP session, empty over stdio
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// PolicyDecision is the webhook's answer. Arguments replaces the call's
+// arguments when Decision is PolicyModify.
+type PolicyDecision struct {
+	Decision  string         `json:"decision"`
+	Reason    string         `json:"reason,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// PolicyHook asks an HTTP webhook whether a tool call may run.
+type PolicyHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewPolicyHook creates a hook posting to url, giving up after timeout.
+func NewPolicyHook(url string, timeout time.Duration) *PolicyHook {
+	return &PolicyHook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Check posts req as JSON and returns the webhook's decision. Any answer
+// other than a 2xx response with a known decision is an error.
+func (h *PolicyHook) Check(ctx context.Context, req PolicyRequest) (*PolicyDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode policy request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("policy webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicyResponse))
+	if err != nil {
+		return nil, fmt.Errorf("policy webhook: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("policy webhook returned %s", resp.Status)
+	}
+	var d PolicyDecision
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("decode policy decision: %w", err)
+	}
+	switch d.Decision {
+	case PolicyAllow, PolicyDeny:
+	case PolicyModify:
+		if d.Arguments == nil {
+			return nil, fmt.Errorf("policy webhook returned %q without arguments", PolicyModify)
+		}
+	default:
+		return nil, fmt.Errorf("policy webhook returned unknown decision %q", d.Decision)
+	}
+	return &d, nil
+}
+
+// RedactArguments returns a copy of tool arguments safe to send to the
+// webhook: secret values, at any depth, are replaced by a marker, as is the
+// password of a user:password@host string.
+func RedactArguments(args map[string]any) map[string]any {
+	out, _ := redactValue("", args).(map[string]any)
+	return out
+}
+
+func redactValue(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = redactValue(k, e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = redactValue(key, e)
+		}
+		return out
+	case string:
+		if secretArgs[key] && v != "" {
+			return redacted
+		}
+		if key == "host" {
+			return redactHostPassword(v)
+		}
+	}
+	return v
+}
+
+// redactHostPassword hides the password of a user:password@host string.
+func redactHostPassword(s string) string {
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return s
+	}
+	if colon := strings.Index(s[:at], ":"); colon >= 0 {
+		return s[:colon+1] + redacted + s[at:]
+	}
+	return s
+}
+
+// RestoreSecrets puts the original secrets back into arguments returned by
+// a modify decision: wherever modified still holds the redacted form of
+// the original value, the original is used.
+func RestoreSecrets(modified, original map[string]any) map[string]any {
+	out, _ := restoreValue("", modified, original).(map[string]any)
+	return out
+}
+
+func restoreValue(key string, mod, orig any) any {
+	switch m := mod.(type) {
+	case map[string]any:
+		o, _ := orig.(map[string]any)
+		out := make(map[string]any, len(m))
+		for k, e := range m {
+			out[k] = restoreValue(k, e, o[k])
+		}
+		return out
+	case []any:
+		o, _ := orig.([]any)
+		out := make([]any, len(m))
+		for i, e := range m {
+			var oe any
+			if i < len(o) {
+				oe = o[i]
+			}
+			out[i] = restoreValue(key, e, oe)
+		}
+		return out
+	case string:
+		if o, ok := orig.(string); ok && strings.Contains(m, redacted) && redactValue(key, o) == m {
+			return o
+		}
+	}
+	return mod
+}
+
+// CollectTargets returns the commands and paths in tool arguments, at any
+// depth so steps of ssh_plan_execute are included: values of command and
+// of keys ending in path, and the elements of paths. Keys are visited in
+// sorted order, so the result is stable.
+func CollectTargets(args map[string]any) (commands, paths []string) {
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				walk(k, v[k])
+			}
+		case []any:
+			for _, e := range v {
+				walk(key, e)
+			}
+		case string:
+			switch {
+			case v == "":
+			case key == "command":
+				commands = append(commands, v)
+			case key == "paths" || strings.HasSuffix(key, "path"):
+				paths = append(paths, v)
+			}
+		}
+	}
+	walk("", args)
+	return commands, paths
+}
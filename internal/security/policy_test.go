@@ -0,0 +1,121 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicyHook_Check(t *testing.T) {
+	var got PolicyRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		switch got.Tool {
+		case "ssh_execute":
+			w.Write([]byte(`{"decision":"deny","reason":"no rm"}`))
+		case "ssh_bogus":
+			w.Write([]byte(`{"decision":"maybe"}`))
+		case "ssh_modify":
+			w.Write([]byte(`{"decision":"modify"}`))
+		case "ssh_error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"decision":"allow"}`))
+		}
+	}))
+	defer srv.Close()
+	h := NewPolicyHook(srv.URL, 5*time.Second)
+
+	d, err := h.Check(context.Background(), PolicyRequest{Tool: "ssh_execute", Host: "web1", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Decision != PolicyDeny || d.Reason != "no rm" {
+		t.Errorf("decision = %+v, want deny with reason", d)
+	}
+	if got.Host != "web1" {
+		t.Errorf("webhook saw host %q, want web1", got.Host)
+	}
+	if d, err := h.Check(context.Background(), PolicyRequest{Tool: "ssh_ping"}); err != nil || d.Decision != PolicyAllow {
+		t.Errorf("Check(ssh_ping) = %+v, %v; want allow", d, err)
+	}
+	for _, tool := range []string{"ssh_bogus", "ssh_modify", "ssh_error"} {
+		if _, err := h.Check(context.Background(), PolicyRequest{Tool: tool}); err == nil {
+			t.Errorf("Check(%s): expected error", tool)
+		}
+	}
+}
+
+func TestPolicyHook_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	if _, err := NewPolicyHook(url, time.Second).Check(context.Background(), PolicyRequest{Tool: "ssh_ping"}); err == nil {
+		t.Error("expected error for unreachable webhook")
+	}
+}
+
+func TestRedactAndRestoreSecrets(t *testing.T) {
+	args := map[string]any{
+		"host":     "root:hunter2@web1:2222",
+		"password": "hunter2",
+		"steps": []any{
+			map[string]any{"execute": map[string]any{"command": "ls", "sudo_password": "s3cret"}},
+		},
+	}
+	red := RedactArguments(args)
+	want := map[string]any{
+		"host":     "root:" + redacted + "@web1:2222",
+		"password": redacted,
+		"steps": []any{
+			map[string]any{"execute": map[string]any{"command": "ls", "sudo_password": redacted}},
+		},
+	}
+	if !reflect.DeepEqual(red, want) {
+		t.Fatalf("RedactArguments = %v, want %v", red, want)
+	}
+	if args["password"] != "hunter2" {
+		t.Error("RedactArguments modified its input")
+	}
+
+	// The webhook changes the command and leaves the secrets redacted.
+	red["steps"].([]any)[0].(map[string]any)["execute"].(map[string]any)["command"] = "ls -la"
+	restored := RestoreSecrets(red, args)
+	step := restored["steps"].([]any)[0].(map[string]any)["execute"].(map[string]any)
+	if restored["password"] != "hunter2" || restored["host"] != "root:hunter2@web1:2222" || step["sudo_password"] != "s3cret" {
+		t.Errorf("secrets not restored: %v", restored)
+	}
+	if step["command"] != "ls -la" {
+		t.Errorf("command = %v, want the modified one", step["command"])
+	}
+
+	// A secret the webhook replaced is kept.
+	red["password"] = "other"
+	if got := RestoreSecrets(red, args)["password"]; got != "other" {
+		t.Errorf("password = %v, want other", got)
+	}
+}
+
+func TestCollectTargets(t *testing.T) {
+	cmds, paths := CollectTargets(map[string]any{
+		"command":     "uptime",
+		"remote_path": "/etc/hosts",
+		"paths":       []any{"/a", "/b"},
+		"steps": []any{
+			map[string]any{"execute": map[string]any{"command": "make"}},
+			map[string]any{"edit": map[string]any{"remote_path": "/etc/motd"}},
+		},
+	})
+	if want := []string{"uptime", "make"}; !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands = %v, want %v", cmds, want)
+	}
+	if want := []string{"/a", "/b", "/etc/hosts", "/etc/motd"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
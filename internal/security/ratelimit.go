@@ -15,7 +15,9 @@ type RateLimiter struct {
 	mu           sync.RWMutex
 	limiters     map[string]*rate.Limiter
 	lastAccessed map[string]time.Time
-	rpm          int // requests per minute
+	rpm          int           // requests per minute
+	maxWait      time.Duration // longest Wait may queue a request, 0 = never
+	onLimit      func(host string)
 }
 
 // NewRateLimiter creates a new per-host rate limiter.
@@ -31,11 +33,93 @@ func NewRateLimiter(requestsPerMinute int) *RateLimiter {
 func (r *RateLimiter) Allow(host string) error {
 	limiter := r.getLimiter(host)
 	if !limiter.Allow() {
+		if r.onLimit != nil {
+			r.onLimit(host)
+		}
 		return fmt.Errorf("rate limit exceeded for host %q (limit: %d requests/min)", host, r.rpm)
 	}
 	return nil
 }
 
+// HostUsage is a snapshot of a host's rate limit budget.
+type HostUsage struct {
+	Limit     int           // requests per minute
+	Burst     int           // requests that can be made back to back
+	Remaining int           // requests that can be made right now
+	NextIn    time.Duration // until the next request is allowed, 0 if Remaining > 0
+}
+
+// Usage reports how much of host's budget is left without consuming any.
+// A host with no requests yet has its full burst.
+func (r *RateLimiter) Usage(host string) HostUsage {
+	rps := float64(r.rpm) / 60.0
+	u := HostUsage{Limit: r.rpm, Burst: max(r.rpm/10, 1)}
+
+	r.mu.RLock()
+	limiter, ok := r.limiters[host]
+	r.mu.RUnlock()
+	if !ok {
+		u.Remaining = u.Burst
+		return u
+	}
+	tokens := limiter.Tokens()
+	if tokens >= 1 {
+		u.Remaining = int(tokens)
+	} else {
+		u.NextIn = time.Duration((1 - tokens) / rps * float64(time.Second))
+	}
+	return u
+}
+
+// MaxWait returns how long Wait queues a request over the limit (0 = never).
+func (r *RateLimiter) MaxWait() time.Duration {
+	return r.maxWait
+}
+
+// Wait is like Allow, but when a max wait is set a request over the limit
+// is queued until its token arrives instead of failing. Requests that would
+// wait longer than the max still fail at once. It returns ctx.Err() if ctx
+// ends while waiting.
+func (r *RateLimiter) Wait(ctx context.Context, host string) error {
+	if r.maxWait <= 0 {
+		return r.Allow(host)
+	}
+	res := r.getLimiter(host).Reserve()
+	delay := res.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if !res.OK() || delay > r.maxWait {
+		res.Cancel()
+		if r.onLimit != nil {
+			r.onLimit(host)
+		}
+		return fmt.Errorf("rate limit exceeded for host %q (limit: %d requests/min; next slot in %s, over the %s wait limit)",
+			host, r.rpm, delay.Round(time.Millisecond), r.maxWait)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		res.Cancel()
+		return ctx.Err()
+	}
+}
+
+// SetMaxWait makes Wait queue requests over the limit for up to d.
+// It must be set before the limiter is used.
+func (r *RateLimiter) SetMaxWait(d time.Duration) {
+	r.maxWait = d
+}
+
+// OnLimit registers f to be called for every request rejected by Allow or Wait.
+// It must be set before the limiter is used.
+func (r *RateLimiter) OnLimit(f func(host string)) {
+	r.onLimit = f
+}
+
 // Cleanup removes rate limiter entries that haven't been accessed for maxAge.
 func (r *RateLimiter) Cleanup(maxAge time.Duration) int {
 	r.mu.Lock()
@@ -99,6 +183,7 @@ func (r *RateLimiter) getLimiter(host string) *rate.Limiter {
 	}
 
 	// Token bucket: rate = rpm/60 tokens per second, burst = rpm/10 (at least 1).
+	// Usage assumes the same shape.
 	rps := rate.Limit(float64(r.rpm) / 60.0)
 	burst := max(r.rpm/10, 1)
 
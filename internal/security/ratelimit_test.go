@@ -1,7 +1,10 @@
 package security
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestRateLimiter_Allow(t *testing.T) {
@@ -66,3 +69,98 @@ func TestRateLimiter_Cleanup(t *testing.T) {
 		t.Errorf("expected request allowed after cleanup: %v", err)
 	}
 }
+
+func TestRateLimiter_OnLimit(t *testing.T) {
+	rl := NewRateLimiter(1)
+	var limited []string
+	rl.OnLimit(func(host string) { limited = append(limited, host) })
+	_ = rl.Allow("h1")
+	_ = rl.Allow("h1")
+	if len(limited) != 1 || limited[0] != "h1" {
+		t.Errorf("OnLimit calls = %v, want [h1]", limited)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	// 600/min: one token every 100ms after a burst of 60.
+	rl := NewRateLimiter(600)
+	rl.SetMaxWait(time.Second)
+	for range 60 {
+		if err := rl.Allow("h1"); err != nil {
+			t.Fatalf("burst request denied: %v", err)
+		}
+	}
+	if err := rl.Allow("h1"); err == nil {
+		t.Fatal("expected Allow to fail once the burst is spent")
+	}
+	start := time.Now()
+	if err := rl.Wait(context.Background(), "h1"); err != nil {
+		t.Fatalf("Wait within max wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Wait returned after %v, expected it to queue", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitOverMax(t *testing.T) {
+	rl := NewRateLimiter(1)
+	rl.SetMaxWait(time.Second)
+	var limited int
+	rl.OnLimit(func(string) { limited++ })
+	if err := rl.Wait(context.Background(), "h1"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	start := time.Now()
+	if err := rl.Wait(context.Background(), "h1"); err == nil {
+		t.Fatal("expected error when the next slot is beyond the max wait")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("rejection took %v, expected it at once", elapsed)
+	}
+	if limited != 1 {
+		t.Errorf("OnLimit calls = %d, want 1", limited)
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	rl := NewRateLimiter(1)
+	rl.SetMaxWait(2 * time.Minute)
+	_ = rl.Allow("h1")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(ctx, "h1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRateLimiter_WaitWithoutMax(t *testing.T) {
+	rl := NewRateLimiter(1)
+	_ = rl.Allow("h1")
+	if err := rl.Wait(context.Background(), "h1"); err == nil {
+		t.Error("expected Wait to fail at once without a max wait")
+	}
+}
+
+func TestRateLimiter_Usage(t *testing.T) {
+	rl := NewRateLimiter(60) // burst 6, one token per second
+	if u := rl.Usage("fresh"); u.Limit != 60 || u.Burst != 6 || u.Remaining != 6 || u.NextIn != 0 {
+		t.Errorf("unused host usage = %+v", u)
+	}
+	if _, ok := rl.limiters["fresh"]; ok {
+		t.Error("Usage must not create a limiter")
+	}
+
+	for range 6 {
+		_ = rl.Allow("h1")
+	}
+	u := rl.Usage("h1")
+	if u.Remaining != 0 {
+		t.Errorf("remaining = %d, want 0", u.Remaining)
+	}
+	if u.NextIn <= 0 || u.NextIn > time.Second {
+		t.Errorf("next in = %v, want (0, 1s]", u.NextIn)
+	}
+	if err := rl.Allow("h1"); err == nil {
+		t.Error("Usage must not consume tokens")
+	}
+}
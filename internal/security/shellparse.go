@@ -0,0 +1,348 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxShellDepth bounds how deep sh -c, eval and here-documents fed to a
+// shell are unpacked.
+const maxShellDepth = 4
+
+// shellNames are interpreters whose -c argument or here-document is parsed
+// as another shell command.
+var shellNames = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "mksh": true, "ash": true,
+}
+
+// IsShell reports whether name, a program name or path, is one of the POSIX
+// shells whose command lines the filter understands.
+func IsShell(name string) bool {
+	return shellNames[path.Base(name)]
+}
+
+// shellCommand is one simple command found in a command line: its words,
+// unquoted where they are literal and as written otherwise, and the
+// positions where a command run by a wrapper like sudo or env starts.
+type shellCommand struct {
+	args   []string
+	starts []int
+}
+
+// String returns the command as matched against the filter patterns.
+func (c shellCommand) String() string {
+	return strings.Join(c.args, " ")
+}
+
+// tail returns the command from word i on.
+func (c shellCommand) tail(i int) string {
+	return strings.Join(c.args[i:], " ")
+}
+
+// splitShell parses src as a bash command line and returns every simple
+// command it would run: the parts of pipelines, lists and compound
+// commands, command substitutions, and the scripts given to sh -c, su -c,
+// eval or a shell reading a here-document. It fails when a command can't be
+// known without running anything: a command name that is not a literal
+// word, a dynamic sh -c script, or a shell reading commands from a pipe.
+func splitShell(src string, depth int) ([]shellCommand, error) {
+	if depth > maxShellDepth {
+		return nil, fmt.Errorf("shell commands nested too deeply")
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(src), "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse command: %w", err)
+	}
+
+	var cmds []shellCommand
+	syntax.Walk(file, func(n syntax.Node) bool {
+		if err != nil {
+			return false
+		}
+		stmt, ok := n.(*syntax.Stmt)
+		if !ok {
+			return true
+		}
+		call, ok := stmt.Cmd.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		name, literal := literalWord(call.Args[0])
+		if !literal || strings.ContainsAny(name, "*?[{") {
+			err = fmt.Errorf("command name %s is not a literal word", wordSource(call.Args[0]))
+			return false
+		}
+		c := shellCommand{args: make([]string, len(call.Args))}
+		for i, w := range call.Args {
+			if s, ok := literalWord(w); ok {
+				c.args[i] = s
+			} else {
+				c.args[i] = wordSource(w)
+			}
+		}
+		c.starts = commandStarts(c.args)
+		cmds = append(cmds, c)
+
+		for _, start := range c.starts {
+			var scripts []string
+			if scripts, err = nestedScripts(stmt, call.Args[start:]); err != nil {
+				return false
+			}
+			for _, script := range scripts {
+				var sub []shellCommand
+				if sub, err = splitShell(script, depth+1); err != nil {
+					return false
+				}
+				cmds = append(cmds, sub...)
+			}
+		}
+		return true // words may hold command substitutions
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cmds, nil
+}
+
+// commandStarts returns 0 and the position of every command run through a
+// chain of wrappers, e.g. 0, 1 and 4 for "sudo env A=1 B=2 make".
+func commandStarts(args []string) []int {
+	starts := []int{0}
+	for i := 0; i < len(args); {
+		next := unwrap(args, i)
+		if next <= i || next >= len(args) {
+			break
+		}
+		starts = append(starts, next)
+		i = next
+	}
+	return starts
+}
+
+// unwrap returns where the command run by the wrapper at args[i] starts,
+// or -1 if args[i] is not a known wrapper.
+func unwrap(args []string, i int) int {
+	// valueOpts are the wrapper's options that take a separate value.
+	var valueOpts string
+	skipOperand := false // wrapper takes one operand before the command
+	switch path.Base(args[i]) {
+	case "sudo", "doas":
+		valueOpts = "ugCDhprtTUc"
+	case "env":
+		valueOpts = "uSC"
+	case "nice":
+		valueOpts = "n"
+	case "ionice":
+		valueOpts = "cnp"
+	case "timeout":
+		valueOpts = "sk"
+		skipOperand = true
+	case "stdbuf":
+		valueOpts = "ioe"
+	case "chroot":
+		skipOperand = true
+	case "xargs":
+		valueOpts = "aEdILnPs"
+	case "exec":
+		valueOpts = "a"
+	case "nohup", "time", "command", "builtin", "setsid", "busybox":
+	default:
+		return -1
+	}
+	j := i + 1
+	for j < len(args) {
+		a := args[j]
+		if a == "--" {
+			j++
+			break
+		}
+		if len(a) > 1 && a[0] == '-' {
+			if !strings.HasPrefix(a, "--") && strings.ContainsRune(valueOpts, rune(a[len(a)-1])) {
+				j++ // -u root
+			}
+			j++
+			continue
+		}
+		if path.Base(args[i]) == "env" && strings.Contains(a, "=") {
+			j++
+			continue
+		}
+		break
+	}
+	if skipOperand {
+		j++
+	}
+	return j
+}
+
+// nestedScripts returns the shell code a command runs that is not part of
+// its own text: the script of sh -c, su -c and eval, or the here-document
+// of a shell reading stdin.
+func nestedScripts(stmt *syntax.Stmt, args []*syntax.Word) ([]string, error) {
+	name, _ := literalWord(args[0])
+	switch {
+	case name == "eval":
+		parts := make([]string, 0, len(args)-1)
+		for _, w := range args[1:] {
+			s, ok := literalWord(w)
+			if !ok {
+				return nil, fmt.Errorf("eval of %s cannot be checked", wordSource(w))
+			}
+			parts = append(parts, s)
+		}
+		return []string{strings.Join(parts, " ")}, nil
+	case path.Base(name) == "su" || path.Base(name) == "runuser":
+		for i, w := range args[1:] {
+			a, _ := literalWord(w)
+			var script *syntax.Word
+			switch {
+			case (a == "-c" || a == "--command") && i+2 < len(args):
+				script = args[i+2]
+			case strings.HasPrefix(a, "--command="):
+				return []string{strings.TrimPrefix(a, "--command=")}, nil
+			default:
+				continue
+			}
+			s, ok := literalWord(script)
+			if !ok {
+				return nil, fmt.Errorf("%s -c script %s cannot be checked", name, wordSource(script))
+			}
+			return []string{s}, nil
+		}
+		return nil, nil
+	case !shellNames[path.Base(name)]:
+		return nil, nil
+	}
+
+	// sh [options] [-c script | file] ...
+	hasC := false
+	operand := -1
+	for i := 1; i < len(args); i++ {
+		a, _ := literalWord(args[i])
+		if a == "--" {
+			if i+1 < len(args) {
+				operand = i + 1
+			}
+			break
+		}
+		if len(a) > 1 && (a[0] == '-' || a[0] == '+') {
+			if strings.HasPrefix(a, "--") {
+				continue
+			}
+			if a[0] == '-' && strings.ContainsRune(a[1:], 'c') {
+				hasC = true
+			}
+			if strings.ContainsAny(a[1:], "oO") {
+				i++ // -o pipefail
+			}
+			continue
+		}
+		operand = i
+		break
+	}
+	switch {
+	case hasC && operand < 0:
+		return nil, fmt.Errorf("%s -c without a script", name)
+	case hasC:
+		s, ok := literalWord(args[operand])
+		if !ok {
+			return nil, fmt.Errorf("%s -c script %s cannot be checked", name, wordSource(args[operand]))
+		}
+		return []string{s}, nil
+	case operand >= 0:
+		return nil, nil // runs a script file
+	}
+
+	// The shell reads commands from stdin.
+	for _, r := range stmt.Redirs {
+		switch r.Op {
+		case syntax.Hdoc, syntax.DashHdoc:
+			if s, ok := literalWord(r.Hdoc); ok || r.Hdoc == nil {
+				return []string{s}, nil
+			}
+			return nil, fmt.Errorf("here-document for %s cannot be checked", name)
+		case syntax.WordHdoc:
+			if s, ok := literalWord(r.Word); ok {
+				return []string{s}, nil
+			}
+			return nil, fmt.Errorf("here-string for %s cannot be checked", name)
+		case syntax.RdrIn:
+			return nil, nil // a script file
+		}
+	}
+	return nil, fmt.Errorf("%s reading commands from stdin cannot be checked", name)
+}
+
+// literalWord returns the value of w with quotes and escapes removed, and
+// false if it has expansions (parameters, command substitutions,
+// arithmetic, $'...' strings) whose value is only known when run.
+func literalWord(w *syntax.Word) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(p.Value, ""))
+		case *syntax.SglQuoted:
+			if p.Dollar {
+				return "", false
+			}
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			if p.Dollar {
+				return "", false
+			}
+			for _, dp := range p.Parts {
+				lit, ok := dp.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				b.WriteString(unescape(lit.Value, "$`\"\\\n"))
+			}
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// unescape removes backslash escapes from a literal: of any character
+// outside quotes (only), or of the characters in special inside double
+// quotes. A backslash-newline is removed entirely.
+func unescape(s, special string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		next := s[i+1]
+		switch {
+		case next == '\n':
+			i++
+		case special == "" || strings.IndexByte(special, next) >= 0:
+			b.WriteByte(next)
+			i++
+		default:
+			b.WriteByte('\\')
+		}
+	}
+	return b.String()
+}
+
+// wordSource returns w as written.
+func wordSource(w *syntax.Word) string {
+	var b strings.Builder
+	if err := syntax.NewPrinter().Print(&b, w); err != nil {
+		return "?"
+	}
+	return b.String()
+}
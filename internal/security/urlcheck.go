@@ -0,0 +1,46 @@
+package security
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateFetchURL parses rawURL and checks that ssh_fetch_url may download
+// it: the scheme must be https and the host must pass AllowDomain.
+func ValidateFetchURL(rawURL string, domains []string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("only https URLs can be fetched, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("URL has no host")
+	}
+	if err := AllowDomain(u.Hostname(), domains); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// AllowDomain checks host against a --fetch-allow-domain list. An entry
+// matches that exact name; an entry "*.example.com" matches any subdomain
+// of example.com but not example.com itself. An empty list allows nothing.
+func AllowDomain(host string, domains []string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if suffix, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return nil
+			}
+			continue
+		}
+		if host == d {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %q is not in the fetch allowlist", host)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/alert"
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// watchSecurityEvents routes denials from the filter, the rate limiter and
+// host key checks to the alert webhooks.
+func (s *Server) watchSecurityEvents(auth *connection.AuthDiscovery) {
+	s.filter.OnDeny(func(kind, subject string) {
+		e := alert.Event{Type: config.AlertCommandDenied, Message: "command rejected by the command filter", Command: subject}
+		if kind == "host" {
+			e = alert.Event{Type: config.AlertHostDenied, Message: "host rejected by the host filter", Host: subject}
+		}
+		s.alerts.Notify(e)
+	})
+	s.rateLimiter.OnLimit(func(host string) {
+		s.alerts.Notify(alert.Event{
+			Type:    config.AlertRateLimited,
+			Message: fmt.Sprintf("requests over the rate limit of %d/min", s.cfg.Security.RateLimit),
+			Host:    host,
+		})
+	})
+	auth.OnHostKeyChanged(func(host string, key ssh.PublicKey) {
+		s.alerts.Notify(alert.Event{
+			Type:    config.AlertHostKeyChanged,
+			Message: fmt.Sprintf("host key %s does not match known_hosts, connection refused", ssh.FingerprintSHA256(key)),
+			Host:    host,
+		})
+	})
+}
+
+// sudoAlertMiddleware alerts on tool calls that use sudo or run_as,
+// including steps of ssh_plan_execute. It runs inside policyMiddleware, so
+// calls the policy denies are not reported as sudo use.
+func (s *Server) sudoAlertMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+		var args map[string]any
+		if json.Unmarshal(call.Params.Arguments, &args) == nil {
+			if how := privilegeUse(args); how != "" {
+				sessionID := callSessionID(call.Params)
+				commands, _ := security.CollectTargets(args)
+				s.alerts.Notify(alert.Event{
+					Type:      config.AlertSudo,
+					Message:   "tool call runs " + how,
+					Host:      connection.ParseHostString(sessionID).Host,
+					SessionID: sessionID,
+					Tool:      call.Params.Name,
+					Command:   strings.Join(commands, "; "),
+				})
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// privilegeUse describes how tool arguments switch user: "with sudo" or
+// "as <user>", at any depth. Empty if they don't.
+func privilegeUse(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		if u, _ := v["run_as"].(string); u != "" {
+			return "as " + u
+		}
+		if sudo, _ := v["sudo"].(bool); sudo {
+			return "with sudo"
+		}
+		for _, e := range v {
+			if how := privilegeUse(e); how != "" {
+				return how
+			}
+		}
+	case []any:
+		for _, e := range v {
+			if how := privilegeUse(e); how != "" {
+				return how
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrivilegeUse(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"command":"ls"}`, ""},
+		{`{"command":"ls","sudo":false}`, ""},
+		{`{"command":"apt upgrade","sudo":true}`, "with sudo"},
+		{`{"command":"psql","run_as":"postgres"}`, "as postgres"},
+		{`{"steps":[{"edit":{"remote_path":"/a"}},{"execute":{"command":"reboot","sudo":true}}]}`, "with sudo"},
+	}
+	for _, tt := range tests {
+		var args map[string]any
+		if err := json.Unmarshal([]byte(tt.args), &args); err != nil {
+			t.Fatal(err)
+		}
+		if got := privilegeUse(args); got != tt.want {
+			t.Errorf("privilegeUse(%s) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
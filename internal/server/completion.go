@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/tools"
+)
+
+// completeArgument answers completion/complete. MCP only asks for completions
+// of prompt and resource template arguments, so this serves the templates in
+// resources.go, matching on the argument name alone.
+func (s *Server) completeArgument(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	var args map[string]string
+	if req.Params.Context != nil {
+		args = req.Params.Context.Arguments
+	}
+	deps := &tools.CompletionDeps{Pool: s.pool, Auth: s.auth, RateLimiter: s.fileOpsRateLimiter()}
+	c, err := tools.HandleComplete(ctx, deps, req.Params.Argument.Name, req.Params.Argument.Value, args)
+	if err != nil {
+		return nil, err
+	}
+	values := c.Values
+	if values == nil {
+		values = []string{}
+	}
+	return &mcp.CompleteResult{
+		Completion: mcp.CompletionResultDetails{Values: values, Total: c.Total, HasMore: c.HasMore},
+	}, nil
+}
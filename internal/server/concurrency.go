@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+// callLimiter bounds how many tool calls run at once, and optionally runs
+// calls on the same SSH session one at a time.
+type callLimiter struct {
+	slots     chan struct{} // nil = unlimited
+	serialize bool
+
+	mu    sync.Mutex
+	locks map[sessionLockKey]*sessionLock
+}
+
+// sessionLockKey identifies an SSH session across owners.
+type sessionLockKey struct {
+	owner string
+	id    string
+}
+
+// sessionLock is a mutex for one session, reference counted so the map
+// only holds sessions with calls in flight.
+type sessionLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newCallLimiter(maxConcurrent int, serialize bool) *callLimiter {
+	l := &callLimiter{
+		serialize: serialize,
+		locks:     make(map[sessionLockKey]*sessionLock),
+	}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// acquire waits for the session lock (if serializing) and then a global
+// slot, returning a func that releases both. Waiting for the session lock
+// first keeps queued calls from holding global slots. It fails only if ctx
+// is cancelled while waiting.
+func (l *callLimiter) acquire(ctx context.Context, owner, sessionID string) (func(), error) {
+	var unlock func()
+	if l.serialize && sessionID != "" {
+		var err error
+		if unlock, err = l.lockSession(ctx, sessionLockKey{owner, sessionID}); err != nil {
+			return nil, err
+		}
+	}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			if unlock != nil {
+				unlock()
+			}
+			return nil, fmt.Errorf("waiting for a free tool slot (limit: %d): %w", cap(l.slots), ctx.Err())
+		}
+	}
+	return func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+		if unlock != nil {
+			unlock()
+		}
+	}, nil
+}
+
+func (l *callLimiter) lockSession(ctx context.Context, key sessionLockKey) (func(), error) {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sessionLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	drop := func() {
+		l.mu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			drop()
+		}, nil
+	case <-ctx.Done():
+		drop()
+		return nil, fmt.Errorf("waiting for another call on session %q: %w", key.id, ctx.Err())
+	}
+}
+
+// concurrencyMiddleware applies the call limiter to tools/call requests.
+// It wraps auditMiddleware, so audit durations measure the call itself and
+// not the time spent waiting for a slot.
+func (s *Server) concurrencyMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+		release, err := s.calls.acquire(ctx, connection.OwnerFrom(ctx), callSessionID(call.Params))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return next(ctx, method, req)
+	}
+}
+
+// callSessionID returns the SSH session a tool call targets: session_id, or
+// source_session_id for transfers between sessions. Empty if neither is set.
+func callSessionID(params *mcp.CallToolParamsRaw) string {
+	var args struct {
+		SessionID       string `json:"session_id"`
+		SourceSessionID string `json:"source_session_id"`
+	}
+	if json.Unmarshal(params.Arguments, &args) != nil {
+		return ""
+	}
+	if args.SessionID != "" {
+		return args.SessionID
+	}
+	return args.SourceSessionID
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// runConcurrent starts n calls through the limiter, each holding its slot for
+// hold, and returns the highest number observed running at once.
+func runConcurrent(t *testing.T, l *callLimiter, n int, sessionID func(i int) string, hold time.Duration) int32 {
+	t.Helper()
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release, err := l.acquire(context.Background(), "", sessionID(i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			cur := running.Add(1)
+			for {
+				p := peak.Load()
+				if cur <= p || peak.CompareAndSwap(p, cur) {
+					break
+				}
+			}
+			time.Sleep(hold)
+			running.Add(-1)
+		}(i)
+	}
+	wg.Wait()
+	return peak.Load()
+}
+
+func TestCallLimiter_GlobalLimit(t *testing.T) {
+	l := newCallLimiter(2, false)
+	peak := runConcurrent(t, l, 8, func(int) string { return "s" }, 20*time.Millisecond)
+	if peak != 2 {
+		t.Errorf("expected at most 2 concurrent calls, peak was %d", peak)
+	}
+}
+
+func TestCallLimiter_SerializeSessions(t *testing.T) {
+	l := newCallLimiter(0, true)
+	peak := runConcurrent(t, l, 5, func(int) string { return "same" }, 10*time.Millisecond)
+	if peak != 1 {
+		t.Errorf("expected calls on one session to run one at a time, peak was %d", peak)
+	}
+	if len(l.locks) != 0 {
+		t.Errorf("expected session locks to be released, %d left", len(l.locks))
+	}
+
+	// Different sessions still run in parallel.
+	peak = runConcurrent(t, l, 4, func(i int) string { return string(rune('a' + i)) }, 50*time.Millisecond)
+	if peak < 2 {
+		t.Errorf("expected different sessions to run in parallel, peak was %d", peak)
+	}
+}
+
+func TestCallLimiter_CancelWhileWaiting(t *testing.T) {
+	l := newCallLimiter(1, false)
+	release, err := l.acquire(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "", ""); err == nil {
+		t.Error("expected error when context is cancelled while waiting")
+	}
+}
+
+func TestCallSessionID(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"session_id":"a","source_session_id":"b"}`, "a"},
+		{`{"source_session_id":"b"}`, "b"},
+		{`{"host":"x"}`, ""},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		got := callSessionID(&mcp.CallToolParamsRaw{Arguments: json.RawMessage(tt.args)})
+		if got != tt.want {
+			t.Errorf("callSessionID(%s) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// drainer tracks in-flight tool calls so shutdown can wait for them, and
+// refuses new ones once draining has started.
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	active   int
+	idle     chan struct{} // closed when active drops to 0 while draining
+}
+
+// begin registers a tool call. It returns false if the server is draining.
+func (d *drainer) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+// end marks a call registered by begin as finished.
+func (d *drainer) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.draining && d.active == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// drain stops new calls and waits up to grace for running ones to finish.
+// It returns the number of calls still running when it gave up.
+func (d *drainer) drain(grace time.Duration) int {
+	d.mu.Lock()
+	d.draining = true
+	if d.active == 0 {
+		d.mu.Unlock()
+		return 0
+	}
+	idle := make(chan struct{})
+	d.idle = idle
+	d.mu.Unlock()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return 0
+	case <-timer.C:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.active
+	}
+}
+
+// drainMiddleware counts running tool calls and rejects new ones once
+// shutdown has begun. It is the outermost middleware, so calls queued by
+// concurrencyMiddleware count as running and are waited for.
+func (s *Server) drainMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		inflight := &s.root().inflight
+		if !inflight.begin() {
+			return nil, fmt.Errorf("server is shutting down, not accepting new tool calls")
+		}
+		defer inflight.end()
+		return next(ctx, method, req)
+	}
+}
+
+// drainCalls waits up to the configured grace period for running tool calls
+// before the transports and SSH connections are torn down.
+func (s *Server) drainCalls() {
+	grace := s.cfg.Transport.ShutdownGrace
+	if grace <= 0 {
+		return
+	}
+	log.Printf("Draining in-flight tool calls (grace period %s)...", grace)
+	if n := s.inflight.drain(grace); n > 0 {
+		log.Printf("Grace period expired with %d tool call(s) still running", n)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestDrainer_WaitsForRunningCalls(t *testing.T) {
+	var d drainer
+	if !d.begin() {
+		t.Fatal("begin refused before draining")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		d.end()
+	}()
+
+	start := time.Now()
+	if n := d.drain(2 * time.Second); n != 0 {
+		t.Errorf("expected all calls to finish, %d still running", n)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("drain returned before the running call finished")
+	}
+	if d.begin() {
+		t.Error("begin accepted a call while draining")
+	}
+}
+
+func TestDrainer_GraceExpires(t *testing.T) {
+	var d drainer
+	d.begin()
+	d.begin()
+	d.end()
+
+	if n := d.drain(20 * time.Millisecond); n != 1 {
+		t.Errorf("expected 1 call still running, got %d", n)
+	}
+	d.end() // a late finish after giving up must not panic
+}
+
+func TestDrainer_Idle(t *testing.T) {
+	var d drainer
+	if n := d.drain(time.Hour); n != 0 {
+		t.Errorf("expected 0, got %d", n)
+	}
+}
+
+func TestDrainMiddleware_RejectsWhileDraining(t *testing.T) {
+	s := &Server{}
+	calls := 0
+	h := s.drainMiddleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		calls++
+		return nil, nil
+	})
+
+	call := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "ssh_execute"}}
+	if _, err := h(context.Background(), "tools/call", call); err != nil {
+		t.Fatalf("unexpected error before draining: %v", err)
+	}
+
+	s.inflight.drain(time.Second)
+
+	if _, err := h(context.Background(), "tools/call", call); err == nil {
+		t.Error("expected tool call to be rejected while draining")
+	}
+	if _, err := h(context.Background(), "tools/list", &mcp.ListToolsRequest{}); err != nil {
+		t.Errorf("non-tool methods should pass while draining: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls to reach the handler, got %d", calls)
+	}
+}
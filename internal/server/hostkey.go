@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+// hostKeySchema is the form shown to confirm a host key: a single checkbox.
+// It has no default, which the SDK would fail to apply to the empty content
+// of a declined form.
+var hostKeySchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"trust": map[string]any{
+			"type":        "boolean",
+			"title":       "Trust this host key",
+			"description": "Save the key to known_hosts and connect",
+		},
+	},
+}
+
+// elicitHostKey returns a tools.ConnectDeps.ConfirmHostKey that asks the
+// user of ss, through MCP elicitation, whether to trust the key. Only an
+// accepted form with trust checked counts as yes. Clients without
+// elicitation support get an error, so the original failure stands.
+func elicitHostKey(ss *mcp.ServerSession) func(context.Context, *connection.HostKeyError) (bool, error) {
+	return func(ctx context.Context, e *connection.HostKeyError) (bool, error) {
+		if ss == nil {
+			return false, fmt.Errorf("no client session")
+		}
+		res, err := ss.Elicit(ctx, &mcp.ElicitParams{
+			Message:         hostKeyMessage(e),
+			RequestedSchema: hostKeySchema,
+		})
+		if err != nil {
+			return false, err
+		}
+		trust, _ := res.Content["trust"].(bool)
+		return res.Action == "accept" && trust, nil
+	}
+}
+
+// hostKeyMessage is the text the user confirms a host key with.
+func hostKeyMessage(e *connection.HostKeyError) string {
+	var b strings.Builder
+	if e.Changed() {
+		fmt.Fprintf(&b, "WARNING: the host key of %s has CHANGED. Someone may be impersonating the host (man-in-the-middle). Only trust the new key if you know it was replaced.\n\n", e.Host)
+	} else {
+		fmt.Fprintf(&b, "%s is not in known_hosts. Check its fingerprint out of band before trusting it.\n\n", e.Host)
+	}
+	fmt.Fprintf(&b, "Presented key: %s %s", e.KeyType, e.Fingerprint)
+	for _, k := range e.Known {
+		fmt.Fprintf(&b, "\nKnown key:     %s %s (%s:%d)", k.KeyType, k.Fingerprint, k.File, k.Line)
+	}
+	return b.String()
+}
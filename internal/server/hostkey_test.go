@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+// elicitSession connects a client answering elicitations with answer and
+// returns the server side of the session.
+func elicitSession(t *testing.T, answer func(*mcp.ElicitRequest) (*mcp.ElicitResult, error)) *mcp.ServerSession {
+	t.Helper()
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1"}, nil)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	var opts *mcp.ClientOptions
+	if answer != nil {
+		opts = &mcp.ClientOptions{ElicitationHandler: func(_ context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			return answer(req)
+		}}
+	}
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, opts).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return ss
+}
+
+func TestElicitHostKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyErr := &connection.HostKeyError{
+		Host: "web1", Key: key, KeyType: key.Type(), Fingerprint: ssh.FingerprintSHA256(key),
+		Known: []connection.KnownHostKey{{KeyType: "ssh-rsa", Fingerprint: "SHA256:old", File: "/k", Line: 3}},
+	}
+
+	var message string
+	reply := func(action string, content map[string]any) func(*mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+		return func(req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			message = req.Params.Message
+			return &mcp.ElicitResult{Action: action, Content: content}, nil
+		}
+	}
+	tests := []struct {
+		name   string
+		answer func(*mcp.ElicitRequest) (*mcp.ElicitResult, error)
+		want   bool
+	}{
+		{"trusted", reply("accept", map[string]any{"trust": true}), true},
+		{"accepted unchecked", reply("accept", map[string]any{"trust": false}), false},
+		{"declined", reply("decline", nil), false},
+		{"cancelled", reply("cancel", nil), false},
+	}
+	for _, tt := range tests {
+		ok, err := elicitHostKey(elicitSession(t, tt.answer))(context.Background(), keyErr)
+		if err != nil || ok != tt.want {
+			t.Errorf("%s: got %v, %v; want %v", tt.name, ok, err, tt.want)
+		}
+	}
+	for _, want := range []string{"CHANGED", "web1", keyErr.Fingerprint, "SHA256:old (/k:3)"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message lacks %q:\n%s", want, message)
+		}
+	}
+
+	if _, err := elicitHostKey(elicitSession(t, nil))(context.Background(), keyErr); err == nil {
+		t.Error("client without elicitation: expected error")
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// accessRecordKey is the context key for the per-request accessRecord.
+type accessRecordKey struct{}
+
+// accessRecord collects what inner middlewares learn about a request so the
+// access log line can report it.
+type accessRecord struct {
+	auth string // "none", "ok" or "denied"; "-" if auth never ran
+}
+
+// setAuthResult records the outcome of bearer token checking, if the request
+// is being access-logged.
+func setAuthResult(r *http.Request, result string) {
+	if rec, ok := r.Context().Value(accessRecordKey{}).(*accessRecord); ok {
+		rec.auth = result
+	}
+}
+
+// statusWriter captures the status code and body size written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps SSE streaming working through the wrapper.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// clientIP returns the host part of the request's remote address. Forwarding
+// headers are ignored: the listener is bound to localhost, so anything in
+// front of it is trusted infrastructure, not a client to be identified.
+func clientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// accessLogMiddleware logs one key=value line per HTTP request once the
+// handler returns. For streaming responses that is when the stream ends.
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessRecord{auth: "-"}
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), accessRecordKey{}, rec)))
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("http access: method=%s path=%s ip=%s auth=%s status=%d bytes=%d duration=%s",
+			r.Method, r.URL.Path, clientIP(r.RemoteAddr), rec.auth, status, sw.bytes,
+			time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// rateLimitMiddleware rejects requests from client IPs that exceed their
+// per-minute budget with 429 Too Many Requests.
+func rateLimitMiddleware(limiter *security.RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := limiter.Allow(clientIP(r.RemoteAddr)); err != nil {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyLimitMiddleware rejects request bodies larger than maxBytes with
+// 413 Request Entity Too Large. Bodies with a declared Content-Length are
+// refused up front; chunked bodies fail when the reader crosses the limit.
+func bodyLimitMiddleware(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// perIPListener caps the number of open connections per client IP. Excess
+// connections are closed as soon as they are accepted.
+type perIPListener struct {
+	net.Listener
+	max int
+
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+func newPerIPListener(l net.Listener, max int) *perIPListener {
+	return &perIPListener{Listener: l, max: max, conns: make(map[string]int)}
+}
+
+// Accept returns the next connection whose IP is under the limit.
+func (l *perIPListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := clientIP(c.RemoteAddr().String())
+
+		l.mu.Lock()
+		if l.conns[ip] >= l.max {
+			l.mu.Unlock()
+			log.Printf("HTTP connection from %s refused: %d connections per IP limit reached", ip, l.max)
+			c.Close()
+			continue
+		}
+		l.conns[ip]++
+		l.mu.Unlock()
+
+		return &perIPConn{Conn: c, release: func() { l.release(ip) }}, nil
+	}
+}
+
+func (l *perIPListener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conns[ip]--
+	if l.conns[ip] <= 0 {
+		delete(l.conns, ip)
+	}
+}
+
+// perIPConn gives its slot back to the listener when closed.
+type perIPConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *perIPConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// httpHandler wraps the MCP mux with authentication, tenant routing and, as
+// configured, body size limits, per-IP rate limiting and access logging.
+// Logging is the outermost layer so rejected requests are logged too.
+func (s *Server) httpHandler(ctx context.Context, mux http.Handler) http.Handler {
+	tc := s.cfg.Transport
+	h := s.authMiddleware(mux)
+	if len(s.tenants) > 0 {
+		h = s.tenantMiddleware(h)
+	}
+	if tc.MaxBodySize > 0 {
+		h = bodyLimitMiddleware(tc.MaxBodySize, h)
+	}
+	if tc.RateLimit > 0 {
+		limiter := security.NewRateLimiter(tc.RateLimit)
+		limiter.StartCleanup(ctx, 10*time.Minute, 30*time.Minute)
+		h = rateLimitMiddleware(limiter, h)
+	}
+	if tc.AccessLog {
+		h = accessLogMiddleware(h)
+	}
+	return h
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestAccessLogMiddleware_LogsAuthResult(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+
+	cfg := testConfig()
+	cfg.Transport.HTTPToken = "secret123"
+	cfg.Transport.AccessLog = true
+	s := &Server{cfg: cfg}
+	h := s.httpHandler(context.Background(), okHandler())
+
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	req.RemoteAddr = "192.0.2.7:5555"
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/mcp", nil)
+	req.RemoteAddr = "192.0.2.7:5556"
+	req.Header.Set("Authorization", "Bearer secret123")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	for _, want := range []string{
+		"method=POST path=/mcp ip=192.0.2.7 auth=denied status=401",
+		"method=GET path=/mcp ip=192.0.2.7 auth=ok status=200 bytes=2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("access log missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_PerIP(t *testing.T) {
+	h := rateLimitMiddleware(security.NewRateLimiter(20), okHandler()) // burst of 2
+
+	do := func(ip string) int {
+		req := httptest.NewRequest("GET", "/mcp", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := do("192.0.2.1"); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := do("192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 after budget is spent, got %d", code)
+	}
+	if code := do("192.0.2.2"); code != http.StatusOK {
+		t.Errorf("other IP should have its own budget, got %d", code)
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	h := bodyLimitMiddleware(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"within limit", "12345678", false, http.StatusOK},
+		{"content-length over limit", "123456789", false, http.StatusRequestEntityTooLarge},
+		{"chunked over limit", "123456789", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/mcp", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPerIPListener_LimitsConnections(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newPerIPListener(inner, 1)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	dial := func() net.Conn {
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	first := dial()
+	defer first.Close()
+	var server1 net.Conn
+	select {
+	case server1 = <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("first connection not accepted")
+	}
+
+	// The second connection is over the limit and closed by the listener.
+	second := dial()
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err == nil {
+		t.Error("expected second connection to be closed")
+	}
+
+	// Closing the first frees the slot for a new connection.
+	server1.Close()
+	third := dial()
+	defer third.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(2 * time.Second):
+		t.Error("connection after release not accepted")
+	}
+}
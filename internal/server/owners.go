@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+// ownerMiddleware binds every request to the owner of the SSH sessions it may
+// see and use: the MCP session over HTTP, so one client cannot reach another
+// client's sessions with the same bearer token. Stdio has a single client and
+// --shared-sessions opts out, both using the shared namespace. Owners of a
+// tenant are prefixed with its name, so tenants never share sessions.
+func (s *Server) ownerMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	prefix := ""
+	if s.tenant != "" {
+		prefix = "tenant:" + s.tenant + "/"
+	}
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		owner := prefix
+		if ss, ok := req.GetSession().(*mcp.ServerSession); ok && !s.cfg.Transport.SharedSessions {
+			if id := ss.ID(); id != "" {
+				owner = prefix + id
+				s.trackOwner(ss, owner)
+			}
+		}
+		return next(connection.WithOwner(ctx, owner), method, req)
+	}
+}
+
+// trackOwner closes owner's SSH sessions, terminals and tunnels once its MCP
+// session ends, since nobody else can use them.
+func (s *Server) trackOwner(ss *mcp.ServerSession, owner string) {
+	s.ownersMu.Lock()
+	defer s.ownersMu.Unlock()
+	if s.owners[owner] {
+		return
+	}
+	if s.owners == nil {
+		s.owners = make(map[string]bool)
+	}
+	s.owners[owner] = true
+
+	go func() {
+		ss.Wait()
+		s.releaseOwner(owner)
+	}()
+}
+
+// releaseOwner closes everything owned by an MCP session that ended.
+func (s *Server) releaseOwner(owner string) {
+	ids := s.pool.CloseOwner(owner)
+	for _, id := range ids {
+		s.closeSessionChildren(owner, id)
+	}
+	if len(ids) > 0 {
+		log.Printf("MCP session ended, closed its %d SSH session(s)", len(ids))
+		s.notifyResources(sessionsResourceURI)
+	}
+
+	s.ownersMu.Lock()
+	delete(s.owners, owner)
+	s.ownersMu.Unlock()
+}
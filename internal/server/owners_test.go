@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectHTTPClient connects an MCP client to srv over the streamable HTTP
+// transport, so it gets its own MCP session ID.
+func connectHTTPClient(t *testing.T, url string) *mcp.ClientSession {
+	t.Helper()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, nil)
+	cs, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{Endpoint: url}, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+// auditEntries reads the audit resource as cs.
+func auditEntries(t *testing.T, cs *mcp.ClientSession) []AuditEntry {
+	t.Helper()
+	res, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: auditResourceURI})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	var entries []AuditEntry
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &entries); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	return entries
+}
+
+func TestOwnerMiddleware_HTTPClientsIsolated(t *testing.T) {
+	for _, shared := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.Transport.SharedSessions = shared
+		srv, err := New(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		hs := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return srv.mcpServer }, nil))
+		t.Cleanup(hs.Close) // after the clients' cleanups close their streams
+
+		a := connectHTTPClient(t, hs.URL)
+		b := connectHTTPClient(t, hs.URL)
+		if _, err := a.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "ssh_execute",
+			Arguments: map[string]any{"session_id": "nobody@nowhere:22", "command": "uptime"},
+		}); err != nil {
+			t.Fatalf("CallTool: %v", err)
+		}
+
+		if got := len(auditEntries(t, a)); got != 1 {
+			t.Errorf("shared=%v: client A sees %d audit entries, want 1", shared, got)
+		}
+		want := 0
+		if shared {
+			want = 1
+		}
+		if got := len(auditEntries(t, b)); got != want {
+			t.Errorf("shared=%v: client B sees %d audit entries, want %d", shared, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/plugin"
+	"github.com/n0madic/ssh-mcp/internal/tools"
+)
+
+// loadPlugins describes every --plugin executable. A tool name may only be
+// used once across plugins and command templates.
+func (s *Server) loadPlugins(ctx context.Context) error {
+	names := map[string]string{}
+	for _, t := range s.cfg.CommandTemplates {
+		names[t.Name] = "command template"
+	}
+	for _, path := range s.cfg.Plugins {
+		p, err := plugin.Load(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, t := range p.Tools {
+			if owner, ok := names[t.Name]; ok {
+				return fmt.Errorf("plugin %s: tool %q is already defined by %s", path, t.Name, owner)
+			}
+			names[t.Name] = "plugin " + path
+		}
+		s.plugins = append(s.plugins, p)
+		log.Printf("Plugin enabled: %s (%d tools)", path, len(p.Tools))
+	}
+	return nil
+}
+
+// isPluginTool reports whether toolName comes from a plugin.
+func (s *Server) isPluginTool(toolName string) bool {
+	return slices.ContainsFunc(s.plugins, func(p *plugin.Plugin) bool {
+		return slices.ContainsFunc(p.Tools, func(t plugin.Tool) bool { return t.Name == toolName })
+	})
+}
+
+// registerPlugins adds the tools of every plugin that --disable-tools or a
+// disabled exec category doesn't turn off. Each call runs the plugin in a
+// process of its own; the remote commands it asks for go through
+// HandleExecute, so the caller's sessions, the command filter, sudo policy
+// and rate limit apply, and the tool call itself is audited like any other.
+func (s *Server) registerPlugins(executeDeps *tools.ExecuteDeps) {
+	execute := func(ctx context.Context, req plugin.ExecRequest) plugin.ExecResult {
+		out, err := tools.HandleExecute(ctx, executeDeps, tools.SSHExecuteInput{
+			SessionID: req.SessionID,
+			Command:   req.Command,
+			Timeout:   req.Timeout,
+			Sudo:      req.Sudo,
+		})
+		if err != nil {
+			return plugin.ExecResult{Error: err.Error()}
+		}
+		return plugin.ExecResult{Stdout: out.Stdout, Stderr: out.Stderr, ExitCode: out.ExitCode, TimedOut: out.TimedOut}
+	}
+	for _, p := range s.plugins {
+		for _, t := range p.Tools {
+			if s.isToolDisabled(t.Name) {
+				continue
+			}
+			schema := t.InputSchema
+			if schema == nil {
+				schema = map[string]any{"type": "object"}
+			}
+			addTool(s, &mcp.Tool{
+				Name:        t.Name,
+				Description: t.Description,
+				InputSchema: schema,
+				Annotations: toolAnnotations(t.Name, t.Annotations),
+			}, func(ctx context.Context, _ *mcp.CallToolRequest, args map[string]any) (*mcp.CallToolResult, any, error) {
+				raw, err := json.Marshal(args)
+				if err != nil {
+					return nil, nil, err
+				}
+				res, err := p.Call(ctx, t.Name, raw, execute)
+				if err != nil {
+					return nil, nil, err
+				}
+				result := textResult(res.Text)
+				result.IsError = res.IsError
+				if len(res.Structured) > 0 && !res.IsError {
+					return result, res.Structured, nil
+				}
+				return result, nil, nil
+			})
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// pluginScript is a plugin offering fw_status, which asks the server to
+// run a command and reports whether the server refused it.
+const pluginScript = `#!/bin/sh
+read -r req
+case "$req" in
+*describe*)
+	echo '{"type":"tools","tools":[{"name":"fw_status","description":"Firewall status.","input_schema":{"type":"object","properties":{"session_id":{"type":"string"}},"required":["session_id"]},"annotations":{"read_only":true}}]}'
+	exit 0 ;;
+esac
+echo '{"type":"exec","id":1,"session_id":"nobody@nowhere:22","command":"show status"}'
+read -r reply
+case "$reply" in
+*'"error"'*) echo '{"type":"result","text":"exec refused","is_error":true}' ;;
+*) echo '{"type":"result","text":"ok","structured":{"ok":true}}' ;;
+esac
+`
+
+func writePlugin(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script needs sh")
+	}
+	path := filepath.Join(t.TempDir(), "fw-plugin")
+	if err := os.WriteFile(path, []byte(pluginScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlugins_Registered(t *testing.T) {
+	cfg := testConfig()
+	cfg.Plugins = []string{writePlugin(t)}
+	srv, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := connectClient(t, srv, nil)
+	ctx := context.Background()
+
+	list, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tool *mcp.Tool
+	for _, tl := range list.Tools {
+		if tl.Name == "fw_status" {
+			tool = tl
+		}
+	}
+	if tool == nil {
+		t.Fatal("fw_status not registered")
+	}
+	if !tool.Annotations.ReadOnlyHint || tool.Description != "Firewall status." {
+		t.Errorf("tool = %+v", tool)
+	}
+
+	// The plugin's exec goes through HandleExecute, which doesn't know the session.
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "fw_status", Arguments: map[string]any{"session_id": "nobody@nowhere:22"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || text != "exec refused" {
+		t.Errorf("result = %q (is_error %v)", text, res.IsError)
+	}
+
+	cfg.DisabledCategories = []string{config.ToolCategoryExec}
+	if registeredNames(t, cfg)["fw_status"] {
+		t.Error("plugin tools should be disabled with the exec category")
+	}
+}
+
+func TestPlugins_NameClash(t *testing.T) {
+	cfg := testConfig()
+	cfg.Plugins = []string{writePlugin(t)}
+	cfg.CommandTemplates = []config.CommandTemplate{{Name: "fw_status", Description: "x", Command: "true"}}
+	if _, err := New(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "already defined") {
+		t.Errorf("New = %v, want a name clash", err)
+	}
+}
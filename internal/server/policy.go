@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/alert"
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// policyMiddleware asks the --policy-webhook whether each tool call may run,
+// before the call reaches its handler and the built-in filters. A deny decision
+// fails the call; a modify decision replaces its arguments. When the webhook
+// cannot be reached or answers nonsense the call is rejected, unless
+// --policy-fail-open is set. It is wrapped by auditMiddleware, so denied
+// calls show up in the audit log.
+func (s *Server) policyMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+
+		var args map[string]any
+		if len(call.Params.Arguments) > 0 {
+			if err := json.Unmarshal(call.Params.Arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid tool arguments: %w", err)
+			}
+		}
+		pr := policyRequest(call, args)
+		d, err := s.policy.Check(ctx, pr)
+		if err != nil {
+			if s.cfg.Security.PolicyFailOpen {
+				log.Printf("Policy check for %s failed, allowing (fail-open): %v", call.Params.Name, err)
+				return next(ctx, method, req)
+			}
+			return nil, fmt.Errorf("tool call rejected: %w", err)
+		}
+
+		switch d.Decision {
+		case security.PolicyDeny:
+			reason := d.Reason
+			if reason == "" {
+				reason = "no reason given"
+			}
+			s.alerts.Notify(alert.Event{
+				Type:      config.AlertPolicyDenied,
+				Message:   "tool call denied by policy: " + reason,
+				Host:      pr.Host,
+				SessionID: pr.SessionID,
+				Tool:      pr.Tool,
+				Command:   strings.Join(pr.Commands, "; "),
+			})
+			return nil, fmt.Errorf("tool call denied by policy: %s", reason)
+		case security.PolicyModify:
+			raw, err := json.Marshal(security.RestoreSecrets(d.Arguments, args))
+			if err != nil {
+				return nil, fmt.Errorf("encode modified arguments: %w", err)
+			}
+			call.Params.Arguments = raw
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// policyRequest describes call to the webhook, with secrets redacted. The
+// host is taken from the session ID, or from the arguments of ssh_connect
+// as given (before SSH config aliases are resolved).
+func policyRequest(call *mcp.CallToolRequest, args map[string]any) security.PolicyRequest {
+	pr := security.PolicyRequest{
+		Tool:      call.Params.Name,
+		SessionID: callSessionID(call.Params),
+		Arguments: security.RedactArguments(args),
+	}
+	if pr.Arguments == nil {
+		pr.Arguments = map[string]any{}
+	}
+	pr.Commands, pr.Paths = security.CollectTargets(pr.Arguments)
+
+	var target connection.ConnectParams
+	switch host, _ := args["host"].(string); {
+	case pr.SessionID != "":
+		target = connection.ParseHostString(pr.SessionID)
+	case call.Params.Name == "ssh_connect" && host != "":
+		target = connection.ParseHostString(host)
+		if u, _ := args["user"].(string); u != "" {
+			target.User = u
+		}
+		if p, _ := args["port"].(float64); p > 0 {
+			target.Port = int(p)
+		}
+	}
+	pr.Host, pr.Port, pr.User = target.Host, target.Port, target.User
+
+	if ss, ok := call.GetSession().(*mcp.ServerSession); ok && ss != nil {
+		pr.Client.SessionID = ss.ID()
+		if p := ss.InitializeParams(); p != nil && p.ClientInfo != nil {
+			pr.Client.Name, pr.Client.Version = p.ClientInfo.Name, p.ClientInfo.Version
+		}
+	}
+	return pr
+}
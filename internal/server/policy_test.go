@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// policyServer returns a Server whose policy webhook answers with decide.
+func policyServer(t *testing.T, failOpen bool, decide func(security.PolicyRequest) string) *Server {
+	t.Helper()
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pr security.PolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+			t.Error(err)
+		}
+		resp := decide(pr)
+		if resp == "" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(hook.Close)
+	return &Server{
+		cfg:    &config.Config{Security: config.SecurityConfig{PolicyFailOpen: failOpen}},
+		policy: security.NewPolicyHook(hook.URL, 5*time.Second),
+	}
+}
+
+// callThrough runs a tools/call through policyMiddleware and returns the
+// arguments the next handler received, or the error.
+func callThrough(s *Server, tool, args string) (string, error) {
+	var seen string
+	next := func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		seen = string(req.(*mcp.CallToolRequest).Params.Arguments)
+		return &mcp.CallToolResult{}, nil
+	}
+	req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: tool, Arguments: json.RawMessage(args)}}
+	_, err := s.policyMiddleware(next)(context.Background(), "tools/call", req)
+	return seen, err
+}
+
+func TestPolicyMiddleware_Decisions(t *testing.T) {
+	var got security.PolicyRequest
+	s := policyServer(t, false, func(pr security.PolicyRequest) string {
+		got = pr
+		switch {
+		case strings.Contains(strings.Join(pr.Commands, " "), "rm -rf"):
+			return `{"decision":"deny","reason":"destructive"}`
+		case pr.Tool == "ssh_execute":
+			args := pr.Arguments
+			args["command"] = "timeout 60 " + args["command"].(string)
+			b, _ := json.Marshal(map[string]any{"decision": "modify", "arguments": args})
+			return string(b)
+		}
+		return `{"decision":"allow"}`
+	})
+
+	if _, err := callThrough(s, "ssh_execute", `{"session_id":"deploy@web1:22","command":"rm -rf /"}`); err == nil || !strings.Contains(err.Error(), "destructive") {
+		t.Errorf("expected denial with reason, got %v", err)
+	}
+	if got.Host != "web1" || got.User != "deploy" || got.Port != 22 {
+		t.Errorf("webhook saw target %s@%s:%d, want deploy@web1:22", got.User, got.Host, got.Port)
+	}
+
+	seen, err := callThrough(s, "ssh_execute", `{"session_id":"deploy@web1:22","command":"make","sudo_password":"pw"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Arguments["sudo_password"] == "pw" {
+		t.Error("webhook received the sudo password")
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(seen), &args); err != nil {
+		t.Fatal(err)
+	}
+	if args["command"] != "timeout 60 make" || args["sudo_password"] != "pw" {
+		t.Errorf("handler got %v, want modified command and original password", args)
+	}
+
+	seen, err = callThrough(s, "ssh_connect", `{"host":"admin:pw@db1","port":2222}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != `{"host":"admin:pw@db1","port":2222}` {
+		t.Errorf("allowed call changed arguments: %s", seen)
+	}
+	if got.Host != "db1" || got.User != "admin" || got.Port != 2222 {
+		t.Errorf("webhook saw target %s@%s:%d, want admin@db1:2222", got.User, got.Host, got.Port)
+	}
+	if strings.Contains(got.Arguments["host"].(string), "pw") {
+		t.Error("webhook received the password in the host string")
+	}
+}
+
+func TestPolicyMiddleware_WebhookDown(t *testing.T) {
+	down := func(security.PolicyRequest) string { return "" }
+	if _, err := callThrough(policyServer(t, false, down), "ssh_list_sessions", `{}`); err == nil {
+		t.Error("expected call to be rejected when the webhook fails")
+	}
+	if _, err := callThrough(policyServer(t, true, down), "ssh_list_sessions", `{}`); err != nil {
+		t.Errorf("fail-open: expected call to run, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/tools"
+)
+
+// URIs of the read-only resources describing server activity.
+const (
+	sessionsResourceURI = "ssh-mcp://sessions"
+	historyResourceURI  = "ssh-mcp://history"
+	auditResourceURI    = "ssh-mcp://audit"
+
+	// Prefixes of the resource templates, whose arguments get completions.
+	hostTemplatePrefix    = "ssh-mcp://hosts/"
+	sessionTemplatePrefix = "ssh-mcp://sessions/"
+	fileTemplatePrefix    = "ssh-mcp://files/"
+)
+
+const (
+	// auditLogSize is how many tool calls the audit log keeps.
+	auditLogSize = 200
+	// maxAuditError caps the error text kept per audit entry.
+	maxAuditError = 200
+)
+
+// AuditEntry is one tool call in the audit log.
+type AuditEntry struct {
+	Time       string `json:"time"`
+	Tool       string `json:"tool"`
+	SessionID  string `json:"session_id,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	owner string // client namespace the call ran in
+}
+
+// auditLog keeps the most recent tool calls in memory, oldest first.
+type auditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (a *auditLog) add(e AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, e)
+	if len(a.entries) > auditLogSize {
+		a.entries = append(a.entries[:0], a.entries[len(a.entries)-auditLogSize:]...)
+	}
+}
+
+// recent returns the kept entries of owner, oldest first.
+func (a *auditLog) recent(owner string) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := []AuditEntry{}
+	for _, e := range a.entries {
+		if e.owner == owner {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// historyEntry is one command in the history resource.
+type historyEntry struct {
+	Time       string `json:"time"`
+	SessionID  string `json:"session_id"`
+	Command    string `json:"command"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// registerResources adds the sessions, command history and audit log
+// resources, and templates for hosts, single sessions and remote files.
+// Subscribers get an update notification after every tool call that may have
+// changed the activity resources.
+func (s *Server) registerResources() {
+	s.mcpServer.AddResource(&mcp.Resource{
+		URI:         sessionsResourceURI,
+		Name:        "sessions",
+		Description: "Active SSH sessions with their terminals, tunnels and usage statistics (same data as ssh_list_sessions).",
+		MIMEType:    "application/json",
+	}, s.readSessionsResource)
+	s.mcpServer.AddResource(&mcp.Resource{
+		URI:         historyResourceURI,
+		Name:        "history",
+		Description: "Most recent remote commands run across all sessions, oldest first.",
+		MIMEType:    "application/json",
+	}, s.readHistoryResource)
+	s.mcpServer.AddResource(&mcp.Resource{
+		URI:         auditResourceURI,
+		Name:        "audit",
+		Description: "Tail of the audit log: most recent tool calls with session, duration and error, oldest first.",
+		MIMEType:    "application/json",
+	}, s.readAuditResource)
+
+	s.mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: hostTemplatePrefix + "{host}",
+		Name:        "host",
+		Description: "Where an ssh_config alias resolves to (host name, port, user, identity file, proxy command).",
+		MIMEType:    "application/json",
+	}, s.readHostResource)
+	s.mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: sessionTemplatePrefix + "{session_id}",
+		Name:        "session",
+		Description: "One active SSH session with its terminals, tunnels and usage statistics.",
+		MIMEType:    "application/json",
+	}, s.readSessionResource)
+	s.mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: fileTemplatePrefix + "{session_id}{+remote_path}",
+		Name:        "file",
+		Description: "Content of a remote file read over SFTP, limited by --max-file-size.",
+	}, s.readFileResource)
+
+	s.mcpServer.AddReceivingMiddleware(s.auditMiddleware)
+}
+
+// subscribeResource accepts subscriptions to the server's own resources only.
+func (s *Server) subscribeResource(_ context.Context, req *mcp.SubscribeRequest) error {
+	switch req.Params.URI {
+	case sessionsResourceURI, historyResourceURI, auditResourceURI:
+		return nil
+	}
+	return mcp.ResourceNotFoundError(req.Params.URI)
+}
+
+func (s *Server) unsubscribeResource(context.Context, *mcp.UnsubscribeRequest) error {
+	return nil
+}
+
+// notifyResources tells subscribers that the resources changed. Notifications
+// are sent in the background so a slow client never delays a tool call.
+// The pools are shared, so subscribers of every tenant are told too.
+func (s *Server) notifyResources(uris ...string) {
+	root := s.root()
+	for _, srv := range append([]*Server{root}, root.tenants...) {
+		for _, uri := range uris {
+			go srv.mcpServer.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+		}
+	}
+}
+
+// auditMiddleware records every tool call in the audit log and notifies
+// resource subscribers afterwards.
+func (s *Server) auditMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || call.Params == nil {
+			return next(ctx, method, req)
+		}
+
+		commands := s.pool.History().Total()
+		start := time.Now()
+		res, err := next(ctx, method, req)
+		e := auditEntry(call.Params, res, err, start)
+		e.owner = connection.OwnerFrom(ctx)
+		s.audit.add(e)
+
+		uris := []string{auditResourceURI, sessionsResourceURI}
+		if s.pool.History().Total() != commands {
+			uris = append(uris, historyResourceURI)
+		}
+		s.notifyResources(uris...)
+		return res, err
+	}
+}
+
+// auditEntry builds the audit record of a finished tool call. Only the tool
+// name and session are kept from the arguments, never commands or secrets.
+func auditEntry(params *mcp.CallToolParamsRaw, res mcp.Result, err error, start time.Time) AuditEntry {
+	e := AuditEntry{
+		Time:       start.UTC().Format(time.RFC3339),
+		Tool:       params.Name,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	e.SessionID = callSessionID(params)
+	switch r, _ := res.(*mcp.CallToolResult); {
+	case err != nil:
+		e.Error = err.Error()
+	case r != nil && r.IsError:
+		e.Error = "tool error"
+		if len(r.Content) > 0 {
+			if t, ok := r.Content[0].(*mcp.TextContent); ok {
+				e.Error = t.Text
+			}
+		}
+	}
+	if i := strings.IndexByte(e.Error, '\n'); i >= 0 {
+		e.Error = e.Error[:i]
+	}
+	if len(e.Error) > maxAuditError {
+		e.Error = e.Error[:maxAuditError] + "..."
+	}
+	return e
+}
+
+func (s *Server) readSessionsResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	out, err := tools.HandleListSessions(ctx, &tools.SessionsDeps{Pool: s.pool, TermPool: s.termPool, TunnelPool: s.tunnelPool}, tools.SSHListSessionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResource(req.Params.URI, out)
+}
+
+func (s *Server) readHistoryResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	owner := connection.OwnerFrom(ctx)
+	entries := []historyEntry{}
+	for _, r := range s.pool.History().Recent() {
+		if r.Owner != owner {
+			continue
+		}
+		entries = append(entries, historyEntry{
+			Time:       r.Time.UTC().Format(time.RFC3339),
+			SessionID:  string(r.SessionID),
+			Command:    r.Command,
+			DurationMs: r.Duration.Milliseconds(),
+			Error:      r.Failure,
+		})
+	}
+	return jsonResource(req.Params.URI, entries)
+}
+
+func (s *Server) readAuditResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return jsonResource(req.Params.URI, s.audit.recent(connection.OwnerFrom(ctx)))
+}
+
+// resolvedHostInfo is the JSON form of a resolved ssh_config alias.
+type resolvedHostInfo struct {
+	Alias        string `json:"alias"`
+	HostName     string `json:"host_name"`
+	Port         int    `json:"port"`
+	User         string `json:"user,omitempty"`
+	IdentityFile string `json:"identity_file,omitempty"`
+	ProxyCommand string `json:"proxy_command,omitempty"`
+}
+
+func (s *Server) readHostResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	alias, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, hostTemplatePrefix))
+	if err != nil || alias == "" {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	r := s.auth.ResolveHost(alias)
+	return jsonResource(req.Params.URI, resolvedHostInfo{
+		Alias:        alias,
+		HostName:     r.HostName,
+		Port:         r.Port,
+		User:         r.User,
+		IdentityFile: r.IdentityFile,
+		ProxyCommand: r.ProxyCommand,
+	})
+}
+
+func (s *Server) readSessionResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	id, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, sessionTemplatePrefix))
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	out, err := tools.HandleListSessions(ctx, &tools.SessionsDeps{Pool: s.pool, TermPool: s.termPool, TunnelPool: s.tunnelPool}, tools.SSHListSessionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sess := range out.Sessions {
+		if sess.SessionID == id {
+			return jsonResource(req.Params.URI, sess)
+		}
+	}
+	return nil, mcp.ResourceNotFoundError(req.Params.URI)
+}
+
+func (s *Server) readFileResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	sessionID, remotePath, ok := splitFileURI(req.Params.URI)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(req.Params.URI)
+	}
+	deps := &tools.FileReadDeps{Pool: s.pool, RateLimiter: s.fileOpsRateLimiter(), MaxFileSize: s.cfg.Security.MaxFileSize}
+	data, _, err := tools.ReadFileContent(ctx, deps, sessionID, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	contents := &mcp.ResourceContents{URI: req.Params.URI}
+	if utf8.Valid(data) {
+		contents.MIMEType = "text/plain"
+		contents.Text = string(data)
+	} else {
+		contents.MIMEType = "application/octet-stream"
+		contents.Blob = data
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+}
+
+// splitFileURI splits a file resource URI into its session ID and remote
+// path. The session ID is percent-encoded, so it ends where the path starts
+// with "/" or "~".
+func splitFileURI(uri string) (sessionID, remotePath string, ok bool) {
+	rest := strings.TrimPrefix(uri, fileTemplatePrefix)
+	i := strings.IndexAny(rest, "/~")
+	if i <= 0 {
+		return "", "", false
+	}
+	sessionID, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return "", "", false
+	}
+	remotePath, err = url.PathUnescape(rest[i:])
+	if err != nil {
+		return "", "", false
+	}
+	return sessionID, remotePath, true
+}
+
+// jsonResource returns v as the JSON contents of the resource at uri.
+func jsonResource(uri string, v any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", uri, err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}},
+	}, nil
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectClient connects an in-memory MCP client to srv.
+func connectClient(t *testing.T, srv *Server, opts *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := srv.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, opts)
+	cs, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+func TestResources_ListAndRead(t *testing.T) {
+	srv, err := New(context.Background(), testConfig())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cs := connectClient(t, srv, nil)
+	ctx := context.Background()
+
+	list, err := cs.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	uris := map[string]bool{}
+	for _, r := range list.Resources {
+		uris[r.URI] = true
+	}
+	for _, want := range []string{sessionsResourceURI, historyResourceURI, auditResourceURI} {
+		if !uris[want] {
+			t.Errorf("resource %s not listed", want)
+		}
+	}
+
+	// A failing tool call shows up in the audit log.
+	if _, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "ssh_execute",
+		Arguments: map[string]any{"session_id": "nobody@nowhere:22", "command": "uptime"},
+	}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: auditResourceURI})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	var entries []AuditEntry
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &entries); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	if e := entries[0]; e.Tool != "ssh_execute" || e.SessionID != "nobody@nowhere:22" || e.Error == "" {
+		t.Errorf("audit entry = %+v", e)
+	}
+
+	for _, uri := range []string{sessionsResourceURI, historyResourceURI} {
+		res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+		if err != nil {
+			t.Fatalf("ReadResource(%s): %v", uri, err)
+		}
+		if !json.Valid([]byte(res.Contents[0].Text)) {
+			t.Errorf("%s is not valid JSON: %s", uri, res.Contents[0].Text)
+		}
+	}
+}
+
+func TestResources_SubscribeNotifies(t *testing.T) {
+	srv, err := New(context.Background(), testConfig())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	updated := make(chan string, 10)
+	cs := connectClient(t, srv, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+	ctx := context.Background()
+
+	if err := cs.Subscribe(ctx, &mcp.SubscribeParams{URI: auditResourceURI}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := cs.Subscribe(ctx, &mcp.SubscribeParams{URI: "ssh-mcp://unknown"}); err == nil {
+		t.Error("expected error subscribing to an unknown resource")
+	}
+
+	if _, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "ssh_list_sessions", Arguments: map[string]any{}}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	select {
+	case uri := <-updated:
+		if uri != auditResourceURI {
+			t.Errorf("updated %s, want %s", uri, auditResourceURI)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no resource update notification")
+	}
+}
+
+func TestAuditEntry(t *testing.T) {
+	start := time.Now()
+	params := &mcp.CallToolParamsRaw{
+		Name:      "ssh_transfer",
+		Arguments: json.RawMessage(`{"source_session_id":"a@b:22","dest_session_id":"c@d:22"}`),
+	}
+	e := auditEntry(params, &mcp.CallToolResult{}, nil, start)
+	if e.Tool != "ssh_transfer" || e.SessionID != "a@b:22" || e.Error != "" {
+		t.Errorf("entry = %+v", e)
+	}
+
+	failed := &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: "first line\nsecond line"}},
+	}
+	if e := auditEntry(params, failed, nil, start); e.Error != "first line" {
+		t.Errorf("error = %q, want first line only", e.Error)
+	}
+
+	long := errors.New(strings.Repeat("x", 500))
+	if e := auditEntry(params, nil, long, start); len(e.Error) != maxAuditError+3 {
+		t.Errorf("error length = %d, want %d", len(e.Error), maxAuditError+3)
+	}
+}
+
+func TestAuditLog_KeepsMostRecent(t *testing.T) {
+	a := &auditLog{}
+	for i := 0; i < auditLogSize+5; i++ {
+		a.add(AuditEntry{DurationMs: int64(i)})
+	}
+	got := a.recent("")
+	if len(got) != auditLogSize {
+		t.Fatalf("len = %d, want %d", len(got), auditLogSize)
+	}
+	if got[0].DurationMs != 5 {
+		t.Errorf("oldest kept = %d, want 5", got[0].DurationMs)
+	}
+}
+
+func TestSplitFileURI(t *testing.T) {
+	tests := []struct {
+		uri, session, path string
+		ok                 bool
+	}{
+		{"ssh-mcp://files/root%40web%3A22/etc/hosts", "root@web:22", "/etc/hosts", true},
+		{"ssh-mcp://files/root%40web%3A22~/app/my%20notes.txt", "root@web:22", "~/app/my notes.txt", true},
+		{"ssh-mcp://files//etc/hosts", "", "", false},
+		{"ssh-mcp://files/root%40web%3A22", "", "", false},
+	}
+	for _, tt := range tests {
+		session, path, ok := splitFileURI(tt.uri)
+		if session != tt.session || path != tt.path || ok != tt.ok {
+			t.Errorf("splitFileURI(%q) = %q, %q, %v, want %q, %q, %v", tt.uri, session, path, ok, tt.session, tt.path, tt.ok)
+		}
+	}
+}
+
+func TestResources_HostTemplateAndCompletion(t *testing.T) {
+	cfg := testConfig()
+	cfg.SSH.ConfigPath = filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(cfg.SSH.ConfigPath, []byte("Host web1 web2 db\n  HostName 10.0.0.5\n  User deploy\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cs := connectClient(t, srv, nil)
+	ctx := context.Background()
+
+	comp, err := cs.Complete(ctx, &mcp.CompleteParams{
+		Ref:      &mcp.CompleteReference{Type: "ref/resource", URI: hostTemplatePrefix + "{host}"},
+		Argument: mcp.CompleteParamsArgument{Name: "host", Value: "we"},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got := strings.Join(comp.Completion.Values, ","); got != "web1,web2" {
+		t.Errorf("host completion = %q, want web1,web2", got)
+	}
+
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: hostTemplatePrefix + "web1"})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	var host resolvedHostInfo
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &host); err != nil {
+		t.Fatalf("decode host: %v", err)
+	}
+	if host.HostName != "10.0.0.5" || host.User != "deploy" || host.Port != 22 {
+		t.Errorf("host = %+v", host)
+	}
+
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: sessionTemplatePrefix + "nobody%40nowhere%3A22"}); err == nil {
+		t.Error("expected error reading an unknown session")
+	}
+}
+
+func TestAuditLog_ScopedToOwner(t *testing.T) {
+	a := &auditLog{}
+	a.add(AuditEntry{Tool: "ssh_execute", owner: "client-a"})
+	a.add(AuditEntry{Tool: "ssh_connect", owner: "client-b"})
+	a.add(AuditEntry{Tool: "ssh_ping"})
+
+	if got := a.recent("client-a"); len(got) != 1 || got[0].Tool != "ssh_execute" {
+		t.Errorf("recent(client-a) = %+v", got)
+	}
+	if got := a.recent(""); len(got) != 1 || got[0].Tool != "ssh_ping" {
+		t.Errorf("recent(shared) = %+v", got)
+	}
+}
@@ -5,18 +5,24 @@ import (
 	"crypto/subtle"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/n0madic/ssh-mcp/internal/alert"
 	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/credentials"
+	"github.com/n0madic/ssh-mcp/internal/plugin"
 	"github.com/n0madic/ssh-mcp/internal/security"
 	"github.com/n0madic/ssh-mcp/internal/tools"
+	"github.com/n0madic/ssh-mcp/internal/transcript"
 	"github.com/n0madic/ssh-mcp/internal/tunnel"
 )
 
@@ -29,7 +35,23 @@ type Server struct {
 	auth        *connection.AuthDiscovery
 	filter      *security.Filter
 	rateLimiter *security.RateLimiter
+	credentials credentials.Store
 	cfg         *config.Config
+	audit       *auditLog
+	calls       *callLimiter
+	policy      *security.PolicyHook // nil unless --policy-webhook is set
+	alerts      *alert.Notifier      // nil unless an alert webhook is set
+	transcripts *transcript.Recorder // nil unless --transcript-dir is set
+	inflight    drainer
+	registered  []tools.ToolInfo // tools added by registerTools, for ssh_server_info
+	plugins     []*plugin.Plugin // loaded --plugin executables, shared with tenants
+
+	parent  *Server   // main server of a tenant, nil for the main server
+	tenant  string    // tenant name, "" for the main server
+	tenants []*Server // servers for --tenants, set on the main server only
+
+	ownersMu sync.Mutex
+	owners   map[string]bool // MCP sessions whose SSH sessions are tracked
 }
 
 func boolPtr(b bool) *bool {
@@ -45,9 +67,22 @@ func textResult(text string) *mcp.CallToolResult {
 	}
 }
 
-// isToolDisabled checks if a tool is in the disabled list.
+// addTool registers a tool on the MCP server and records it for
+// ssh_server_info. A tool left enabled with some of its categories
+// disabled says so in its description.
+func addTool[In any](s *Server, t *mcp.Tool, h mcp.ToolHandlerFor[In, any]) {
+	t.Description += s.categoryNote(t.Name)
+	mcp.AddTool(s.mcpServer, t, h)
+	s.registered = append(s.registered, tools.ToolInfo{
+		Name:     t.Name,
+		ReadOnly: t.Annotations != nil && t.Annotations.ReadOnlyHint,
+	})
+}
+
+// isToolDisabled checks if a tool is in the disabled list or all of its
+// categories are disabled.
 func (s *Server) isToolDisabled(toolName string) bool {
-	return slices.Contains(s.cfg.DisabledTools, toolName)
+	return slices.Contains(s.cfg.DisabledTools, toolName) || s.isCategoryDisabled(toolName)
 }
 
 // New creates and configures a new SSH MCP server.
@@ -64,16 +99,23 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("create filter: %w", err)
 	}
+	if cfg.Security.ParseCommands {
+		filter.EnableShellParsing()
+	}
+	if cfg.Security.ResolveHosts {
+		filter.EnableHostResolution()
+	}
 
 	rateLimiter := security.NewRateLimiter(cfg.Security.RateLimit)
+	rateLimiter.SetMaxWait(cfg.Security.RateLimitWait)
 
-	mcpServer := mcp.NewServer(
-		&mcp.Implementation{
-			Name:    "ssh-mcp",
-			Version: config.Version,
-		},
-		nil,
-	)
+	credStore, err := credentials.New(cfg.Security)
+	if err != nil {
+		return nil, fmt.Errorf("create credential store: %w", err)
+	}
+	if credStore != nil {
+		log.Printf("Credential storage enabled: %s", credStore.Name())
+	}
 
 	var tunnelPool *tunnel.TunnelPool
 	if cfg.SSH.AllowTunnels {
@@ -81,23 +123,88 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	}
 
 	s := &Server{
-		mcpServer:   mcpServer,
 		pool:        pool,
 		termPool:    connection.NewTerminalPool(cfg.SSH.MaxTerminals),
 		tunnelPool:  tunnelPool,
 		auth:        auth,
 		filter:      filter,
 		rateLimiter: rateLimiter,
+		credentials: credStore,
 		cfg:         cfg,
+		audit:       &auditLog{},
+		calls:       newCallLimiter(cfg.Security.MaxConcurrent, cfg.Security.SerializeCalls),
 	}
 
-	s.registerTools()
+	if cfg.Security.PolicyWebhook != "" {
+		s.policy = security.NewPolicyHook(cfg.Security.PolicyWebhook, cfg.Security.PolicyTimeout)
+		log.Printf("Policy webhook enabled: %s", cfg.Security.PolicyWebhook)
+	}
+
+	if s.alerts = alert.New(cfg.Alert); s.alerts != nil {
+		s.watchSecurityEvents(auth)
+		s.alerts.Start(ctx)
+		log.Printf("Security alerts enabled")
+	}
+
+	if cfg.Transcript.Dir != "" {
+		if s.transcripts, err = transcript.New(cfg.Transcript.Dir, cfg.Transcript.Retention); err != nil {
+			return nil, err
+		}
+		s.transcripts.StartCleanup(ctx, time.Hour)
+		log.Printf("Session transcripts enabled: %s", cfg.Transcript.Dir)
+	}
+
+	if err := s.loadPlugins(ctx); err != nil {
+		return nil, err
+	}
+
+	s.setupMCP()
+	for _, t := range cfg.Tenants {
+		ts, err := s.newTenant(t)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", t.Name, err)
+		}
+		s.tenants = append(s.tenants, ts)
+		log.Printf("Tenant enabled: %s", t.Name)
+	}
+	pool.OnIdleClose(func() { s.notifyResources(sessionsResourceURI) })
+	pool.OnExpire(s.closeSessionChildren)
 	pool.StartIdleCleanup(ctx)
 	rateLimiter.StartCleanup(ctx, 10*time.Minute, 30*time.Minute)
 
 	return s, nil
 }
 
+// setupMCP creates the MCP server with its tools, resources and middleware.
+func (s *Server) setupMCP() {
+	s.mcpServer = mcp.NewServer(
+		&mcp.Implementation{
+			Name:    "ssh-mcp",
+			Version: config.Version,
+		},
+		&mcp.ServerOptions{
+			SubscribeHandler:   s.subscribeResource,
+			UnsubscribeHandler: s.unsubscribeResource,
+			CompletionHandler:  s.completeArgument,
+		},
+	)
+
+	s.registerTools()
+	if s.alerts != nil {
+		s.mcpServer.AddReceivingMiddleware(s.sudoAlertMiddleware)
+	}
+	if s.policy != nil {
+		s.mcpServer.AddReceivingMiddleware(s.policyMiddleware)
+	}
+	s.registerResources()
+	if s.transcripts != nil {
+		s.mcpServer.AddReceivingMiddleware(s.transcriptMiddleware)
+	}
+	s.mcpServer.AddReceivingMiddleware(s.concurrencyMiddleware)
+	s.mcpServer.AddReceivingMiddleware(s.ownerMiddleware)
+	s.mcpServer.AddReceivingMiddleware(s.drainMiddleware)
+}
+
 // fileOpsRateLimiter returns the rate limiter if file ops rate limiting is enabled, nil otherwise.
 func (s *Server) fileOpsRateLimiter() *security.RateLimiter {
 	if s.cfg.Security.RateLimitFileOps {
@@ -111,40 +218,878 @@ func (s *Server) registerTools() {
 
 	connectDeps := &tools.ConnectDeps{
 		Pool: s.pool, Auth: s.auth, Filter: s.filter, RateLimiter: s.rateLimiter,
+		Credentials: s.credentials, HostKeyPrompt: s.cfg.SSH.HostKeyPrompt,
 	}
 	executeDeps := &tools.ExecuteDeps{
 		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
-		MaxOutputSize: s.cfg.SSH.MaxOutputSize,
+		MaxOutputSize: s.cfg.SSH.MaxOutputSize, Credentials: s.credentials,
 	}
 	disconnectDeps := &tools.DisconnectDeps{Pool: s.pool, TermPool: s.termPool, TunnelPool: s.tunnelPool}
 	sessionsDeps := &tools.SessionsDeps{Pool: s.pool, TermPool: s.termPool, TunnelPool: s.tunnelPool}
+	usageDeps := &tools.UsageDeps{Pool: s.pool, RateLimiter: s.rateLimiter, Security: &s.cfg.Security}
+	pingDeps := &tools.PingDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter}
+	hostInfoDeps := &tools.HostInfoDeps{Pool: s.pool, RateLimiter: s.rateLimiter}
 	uploadDeps := &tools.UploadDeps{
-		Pool: s.pool, LocalBaseDir: s.cfg.Security.LocalBaseDir, RateLimiter: fileRateLimiter,
+		Pool: s.pool, LocalDirs: s.cfg.Security.LocalDirs, RateLimiter: fileRateLimiter,
+		Config: &s.cfg.SSH, Filter: s.filter,
+	}
+	downloadDeps := &tools.DownloadDeps{
+		Pool: s.pool, LocalDirs: s.cfg.Security.LocalDirs, RateLimiter: fileRateLimiter,
+		Config: &s.cfg.SSH, Filter: s.filter, MaxFileSize: s.cfg.Security.MaxFileSize,
+	}
+	fileEditDeps := &tools.FileEditDeps{
+		Pool: s.pool, RateLimiter: fileRateLimiter, MaxFileSize: s.cfg.Security.MaxFileSize,
+		Backup: s.cfg.Backup, Locks: tools.NewEditLocks(),
+	}
+	fileReadDeps := &tools.FileReadDeps{
+		Pool: s.pool, RateLimiter: fileRateLimiter, MaxFileSize: s.cfg.Security.MaxFileSize,
+		Config: &s.cfg.SSH,
+	}
+	fileStatDeps := &tools.FileStatDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: fileRateLimiter, Config: &s.cfg.SSH,
+	}
+	copyDeps := &tools.CopyDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
+	}
+	renameDeps := &tools.RenameDeps{Pool: s.pool, RateLimiter: fileRateLimiter}
+	transferDeps := &tools.TransferDeps{Pool: s.pool, RateLimiter: fileRateLimiter}
+	watchDeps := &tools.WatchDeps{Pool: s.pool, RateLimiter: fileRateLimiter}
+	listDirectoryDeps := &tools.ListDirectoryDeps{Pool: s.pool, RateLimiter: fileRateLimiter}
+	fileDiffDeps := &tools.FileDiffDeps{
+		Pool: s.pool, RateLimiter: fileRateLimiter, LocalDirs: s.cfg.Security.LocalDirs,
+		MaxFileSize: s.cfg.Security.MaxFileSize, MaxOutputSize: s.cfg.SSH.MaxOutputSize,
+	}
+	archiveDeps := &tools.ArchiveDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
+	}
+	keygenDeps := &tools.KeygenDeps{LocalDirs: s.cfg.Security.LocalDirs, SSHDir: s.cfg.SSH.SSHDir}
+	deployKeyDeps := &tools.DeployKeyDeps{
+		Pool: s.pool, LocalDirs: s.cfg.Security.LocalDirs, RateLimiter: s.rateLimiter,
+	}
+	runScriptDeps := &tools.RunScriptDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
+		MaxOutputSize: s.cfg.SSH.MaxOutputSize,
+	}
+	dockerDeps := &tools.DockerDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
+		MaxOutputSize: s.cfg.SSH.MaxOutputSize,
+	}
+	kubectlDeps := &tools.KubectlDeps{
+		Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH,
+		MaxOutputSize: s.cfg.SSH.MaxOutputSize,
+	}
+
+	// ssh_connect
+	if !s.isToolDisabled("ssh_connect") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_connect",
+			Description: "Connect to a remote host via SSH. Only 'host' is required — authentication is automatic (tries SSH keys from ~/.ssh/, ssh-agent, then ~/.ssh/config). SSH config aliases (~/.ssh/config) are resolved automatically. Do NOT ask the user for auth details unless connection fails. Returns a session_id for use with other tools.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Connect",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.SSHConnectInput) (*mcp.CallToolResult, any, error) {
+			deps := *connectDeps
+			deps.ConfirmHostKey = elicitHostKey(req.Session)
+			out, err := tools.HandleConnect(ctx, &deps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_execute
+	if !s.isToolDisabled("ssh_execute") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_execute",
+			Description: "Execute a command on a remote host via SSH. Supports sudo, working directory, and timeout. Returns stdout, stderr, exit code, and duration; on timeout returns the output captured so far with timed_out set. expect answers prompts (e.g. \"[y/N]\") by waiting for regex patterns in the output and writing responses to stdin. parse_json returns JSON stdout (docker inspect, kubectl -o json) as structured content.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Execute",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHExecuteInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleExecute(ctx, executeDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			if out.JSON != nil {
+				return textResult(out.Text()), out.Structured(), nil
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_run_script
+	if !s.isToolDisabled("ssh_run_script") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_run_script",
+			Description: "Run a multi-line script on the remote host without shell quoting: the script is uploaded to a private temp file, run with the chosen interpreter (bash, sh, python, powershell) and arguments, and deleted afterwards. Each script line is checked against the command filter. Returns stdout, stderr, exit code, and duration.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Run Script",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHRunScriptInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleRunScript(ctx, runScriptDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_disconnect
+	if !s.isToolDisabled("ssh_disconnect") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_disconnect",
+			Description: "Disconnect an active SSH session. The session_id will no longer be usable.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Disconnect",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(false),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDisconnectInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDisconnect(ctx, disconnectDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_list_sessions
+	if !s.isToolDisabled("ssh_list_sessions") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_list_sessions",
+			Description: "List all active SSH sessions with their connection details and statistics.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH List Sessions",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(false),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHListSessionsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleListSessions(ctx, sessionsDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_usage
+	if !s.isToolDisabled("ssh_usage") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_usage",
+			Description: "Report your current usage against the server's limits: requests left in each host's rate limit and when the next one frees up, plus commands run, failed and still running and bytes transferred per session. Check it before a burst of calls instead of running into rate limit errors. Does not count against the rate limit.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Usage",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(false),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHUsageInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleUsage(ctx, usageDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_ping
+	if !s.isToolDisabled("ssh_ping") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_ping",
+			Description: "Check that a session is usable before a long operation: sends an SSH keepalive and runs a trivial command, returning round-trip latencies, the server version banner, and whether the connection had to be re-established. A failed probe is reported as unhealthy.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Ping",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHPingInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandlePing(ctx, pingDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_host_info
+	if !s.isToolDisabled("ssh_host_info") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_host_info",
+			Description: "Describe the remote host of a session so you can build the right commands: OS and architecture, login shell, distribution (os-release ID, ID_LIKE, version and name), package manager (apt, dnf, yum, zypper, apk, pacman, brew, pkg), init system (systemd, openrc, runit, launchd, sysvinit, rc), and whether sudo (and passwordless sudo) or doas is available. Detected once on connect; set refresh to probe again.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Host Info",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHHostInfoInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleHostInfo(ctx, hostInfoDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_which
+	if !s.isToolDisabled("ssh_which") {
+		whichDeps := &tools.WhichDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_which",
+			Description: "Check which programs exist on the remote host before relying on them: looks every name up on PATH (command -v) and, unless no_version, reports the first line of its version output (--version, or the program's own option such as ssh -V or go version) and the version number in it. Up to 50 names in one round trip; use it to plan around missing tools instead of failing mid-sequence.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Which",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHWhichInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleWhich(ctx, whichDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_health
+	if !s.isToolDisabled("ssh_health") {
+		healthDeps := &tools.HealthDeps{Pool: s.pool, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_health",
+			Description: "Take a quick health snapshot of the remote host in one round trip: uptime, load averages against the CPU count, memory and swap, disk usage of the fullest real filesystems (or the given mounts), the top processes by CPU and by memory, and failed systemd units. Values past common thresholds (load per CPU >= 1, memory or disk >= 90%, swap >= 50%, any failed unit) are listed as warnings. Read-only; POSIX hosts only.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Health",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHHealthInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleHealth(ctx, healthDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_git
+	if !s.isToolDisabled("ssh_git") {
+		gitDeps := &tools.GitDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_git",
+			Description: "Run git on the remote host with structured results: clone (url into path, optional ref and depth), pull (fast-forward only; lists the new commits), status (branch, upstream, ahead/behind and changed files from porcelain output), checkout (ref, or create a branch) and log (recent commits). clone, pull and checkout return the status afterwards. forward_agent lets clone and pull use the local ssh-agent for private repositories (requires --enable-agent-forwarding). Git never prompts: missing credentials fail the call.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Git",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHGitInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleGit(ctx, gitDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_multiplexer
+	if !s.isToolDisabled("ssh_multiplexer") {
+		muxDeps := &tools.MultiplexerDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH, MaxOutputSize: s.cfg.SSH.MaxOutputSize}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_multiplexer",
+			Description: "Manage tmux (default) or screen sessions on the remote host. They keep running after this server restarts or the SSH connection drops, and a person can attach to the same session. Actions: list (sessions with windows, attached clients and the running command), create (detached session named name, optionally running command in dir), send-keys (type text or a special_key into the session, optionally press enter, then return its screen after wait_ms), capture (return the screen, plus history_lines of scrollback) and kill. The command of create and each line of typed text go through the command filter.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Terminal Multiplexer",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHMultiplexerInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleMultiplexer(ctx, muxDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_reboot
+	if !s.isToolDisabled("ssh_reboot") {
+		rebootDeps := &tools.RebootDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_reboot",
+			Description: "Reboot the remote host (shutdown -r now, with sudo unless connected as root) and wait until it is back: polls every poll_interval seconds until SSH answers and the boot ID has changed, then reconnects the session and reports the downtime and the kernel before and after. Other tools fail fast on the session while it reboots. Fails if the host does not go down or come back within timeout (default 600s).",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Reboot",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHRebootInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleReboot(ctx, rebootDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_signal
+	if !s.isToolDisabled("ssh_signal") {
+		signalDeps := &tools.SignalDeps{
+			Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter,
+			Config: &s.cfg.SSH, Protected: s.cfg.Security.ProtectProcesses,
+		}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_signal",
+			Description: "Send a signal (TERM by default, or INT, HUP, QUIT, KILL, USR1, USR2, STOP, CONT) to a remote process by pid, or to the processes matching name like pkill. With name, the matches are listed first and only signaled when confirm is true. PID 1 and processes protected by --protect-process are refused; the ps, pgrep and kill commands go through the command filter.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Signal",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHSignalInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleSignal(ctx, signalDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_workspace
+	if !s.isToolDisabled("ssh_workspace") {
+		workspaceDeps := &tools.WorkspaceDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_workspace",
+			Description: "Manage private scratch directories on the remote host for intermediate files, instead of writing into /tmp directly. create makes one with mktemp -d and returns its path; the server deletes the session's workspaces when it is disconnected, expires or the server shuts down. list shows them; remove deletes one (path) or all of them early.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Workspace",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHWorkspaceInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleWorkspace(ctx, workspaceDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_user
+	if !s.isToolDisabled("ssh_user") {
+		userDeps := &tools.UserDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_user",
+			Description: "Manage user accounts on a Linux or macOS host without free-form shell: list users with their groups, create a user (with home directory, shell, comment and groups), disable one (locks the password and expires the account, so keys stop working too), or add one to groups. Changes need sudo: true (requires --enable-sudo) unless the session is root, and return the account as it is afterwards. All commands go through the command filter.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH User",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHUserInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleUser(ctx, userDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_logs
+	if !s.isToolDisabled("ssh_logs") {
+		logsDeps := &tools.LogsDeps{Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter, Config: &s.cfg.SSH}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_logs",
+			Description: "Read system logs as structured entries (time, unit, identifier, PID, priority, message), oldest first: the systemd journal via journalctl, filtered by unit, priority, since/until and a grep pattern, or /var/log/syslog or /var/log/messages (or file) on hosts without a journal. Returns limit entries (default 100) and a next_cursor for the page of older ones. Prefer this over cat/tail of log files.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Logs",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHLogsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleLogs(ctx, logsDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_collect_diagnostics
+	if !s.isToolDisabled("ssh_collect_diagnostics") {
+		diagnosticsDeps := &tools.DiagnosticsDeps{
+			Pool: s.pool, Filter: s.filter, RateLimiter: s.rateLimiter,
+			Config: &s.cfg.SSH, LocalDirs: s.cfg.Security.LocalDirs,
+		}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_collect_diagnostics",
+			Description: "Collect a diagnostics bundle for incident handoff: runs built-in read-only commands (uname, uptime, df, free, ps, dmesg, journal, network, sockets, mounts) and reads the given files on the remote host, caps each item (default 1 MiB, keeping the end of logs and files), and writes them with a manifest.json to a local .tar.gz. Failed items are listed in the manifest rather than failing the call.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Collect Diagnostics",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHCollectDiagnosticsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleCollectDiagnostics(ctx, diagnosticsDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_get_transcript
+	if s.transcripts != nil && !s.isToolDisabled("ssh_get_transcript") {
+		transcriptDeps := &tools.TranscriptDeps{Recorder: s.transcripts}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_get_transcript",
+			Description: "Read the recorded transcript of a session: the most recent tool calls made on it, with their arguments (secrets redacted), output and errors. Works for sessions that are already disconnected. Only available when the server records transcripts (--transcript-dir).",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Get Transcript",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(false),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHGetTranscriptInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleGetTranscript(ctx, transcriptDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_upload
+	if !s.isToolDisabled("ssh_upload") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_upload",
+			Description: "Upload a local file or directory to a remote host via SFTP (or scp when the server has no SFTP subsystem). Automatically detects whether the local path is a file or directory. Preserves file permissions and directory structure.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Upload",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHUploadInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleUpload(ctx, uploadDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_download
+	if !s.isToolDisabled("ssh_download") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_download",
+			Description: "Download a file or directory from a remote host via SFTP (or scp when the server has no SFTP subsystem). Automatically detects whether the remote path is a file or directory. Preserves file permissions and directory structure. With inline, returns a small file's content (text or base64) in the result instead of writing it locally.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Download",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDownloadInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDownload(ctx, downloadDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_download_files
+	if !s.isToolDisabled("ssh_download_files") {
+		downloadFilesDeps := &tools.DownloadFilesDeps{
+			Pool: s.pool, LocalDirs: s.cfg.Security.LocalDirs, RateLimiter: fileRateLimiter, Config: &s.cfg.SSH,
+		}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_download_files",
+			Description: "Download many remote files into one local directory in a single call: remote_paths lists up to 100 files or globs (e.g. /var/log/nginx/*.log, expanded on the remote host over SFTP), copied concurrently (parallel, default --transfer-workers) under their base names. Returns a status per file (downloaded, skipped or failed with the reason); one failure doesn't stop the rest. Directories are skipped; use ssh_download for those. Needs SFTP.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Download Files",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDownloadFilesInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDownloadFiles(ctx, downloadFilesDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_fetch_url is only offered once --fetch-allow-domain names a domain.
+	if len(s.cfg.Security.FetchDomains) > 0 && !s.isToolDisabled("ssh_fetch_url") {
+		fetchURLDeps := &tools.FetchURLDeps{
+			Pool: s.pool, LocalDirs: s.cfg.Security.LocalDirs, RateLimiter: fileRateLimiter,
+			Config: &s.cfg.SSH, Domains: s.cfg.Security.FetchDomains, MaxSize: s.cfg.Security.FetchMaxSize,
+		}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_fetch_url",
+			Description: "Download an HTTPS URL on an allowed domain (--fetch-allow-domain) and write it to remote_path on a session, streamed over SFTP, or to local_path on the MCP host. Size-capped by --fetch-max-size; with sha256 the download is checked before anything is written. Use this for release tarballs and other artifacts instead of passing them through the conversation.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Fetch URL",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHFetchURLInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleFetchURL(ctx, fetchURLDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_edit_file
+	if !s.isToolDisabled("ssh_edit_file") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_edit_file",
+			Description: "Edit a file on a remote host. Supports 'replace' mode (full content replacement or new file creation), 'patch' mode (find and replace a string, or several at once via 'edits', applied all-or-nothing in one write; 'replace_all' replaces every occurrence and 'expected_count' asserts how many there are), 'lines' mode (insert after line N, replace or delete lines N-M, append), 'append' mode (add raw bytes to the end in place), and 'write_at' mode (overwrite bytes at a byte offset in place, for large files). replace/patch/lines write atomically and back up the file first by default (see ssh_restore_backup).",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Edit File",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHEditFileInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleEditFile(ctx, fileEditDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_restore_backup
+	if !s.isToolDisabled("ssh_restore_backup") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_restore_backup",
+			Description: "List the backups ssh_edit_file made of a remote file (list=true), or restore one over the file: the newest by default, or a named one from the list. The current content is backed up first unless backup_current=false, so a restore can be undone.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Restore Backup",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHRestoreBackupInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleRestoreBackup(ctx, fileEditDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_read_file
+	if !s.isToolDisabled("ssh_read_file") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_read_file",
+			Description: "Read a file from a remote host with optional line offset and limit. Returns content with line numbers. Supports ~ for home directory.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Read File",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHReadFileInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleReadFile(ctx, fileReadDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_plan_execute
+	if !s.isToolDisabled("ssh_plan_execute") {
+		// Plans may only use tools that are enabled on their own.
+		planDeps := &tools.PlanDeps{}
+		if !s.isToolDisabled("ssh_edit_file") {
+			planDeps.Edit = fileEditDeps
+		}
+		if !s.isToolDisabled("ssh_upload") {
+			planDeps.Upload = uploadDeps
+		}
+		if !s.isToolDisabled("ssh_execute") {
+			planDeps.Execute = executeDeps
+		}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_plan_execute",
+			Description: "Run an ordered list of steps (file edits, uploads, commands; each with the arguments of ssh_edit_file, ssh_upload or ssh_execute) as one deployment. Each step's on_failure decides what a failure does: abort (default) skips the rest and restores every file the plan edited to its content before the plan, overwriting any change made to it by others in the meantime; stop skips the rest, continue goes on. Uploads and commands are not rolled back. Returns each step's status and the rollback result.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Plan Execute",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHPlanExecuteInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandlePlanExecute(ctx, planDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_copy
+	if !s.isToolDisabled("ssh_copy") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_copy",
+			Description: "Copy a file or directory to another path on the same remote host, without downloading and re-uploading it. Runs 'cp -a' on the host (preserving modes, times and symlinks) and falls back to streaming through SFTP where cp is unavailable or not allowed. dest_path is the full path of the copy; an existing file is replaced only with overwrite=true.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Copy",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHCopyInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleCopy(ctx, copyDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_rename
+	if !s.isToolDisabled("ssh_rename") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_rename",
+			Description: "Rename or move a file or directory on the remote host. dest_path is the full new path. An existing destination file is replaced only with overwrite=true; the replace is atomic when the server supports the posix-rename SFTP extension (OpenSSH does), otherwise the old file is removed first. Existing directories are never overwritten.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Rename",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHRenameInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleRename(ctx, renameDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_transfer
+	if !s.isToolDisabled("ssh_transfer") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_transfer",
+			Description: "Copy a file from one connected host to another by streaming it between the two sessions' SFTP connections through the MCP server, with no temporary copy on the operator's machine. The destination is written atomically (temp file + rename) with the source file's mode. Use ssh_copy for copies on the same host and ssh_archive to transfer directories.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Transfer",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHTransferInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleTransfer(ctx, transferDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_file_stat
+	if !s.isToolDisabled("ssh_file_stat") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_file_stat",
+			Description: "Show metadata for a remote path without following symlinks: type, mode and octal permissions, size and block count, UID/GID with user/group names, symlink target, and modification/access times. Use it to debug permission problems.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH File Stat",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHFileStatInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleFileStat(ctx, fileStatDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_list_directory
+	if !s.isToolDisabled("ssh_list_directory") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_list_directory",
+			Description: "List a remote directory with paging. Filter by glob 'pattern', 'regex' and entry 'type'; sort by name, size or mtime ('reverse' for largest/newest first); page with 'limit' (default 200) and 'offset'. Returns the total number of matching entries so large directories can be walked page by page.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH List Directory",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHListDirectoryInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleListDirectory(ctx, listDirectoryDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_glob
+	if !s.isToolDisabled("ssh_glob") {
+		globDeps := &tools.GlobDeps{Pool: s.pool, RateLimiter: fileRateLimiter}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_glob",
+			Description: "Expand a remote glob such as /etc/nginx/sites-*/*.conf or ~/app/**/*.py into the matching paths with type, size, mode and mtime, to pick targets precisely before acting on them. Expanded over SFTP without a shell: * ? and [...] match within one path component (dotfiles included) and ** matches any number of directories. Filter by 'type'; at most 'limit' matches (default 200).",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Glob",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHGlobInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleGlob(ctx, globDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
 	}
-	downloadDeps := &tools.DownloadDeps{
-		Pool: s.pool, LocalBaseDir: s.cfg.Security.LocalBaseDir, RateLimiter: fileRateLimiter,
+
+	// ssh_file_head
+	if !s.isToolDisabled("ssh_file_head") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_file_head",
+			Description: "Return the first N lines (default 10) or bytes of a remote file, reading only that much, so it works on files of any size. Output is capped at 1 MiB.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH File Head",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHFileHeadTailInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleFileHead(ctx, fileReadDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
 	}
-	fileEditDeps := &tools.FileEditDeps{
-		Pool: s.pool, RateLimiter: fileRateLimiter, MaxFileSize: s.cfg.Security.MaxFileSize,
+
+	// ssh_file_tail
+	if !s.isToolDisabled("ssh_file_tail") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_file_tail",
+			Description: "Return the last N lines (default 10) or bytes of a remote file by seeking to the end and reading backwards, so multi-GB logs are not downloaded. Output is capped at 1 MiB.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH File Tail",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHFileHeadTailInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleFileTail(ctx, fileReadDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
 	}
-	fileReadDeps := &tools.FileReadDeps{
-		Pool: s.pool, RateLimiter: fileRateLimiter, MaxFileSize: s.cfg.Security.MaxFileSize,
+
+	// ssh_watch_path
+	if !s.isToolDisabled("ssh_watch_path") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_watch_path",
+			Description: "Watch a remote file or directory for a bounded time (default 30s, max 10m) by polling over SFTP, and report entries created, modified or deleted with timestamps. Use until_change to return on the first change (e.g. waiting for a build artifact) or contains to return when a log file gains a line with a marker string. The path may not exist yet.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Watch Path",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHWatchPathInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleWatchPath(ctx, watchDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
 	}
 
-	// ssh_connect
-	if !s.isToolDisabled("ssh_connect") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_connect",
-			Description: "Connect to a remote host via SSH. Only 'host' is required — authentication is automatic (tries SSH keys from ~/.ssh/, ssh-agent, then ~/.ssh/config). SSH config aliases (~/.ssh/config) are resolved automatically. Do NOT ask the user for auth details unless connection fails. Returns a session_id for use with other tools.",
+	// ssh_diff
+	if !s.isToolDisabled("ssh_diff") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_diff",
+			Description: "Show a unified diff between a remote file and either a local file, another remote file on the same session, or proposed content, without modifying anything. Use it to preview what an upload or ssh_edit_file would change. A missing remote_path is treated as empty.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Connect",
-				ReadOnlyHint:    false,
+				Title:           "SSH Diff",
+				ReadOnlyHint:    true,
 				DestructiveHint: boolPtr(false),
 				IdempotentHint:  true,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHConnectInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleConnect(ctx, connectDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDiffInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDiff(ctx, fileDiffDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -152,20 +1097,20 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_execute
-	if !s.isToolDisabled("ssh_execute") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_execute",
-			Description: "Execute a command on a remote host via SSH. Supports sudo, working directory, and timeout. Returns stdout, stderr, exit code, and duration.",
+	// ssh_archive
+	if !s.isToolDisabled("ssh_archive") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_archive",
+			Description: "Create a tar.gz or zip archive on the remote host from files and directories (relative to base_dir), using tar, zip, or 7z, whichever is installed. Useful to grab many files as one ssh_download.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Execute",
+				Title:           "SSH Archive",
 				ReadOnlyHint:    false,
 				DestructiveHint: boolPtr(true),
-				IdempotentHint:  false,
+				IdempotentHint:  true,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHExecuteInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleExecute(ctx, executeDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHArchiveInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleArchive(ctx, archiveDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -173,20 +1118,41 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_disconnect
-	if !s.isToolDisabled("ssh_disconnect") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_disconnect",
-			Description: "Disconnect an active SSH session. The session_id will no longer be usable.",
+	// ssh_extract
+	if !s.isToolDisabled("ssh_extract") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_extract",
+			Description: "Unpack a tar.gz or zip archive on the remote host into dest_dir (created if missing), using tar, unzip, or 7z, whichever is installed. The archive listing is checked first: members with absolute paths or '..' are rejected.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Disconnect",
+				Title:           "SSH Extract",
 				ReadOnlyHint:    false,
-				DestructiveHint: boolPtr(false),
+				DestructiveHint: boolPtr(true),
 				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHExtractInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleExtract(ctx, archiveDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_keygen
+	if !s.isToolDisabled("ssh_keygen") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_keygen",
+			Description: "Generate a new SSH keypair (ed25519, ecdsa, or rsa) on the local machine, under the local base dir or ~/.ssh. Returns the public key for deployment to remote hosts. Never overwrites existing keys unless overwrite is set.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Keygen",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  false,
 				OpenWorldHint:   boolPtr(false),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDisconnectInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleDisconnect(ctx, disconnectDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHKeygenInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleKeygen(ctx, keygenDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -194,20 +1160,41 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_list_sessions
-	if !s.isToolDisabled("ssh_list_sessions") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_list_sessions",
-			Description: "List all active SSH sessions with their connection details and statistics.",
+	// ssh_deploy_key
+	if !s.isToolDisabled("ssh_deploy_key") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_deploy_key",
+			Description: "Install a public key into ~/.ssh/authorized_keys on the remote host (ssh-copy-id equivalent). Creates ~/.ssh with mode 0700 if needed and keeps authorized_keys at 0600. Skips keys that are already present. Use after a password login to switch to key auth, e.g. with a key from ssh_keygen.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH List Sessions",
+				Title:           "SSH Deploy Key",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDeployKeyInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDeployKey(ctx, deployKeyDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_docker_ps
+	if !s.isToolDisabled("ssh_docker_ps") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_ps",
+			Description: "List Docker containers on the remote host (docker ps) as structured data: ID, name, image, state, status, ports. Use all=true to include stopped containers and filters (key=value) to narrow the list.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Docker PS",
 				ReadOnlyHint:    true,
 				DestructiveHint: boolPtr(false),
 				IdempotentHint:  true,
-				OpenWorldHint:   boolPtr(false),
+				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHListSessionsInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleListSessions(ctx, sessionsDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerPsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerPs(ctx, dockerDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -215,20 +1202,20 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_upload
-	if !s.isToolDisabled("ssh_upload") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_upload",
-			Description: "Upload a local file or directory to a remote host via SFTP. Automatically detects whether the local path is a file or directory. Preserves file permissions and directory structure.",
+	// ssh_docker_images
+	if !s.isToolDisabled("ssh_docker_images") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_images",
+			Description: "List Docker images on the remote host (docker images) as structured data: ID, repository, tag, size, age.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Upload",
-				ReadOnlyHint:    false,
+				Title:           "SSH Docker Images",
+				ReadOnlyHint:    true,
 				DestructiveHint: boolPtr(false),
-				IdempotentHint:  false,
+				IdempotentHint:  true,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHUploadInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleUpload(ctx, uploadDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerImagesInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerImages(ctx, dockerDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -236,20 +1223,20 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_download
-	if !s.isToolDisabled("ssh_download") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_download",
-			Description: "Download a file or directory from a remote host via SFTP. Automatically detects whether the remote path is a file or directory. Preserves file permissions and directory structure.",
+	// ssh_docker_inspect
+	if !s.isToolDisabled("ssh_docker_inspect") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_inspect",
+			Description: "Return the docker inspect JSON for a container or image on the remote host.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Download",
+				Title:           "SSH Docker Inspect",
 				ReadOnlyHint:    true,
 				DestructiveHint: boolPtr(false),
 				IdempotentHint:  true,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDownloadInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleDownload(ctx, downloadDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerInspectInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerInspect(ctx, dockerDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -257,20 +1244,41 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_edit_file
-	if !s.isToolDisabled("ssh_edit_file") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_edit_file",
-			Description: "Edit a file on a remote host. Supports 'replace' mode (full content replacement or new file creation) and 'patch' mode (find and replace a string). Creates .bak backup by default.",
+	// ssh_docker_logs
+	if !s.isToolDisabled("ssh_docker_logs") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_logs",
+			Description: "Fetch the last lines of a container's logs on the remote host (docker logs --tail, default 100 lines), optionally since a time and with timestamps. stdout and stderr are merged.",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Edit File",
+				Title:           "SSH Docker Logs",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerLogsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerLogs(ctx, dockerDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_docker_exec
+	if !s.isToolDisabled("ssh_docker_exec") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_exec",
+			Description: "Run a command inside a running container on the remote host (docker exec ... sh -c). The command is checked against the command filter. Returns stdout, stderr, exit code, and duration.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Docker Exec",
 				ReadOnlyHint:    false,
 				DestructiveHint: boolPtr(true),
 				IdempotentHint:  false,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHEditFileInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleEditFile(ctx, fileEditDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerExecInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerExec(ctx, dockerDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -278,20 +1286,104 @@ func (s *Server) registerTools() {
 		})
 	}
 
-	// ssh_read_file
-	if !s.isToolDisabled("ssh_read_file") {
-		mcp.AddTool(s.mcpServer, &mcp.Tool{
-			Name:        "ssh_read_file",
-			Description: "Read a file from a remote host with optional line offset and limit. Returns content with line numbers. Supports ~ for home directory.",
+	// ssh_docker_restart
+	if !s.isToolDisabled("ssh_docker_restart") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_docker_restart",
+			Description: "Restart one or more containers on the remote host (docker restart).",
 			Annotations: &mcp.ToolAnnotations{
-				Title:           "SSH Read File",
+				Title:           "SSH Docker Restart",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDockerRestartInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleDockerRestart(ctx, dockerDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_kubectl_get_pods
+	if !s.isToolDisabled("ssh_kubectl_get_pods") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_kubectl_get_pods",
+			Description: "List Kubernetes pods using kubectl on the remote host (e.g. a bastion) as structured data: name, namespace, ready, status, restarts, age, node, IP. Supports context, namespace, all_namespaces, and a label selector.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Kubectl Get Pods",
 				ReadOnlyHint:    true,
 				DestructiveHint: boolPtr(false),
 				IdempotentHint:  true,
 				OpenWorldHint:   boolPtr(true),
 			},
-		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHReadFileInput) (*mcp.CallToolResult, any, error) {
-			out, err := tools.HandleReadFile(ctx, fileReadDeps, input)
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHKubectlGetPodsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleKubectlGetPods(ctx, kubectlDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_kubectl_logs
+	if !s.isToolDisabled("ssh_kubectl_logs") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_kubectl_logs",
+			Description: "Fetch the last lines of a pod's logs using kubectl on the remote host (default 100 lines). Supports container, since, previous (crashed instance), and timestamps.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Kubectl Logs",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHKubectlLogsInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleKubectlLogs(ctx, kubectlDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_kubectl_describe
+	if !s.isToolDisabled("ssh_kubectl_describe") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_kubectl_describe",
+			Description: "Describe a Kubernetes resource (default kind pod) using kubectl on the remote host, including recent events.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Kubectl Describe",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHKubectlDescribeInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleKubectlDescribe(ctx, kubectlDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+
+	// ssh_kubectl_exec
+	if !s.isToolDisabled("ssh_kubectl_exec") {
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_kubectl_exec",
+			Description: "Run a command inside a pod container using kubectl exec on the remote host (via sh -c). The command is checked against the command filter. Returns stdout, stderr, exit code, and duration.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Kubectl Exec",
+				ReadOnlyHint:    false,
+				DestructiveHint: boolPtr(true),
+				IdempotentHint:  false,
+				OpenWorldHint:   boolPtr(true),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHKubectlExecInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleKubectlExec(ctx, kubectlDeps, input)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -309,9 +1401,9 @@ func (s *Server) registerTools() {
 
 		// ssh_open_terminal
 		if !s.isToolDisabled("ssh_open_terminal") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_open_terminal",
-				Description: "Open an interactive PTY terminal session over SSH. Returns a terminal_id for use with ssh_send_input, ssh_read_output, and ssh_close_terminal.",
+				Description: "Open an interactive PTY terminal session over SSH. Returns a terminal_id for use with ssh_send_input, ssh_read_output, ssh_terminal_screenshot, and ssh_close_terminal.",
 				Annotations: &mcp.ToolAnnotations{
 					Title:           "SSH Open Terminal",
 					ReadOnlyHint:    false,
@@ -330,7 +1422,7 @@ func (s *Server) registerTools() {
 
 		// ssh_send_input
 		if !s.isToolDisabled("ssh_send_input") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_send_input",
 				Description: "Send text or a special key (CTRL_C, ENTER, TAB, etc.) to an interactive PTY terminal and read back the new output. Always returns output captured during wait_ms — no need to call ssh_read_output afterwards for quick commands. Use ssh_read_output only for long-running commands or TUI programs that produce output without further input.",
 				Annotations: &mcp.ToolAnnotations{
@@ -351,7 +1443,7 @@ func (s *Server) registerTools() {
 
 		// ssh_read_output
 		if !s.isToolDisabled("ssh_read_output") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_read_output",
 				Description: "Read buffered output from a PTY terminal since the last read. Optionally waits up to wait_ms milliseconds for new data. Use this for long-running commands or TUI programs that produce output independently of input; for quick commands prefer ssh_send_input which already returns output.",
 				Annotations: &mcp.ToolAnnotations{
@@ -370,9 +1462,30 @@ func (s *Server) registerTools() {
 			})
 		}
 
+		// ssh_terminal_screenshot
+		if !s.isToolDisabled("ssh_terminal_screenshot") {
+			addTool(s, &mcp.Tool{
+				Name:        "ssh_terminal_screenshot",
+				Description: "Capture what a PTY terminal currently shows, as text: the output is rendered with a VT100/xterm emulator, so full-screen programs (htop, top, installers, menuconfig, vim) come out as the screen a user would see rather than raw escape sequences. Returns the rows, the cursor position and whether a full-screen program is on the alternate screen. Does not consume output buffered for ssh_read_output.",
+				Annotations: &mcp.ToolAnnotations{
+					Title:           "SSH Terminal Screenshot",
+					ReadOnlyHint:    true,
+					DestructiveHint: boolPtr(false),
+					IdempotentHint:  true,
+					OpenWorldHint:   boolPtr(false),
+				},
+			}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHTerminalScreenshotInput) (*mcp.CallToolResult, any, error) {
+				out, err := tools.HandleTerminalScreenshot(ctx, terminalDeps, input)
+				if err != nil {
+					return nil, nil, err
+				}
+				return textResult(out.Text()), nil, nil
+			})
+		}
+
 		// ssh_close_terminal
 		if !s.isToolDisabled("ssh_close_terminal") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_close_terminal",
 				Description: "Close an active PTY terminal session. The terminal_id will no longer be usable.",
 				Annotations: &mcp.ToolAnnotations{
@@ -401,7 +1514,7 @@ func (s *Server) registerTools() {
 
 		// ssh_tunnel_create
 		if !s.isToolDisabled("ssh_tunnel_create") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_tunnel_create",
 				Description: "Create a local port forwarding tunnel (localhost:port → remote:port via SSH). Binds a local port and forwards connections through the SSH session to the specified remote address. Returns the tunnel_id and local address for use.",
 				Annotations: &mcp.ToolAnnotations{
@@ -420,9 +1533,30 @@ func (s *Server) registerTools() {
 			})
 		}
 
+		// ssh_db_tunnel
+		if !s.isToolDisabled("ssh_db_tunnel") {
+			addTool(s, &mcp.Tool{
+				Name:        "ssh_db_tunnel",
+				Description: "Open a local tunnel to a database reachable from the remote host and return a ready-to-use connection string. Only 'engine' is required: the remote port defaults to the engine's standard port and the local port is auto-assigned. The tunnel closes on ssh_tunnel_close or ssh_disconnect.",
+				Annotations: &mcp.ToolAnnotations{
+					Title:           "SSH Database Tunnel",
+					ReadOnlyHint:    false,
+					DestructiveHint: boolPtr(false),
+					IdempotentHint:  false,
+					OpenWorldHint:   boolPtr(true),
+				},
+			}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHDBTunnelInput) (*mcp.CallToolResult, any, error) {
+				out, err := tools.HandleDBTunnel(ctx, tunnelDeps, input)
+				if err != nil {
+					return nil, nil, err
+				}
+				return textResult(out.Text()), nil, nil
+			})
+		}
+
 		// ssh_tunnel_list
 		if !s.isToolDisabled("ssh_tunnel_list") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_tunnel_list",
 				Description: "List all active SSH tunnels with their connection details. Optionally filter by session ID.",
 				Annotations: &mcp.ToolAnnotations{
@@ -443,7 +1577,7 @@ func (s *Server) registerTools() {
 
 		// ssh_tunnel_close
 		if !s.isToolDisabled("ssh_tunnel_close") {
-			mcp.AddTool(s.mcpServer, &mcp.Tool{
+			addTool(s, &mcp.Tool{
 				Name:        "ssh_tunnel_close",
 				Description: "Close an active SSH tunnel. The tunnel_id will no longer be usable.",
 				Annotations: &mcp.ToolAnnotations{
@@ -462,34 +1596,71 @@ func (s *Server) registerTools() {
 			})
 		}
 	} // AllowTunnels
+
+	s.registerCommandTemplates(executeDeps, runScriptDeps)
+	s.registerPlugins(executeDeps)
+
+	// ssh_server_info is registered last so that it lists every other tool.
+	if !s.isToolDisabled("ssh_server_info") {
+		serverInfoDeps := &tools.ServerInfoDeps{Config: s.cfg, Tenant: s.tenant}
+		addTool(s, &mcp.Tool{
+			Name:        "ssh_server_info",
+			Description: "Describe this server's configuration so you can plan within it: version, enabled and disabled tools, whether every tool is read-only, security posture (sudo, run_as, terminal and tunnels allowed, which host/command filters are set, host key verification, max file and output size, local base dir, command timeout, rate limit) and transports. Filter patterns and secrets are not shown.",
+			Annotations: &mcp.ToolAnnotations{
+				Title:           "SSH Server Info",
+				ReadOnlyHint:    true,
+				DestructiveHint: boolPtr(false),
+				IdempotentHint:  true,
+				OpenWorldHint:   boolPtr(false),
+			},
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, input tools.SSHServerInfoInput) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleServerInfo(ctx, serverInfoDeps, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+		serverInfoDeps.Tools = s.registered
+	}
 }
 
 // authMiddleware wraps an HTTP handler with bearer token authentication.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := s.cfg.Transport.HTTPToken
+		if token == "" && len(s.tenants) > 0 {
+			// Only tenant tokens are valid; tenantMiddleware routed those.
+			setAuthResult(r, "denied")
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
 		if token == "" {
+			setAuthResult(r, "none")
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
+			setAuthResult(r, "denied")
 			http.Error(w, "missing Authorization header", http.StatusUnauthorized)
 			return
 		}
 
 		const prefix = "Bearer "
 		if !strings.HasPrefix(authHeader, prefix) {
+			setAuthResult(r, "denied")
 			http.Error(w, "invalid Authorization header format (expected Bearer token)", http.StatusUnauthorized)
 			return
 		}
 
 		if subtle.ConstantTimeCompare([]byte(authHeader[len(prefix):]), []byte(token)) != 1 {
+			setAuthResult(r, "denied")
 			http.Error(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
+		setAuthResult(r, "ok")
 		next.ServeHTTP(w, r)
 	})
 }
@@ -498,9 +1669,14 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 func (s *Server) Run(ctx context.Context) error {
 	errCh := make(chan error, 2)
 
+	// Transports outlive ctx so that in-flight tool calls keep their
+	// connection while shutdown drains them.
+	serveCtx, stopServing := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopServing()
+
 	if s.cfg.Transport.HTTPEnabled {
 		go func() {
-			errCh <- s.runHTTP(ctx)
+			errCh <- s.runHTTP(serveCtx)
 		}()
 	}
 
@@ -514,7 +1690,7 @@ func (s *Server) Run(ctx context.Context) error {
 			}
 		} else {
 			go func() {
-				errCh <- s.runStdio(ctx)
+				errCh <- s.runStdio(serveCtx)
 			}()
 		}
 	}
@@ -524,6 +1700,7 @@ func (s *Server) Run(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		log.Println("Shutting down...")
+		s.drainCalls()
 	case err := <-errCh:
 		if err != nil {
 			log.Printf("Transport error: %v", err)
@@ -531,6 +1708,7 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}
 
+	stopServing()
 	s.shutdown()
 	return transportErr
 }
@@ -549,27 +1727,27 @@ func isStdinTerminal() bool {
 	return fi.Mode()&os.ModeCharDevice != 0
 }
 
-func (s *Server) runHTTP(ctx context.Context) error {
-	addr := fmt.Sprintf("%s:%d", s.cfg.Transport.HTTPHost, s.cfg.Transport.HTTPPort)
-	log.Printf("Starting HTTP transport on %s%s", addr, s.cfg.Transport.HTTPPath)
-
+// mcpMux serves the MCP server on the configured HTTP path. Each server gets
+// its own handler, so MCP sessions never cross between tenants.
+func (s *Server) mcpMux() http.Handler {
 	handler := mcp.NewStreamableHTTPHandler(
 		func(r *http.Request) *mcp.Server {
 			return s.mcpServer
 		},
 		nil,
 	)
-
 	mux := http.NewServeMux()
 	mux.Handle(s.cfg.Transport.HTTPPath, handler)
+	return mux
+}
 
-	// Wrap with auth middleware.
-	var httpHandler http.Handler = mux
-	httpHandler = s.authMiddleware(httpHandler)
+func (s *Server) runHTTP(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%d", s.cfg.Transport.HTTPHost, s.cfg.Transport.HTTPPort)
+	log.Printf("Starting HTTP transport on %s%s", addr, s.cfg.Transport.HTTPPath)
 
 	httpServer := &http.Server{
 		Addr:              addr,
-		Handler:           httpHandler,
+		Handler:           s.httpHandler(ctx, s.mcpMux()),
 		ReadHeaderTimeout: 10 * time.Second,
 		IdleTimeout:       120 * time.Second,
 	}
@@ -581,12 +1759,29 @@ func (s *Server) runHTTP(ctx context.Context) error {
 		httpServer.Shutdown(shutdownCtx)
 	}()
 
-	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("HTTP server: %w", err)
+	}
+	if n := s.cfg.Transport.MaxConnsPerIP; n > 0 {
+		ln = newPerIPListener(ln, n)
+	}
+
+	if err := httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("HTTP server: %w", err)
 	}
 	return nil
 }
 
+// closeSessionChildren closes the terminals and tunnels of a session the pool
+// closed on its own, for reaching its max lifetime or because its owner left.
+func (s *Server) closeSessionChildren(owner string, id connection.SessionID) {
+	s.termPool.CloseBySession(owner, id)
+	if s.tunnelPool != nil {
+		s.tunnelPool.CloseBySession(owner, string(id))
+	}
+}
+
 func (s *Server) shutdown() {
 	if s.tunnelPool != nil {
 		log.Println("Closing all tunnels...")
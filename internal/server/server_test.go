@@ -163,6 +163,38 @@ func TestIsToolDisabled_DirectName(t *testing.T) {
 	}
 }
 
+func TestRegisterTools_RecordsRegistered(t *testing.T) {
+	cfg := testConfig()
+	cfg.DisabledTools = []string{"ssh_upload"}
+	srv, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make(map[string]bool)
+	for _, ti := range srv.registered {
+		names[ti.Name] = true
+		if ti.Name == "ssh_list_sessions" && !ti.ReadOnly {
+			t.Error("ssh_list_sessions should be recorded as read-only")
+		}
+		if ti.Name == "ssh_execute" && ti.ReadOnly {
+			t.Error("ssh_execute should not be recorded as read-only")
+		}
+	}
+	if names["ssh_upload"] {
+		t.Error("disabled ssh_upload was recorded")
+	}
+	if names["ssh_open_terminal"] {
+		t.Error("terminal tools recorded without --enable-terminal")
+	}
+	if names["ssh_fetch_url"] {
+		t.Error("ssh_fetch_url recorded without --fetch-allow-domain")
+	}
+	if !names["ssh_execute"] || !names["ssh_server_info"] {
+		t.Errorf("registered tools missing ssh_execute or ssh_server_info: %v", names)
+	}
+}
+
 func TestAuthMiddleware_MissingHeader(t *testing.T) {
 	cfg := testConfig()
 	cfg.Transport.HTTPToken = "secret123"
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/tools"
+)
+
+// registerCommandTemplates adds a tool for every --command-templates
+// entry that --disable-tools or a disabled exec category doesn't turn off.
+// Its input schema comes from the template's parameters, so the SDK
+// validates arguments and fills in defaults before the handler runs.
+func (s *Server) registerCommandTemplates(executeDeps *tools.ExecuteDeps, runScriptDeps *tools.RunScriptDeps) {
+	for _, tpl := range s.cfg.CommandTemplates {
+		if s.isToolDisabled(tpl.Name) {
+			continue
+		}
+		deps := &tools.CommandTemplateDeps{Execute: executeDeps, RunScript: runScriptDeps}
+		if len(tpl.Hosts) > 0 {
+			hosts, err := security.NewFilter(tpl.Hosts, nil, nil, nil)
+			if err != nil {
+				continue // rejected by Config.Validate
+			}
+			deps.Hosts = hosts
+		}
+		addTool(s, &mcp.Tool{
+			Name:        tpl.Name,
+			Description: templateDescription(tpl),
+			InputSchema: tools.CommandTemplateSchema(tpl),
+			Annotations: toolAnnotations(tpl.Name, tpl.Annotations),
+		}, func(ctx context.Context, _ *mcp.CallToolRequest, args map[string]any) (*mcp.CallToolResult, any, error) {
+			out, err := tools.HandleCommandTemplate(ctx, deps, tpl, args)
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(out.Text()), nil, nil
+		})
+	}
+}
+
+// templateDescription is tpl's description plus what it runs and where.
+func templateDescription(tpl config.CommandTemplate) string {
+	var b strings.Builder
+	b.WriteString(tpl.Description)
+	if tpl.Script != "" {
+		b.WriteString(" Runs a fixed script with the parameters as arguments")
+	} else {
+		b.WriteString(" Runs `" + tpl.Command + "`")
+	}
+	if tpl.SessionID != "" {
+		b.WriteString(" on session " + tpl.SessionID + ", which must be connected with ssh_connect.")
+	} else {
+		b.WriteString(" on the host of session_id.")
+	}
+	if len(tpl.Hosts) > 0 {
+		b.WriteString(" Only allowed on hosts matching " + strings.Join(tpl.Hosts, ", ") + ".")
+	}
+	return b.String()
+}
+
+// toolAnnotations returns the MCP hints of a configured tool from a, with
+// the hints of ssh_execute for what a leaves out.
+func toolAnnotations(name string, a *config.TemplateAnnotations) *mcp.ToolAnnotations {
+	if a == nil {
+		a = &config.TemplateAnnotations{}
+	}
+	title := a.Title
+	if title == "" {
+		title = name
+	}
+	destructive := !a.ReadOnly
+	if a.Destructive != nil {
+		destructive = *a.Destructive
+	}
+	return &mcp.ToolAnnotations{
+		Title:           title,
+		ReadOnlyHint:    a.ReadOnly,
+		DestructiveHint: boolPtr(destructive),
+		IdempotentHint:  a.Idempotent,
+		OpenWorldHint:   boolPtr(true),
+	}
+}
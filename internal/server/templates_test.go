@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func templateConfig() *config.Config {
+	cfg := testConfig()
+	cfg.CommandTemplates = []config.CommandTemplate{
+		{Name: "restart_service", Description: "Restart a systemd unit.", Command: "systemctl restart {{name}}",
+			Params: []config.TemplateParam{{Name: "name", Pattern: "[a-z0-9@._-]+"}}},
+		{Name: "disk_usage", Description: "Show disk usage.", Command: "df -h",
+			Annotations: &config.TemplateAnnotations{Title: "Disk usage", ReadOnly: true, Idempotent: true}},
+		{Name: "rotate_logs", Description: "Rotate logs.", Script: "logrotate -f /etc/logrotate.conf",
+			SessionID: "root@web1:22", Hosts: []string{"web1"}},
+	}
+	return cfg
+}
+
+func TestCommandTemplates_Registered(t *testing.T) {
+	names := registeredNames(t, templateConfig())
+	if !names["restart_service"] || !names["disk_usage"] {
+		t.Errorf("templates not registered: %v", names)
+	}
+
+	cfg := templateConfig()
+	cfg.DisabledTools = []string{"disk_usage"}
+	if names := registeredNames(t, cfg); !names["restart_service"] || names["disk_usage"] {
+		t.Error("--disable-tools should remove only disk_usage")
+	}
+
+	cfg = templateConfig()
+	cfg.DisabledCategories = config.PresetCategories[config.PresetReadOnly]
+	if names := registeredNames(t, cfg); names["restart_service"] || names["disk_usage"] {
+		t.Error("templates should be disabled with the exec category")
+	}
+}
+
+func TestCommandTemplates_Call(t *testing.T) {
+	srv, err := New(context.Background(), templateConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := connectClient(t, srv, nil)
+	ctx := context.Background()
+
+	list, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]*mcp.Tool{}
+	for _, tool := range list.Tools {
+		byName[tool.Name] = tool
+	}
+	if d := byName["restart_service"].Description; !strings.Contains(d, "systemctl restart {{name}}") {
+		t.Errorf("restart_service description = %q", d)
+	}
+	if a := byName["restart_service"].Annotations; a.ReadOnlyHint || !*a.DestructiveHint || a.Title != "restart_service" {
+		t.Errorf("restart_service annotations = %+v", a)
+	}
+	if a := byName["disk_usage"].Annotations; !a.ReadOnlyHint || *a.DestructiveHint || !a.IdempotentHint || a.Title != "Disk usage" {
+		t.Errorf("disk_usage annotations = %+v", a)
+	}
+	if d := byName["rotate_logs"].Description; !strings.Contains(d, "fixed script") || !strings.Contains(d, "session root@web1:22") || !strings.Contains(d, "matching web1") {
+		t.Errorf("rotate_logs description = %q", d)
+	}
+
+	// A fixed session takes no session_id; here it isn't connected.
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "rotate_logs", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || !strings.Contains(text, "root@web1:22") {
+		t.Errorf("rotate_logs result = %q", text)
+	}
+
+	// The schema rejects a name outside the pattern before the handler runs.
+	res, err = cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "restart_service",
+		Arguments: map[string]any{"session_id": "nobody@nowhere:22", "name": "nginx; reboot"},
+	})
+	if err == nil && !res.IsError {
+		t.Error("expected an error for a name outside the pattern")
+	}
+
+	// A valid call gets as far as the session lookup.
+	res, err = cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "restart_service",
+		Arguments: map[string]any{"session_id": "nobody@nowhere:22", "name": "nginx"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || !strings.Contains(text, "session") {
+		t.Errorf("result = %q, want a session error", text)
+	}
+}
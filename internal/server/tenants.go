@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// root returns the main server of a tenant, or s itself.
+func (s *Server) root() *Server {
+	if s.parent != nil {
+		return s.parent
+	}
+	return s
+}
+
+// newTenant creates the server for a --tenants entry. It shares the
+// connection pools, rate limiter, audit log and hooks with s; its filter and
+// tool set only narrow those of s.
+func (s *Server) newTenant(t config.Tenant) (*Server, error) {
+	filter, err := s.filter.Child(t.HostAllowlist, t.HostDenylist, t.CommandAllowlist, t.CommandDenylist)
+	if err != nil {
+		return nil, fmt.Errorf("create filter: %w", err)
+	}
+	ts := &Server{
+		pool:        s.pool,
+		termPool:    s.termPool,
+		tunnelPool:  s.tunnelPool,
+		auth:        s.auth,
+		filter:      filter,
+		rateLimiter: s.rateLimiter,
+		credentials: s.credentials,
+		cfg:         tenantConfig(s.cfg, t),
+		audit:       s.audit,
+		calls:       s.calls,
+		policy:      s.policy,
+		alerts:      s.alerts,
+		transcripts: s.transcripts,
+		plugins:     s.plugins,
+		parent:      s,
+		tenant:      t.Name,
+	}
+	ts.setupMCP()
+	return ts, nil
+}
+
+// tenantConfig returns a copy of cfg with t's rules added. The filter lists
+// are merged only so ssh_server_info reports them; the tenant's filter
+// enforces them.
+func tenantConfig(cfg *config.Config, t config.Tenant) *config.Config {
+	c := *cfg
+	c.DisabledTools = slices.Concat(cfg.DisabledTools, t.DisableTools)
+	c.DisabledCategories = slices.Clone(cfg.DisabledCategories)
+	for _, cat := range config.PresetCategories[t.Preset] {
+		if !slices.Contains(c.DisabledCategories, cat) {
+			c.DisabledCategories = append(c.DisabledCategories, cat)
+		}
+	}
+	// Presets are nested, so the one disabling more wins.
+	if len(config.PresetCategories[t.Preset]) > len(config.PresetCategories[c.Preset]) {
+		c.Preset = t.Preset
+	}
+	c.Security.HostAllowlist = slices.Concat(cfg.Security.HostAllowlist, t.HostAllowlist)
+	c.Security.HostDenylist = slices.Concat(cfg.Security.HostDenylist, t.HostDenylist)
+	c.Security.CommandAllowlist = slices.Concat(cfg.Security.CommandAllowlist, t.CommandAllowlist)
+	c.Security.CommandDenylist = slices.Concat(cfg.Security.CommandDenylist, t.CommandDenylist)
+	c.Transport.StdioEnabled = false
+	c.Transport.HTTPToken = t.Token
+	c.Tenants = nil
+	return &c
+}
+
+// tenantMiddleware sends requests carrying a tenant's bearer token to that
+// tenant's MCP handler. Other requests go on to next, which checks the main
+// token.
+func (s *Server) tenantMiddleware(next http.Handler) http.Handler {
+	handlers := make([]http.Handler, len(s.tenants))
+	for i, ts := range s.tenants {
+		handlers[i] = ts.mcpMux()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Compare with every token so the timing doesn't tell which matched.
+		match := -1
+		for i, ts := range s.tenants {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(ts.cfg.Transport.HTTPToken)) == 1 {
+				match = i
+			}
+		}
+		if match < 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		setAuthResult(r, "ok")
+		handlers[match].ServeHTTP(w, r)
+	})
+}
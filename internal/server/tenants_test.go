@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/tools"
+)
+
+func tenantTestServer(t *testing.T, mainToken string) *Server {
+	t.Helper()
+	cfg := testConfig()
+	cfg.Transport.HTTPEnabled = true
+	cfg.Transport.HTTPToken = mainToken
+	cfg.Security.HostDenylist = []string{"prod-.*"}
+	cfg.Tenants = []config.Tenant{
+		{Name: "team-a", Token: "tok-a", HostAllowlist: []string{"a-.*", "prod-1"}, DisableTools: []string{"ssh_upload"}},
+		{Name: "team-b", Token: "tok-b", CommandDenylist: []string{"reboot"}, Preset: config.PresetReadOnly},
+	}
+	srv, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestNew_Tenants(t *testing.T) {
+	srv := tenantTestServer(t, "main")
+	if len(srv.tenants) != 2 {
+		t.Fatalf("tenants = %d, want 2", len(srv.tenants))
+	}
+	a, b := srv.tenants[0], srv.tenants[1]
+	if a.root() != srv || a.pool != srv.pool || a.audit != srv.audit {
+		t.Error("tenant should share the main server's state")
+	}
+
+	if err := a.filter.AllowHost("a-1"); err != nil {
+		t.Errorf("team-a a-1: %v", err)
+	}
+	if err := a.filter.AllowHost("prod-1"); err == nil {
+		t.Error("team-a prod-1 should be denied by the global denylist")
+	}
+	if err := srv.filter.AllowHost("b-1"); err != nil {
+		t.Errorf("main b-1: %v", err)
+	}
+	if err := b.filter.AllowCommand("reboot"); err == nil {
+		t.Error("team-b reboot should be denied")
+	}
+
+	has := func(s *Server, name string) bool {
+		return slices.ContainsFunc(s.registered, func(ti tools.ToolInfo) bool { return ti.Name == name })
+	}
+	if !has(srv, "ssh_upload") || has(a, "ssh_upload") || !has(a, "ssh_execute") {
+		t.Error("ssh_upload should be disabled for team-a only")
+	}
+	if has(b, "ssh_execute") || !has(b, "ssh_list_sessions") {
+		t.Error("team-b should get the readonly preset")
+	}
+	if b.cfg.Preset != config.PresetReadOnly || srv.cfg.Preset == config.PresetReadOnly {
+		t.Errorf("presets: main %q, team-b %q", srv.cfg.Preset, b.cfg.Preset)
+	}
+}
+
+func TestTenantMiddleware_RoutesByToken(t *testing.T) {
+	for _, mainToken := range []string{"main", ""} {
+		srv := tenantTestServer(t, mainToken)
+		var reachedMain bool
+		h := srv.httpHandler(context.Background(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reachedMain = true
+		}))
+		for _, tt := range []struct {
+			auth       string
+			wantMain   bool
+			wantStatus int
+		}{
+			{"Bearer main", mainToken != "", http.StatusOK},
+			{"Bearer tok-a", false, 0},
+			{"Bearer tok-b", false, 0},
+			{"Bearer nope", false, http.StatusUnauthorized},
+			{"", false, http.StatusUnauthorized},
+		} {
+			if tt.auth == "Bearer main" && mainToken == "" {
+				tt.wantStatus = http.StatusUnauthorized
+			}
+			reachedMain = false
+			req := httptest.NewRequest(http.MethodGet, "/mcp", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if reachedMain != tt.wantMain {
+				t.Errorf("main token %q, %q: reached main = %v", mainToken, tt.auth, reachedMain)
+			}
+			switch {
+			case tt.wantStatus != 0 && rec.Code != tt.wantStatus:
+				t.Errorf("main token %q, %q: status %d, want %d", mainToken, tt.auth, rec.Code, tt.wantStatus)
+			case tt.wantStatus == 0 && rec.Code == http.StatusUnauthorized:
+				t.Errorf("main token %q, %q: tenant token rejected", mainToken, tt.auth)
+			}
+		}
+	}
+}
+
+// bearerTransport adds a bearer token to every request.
+type bearerTransport string
+
+func (t bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+string(t))
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestTenants_IsolatedOverHTTP(t *testing.T) {
+	srv := tenantTestServer(t, "main")
+	// Even with shared sessions, tenants must not see each other's activity.
+	srv.cfg.Transport.SharedSessions = true
+	for _, ts := range srv.tenants {
+		ts.cfg.Transport.SharedSessions = true
+	}
+	hs := httptest.NewServer(srv.httpHandler(context.Background(), srv.mcpMux()))
+	t.Cleanup(hs.Close)
+
+	connect := func(token string) *mcp.ClientSession {
+		client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, nil)
+		cs, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{
+			Endpoint:   hs.URL + "/mcp",
+			HTTPClient: &http.Client{Transport: bearerTransport(token)},
+		}, nil)
+		if err != nil {
+			t.Fatalf("connect with %s: %v", token, err)
+		}
+		t.Cleanup(func() { cs.Close() })
+		return cs
+	}
+	main, a := connect("main"), connect("tok-a")
+
+	res, err := a.CallTool(context.Background(), &mcp.CallToolParams{Name: "ssh_server_info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "Tenant: team-a") {
+		t.Errorf("team-a server info:\n%s", text)
+	}
+	if got := len(auditEntries(t, a)); got != 1 {
+		t.Errorf("team-a sees %d audit entries, want 1", got)
+	}
+	if got := len(auditEntries(t, main)); got != 0 {
+		t.Errorf("main sees %d audit entries of team-a, want 0", got)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// toolCategories assigns tools to the categories that --preset,
+// --disable-exec and --disable-file-write turn off. A tool is disabled when
+// all of its categories are; tools not listed here (sessions, reads, logs)
+// are never disabled by category.
+var toolCategories = map[string][]string{
+	"ssh_execute":        {config.ToolCategoryExec},
+	"ssh_run_script":     {config.ToolCategoryExec},
+	"ssh_signal":         {config.ToolCategoryExec},
+	"ssh_user":           {config.ToolCategoryExec},
+	"ssh_multiplexer":    {config.ToolCategoryExec},
+	"ssh_reboot":         {config.ToolCategoryExec},
+	"ssh_docker_exec":    {config.ToolCategoryExec},
+	"ssh_docker_restart": {config.ToolCategoryExec},
+	"ssh_kubectl_exec":   {config.ToolCategoryExec},
+	"ssh_open_terminal":  {config.ToolCategoryExec},
+	"ssh_send_input":     {config.ToolCategoryExec},
+	"ssh_read_output":    {config.ToolCategoryExec},
+	"ssh_close_terminal": {config.ToolCategoryExec},
+	"ssh_upload":         {config.ToolCategoryFileWrite},
+	"ssh_fetch_url":      {config.ToolCategoryFileWrite},
+	"ssh_edit_file":      {config.ToolCategoryFileWrite},
+	"ssh_restore_backup": {config.ToolCategoryFileWrite},
+	"ssh_copy":           {config.ToolCategoryFileWrite},
+	"ssh_rename":         {config.ToolCategoryFileWrite},
+	"ssh_transfer":       {config.ToolCategoryFileWrite},
+	"ssh_archive":        {config.ToolCategoryFileWrite},
+	"ssh_extract":        {config.ToolCategoryFileWrite},
+	"ssh_keygen":         {config.ToolCategoryFileWrite},
+	"ssh_deploy_key":     {config.ToolCategoryFileWrite},
+	"ssh_workspace":      {config.ToolCategoryFileWrite},
+	"ssh_git":            {config.ToolCategoryFileWrite},
+	"ssh_tunnel_create":  {config.ToolCategoryTunnels},
+	"ssh_db_tunnel":      {config.ToolCategoryTunnels},
+	"ssh_tunnel_list":    {config.ToolCategoryTunnels},
+	"ssh_tunnel_close":   {config.ToolCategoryTunnels},
+	// Plans mix edits and uploads with commands.
+	"ssh_plan_execute": {config.ToolCategoryExec, config.ToolCategoryFileWrite},
+
+	// The screenshot only reads, but goes with the terminal it captures.
+	"ssh_terminal_screenshot": {config.ToolCategoryExec},
+
+	// Read-only, but they run commands: version probes and diagnostics.
+	"ssh_which":               {config.ToolCategoryExec},
+	"ssh_collect_diagnostics": {config.ToolCategoryExec},
+}
+
+// categoriesOf returns the categories of toolName. Command templates and
+// plugin tools run remote commands, so they are exec tools.
+func (s *Server) categoriesOf(toolName string) []string {
+	if cats, ok := toolCategories[toolName]; ok {
+		return cats
+	}
+	if slices.ContainsFunc(s.cfg.CommandTemplates, func(t config.CommandTemplate) bool { return t.Name == toolName }) || s.isPluginTool(toolName) {
+		return []string{config.ToolCategoryExec}
+	}
+	return nil
+}
+
+// disabledCategoriesOf returns the categories of toolName that are disabled.
+func (s *Server) disabledCategoriesOf(toolName string) []string {
+	var off []string
+	for _, c := range s.categoriesOf(toolName) {
+		if slices.Contains(s.cfg.DisabledCategories, c) {
+			off = append(off, c)
+		}
+	}
+	return off
+}
+
+// isCategoryDisabled reports whether every category of toolName is disabled.
+func (s *Server) isCategoryDisabled(toolName string) bool {
+	cats := s.categoriesOf(toolName)
+	return len(cats) > 0 && len(s.disabledCategoriesOf(toolName)) == len(cats)
+}
+
+// categoryNote describes the disabled categories of a tool that stays
+// enabled, for its description ("" if none are).
+func (s *Server) categoryNote(toolName string) string {
+	off := s.disabledCategoriesOf(toolName)
+	if len(off) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" On this server the %s tool categories are disabled, so parts of this tool that need them are rejected.", strings.Join(off, " and "))
+}
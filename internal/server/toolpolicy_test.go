@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func registeredNames(t *testing.T, cfg *config.Config) map[string]bool {
+	t.Helper()
+	srv, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, ti := range srv.registered {
+		names[ti.Name] = true
+	}
+	return names
+}
+
+func TestToolCategories_NamesRegisteredTools(t *testing.T) {
+	cfg := testConfig()
+	cfg.SSH.AllowTerminal = true
+	cfg.SSH.AllowTunnels = true
+	cfg.Security.FetchDomains = []string{"example.com"}
+	names := registeredNames(t, cfg)
+	for name := range toolCategories {
+		if !names[name] {
+			t.Errorf("toolCategories lists %q, which is not a registered tool", name)
+		}
+	}
+}
+
+func TestPresets_DisableCategories(t *testing.T) {
+	for _, tt := range []struct {
+		cats     []string
+		disabled []string
+		enabled  []string
+	}{
+		{
+			cats:     config.PresetCategories[config.PresetReadOnly],
+			disabled: []string{"ssh_execute", "ssh_upload", "ssh_plan_execute", "ssh_open_terminal", "ssh_tunnel_create"},
+			enabled:  []string{"ssh_connect", "ssh_read_file", "ssh_download", "ssh_docker_logs", "ssh_server_info"},
+		},
+		{
+			cats:     config.PresetCategories[config.PresetDiagnostics],
+			disabled: []string{"ssh_upload", "ssh_edit_file", "ssh_deploy_key"},
+			enabled:  []string{"ssh_execute", "ssh_plan_execute", "ssh_read_file", "ssh_tunnel_create"},
+		},
+		{
+			cats:     []string{config.ToolCategoryExec},
+			disabled: []string{"ssh_execute", "ssh_run_script", "ssh_docker_exec", "ssh_send_input", "ssh_which", "ssh_collect_diagnostics"},
+			enabled:  []string{"ssh_upload", "ssh_plan_execute", "ssh_docker_ps"},
+		},
+	} {
+		cfg := testConfig()
+		cfg.SSH.AllowTerminal = true
+		cfg.SSH.AllowTunnels = true
+		cfg.DisabledCategories = tt.cats
+		names := registeredNames(t, cfg)
+		for _, n := range tt.disabled {
+			if names[n] {
+				t.Errorf("categories %v: %s is registered", tt.cats, n)
+			}
+		}
+		for _, n := range tt.enabled {
+			if !names[n] {
+				t.Errorf("categories %v: %s is not registered", tt.cats, n)
+			}
+		}
+	}
+}
+
+func TestCategoryNote(t *testing.T) {
+	cfg := testConfig()
+	cfg.DisabledCategories = []string{config.ToolCategoryExec}
+	s := &Server{cfg: cfg}
+
+	if note := s.categoryNote("ssh_plan_execute"); !strings.Contains(note, "exec tool categories are disabled") {
+		t.Errorf("plan note = %q", note)
+	}
+	if note := s.categoryNote("ssh_upload"); note != "" {
+		t.Errorf("upload note = %q, want none", note)
+	}
+	if !s.isToolDisabled("ssh_execute") || s.isToolDisabled("ssh_plan_execute") {
+		t.Error("exec category should disable ssh_execute but not ssh_plan_execute")
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/transcript"
+)
+
+// transcriptMiddleware records every tool call, with its redacted
+// arguments and full text result, in the transcript of the SSH session it
+// used (--transcript-dir). It wraps auditMiddleware, so calls denied by the
+// policy webhook are recorded too. Reading a transcript is not recorded.
+func (s *Server) transcriptMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || call.Params == nil || call.Params.Name == "ssh_get_transcript" {
+			return next(ctx, method, req)
+		}
+
+		// Arguments are captured first: the policy webhook may replace them.
+		var args map[string]any
+		_ = json.Unmarshal(call.Params.Arguments, &args)
+		e := transcript.Entry{
+			Time:      time.Now().UTC().Format(time.RFC3339),
+			Owner:     connection.OwnerFrom(ctx),
+			SessionID: callSessionID(call.Params),
+			Tool:      call.Params.Name,
+			Arguments: security.RedactArguments(args),
+		}
+
+		start := time.Now()
+		res, err := next(ctx, method, req)
+		e.DurationMs = time.Since(start).Milliseconds()
+
+		text := resultText(res)
+		switch r, _ := res.(*mcp.CallToolResult); {
+		case err != nil:
+			e.Error = err.Error()
+		case r != nil && r.IsError:
+			e.Error = text
+		default:
+			e.Output = text
+		}
+		if e.SessionID == "" && e.Tool == "ssh_connect" && e.Error == "" {
+			e.SessionID = connectedSessionID(text)
+		}
+		if rerr := s.transcripts.Record(e); rerr != nil {
+			log.Printf("Transcript: %v", rerr)
+		}
+		return res, err
+	}
+}
+
+// resultText joins the text content of a tool result.
+func resultText(res mcp.Result) string {
+	r, ok := res.(*mcp.CallToolResult)
+	if !ok || r == nil {
+		return ""
+	}
+	var parts []string
+	for _, c := range r.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// connectedSessionID extracts the session ID from ssh_connect's message,
+// "Connected to user@host:port ...", which is how the ID is formed.
+func connectedSessionID(text string) string {
+	rest, ok := strings.CutPrefix(text, "Connected to ")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimRight(fields[0], ",")
+}
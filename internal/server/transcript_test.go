@@ -0,0 +1,20 @@
+package server
+
+import "testing"
+
+func TestConnectedSessionID(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Connected to root@web:22 (linux, bash)", "root@web:22"},
+		{"Connected to admin@10.0.0.1:2222, reused existing connection", "admin@10.0.0.1:2222"},
+		{"Connected to ", ""},
+		{"connection refused", ""},
+	}
+	for _, tt := range tests {
+		if got := connectedSessionID(tt.text); got != tt.want {
+			t.Errorf("connectedSessionID(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+// FilePair names a regular file copied by a transfer on both ends.
+type FilePair struct {
+	Local  string
+	Remote string
+}
+
+// LocalSHA256 returns the hex SHA-256 of a local file.
+func LocalSHA256(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+// RemoteSHA256 returns the hex SHA-256 of a remote file, reading it back
+// over SFTP. Prefer a remote sha256sum where one can run: it hashes the
+// bytes on disk without sending them over the link again.
+func RemoteSHA256(sftpClient *sftp.Client, remotePath string) (string, error) {
+	f, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(f)
+}
+
+func hashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("read for checksum: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,39 @@
+package sshclient
+
+import (
+	"context"
+	"io"
+)
+
+// NewContextReader returns a reader that fails with ctx's error once ctx is
+// done, so a cancelled tool call stops a stream at the next read.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// contextWriter is the writing side of contextReader. Downloads wrap the
+// local file rather than the remote one so io.Copy still uses
+// sftp.File.WriteTo, which reads ahead with concurrent requests.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package sshclient
+
+import "os"
+
+// localOwner reports that file ownership is not available: Windows files
+// have no numeric UID/GID.
+func localOwner(os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
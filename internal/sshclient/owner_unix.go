@@ -0,0 +1,17 @@
+//go:build unix
+
+package sshclient
+
+import (
+	"os"
+	"syscall"
+)
+
+// localOwner returns the numeric owner and group of a local file.
+func localOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
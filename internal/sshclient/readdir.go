@@ -0,0 +1,345 @@
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrStopListing can be returned by a ReadDirStream callback to stop
+// reading the directory early without an error.
+var ErrStopListing = errors.New("stop listing")
+
+// maxListPacket bounds one SFTP reply read by ReadDirStream. Servers send
+// READDIR replies of around a hundred names; OpenSSH caps packets at 256 KiB.
+const maxListPacket = 1 << 20
+
+// SFTP v3 packet types and status codes used by ReadDirStream.
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpClose   = 4
+	fxpOpendir = 11
+	fxpReaddir = 12
+	fxpStatus  = 101
+	fxpHandle  = 102
+	fxpName    = 104
+
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+)
+
+// ReadDirStream lists dir on an SFTP channel of its own, calling fn for each
+// entry as the server's READDIR replies arrive. Unlike sftp.Client.ReadDir,
+// which returns the whole directory, memory use stays at one reply however
+// many entries the directory holds, and fn can stop early by returning
+// ErrStopListing. Entries come in the server's order, without "." and "..";
+// their Sys() is a *sftp.FileStat, as with sftp.Client. opTimeout works as
+// in NewSFTPClient.
+func ReadDirStream(ctx context.Context, client *ssh.Client, opTimeout time.Duration, dir string, fn func(os.FileInfo) error) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	session.Stderr = io.Discard
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return fmt.Errorf("start sftp subsystem: %w", err)
+	}
+	// Closing the session unblocks a read stuck on a hung server.
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	var r io.Reader = stdout
+	var w io.Writer = stdin
+	if opTimeout > 0 {
+		wd := &sftpWatchdog{timeout: opTimeout, kill: func() { session.Close() }}
+		defer wd.stop()
+		r = &watchedReader{r: stdout, w: wd}
+		w = &watchedWriter{WriteCloser: stdin, w: wd}
+	}
+	err = readDirStream(ctx, r, w, dir, fn)
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// readDirStream speaks the SFTP side of ReadDirStream over r and w.
+func readDirStream(ctx context.Context, r io.Reader, w io.Writer, dir string, fn func(os.FileInfo) error) error {
+	br := bufio.NewReader(r)
+	if err := writeSFTPPacket(w, fxpInit, nil, 3); err != nil {
+		return err
+	}
+	if typ, _, err := readSFTPPacket(br); err != nil {
+		return err
+	} else if typ != fxpVersion {
+		return fmt.Errorf("sftp: unexpected packet type %d during init", typ)
+	}
+
+	id := uint32(1)
+	if err := writeSFTPPacket(w, fxpOpendir, []string{dir}, id); err != nil {
+		return err
+	}
+	data, err := expectSFTPReply(br, id, fxpHandle)
+	if err != nil {
+		return fmt.Errorf("open directory %s: %w", dir, err)
+	}
+	handle, _, err := sftpString(data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		id++
+		if writeSFTPPacket(w, fxpClose, []string{handle}, id) == nil {
+			_, _, _ = readSFTPPacket(br)
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id++
+		if err := writeSFTPPacket(w, fxpReaddir, []string{handle}, id); err != nil {
+			return err
+		}
+		data, err := expectSFTPReply(br, id, fxpName)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read directory %s: %w", dir, err)
+		}
+		if len(data) < 4 {
+			return errors.New("sftp: short NAME packet")
+		}
+		count, data := binary.BigEndian.Uint32(data), data[4:]
+		for range count {
+			var name string
+			var info os.FileInfo
+			if name, data, err = sftpString(data); err != nil {
+				return err
+			}
+			if _, data, err = sftpString(data); err != nil { // long name
+				return err
+			}
+			if info, data, err = parseSFTPAttrs(name, data); err != nil {
+				return err
+			}
+			if name == "." || name == ".." {
+				continue
+			}
+			if err := fn(info); err != nil {
+				if errors.Is(err, ErrStopListing) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// writeSFTPPacket sends a packet: type, then id (or the version for
+// INIT), then each argument as an SFTP string.
+func writeSFTPPacket(w io.Writer, typ byte, args []string, id uint32) error {
+	size := 1 + 4
+	for _, a := range args {
+		size += 4 + len(a)
+	}
+	buf := make([]byte, 0, 4+size)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(size))
+	buf = append(buf, typ)
+	buf = binary.BigEndian.AppendUint32(buf, id)
+	for _, a := range args {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(a)))
+		buf = append(buf, a...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readSFTPPacket reads one packet and returns its type and payload.
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp: read reply: %w", err)
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size == 0 || size > maxListPacket {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, fmt.Errorf("sftp: read reply: %w", err)
+	}
+	return body[0], body[1:], nil
+}
+
+// expectSFTPReply reads the reply to request id and returns its payload
+// after the id if it has type want. A STATUS reply becomes an error:
+// io.EOF for SSH_FX_EOF, or one matching fs.ErrNotExist/fs.ErrPermission.
+func expectSFTPReply(r io.Reader, id uint32, want byte) ([]byte, error) {
+	typ, data, err := readSFTPPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != id {
+		return nil, fmt.Errorf("sftp: reply for unexpected request")
+	}
+	data = data[4:]
+	switch typ {
+	case want:
+		return data, nil
+	case fxpStatus:
+		if len(data) < 4 {
+			return nil, errors.New("sftp: short STATUS packet")
+		}
+		code := binary.BigEndian.Uint32(data)
+		msg, _, _ := sftpString(data[4:])
+		switch code {
+		case fxEOF:
+			return nil, io.EOF
+		case fxNoSuchFile:
+			return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, msg)
+		case fxPermissionDenied:
+			return nil, fmt.Errorf("%w: %s", fs.ErrPermission, msg)
+		}
+		return nil, fmt.Errorf("sftp: %s (status %d)", msg, code)
+	}
+	return nil, fmt.Errorf("sftp: unexpected packet type %d", typ)
+}
+
+func sftpString(data []byte) (string, []byte, error) {
+	if len(data) < 4 {
+		return "", nil, errors.New("sftp: truncated string")
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return "", nil, errors.New("sftp: truncated string")
+	}
+	return string(data[4 : 4+n]), data[4+n:], nil
+}
+
+// SFTP v3 attribute flags.
+const (
+	attrSize     = 0x00000001
+	attrUIDGID   = 0x00000002
+	attrPerms    = 0x00000004
+	attrTimes    = 0x00000008
+	attrExtended = 0x80000000
+)
+
+// parseSFTPAttrs decodes an ATTRS block into a FileInfo named name.
+func parseSFTPAttrs(name string, data []byte) (os.FileInfo, []byte, error) {
+	short := errors.New("sftp: truncated attributes")
+	u32 := func() (uint32, error) {
+		if len(data) < 4 {
+			return 0, short
+		}
+		v := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		return v, nil
+	}
+	flags, err := u32()
+	if err != nil {
+		return nil, nil, err
+	}
+	st := &sftp.FileStat{}
+	if flags&attrSize != 0 {
+		if len(data) < 8 {
+			return nil, nil, short
+		}
+		st.Size, data = binary.BigEndian.Uint64(data), data[8:]
+	}
+	for _, f := range []struct {
+		flag uint32
+		dst  []*uint32
+	}{
+		{attrUIDGID, []*uint32{&st.UID, &st.GID}},
+		{attrPerms, []*uint32{&st.Mode}},
+		{attrTimes, []*uint32{&st.Atime, &st.Mtime}},
+	} {
+		if flags&f.flag == 0 {
+			continue
+		}
+		for _, dst := range f.dst {
+			if *dst, err = u32(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if flags&attrExtended != 0 {
+		n, err := u32()
+		if err != nil {
+			return nil, nil, err
+		}
+		for range 2 * n {
+			if _, data, err = sftpString(data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return &streamedEntry{name: name, stat: st}, data, nil
+}
+
+// streamedEntry is the os.FileInfo of a ReadDirStream entry.
+type streamedEntry struct {
+	name string
+	stat *sftp.FileStat
+}
+
+func (e *streamedEntry) Name() string       { return e.name }
+func (e *streamedEntry) Size() int64        { return int64(e.stat.Size) }
+func (e *streamedEntry) Mode() fs.FileMode  { return posixFileMode(e.stat.Mode) }
+func (e *streamedEntry) ModTime() time.Time { return time.Unix(int64(e.stat.Mtime), 0) }
+func (e *streamedEntry) IsDir() bool        { return e.Mode().IsDir() }
+func (e *streamedEntry) Sys() any           { return e.stat }
+
+// posixFileMode converts st_mode bits, as sent in SFTP attributes, to an
+// fs.FileMode.
+func posixFileMode(m uint32) fs.FileMode {
+	mode := fs.FileMode(m & 0777)
+	switch m & 0170000 {
+	case 0040000:
+		mode |= fs.ModeDir
+	case 0120000:
+		mode |= fs.ModeSymlink
+	case 0010000:
+		mode |= fs.ModeNamedPipe
+	case 0140000:
+		mode |= fs.ModeSocket
+	case 0020000:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0060000:
+		mode |= fs.ModeDevice
+	}
+	if m&04000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
@@ -0,0 +1,132 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// streamTestDir runs readDirStream against an in-process SFTP server.
+func streamTestDir(t *testing.T, dir string, fn func(os.FileInfo) error) error {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	t.Cleanup(func() {
+		server.Close()
+		clientWrite.Close()
+	})
+	return readDirStream(t.Context(), clientRead, clientWrite, dir, fn)
+}
+
+func TestReadDirStream(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for i := range 1000 {
+		name := fmt.Sprintf("f%04d", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0640); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, name)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f0001", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, "link", "sub")
+	slices.Sort(want)
+
+	got := map[string]os.FileInfo{}
+	if err := streamTestDir(t, dir, func(fi os.FileInfo) error {
+		got[fi.Name()] = fi
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(got))
+	for name := range got {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, want) {
+		t.Fatalf("got %d entries, want %d", len(names), len(want))
+	}
+	if fi := got["f0042"]; fi.Size() != 5 || fi.Mode() != 0640 || fi.ModTime().IsZero() {
+		t.Errorf("f0042: size %d, mode %v, mtime %v", fi.Size(), fi.Mode(), fi.ModTime())
+	}
+	if fi := got["sub"]; !fi.IsDir() || fi.Mode().Perm() != 0750 {
+		t.Errorf("sub: mode %v", fi.Mode())
+	}
+	if fi := got["link"]; fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("link: mode %v", fi.Mode())
+	}
+	if _, ok := got["f0001"].Sys().(*sftp.FileStat); !ok {
+		t.Errorf("Sys() = %T, want *sftp.FileStat", got["f0001"].Sys())
+	}
+}
+
+func TestReadDirStream_Stop(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 500 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprint(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seen := 0
+	err := streamTestDir(t, dir, func(os.FileInfo) error {
+		if seen++; seen == 10 {
+			return ErrStopListing
+		}
+		return nil
+	})
+	if err != nil || seen != 10 {
+		t.Errorf("seen %d entries, err %v; want 10, nil", seen, err)
+	}
+
+	boom := errors.New("boom")
+	if err := streamTestDir(t, dir, func(os.FileInfo) error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want callback error", err)
+	}
+}
+
+func TestReadDirStream_Missing(t *testing.T) {
+	err := streamTestDir(t, filepath.Join(t.TempDir(), "nope"), func(os.FileInfo) error { return nil })
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestPosixFileMode(t *testing.T) {
+	tests := map[uint32]fs.FileMode{
+		0100644: 0644,
+		0040755: fs.ModeDir | 0755,
+		0120777: fs.ModeSymlink | 0777,
+		0010600: fs.ModeNamedPipe | 0600,
+		0140755: fs.ModeSocket | 0755,
+		0020620: fs.ModeDevice | fs.ModeCharDevice | 0620,
+		0060660: fs.ModeDevice | 0660,
+		0104755: fs.ModeSetuid | 0755,
+		0041777: fs.ModeDir | fs.ModeSticky | 0777,
+	}
+	for in, want := range tests {
+		if got := posixFileMode(in); got != want {
+			t.Errorf("posixFileMode(%o) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SCPUpload copies a local file or directory to remotePath with the scp
+// protocol, for hosts whose sshd has no SFTP subsystem. Like UploadFile and
+// UploadDir, remotePath names the file or directory to create, and modes
+// are set by modes. Unlike UploadDir, the parent of remotePath must exist.
+// It returns the number of files and bytes uploaded. Cancelling ctx
+// aborts the copy.
+func SCPUpload(ctx context.Context, client *ssh.Client, localPath, remotePath string, modes ModePolicy) (int, int64, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("stat local path: %w", err)
+	}
+	remotePath = path.Clean(remotePath)
+	cmd := "scp -t " + scpRemoteArg(path.Dir(remotePath))
+	if info.IsDir() {
+		cmd = "scp -r -t " + scpRemoteArg(path.Dir(remotePath))
+	}
+
+	var files int
+	var total int64
+	err = runSCP(ctx, client, cmd, func(w io.Writer, r *bufio.Reader) error {
+		var err error
+		files, total, err = scpSend(w, r, localPath, path.Base(remotePath), modes)
+		return err
+	})
+	return files, total, err
+}
+
+// SCPDownload copies a remote file or directory to localPath with the scp
+// protocol. localPath becomes the file, or the directory holding the remote
+// directory's contents, as with DownloadFile and DownloadDir. Names sent by
+// the server are checked so it cannot write outside localPath. It returns
+// the number of files and bytes downloaded. Cancelling ctx aborts the copy.
+func SCPDownload(ctx context.Context, client *ssh.Client, remotePath, localPath string) (int, int64, error) {
+	var files int
+	var total int64
+	err := runSCP(ctx, client, "scp -r -f "+scpRemoteArg(path.Clean(remotePath)), func(w io.Writer, r *bufio.Reader) error {
+		var err error
+		files, total, err = scpReceive(w, r, localPath)
+		return err
+	})
+	return files, total, err
+}
+
+// runSCP starts the remote scp command and runs the local side of the
+// protocol against its stdin and stdout. The remote stderr is added to
+// errors since scp reports most failures there.
+func runSCP(ctx context.Context, client *ssh.Client, cmd string, proto func(io.Writer, *bufio.Reader) error) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("scp stdin: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("scp stdout: %w", err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("start scp: %w", err)
+	}
+	// Closing the session unblocks the protocol and stops the remote scp.
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	protoErr := proto(stdin, bufio.NewReader(stdout))
+	stdin.Close()
+	waitErr := session.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil && (protoErr != nil || waitErr != nil) {
+		return ctxErr
+	}
+
+	err = protoErr
+	if err == nil && waitErr != nil {
+		err = fmt.Errorf("scp: %w", waitErr)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// scpRemoteArg quotes a remote path for the remote shell. scp runs in the
+// login directory, so a leading ~/ is dropped to make the path relative to
+// home instead of being quoted away.
+func scpRemoteArg(p string) string {
+	switch {
+	case p == "~":
+		p = "."
+	case strings.HasPrefix(p, "~/"):
+		p = strings.TrimPrefix(p, "~/")
+	}
+	if strings.HasPrefix(p, "-") {
+		p = "./" + p
+	}
+	return "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+}
+
+// scpReadAck reads the one-byte reply that follows every scp record: 0 for
+// success, or 1 (warning) or 2 (error) followed by a message line.
+func scpReadAck(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read scp reply: %w", err)
+	}
+	if b == 0 {
+		return nil
+	}
+	msg, _ := r.ReadString('\n')
+	return fmt.Errorf("remote scp: %s", strings.TrimSpace(msg))
+}
+
+// scpSend runs the source side of the protocol: a C record with the data
+// for a file, or a D ... E block for a directory. Symlinks are skipped as
+// in UploadDir.
+func scpSend(w io.Writer, r *bufio.Reader, localPath, name string, modes ModePolicy) (int, int64, error) {
+	if err := scpReadAck(r); err != nil {
+		return 0, 0, err
+	}
+	var files int
+	var total int64
+	var send func(localPath, name string) error
+	send = func(localPath, name string) error {
+		info, err := os.Lstat(localPath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			log.Printf("upload: skipping symlink %s", localPath)
+			return nil
+		case info.IsDir():
+			if _, err := fmt.Fprintf(w, "D%04o 0 %s\n", modes.DirMode(info.Mode()), name); err != nil {
+				return err
+			}
+			if err := scpReadAck(r); err != nil {
+				return err
+			}
+			entries, err := os.ReadDir(localPath)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				if err := send(filepath.Join(localPath, e.Name()), e.Name()); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, "E\n"); err != nil {
+				return err
+			}
+			return scpReadAck(r)
+		case !info.Mode().IsRegular():
+			log.Printf("upload: skipping special file %s", localPath)
+			return nil
+		}
+
+		f, err := os.Open(localPath)
+		if err != nil {
+			return fmt.Errorf("open local file: %w", err)
+		}
+		defer f.Close()
+		if _, err := fmt.Fprintf(w, "C%04o %d %s\n", modes.FileMode(info.Mode()), info.Size(), name); err != nil {
+			return err
+		}
+		if err := scpReadAck(r); err != nil {
+			return err
+		}
+		n, err := io.CopyN(w, f, info.Size())
+		if err != nil {
+			return fmt.Errorf("send %s: %w", localPath, err)
+		}
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+		if err := scpReadAck(r); err != nil {
+			return err
+		}
+		files++
+		total += n
+		return nil
+	}
+	err := send(localPath, name)
+	return files, total, err
+}
+
+// scpReceive runs the sink side of the protocol, writing the first record
+// to localPath and everything below it under that directory.
+func scpReceive(w io.Writer, r *bufio.Reader, localPath string) (int, int64, error) {
+	ack := func() error {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	if err := ack(); err != nil {
+		return 0, 0, err
+	}
+
+	type dir struct {
+		path string
+		mode os.FileMode
+	}
+	var files int
+	var total int64
+	var dirs []dir // directories entered so far; the last is current
+	for {
+		line, err := r.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			if len(dirs) > 0 {
+				return files, total, fmt.Errorf("scp stream ended inside directory %s", dirs[len(dirs)-1].path)
+			}
+			return files, total, nil
+		}
+		if err != nil {
+			return files, total, fmt.Errorf("read scp record: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return files, total, fmt.Errorf("empty scp record")
+		}
+
+		switch line[0] {
+		case 1, 2:
+			return files, total, fmt.Errorf("remote scp: %s", strings.TrimSpace(line[1:]))
+		case 'T':
+			// Timestamps, only sent with -p.
+		case 'E':
+			if len(dirs) == 0 {
+				return files, total, fmt.Errorf("unexpected scp end-of-directory record")
+			}
+			// Directories are created writable so their contents can be
+			// written; their own mode is applied once they are complete.
+			d := dirs[len(dirs)-1]
+			dirs = dirs[:len(dirs)-1]
+			if err := os.Chmod(d.path, d.mode); err != nil {
+				return files, total, fmt.Errorf("chmod %s: %w", d.path, err)
+			}
+		case 'C', 'D':
+			mode, size, name, err := parseSCPRecord(line)
+			if err != nil {
+				return files, total, err
+			}
+			target := localPath
+			if len(dirs) > 0 {
+				target = filepath.Join(dirs[len(dirs)-1].path, name)
+			}
+			if line[0] == 'D' {
+				if err := os.MkdirAll(target, 0700); err != nil {
+					return files, total, fmt.Errorf("mkdir %s: %w", target, err)
+				}
+				dirs = append(dirs, dir{target, mode})
+				break
+			}
+			if err := ack(); err != nil {
+				return files, total, err
+			}
+			if err := scpReceiveFile(r, target, mode, size); err != nil {
+				return files, total, err
+			}
+			if err := scpReadAck(r); err != nil {
+				return files, total, err
+			}
+			files++
+			total += size
+		default:
+			return files, total, fmt.Errorf("unexpected scp record %q", line)
+		}
+		if err := ack(); err != nil {
+			return files, total, err
+		}
+	}
+}
+
+// parseSCPRecord parses "Cmmmm size name" or "Dmmmm 0 name". The name must
+// be a single path element so the server cannot write outside the target.
+func parseSCPRecord(line string) (os.FileMode, int64, string, error) {
+	fields := strings.SplitN(line[1:], " ", 3)
+	if len(fields) != 3 {
+		return 0, 0, "", fmt.Errorf("malformed scp record %q", line)
+	}
+	mode, err := strconv.ParseUint(fields[0], 8, 32)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("malformed scp mode in %q", line)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || size < 0 {
+		return 0, 0, "", fmt.Errorf("malformed scp size in %q", line)
+	}
+	name := fields[2]
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return 0, 0, "", fmt.Errorf("scp: refusing unsafe file name %q", name)
+	}
+	return os.FileMode(mode).Perm(), size, name, nil
+}
+
+func scpReceiveFile(r io.Reader, target string, mode os.FileMode, size int64) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return fmt.Errorf("create local file: %w", err)
+	}
+	if _, err := io.CopyN(f, r, size); err != nil {
+		f.Close()
+		return fmt.Errorf("receive %s: %w", target, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// OpenFile applies the umask; set the mode the server sent.
+	return os.Chmod(target, mode)
+}
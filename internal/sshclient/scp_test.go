@@ -0,0 +1,206 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scpRoundTrip connects scpSend to scpReceive over pipes, as the remote
+// scp -t / scp -f pair would be.
+func scpRoundTrip(t *testing.T, src, name, dst string, modes ModePolicy) (int, int64) {
+	t.Helper()
+	dataR, dataW := io.Pipe()
+	ackR, ackW := io.Pipe()
+
+	type result struct {
+		files int
+		total int64
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		files, total, err := scpReceive(ackW, bufio.NewReader(dataR), dst)
+		ackW.Close()
+		done <- result{files, total, err}
+	}()
+
+	sentFiles, sentBytes, err := scpSend(dataW, bufio.NewReader(ackR), src, name, modes)
+	dataW.Close()
+	if err != nil {
+		t.Fatalf("scpSend: %v", err)
+	}
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("scpReceive: %v", got.err)
+	}
+	if got.files != sentFiles || got.total != sentBytes {
+		t.Errorf("received %d files/%d bytes, sent %d/%d", got.files, got.total, sentFiles, sentBytes)
+	}
+	return sentFiles, sentBytes
+}
+
+func TestSCP_RoundTripFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.sh")
+	if err := os.WriteFile(src, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst.sh")
+
+	files, n := scpRoundTrip(t, src, "dst.sh", dst, ModePolicy{})
+	if files != 1 || n != 18 {
+		t.Errorf("got %d files, %d bytes", files, n)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "#!/bin/sh\necho hi\n" {
+		t.Fatalf("content = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestSCP_RoundTripDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	for path, content := range map[string]string{
+		"a.txt":         "alpha",
+		"sub/b.txt":     "bravo",
+		"sub/deep/c.md": "",
+	} {
+		p := filepath.Join(src, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(src, "sub"), 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(filepath.Join(src, "sub"), 0755) })
+	dst := filepath.Join(dir, "dst")
+
+	files, n := scpRoundTrip(t, src, "dst", dst, ModePolicy{})
+	t.Cleanup(func() { os.Chmod(filepath.Join(dst, "sub"), 0755) })
+	if files != 3 || n != 10 {
+		t.Errorf("got %d files, %d bytes", files, n)
+	}
+	for path, want := range map[string]string{"a.txt": "alpha", "sub/b.txt": "bravo", "sub/deep/c.md": ""} {
+		data, err := os.ReadFile(filepath.Join(dst, path))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v", path, data, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "link")); !os.IsNotExist(err) {
+		t.Errorf("symlink should be skipped, got %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "sub")); info.Mode().Perm() != 0500 {
+		t.Errorf("sub mode = %v, want 0500", info.Mode().Perm())
+	}
+}
+
+func TestSCP_RoundTripModes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "run.sh"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+
+	scpRoundTrip(t, src, "dst", dst, ModePolicy{File: 0600, Dir: 0777, Umask: 0027})
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0750 {
+		t.Errorf("dir mode = %v, want 0750", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "run.sh")); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestSCPReceive_Stream(t *testing.T) {
+	// What "scp -r -f dir" sends for a directory holding one file.
+	stream := "D0755 0 dir\n" +
+		"T1700000000 0 1700000000 0\n" +
+		"C0600 5 f.txt\nhello\x00" +
+		"E\n"
+	dst := filepath.Join(t.TempDir(), "out")
+	var acks bytes.Buffer
+	files, n, err := scpReceive(&acks, bufio.NewReader(strings.NewReader(stream)), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 1 || n != 5 {
+		t.Errorf("got %d files, %d bytes", files, n)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "f.txt")); string(data) != "hello" {
+		t.Errorf("content = %q", data)
+	}
+	// Initial ack, one per record, and one after the file data.
+	if acks.Len() != 6 {
+		t.Errorf("sent %d acks, want 6", acks.Len())
+	}
+}
+
+func TestSCPReceive_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   string
+	}{
+		{"remote error", "\x01scp: /nope: No such file or directory\n", "No such file"},
+		{"path traversal", "D0755 0 dir\nC0644 1 ../evil\nx\x00E\n", "unsafe file name"},
+		{"absolute name", "C0644 1 /etc/passwd\nx\x00", "unsafe file name"},
+		{"malformed", "C0644 lots f\n", "malformed"},
+		{"unterminated dir", "D0755 0 dir\n", "ended inside directory"},
+		{"stray end", "E\n", "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "out")
+			_, _, err := scpReceive(io.Discard, bufio.NewReader(strings.NewReader(tt.stream)), dst)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSCPSend_RemoteError(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(src, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	replies := "\x00\x01scp: /ro/f: Permission denied\n"
+	_, _, err := scpSend(io.Discard, bufio.NewReader(strings.NewReader(replies)), src, "f", ModePolicy{})
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSCPRemoteArg(t *testing.T) {
+	tests := map[string]string{
+		"/var/log":       `'/var/log'`,
+		"~":              `'.'`,
+		"~/dir":          `'dir'`,
+		"-rf":            `'./-rf'`,
+		"it's":           `'it'\''s'`,
+		"/tmp/$(reboot)": `'/tmp/$(reboot)'`,
+	}
+	for in, want := range tests {
+		if got := scpRemoteArg(in); got != want {
+			t.Errorf("scpRemoteArg(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package sshclient
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
@@ -8,14 +12,25 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
-// NewSFTPClient creates a new SFTP client from an SSH client.
-func NewSFTPClient(client *ssh.Client) (*sftp.Client, error) {
-	sftpClient, err := sftp.NewClient(client)
+// NewSFTPClient creates a new SFTP client from an SSH client. With a
+// positive opTimeout, a server that leaves a request unanswered for that
+// long has its SFTP session closed, failing the pending operations.
+func NewSFTPClient(client *ssh.Client, opTimeout time.Duration) (*sftp.Client, error) {
+	var sftpClient *sftp.Client
+	var err error
+	if opTimeout > 0 {
+		sftpClient, err = newWatchedSFTPClient(client, opTimeout)
+	} else {
+		sftpClient, err = sftp.NewClient(client)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
 	}
@@ -32,8 +47,28 @@ func ExpandRemotePath(sftpClient *sftp.Client, remotePath string) string {
 	return remotePath
 }
 
+// WindowsPath converts a Windows path to the form Windows OpenSSH's SFTP
+// server expects: backslashes become slashes and a drive letter gets a
+// leading slash, so C:\Users\me and C:/Users/me both become /C:/Users/me.
+// Other paths only have their backslashes replaced.
+func WindowsPath(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if len(p) >= 2 && p[1] == ':' && isDriveLetter(p[0]) && (len(p) == 2 || p[2] == '/') {
+		p = "/" + p
+		if len(p) == 3 {
+			p += "/"
+		}
+	}
+	return p
+}
+
+func isDriveLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
 // UploadFile uploads a local file to a remote path, preserving permissions.
-func UploadFile(sftpClient *sftp.Client, localPath, remotePath string, perms *fs.FileMode) (int64, error) {
+// The copy stops with ctx's error once ctx is done.
+func UploadFile(ctx context.Context, sftpClient *sftp.Client, localPath, remotePath string, perms *fs.FileMode) (int64, error) {
 	localFile, err := os.Open(localPath)
 	if err != nil {
 		return 0, fmt.Errorf("open local file: %w", err)
@@ -56,7 +91,7 @@ func UploadFile(sftpClient *sftp.Client, localPath, remotePath string, perms *fs
 	}
 	defer remoteFile.Close()
 
-	n, err := io.Copy(remoteFile, localFile)
+	n, err := io.Copy(remoteFile, NewContextReader(ctx, localFile))
 	if err != nil {
 		return 0, fmt.Errorf("copy to remote: %w", err)
 	}
@@ -69,7 +104,8 @@ func UploadFile(sftpClient *sftp.Client, localPath, remotePath string, perms *fs
 }
 
 // DownloadFile downloads a remote file to a local path, preserving permissions.
-func DownloadFile(sftpClient *sftp.Client, remotePath, localPath string) (int64, error) {
+// The copy stops with ctx's error once ctx is done.
+func DownloadFile(ctx context.Context, sftpClient *sftp.Client, remotePath, localPath string) (int64, error) {
 	remoteFile, err := sftpClient.Open(remotePath)
 	if err != nil {
 		return 0, fmt.Errorf("open remote file: %w", err)
@@ -88,7 +124,7 @@ func DownloadFile(sftpClient *sftp.Client, remotePath, localPath string) (int64,
 	}
 	defer localFile.Close()
 
-	n, err := io.Copy(localFile, remoteFile)
+	n, err := io.Copy(&contextWriter{ctx: ctx, w: localFile}, remoteFile)
 	if err != nil {
 		return 0, fmt.Errorf("copy to local: %w", err)
 	}
@@ -101,92 +137,495 @@ func DownloadFile(sftpClient *sftp.Client, remotePath, localPath string) (int64,
 	return n, nil
 }
 
-// UploadDir recursively uploads a local directory to a remote path, preserving permissions.
-func UploadDir(sftpClient *sftp.Client, localDir, remoteDir string) (int, int64, error) {
-	fileCount := 0
-	var totalBytes int64
+// SymlinkPolicy says what UploadDir and DownloadDir do with symbolic links.
+type SymlinkPolicy string
 
-	err := filepath.Walk(localDir, func(localPath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// Symlink policies.
+const (
+	SymlinkSkip     SymlinkPolicy = "skip"     // leave links out (the default)
+	SymlinkFollow   SymlinkPolicy = "follow"   // transfer what they point to
+	SymlinkPreserve SymlinkPolicy = "preserve" // recreate them with the same target
+)
 
-		// Skip symlinks to prevent reading files outside the allowed directory.
-		// filepath.Walk uses os.Lstat, so info correctly reports symlinks.
-		if info.Mode()&os.ModeSymlink != 0 {
-			log.Printf("upload: skipping symlink %s", localPath)
-			return nil
+// ParseSymlinkPolicy parses a policy name; empty means SymlinkSkip.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch p := SymlinkPolicy(s); p {
+	case "":
+		return SymlinkSkip, nil
+	case SymlinkSkip, SymlinkFollow, SymlinkPreserve:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid symlink policy %q (must be %s, %s or %s)", s, SymlinkSkip, SymlinkFollow, SymlinkPreserve)
+}
+
+// TransferStats summarizes a directory transfer. Preserved symlinks count
+// as files.
+type TransferStats struct {
+	Files   int
+	Bytes   int64
+	Skipped []string   // "path (reason)" for each entry left out
+	Copied  []FilePair // regular files transferred, for verification
+}
+
+func (s *TransferStats) skip(p, reason string) {
+	s.Skipped = append(s.Skipped, p+" ("+reason+")")
+}
+
+// ModePolicy decides the permissions of uploaded files and directories.
+// Only the 0777 permission bits are ever uploaded; setuid, setgid and
+// sticky bits are always dropped.
+type ModePolicy struct {
+	File  fs.FileMode // mode for every uploaded file (0 = keep the local mode)
+	Dir   fs.FileMode // mode for every created directory (0 = keep the local mode)
+	Umask fs.FileMode // bits cleared from every mode, e.g. 022 for no group/world write
+}
+
+// FileMode returns the remote mode for a file whose local mode is local.
+func (p ModePolicy) FileMode(local fs.FileMode) fs.FileMode {
+	return p.apply(p.File, local)
+}
+
+// DirMode returns the remote mode for a directory whose local mode is local.
+func (p ModePolicy) DirMode(local fs.FileMode) fs.FileMode {
+	return p.apply(p.Dir, local)
+}
+
+// String describes the policy, e.g. "files 0640, umask 0022"; it is empty
+// when local modes are kept as they are.
+func (p ModePolicy) String() string {
+	var parts []string
+	if p.File != 0 {
+		parts = append(parts, fmt.Sprintf("files %04o", uint32(p.File.Perm())))
+	}
+	if p.Dir != 0 {
+		parts = append(parts, fmt.Sprintf("dirs %04o", uint32(p.Dir.Perm())))
+	}
+	if p.Umask != 0 {
+		parts = append(parts, fmt.Sprintf("umask %04o", uint32(p.Umask.Perm())))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p ModePolicy) apply(fixed, local fs.FileMode) fs.FileMode {
+	mode := local.Perm()
+	if fixed != 0 {
+		mode = fixed.Perm()
+	}
+	return mode &^ p.Umask
+}
+
+// UploadOptions adjusts UploadDir.
+type UploadOptions struct {
+	// PreserveOwner gives every remote file and directory the numeric
+	// UID/GID of its local counterpart (see ChownLikeLocal).
+	PreserveOwner bool
+	// Symlinks is the symlink policy; empty means SymlinkSkip.
+	Symlinks SymlinkPolicy
+	// AllowFollow, if set, vets the resolved target of a link before
+	// SymlinkFollow reads it; links it rejects are skipped. It keeps
+	// uploads inside --local-base-dir.
+	AllowFollow func(realPath string) error
+	// Parallel is how many files are copied at once (at most
+	// MaxTransferWorkers); 0 or 1 copies them one at a time.
+	Parallel int
+	// Modes sets the permissions of the remote files and directories.
+	Modes ModePolicy
+}
+
+// DownloadOptions adjusts DownloadDir.
+type DownloadOptions struct {
+	// Symlinks is the symlink policy; empty means SymlinkSkip.
+	Symlinks SymlinkPolicy
+	// Parallel works as in UploadOptions.
+	Parallel int
+}
+
+// ChownLikeLocal sets the owner and group of remotePath to the numeric
+// UID/GID of the local file described by info. Only root, or a user with
+// CAP_CHOWN, can do this on the remote host.
+func ChownLikeLocal(sftpClient *sftp.Client, remotePath string, info os.FileInfo) error {
+	uid, gid, ok := localOwner(info)
+	if !ok {
+		return fmt.Errorf("local file ownership is not available on this platform")
+	}
+	if err := sftpClient.Chown(remotePath, uid, gid); err != nil {
+		return fmt.Errorf("chown %s to %d:%d (needs root or CAP_CHOWN on the remote host): %w", remotePath, uid, gid, err)
+	}
+	return nil
+}
+
+// UploadDir recursively uploads a local directory to a remote path,
+// with permissions set by opts.Modes. Symlinks are handled per opts.Symlinks; when
+// following, a link back to a directory being uploaded is skipped as a loop.
+// Special files are always skipped. Directories are walked in order while
+// up to opts.Parallel files copy concurrently; on failure the error is that
+// of the first failing file in walk order. Once ctx is done it stops with
+// ctx's error, leaving what was copied so far.
+func UploadDir(ctx context.Context, sftpClient *sftp.Client, localDir, remoteDir string, opts UploadOptions) (*TransferStats, error) {
+	info, err := os.Stat(localDir)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(localDir)
+	if err != nil {
+		return nil, err
+	}
+	u := &dirUpload{sc: sftpClient, opts: opts, stats: &TransferStats{}, files: newFileWorkers(ctx, opts.Parallel)}
+	err = u.dir(localDir, real, remoteDir, info, nil, 0)
+	return u.stats, u.files.wait(u.stats, err)
+}
+
+type dirUpload struct {
+	sc    *sftp.Client
+	opts  UploadOptions
+	stats *TransferStats
+	files *fileWorkers
+}
+
+// dir uploads localDir, whose symlink-free path is real. ancestors holds
+// the real paths of the directories above it, for loop detection, and hops
+// counts the directory links followed to get here, as a backstop.
+func (u *dirUpload) dir(localDir, real, remoteDir string, info os.FileInfo, ancestors []string, hops int) error {
+	if err := u.sc.MkdirAll(remoteDir); err != nil {
+		return fmt.Errorf("mkdir %s: %w", remoteDir, err)
+	}
+	// Non-fatal: some servers may not support chmod on dirs.
+	_ = u.sc.Chmod(remoteDir, u.opts.Modes.DirMode(info.Mode()))
+	if u.opts.PreserveOwner {
+		if err := ChownLikeLocal(u.sc, remoteDir, info); err != nil {
+			return err
 		}
+	}
 
-		relPath, err := filepath.Rel(localDir, localPath)
-		if err != nil {
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		return err
+	}
+	ancestors = append(ancestors, real)
+	for _, e := range entries {
+		name := e.Name()
+		if err := u.entry(filepath.Join(localDir, name), filepath.Join(real, name), path.Join(remoteDir, name), ancestors, hops); err != nil {
 			return err
 		}
-		remotePath := path.Join(remoteDir, filepath.ToSlash(relPath))
+	}
+	return nil
+}
 
-		if info.IsDir() {
-			if err := sftpClient.MkdirAll(remotePath); err != nil {
-				return fmt.Errorf("mkdir %s: %w", remotePath, err)
+func (u *dirUpload) entry(localPath, real, remotePath string, ancestors []string, hops int) error {
+	if err := u.files.ctx.Err(); err != nil {
+		return err
+	}
+	info, err := os.Lstat(localPath)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch u.opts.Symlinks {
+		case SymlinkPreserve:
+			target, err := os.Readlink(localPath)
+			if err != nil {
+				return fmt.Errorf("readlink %s: %w", localPath, err)
+			}
+			_ = u.sc.Remove(remotePath)
+			if err := u.sc.Symlink(target, remotePath); err != nil {
+				return fmt.Errorf("symlink %s: %w", remotePath, err)
+			}
+			u.stats.Files++
+			return nil
+		case SymlinkFollow:
+			if real, err = filepath.EvalSymlinks(localPath); err != nil {
+				u.stats.skip(localPath, "broken symlink")
+				return nil
 			}
-			if err := sftpClient.Chmod(remotePath, info.Mode().Perm()); err != nil {
-				// Non-fatal: some servers may not support chmod on dirs.
-				_ = err
+			if u.opts.AllowFollow != nil {
+				if err := u.opts.AllowFollow(real); err != nil {
+					u.stats.skip(localPath, "symlink target not allowed")
+					return nil
+				}
 			}
+			if info, err = os.Stat(real); err != nil {
+				u.stats.skip(localPath, "broken symlink")
+				return nil
+			}
+			if info.IsDir() {
+				if reason := followLoop(ancestors, real, hops); reason != "" {
+					u.stats.skip(localPath, reason)
+					return nil
+				}
+				hops++
+			}
+		default:
+			u.stats.skip(localPath, "symlink")
 			return nil
 		}
+	}
 
-		perms := info.Mode().Perm()
-		n, err := UploadFile(sftpClient, localPath, remotePath, &perms)
+	switch {
+	case info.IsDir():
+		return u.dir(localPath, real, remotePath, info, ancestors, hops)
+	case !info.Mode().IsRegular():
+		u.stats.skip(localPath, "special file")
+		return nil
+	}
+	return u.files.copy(FilePair{Local: real, Remote: remotePath}, func(ctx context.Context) (int64, error) {
+		perms := u.opts.Modes.FileMode(info.Mode())
+		n, err := UploadFile(ctx, u.sc, real, remotePath, &perms)
 		if err != nil {
-			return fmt.Errorf("upload %s: %w", localPath, err)
+			return 0, fmt.Errorf("upload %s: %w", localPath, err)
 		}
-		fileCount++
-		totalBytes += n
-		return nil
+		if u.opts.PreserveOwner {
+			if err := ChownLikeLocal(u.sc, remotePath, info); err != nil {
+				return 0, err
+			}
+		}
+		return n, nil
 	})
+}
 
-	return fileCount, totalBytes, err
+// followLoop returns why a followed directory link at real must not be
+// entered: it leads back to a directory being transferred, or too many
+// links were followed already (a loop that path comparison missed).
+func followLoop(ancestors []string, real string, hops int) string {
+	switch {
+	case slices.Contains(ancestors, real):
+		return "symlink loop"
+	case hops >= maxSymlinkHops:
+		return "too many levels of symbolic links"
+	}
+	return ""
 }
 
-// DownloadDir recursively downloads a remote directory to a local path, preserving permissions.
-func DownloadDir(sftpClient *sftp.Client, remoteDir, localDir string) (int, int64, error) {
-	fileCount := 0
-	var totalBytes int64
+// DownloadDir recursively downloads a remote directory to a local path,
+// preserving permissions. Symlinks are handled per opts.Symlinks; when
+// following, a link back to a directory being downloaded is skipped as a
+// loop. Special files are always skipped. Files copy concurrently as in
+// UploadDir. Once ctx is done it stops with ctx's error, leaving what was
+// copied so far.
+func DownloadDir(ctx context.Context, sftpClient *sftp.Client, remoteDir, localDir string, opts DownloadOptions) (*TransferStats, error) {
+	info, err := sftpClient.Stat(remoteDir)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", remoteDir, err)
+	}
+	real, err := sftpClient.RealPath(remoteDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", remoteDir, err)
+	}
+	d := &dirDownload{sc: sftpClient, policy: opts.Symlinks, stats: &TransferStats{}, files: newFileWorkers(ctx, opts.Parallel)}
+	err = d.files.wait(d.stats, d.dir(remoteDir, real, localDir, info, nil, 0))
+	// Set directory modes last, deepest first, so a read-only directory
+	// doesn't block its contents.
+	for _, m := range d.modes {
+		if cerr := os.Chmod(m.dir, m.perm); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return d.stats, err
+}
 
-	err := walkRemoteDir(sftpClient, remoteDir, func(remotePath string, info os.FileInfo) error {
-		relPath, err := filepath.Rel(remoteDir, remotePath)
-		if err != nil {
+type dirDownload struct {
+	sc     *sftp.Client
+	policy SymlinkPolicy
+	stats  *TransferStats
+	files  *fileWorkers
+	modes  []dirMode
+}
+
+// dirMode is a local directory's permissions, applied once its files are in.
+type dirMode struct {
+	dir  string
+	perm os.FileMode
+}
+
+// dir downloads remoteDir like dirUpload.dir. Links are resolved here rather
+// than with RealPath, which not every server applies to symlinks.
+func (d *dirDownload) dir(remoteDir, real, localDir string, info os.FileInfo, ancestors []string, hops int) error {
+	if err := os.MkdirAll(localDir, info.Mode().Perm()|0700); err != nil {
+		return fmt.Errorf("mkdir %s: %w", localDir, err)
+	}
+	entries, err := d.sc.ReadDir(remoteDir)
+	if err != nil {
+		return fmt.Errorf("read dir %s: %w", remoteDir, err)
+	}
+	ancestors = append(ancestors, real)
+	for _, e := range entries {
+		name := e.Name()
+		if err := d.entry(path.Join(remoteDir, name), path.Join(real, name), filepath.Join(localDir, name), e, ancestors, hops); err != nil {
 			return err
 		}
-		localPath := filepath.Join(localDir, relPath)
+	}
+	d.modes = append(d.modes, dirMode{localDir, info.Mode().Perm()})
+	return nil
+}
 
-		if info.IsDir() {
-			if err := os.MkdirAll(localPath, info.Mode().Perm()); err != nil {
-				return fmt.Errorf("mkdir %s: %w", localPath, err)
+func (d *dirDownload) entry(remotePath, real, localPath string, info os.FileInfo, ancestors []string, hops int) error {
+	if err := d.files.ctx.Err(); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch d.policy {
+		case SymlinkPreserve:
+			target, err := d.sc.ReadLink(remotePath)
+			if err != nil {
+				return fmt.Errorf("readlink %s: %w", remotePath, err)
+			}
+			_ = os.Remove(localPath)
+			if err := os.Symlink(target, localPath); err != nil {
+				return fmt.Errorf("symlink %s: %w", localPath, err)
 			}
+			d.stats.Files++
 			return nil
+		case SymlinkFollow:
+			var err error
+			if real, err = resolveSymlinks(d.sc, real); err != nil {
+				d.stats.skip(remotePath, "symlink loop")
+				return nil
+			}
+			if info, err = d.sc.Stat(real); err != nil {
+				d.stats.skip(remotePath, "broken symlink")
+				return nil
+			}
+			if info.IsDir() {
+				if reason := followLoop(ancestors, real, hops); reason != "" {
+					d.stats.skip(remotePath, reason)
+					return nil
+				}
+				hops++
+			}
+		default:
+			d.stats.skip(remotePath, "symlink")
+			return nil
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		return d.dir(remotePath, real, localPath, info, ancestors, hops)
+	case !info.Mode().IsRegular():
+		d.stats.skip(remotePath, "special file")
+		return nil
+	}
+	return d.files.copy(FilePair{Local: localPath, Remote: real}, func(ctx context.Context) (int64, error) {
+		n, err := DownloadFile(ctx, d.sc, real, localPath)
+		if err != nil {
+			return 0, fmt.Errorf("download %s: %w", remotePath, err)
 		}
+		return n, nil
+	})
+}
 
-		// Ensure parent directory exists.
-		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-			return fmt.Errorf("mkdir parent %s: %w", filepath.Dir(localPath), err)
+// CopyRemote copies a remote file or directory tree to another path on the
+// same host, like cp -a: modes and modification times are preserved and
+// symlinks are recreated rather than followed. Owner and group are kept
+// when the SSH user may change them (root or CAP_CHOWN) and silently left
+// to the SSH user otherwise, as cp -a does. Data streams through the
+// SFTP connection. It returns the number of files and bytes copied.
+func CopyRemote(sftpClient *sftp.Client, srcPath, dstPath string) (int, int64, error) {
+	srcPath, dstPath = path.Clean(srcPath), path.Clean(dstPath)
+	info, err := sftpClient.Lstat(srcPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("stat %s: %w", srcPath, err)
+	}
+	if !info.IsDir() {
+		n, err := copyRemoteEntry(sftpClient, srcPath, dstPath, info)
+		if err != nil {
+			return 0, 0, err
 		}
+		return 1, n, nil
+	}
 
-		n, err := DownloadFile(sftpClient, remotePath, localPath)
+	fileCount := 0
+	var totalBytes int64
+	var dirs []string
+	var dirInfos []os.FileInfo
+	err = walkRemoteDir(sftpClient, srcPath, func(remotePath string, info os.FileInfo) error {
+		target := path.Join(dstPath, strings.TrimPrefix(remotePath, srcPath))
+		if info.IsDir() {
+			if err := sftpClient.MkdirAll(target); err != nil {
+				return fmt.Errorf("mkdir %s: %w", target, err)
+			}
+			dirs = append(dirs, target)
+			dirInfos = append(dirInfos, info)
+			return nil
+		}
+		n, err := copyRemoteEntry(sftpClient, remotePath, target, info)
 		if err != nil {
-			return fmt.Errorf("download %s: %w", remotePath, err)
+			return err
 		}
 		fileCount++
 		totalBytes += n
 		return nil
 	})
+	if err != nil {
+		return fileCount, totalBytes, err
+	}
+	// Set directory modes and times last, deepest first, so read-only
+	// directories don't block their own contents and writes don't bump mtimes.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		chownLikeRemote(sftpClient, dirs[i], dirInfos[i])
+		_ = sftpClient.Chmod(dirs[i], dirInfos[i].Mode().Perm())
+		_ = sftpClient.Chtimes(dirs[i], dirInfos[i].ModTime(), dirInfos[i].ModTime())
+	}
+	return fileCount, totalBytes, nil
+}
 
-	return fileCount, totalBytes, err
+// copyRemoteEntry copies one non-directory entry for CopyRemote.
+func copyRemoteEntry(sftpClient *sftp.Client, srcPath, dstPath string, info os.FileInfo) (int64, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := sftpClient.ReadLink(srcPath)
+		if err != nil {
+			return 0, fmt.Errorf("readlink %s: %w", srcPath, err)
+		}
+		_ = sftpClient.Remove(dstPath)
+		if err := sftpClient.Symlink(target, dstPath); err != nil {
+			return 0, fmt.Errorf("symlink %s: %w", dstPath, err)
+		}
+		return 0, nil
+	}
+	if !info.Mode().IsRegular() {
+		log.Printf("copy: skipping special file %s", srcPath)
+		return 0, nil
+	}
+
+	src, err := sftpClient.Open(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	dst, err := sftpClient.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", dstPath, err)
+	}
+	n, err := io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("copy %s: %w", srcPath, err)
+	}
+
+	// Chown before chmod: changing the owner may clear the mode's set-id bits.
+	chownLikeRemote(sftpClient, dstPath, info)
+	if err := sftpClient.Chmod(dstPath, info.Mode().Perm()); err != nil {
+		return n, fmt.Errorf("chmod %s: %w", dstPath, err)
+	}
+	_ = sftpClient.Chtimes(dstPath, info.ModTime(), info.ModTime())
+	return n, nil
+}
+
+// chownLikeRemote gives dstPath the UID/GID of the remote file described by
+// info, if the server may. Failures are ignored: only root can give files
+// away, and everyone else keeps their own ownership.
+func chownLikeRemote(sftpClient *sftp.Client, dstPath string, info os.FileInfo) {
+	if st, ok := info.Sys().(*sftp.FileStat); ok {
+		_ = sftpClient.Chown(dstPath, int(st.UID), int(st.GID))
+	}
 }
 
 // ReadFile reads a remote file and returns its contents.
 // If maxSize > 0, the file size is checked first and reading is capped with io.LimitReader.
-func ReadFile(sftpClient *sftp.Client, remotePath string, maxSize ...int64) ([]byte, error) {
+// Reading stops with ctx's error once ctx is done.
+func ReadFile(ctx context.Context, sftpClient *sftp.Client, remotePath string, maxSize ...int64) ([]byte, error) {
 	var limit int64
 	if len(maxSize) > 0 {
 		limit = maxSize[0]
@@ -207,14 +646,14 @@ func ReadFile(sftpClient *sftp.Client, remotePath string, maxSize ...int64) ([]b
 			return nil, fmt.Errorf("file %s is %d bytes, exceeds maximum allowed size of %d bytes",
 				remotePath, stat.Size(), limit)
 		}
-		data, err := io.ReadAll(io.LimitReader(file, limit+1))
+		data, err := io.ReadAll(io.LimitReader(NewContextReader(ctx, file), limit+1))
 		if err != nil {
 			return nil, fmt.Errorf("read remote file: %w", err)
 		}
 		return data, nil
 	}
 
-	data, err := io.ReadAll(file)
+	data, err := io.ReadAll(NewContextReader(ctx, file))
 	if err != nil {
 		return nil, fmt.Errorf("read remote file: %w", err)
 	}
@@ -248,6 +687,182 @@ func WriteFile(sftpClient *sftp.Client, remotePath string, data []byte, perms fs
 	return int64(n), nil
 }
 
+// AppendFile appends data to the end of a remote file, creating it (and its
+// parent directories) with perms if it doesn't exist. An existing file's
+// mode is left alone.
+func AppendFile(sftpClient *sftp.Client, remotePath string, data []byte, perms fs.FileMode) (int64, error) {
+	_, statErr := sftpClient.Stat(remotePath)
+	isNew := statErr != nil
+	if isNew {
+		if dir := path.Dir(remotePath); dir != "." && dir != "/" {
+			if err := sftpClient.MkdirAll(dir); err != nil {
+				return 0, fmt.Errorf("create parent directories: %w", err)
+			}
+		}
+	}
+	file, err := sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	// Not every server honors SSH_FXF_APPEND, so write at the current end
+	// explicitly as well.
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return 0, fmt.Errorf("seek to end: %w", err)
+	}
+	n, err := file.Write(data)
+	if err != nil {
+		return int64(n), fmt.Errorf("append remote file: %w", err)
+	}
+	if isNew {
+		if err := sftpClient.Chmod(remotePath, perms); err != nil {
+			return int64(n), fmt.Errorf("chmod remote file: %w", err)
+		}
+	}
+	return int64(n), nil
+}
+
+// WriteFileAt overwrites len(data) bytes of an existing remote file at
+// offset, in place and without truncating. Writing past the current end
+// extends the file.
+func WriteFileAt(sftpClient *sftp.Client, remotePath string, data []byte, offset int64) (int64, error) {
+	file, err := sftpClient.OpenFile(remotePath, os.O_WRONLY)
+	if err != nil {
+		return 0, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+
+	n, err := file.WriteAt(data, offset)
+	if err != nil {
+		return int64(n), fmt.Errorf("write remote file at offset %d: %w", offset, err)
+	}
+	return int64(n), nil
+}
+
+// maxSymlinkHops bounds symlink resolution, like the kernel's ELOOP limit.
+const maxSymlinkHops = 40
+
+// resolveSymlinks follows remotePath through any chain of symlinks and returns
+// the path of the final (possibly not yet existing) file.
+func resolveSymlinks(sftpClient *sftp.Client, remotePath string) (string, error) {
+	p := remotePath
+	for range maxSymlinkHops {
+		fi, err := sftpClient.Lstat(p)
+		if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+			return p, nil
+		}
+		link, err := sftpClient.ReadLink(p)
+		if err != nil {
+			return "", fmt.Errorf("read symlink %s: %w", p, err)
+		}
+		if !path.IsAbs(link) {
+			link = path.Join(path.Dir(p), link)
+		}
+		p = link
+	}
+	return "", fmt.Errorf("too many levels of symbolic links: %s", remotePath)
+}
+
+// WriteFileAtomic replaces remotePath with data without ever exposing a
+// partially written file: data goes to a temp file in the same directory,
+// which is fsynced (if the server supports it) and renamed over the target.
+// An existing file's owner and group are preserved when the server allows
+// it; a symlink target is written through, keeping the link.
+func WriteFileAtomic(sftpClient *sftp.Client, remotePath string, data []byte, perms fs.FileMode) (int64, error) {
+	return WriteFileAtomicFrom(sftpClient, remotePath, bytes.NewReader(data), perms)
+}
+
+// WriteFileAtomicFrom is WriteFileAtomic with the content streamed from r,
+// so large files never need to be held in memory. If reading r fails, the
+// target is left untouched.
+func WriteFileAtomicFrom(sftpClient *sftp.Client, remotePath string, r io.Reader, perms fs.FileMode) (int64, error) {
+	target, err := resolveSymlinks(sftpClient, remotePath)
+	if err != nil {
+		return 0, err
+	}
+	dir := path.Dir(target)
+	if dir != "." && dir != "/" {
+		if err := sftpClient.MkdirAll(dir); err != nil {
+			return 0, fmt.Errorf("create parent directories: %w", err)
+		}
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return 0, fmt.Errorf("generate temp name: %w", err)
+	}
+	tmpPath := path.Join(dir, "."+path.Base(target)+".ssh-mcp-"+hex.EncodeToString(suffix)+".tmp")
+	file, err := sftpClient.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return 0, fmt.Errorf("create temp file: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			file.Close()
+			_ = sftpClient.Remove(tmpPath)
+		}
+	}()
+
+	n, err := io.Copy(file, r)
+	if err != nil {
+		return 0, fmt.Errorf("write temp file: %w", err)
+	}
+	if _, ok := sftpClient.HasExtension("fsync@openssh.com"); ok {
+		if err := file.Sync(); err != nil {
+			return 0, fmt.Errorf("fsync temp file: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("close temp file: %w", err)
+	}
+
+	if fi, err := sftpClient.Stat(target); err == nil {
+		chownLikeRemote(sftpClient, tmpPath, fi)
+	}
+	if err := sftpClient.Chmod(tmpPath, perms); err != nil {
+		return 0, fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if _, err := Rename(sftpClient, tmpPath, target, true); err != nil {
+		return 0, fmt.Errorf("rename temp file over %s: %w", target, err)
+	}
+	committed = true
+	return n, nil
+}
+
+// Rename moves oldPath to newPath. Without overwrite, an existing newPath is
+// an error wrapping fs.ErrExist. With overwrite it is replaced atomically
+// via posix-rename@openssh.com when the server offers it; otherwise plain
+// SFTP rename is tried, and if that fails (it refuses existing targets on
+// most servers) newPath is removed first. That fallback leaves a short
+// window without the file, but never a partial one. Rename reports whether
+// posix-rename was used.
+func Rename(sftpClient *sftp.Client, oldPath, newPath string, overwrite bool) (bool, error) {
+	if !overwrite {
+		if _, err := sftpClient.Lstat(newPath); err == nil {
+			return false, fmt.Errorf("%s: %w", newPath, fs.ErrExist)
+		}
+		return false, sftpClient.Rename(oldPath, newPath)
+	}
+	if _, ok := sftpClient.HasExtension("posix-rename@openssh.com"); ok {
+		return true, sftpClient.PosixRename(oldPath, newPath)
+	}
+	err := sftpClient.Rename(oldPath, newPath)
+	if err == nil {
+		return false, nil
+	}
+	// Don't remove the target when the rename failed for lack of a source.
+	if _, statErr := sftpClient.Lstat(oldPath); statErr != nil {
+		return false, err
+	}
+	if rmErr := sftpClient.Remove(newPath); rmErr != nil {
+		return false, err
+	}
+	return false, sftpClient.Rename(oldPath, newPath)
+}
+
 func walkRemoteDir(sftpClient *sftp.Client, dirPath string, fn func(string, os.FileInfo) error) error {
 	// Use Walker for efficient directory traversal.
 	walker := sftpClient.Walk(dirPath)
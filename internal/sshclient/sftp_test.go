@@ -1,9 +1,19 @@
 package sshclient
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/sshclient/sftptest"
 )
 
 // TestUploadDirSkipsSymlinks verifies that UploadDir skips symlinks
@@ -57,3 +67,718 @@ func TestUploadDirSkipsSymlinks(t *testing.T) {
 		t.Skip("filepath.Walk did not report symlink via info.Mode() on this platform")
 	}
 }
+
+// symlinkTree creates root/data/file.txt plus links next to it: a file
+// link, a link to the parent directory (a loop), a dangling link, and a
+// link to a directory outside root.
+func symlinkTree(t *testing.T) (root, outside string) {
+	t.Helper()
+	dir := t.TempDir()
+	root = filepath.Join(dir, "root")
+	outside = filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "data"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "file.txt"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{
+		"data/file-link": "file.txt",
+		"data/loop":      "..",
+		"data/dangling":  "missing",
+		"ext":            outside,
+	} {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, outside
+}
+
+func TestUploadDir_Symlinks(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	root, outside := symlinkTree(t)
+
+	t.Run("skip", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst")
+		stats, err := UploadDir(t.Context(), sc, root, dst, UploadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Files != 1 || len(stats.Skipped) != 4 {
+			t.Errorf("files=%d skipped=%v", stats.Files, stats.Skipped)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, "ext")); !os.IsNotExist(err) {
+			t.Errorf("ext link uploaded: %v", err)
+		}
+	})
+
+	t.Run("preserve", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst")
+		stats, err := UploadDir(t.Context(), sc, root, dst, UploadOptions{Symlinks: SymlinkPreserve})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Files != 5 || len(stats.Skipped) != 0 {
+			t.Errorf("files=%d skipped=%v", stats.Files, stats.Skipped)
+		}
+		if target, err := os.Readlink(filepath.Join(dst, "data", "loop")); err != nil || target != ".." {
+			t.Errorf("loop link = %q, %v", target, err)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst")
+		stats, err := UploadDir(t.Context(), sc, root, dst, UploadOptions{Symlinks: SymlinkFollow})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// file.txt, file-link (as a copy) and ext/secret.txt.
+		if stats.Files != 3 {
+			t.Errorf("files=%d", stats.Files)
+		}
+		want := []string{
+			filepath.Join(root, "data", "dangling") + " (broken symlink)",
+			filepath.Join(root, "data", "loop") + " (symlink loop)",
+		}
+		if !slices.Equal(stats.Skipped, want) {
+			t.Errorf("skipped = %q, want %q", stats.Skipped, want)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dst, "data", "file-link")); string(data) != "data" {
+			t.Errorf("followed file = %q", data)
+		}
+		if fi, err := os.Lstat(filepath.Join(dst, "ext")); err != nil || !fi.IsDir() {
+			t.Errorf("ext not uploaded as a directory: %v", err)
+		}
+	})
+
+	t.Run("follow with AllowFollow", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "dst")
+		opts := UploadOptions{Symlinks: SymlinkFollow, AllowFollow: func(p string) error {
+			if strings.HasPrefix(p, outside) {
+				return errors.New("outside")
+			}
+			return nil
+		}}
+		stats, err := UploadDir(t.Context(), sc, root, dst, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(stats.Skipped, filepath.Join(root, "ext")+" (symlink target not allowed)") {
+			t.Errorf("skipped = %q", stats.Skipped)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, "ext")); !os.IsNotExist(err) {
+			t.Errorf("ext uploaded despite AllowFollow: %v", err)
+		}
+	})
+}
+
+func TestDownloadDir_Symlinks(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	root, _ := symlinkTree(t)
+
+	tests := []struct {
+		policy      SymlinkPolicy
+		files       int
+		skipped     int
+		linkIsDir   bool // whether dst/ext is a real directory
+		linkPresent bool
+	}{
+		{SymlinkSkip, 1, 4, false, false},
+		{SymlinkPreserve, 5, 0, false, true},
+		{SymlinkFollow, 3, 2, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "dst")
+			stats, err := DownloadDir(t.Context(), sc, root, dst, DownloadOptions{Symlinks: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Files != tt.files || len(stats.Skipped) != tt.skipped {
+				t.Errorf("files=%d skipped=%q", stats.Files, stats.Skipped)
+			}
+			fi, err := os.Lstat(filepath.Join(dst, "ext"))
+			if (err == nil) != tt.linkPresent {
+				t.Fatalf("ext present = %v, want %v", err == nil, tt.linkPresent)
+			}
+			if err == nil && fi.IsDir() != tt.linkIsDir {
+				t.Errorf("ext is dir = %v, want %v", fi.IsDir(), tt.linkIsDir)
+			}
+		})
+	}
+}
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	if p, err := ParseSymlinkPolicy(""); err != nil || p != SymlinkSkip {
+		t.Errorf(`ParseSymlinkPolicy("") = %q, %v`, p, err)
+	}
+	if _, err := ParseSymlinkPolicy("hardlink"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestUploadDir_PreserveOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root to give files away")
+	}
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(src, "sub", "app.conf")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{src, filepath.Join(src, "sub"), file} {
+		if err := os.Chown(p, 1234, 5678); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if _, err := UploadDir(t.Context(), sc, src, dst, UploadOptions{PreserveOwner: true}); err != nil {
+		t.Fatalf("UploadDir: %v", err)
+	}
+	for _, p := range []string{dst, filepath.Join(dst, "sub"), filepath.Join(dst, "sub", "app.conf")} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid, gid, _ := localOwner(fi); uid != 1234 || gid != 5678 {
+			t.Errorf("%s owned by %d:%d, want 1234:5678", p, uid, gid)
+		}
+	}
+
+	// Without the option, files belong to the SSH user.
+	plain := filepath.Join(dir, "plain")
+	if _, err := UploadDir(t.Context(), sc, src, plain, UploadOptions{}); err != nil {
+		t.Fatalf("UploadDir: %v", err)
+	}
+	fi, _ := os.Stat(filepath.Join(plain, "sub", "app.conf"))
+	if uid, _, _ := localOwner(fi); uid != 0 {
+		t.Errorf("plain upload owned by uid %d, want 0", uid)
+	}
+}
+
+func TestModePolicy(t *testing.T) {
+	for _, tt := range []struct {
+		policy    ModePolicy
+		file, dir fs.FileMode
+	}{
+		{ModePolicy{}, 0o775, 0o777},
+		{ModePolicy{Umask: 0o022}, 0o755, 0o755},
+		{ModePolicy{File: 0o640, Dir: 0o750}, 0o640, 0o750},
+		{ModePolicy{File: 0o666, Umask: 0o027}, 0o640, 0o750},
+	} {
+		if got := tt.policy.FileMode(0o775 | fs.ModeSetuid); got != tt.file {
+			t.Errorf("%+v: file mode %o, want %o", tt.policy, got, tt.file)
+		}
+		if got := tt.policy.DirMode(fs.ModeDir | 0o777); got != tt.dir {
+			t.Errorf("%+v: dir mode %o, want %o", tt.policy, got, tt.dir)
+		}
+	}
+}
+
+func TestUploadDir_Modes(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	for name, mode := range map[string]fs.FileMode{"run.sh": 0o777, "sub/app.conf": 0o666} {
+		p := filepath.Join(src, name)
+		if err := os.WriteFile(p, []byte("x"), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(src, "sub"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	if _, err := UploadDir(t.Context(), sc, src, dst, UploadOptions{Modes: ModePolicy{Umask: 0o022}}); err != nil {
+		t.Fatalf("UploadDir: %v", err)
+	}
+	for name, want := range map[string]fs.FileMode{"run.sh": 0o755, "sub": 0o755, "sub/app.conf": 0o644} {
+		fi, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != want {
+			t.Errorf("%s mode %o, want %o", name, fi.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(target, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := WriteFileAtomic(sc, target, []byte("new content"), 0o640)
+	if err != nil {
+		t.Fatalf("WriteFileAtomic: %v", err)
+	}
+	if n != int64(len("new content")) {
+		t.Errorf("wrote %d bytes", n)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "new content" {
+		t.Errorf("target content = %q, %v", data, err)
+	}
+	if fi, err := os.Stat(target); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("target mode = %v, %v; want 0640", fi.Mode().Perm(), err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temp file left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "new.conf")
+	dst := filepath.Join(dir, "app.conf")
+	for p, content := range map[string]string{src: "new", dst: "old"} {
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Rename(sc, src, dst, false); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("Rename without overwrite: err = %v, want fs.ErrExist", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "old" {
+		t.Fatalf("target changed without overwrite: %q", data)
+	}
+
+	atomic, err := Rename(sc, src, dst, true)
+	if err != nil {
+		t.Fatalf("Rename with overwrite: %v", err)
+	}
+	if !atomic {
+		t.Error("expected posix-rename to be used")
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "new" {
+		t.Errorf("target content = %q, want new", data)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists: %v", err)
+	}
+
+	moved := filepath.Join(dir, "moved.conf")
+	if _, err := Rename(sc, dst, moved, false); err != nil {
+		t.Fatalf("Rename to a new name: %v", err)
+	}
+	if _, err := Rename(sc, filepath.Join(dir, "missing"), moved, true); err == nil {
+		t.Error("expected an error for a missing source")
+	}
+	if data, _ := os.ReadFile(moved); string(data) != "new" {
+		t.Errorf("target lost after failed rename: %q", data)
+	}
+}
+
+func TestWriteFileAtomic_NewFileAndSymlink(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+
+	created := filepath.Join(dir, "sub", "new.txt")
+	if _, err := WriteFileAtomic(sc, created, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("WriteFileAtomic new file: %v", err)
+	}
+	if data, err := os.ReadFile(created); err != nil || string(data) != "hello" {
+		t.Errorf("new file content = %q, %v", data, err)
+	}
+
+	real := filepath.Join(dir, "real.conf")
+	link := filepath.Join(dir, "link.conf")
+	if err := os.WriteFile(real, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteFileAtomic(sc, link, []byte("via link"), 0o644); err != nil {
+		t.Fatalf("WriteFileAtomic through symlink: %v", err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink was replaced: %v, %v", fi.Mode(), err)
+	}
+	if data, _ := os.ReadFile(real); string(data) != "via link" {
+		t.Errorf("symlink target content = %q", data)
+	}
+}
+
+func TestCopyRemote(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "app.conf"), []byte("port=8080\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "app.conf"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app.conf", filepath.Join(src, "current.conf")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst")
+	files, n, err := CopyRemote(sc, src, dst)
+	if err != nil {
+		t.Fatalf("CopyRemote: %v", err)
+	}
+	if files != 3 || n != int64(len("port=8080\n")+len("#!/bin/sh\n")) {
+		t.Errorf("CopyRemote = %d files, %d bytes", files, n)
+	}
+
+	fi, err := os.Stat(filepath.Join(dst, "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o640 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("app.conf mode %v mtime %v, want 0640 %v", fi.Mode().Perm(), fi.ModTime(), mtime)
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "bin", "run.sh")); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Errorf("run.sh not copied with mode 0755: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "current.conf")); err != nil || target != "app.conf" {
+		t.Errorf("symlink = %q, %v; want app.conf", target, err)
+	}
+	if os.Geteuid() == 0 {
+		if err := os.Chown(filepath.Join(src, "app.conf"), 1234, 5678); err != nil {
+			t.Fatal(err)
+		}
+		owned := filepath.Join(dir, "owned.conf")
+		if _, _, err := CopyRemote(sc, filepath.Join(src, "app.conf"), owned); err != nil {
+			t.Fatalf("CopyRemote: %v", err)
+		}
+		fi, _ := os.Stat(owned)
+		if uid, gid, _ := localOwner(fi); uid != 1234 || gid != 5678 {
+			t.Errorf("copy owned by %d:%d, want 1234:5678", uid, gid)
+		}
+	}
+
+	// A single file copies to the given path.
+	single := filepath.Join(dir, "single.conf")
+	if files, _, err := CopyRemote(sc, filepath.Join(src, "app.conf"), single); err != nil || files != 1 {
+		t.Fatalf("CopyRemote file = %d, %v", files, err)
+	}
+	if data, _ := os.ReadFile(single); string(data) != "port=8080\n" {
+		t.Errorf("single file content = %q", data)
+	}
+}
+
+type failingReader struct{ err error }
+
+func (f failingReader) Read([]byte) (int, error) { return 0, f.err }
+
+func TestWriteFileAtomicFrom_ReadErrorKeepsTarget(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(target, []byte("original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	readErr := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("partial"), failingReader{readErr})
+	if _, err := WriteFileAtomicFrom(sc, target, r, 0o644); !errors.Is(err, readErr) {
+		t.Fatalf("expected read error, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "original" {
+		t.Errorf("target content = %q, want original", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+
+	p := filepath.Join(dir, "logs", "build.log")
+	if _, err := AppendFile(sc, p, []byte("step 1\n"), 0o600); err != nil {
+		t.Fatalf("AppendFile new file: %v", err)
+	}
+	if n, err := AppendFile(sc, p, []byte("step 2\n"), 0o644); err != nil || n != 7 {
+		t.Fatalf("AppendFile = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(p); string(data) != "step 1\nstep 2\n" {
+		t.Errorf("content = %q", data)
+	}
+	// The mode is set only when the file is created.
+	if fi, err := os.Stat(p); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, %v; want 0600", fi.Mode().Perm(), err)
+	}
+}
+
+func TestWriteFileAt(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	p := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(p, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := WriteFileAt(sc, p, []byte("WORLD"), 6); err != nil || n != 5 {
+		t.Fatalf("WriteFileAt = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(p); string(data) != "hello WORLD" {
+		t.Errorf("content = %q", data)
+	}
+	if _, err := WriteFileAt(sc, p, []byte("!!"), 10); err != nil {
+		t.Fatalf("WriteFileAt past end: %v", err)
+	}
+	if data, _ := os.ReadFile(p); string(data) != "hello WORL!!" {
+		t.Errorf("content = %q", data)
+	}
+	if _, err := WriteFileAt(sc, filepath.Join(t.TempDir(), "missing"), []byte("x"), 0); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestSHA256_CopiedFiles(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	src := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "alpha", "sub/b.txt": ""} {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(t.TempDir(), "dst")
+	stats, err := UploadDir(t.Context(), sc, src, dst, UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Copied) != 2 {
+		t.Fatalf("copied = %v", stats.Copied)
+	}
+	for _, f := range stats.Copied {
+		local, err := LocalSHA256(f.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		remote, err := RemoteSHA256(sc, f.Remote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if local != remote {
+			t.Errorf("%s: local %s, remote %s", f.Remote, local, remote)
+		}
+	}
+	if sum, _ := LocalSHA256(filepath.Join(src, "sub", "b.txt")); sum != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("sha256 of empty file = %s", sum)
+	}
+	if _, err := RemoteSHA256(sc, filepath.Join(dst, "missing")); err == nil {
+		t.Error("expected error for missing remote file")
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f"), []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for in, want := range map[string]string{
+		dir:                              dir,
+		filepath.Join(dir, "new", "a/b"): dir,
+		filepath.Join(dir, "f"):          dir,
+	} {
+		if got, err := NearestExistingDir(sc, in); err != nil || got != want {
+			t.Errorf("NearestExistingDir(%s) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	free, err := FreeSpace(sc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free == 0 {
+		t.Error("FreeSpace returned 0")
+	}
+
+	if err := os.Symlink("f", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := LocalSize(dir); err != nil || n != 5 {
+		t.Errorf("LocalSize = %d, %v; want 5 (symlink not counted)", n, err)
+	}
+}
+
+func TestTransfers_Cancelled(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("x", 1<<20)
+	for _, name := range []string{"a.bin", "sub/b.bin"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(big), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := UploadFile(ctx, sc, filepath.Join(src, "a.bin"), filepath.Join(dir, "up.bin"), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("UploadFile err = %v, want context.Canceled", err)
+	}
+	if _, err := DownloadFile(ctx, sc, filepath.Join(src, "a.bin"), filepath.Join(dir, "down.bin")); !errors.Is(err, context.Canceled) {
+		t.Errorf("DownloadFile err = %v, want context.Canceled", err)
+	}
+	if _, err := ReadFile(ctx, sc, filepath.Join(src, "a.bin")); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadFile err = %v, want context.Canceled", err)
+	}
+	stats, err := UploadDir(ctx, sc, src, filepath.Join(dir, "updir"), UploadOptions{})
+	if !errors.Is(err, context.Canceled) || stats.Files != 0 {
+		t.Errorf("UploadDir = %d files, %v; want context.Canceled", stats.Files, err)
+	}
+	stats, err = DownloadDir(ctx, sc, src, filepath.Join(dir, "downdir"), DownloadOptions{})
+	if !errors.Is(err, context.Canceled) || stats.Files != 0 {
+		t.Errorf("DownloadDir = %d files, %v; want context.Canceled", stats.Files, err)
+	}
+
+	// A live context copies everything.
+	if data, err := ReadFile(t.Context(), sc, filepath.Join(src, "sub", "b.bin")); err != nil || len(data) != len(big) {
+		t.Errorf("ReadFile = %d bytes, %v", len(data), err)
+	}
+}
+
+func TestDirTransfers_Parallel(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	for i := range 40 {
+		p := filepath.Join(src, fmt.Sprintf("d%d", i%4), fmt.Sprintf("f%02d.txt", i))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(strings.Repeat("x", i*100)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Its files must land before the read-only mode is applied.
+	if err := os.Chmod(filepath.Join(src, "d1"), 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, d := range []string{src, filepath.Join(dir, "seq"), filepath.Join(dir, "par"), filepath.Join(dir, "down")} {
+			_ = os.Chmod(filepath.Join(d, "d1"), 0755)
+		}
+	})
+
+	seq, err := UploadDir(t.Context(), sc, src, filepath.Join(dir, "seq"), UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	par, err := UploadDir(t.Context(), sc, src, filepath.Join(dir, "par"), UploadOptions{Parallel: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if par.Files != 40 || par.Bytes != seq.Bytes {
+		t.Errorf("parallel upload = %d files, %d bytes; want 40, %d", par.Files, par.Bytes, seq.Bytes)
+	}
+	for i := range seq.Copied {
+		want, _ := filepath.Rel(filepath.Join(dir, "seq"), seq.Copied[i].Remote)
+		if got, _ := filepath.Rel(filepath.Join(dir, "par"), par.Copied[i].Remote); got != want {
+			t.Fatalf("copied[%d] = %s, want %s (walk order)", i, got, want)
+		}
+	}
+
+	down, err := DownloadDir(t.Context(), sc, filepath.Join(dir, "par"), filepath.Join(dir, "down"), DownloadOptions{Parallel: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down.Files != 40 || down.Bytes != seq.Bytes {
+		t.Errorf("parallel download = %d files, %d bytes; want 40, %d", down.Files, down.Bytes, seq.Bytes)
+	}
+	for _, f := range down.Copied {
+		local, _ := LocalSHA256(f.Local)
+		remote, _ := RemoteSHA256(sc, f.Remote)
+		if local != remote {
+			t.Errorf("%s differs after download", f.Local)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "down", "d1")); err != nil || info.Mode().Perm() != 0555 {
+		t.Errorf("d1 mode = %v, %v; want 0555", info, err)
+	}
+}
+
+func TestFileWorkers_FirstErrorInWalkOrder(t *testing.T) {
+	w := newFileWorkers(t.Context(), 4)
+	errSlow, errFast := errors.New("slow failure"), errors.New("fast failure")
+	release := make(chan struct{})
+	for i, fn := range []func(context.Context) (int64, error){
+		func(context.Context) (int64, error) { return 1, nil },
+		func(context.Context) (int64, error) { <-release; return 0, errSlow },
+		func(ctx context.Context) (int64, error) { <-ctx.Done(); return 0, ctx.Err() },
+		func(context.Context) (int64, error) { defer close(release); return 0, errFast },
+	} {
+		if err := w.copy(FilePair{Remote: fmt.Sprint(i)}, fn); err != nil {
+			t.Fatalf("copy %d: %v", i, err)
+		}
+	}
+	stats := &TransferStats{}
+	if err := w.wait(stats, nil); !errors.Is(err, errSlow) {
+		t.Errorf("err = %v, want the earlier file's error", err)
+	}
+	if stats.Files != 1 || stats.Bytes != 1 || len(stats.Copied) != 1 {
+		t.Errorf("stats = %+v, want only the first file", stats)
+	}
+}
+
+func TestWindowsPath(t *testing.T) {
+	tests := map[string]string{
+		`C:\Users\me\file.txt`: "/C:/Users/me/file.txt",
+		"C:/Users/me":          "/C:/Users/me",
+		"d:":                   "/d:/",
+		`D:\`:                  "/D:/",
+		"/C:/Users/me":         "/C:/Users/me",
+		`logs\app.log`:         "logs/app.log",
+		"~/file.txt":           "~/file.txt",
+		"C:file":               "C:file",
+	}
+	for in, want := range tests {
+		if got := WindowsPath(in); got != want {
+			t.Errorf("WindowsPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Package sftptest provides an in-process SFTP server for tests of code
+// that takes an *sftp.Client.
+package sftptest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// NewClient returns an SFTP client served from the local filesystem over
+// in-memory pipes. Both ends are closed when the test finishes.
+func NewClient(t testing.TB) *sftp.Client {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatalf("NewClientPipe: %v", err)
+	}
+	t.Cleanup(func() {
+		// Closing the server side first unblocks the client's reader.
+		server.Close()
+		client.Close()
+	})
+	return client
+}
@@ -0,0 +1,72 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrStatVFSUnsupported is returned by FreeSpace when the server does not
+// offer the statvfs@openssh.com extension.
+var ErrStatVFSUnsupported = errors.New("server does not support statvfs@openssh.com")
+
+// NearestExistingDir returns remotePath if it is an existing directory,
+// otherwise its closest existing ancestor: the directory whose filesystem
+// will hold anything created at remotePath.
+func NearestExistingDir(sftpClient *sftp.Client, remotePath string) (string, error) {
+	p := path.Clean(remotePath)
+	for {
+		info, err := sftpClient.Stat(p)
+		if err == nil {
+			if info.IsDir() {
+				return p, nil
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+			return "", fmt.Errorf("stat %s: %w", p, err)
+		}
+		parent := path.Dir(p)
+		if parent == p {
+			return p, nil
+		}
+		p = parent
+	}
+}
+
+// FreeSpace returns the bytes available to the login user on the
+// filesystem holding dir, using the statvfs@openssh.com extension.
+func FreeSpace(sftpClient *sftp.Client, dir string) (uint64, error) {
+	if _, ok := sftpClient.HasExtension("statvfs@openssh.com"); !ok {
+		return 0, ErrStatVFSUnsupported
+	}
+	st, err := sftpClient.StatVFS(dir)
+	if err != nil {
+		return 0, fmt.Errorf("statvfs %s: %w", dir, err)
+	}
+	return st.Bavail * st.Frsize, nil
+}
+
+// LocalSize returns the total size of the regular files at localPath, a
+// file or a directory walked without following symlinks.
+func LocalSize(localPath string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
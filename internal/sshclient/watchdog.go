@@ -0,0 +1,210 @@
+package sshclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newWatchedSFTPClient starts the sftp subsystem on a session of its own
+// and watches the request stream: every SFTP request (stat, readdir, open,
+// each read or write of a transfer) gets exactly one reply, so when requests
+// are outstanding and no reply arrives for opTimeout the server is hung. The
+// session is then closed, which fails the pending calls instead of leaving
+// the tool call blocked until the SSH connection itself times out.
+func newWatchedSFTPClient(client *ssh.Client, opTimeout time.Duration) (*sftp.Client, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	session.Stderr = io.Discard
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return newWatchedClientPipe(stdout, &sessionCloser{WriteCloser: stdin, session: session}, opTimeout, func() { session.Close() })
+}
+
+// sessionCloser closes the session along with its stdin when the SFTP
+// client is closed.
+type sessionCloser struct {
+	io.WriteCloser
+	session *ssh.Session
+}
+
+func (s *sessionCloser) Close() error {
+	err := s.WriteCloser.Close()
+	s.session.Close()
+	return err
+}
+
+// newWatchedClientPipe is newWatchedSFTPClient over any transport; kill
+// must make pending reads on rd fail.
+func newWatchedClientPipe(rd io.Reader, wr io.WriteCloser, opTimeout time.Duration, kill func()) (*sftp.Client, error) {
+	w := &sftpWatchdog{timeout: opTimeout, kill: kill}
+	sc, err := sftp.NewClientPipe(&watchedReader{r: rd, w: w}, &watchedWriter{WriteCloser: wr, w: w})
+	if err != nil {
+		w.stop()
+		kill()
+		if w.expired() {
+			return nil, fmt.Errorf("sftp server sent no reply within %s", opTimeout)
+		}
+		return nil, err
+	}
+	return sc, nil
+}
+
+// sftpWatchdog tracks outstanding SFTP requests and kills the transport
+// when the oldest wait for a reply exceeds timeout.
+type sftpWatchdog struct {
+	timeout time.Duration
+	kill    func()
+
+	mu       sync.Mutex
+	pending  int
+	progress time.Time // last request sent while idle, or last reply
+	timer    *time.Timer
+	fired    bool
+}
+
+func (w *sftpWatchdog) sent(n int) {
+	if n == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending == 0 {
+		w.progress = time.Now()
+		w.arm(w.timeout)
+	}
+	w.pending += n
+}
+
+func (w *sftpWatchdog) received(n int) {
+	if n == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = max(w.pending-n, 0)
+	w.progress = time.Now()
+}
+
+// arm schedules a check after d. Called with mu held.
+func (w *sftpWatchdog) arm(d time.Duration) {
+	if w.timer == nil {
+		w.timer = time.AfterFunc(d, w.check)
+		return
+	}
+	w.timer.Reset(d)
+}
+
+func (w *sftpWatchdog) check() {
+	w.mu.Lock()
+	if w.pending == 0 || w.fired {
+		w.mu.Unlock()
+		return
+	}
+	if wait := time.Since(w.progress); wait < w.timeout {
+		w.arm(w.timeout - wait)
+		w.mu.Unlock()
+		return
+	}
+	w.fired = true
+	w.mu.Unlock()
+	log.Printf("sftp: no reply for %s with %d request(s) outstanding; closing the SFTP session", w.timeout, w.pending)
+	w.kill()
+}
+
+func (w *sftpWatchdog) expired() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fired
+}
+
+func (w *sftpWatchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+type watchedReader struct {
+	r      io.Reader
+	w      *sftpWatchdog
+	frames packetFramer
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.w.received(r.frames.feed(p[:n]))
+	return n, err
+}
+
+type watchedWriter struct {
+	io.WriteCloser
+	w      *sftpWatchdog
+	frames packetFramer
+}
+
+func (w *watchedWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.w.sent(w.frames.feed(p[:n]))
+	return n, err
+}
+
+func (w *watchedWriter) Close() error {
+	w.w.stop()
+	return w.WriteCloser.Close()
+}
+
+// packetFramer counts the SFTP packets (a uint32 length, then that many
+// bytes) completed in a byte stream, however it is split into writes.
+type packetFramer struct {
+	header [4]byte
+	have   int    // header bytes collected
+	left   uint32 // body bytes still to come
+}
+
+func (f *packetFramer) feed(p []byte) int {
+	done := 0
+	for len(p) > 0 {
+		if f.left == 0 {
+			k := copy(f.header[f.have:], p)
+			f.have += k
+			p = p[k:]
+			if f.have < len(f.header) {
+				break
+			}
+			f.have = 0
+			if f.left = binary.BigEndian.Uint32(f.header[:]); f.left == 0 {
+				done++
+			}
+			continue
+		}
+		k := min(uint32(len(p)), f.left)
+		f.left -= k
+		p = p[k:]
+		if f.left == 0 {
+			done++
+		}
+	}
+	return done
+}
@@ -0,0 +1,116 @@
+package sshclient
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+func TestPacketFramer(t *testing.T) {
+	stream := []byte{
+		0, 0, 0, 5, 2, 0, 0, 0, 3, // one 5-byte packet
+		0, 0, 0, 0, // an empty packet
+		0, 0, 0, 2, 7, 7, // a 2-byte packet
+	}
+	for _, size := range []int{1, 2, 3, 5, 7, len(stream)} {
+		var f packetFramer
+		total := 0
+		for p := stream; len(p) > 0; {
+			k := min(size, len(p))
+			total += f.feed(p[:k])
+			p = p[k:]
+		}
+		if total != 3 {
+			t.Errorf("chunks of %d: counted %d packets, want 3", size, total)
+		}
+	}
+}
+
+// watchedTestClient connects a watched client to an in-process server.
+func watchedTestClient(t *testing.T, timeout time.Duration) *sftp.Client {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	sc, err := newWatchedClientPipe(clientRead, clientWrite, timeout, func() { clientRead.Close() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		sc.Close()
+	})
+	return sc
+}
+
+func TestWatchedClient_Works(t *testing.T) {
+	sc := watchedTestClient(t, 50*time.Millisecond)
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		// Idle time between operations must not count against the timeout.
+		time.Sleep(80 * time.Millisecond)
+		if _, err := sc.Stat(dir); err != nil {
+			t.Fatalf("Stat after idle: %v", err)
+		}
+	}
+	data := make([]byte, 1<<20)
+	if err := os.WriteFile(dir+"/f", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadFile(t.Context(), sc, dir+"/f"); err != nil || len(got) != len(data) {
+		t.Fatalf("ReadFile = %d bytes, %v", len(got), err)
+	}
+}
+
+func TestWatchedClient_HungServer(t *testing.T) {
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	// A server that completes the handshake and then never replies.
+	go func() {
+		buf := make([]byte, 9)
+		if _, err := io.ReadFull(serverRead, buf); err != nil {
+			return
+		}
+		serverWrite.Write([]byte{0, 0, 0, 5, 2, 0, 0, 0, 3}) // SSH_FXP_VERSION 3
+		io.Copy(io.Discard, serverRead)
+	}()
+	sc, err := newWatchedClientPipe(clientRead, clientWrite, 50*time.Millisecond, func() { clientRead.Close() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sc.Stat("/")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Stat on a hung server succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stat still blocked after the SFTP timeout")
+	}
+}
+
+func TestWatchedClient_HungHandshake(t *testing.T) {
+	clientRead, _ := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	go io.Copy(io.Discard, serverRead)
+	_, err := newWatchedClientPipe(clientRead, clientWrite, 50*time.Millisecond, func() { clientRead.Close() })
+	if err == nil || err.Error() != "sftp server sent no reply within 50ms" {
+		t.Errorf("err = %v", err)
+	}
+}
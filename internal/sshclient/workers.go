@@ -0,0 +1,105 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MaxTransferWorkers caps the file copies a directory transfer runs at once.
+const MaxTransferWorkers = 32
+
+// fileWorkers runs the file copies of a directory transfer on up to n
+// goroutines while the walk goes on, over one SFTP client (pkg/sftp
+// pipelines concurrent requests). Results are kept in walk order, so stats
+// and the reported error are the same whatever order the copies finish in.
+type fileWorkers struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{} // nil: copy inline, one file at a time
+	wg     sync.WaitGroup
+	jobs   []*fileJob // appended by the walk only
+}
+
+type fileJob struct {
+	pair FilePair
+	n    int64
+	err  error
+}
+
+// newFileWorkers returns workers for n parallel copies; n <= 1 copies each
+// file before the walk moves on, as a plain sequential transfer would.
+func newFileWorkers(ctx context.Context, n int) *fileWorkers {
+	wctx, cancel := context.WithCancel(ctx)
+	w := &fileWorkers{parent: ctx, ctx: wctx, cancel: cancel}
+	if n > 1 {
+		w.sem = make(chan struct{}, min(n, MaxTransferWorkers))
+	}
+	return w
+}
+
+// copy queues copyFn for pair, waiting for a free worker. The first failure
+// cancels the copies still running and stops the walk; an error returned
+// here only means the walk should stop, wait reports the cause.
+func (w *fileWorkers) copy(pair FilePair, copyFn func(ctx context.Context) (int64, error)) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	job := &fileJob{pair: pair}
+	w.jobs = append(w.jobs, job)
+	if w.sem == nil {
+		job.n, job.err = copyFn(w.ctx)
+		return job.err
+	}
+	select {
+	case w.sem <- struct{}{}:
+	case <-w.ctx.Done():
+		job.err = w.ctx.Err()
+		return job.err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.sem }()
+		job.n, job.err = copyFn(w.ctx)
+		if job.err != nil {
+			w.cancel()
+		}
+	}()
+	return nil
+}
+
+// wait lets the running copies finish and adds the successful ones to
+// stats in walk order. It returns the error of the earliest failed file in
+// walk order, which is what a sequential transfer would have stopped at, or
+// else walkErr. Copies cut short only because another one failed don't
+// count as failures of their own.
+func (w *fileWorkers) wait(stats *TransferStats, walkErr error) error {
+	w.wg.Wait()
+	w.cancel()
+	var first, cancelled error
+	for _, job := range w.jobs {
+		if job.err != nil {
+			switch {
+			case w.parent.Err() == nil && errors.Is(job.err, context.Canceled):
+				if cancelled == nil {
+					cancelled = job.err
+				}
+			case first == nil:
+				first = job.err
+			}
+			continue
+		}
+		stats.Files++
+		stats.Bytes += job.n
+		stats.Copied = append(stats.Copied, job.pair)
+	}
+	if first != nil {
+		return first
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+	return cancelled
+}
@@ -0,0 +1,327 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// Archive formats supported by ssh_archive and ssh_extract.
+const (
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// archiveToolProbe prints the name of each archiver found on the remote PATH.
+const archiveToolProbe = `for t in tar zip unzip 7z 7za; do command -v "$t" >/dev/null 2>&1 && echo "$t"; done`
+
+// ArchiveDeps holds dependencies for the ssh_archive and ssh_extract tool handlers.
+type ArchiveDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// archiveFormat returns the format requested explicitly or implied by the
+// archive file name.
+func archiveFormat(format, archivePath string) (string, error) {
+	switch strings.ToLower(format) {
+	case "tar.gz", "tgz":
+		return archiveTarGz, nil
+	case "zip":
+		return archiveZip, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q (must be tar.gz or zip)", format)
+	}
+	name := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip, nil
+	}
+	return "", fmt.Errorf("cannot infer archive format from %q; set format to tar.gz or zip", archivePath)
+}
+
+// archiveTools probes which archivers are installed on the remote host.
+func archiveTools(ctx context.Context, deps *ArchiveDeps, sessionID string) (map[string]bool, error) {
+	res, err := runCLICommand(ctx, deps.Pool, nil, sessionID, "archiver probe", archiveToolProbe, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	tools := make(map[string]bool)
+	for _, line := range strings.Fields(res.Stdout) {
+		tools[line] = true
+	}
+	if tools["7za"] && !tools["7z"] {
+		tools["7z"] = true
+	}
+	return tools, nil
+}
+
+// sevenZip returns the installed 7-Zip binary name.
+func sevenZip(tools map[string]bool) string {
+	if tools["7za"] {
+		return "7za"
+	}
+	return "7z"
+}
+
+// memberArg makes a relative path safe to pass as a positional argument.
+func memberArg(p string) string {
+	if strings.HasPrefix(p, "-") {
+		return "./" + p
+	}
+	return p
+}
+
+// resolveArchivePaths validates remote paths and makes them absolute, so they
+// still mean the same thing once shell-quoted (a quoted ~ is not expanded).
+// This is the handlers' rate-limited step; later commands skip the limiter.
+func resolveArchivePaths(ctx context.Context, deps *ArchiveDeps, sessionID string, paths ...*string) error {
+	for _, p := range paths {
+		if err := security.ValidatePath(*p); err != nil {
+			return fmt.Errorf("invalid remote path: %w", err)
+		}
+	}
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, sessionID)
+	if err != nil {
+		return err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return fmt.Errorf("archive tools are not supported on Windows hosts")
+	}
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	home, err := sc.Getwd()
+	if err != nil {
+		return fmt.Errorf("resolve remote home: %w", err)
+	}
+	for _, p := range paths {
+		resolved := expandRemotePath(conn, sc, *p)
+		// RealPath fails for paths that do not exist yet.
+		if rest, ok := strings.CutPrefix(resolved, "~"); ok && (rest == "" || rest[0] == '/') {
+			resolved = home + rest
+		}
+		if !path.IsAbs(resolved) {
+			resolved = path.Join(home, resolved)
+		}
+		*p = resolved
+	}
+	return nil
+}
+
+// HandleArchive implements the ssh_archive tool.
+func HandleArchive(ctx context.Context, deps *ArchiveDeps, input SSHArchiveInput) (*SSHArchiveOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if input.ArchivePath == "" {
+		return nil, fmt.Errorf("archive_path is required")
+	}
+	if len(input.Paths) == 0 {
+		return nil, fmt.Errorf("at least one path is required")
+	}
+	format, err := archiveFormat(input.Format, input.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, len(input.Paths))
+	for i, p := range input.Paths {
+		if err := security.ValidatePath(p); err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+		if path.IsAbs(p) && input.BaseDir != "" {
+			return nil, fmt.Errorf("path %q must be relative to base_dir", p)
+		}
+		members[i] = memberArg(p)
+	}
+
+	baseDir := input.BaseDir
+	if baseDir == "" {
+		baseDir = "."
+	}
+	archivePath := input.ArchivePath
+	if err := resolveArchivePaths(ctx, deps, input.SessionID, &baseDir, &archivePath); err != nil {
+		return nil, err
+	}
+
+	tools, err := archiveTools(ctx, deps, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tool, cmd string
+	switch {
+	case format == archiveTarGz && tools["tar"]:
+		tool = "tar"
+		cmd, err = buildCLICommand(deps.Filter, deps.Config, input.Sudo, "tar",
+			append([]string{"-czf", archivePath, "-C", baseDir}, members...)...)
+	case format == archiveZip && tools["zip"]:
+		tool = "zip"
+		cmd, err = buildCLICommand(deps.Filter, deps.Config, input.Sudo, "zip",
+			append([]string{"-r", "-q", archivePath}, members...)...)
+	case format == archiveZip && tools["7z"]:
+		tool = sevenZip(tools)
+		cmd, err = buildCLICommand(deps.Filter, deps.Config, input.Sudo, tool,
+			append([]string{"a", "-tzip", "-bd", archivePath}, members...)...)
+	default:
+		return nil, fmt.Errorf("no archiver for %s found on the remote host (need %s)", format, neededTools(format, false))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tool != "tar" {
+		// zip and 7z store paths relative to the current directory.
+		cmd = fmt.Sprintf("cd %s && %s", shellQuote(baseDir), cmd)
+	}
+
+	if _, err := runCLICommand(ctx, deps.Pool, nil, input.SessionID, tool, cmd, deps.Config.CommandTimeout); err != nil {
+		return nil, err
+	}
+
+	out := &SSHArchiveOutput{ArchivePath: archivePath, Format: format, Tool: tool, Paths: len(members), Size: -1}
+	if conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, nil, input.SessionID); err == nil {
+		if sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout); err == nil {
+			if fi, err := sc.Stat(archivePath); err == nil {
+				out.Size = fi.Size()
+			}
+			sc.Close()
+		}
+	}
+	return out, nil
+}
+
+// HandleExtract implements the ssh_extract tool. The archive listing is
+// checked before anything is written: members with absolute paths or ".."
+// segments are rejected, so extraction cannot escape dest_dir.
+func HandleExtract(ctx context.Context, deps *ArchiveDeps, input SSHExtractInput) (*SSHExtractOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if input.ArchivePath == "" {
+		return nil, fmt.Errorf("archive_path is required")
+	}
+	if input.DestDir == "" {
+		return nil, fmt.Errorf("dest_dir is required")
+	}
+	format, err := archiveFormat(input.Format, input.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	archivePath, destDir := input.ArchivePath, input.DestDir
+	if err := resolveArchivePaths(ctx, deps, input.SessionID, &archivePath, &destDir); err != nil {
+		return nil, err
+	}
+
+	tools, err := archiveTools(ctx, deps, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tool string
+	var listArgs, extractArgs []string
+	switch {
+	case format == archiveTarGz && tools["tar"]:
+		tool = "tar"
+		listArgs = []string{"-tzf", archivePath}
+		extractArgs = []string{"-xzf", archivePath, "-C", destDir, "--no-same-owner"}
+	case format == archiveZip && tools["unzip"]:
+		tool = "unzip"
+		listArgs = []string{"-Z1", archivePath}
+		extractArgs = []string{"-o", "-q", archivePath, "-d", destDir}
+	case tools["7z"]:
+		// 7-Zip unpacks zip directly; tar.gz needs tar.
+		if format == archiveTarGz {
+			return nil, fmt.Errorf("no archiver for %s found on the remote host (need %s)", format, neededTools(format, true))
+		}
+		tool = sevenZip(tools)
+		listArgs = []string{"l", "-ba", "-slt", archivePath}
+		extractArgs = []string{"x", "-y", "-bd", "-o" + destDir, archivePath}
+	default:
+		return nil, fmt.Errorf("no archiver for %s found on the remote host (need %s)", format, neededTools(format, true))
+	}
+
+	listCmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, tool, listArgs...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runCLICommand(ctx, deps.Pool, nil, input.SessionID, tool+" list", listCmd, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	if strings.HasPrefix(tool, "7z") {
+		members = parse7zListing(res.Stdout)
+	} else {
+		members = strings.FieldsFunc(res.Stdout, func(r rune) bool { return r == '\n' || r == '\r' })
+	}
+	if err := checkArchiveMembers(members); err != nil {
+		return nil, err
+	}
+
+	mkdirCmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, "mkdir", "-p", destDir)
+	if err != nil {
+		return nil, err
+	}
+	extractCmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, tool, extractArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runCLICommand(ctx, deps.Pool, nil, input.SessionID, tool, mkdirCmd+" && "+extractCmd, deps.Config.CommandTimeout); err != nil {
+		return nil, err
+	}
+
+	return &SSHExtractOutput{ArchivePath: archivePath, DestDir: destDir, Format: format, Tool: tool, Entries: len(members)}, nil
+}
+
+// neededTools names the archivers that can handle format.
+func neededTools(format string, extract bool) string {
+	switch {
+	case format == archiveTarGz:
+		return "tar"
+	case extract:
+		return "unzip or 7z"
+	default:
+		return "zip or 7z"
+	}
+}
+
+// parse7zListing extracts member paths from `7z l -ba -slt` output.
+func parse7zListing(out string) []string {
+	var members []string
+	for _, line := range strings.Split(out, "\n") {
+		if name, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), "Path = "); ok {
+			members = append(members, name)
+		}
+	}
+	return members
+}
+
+// checkArchiveMembers rejects member names that would be written outside the
+// destination directory ("zip slip").
+func checkArchiveMembers(members []string) error {
+	for _, m := range members {
+		name := strings.ReplaceAll(m, `\`, "/")
+		if strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':') {
+			return fmt.Errorf("archive member %q has an absolute path", m)
+		}
+		for _, seg := range strings.Split(name, "/") {
+			if seg == ".." {
+				return fmt.Errorf("archive member %q escapes the destination directory", m)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	tests := []struct {
+		format, path, want string
+	}{
+		{"", "/tmp/bundle.tar.gz", archiveTarGz},
+		{"", "/tmp/bundle.TGZ", archiveTarGz},
+		{"", "logs.zip", archiveZip},
+		{"tgz", "/tmp/bundle", archiveTarGz},
+		{"ZIP", "/tmp/bundle.tar.gz", archiveZip},
+	}
+	for _, tt := range tests {
+		got, err := archiveFormat(tt.format, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("archiveFormat(%q, %q) = %q, %v; want %q", tt.format, tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := archiveFormat("", "/tmp/bundle.rar"); err == nil {
+		t.Error("expected error for unknown extension")
+	}
+	if _, err := archiveFormat("rar", "/tmp/bundle.rar"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestCheckArchiveMembers(t *testing.T) {
+	if err := checkArchiveMembers([]string{"app/", "app/bin/server", "app/..hidden", "README.md"}); err != nil {
+		t.Errorf("expected safe members to pass, got %v", err)
+	}
+	for _, bad := range []string{"/etc/passwd", "../../.ssh/authorized_keys", "app/../../x", `..\evil.dll`, `C:\Windows\x`} {
+		if err := checkArchiveMembers([]string{"ok.txt", bad}); err == nil {
+			t.Errorf("expected member %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParse7zListing(t *testing.T) {
+	out := "Path = app\r\nFolder = +\r\n\r\nPath = app/main.go\r\nSize = 12\r\n"
+	got := parse7zListing(out)
+	if len(got) != 2 || got[0] != "app" || got[1] != "app/main.go" {
+		t.Errorf("parse7zListing = %q", got)
+	}
+}
+
+func TestMemberArg(t *testing.T) {
+	if got := memberArg("-rf"); got != "./-rf" {
+		t.Errorf("memberArg(-rf) = %q", got)
+	}
+	if got := memberArg("logs/app.log"); got != "logs/app.log" {
+		t.Errorf("memberArg(logs/app.log) = %q", got)
+	}
+}
+
+func TestHandleArchive_Validation(t *testing.T) {
+	deps := &ArchiveDeps{}
+	for _, tc := range []struct {
+		input SSHArchiveInput
+		want  string
+	}{
+		{SSHArchiveInput{ArchivePath: "a.zip", Paths: []string{"x"}}, "session_id"},
+		{SSHArchiveInput{SessionID: "s", Paths: []string{"x"}}, "archive_path"},
+		{SSHArchiveInput{SessionID: "s", ArchivePath: "a.zip"}, "at least one path"},
+		{SSHArchiveInput{SessionID: "s", ArchivePath: "a.rar", Paths: []string{"x"}}, "cannot infer"},
+		{SSHArchiveInput{SessionID: "s", ArchivePath: "a.zip", Paths: []string{"../etc"}}, "traversal"},
+		{SSHArchiveInput{SessionID: "s", ArchivePath: "a.zip", Paths: []string{"/etc"}, BaseDir: "/srv"}, "relative to base_dir"},
+	} {
+		_, err := HandleArchive(context.Background(), deps, tc.input)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("input %+v: expected error containing %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
+
+func TestHandleExtract_Validation(t *testing.T) {
+	deps := &ArchiveDeps{}
+	for _, tc := range []struct {
+		input SSHExtractInput
+		want  string
+	}{
+		{SSHExtractInput{SessionID: "s", DestDir: "/srv"}, "archive_path"},
+		{SSHExtractInput{SessionID: "s", ArchivePath: "a.zip"}, "dest_dir"},
+		{SSHExtractInput{SessionID: "s", ArchivePath: "a.7z", DestDir: "/srv"}, "cannot infer"},
+	} {
+		_, err := HandleExtract(context.Background(), deps, tc.input)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("input %+v: expected error containing %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
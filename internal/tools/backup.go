@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// backupTimeFormat is the UTC timestamp in timestamped backup names. It is
+// fixed-width, so names sort chronologically.
+const backupTimeFormat = "20060102T150405.000Z"
+
+// backupLocation returns the directory holding backups of remotePath and
+// the base name they are derived from. With a backup directory configured,
+// the file's absolute path is mirrored below it so equal names in different
+// directories don't collide.
+func backupLocation(sc *sftp.Client, cfg config.BackupConfig, remotePath string) (string, string, error) {
+	if cfg.Dir == "" {
+		return path.Dir(remotePath), path.Base(remotePath), nil
+	}
+	absPath := remotePath
+	if !path.IsAbs(absPath) {
+		wd, err := sc.Getwd()
+		if err != nil {
+			return "", "", fmt.Errorf("resolve %s: %w", remotePath, err)
+		}
+		absPath = path.Join(wd, absPath)
+	}
+	root := sshclient.ExpandRemotePath(sc, cfg.Dir)
+	return mirrorBackupDir(root, absPath), path.Base(absPath), nil
+}
+
+// mirrorBackupDir returns the directory below root that mirrors the parent
+// directory of the absolute path absPath.
+func mirrorBackupDir(root, absPath string) string {
+	return path.Join(root, path.Dir(path.Clean(absPath)))
+}
+
+// backupName returns the name of a new backup of a file named base.
+func backupName(style, base string, now time.Time) string {
+	if style == config.BackupStyleTimestamped {
+		return base + "." + now.UTC().Format(backupTimeFormat) + ".bak"
+	}
+	return base + ".bak"
+}
+
+// parseBackupName reports whether name is a backup of a file named base and
+// returns its timestamp (zero for a simple .bak backup).
+func parseBackupName(base, name string) (time.Time, bool) {
+	if name == base+".bak" {
+		return time.Time{}, true
+	}
+	stamp, ok := strings.CutPrefix(name, base+".")
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ".bak")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, stamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// listBackups returns the backups of remotePath, newest first. A missing
+// backup directory yields no backups.
+func listBackups(sc *sftp.Client, cfg config.BackupConfig, remotePath string) ([]BackupInfo, error) {
+	dir, base, err := backupLocation(sc, cfg, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := sc.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list backups in %s: %w", dir, err)
+	}
+
+	type datedBackup struct {
+		info    BackupInfo
+		created time.Time
+	}
+	var dated []datedBackup
+	for _, e := range entries {
+		if !e.Mode().IsRegular() {
+			continue
+		}
+		stamp, ok := parseBackupName(base, e.Name())
+		if !ok {
+			continue
+		}
+		created := stamp
+		if created.IsZero() {
+			created = e.ModTime()
+		}
+		dated = append(dated, datedBackup{
+			info: BackupInfo{
+				Name:        e.Name(),
+				Path:        path.Join(dir, e.Name()),
+				Size:        e.Size(),
+				Created:     created.UTC().Format(time.RFC3339),
+				Timestamped: !stamp.IsZero(),
+			},
+			created: created,
+		})
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].created.After(dated[j].created)
+	})
+
+	backups := make([]BackupInfo, len(dated))
+	for i, d := range dated {
+		backups[i] = d.info
+	}
+	return backups, nil
+}
+
+// staleBackups returns the timestamped backups beyond the newest keep, given
+// backups sorted newest first. A simple .bak backup is never rotated.
+func staleBackups(backups []BackupInfo, keep int) []BackupInfo {
+	if keep <= 0 {
+		return nil
+	}
+	var stale []BackupInfo
+	n := 0
+	for _, b := range backups {
+		if !b.Timestamped {
+			continue
+		}
+		n++
+		if n > keep {
+			stale = append(stale, b)
+		}
+	}
+	return stale
+}
+
+// writeBackup stores data as a new backup of remotePath according to cfg,
+// removes timestamped backups beyond cfg.Keep, and returns the backup path.
+func writeBackup(sc *sftp.Client, cfg config.BackupConfig, remotePath string, data []byte, perms os.FileMode) (string, error) {
+	dir, base, err := backupLocation(sc, cfg, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Dir != "" {
+		if err := sc.MkdirAll(dir); err != nil {
+			return "", fmt.Errorf("create backup directory %s: %w", dir, err)
+		}
+	}
+	backupPath := path.Join(dir, backupName(cfg.Style, base, time.Now()))
+	if _, err := sshclient.WriteFile(sc, backupPath, data, perms); err != nil {
+		return "", err
+	}
+
+	if cfg.Style == config.BackupStyleTimestamped && cfg.Keep > 0 {
+		backups, err := listBackups(sc, cfg, remotePath)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range staleBackups(backups, cfg.Keep) {
+			if err := sc.Remove(b.Path); err != nil {
+				return "", fmt.Errorf("rotate backup %s: %w", b.Path, err)
+			}
+		}
+	}
+	return backupPath, nil
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestBackupName(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 9, 26, 535_000_000, time.FixedZone("X", 3600))
+	if got := backupName(config.BackupStyleSimple, "app.conf", now); got != "app.conf.bak" {
+		t.Errorf("simple backupName = %q", got)
+	}
+	if got := backupName("", "app.conf", now); got != "app.conf.bak" {
+		t.Errorf("default backupName = %q", got)
+	}
+	got := backupName(config.BackupStyleTimestamped, "app.conf", now)
+	if got != "app.conf.20260314T140926.535Z.bak" {
+		t.Errorf("timestamped backupName = %q", got)
+	}
+
+	stamp, ok := parseBackupName("app.conf", got)
+	if !ok || !stamp.Equal(now) {
+		t.Errorf("parseBackupName(%q) = %v, %v; want %v", got, stamp, ok, now)
+	}
+}
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct {
+		name      string
+		ok        bool
+		timestamp bool
+	}{
+		{"app.conf.bak", true, false},
+		{"app.conf.20260314T140926.535Z.bak", true, true},
+		{"app.conf", false, false},
+		{"app.conf.old.bak", false, false},
+		{"app.conf.local.20260314T140926.535Z.bak", false, false},
+		{"other.conf.bak", false, false},
+		{"app.conf.20260314T140926.535Z.bak.tmp", false, false},
+	}
+	for _, tt := range tests {
+		stamp, ok := parseBackupName("app.conf", tt.name)
+		if ok != tt.ok || !stamp.IsZero() != tt.timestamp {
+			t.Errorf("parseBackupName(%q) = %v, %v; want ok=%v timestamped=%v", tt.name, stamp, ok, tt.ok, tt.timestamp)
+		}
+	}
+}
+
+func TestMirrorBackupDir(t *testing.T) {
+	tests := []struct{ root, path, want string }{
+		{"/var/backups/ssh-mcp", "/etc/nginx/nginx.conf", "/var/backups/ssh-mcp/etc/nginx"},
+		{"/var/backups/ssh-mcp/", "/etc/../etc/hosts", "/var/backups/ssh-mcp/etc"},
+		{"/backups", "/motd", "/backups"},
+	}
+	for _, tt := range tests {
+		if got := mirrorBackupDir(tt.root, tt.path); got != tt.want {
+			t.Errorf("mirrorBackupDir(%q, %q) = %q, want %q", tt.root, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestStaleBackups(t *testing.T) {
+	backups := []BackupInfo{
+		{Name: "f.5.bak", Timestamped: true},
+		{Name: "f.bak"},
+		{Name: "f.4.bak", Timestamped: true},
+		{Name: "f.3.bak", Timestamped: true},
+		{Name: "f.2.bak", Timestamped: true},
+	}
+	var names []string
+	for _, b := range staleBackups(backups, 2) {
+		names = append(names, b.Name)
+	}
+	if got := strings.Join(names, ","); got != "f.3.bak,f.2.bak" {
+		t.Errorf("staleBackups(keep=2) = %s", got)
+	}
+	if got := staleBackups(backups, 0); got != nil {
+		t.Errorf("staleBackups(keep=0) = %v, want none", got)
+	}
+	if got := staleBackups(backups, 10); got != nil {
+		t.Errorf("staleBackups(keep=10) = %v, want none", got)
+	}
+}
+
+func TestSSHRestoreBackupOutput_Text(t *testing.T) {
+	out := SSHRestoreBackupOutput{RemotePath: "/etc/app.conf"}
+	if got := out.Text(); got != "No backups of /etc/app.conf" {
+		t.Errorf("empty Text = %q", got)
+	}
+
+	out.Backups = []BackupInfo{{Name: "app.conf.20260314T140926.535Z.bak", Created: "2026-03-14T14:09:26Z", Size: 42}}
+	if got := out.Text(); !strings.Contains(got, "1 backup(s)") || !strings.Contains(got, "app.conf.20260314T140926.535Z.bak") {
+		t.Errorf("list Text = %q", got)
+	}
+
+	out = SSHRestoreBackupOutput{
+		RemotePath:   "/etc/app.conf",
+		RestoredFrom: "/etc/app.conf.bak",
+		BytesWritten: 42,
+		BackupPath:   "/etc/app.conf.bak",
+	}
+	got := out.Text()
+	if !strings.Contains(got, "Restored /etc/app.conf from /etc/app.conf.bak (42 bytes)") || !strings.Contains(got, "backed up to") {
+		t.Errorf("restore Text = %q", got)
+	}
+}
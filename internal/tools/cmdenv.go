@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// commandEnv is the locale and terminal type a user command runs with;
+// empty fields leave the remote account's settings alone.
+type commandEnv struct {
+	locale string // LANG and LC_ALL
+	term   string // TERM
+}
+
+// resolveCommandEnv returns the environment for a command: the call's
+// locale and term if set, otherwise --locale and --term. Windows hosts get
+// none, and asking for one there is an error.
+func resolveCommandEnv(cfg *config.SSHConfig, locale, term string, windows bool) (commandEnv, error) {
+	if locale != "" && !config.LocalePattern.MatchString(locale) {
+		return commandEnv{}, fmt.Errorf("invalid locale %q", locale)
+	}
+	if term != "" && !config.TermPattern.MatchString(term) {
+		return commandEnv{}, fmt.Errorf("invalid term %q", term)
+	}
+	if windows {
+		if locale != "" || term != "" {
+			return commandEnv{}, fmt.Errorf("locale and term are only supported on POSIX hosts")
+		}
+		return commandEnv{}, nil
+	}
+	env := commandEnv{locale: cfg.Locale, term: cfg.Term}
+	if locale != "" {
+		env.locale = locale
+	}
+	if term != "" {
+		env.term = term
+	}
+	return env, nil
+}
+
+// vars returns the variable assignments, in NAME=value form.
+func (e commandEnv) vars() []string {
+	var vars []string
+	if e.locale != "" {
+		vars = append(vars, "LANG="+e.locale, "LC_ALL="+e.locale)
+	}
+	if e.term != "" {
+		vars = append(vars, "TERM="+e.term)
+	}
+	return vars
+}
+
+// export returns shell code that sets the environment, to go in front of a
+// command line. LANGUAGE is unset with a locale, since gettext would
+// otherwise prefer it for messages.
+func (e commandEnv) export() string {
+	vars := e.vars()
+	if len(vars) == 0 {
+		return ""
+	}
+	s := "export " + strings.Join(vars, " ") + "; "
+	if e.locale != "" {
+		s += "unset LANGUAGE; "
+	}
+	return s
+}
+
+// envArgs returns an env(1) invocation that sets the environment, to go in
+// front of a program and its arguments, or nil if there is nothing to set.
+func (e commandEnv) envArgs() []string {
+	vars := e.vars()
+	if len(vars) == 0 {
+		return nil
+	}
+	args := []string{"env"}
+	if e.locale != "" {
+		args = append(args, "-u", "LANGUAGE")
+	}
+	return append(args, vars...)
+}
+
+// unsafeEnvNames are variables ssh_connect refuses as session defaults:
+// they make shells and programs load code, or change which program a
+// command name runs, behind the command filter's back. --host-defaults
+// may set them.
+var unsafeEnvNames = map[string]bool{
+	"PATH": true, "CDPATH": true, "IFS": true, "ENV": true, "BASH_ENV": true,
+	"SHELLOPTS": true, "BASHOPTS": true, "PS4": true, "PROMPT_COMMAND": true,
+	"GCONV_PATH": true, "PYTHONPATH": true, "PYTHONSTARTUP": true, "PERL5LIB": true,
+	"PERL5OPT": true, "RUBYOPT": true, "NODE_OPTIONS": true,
+}
+
+// checkSessionEnv refuses variables ssh_connect may not set: those in
+// unsafeEnvNames and those of the dynamic linker and bash functions.
+func checkSessionEnv(env map[string]string) error {
+	for name := range env {
+		if unsafeEnvNames[name] || strings.HasPrefix(name, "LD_") || strings.HasPrefix(name, "DYLD_") || strings.HasPrefix(name, "BASH_FUNC_") {
+			return fmt.Errorf("environment variable %s can't be set on connect; ask the operator for --host-defaults", name)
+		}
+	}
+	return nil
+}
+
+// exportVars returns shell code that exports vars, in name order and with
+// the values quoted, to go in front of a command line.
+func exportVars(vars map[string]string) string {
+	if len(vars) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("export")
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		fmt.Fprintf(&b, " %s=%s", name, shellQuote(vars[name]))
+	}
+	b.WriteString("; ")
+	return b.String()
+}
+
+// defaultsOutput returns session defaults as reported to the client, naming
+// the variables without their values, or nil if there are none.
+func defaultsOutput(d config.SessionDefaults) *SessionDefaultsOutput {
+	if d.IsZero() {
+		return nil
+	}
+	return &SessionDefaultsOutput{
+		WorkingDir: d.WorkingDir,
+		Shell:      d.Shell,
+		Env:        slices.Sorted(maps.Keys(d.Env)),
+	}
+}
+
+// describeDefaults returns session defaults as text.
+func describeDefaults(d *SessionDefaultsOutput) string {
+	var parts []string
+	if d.WorkingDir != "" {
+		parts = append(parts, "dir="+d.WorkingDir)
+	}
+	if d.Shell != "" {
+		parts = append(parts, "shell="+d.Shell)
+	}
+	if len(d.Env) > 0 {
+		parts = append(parts, "env="+strings.Join(d.Env, ","))
+	}
+	return strings.Join(parts, " ")
+}
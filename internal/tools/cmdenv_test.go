@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestResolveCommandEnv(t *testing.T) {
+	cfg := &config.SSHConfig{Locale: "C.UTF-8", Term: "dumb"}
+	tests := []struct {
+		name         string
+		cfg          *config.SSHConfig
+		locale, term string
+		windows      bool
+		want         commandEnv
+		wantErr      bool
+	}{
+		{"server default", cfg, "", "", false, commandEnv{"C.UTF-8", "dumb"}, false},
+		{"call overrides", cfg, "de_DE.UTF-8", "xterm-256color", false, commandEnv{"de_DE.UTF-8", "xterm-256color"}, false},
+		{"none configured", &config.SSHConfig{}, "", "", false, commandEnv{}, false},
+		{"windows ignores default", cfg, "", "", true, commandEnv{}, false},
+		{"windows rejects call", cfg, "C.UTF-8", "", true, commandEnv{}, true},
+		{"invalid locale", cfg, "C; rm -rf /", "", false, commandEnv{}, true},
+		{"invalid term", cfg, "", "$(id)", false, commandEnv{}, true},
+	}
+	for _, tt := range tests {
+		got, err := resolveCommandEnv(tt.cfg, tt.locale, tt.term, tt.windows)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %+v, %v; want %+v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCommandEnv_Export(t *testing.T) {
+	tests := []struct {
+		env  commandEnv
+		want string
+		args []string
+	}{
+		{commandEnv{}, "", nil},
+		{commandEnv{"C.UTF-8", "dumb"}, "export LANG=C.UTF-8 LC_ALL=C.UTF-8 TERM=dumb; unset LANGUAGE; ",
+			[]string{"env", "-u", "LANGUAGE", "LANG=C.UTF-8", "LC_ALL=C.UTF-8", "TERM=dumb"}},
+		{commandEnv{term: "dumb"}, "export TERM=dumb; ", []string{"env", "TERM=dumb"}},
+	}
+	for _, tt := range tests {
+		if got := tt.env.export(); got != tt.want {
+			t.Errorf("%+v export() = %q, want %q", tt.env, got, tt.want)
+		}
+		if got := tt.env.envArgs(); !slices.Equal(got, tt.args) {
+			t.Errorf("%+v envArgs() = %q, want %q", tt.env, got, tt.args)
+		}
+	}
+}
+
+func TestExportVars(t *testing.T) {
+	if got := exportVars(nil); got != "" {
+		t.Errorf("exportVars(nil) = %q, want empty", got)
+	}
+	got := exportVars(map[string]string{"RAILS_ENV": "production", "MSG": "it's $HOME"})
+	want := `export MSG='it'\''s $HOME' RAILS_ENV='production'; `
+	if got != want {
+		t.Errorf("exportVars = %q, want %q", got, want)
+	}
+}
+
+func TestDescribeDefaults(t *testing.T) {
+	d := config.SessionDefaults{WorkingDir: "/srv/app", Shell: "bash", Env: map[string]string{"TOKEN": "secret", "A": "1"}}
+	if got, want := describeDefaults(defaultsOutput(d)), "dir=/srv/app shell=bash env=A,TOKEN"; got != want {
+		t.Errorf("describeDefaults = %q, want %q", got, want)
+	}
+}
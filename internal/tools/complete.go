@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// maxCompletions is the most values one completion may return (MCP limit).
+const maxCompletions = 100
+
+// CompletionDeps holds dependencies for argument completion.
+type CompletionDeps struct {
+	Pool        *connection.Pool
+	Auth        *connection.AuthDiscovery
+	RateLimiter *security.RateLimiter
+}
+
+// Completion is the set of suggested values for one argument.
+type Completion struct {
+	Values  []string
+	Total   int
+	HasMore bool
+}
+
+// HandleComplete suggests values for the argument named arg, given the
+// partial value typed so far and the other arguments already filled in.
+// Hosts come from ssh_config and active sessions, session IDs from the pool,
+// and remote paths from an SFTP listing on the session the path belongs to.
+// Unknown arguments get no suggestions.
+func HandleComplete(ctx context.Context, deps *CompletionDeps, arg, value string, args map[string]string) (*Completion, error) {
+	switch arg {
+	case "host":
+		hosts := deps.Auth.ConfigHosts()
+		for _, c := range deps.Pool.ListConnections(ctx) {
+			hosts = append(hosts, c.Host)
+		}
+		return completeValues(hosts, value), nil
+	case "session_id", "source_session_id", "dest_session_id":
+		var ids []string
+		for _, c := range deps.Pool.ListConnections(ctx) {
+			ids = append(ids, string(c.SessionID))
+		}
+		return completeValues(ids, value), nil
+	case "remote_path", "source_path", "dest_path":
+		sessionID := pathSession(arg, args)
+		if sessionID == "" {
+			return &Completion{}, nil
+		}
+		return completeRemotePath(ctx, deps, sessionID, value)
+	}
+	return &Completion{}, nil
+}
+
+// pathSession returns the session a path argument refers to: the
+// destination session for dest_path and the source session for source_path
+// (as in ssh_transfer), otherwise session_id.
+func pathSession(arg string, args map[string]string) string {
+	switch {
+	case arg == "dest_path" && args["dest_session_id"] != "":
+		return args["dest_session_id"]
+	case arg == "source_path" && args["source_session_id"] != "":
+		return args["source_session_id"]
+	}
+	return args["session_id"]
+}
+
+// completeRemotePath lists the directory part of value on the remote host
+// and suggests the entries starting with the rest. Directories end in "/" so
+// the next completion descends into them; dotfiles are only suggested once
+// the typed name starts with a dot.
+func completeRemotePath(ctx context.Context, deps *CompletionDeps, sessionID, value string) (*Completion, error) {
+	dir, base := splitPathPrefix(value)
+	if dir != "" {
+		if err := security.ValidatePath(dir); err != nil {
+			return nil, fmt.Errorf("invalid remote path: %w", err)
+		}
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	listDir := dir
+	if listDir == "" {
+		listDir = "."
+	}
+	infos, err := sc.ReadDir(expandRemotePath(conn, sc, listDir))
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("list %s: %w", listDir, err)
+	}
+
+	var paths []string
+	for _, fi := range infos {
+		name := fi.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if fi.IsDir() {
+			name += "/"
+		}
+		paths = append(paths, dir+name)
+	}
+	return completeValues(paths, value), nil
+}
+
+// splitPathPrefix splits a partially typed path into the directory to list
+// (kept as typed, with its trailing slash) and the name prefix after it.
+func splitPathPrefix(value string) (dir, base string) {
+	i := strings.LastIndex(value, "/")
+	return value[:i+1], value[i+1:]
+}
+
+// completeValues returns the distinct candidates starting with prefix,
+// sorted and capped at maxCompletions.
+func completeValues(candidates []string, prefix string) *Completion {
+	seen := make(map[string]bool)
+	var values []string
+	for _, c := range candidates {
+		if c == "" || seen[c] || !strings.HasPrefix(c, prefix) {
+			continue
+		}
+		seen[c] = true
+		values = append(values, c)
+	}
+	sort.Strings(values)
+	out := &Completion{Values: values, Total: len(values)}
+	if len(values) > maxCompletions {
+		out.Values = values[:maxCompletions]
+		out.HasMore = true
+	}
+	return out
+}
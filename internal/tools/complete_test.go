@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+func TestCompleteValues(t *testing.T) {
+	got := completeValues([]string{"web2", "db", "web1", "web1", ""}, "web")
+	if strings.Join(got.Values, ",") != "web1,web2" || got.Total != 2 || got.HasMore {
+		t.Errorf("completeValues = %+v", got)
+	}
+
+	var many []string
+	for i := 0; i < maxCompletions+10; i++ {
+		many = append(many, fmt.Sprintf("host%03d", i))
+	}
+	got = completeValues(many, "")
+	if len(got.Values) != maxCompletions || got.Total != maxCompletions+10 || !got.HasMore {
+		t.Errorf("capped completion: %d values, total %d, has_more %v", len(got.Values), got.Total, got.HasMore)
+	}
+}
+
+func TestSplitPathPrefix(t *testing.T) {
+	tests := []struct {
+		value, dir, base string
+	}{
+		{"", "", ""},
+		{"conf", "", "conf"},
+		{"/etc/ng", "/etc/", "ng"},
+		{"/etc/", "/etc/", ""},
+		{"/", "/", ""},
+		{"~/.ss", "~/", ".ss"},
+	}
+	for _, tt := range tests {
+		dir, base := splitPathPrefix(tt.value)
+		if dir != tt.dir || base != tt.base {
+			t.Errorf("splitPathPrefix(%q) = %q, %q, want %q, %q", tt.value, dir, base, tt.dir, tt.base)
+		}
+	}
+}
+
+func TestPathSession(t *testing.T) {
+	args := map[string]string{"session_id": "a@h:22", "source_session_id": "s@h:22", "dest_session_id": "d@h:22"}
+	tests := map[string]string{
+		"remote_path": "a@h:22",
+		"source_path": "s@h:22",
+		"dest_path":   "d@h:22",
+	}
+	for arg, want := range tests {
+		if got := pathSession(arg, args); got != want {
+			t.Errorf("pathSession(%q) = %q, want %q", arg, got, want)
+		}
+	}
+	// ssh_copy paths belong to session_id.
+	if got := pathSession("dest_path", map[string]string{"session_id": "a@h:22"}); got != "a@h:22" {
+		t.Errorf("pathSession(dest_path) without dest_session_id = %q", got)
+	}
+}
+
+func TestHandleComplete_NoSessions(t *testing.T) {
+	cfg := &config.SSHConfig{ConfigPath: "/nonexistent/ssh/config"}
+	deps := &CompletionDeps{
+		Pool: connection.NewPool(cfg, connection.NewAuthDiscovery(cfg)),
+		Auth: connection.NewAuthDiscovery(cfg),
+	}
+	for _, arg := range []string{"host", "session_id", "remote_path", "command"} {
+		got, err := HandleComplete(context.Background(), deps, arg, "", nil)
+		if err != nil {
+			t.Errorf("HandleComplete(%q): %v", arg, err)
+			continue
+		}
+		if len(got.Values) != 0 {
+			t.Errorf("HandleComplete(%q) = %v, want no values", arg, got.Values)
+		}
+	}
+}
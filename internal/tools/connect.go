@@ -2,11 +2,18 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"os"
 	"os/user"
+	"strings"
+	"time"
 
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/credentials"
 	"github.com/n0madic/ssh-mcp/internal/security"
 )
 
@@ -16,6 +23,12 @@ type ConnectDeps struct {
 	Auth        *connection.AuthDiscovery
 	Filter      *security.Filter
 	RateLimiter *security.RateLimiter
+	Credentials credentials.Store // nil when credential storage is disabled
+
+	// HostKeyPrompt is --host-key-prompt. When it allows asking about a
+	// rejected host key, ConfirmHostKey asks the user (nil = can't ask).
+	HostKeyPrompt  string
+	ConfirmHostKey func(ctx context.Context, e *connection.HostKeyError) (bool, error)
 }
 
 // HandleConnect implements the ssh_connect tool.
@@ -39,6 +52,26 @@ func HandleConnect(ctx context.Context, deps *ConnectDeps, input SSHConnectInput
 	if input.KeyPath != "" {
 		params.KeyPath = input.KeyPath
 	}
+	if input.IdleTimeout < 0 {
+		return nil, fmt.Errorf("invalid idle_timeout: %d (must be non-negative)", input.IdleTimeout)
+	}
+	params.IdleTimeout = time.Duration(input.IdleTimeout) * time.Second
+	if input.MaxLifetime < 0 {
+		return nil, fmt.Errorf("invalid max_lifetime: %d (must be non-negative)", input.MaxLifetime)
+	}
+	params.MaxLifetime = time.Duration(input.MaxLifetime) * time.Second
+	params.Defaults = config.SessionDefaults{WorkingDir: input.WorkingDir, Shell: input.Shell, Env: input.Env}
+	if err := params.Defaults.Validate(); err != nil {
+		return nil, err
+	}
+	if input.Shell != "" {
+		if _, err := resolveShell(input.Shell, "", false); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSessionEnv(input.Env); err != nil {
+		return nil, err
+	}
 
 	// Always resolve from SSH config (transparent alias discovery).
 	parsedHost := params.Host // host after ParseHostString (without user@/:port)
@@ -55,6 +88,10 @@ func HandleConnect(ctx context.Context, deps *ConnectDeps, input SSHConnectInput
 	if input.KeyPath == "" && resolved.IdentityFile != "" {
 		params.KeyPath = resolved.IdentityFile
 	}
+	params.ProxyCommand = resolved.ProxyCommand
+	if input.ViaSession != "" {
+		params.ViaSession = connection.SessionID(input.ViaSession)
+	}
 
 	// Default user to current OS user.
 	if params.User == "" {
@@ -69,22 +106,91 @@ func HandleConnect(ctx context.Context, deps *ConnectDeps, input SSHConnectInput
 		}
 	}
 
+	// Fall back to a saved password, or make sure one can be saved.
+	credKey := credentialKey(ctx, string(connection.MakeSessionID(params.User, params.Host, params.Port)))
+	usedSaved := false
+	if input.SaveCredentials {
+		if deps.Credentials == nil {
+			return nil, fmt.Errorf("credential storage is disabled; start server with --credential-store to allow save_credentials")
+		}
+		if params.Password == "" {
+			return nil, fmt.Errorf("save_credentials requires a password")
+		}
+	} else if params.Password == "" && deps.Credentials != nil {
+		cred, ok, err := deps.Credentials.Get(credKey)
+		if err != nil {
+			return nil, fmt.Errorf("read saved credentials: %w", err)
+		}
+		if ok && cred.Password != "" {
+			params.Password = cred.Password
+			usedSaved = true
+		}
+	}
+
 	// Rate limit check.
-	if err := deps.RateLimiter.Allow(params.Host); err != nil {
+	if err := deps.RateLimiter.Wait(ctx, params.Host); err != nil {
 		return nil, err
 	}
 
-	// Host filter check.
-	if err := deps.Filter.AllowHost(params.Host); err != nil {
+	// The jump session must be one of the caller's live sessions.
+	if params.ViaSession != "" {
+		if params.ViaSession == connection.MakeSessionID(params.User, params.Host, params.Port) {
+			return nil, fmt.Errorf("via_session %s is the session being connected", params.ViaSession)
+		}
+		if _, err := deps.Pool.GetConnection(ctx, params.ViaSession); err != nil {
+			return nil, fmt.Errorf("via_session: %w", err)
+		}
+	}
+
+	// Host filter check, on the resolved addresses too with --resolve-hosts.
+	// They are pinned for the dial so DNS can't change between check and use.
+	var addrs []net.IP
+	if deps.Filter.ResolvesHosts() {
+		var err error
+		if addrs, err = deps.Pool.LookupTarget(ctx, params); err != nil {
+			return nil, err
+		}
+	}
+	if err := deps.Filter.AllowHostAddrs(params.Host, addrs); err != nil {
 		return nil, err
 	}
+	params.Addresses = addrs
 
 	// Connect.
 	sessionID, err := deps.Pool.Connect(ctx, params)
+	var keyErr *connection.HostKeyError
+	if errors.As(err, &keyErr) {
+		// Report host key problems as they are, not buried in dial errors.
+		if err := confirmHostKey(ctx, deps, keyErr); err != nil {
+			return nil, err
+		}
+		sessionID, err = deps.Pool.Connect(ctx, params)
+		if errors.As(err, &keyErr) {
+			return nil, fmt.Errorf("host key changed again after it was trusted: %w", keyErr)
+		}
+	}
 	if err != nil {
+		if usedSaved {
+			return nil, fmt.Errorf("connect failed (using saved password): %w", err)
+		}
 		return nil, fmt.Errorf("connect failed: %w", err)
 	}
 
+	var credNote string
+	switch {
+	case usedSaved:
+		credNote = " [using saved password]"
+	case input.SaveCredentials:
+		err := credentials.Update(deps.Credentials, credKey, func(c *credentials.Credential) {
+			c.Password = params.Password
+		})
+		if err != nil {
+			credNote = fmt.Sprintf(" [warning: password not saved: %v]", err)
+		} else {
+			credNote = fmt.Sprintf(" [password saved to %s]", deps.Credentials.Name())
+		}
+	}
+
 	// Retrieve detected remote info.
 	conn, err := deps.Pool.GetConnection(ctx, sessionID)
 	if err != nil {
@@ -94,28 +200,51 @@ func HandleConnect(ctx context.Context, deps *ConnectDeps, input SSHConnectInput
 			Host:      params.Host,
 			Port:      params.Port,
 			User:      params.User,
-			Message:   fmt.Sprintf("Connected to %s@%s:%d", params.User, params.Host, params.Port),
+			Message:   fmt.Sprintf("Connected to %s@%s:%d", params.User, params.Host, params.Port) + credNote,
 		}, nil
 	}
 
 	info := conn.GetRemoteInfo()
 	message := fmt.Sprintf("Connected to %s@%s:%d", params.User, params.Host, params.Port)
+	if params.ViaSession != "" {
+		message += " via " + string(params.ViaSession)
+	}
 	if info.OS != "" {
 		detail := info.OS
 		if info.Arch != "" {
 			detail += " " + info.Arch
 		}
+		if info.Distro != "" {
+			detail += ", " + strings.TrimSpace(info.Distro+" "+info.DistroVersion)
+		}
 		if info.Shell != "" {
 			detail += ", " + info.Shell
 		}
 		if info.PackageManager != "" {
 			detail += ", pkg=" + info.PackageManager
 		}
+		if info.InitSystem != "" {
+			detail += ", init=" + info.InitSystem
+		}
 		if info.SudoNoninteractive {
 			detail += ", sudo-n"
 		}
 		message += fmt.Sprintf(" (%s)", detail)
 	}
+	keyType, fingerprint := conn.GetHostKey()
+	if fingerprint != "" {
+		message += fmt.Sprintf(", host key %s %s", keyType, fingerprint)
+	}
+	var expiresAt string
+	if exp := conn.GetExpiresAt(); !exp.IsZero() {
+		expiresAt = exp.Format(time.RFC3339)
+		message += ", session expires " + expiresAt
+	}
+	defaults := defaultsOutput(conn.GetDefaults())
+	if defaults != nil {
+		message += ", defaults " + describeDefaults(defaults)
+	}
+	message += credNote
 
 	return &SSHConnectOutput{
 		SessionID:          string(sessionID),
@@ -128,5 +257,40 @@ func HandleConnect(ctx context.Context, deps *ConnectDeps, input SSHConnectInput
 		Shell:              info.Shell,
 		PackageManager:     info.PackageManager,
 		SudoNoninteractive: info.SudoNoninteractive,
+		Distro:             info.Distro,
+		DistroVersion:      info.DistroVersion,
+		InitSystem:         info.InitSystem,
+		ExpiresAt:          expiresAt,
+		HostKeyType:        keyType,
+		HostKeyFingerprint: fingerprint,
+		Defaults:           defaults,
 	}, nil
 }
+
+// confirmHostKey asks the user whether to trust the key of a host that
+// known_hosts rejected, if --host-key-prompt allows it, and saves it on
+// approval. It returns nil only when the key was saved; otherwise it
+// returns e, noting why the user wasn't asked or what they answered.
+func confirmHostKey(ctx context.Context, deps *ConnectDeps, e *connection.HostKeyError) error {
+	switch {
+	case deps.HostKeyPrompt == config.HostKeyPromptAll:
+	case deps.HostKeyPrompt == config.HostKeyPromptUnknown && !e.Changed():
+	default:
+		return e
+	}
+	if deps.ConfirmHostKey == nil {
+		return e
+	}
+	ok, err := deps.ConfirmHostKey(ctx, e)
+	if err != nil {
+		return fmt.Errorf("%w\nCould not ask the user to confirm the key: %v", e, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w\nThe user did not trust the key.", e)
+	}
+	if err := deps.Auth.AddKnownHost(e); err != nil {
+		return fmt.Errorf("save trusted host key: %w", err)
+	}
+	log.Printf("host key %s %s for %s trusted by the user and saved to known_hosts", e.KeyType, e.Fingerprint, e.Host)
+	return nil
+}
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/credentials"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func TestHandleConnect_SaveCredentialsValidation(t *testing.T) {
+	sshCfg := &config.SSHConfig{ConfigPath: filepath.Join(t.TempDir(), "config")}
+	store := credentials.NewFileStore(filepath.Join(t.TempDir(), "credentials"), "pass")
+
+	tests := []struct {
+		name  string
+		store credentials.Store
+		input SSHConnectInput
+		want  string
+	}{
+		{"store disabled", nil, SSHConnectInput{Host: "root:pw@example.com", SaveCredentials: true}, "--credential-store"},
+		{"no password", store, SSHConnectInput{Host: "root@example.com", SaveCredentials: true}, "requires a password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := &ConnectDeps{Auth: connection.NewAuthDiscovery(sshCfg), Credentials: tt.store}
+			_, err := HandleConnect(context.Background(), deps, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleConnect_ViaSessionValidation(t *testing.T) {
+	sshCfg := &config.SSHConfig{ConfigPath: filepath.Join(t.TempDir(), "config"), ConnectionTimeout: time.Second}
+	auth := connection.NewAuthDiscovery(sshCfg)
+	filter, err := security.NewFilter(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := &ConnectDeps{
+		Pool:        connection.NewPool(sshCfg, auth),
+		Auth:        auth,
+		Filter:      filter,
+		RateLimiter: security.NewRateLimiter(60),
+	}
+
+	tests := []struct {
+		name  string
+		input SSHConnectInput
+		want  string
+	}{
+		{"unknown session", SSHConnectInput{Host: "root@db", ViaSession: "root@bastion:22"}, "via_session: session root@bastion:22 not found"},
+		{"itself", SSHConnectInput{Host: "root@db", ViaSession: "root@db:22"}, "is the session being connected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleConnect(context.Background(), deps, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleConnect_SavedCredentialsPerOwner(t *testing.T) {
+	sshCfg := &config.SSHConfig{ConfigPath: filepath.Join(t.TempDir(), "config"), ConnectionTimeout: time.Second}
+	auth := connection.NewAuthDiscovery(sshCfg)
+	filter, err := security.NewFilter(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := credentials.NewFileStore(filepath.Join(t.TempDir(), "credentials"), "pass")
+	deps := &ConnectDeps{
+		Pool:        connection.NewPool(sshCfg, auth),
+		Auth:        auth,
+		Filter:      filter,
+		RateLimiter: security.NewRateLimiter(60),
+		Credentials: store,
+	}
+
+	ctxA := connection.WithOwner(context.Background(), "tenant:a/")
+	ctxB := connection.WithOwner(context.Background(), "tenant:b/")
+	if err := store.Set(credentialKey(ctxA, "root@127.0.0.1:1"), credentials.Credential{Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing listens on port 1, so both connects fail; only tenant A's
+	// attempt may have used the saved password.
+	input := SSHConnectInput{Host: "root@127.0.0.1", Port: 1, KeyPath: filepath.Join(t.TempDir(), "none")}
+	_, err = HandleConnect(ctxA, deps, input)
+	if err == nil || !strings.Contains(err.Error(), "using saved password") {
+		t.Errorf("tenant A: error = %v, want the saved password used", err)
+	}
+	_, err = HandleConnect(ctxB, deps, input)
+	if err == nil || strings.Contains(err.Error(), "using saved password") {
+		t.Errorf("tenant B: error = %v, want tenant A's password not used", err)
+	}
+	_, err = HandleConnect(context.Background(), deps, input)
+	if err == nil || strings.Contains(err.Error(), "using saved password") {
+		t.Errorf("shared: error = %v, want tenant A's password not used", err)
+	}
+}
+
+func TestHandleConnect_DefaultsValidation(t *testing.T) {
+	sshCfg := &config.SSHConfig{ConfigPath: filepath.Join(t.TempDir(), "config")}
+	deps := &ConnectDeps{Auth: connection.NewAuthDiscovery(sshCfg)}
+
+	tests := []struct {
+		name  string
+		input SSHConnectInput
+		want  string
+	}{
+		{"bad name", SSHConnectInput{Host: "root@app", Env: map[string]string{"A-B": "1"}}, "invalid environment variable name"},
+		{"line break", SSHConnectInput{Host: "root@app", Env: map[string]string{"A": "1\nB=2"}}, "line breaks"},
+		{"PATH", SSHConnectInput{Host: "root@app", Env: map[string]string{"PATH": "/tmp"}}, "PATH can't be set"},
+		{"loader", SSHConnectInput{Host: "root@app", Env: map[string]string{"LD_PRELOAD": "/tmp/x.so"}}, "LD_PRELOAD can't be set"},
+		{"bad shell", SSHConnectInput{Host: "root@app", Shell: "python3"}, "unsupported shell"},
+		{"shell args", SSHConnectInput{Host: "root@app", Shell: "bash -x"}, "invalid shell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleConnect(context.Background(), deps, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmHostKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := &connection.HostKeyError{Host: "web1", Key: key, KeyType: key.Type(), Fingerprint: ssh.FingerprintSHA256(key)}
+	changed := *unknown
+	changed.Known = []connection.KnownHostKey{{KeyType: "ssh-rsa", Fingerprint: "SHA256:old", File: "known_hosts", Line: 1}}
+
+	yes := func(context.Context, *connection.HostKeyError) (bool, error) { return true, nil }
+	no := func(context.Context, *connection.HostKeyError) (bool, error) { return false, nil }
+	broken := func(context.Context, *connection.HostKeyError) (bool, error) {
+		return false, errors.New("client does not support elicitation")
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		confirm func(context.Context, *connection.HostKeyError) (bool, error)
+		keyErr  *connection.HostKeyError
+		want    string // "" = trusted and saved
+	}{
+		{"off", config.HostKeyPromptOff, yes, unknown, "not in known_hosts"},
+		{"unknown host", config.HostKeyPromptUnknown, yes, unknown, ""},
+		{"changed key not asked", config.HostKeyPromptUnknown, yes, &changed, "HOST KEY MISMATCH"},
+		{"changed key", config.HostKeyPromptAll, yes, &changed, ""},
+		{"no client", config.HostKeyPromptAll, nil, unknown, "not in known_hosts"},
+		{"declined", config.HostKeyPromptAll, no, unknown, "did not trust"},
+		{"elicitation failed", config.HostKeyPromptAll, broken, unknown, "does not support elicitation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "known_hosts")
+			deps := &ConnectDeps{
+				Auth:           connection.NewAuthDiscovery(&config.SSHConfig{KnownHostsPath: path, VerifyHostKey: true, HostKeyPrompt: tt.mode}),
+				HostKeyPrompt:  tt.mode,
+				ConfirmHostKey: tt.confirm,
+			}
+			err := confirmHostKey(context.Background(), deps, tt.keyErr)
+			_, statErr := os.Stat(path)
+			if tt.want == "" {
+				if err != nil || statErr != nil {
+					t.Fatalf("err = %v, known_hosts: %v", err, statErr)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+			var keyErr *connection.HostKeyError
+			if !errors.As(err, &keyErr) {
+				t.Error("error should wrap the HostKeyError")
+			}
+			if statErr == nil {
+				t.Error("known_hosts written without approval")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// exitCommandNotFound is the shell's exit status for a missing command.
+const exitCommandNotFound = 127
+
+// CopyDeps holds dependencies for the ssh_copy tool handler.
+type CopyDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// HandleCopy implements the ssh_copy tool. It copies on the remote host
+// with cp -a, and falls back to streaming through SFTP when cp cannot run
+// there (Windows, no cp, or cp not allowed by the command filter).
+func HandleCopy(ctx context.Context, deps *CopyDeps, input SSHCopyInput) (*SSHCopyOutput, error) {
+	if input.SourcePath == "" || input.DestPath == "" {
+		return nil, fmt.Errorf("source_path and dest_path are required")
+	}
+	if err := security.ValidatePath(input.SourcePath); err != nil {
+		return nil, fmt.Errorf("invalid source path: %w", err)
+	}
+	if err := security.ValidatePath(input.DestPath); err != nil {
+		return nil, fmt.Errorf("invalid destination path: %w", err)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	src := path.Clean(expandRemotePath(conn, sc, input.SourcePath))
+	dst := path.Clean(expandRemotePath(conn, sc, input.DestPath))
+	if err := checkCopyPaths(src, dst); err != nil {
+		return nil, err
+	}
+
+	srcInfo, err := sc.Lstat(src)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("stat source %s: %w", src, err)
+	}
+	if dstInfo, err := sc.Lstat(dst); err == nil {
+		switch {
+		case dstInfo.IsDir():
+			return nil, fmt.Errorf("destination %s is an existing directory; give the full path of the copy", dst)
+		case srcInfo.IsDir():
+			return nil, fmt.Errorf("cannot overwrite file %s with a directory", dst)
+		case !input.Overwrite:
+			return nil, fmt.Errorf("destination %s already exists (set overwrite to replace it)", dst)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("stat destination %s: %w", dst, err)
+	}
+	if err := sc.MkdirAll(path.Dir(dst)); err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("create parent directories: %w", err)
+	}
+
+	out := &SSHCopyOutput{Source: src, Destination: dst, Directory: srcInfo.IsDir()}
+
+	if conn.GetRemoteInfo().OS != "Windows" {
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "cp", "-a", "--", src, dst)
+		if err == nil {
+			res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+			if err != nil {
+				conn.SetLastError(err)
+				return nil, err
+			}
+			if res.ExitCode == 0 && !res.TimedOut {
+				out.Method = "cp"
+				return out, nil
+			}
+			if res.TimedOut || res.ExitCode != exitCommandNotFound {
+				err := remoteFailure("cp", res)
+				conn.SetLastError(err)
+				return nil, err
+			}
+		}
+	}
+
+	files, n, err := sshclient.CopyRemote(sc, src, dst)
+	conn.AddBytesDownloaded(n)
+	conn.AddBytesUploaded(n)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("copy via SFTP: %w", err)
+	}
+	out.Method = "sftp"
+	out.Files = files
+	out.Bytes = n
+	return out, nil
+}
+
+// checkCopyPaths rejects copying a path onto itself or a directory into its
+// own subtree.
+func checkCopyPaths(src, dst string) error {
+	if src == dst {
+		return fmt.Errorf("source and destination are the same path: %s", src)
+	}
+	if strings.HasPrefix(dst, strings.TrimSuffix(src, "/")+"/") {
+		return fmt.Errorf("cannot copy %s into itself (%s)", src, dst)
+	}
+	return nil
+}
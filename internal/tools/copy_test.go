@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckCopyPaths(t *testing.T) {
+	tests := []struct {
+		src, dst string
+		wantErr  string
+	}{
+		{"/srv/app", "/srv/app-backup", ""},
+		{"/srv/app", "/srv/backup/app", ""},
+		{"/etc/app.conf", "/etc/app.conf.orig", ""},
+		{"/srv/app", "/srv/app", "same path"},
+		{"/srv/app", "/srv/app/copy", "into itself"},
+		{"/", "/copy", "into itself"},
+	}
+	for _, tt := range tests {
+		err := checkCopyPaths(tt.src, tt.dst)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkCopyPaths(%q, %q) = %v", tt.src, tt.dst, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkCopyPaths(%q, %q) = %v, want %q", tt.src, tt.dst, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHandleCopy_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input SSHCopyInput
+		want  string
+	}{
+		{"missing source", SSHCopyInput{SessionID: "s", DestPath: "/tmp/b"}, "required"},
+		{"missing dest", SSHCopyInput{SessionID: "s", SourcePath: "/tmp/a"}, "required"},
+		{"traversal", SSHCopyInput{SessionID: "s", SourcePath: "/tmp/a", DestPath: "/tmp/../etc/b"}, "invalid destination path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleCopy(context.Background(), &CopyDeps{}, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSSHCopyOutput_Text(t *testing.T) {
+	out := SSHCopyOutput{Source: "/a", Destination: "/b", Method: "cp"}
+	if got := out.Text(); !strings.Contains(got, "cp -a") {
+		t.Errorf("cp Text() = %q", got)
+	}
+	out = SSHCopyOutput{Source: "/a", Destination: "/b", Method: "sftp", Files: 2, Bytes: 10}
+	if got := out.Text(); got != "Copied /a to /b via SFTP (2 file(s), 10 bytes)" {
+		t.Errorf("sftp Text() = %q", got)
+	}
+}
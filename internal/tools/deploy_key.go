@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// DeployKeyDeps holds dependencies for the ssh_deploy_key tool handler.
+type DeployKeyDeps struct {
+	Pool        *connection.Pool
+	LocalDirs   []config.LocalDir
+	RateLimiter *security.RateLimiter
+}
+
+// HandleDeployKey implements the ssh_deploy_key tool (an ssh-copy-id equivalent).
+// The key is appended to ~/.ssh/authorized_keys on the remote host; ~/.ssh is
+// created with mode 0700 and authorized_keys is kept at mode 0600.
+func HandleDeployKey(ctx context.Context, deps *DeployKeyDeps, input SSHDeployKeyInput) (*SSHDeployKeyOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if input.PublicKey != "" && input.PublicKeyPath != "" {
+		return nil, fmt.Errorf("only one of public_key or public_key_path can be provided, not both")
+	}
+
+	keyLine := strings.TrimSpace(input.PublicKey)
+	if input.PublicKeyPath != "" {
+		if err := security.ValidateLocalAccess(input.PublicKeyPath, deps.LocalDirs, false); err != nil {
+			return nil, fmt.Errorf("invalid public key path: %w", err)
+		}
+		data, err := os.ReadFile(input.PublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+		keyLine = strings.TrimSpace(string(data))
+	}
+	if keyLine == "" {
+		return nil, fmt.Errorf("either public_key or public_key_path must be provided")
+	}
+
+	pubKey, _, _, rest, err := ssh.ParseAuthorizedKey([]byte(keyLine))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("public key must contain exactly one key")
+	}
+	if strings.ContainsAny(keyLine, "\r\n") {
+		return nil, fmt.Errorf("public key must be a single line")
+	}
+	fingerprint := ssh.FingerprintSHA256(pubKey)
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	home := expandRemotePath(conn, sc, ".")
+	sshDir := path.Join(home, ".ssh")
+	authKeysPath := path.Join(sshDir, "authorized_keys")
+
+	if err := sc.MkdirAll(sshDir); err != nil {
+		return nil, fmt.Errorf("create %s: %w", sshDir, err)
+	}
+	if err := sc.Chmod(sshDir, 0700); err != nil {
+		return nil, fmt.Errorf("chmod %s: %w", sshDir, err)
+	}
+
+	existing, err := sshclient.ReadFile(ctx, sc, authKeysPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read %s: %w", authKeysPath, err)
+	}
+
+	if authorizedKeysContains(existing, pubKey) {
+		return &SSHDeployKeyOutput{
+			AuthorizedKeysPath: authKeysPath,
+			Fingerprint:        fingerprint,
+			AlreadyPresent:     true,
+			Message:            fmt.Sprintf("Key %s is already present in %s", fingerprint, authKeysPath),
+		}, nil
+	}
+
+	f, err := sc.OpenFile(authKeysPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", authKeysPath, err)
+	}
+	entry := keyLine + "\n"
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		entry = "\n" + entry
+	}
+	if _, err := f.Write([]byte(entry)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("append to %s: %w", authKeysPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("close %s: %w", authKeysPath, err)
+	}
+	if err := sc.Chmod(authKeysPath, 0600); err != nil {
+		return nil, fmt.Errorf("chmod %s: %w", authKeysPath, err)
+	}
+
+	return &SSHDeployKeyOutput{
+		AuthorizedKeysPath: authKeysPath,
+		Fingerprint:        fingerprint,
+		Message:            fmt.Sprintf("Added key %s to %s", fingerprint, authKeysPath),
+	}, nil
+}
+
+// authorizedKeysContains reports whether data (authorized_keys content)
+// already contains key, comparing key material and ignoring options/comments.
+func authorizedKeysContains(data []byte, key ssh.PublicKey) bool {
+	want := key.Marshal()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		existing, _, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(existing.Marshal(), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func newTestAuthorizedKey(t *testing.T) (ssh.PublicKey, string) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("convert key: %v", err)
+	}
+	return sshPub, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+func TestAuthorizedKeysContains(t *testing.T) {
+	key, line := newTestAuthorizedKey(t)
+	_, otherLine := newTestAuthorizedKey(t)
+
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"empty", "", false},
+		{"other key only", otherLine + "\n", false},
+		{"exact line", otherLine + "\n" + line + "\n", true},
+		{"different comment", line + " someone@host\n", true},
+		{"with options", `no-pty,command="true" ` + line + "\n", true},
+		{"commented out", "# " + line + "\n", false},
+		{"no trailing newline", line, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authorizedKeysContains([]byte(tt.data), key); got != tt.want {
+				t.Errorf("authorizedKeysContains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDeployKey_InputValidation(t *testing.T) {
+	_, line := newTestAuthorizedKey(t)
+	deps := &DeployKeyDeps{LocalDirs: []config.LocalDir{{Path: t.TempDir()}}}
+
+	tests := []struct {
+		name  string
+		input SSHDeployKeyInput
+		want  string
+	}{
+		{"missing session", SSHDeployKeyInput{PublicKey: line}, "session_id is required"},
+		{"missing key", SSHDeployKeyInput{SessionID: "s"}, "must be provided"},
+		{"both key sources", SSHDeployKeyInput{SessionID: "s", PublicKey: line, PublicKeyPath: "/tmp/k.pub"}, "not both"},
+		{"invalid key", SSHDeployKeyInput{SessionID: "s", PublicKey: "not-a-key"}, "invalid public key"},
+		{"multiple keys", SSHDeployKeyInput{SessionID: "s", PublicKey: line + "\n" + line}, "exactly one key"},
+		{"path outside base dir", SSHDeployKeyInput{SessionID: "s", PublicKeyPath: "/etc/passwd"}, "invalid public key path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleDeployKey(context.Background(), deps, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
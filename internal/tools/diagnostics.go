@@ -0,0 +1,244 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// DiagnosticsDeps holds dependencies for the ssh_collect_diagnostics tool handler.
+type DiagnosticsDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+	LocalDirs   []config.LocalDir
+}
+
+const (
+	defaultDiagnosticItemSize = 1 << 20  // per-item cap when max_item_size is unset
+	maxDiagnosticItemSize     = 16 << 20 // upper bound for max_item_size
+	maxDiagnosticFiles        = 50       // files per bundle
+)
+
+// diagnosticCommand is a built-in item of ssh_collect_diagnostics. With
+// tail set, the end of an oversized output is kept rather than the start.
+type diagnosticCommand struct {
+	program string
+	args    []string
+	tail    bool
+}
+
+// diagnosticCommands are the items ssh_collect_diagnostics can run, by name.
+var diagnosticCommands = map[string]diagnosticCommand{
+	"uname":   {program: "uname", args: []string{"-a"}},
+	"uptime":  {program: "uptime"},
+	"df":      {program: "df", args: []string{"-h"}},
+	"free":    {program: "free", args: []string{"-m"}},
+	"ps":      {program: "ps", args: []string{"aux"}},
+	"dmesg":   {program: "dmesg", tail: true},
+	"journal": {program: "journalctl", args: []string{"--no-pager", "--lines=2000"}, tail: true},
+	"network": {program: "ip", args: []string{"addr"}},
+	"sockets": {program: "ss", args: []string{"-tulpn"}},
+	"mounts":  {program: "mount"},
+}
+
+// defaultDiagnosticItems and defaultDiagnosticFiles make up the bundle
+// when neither items nor files are given.
+var (
+	defaultDiagnosticItems = []string{"uname", "uptime", "df", "free", "ps", "dmesg", "journal", "network"}
+	defaultDiagnosticFiles = []string{"/etc/os-release"}
+)
+
+// bundleNameUnsafe matches characters replaced in bundle entry names.
+var bundleNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// HandleCollectDiagnostics implements the ssh_collect_diagnostics tool. It
+// runs the chosen built-in commands and reads the given files (the end of
+// each, through tail -c) on the host, caps every item at max_item_size, and
+// writes them with a manifest.json to a local tar.gz. An item that fails is
+// recorded in the manifest instead of failing the call.
+func HandleCollectDiagnostics(ctx context.Context, deps *DiagnosticsDeps, input SSHCollectDiagnosticsInput) (*SSHCollectDiagnosticsOutput, error) {
+	if input.LocalPath == "" {
+		return nil, fmt.Errorf("local_path is required")
+	}
+	if err := security.ValidateLocalAccess(input.LocalPath, deps.LocalDirs, true); err != nil {
+		return nil, fmt.Errorf("invalid local path: %w", err)
+	}
+	maxSize := input.MaxItemSize
+	switch {
+	case maxSize < 0 || maxSize > maxDiagnosticItemSize:
+		return nil, fmt.Errorf("max_item_size must be between 0 and %d", maxDiagnosticItemSize)
+	case maxSize == 0:
+		maxSize = defaultDiagnosticItemSize
+	}
+	items, files := input.Items, input.Files
+	if len(items) == 0 && len(files) == 0 {
+		items, files = defaultDiagnosticItems, defaultDiagnosticFiles
+	}
+	for _, name := range items {
+		if _, ok := diagnosticCommands[name]; !ok {
+			return nil, fmt.Errorf("unknown item %q (must be one of %s)", name, strings.Join(diagnosticItemNames(), ", "))
+		}
+	}
+	if len(files) > maxDiagnosticFiles {
+		return nil, fmt.Errorf("at most %d files per bundle", maxDiagnosticFiles)
+	}
+	for _, f := range files {
+		if err := security.ValidatePath(f); err != nil {
+			return nil, fmt.Errorf("invalid file %q: %w", f, err)
+		}
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_collect_diagnostics needs a POSIX host")
+	}
+
+	// collect runs one item and stores its (capped) output.
+	var entries []bundleEntry
+	var report []DiagnosticItem
+	collect := func(item DiagnosticItem, entry string, tail bool, program string, args ...string) {
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, program, args...)
+		if err == nil {
+			var res *remoteResult
+			if res, err = runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout); err == nil {
+				data, truncated := capDiagnostic([]byte(res.Stdout), maxSize, tail)
+				item.Size, item.Truncated = len(data), truncated
+				if len(data) > 0 {
+					item.Entry = entry
+					entries = append(entries, bundleEntry{entry, data})
+				}
+				if res.TimedOut || res.ExitCode != 0 {
+					err = remoteFailure(program, res)
+				}
+			}
+		}
+		if err != nil {
+			item.Error = err.Error()
+		}
+		report = append(report, item)
+	}
+	for _, name := range items {
+		c := diagnosticCommands[name]
+		item := DiagnosticItem{Name: name, Command: strings.Join(append([]string{c.program}, c.args...), " ")}
+		collect(item, "commands/"+name+".txt", c.tail, c.program, c.args...)
+	}
+	for _, f := range files {
+		// One byte over the cap tells a file that was cut from one that fits.
+		collect(DiagnosticItem{Name: f, Path: f}, "files/"+bundleFileName(f), true,
+			"tail", "-c", strconv.Itoa(maxSize+1), "--", f)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	collected := time.Now().UTC()
+	root := "diagnostics-" + bundleNameUnsafe.ReplaceAllString(input.SessionID, "_") + "-" + collected.Format("20060102T150405Z")
+	manifest, err := json.MarshalIndent(diagnosticManifest{SessionID: input.SessionID, CollectedAt: collected.Format(time.RFC3339), Items: report}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, bundleEntry{"manifest.json", append(manifest, '\n')})
+	bundle, err := writeBundle(root, collected, entries)
+	if err != nil {
+		return nil, err
+	}
+	n, err := writeLocalAtomic(input.LocalPath, bytes.NewReader(bundle))
+	if err != nil {
+		return nil, fmt.Errorf("write %s: %w", input.LocalPath, err)
+	}
+	conn.AddBytesDownloaded(n)
+
+	out := &SSHCollectDiagnosticsOutput{LocalPath: input.LocalPath, Bytes: n, Items: report}
+	failed := 0
+	for _, it := range report {
+		if it.Error != "" {
+			failed++
+		}
+	}
+	out.Message = fmt.Sprintf("Collected %d item(s) from %s into %s (%d bytes)", len(report)-failed, input.SessionID, input.LocalPath, n)
+	if failed > 0 {
+		out.Message += fmt.Sprintf("; %d failed", failed)
+	}
+	return out, nil
+}
+
+// diagnosticItemNames returns the built-in item names, sorted.
+func diagnosticItemNames() []string {
+	names := make([]string, 0, len(diagnosticCommands))
+	for name := range diagnosticCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// capDiagnostic cuts data to max bytes, keeping the end with tail and the
+// start otherwise, and reports whether it cut anything.
+func capDiagnostic(data []byte, max int, tail bool) ([]byte, bool) {
+	if len(data) <= max {
+		return data, false
+	}
+	if tail {
+		return data[len(data)-max:], true
+	}
+	return data[:max], true
+}
+
+// bundleFileName maps a remote path to an entry name: /var/log/syslog
+// becomes var_log_syslog.
+func bundleFileName(p string) string {
+	return strings.Trim(bundleNameUnsafe.ReplaceAllString(strings.ReplaceAll(p, "/", "_"), "_"), "_.")
+}
+
+// diagnosticManifest is the manifest.json of a diagnostics bundle.
+type diagnosticManifest struct {
+	SessionID   string           `json:"session_id"`
+	CollectedAt string           `json:"collected_at"`
+	Items       []DiagnosticItem `json:"items"`
+}
+
+// bundleEntry is a file in a diagnostics bundle.
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// writeBundle returns a tar.gz with the entries under the root directory.
+func writeBundle(root string, modTime time.Time, entries []bundleEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: root + "/" + e.name, Mode: 0o644, Size: int64(len(e.data)), ModTime: modTime, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
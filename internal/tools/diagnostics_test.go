@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestHandleCollectDiagnostics_Validation(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "bundle.tar.gz")
+	manyFiles := make([]string, maxDiagnosticFiles+1)
+	for i := range manyFiles {
+		manyFiles[i] = "/var/log/syslog"
+	}
+	tests := []struct {
+		input SSHCollectDiagnosticsInput
+		want  string
+	}{
+		{SSHCollectDiagnosticsInput{}, "local_path is required"},
+		{SSHCollectDiagnosticsInput{LocalPath: "/etc/bundle.tar.gz"}, "invalid local path"},
+		{SSHCollectDiagnosticsInput{LocalPath: local, MaxItemSize: -1}, "max_item_size"},
+		{SSHCollectDiagnosticsInput{LocalPath: local, MaxItemSize: maxDiagnosticItemSize + 1}, "max_item_size"},
+		{SSHCollectDiagnosticsInput{LocalPath: local, Items: []string{"df", "rm"}}, `unknown item "rm"`},
+		{SSHCollectDiagnosticsInput{LocalPath: local, Files: manyFiles}, "at most"},
+		{SSHCollectDiagnosticsInput{LocalPath: local, Files: []string{"/var/log/\x00x"}}, "invalid file"},
+	}
+	deps := &DiagnosticsDeps{LocalDirs: []config.LocalDir{{Path: dir}}}
+	for _, tt := range tests {
+		_, err := HandleCollectDiagnostics(context.Background(), deps, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDiagnosticDefaults(t *testing.T) {
+	for _, name := range defaultDiagnosticItems {
+		if _, ok := diagnosticCommands[name]; !ok {
+			t.Errorf("default item %q is not a built-in", name)
+		}
+	}
+	if names := diagnosticItemNames(); len(names) != len(diagnosticCommands) || names[0] != "df" {
+		t.Errorf("diagnosticItemNames = %v", names)
+	}
+}
+
+func TestCapDiagnostic(t *testing.T) {
+	data := []byte("0123456789")
+	tests := []struct {
+		max       int
+		tail      bool
+		want      string
+		truncated bool
+	}{
+		{10, false, "0123456789", false},
+		{4, false, "0123", true},
+		{4, true, "6789", true},
+	}
+	for _, tt := range tests {
+		got, truncated := capDiagnostic(data, tt.max, tt.tail)
+		if string(got) != tt.want || truncated != tt.truncated {
+			t.Errorf("capDiagnostic(%d, %v) = %q, %v", tt.max, tt.tail, got, truncated)
+		}
+	}
+}
+
+func TestBundleFileName(t *testing.T) {
+	tests := map[string]string{
+		"/var/log/syslog":       "var_log_syslog",
+		"/etc/nginx/nginx.conf": "etc_nginx_nginx.conf",
+		"~/app/log file.txt":    "app_log_file.txt",
+	}
+	for in, want := range tests {
+		if got := bundleFileName(in); got != want {
+			t.Errorf("bundleFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	data, err := writeBundle("diagnostics-x", time.Unix(1704207845, 0), []bundleEntry{
+		{"commands/df.txt", []byte("Filesystem Size\n")},
+		{"manifest.json", []byte("{}\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		names = append(names, hdr.Name+"="+string(body))
+	}
+	want := "diagnostics-x/commands/df.txt=Filesystem Size\n,diagnostics-x/manifest.json={}\n"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("bundle entries = %q, want %q", got, want)
+	}
+}
+
+func TestSSHCollectDiagnosticsOutput_Text(t *testing.T) {
+	out := SSHCollectDiagnosticsOutput{
+		Message: "Collected 1 item(s) from root@web1:22 into /tmp/b.tar.gz (512 bytes); 1 failed",
+		Items: []DiagnosticItem{
+			{Name: "journal", Size: 1024, Truncated: true},
+			{Name: "dmesg", Error: "dmesg failed (exit code 1): Operation not permitted"},
+		},
+	}
+	want := "Collected 1 item(s) from root@web1:22 into /tmp/b.tar.gz (512 bytes); 1 failed\n" +
+		"  journal: 1024 bytes (truncated)\n" +
+		"  dmesg: 0 bytes, error: dmesg failed (exit code 1): Operation not permitted"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxDiffEdits bounds the edit distance unifiedDiff will compute; the Myers
+// trace it keeps grows quadratically with the number of changed lines.
+const maxDiffEdits = 2000
+
+// errDiffTooLarge is returned when two texts differ by more than maxDiffEdits lines.
+var errDiffTooLarge = errors.New("files differ in too many lines to diff")
+
+// diffOp is one line of an edit script: ' ' (keep), '-' (delete), '+' (insert).
+type diffOp struct {
+	kind byte
+	text string // line including its trailing newline, if any
+}
+
+// diffStats counts the changed lines of a diff.
+type diffStats struct {
+	Added, Removed int
+}
+
+// splitLines splits s into lines that keep their "\n", so a missing final
+// newline is a difference like any other.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// myersDiff returns the shortest edit script turning a into b.
+func myersDiff(a, b []string) ([]diffOp, error) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds v[-d..d] as it was before step d.
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace), nil
+			}
+		}
+	}
+	return nil, errDiffTooLarge
+}
+
+// backtrackDiff walks the Myers trace back from (len(a), len(b)).
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		vd := trace[d] // index k+d holds v[k]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && vd[k-1+d] < vd[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := vd[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff returns a unified diff (as produced by `diff -u`) turning a
+// into b, with the given number of context lines. It returns "" if the texts
+// are identical.
+func unifiedDiff(aName, bName, a, b string, context int) (string, diffStats, error) {
+	var stats diffStats
+	if a == b {
+		return "", stats, nil
+	}
+	ops, err := myersDiff(splitLines(a), splitLines(b))
+	if err != nil {
+		return "", stats, err
+	}
+
+	// aPos[i]/bPos[i] are the lines of a/b consumed before ops[i].
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		switch op.kind {
+		case ' ':
+			aPos[i+1]++
+			bPos[i+1]++
+		case '-':
+			aPos[i+1]++
+			stats.Removed++
+		case '+':
+			bPos[i+1]++
+			stats.Added++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is within 2*context lines.
+		start := max(0, i-context)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(len(ops), end+context)
+
+		aStart, aCount := aPos[start], aPos[end]-aPos[start]
+		bStart, bCount := bPos[start], bPos[end]-bPos[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String(), stats, nil
+}
+
+// hunkRange formats one side of a hunk header: "start,count", with the
+// count omitted when it is 1 and start pointing before the hunk when empty.
+func hunkRange(consumed, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", consumed)
+	case 1:
+		return fmt.Sprintf("%d", consumed+1)
+	default:
+		return fmt.Sprintf("%d,%d", consumed+1, count)
+	}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	got, stats, err := unifiedDiff("a.conf", "b.conf", a, b, 2)
+	if err != nil {
+		t.Fatalf("unifiedDiff: %v", err)
+	}
+	want := `--- a.conf
++++ b.conf
+@@ -1,5 +1,5 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+@@ -9,2 +9,3 @@
+ nine
+ ten
++eleven
+`
+	if got != want {
+		t.Errorf("unifiedDiff mismatch:\n got:\n%s\nwant:\n%s", got, want)
+	}
+	if stats.Added != 2 || stats.Removed != 1 {
+		t.Errorf("stats = %+v, want 2 added, 1 removed", stats)
+	}
+}
+
+func TestUnifiedDiff_MergesNearbyHunks(t *testing.T) {
+	got, _, err := unifiedDiff("a", "b", "1\n2\n3\n4\n5\n", "x\n2\n3\n4\ny\n", 3)
+	if err != nil {
+		t.Fatalf("unifiedDiff: %v", err)
+	}
+	if n := strings.Count(got, "@@ -"); n != 1 {
+		t.Errorf("expected a single hunk, got %d:\n%s", n, got)
+	}
+}
+
+func TestUnifiedDiff_EdgeCases(t *testing.T) {
+	if got, _, _ := unifiedDiff("a", "b", "same\n", "same\n", 3); got != "" {
+		t.Errorf("expected empty diff for identical input, got %q", got)
+	}
+
+	got, _, _ := unifiedDiff("/dev/null", "new", "", "hello\n", 3)
+	if !strings.Contains(got, "@@ -0,0 +1 @@\n+hello\n") {
+		t.Errorf("unexpected diff for new file:\n%s", got)
+	}
+
+	got, _, _ = unifiedDiff("a", "b", "x\nend", "x\nend\n", 3)
+	want := "@@ -1,2 +1,2 @@\n x\n-end\n\\ No newline at end of file\n+end\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("missing newline marker not reported:\n%s", got)
+	}
+}
+
+func TestMyersDiff_TooLarge(t *testing.T) {
+	a := strings.Repeat("a\n", maxDiffEdits)
+	b := strings.Repeat("b\n", maxDiffEdits)
+	if _, _, err := unifiedDiff("a", "b", a, b, 3); err != errDiffTooLarge {
+		t.Errorf("expected errDiffTooLarge, got %v", err)
+	}
+}
+
+func TestSSHDiffOutput_Text(t *testing.T) {
+	if got := (SSHDiffOutput{OldPath: "a", NewPath: "b", Identical: true}).Text(); !strings.Contains(got, "No differences") {
+		t.Errorf("identical Text() = %q", got)
+	}
+	if got := (SSHDiffOutput{OldPath: "a", NewPath: "b", Binary: true}).Text(); got != "Binary files a and b differ" {
+		t.Errorf("binary Text() = %q", got)
+	}
+	out := SSHDiffOutput{Added: 1, Removed: 2, Diff: "--- a\n+++ b\n"}
+	if got := out.Text(); !strings.HasPrefix(got, "1 line(s) added, 2 removed\n--- a") {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestHandleDiff_Validation(t *testing.T) {
+	deps := &FileDiffDeps{}
+	content := "x"
+	negative := -1
+	for _, tc := range []struct {
+		input SSHDiffInput
+		want  string
+	}{
+		{SSHDiffInput{SessionID: "s", RemotePath: "/etc/hosts"}, "exactly one"},
+		{SSHDiffInput{SessionID: "s", RemotePath: "/etc/hosts", LocalPath: "hosts", Content: &content}, "exactly one"},
+		{SSHDiffInput{SessionID: "s", RemotePath: "../etc/hosts", Content: &content}, "invalid remote path"},
+		{SSHDiffInput{SessionID: "s", RemotePath: "/etc/hosts", OtherRemotePath: "/tmp/../x"}, "invalid other remote path"},
+		{SSHDiffInput{SessionID: "s", RemotePath: "/etc/hosts", Content: &content, ContextLines: &negative}, "context_lines"},
+	} {
+		_, err := HandleDiff(context.Background(), deps, tc.input)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("input %+v: expected error containing %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
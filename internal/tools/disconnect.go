@@ -16,20 +16,21 @@ type DisconnectDeps struct {
 }
 
 // HandleDisconnect implements the ssh_disconnect tool.
-func HandleDisconnect(_ context.Context, deps *DisconnectDeps, input SSHDisconnectInput) (*SSHDisconnectOutput, error) {
+func HandleDisconnect(ctx context.Context, deps *DisconnectDeps, input SSHDisconnectInput) (*SSHDisconnectOutput, error) {
 	sessionID := connection.SessionID(input.SessionID)
+	owner := connection.OwnerFrom(ctx)
 
 	// Close all terminals for this session before disconnecting.
 	if deps.TermPool != nil {
-		deps.TermPool.CloseBySession(connection.SessionID(input.SessionID))
+		deps.TermPool.CloseBySession(owner, sessionID)
 	}
 
 	// Close all tunnels for this session before disconnecting.
 	if deps.TunnelPool != nil {
-		deps.TunnelPool.CloseBySession(input.SessionID)
+		deps.TunnelPool.CloseBySession(owner, input.SessionID)
 	}
 
-	if err := deps.Pool.Disconnect(sessionID); err != nil {
+	if err := deps.Pool.Disconnect(ctx, sessionID); err != nil {
 		return nil, fmt.Errorf("disconnect failed: %w", err)
 	}
 
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// defaultDockerLogTail is the number of log lines returned when tail is unset.
+const defaultDockerLogTail = 100
+
+// dockerRefPattern matches container names/IDs and image references. The
+// leading character rule also prevents arguments being parsed as options.
+var dockerRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/@-]*$`)
+
+// DockerDeps holds dependencies for the ssh_docker_* tool handlers.
+type DockerDeps struct {
+	Pool          *connection.Pool
+	Filter        *security.Filter
+	RateLimiter   *security.RateLimiter
+	Config        *config.SSHConfig
+	MaxOutputSize int
+}
+
+// dockerCommand builds a filtered docker CLI invocation.
+func dockerCommand(deps *DockerDeps, sudo bool, args ...string) (string, error) {
+	return buildCLICommand(deps.Filter, deps.Config, sudo, "docker", args...)
+}
+
+// validateDockerRef checks a container or image reference.
+func validateDockerRef(kind, ref string) error {
+	if ref == "" {
+		return fmt.Errorf("%s is required", kind)
+	}
+	if !dockerRefPattern.MatchString(ref) {
+		return fmt.Errorf("invalid %s %q", kind, ref)
+	}
+	return nil
+}
+
+// runDocker runs a docker command, treating non-zero exits as errors.
+func runDocker(ctx context.Context, deps *DockerDeps, sessionID, what, cmd string) (*remoteResult, error) {
+	return runCLICommand(ctx, deps.Pool, deps.RateLimiter, sessionID, what, cmd, deps.Config.CommandTimeout)
+}
+
+// HandleDockerPs implements the ssh_docker_ps tool.
+func HandleDockerPs(ctx context.Context, deps *DockerDeps, input SSHDockerPsInput) (*SSHDockerPsOutput, error) {
+	args := []string{"ps", "--no-trunc", "--format", "{{json .}}"}
+	if input.All {
+		args = append(args, "--all")
+	}
+	for _, f := range input.Filters {
+		if f == "" || strings.HasPrefix(f, "-") || !strings.Contains(f, "=") {
+			return nil, fmt.Errorf("invalid filter %q (expected key=value, e.g. status=running)", f)
+		}
+		args = append(args, "--filter", f)
+	}
+	cmd, err := dockerCommand(deps, input.Sudo, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runDocker(ctx, deps, input.SessionID, "docker ps", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		ID        string `json:"ID"`
+		Names     string `json:"Names"`
+		Image     string `json:"Image"`
+		Command   string `json:"Command"`
+		State     string `json:"State"`
+		Status    string `json:"Status"`
+		Ports     string `json:"Ports"`
+		CreatedAt string `json:"CreatedAt"`
+	}
+	if err := decodeJSONLines(res.Stdout, &raw); err != nil {
+		return nil, fmt.Errorf("parse docker ps output: %w", err)
+	}
+	out := &SSHDockerPsOutput{Containers: make([]DockerContainer, 0, len(raw))}
+	for _, r := range raw {
+		out.Containers = append(out.Containers, DockerContainer{
+			ID:        shortDockerID(r.ID),
+			Name:      r.Names,
+			Image:     r.Image,
+			Command:   strings.Trim(r.Command, `"`),
+			State:     r.State,
+			Status:    r.Status,
+			Ports:     r.Ports,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// HandleDockerImages implements the ssh_docker_images tool.
+func HandleDockerImages(ctx context.Context, deps *DockerDeps, input SSHDockerImagesInput) (*SSHDockerImagesOutput, error) {
+	args := []string{"images", "--format", "{{json .}}"}
+	if input.All {
+		args = append(args, "--all")
+	}
+	if input.Reference != "" {
+		if err := validateDockerRef("reference", input.Reference); err != nil {
+			return nil, err
+		}
+		args = append(args, input.Reference)
+	}
+	cmd, err := dockerCommand(deps, input.Sudo, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runDocker(ctx, deps, input.SessionID, "docker images", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		ID           string `json:"ID"`
+		Repository   string `json:"Repository"`
+		Tag          string `json:"Tag"`
+		Size         string `json:"Size"`
+		CreatedSince string `json:"CreatedSince"`
+	}
+	if err := decodeJSONLines(res.Stdout, &raw); err != nil {
+		return nil, fmt.Errorf("parse docker images output: %w", err)
+	}
+	out := &SSHDockerImagesOutput{Images: make([]DockerImage, 0, len(raw))}
+	for _, r := range raw {
+		out.Images = append(out.Images, DockerImage{
+			ID:         shortDockerID(r.ID),
+			Repository: r.Repository,
+			Tag:        r.Tag,
+			Size:       r.Size,
+			Created:    r.CreatedSince,
+		})
+	}
+	return out, nil
+}
+
+// HandleDockerInspect implements the ssh_docker_inspect tool.
+func HandleDockerInspect(ctx context.Context, deps *DockerDeps, input SSHDockerInspectInput) (*SSHDockerInspectOutput, error) {
+	if err := validateDockerRef("target", input.Target); err != nil {
+		return nil, err
+	}
+	cmd, err := dockerCommand(deps, input.Sudo, "inspect", input.Target)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runDocker(ctx, deps, input.SessionID, "docker inspect", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []json.RawMessage
+	if err := json.Unmarshal([]byte(res.Stdout), &objects); err != nil || len(objects) == 0 {
+		return nil, fmt.Errorf("parse docker inspect output: unexpected format")
+	}
+	return &SSHDockerInspectOutput{
+		Target: input.Target,
+		JSON:   TruncateOutput(strings.TrimSpace(res.Stdout), deps.MaxOutputSize),
+	}, nil
+}
+
+// HandleDockerLogs implements the ssh_docker_logs tool.
+func HandleDockerLogs(ctx context.Context, deps *DockerDeps, input SSHDockerLogsInput) (*SSHDockerLogsOutput, error) {
+	if err := validateDockerRef("container", input.Container); err != nil {
+		return nil, err
+	}
+	if input.Tail < 0 {
+		return nil, fmt.Errorf("invalid tail: %d (must be non-negative)", input.Tail)
+	}
+	tail := input.Tail
+	if tail == 0 {
+		tail = defaultDockerLogTail
+	}
+	args := []string{"logs", "--tail", fmt.Sprint(tail)}
+	if input.Since != "" {
+		if strings.HasPrefix(input.Since, "-") {
+			return nil, fmt.Errorf("invalid since %q", input.Since)
+		}
+		args = append(args, "--since", input.Since)
+	}
+	if input.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	args = append(args, input.Container)
+
+	cmd, err := dockerCommand(deps, input.Sudo, args...)
+	if err != nil {
+		return nil, err
+	}
+	// docker logs replays the container's stderr on stderr; merge the streams.
+	res, err := runDocker(ctx, deps, input.SessionID, "docker logs", cmd+" 2>&1")
+	if err != nil {
+		return nil, err
+	}
+
+	logs := decodeLogs(deps.Config, res.Stdout)
+	if deps.Config.StripANSI {
+		logs = sanitizeOutput(logs)
+	}
+	lines := strings.Count(logs, "\n")
+	if logs != "" && !strings.HasSuffix(logs, "\n") {
+		lines++
+	}
+	return &SSHDockerLogsOutput{
+		Container: input.Container,
+		Lines:     lines,
+		Logs:      TruncateOutput(logs, deps.MaxOutputSize),
+	}, nil
+}
+
+// HandleDockerExec implements the ssh_docker_exec tool.
+func HandleDockerExec(ctx context.Context, deps *DockerDeps, input SSHDockerExecInput) (*SSHExecuteOutput, error) {
+	if err := validateDockerRef("container", input.Container); err != nil {
+		return nil, err
+	}
+	if input.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	// The in-container command is subject to the same filter as ssh_execute.
+	if err := deps.Filter.AllowCommand(input.Command); err != nil {
+		return nil, err
+	}
+
+	args := []string{"exec"}
+	if input.User != "" {
+		if strings.HasPrefix(input.User, "-") {
+			return nil, fmt.Errorf("invalid user %q", input.User)
+		}
+		args = append(args, "--user", input.User)
+	}
+	if input.WorkingDir != "" {
+		args = append(args, "--workdir", input.WorkingDir)
+	}
+	args = append(args, input.Container, "sh", "-c", input.Command)
+
+	cmd, err := dockerCommand(deps, input.Sudo, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	timeout := deps.Config.CommandTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return execOutput(res, timeout, deps.Config, deps.MaxOutputSize), nil
+}
+
+// HandleDockerRestart implements the ssh_docker_restart tool.
+func HandleDockerRestart(ctx context.Context, deps *DockerDeps, input SSHDockerRestartInput) (*SSHDockerRestartOutput, error) {
+	if len(input.Containers) == 0 {
+		return nil, fmt.Errorf("containers is required")
+	}
+	if input.StopTimeout < 0 {
+		return nil, fmt.Errorf("invalid stop_timeout: %d (must be non-negative)", input.StopTimeout)
+	}
+	args := []string{"restart"}
+	if input.StopTimeout > 0 {
+		args = append(args, "--time", fmt.Sprint(input.StopTimeout))
+	}
+	for _, c := range input.Containers {
+		if err := validateDockerRef("container", c); err != nil {
+			return nil, err
+		}
+		args = append(args, c)
+	}
+	cmd, err := dockerCommand(deps, input.Sudo, args...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runDocker(ctx, deps, input.SessionID, "docker restart", cmd); err != nil {
+		return nil, err
+	}
+	return &SSHDockerRestartOutput{
+		Restarted: input.Containers,
+		Message:   fmt.Sprintf("Restarted %s", strings.Join(input.Containers, ", ")),
+	}, nil
+}
+
+// decodeJSONLines decodes newline-delimited JSON objects (docker --format
+// '{{json .}}' output) into the slice pointed to by out.
+func decodeJSONLines[T any](data string, out *[]T) error {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return err
+		}
+		*out = append(*out, v)
+	}
+	return nil
+}
+
+// shortDockerID shortens a full container/image ID to docker's 12-character form.
+func shortDockerID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func newTestDockerDeps(t *testing.T, allowSudo bool, commandDenylist ...string) *DockerDeps {
+	t.Helper()
+	filter, err := security.NewFilter(nil, nil, nil, commandDenylist)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	return &DockerDeps{Filter: filter, Config: &config.SSHConfig{AllowSudo: allowSudo}}
+}
+
+func TestDockerCommand(t *testing.T) {
+	deps := newTestDockerDeps(t, true)
+
+	cmd, err := dockerCommand(deps, false, "logs", "--tail", "50", "web; rm -rf /")
+	if err != nil {
+		t.Fatalf("dockerCommand: %v", err)
+	}
+	if want := `docker 'logs' '--tail' '50' 'web; rm -rf /'`; cmd != want {
+		t.Errorf("dockerCommand = %q, want %q", cmd, want)
+	}
+
+	cmd, err = dockerCommand(deps, true, "ps")
+	if err != nil {
+		t.Fatalf("dockerCommand with sudo: %v", err)
+	}
+	if !strings.HasPrefix(cmd, "sudo -n docker ") {
+		t.Errorf("expected non-interactive sudo prefix, got %q", cmd)
+	}
+
+	if _, err := dockerCommand(newTestDockerDeps(t, false), true, "ps"); err == nil {
+		t.Error("expected error for sudo when disabled")
+	}
+	if _, err := dockerCommand(newTestDockerDeps(t, false, "docker .*restart.*"), false, "restart", "web"); err == nil {
+		t.Error("expected command filter to block docker restart")
+	}
+}
+
+func TestValidateDockerRef(t *testing.T) {
+	valid := []string{"web", "my_app.1", "3f2a9c1b0d4e", "nginx:1.25", "ghcr.io/org/app@sha256:abc"}
+	for _, ref := range valid {
+		if err := validateDockerRef("container", ref); err != nil {
+			t.Errorf("validateDockerRef(%q) unexpected error: %v", ref, err)
+		}
+	}
+	invalid := []string{"", "-f", "--rm", "web app", "web;id", "$(id)"}
+	for _, ref := range invalid {
+		if err := validateDockerRef("container", ref); err == nil {
+			t.Errorf("validateDockerRef(%q) expected error", ref)
+		}
+	}
+}
+
+func TestDecodeJSONLines(t *testing.T) {
+	data := `{"ID":"aaa","Names":"web"}
+{"ID":"bbb","Names":"db"}
+
+`
+	var rows []struct {
+		ID    string `json:"ID"`
+		Names string `json:"Names"`
+	}
+	if err := decodeJSONLines(data, &rows); err != nil {
+		t.Fatalf("decodeJSONLines: %v", err)
+	}
+	if len(rows) != 2 || rows[1].Names != "db" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+
+	if err := decodeJSONLines("not json", &rows); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestShortDockerID(t *testing.T) {
+	if got := shortDockerID("sha256:0123456789abcdef0123"); got != "0123456789ab" {
+		t.Errorf("shortDockerID = %q", got)
+	}
+	if got := shortDockerID("abc"); got != "abc" {
+		t.Errorf("shortDockerID = %q", got)
+	}
+}
+
+func TestSSHDockerPsOutput_Text(t *testing.T) {
+	if got := (SSHDockerPsOutput{}).Text(); got != "No containers" {
+		t.Errorf("empty Text() = %q", got)
+	}
+	out := SSHDockerPsOutput{Containers: []DockerContainer{
+		{ID: "0123456789ab", Name: "web", Image: "nginx:1.25", State: "running", Status: "Up 2 hours", Ports: "0.0.0.0:80->80/tcp"},
+	}}
+	text := out.Text()
+	for _, want := range []string{"1 container(s)", "web", "nginx:1.25", "[running] Up 2 hours", "ports: 0.0.0.0:80->80/tcp"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHandleDockerRestart_Validation(t *testing.T) {
+	deps := newTestDockerDeps(t, false)
+	if _, err := HandleDockerRestart(t.Context(), deps, SSHDockerRestartInput{SessionID: "s"}); err == nil {
+		t.Error("expected error for empty containers")
+	}
+	if _, err := HandleDockerRestart(t.Context(), deps, SSHDockerRestartInput{SessionID: "s", Containers: []string{"--all"}}); err == nil {
+		t.Error("expected error for option-like container name")
+	}
+}
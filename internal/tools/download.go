@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"unicode/utf8"
 
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
 	"github.com/n0madic/ssh-mcp/internal/sshclient"
@@ -11,33 +15,83 @@ import (
 
 // DownloadDeps holds dependencies for the ssh_download tool handler.
 type DownloadDeps struct {
-	Pool         *connection.Pool
-	LocalBaseDir string
-	RateLimiter  *security.RateLimiter
+	Pool        *connection.Pool
+	LocalDirs   []config.LocalDir
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+	Filter      *security.Filter // gates the remote sha256sum used by verify
+	MaxFileSize int64            // --max-file-size; also caps inline downloads
 }
 
+// MaxInlineDownload is the largest file ssh_download returns inline.
+const MaxInlineDownload = 1 << 20
+
+// Content encodings of an inline download.
+const (
+	InlineText   = "text"
+	InlineBase64 = "base64"
+)
+
 // HandleDownload implements the ssh_download tool.
 // It auto-detects whether remote_path is a file or directory and delegates accordingly.
 func HandleDownload(ctx context.Context, deps *DownloadDeps, input SSHDownloadInput) (*SSHDownloadOutput, error) {
-	if err := security.ValidateLocalPath(input.LocalPath, deps.LocalBaseDir); err != nil {
+	if input.Inline {
+		return handleInlineDownload(ctx, deps, input)
+	}
+	if input.LocalPath == "" {
+		return nil, fmt.Errorf("local_path is required unless inline is set")
+	}
+	if err := security.ValidateLocalAccess(input.LocalPath, deps.LocalDirs, true); err != nil {
 		return nil, fmt.Errorf("invalid local path: %w", err)
 	}
 	if err := security.ValidatePath(input.RemotePath); err != nil {
 		return nil, fmt.Errorf("invalid remote path: %w", err)
 	}
 
-	_, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	symlinks, err := sshclient.ParseSymlinkPolicy(input.Symlinks)
+	if err != nil {
+		return nil, err
+	}
+	parallel, err := transferWorkers(deps.Config, input.Parallel)
 	if err != nil {
 		return nil, err
 	}
 
-	sftpClient, err := sshclient.NewSFTPClient(client)
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
 	if err != nil {
 		return nil, err
 	}
+
+	sftpClient, err := transferClient(deps.Config, client)
+	if err != nil {
+		return nil, err
+	}
+	if sftpClient == nil {
+		if symlinks != sshclient.SymlinkSkip {
+			return nil, fmt.Errorf("symlinks=%s needs SFTP; over scp the remote side decides", symlinks)
+		}
+		if input.Verify {
+			return nil, fmt.Errorf("verify needs SFTP")
+		}
+		if input.Parallel > 1 {
+			return nil, fmt.Errorf("parallel needs SFTP; scp copies one file at a time")
+		}
+		fileCount, totalBytes, err := sshclient.SCPDownload(ctx, client, input.RemotePath, input.LocalPath)
+		if err != nil {
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+		conn.AddBytesDownloaded(totalBytes)
+		return &SSHDownloadOutput{
+			FilesDownloaded: fileCount,
+			BytesRead:       totalBytes,
+			Protocol:        config.TransferSCP,
+			Message:         fmt.Sprintf("Downloaded %d files (%d bytes) from %s via scp", fileCount, totalBytes, input.RemotePath),
+		}, nil
+	}
 	defer sftpClient.Close()
 
-	input.RemotePath = sshclient.ExpandRemotePath(sftpClient, input.RemotePath)
+	input.RemotePath = expandRemotePath(conn, sftpClient, input.RemotePath)
 
 	stat, err := sftpClient.Stat(input.RemotePath)
 	if err != nil {
@@ -45,24 +99,112 @@ func HandleDownload(ctx context.Context, deps *DownloadDeps, input SSHDownloadIn
 	}
 
 	if stat.IsDir() {
-		fileCount, totalBytes, err := sshclient.DownloadDir(sftpClient, input.RemotePath, input.LocalPath)
+		opts := sshclient.DownloadOptions{Symlinks: symlinks, Parallel: parallel}
+		stats, err := sshclient.DownloadDir(ctx, sftpClient, input.RemotePath, input.LocalPath, opts)
 		if err != nil {
+			conn.SetLastError(err)
 			return nil, fmt.Errorf("download directory: %w", err)
 		}
-		return &SSHDownloadOutput{
-			FilesDownloaded: fileCount,
-			BytesRead:       totalBytes,
-			Message:         fmt.Sprintf("Downloaded %d files (%d bytes) from %s", fileCount, totalBytes, input.RemotePath),
-		}, nil
+		conn.AddBytesDownloaded(stats.Bytes)
+		out := &SSHDownloadOutput{
+			FilesDownloaded: stats.Files,
+			BytesRead:       stats.Bytes,
+			Skipped:         stats.Skipped,
+			Message:         fmt.Sprintf("Downloaded %d files (%d bytes) from %s", stats.Files, stats.Bytes, input.RemotePath),
+		}
+		if input.Verify {
+			v, err := verifyTransfer(ctx, deps.Filter, deps.Config, conn, client, sftpClient, stats.Copied)
+			if err != nil {
+				return nil, err
+			}
+			out.Verified, out.VerifyMethod, out.Mismatches = v.Verified, v.Method, v.Mismatches
+		}
+		return out, nil
 	}
 
-	n, err := sshclient.DownloadFile(sftpClient, input.RemotePath, input.LocalPath)
+	n, err := sshclient.DownloadFile(ctx, sftpClient, input.RemotePath, input.LocalPath)
 	if err != nil {
+		conn.SetLastError(err)
 		return nil, fmt.Errorf("download failed: %w", err)
 	}
-	return &SSHDownloadOutput{
+	conn.AddBytesDownloaded(n)
+	out := &SSHDownloadOutput{
 		FilesDownloaded: 1,
 		BytesRead:       n,
 		Message:         fmt.Sprintf("Downloaded %d bytes from %s", n, input.RemotePath),
-	}, nil
+	}
+	if input.Verify {
+		files := []sshclient.FilePair{{Local: input.LocalPath, Remote: input.RemotePath}}
+		v, err := verifyTransfer(ctx, deps.Filter, deps.Config, conn, client, sftpClient, files)
+		if err != nil {
+			return nil, err
+		}
+		out.Verified, out.VerifyMethod, out.Mismatches = v.Verified, v.Method, v.Mismatches
+	}
+	return out, nil
+}
+
+// handleInlineDownload returns a small remote file in the result instead
+// of writing it locally: as text when it is UTF-8 without NUL bytes,
+// otherwise base64-encoded.
+func handleInlineDownload(ctx context.Context, deps *DownloadDeps, input SSHDownloadInput) (*SSHDownloadOutput, error) {
+	switch {
+	case input.LocalPath != "":
+		return nil, fmt.Errorf("inline returns the content instead of writing local_path; set only one")
+	case input.Verify || input.Symlinks != "" || input.Parallel != 0:
+		return nil, fmt.Errorf("verify, symlinks and parallel don't apply to inline downloads")
+	}
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("inline download needs SFTP: %w", err)
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+	stat, err := sc.Stat(input.RemotePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat remote path: %w", err)
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, fmt.Errorf("inline download only returns regular files; use local_path for %s", input.RemotePath)
+	}
+	data, err := sshclient.ReadFile(ctx, sc, input.RemotePath, inlineLimit(deps.MaxFileSize))
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	conn.AddBytesDownloaded(int64(len(data)))
+	return inlineOutput(input.RemotePath, data), nil
+}
+
+// inlineLimit is MaxInlineDownload, or --max-file-size when smaller.
+func inlineLimit(maxFileSize int64) int64 {
+	if maxFileSize > 0 && maxFileSize < MaxInlineDownload {
+		return maxFileSize
+	}
+	return MaxInlineDownload
+}
+
+func inlineOutput(remotePath string, data []byte) *SSHDownloadOutput {
+	out := &SSHDownloadOutput{
+		FilesDownloaded: 1,
+		BytesRead:       int64(len(data)),
+		Content:         string(data),
+		ContentEncoding: InlineText,
+		SHA256:          contentHash(data),
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		out.Content = base64.StdEncoding.EncodeToString(data)
+		out.ContentEncoding = InlineBase64
+	}
+	out.Message = fmt.Sprintf("Read %d bytes from %s (%s)", len(data), remotePath, out.ContentEncoding)
+	return out
 }
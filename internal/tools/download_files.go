@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// DownloadFilesDeps holds dependencies for the ssh_download_files tool handler.
+type DownloadFilesDeps struct {
+	Pool        *connection.Pool
+	LocalDirs   []config.LocalDir
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+const (
+	maxDownloadPaths = 100 // remote_paths entries per call
+	maxDownloadFiles = 500 // files after glob expansion
+)
+
+// Statuses of a file in an ssh_download_files result.
+const (
+	DownloadStatusOK      = "downloaded"
+	DownloadStatusSkipped = "skipped"
+	DownloadStatusFailed  = "failed"
+)
+
+// HandleDownloadFiles implements the ssh_download_files tool. Every entry
+// of remote_paths is a file or a glob, expanded on the remote host over
+// SFTP; the matches are copied into local_dir under their base names by up
+// to parallel workers over one SFTP client. The whole batch costs one rate
+// limit token, and one file failing doesn't stop the others: each gets its
+// own status.
+func HandleDownloadFiles(ctx context.Context, deps *DownloadFilesDeps, input SSHDownloadFilesInput) (*SSHDownloadFilesOutput, error) {
+	switch {
+	case len(input.RemotePaths) == 0:
+		return nil, fmt.Errorf("remote_paths is required")
+	case len(input.RemotePaths) > maxDownloadPaths:
+		return nil, fmt.Errorf("at most %d remote_paths per call", maxDownloadPaths)
+	case input.LocalDir == "":
+		return nil, fmt.Errorf("local_dir is required")
+	}
+	for _, p := range input.RemotePaths {
+		if err := security.ValidatePath(p); err != nil {
+			return nil, fmt.Errorf("invalid remote path: %w", err)
+		}
+	}
+	if err := security.ValidateLocalAccess(input.LocalDir, deps.LocalDirs, true); err != nil {
+		return nil, fmt.Errorf("invalid local path: %w", err)
+	}
+	parallel, err := transferWorkers(deps.Config, input.Parallel)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("ssh_download_files needs SFTP: %w", err)
+	}
+	defer sc.Close()
+
+	files, err := planDownloads(ctx, sc, conn.GetRemoteInfo().OS == "Windows", input.RemotePaths, input.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(input.LocalDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create local directory: %w", err)
+	}
+	downloadFiles(ctx, sc, files, parallel)
+
+	out := &SSHDownloadFilesOutput{LocalDir: input.LocalDir, Files: files}
+	for _, f := range files {
+		switch f.Status {
+		case DownloadStatusOK:
+			out.Downloaded++
+			out.BytesRead += f.Bytes
+		case DownloadStatusSkipped:
+			out.Skipped++
+		default:
+			if out.Failed == 0 {
+				conn.SetLastError(fmt.Errorf("download %s: %s", f.RemotePath, f.Error))
+			}
+			out.Failed++
+		}
+	}
+	conn.AddBytesDownloaded(out.BytesRead)
+	return out, nil
+}
+
+// planDownloads expands paths into one entry per remote file, with the
+// local path under localDir it goes to. Entries that can't be downloaded
+// (a glob without matches, two files with the same base name) are marked
+// failed; the rest have no status yet. It fails if the paths match more
+// than maxDownloadFiles files.
+func planDownloads(ctx context.Context, sc *sftp.Client, windows bool, paths []string, localDir string) ([]DownloadFileResult, error) {
+	var files []DownloadFileResult
+	seen := map[string]bool{}    // remote paths
+	owner := map[string]string{} // local base name -> remote path
+	for _, p := range paths {
+		if windows {
+			p = sshclient.WindowsPath(p)
+		}
+		var matches []string
+		if hasGlobMeta(p) {
+			var err error
+			if matches, err = expandRemoteGlob(ctx, sc, p); err != nil || len(matches) == 0 {
+				reason := "no match"
+				if err != nil {
+					reason = err.Error()
+				}
+				files = append(files, DownloadFileResult{RemotePath: p, Status: DownloadStatusFailed, Error: reason})
+				continue
+			}
+		} else {
+			matches = []string{sshclient.ExpandRemotePath(sc, p)}
+		}
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			f := DownloadFileResult{RemotePath: m}
+			name := path.Base(m)
+			switch err := security.ValidateFilename(name); {
+			case err != nil:
+				f.Status, f.Error = DownloadStatusFailed, err.Error()
+			case owner[name] != "":
+				f.Status, f.Error = DownloadStatusFailed, fmt.Sprintf("same file name as %s", owner[name])
+			default:
+				owner[name] = m
+				f.LocalPath = filepath.Join(localDir, name)
+			}
+			files = append(files, f)
+		}
+		if len(files) > maxDownloadFiles {
+			return nil, fmt.Errorf("remote_paths match more than %d files; narrow the globs", maxDownloadFiles)
+		}
+	}
+	return files, nil
+}
+
+// downloadFiles copies the files without a status on up to parallel
+// goroutines and sets the status of each.
+func downloadFiles(ctx context.Context, sc *sftp.Client, files []DownloadFileResult, parallel int) {
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i := range files {
+		if files[i].Status != "" {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(f *DownloadFileResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fail := func(status, reason string) {
+				f.Status, f.Error, f.LocalPath = status, reason, ""
+			}
+			if err := ctx.Err(); err != nil {
+				fail(DownloadStatusFailed, err.Error())
+				return
+			}
+			info, err := sc.Stat(f.RemotePath)
+			switch {
+			case err != nil:
+				fail(DownloadStatusFailed, err.Error())
+				return
+			case info.IsDir():
+				fail(DownloadStatusSkipped, "is a directory; use ssh_download")
+				return
+			case !info.Mode().IsRegular():
+				fail(DownloadStatusSkipped, "not a regular file")
+				return
+			}
+			n, err := sshclient.DownloadFile(ctx, sc, f.RemotePath, f.LocalPath)
+			if err != nil {
+				f.Status, f.Error = DownloadStatusFailed, err.Error()
+				return
+			}
+			f.Status, f.Bytes = DownloadStatusOK, n
+		}(&files[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/sshclient/sftptest"
+)
+
+// remoteTree creates files (relative path -> content) in a temp dir.
+func remoteTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestHandleDownloadFiles_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHDownloadFilesInput
+		want  string
+	}{
+		{SSHDownloadFilesInput{SessionID: "s", LocalDir: "/tmp/x"}, "remote_paths is required"},
+		{SSHDownloadFilesInput{SessionID: "s", RemotePaths: make([]string, maxDownloadPaths+1), LocalDir: "/tmp/x"}, "at most"},
+		{SSHDownloadFilesInput{SessionID: "s", RemotePaths: []string{"/a"}}, "local_dir is required"},
+		{SSHDownloadFilesInput{SessionID: "s", RemotePaths: []string{"/var/../etc/shadow"}, LocalDir: "/tmp/x"}, "invalid remote path"},
+		{SSHDownloadFilesInput{SessionID: "s", RemotePaths: []string{"/a"}, LocalDir: "/tmp/x", Parallel: 99}, "parallel must be"},
+	}
+	for _, tt := range tests {
+		_, err := HandleDownloadFiles(context.Background(), &DownloadFilesDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestPlanAndDownloadFiles(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	root := remoteTree(t, map[string]string{
+		"log/a.log":       "aaa",
+		"log/b.log":       "bbbb",
+		"log/c.txt":       "c",
+		"other/a.log":     "clash",
+		"log/sub/deep.gz": "deep",
+	})
+	local := t.TempDir()
+	paths := []string{
+		root + "/log/*.log",
+		root + "/log/c.txt",
+		root + "/log/a.log", // already matched by the glob
+		root + "/other/a.log",
+		root + "/log/sub",
+		root + "/missing",
+		root + "/none/*.gz",
+	}
+	files, err := planDownloads(context.Background(), sc, false, paths, local)
+	if err != nil {
+		t.Fatalf("planDownloads: %v", err)
+	}
+	downloadFiles(context.Background(), sc, files, 4)
+
+	want := []struct{ remote, status, errPart string }{
+		{"/log/a.log", DownloadStatusOK, ""},
+		{"/log/b.log", DownloadStatusOK, ""},
+		{"/log/c.txt", DownloadStatusOK, ""},
+		{"/other/a.log", DownloadStatusFailed, "same file name as"},
+		{"/log/sub", DownloadStatusSkipped, "is a directory"},
+		{"/missing", DownloadStatusFailed, "not exist"},
+		{"/none/*.gz", DownloadStatusFailed, "no match"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("files = %+v", files)
+	}
+	for i, w := range want {
+		f := files[i]
+		if f.RemotePath != root+w.remote || f.Status != w.status || !strings.Contains(f.Error, w.errPart) {
+			t.Errorf("file %d = %+v, want %s %s %q", i, f, w.remote, w.status, w.errPart)
+		}
+	}
+	if files[1].Bytes != 4 || files[1].LocalPath != filepath.Join(local, "b.log") {
+		t.Errorf("b.log = %+v", files[1])
+	}
+	if data, _ := os.ReadFile(filepath.Join(local, "a.log")); string(data) != "aaa" {
+		t.Errorf("local a.log = %q, want the first match", data)
+	}
+	if info, err := os.Stat(filepath.Join(local, "c.txt")); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("local c.txt: %v, %v", info, err)
+	}
+}
+
+func TestPlanDownloads_Cap(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	tree := map[string]string{}
+	for i := range maxDownloadFiles + 1 {
+		tree[fmt.Sprintf("d/f%03d", i)] = ""
+	}
+	root := remoteTree(t, tree)
+	if _, err := planDownloads(context.Background(), sc, false, []string{root + "/d/*"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSSHDownloadFilesOutput_Text(t *testing.T) {
+	out := SSHDownloadFilesOutput{
+		LocalDir: "/tmp/logs", Downloaded: 1, Skipped: 1, Failed: 1, BytesRead: 120,
+		Files: []DownloadFileResult{
+			{RemotePath: "/var/log/a.log", Status: DownloadStatusOK, Bytes: 120},
+			{RemotePath: "/var/log/old", Status: DownloadStatusSkipped, Error: "is a directory; use ssh_download"},
+			{RemotePath: "/var/log/x.log", Status: DownloadStatusFailed, Error: "permission denied"},
+		},
+	}
+	text := out.Text()
+	for _, want := range []string{
+		"Downloaded 1 of 3 files (120 bytes) to /tmp/logs, 1 skipped, 1 failed",
+		"ok      /var/log/a.log (120 bytes)",
+		"skipped /var/log/old: is a directory",
+		"failed  /var/log/x.log: permission denied",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
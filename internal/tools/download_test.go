@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestInlineOutput(t *testing.T) {
+	out := inlineOutput("/etc/hostname", []byte("web-1\n"))
+	if out.ContentEncoding != InlineText || out.Content != "web-1\n" || out.BytesRead != 6 {
+		t.Errorf("text file: %+v", out)
+	}
+	if text := out.Text(); !strings.HasSuffix(text, "\n\nweb-1\n") || !strings.Contains(text, "SHA-256: "+out.SHA256) {
+		t.Errorf("Text():\n%s", text)
+	}
+
+	for _, data := range [][]byte{{0x7f, 'E', 'L', 'F', 0, 1}, {0xff, 0xfe, 'x'}} {
+		out := inlineOutput("/bin/x", data)
+		if out.ContentEncoding != InlineBase64 {
+			t.Errorf("%q: encoding %s, want base64", data, out.ContentEncoding)
+		}
+		if got, _ := base64.StdEncoding.DecodeString(out.Content); string(got) != string(data) {
+			t.Errorf("%q: content %q does not decode back", data, out.Content)
+		}
+	}
+}
+
+func TestInlineLimit(t *testing.T) {
+	for maxFileSize, want := range map[int64]int64{0: MaxInlineDownload, 4096: 4096, 10 << 20: MaxInlineDownload} {
+		if got := inlineLimit(maxFileSize); got != want {
+			t.Errorf("inlineLimit(%d) = %d, want %d", maxFileSize, got, want)
+		}
+	}
+}
+
+func TestHandleDownload_InlineArgs(t *testing.T) {
+	for _, tt := range []struct {
+		input SSHDownloadInput
+		want  string
+	}{
+		{SSHDownloadInput{SessionID: "s", RemotePath: "/etc/hosts"}, "local_path is required"},
+		{SSHDownloadInput{SessionID: "s", RemotePath: "/etc/hosts", LocalPath: "/tmp/hosts", Inline: true}, "set only one"},
+		{SSHDownloadInput{SessionID: "s", RemotePath: "/etc/hosts", Inline: true, Verify: true}, "don't apply"},
+		{SSHDownloadInput{SessionID: "s", RemotePath: "../etc/hosts", Inline: true}, "invalid remote path"},
+	} {
+		_, err := HandleDownload(context.Background(), &DownloadDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+// staleEditLock is the age after which a remote lock file is assumed to be
+// left over from an editor that died, and is taken over.
+const staleEditLock = 10 * time.Minute
+
+// EditLocks serializes edits of the same remote file made through this
+// server, so two sessions or clients doing read-modify-write on one file
+// take turns instead of one silently losing the other's update. Files are
+// keyed by host:port as connected and the expanded path; the same host
+// reached under two names is not recognized.
+type EditLocks struct {
+	mu    sync.Mutex
+	locks map[string]*editLock
+}
+
+type editLock struct {
+	held chan struct{} // holds a token while locked
+	refs int           // holders and waiters, to drop unused entries
+}
+
+// NewEditLocks creates an empty lock table.
+func NewEditLocks() *EditLocks {
+	return &EditLocks{locks: make(map[string]*editLock)}
+}
+
+// Lock waits until no other edit of remotePath on conn's host is running,
+// or ctx is done, and returns the function that releases the lock. A nil
+// EditLocks does no locking.
+func (l *EditLocks) Lock(ctx context.Context, conn *connection.Connection, remotePath string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	key := net.JoinHostPort(conn.Host, strconv.Itoa(conn.Port)) + path.Clean(remotePath)
+
+	l.mu.Lock()
+	lk := l.locks[key]
+	if lk == nil {
+		lk = &editLock{held: make(chan struct{}, 1)}
+		l.locks[key] = lk
+	}
+	lk.refs++
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		if lk.refs--; lk.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+	select {
+	case lk.held <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		return nil, fmt.Errorf("waiting for another edit of %s: %w", remotePath, ctx.Err())
+	}
+	return func() {
+		<-lk.held
+		release()
+	}, nil
+}
+
+// editLockPath returns the remote lock file for remotePath, next to it and
+// hidden like the temp files of atomic writes.
+func editLockPath(remotePath string) string {
+	return path.Join(path.Dir(remotePath), "."+path.Base(remotePath)+".ssh-mcp.lock")
+}
+
+// acquireRemoteLock creates the lock file for remotePath exclusively, so
+// editors outside this server that honor it (other ssh-mcp instances,
+// scripts checking for it) keep off the file until release is called. A
+// lock file older than staleEditLock is replaced; a fresh one fails with
+// its owner's description.
+func acquireRemoteLock(sc *sftp.Client, remotePath, owner string) (func(), error) {
+	lockPath := editLockPath(remotePath)
+	for attempt := 0; ; attempt++ {
+		f, err := sc.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+		if err == nil {
+			_, werr := fmt.Fprintf(f, "%s since %s\n", owner, time.Now().UTC().Format(time.RFC3339))
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				_ = sc.Remove(lockPath)
+				return nil, fmt.Errorf("write lock file %s: %w", lockPath, werr)
+			}
+			return func() { _ = sc.Remove(lockPath) }, nil
+		}
+
+		info, statErr := sc.Stat(lockPath)
+		switch {
+		case statErr != nil && (errors.Is(statErr, fs.ErrNotExist) || os.IsNotExist(statErr)) && attempt == 0:
+			continue // released between our open and stat
+		case statErr != nil:
+			return nil, fmt.Errorf("create lock file %s: %w", lockPath, err)
+		case time.Since(info.ModTime()) > staleEditLock && attempt == 0:
+			if rmErr := sc.Remove(lockPath); rmErr != nil {
+				return nil, fmt.Errorf("remove stale lock file %s: %w", lockPath, rmErr)
+			}
+			continue
+		}
+		holder := "unknown editor"
+		if lf, err := sc.Open(lockPath); err == nil {
+			b, _ := io.ReadAll(io.LimitReader(lf, 512))
+			lf.Close()
+			if s := strings.TrimSpace(string(b)); s != "" {
+				holder = s
+			}
+		}
+		return nil, fmt.Errorf("%w: %s is locked by %s (lock file %s)", errEditConflict, remotePath, holder, lockPath)
+	}
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/sshclient/sftptest"
+)
+
+func TestEditLocks(t *testing.T) {
+	locks := NewEditLocks()
+	web := &connection.Connection{Host: "web", Port: 22}
+
+	unlock, err := locks.Lock(t.Context(), web, "/etc/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other files and hosts are not blocked.
+	for _, c := range []struct {
+		conn *connection.Connection
+		path string
+	}{{web, "/etc/other.conf"}, {&connection.Connection{Host: "db", Port: 22}, "/etc/app.conf"}} {
+		u, err := locks.Lock(t.Context(), c.conn, c.path)
+		if err != nil {
+			t.Fatalf("%s:%s: %v", c.conn.Host, c.path, err)
+		}
+		u()
+	}
+
+	// The same file waits until the holder is done, or the wait is cancelled.
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := locks.Lock(ctx, web, "/etc/./app.conf"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("locked file: err = %v, want deadline exceeded", err)
+	}
+	got := make(chan error, 1)
+	go func() {
+		u, err := locks.Lock(t.Context(), web, "/etc/app.conf")
+		if err == nil {
+			u()
+		}
+		got <- err
+	}()
+	select {
+	case <-got:
+		t.Fatal("second edit did not wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	if err := <-got; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(locks.locks); n != 0 {
+		t.Errorf("%d lock entries left after release", n)
+	}
+
+	var none *EditLocks
+	u, err := none.Lock(t.Context(), web, "/etc/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u()
+}
+
+func TestAcquireRemoteLock(t *testing.T) {
+	sc := sftptest.NewClient(t)
+
+	file := filepath.Join(t.TempDir(), "app.conf")
+	lockPath := editLockPath(file)
+	if want := filepath.Join(filepath.Dir(file), ".app.conf.ssh-mcp.lock"); lockPath != want {
+		t.Fatalf("editLockPath = %s, want %s", lockPath, want)
+	}
+
+	release, err := acquireRemoteLock(sc, file, "ssh-mcp session a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = acquireRemoteLock(sc, file, "ssh-mcp session b")
+	if !errors.Is(err, errEditConflict) || !strings.Contains(err.Error(), "ssh-mcp session a since") {
+		t.Errorf("second lock: err = %v, want conflict naming the holder", err)
+	}
+	release()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lock file left after release: %v", err)
+	}
+
+	// A stale lock file is taken over.
+	if err := os.WriteFile(lockPath, []byte("crashed editor\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleEditLock)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	release, err = acquireRemoteLock(sc, file, "ssh-mcp session c")
+	if err != nil {
+		t.Fatalf("stale lock: %v", err)
+	}
+	release()
+}
@@ -3,21 +3,98 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/acarl005/stripansi"
-
 	"golang.org/x/crypto/ssh"
 
 	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/credentials"
 	"github.com/n0madic/ssh-mcp/internal/security"
 )
 
-// killGracePeriod is the time to wait after SIGTERM before sending SIGKILL.
-const killGracePeriod = 5 * time.Second
+const (
+	// interruptGracePeriod is the time to wait after SIGINT before SIGTERM.
+	interruptGracePeriod = 2 * time.Second
+	// killGracePeriod is the time to wait after SIGTERM before sending SIGKILL.
+	killGracePeriod = 5 * time.Second
+	// killWait is how long SIGKILL gets before the session is closed.
+	killWait = time.Second
+)
+
+// stopStage is one signal sent to stop a remote command and how long to wait
+// for it to exit afterwards.
+type stopStage struct {
+	sig  ssh.Signal
+	wait time.Duration
+}
+
+// stopStages escalates from SIGINT to SIGTERM to SIGKILL, sending SIGKILL
+// grace after SIGINT. SIGINT gets up to interruptGracePeriod (at most half
+// of grace) and SIGTERM the rest. A grace of zero sends SIGKILL at once.
+func stopStages(grace time.Duration) []stopStage {
+	if grace <= 0 {
+		return []stopStage{{ssh.SIGKILL, killWait}}
+	}
+	interrupt := min(interruptGracePeriod, grace/2)
+	return []stopStage{
+		{ssh.SIGINT, interrupt},
+		{ssh.SIGTERM, grace - interrupt},
+		{ssh.SIGKILL, killWait},
+	}
+}
+
+// killGrace returns the grace period for a command: the call's kill_grace
+// in seconds if set, otherwise --kill-grace.
+func killGrace(cfg *config.SSHConfig, sec *int) (time.Duration, error) {
+	if sec == nil {
+		return cfg.KillGrace, nil
+	}
+	grace := time.Duration(*sec) * time.Second
+	if *sec < 0 || grace > config.MaxKillGrace {
+		return 0, fmt.Errorf("invalid kill_grace: %d (must be 0-%d)", *sec, int(config.MaxKillGrace.Seconds()))
+	}
+	return grace, nil
+}
+
+// remoteSession is the part of *ssh.Session needed to stop a command.
+type remoteSession interface {
+	Signal(sig ssh.Signal) error
+	Close() error
+}
+
+// stopRemoteCommand stops a running command, whose session.Run result
+// arrives on done, by sending each stage's signal until it exits. Signals
+// are delivered with SSH "signal" requests (OpenSSH 7.9+); servers that do
+// not support them get the session closed after the last stage. It returns
+// the command's exit code, or -1 if it never exited.
+func stopRemoteCommand(session remoteSession, done <-chan error, stages []stopStage) int {
+	for _, st := range stages {
+		_ = session.Signal(st.sig)
+		timer := time.NewTimer(st.wait)
+		select {
+		case err := <-done:
+			timer.Stop()
+			if exitErr, ok := err.(interface{ ExitStatus() int }); ok {
+				return exitErr.ExitStatus()
+			}
+			return 0
+		case <-timer.C:
+		}
+	}
+	// Closing the channel makes Run return, so the output buffers are no
+	// longer written once this returns.
+	_ = session.Close()
+	<-done
+	return -1
+}
 
 // ExecuteDeps holds dependencies for the ssh_execute tool handler.
 type ExecuteDeps struct {
@@ -26,12 +103,18 @@ type ExecuteDeps struct {
 	RateLimiter   *security.RateLimiter
 	Config        *config.SSHConfig
 	MaxOutputSize int
+	Credentials   credentials.Store // nil when credential storage is disabled
 }
 
 // HandleExecute implements the ssh_execute tool.
 func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput) (*SSHExecuteOutput, error) {
 	sessionID := connection.SessionID(input.SessionID)
 
+	dec, err := outputDecoder(deps.Config, input.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get connection (with auto-reconnect).
 	conn, err := deps.Pool.GetConnection(ctx, sessionID)
 	if err != nil {
@@ -39,7 +122,7 @@ func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput
 	}
 
 	// Rate limit check.
-	if err := deps.RateLimiter.Allow(conn.Host); err != nil {
+	if err := deps.RateLimiter.Wait(ctx, conn.Host); err != nil {
 		return nil, err
 	}
 
@@ -52,11 +135,69 @@ func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput
 		return nil, err
 	}
 
+	// Fill in what the call leaves unset from the session's defaults.
+	windows := conn.GetRemoteInfo().OS == "Windows"
+	defaults := conn.GetDefaults()
+	if input.WorkingDir == "" {
+		input.WorkingDir = defaults.WorkingDir
+	}
+	if input.Shell == "" && !windows {
+		input.Shell = defaults.Shell
+	}
+
 	// Prepend working directory if specified.
 	if input.WorkingDir != "" {
 		cmd = fmt.Sprintf("cd %s && %s", shellQuote(input.WorkingDir), cmd)
 	}
 
+	// The history gets the command as it is so far: the exports below
+	// would put the session's variable values in it.
+	histCmd := cmd
+
+	// Export the session's variables; locale and term, set innermost
+	// below, win over them.
+	if !windows {
+		cmd = exportVars(defaults.Env) + cmd
+	}
+
+	// Set the locale and terminal type innermost, so login profiles, su -
+	// and sudo can't reset them.
+	env, err := resolveCommandEnv(deps.Config, input.Locale, input.Term, windows)
+	if err != nil {
+		return nil, err
+	}
+	cmd = env.export() + cmd
+
+	// Run through the requested shell, inside sudo so the shell runs as root.
+	if input.Shell != "" || input.LoginShell {
+		info := conn.GetRemoteInfo()
+		if info.OS == "Windows" {
+			return nil, fmt.Errorf("shell and login_shell are only supported on POSIX hosts")
+		}
+		shell, err := resolveShell(input.Shell, info.Shell, input.LoginShell)
+		if err != nil {
+			return nil, err
+		}
+		cmd = wrapShell(cmd, shell, input.LoginShell)
+	}
+
+	// Switch to another user; sudo_password then goes to sudo -u.
+	viaSudo := input.Sudo
+	if input.RunAs != "" {
+		if input.Sudo {
+			return nil, fmt.Errorf("use either sudo or run_as, not both")
+		}
+		if conn.GetRemoteInfo().OS == "Windows" {
+			return nil, fmt.Errorf("run_as is only supported on POSIX hosts")
+		}
+		wrapped, err := wrapRunAs(cmd, input.RunAs, input.RunAsMethod, deps.Config)
+		if err != nil {
+			return nil, err
+		}
+		cmd = wrapped
+		viaSudo = input.RunAsMethod != runAsSu
+	}
+
 	// Handle sudo.
 	if input.Sudo {
 		if !deps.Config.AllowSudo {
@@ -66,11 +207,248 @@ func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput
 		cmd = fmt.Sprintf("sudo -S sh -c %s", shellQuote(cmd))
 	}
 
+	// Fall back to a saved sudo password, or make sure one can be saved.
+	sudoPassword := input.SudoPassword
+	if input.SaveSudoPassword {
+		if deps.Credentials == nil {
+			return nil, fmt.Errorf("credential storage is disabled; start server with --credential-store to allow save_sudo_password")
+		}
+		if !viaSudo || sudoPassword == "" {
+			return nil, fmt.Errorf("save_sudo_password requires sudo (or run_as via sudo) and sudo_password")
+		}
+	} else if viaSudo && sudoPassword == "" && deps.Credentials != nil {
+		cred, ok, err := deps.Credentials.Get(credentialKey(ctx, input.SessionID))
+		if err != nil {
+			return nil, fmt.Errorf("read saved credentials: %w", err)
+		}
+		if ok {
+			sudoPassword = cred.SudoPassword
+		}
+	}
+
 	// Set timeout.
 	timeout := deps.Config.CommandTimeout
 	if input.Timeout > 0 {
 		timeout = time.Duration(input.Timeout) * time.Second
 	}
+
+	grace, err := killGrace(deps.Config, input.KillGrace)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := compileExpect(deps.Filter, input.Expect)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := deps.Config.ExecuteRetries
+	if input.Retries != nil {
+		retries = *input.Retries
+	}
+	if retries < 0 || retries > config.MaxExecuteRetries {
+		return nil, fmt.Errorf("invalid retries: %d (must be 0-%d)", retries, config.MaxExecuteRetries)
+	}
+
+	var stdin string
+	if viaSudo && sudoPassword != "" {
+		stdin = sudoPassword + "\n"
+	}
+
+	// Retry only when the command did not complete for a connection-level
+	// reason; an exit status, timeout or cancellation is final.
+	var res *execResult
+	attempts, err := retryConnection(ctx, retries, deps.Config.RetryBackoff, func(attempt int) error {
+		if attempt > 1 {
+			c, err := deps.Pool.GetConnection(ctx, sessionID)
+			if err != nil {
+				return fmt.Errorf("get connection: %w", err)
+			}
+			conn = c
+		}
+		var err error
+		res, err = executeOnce(ctx, conn, cmd, histCmd, stdin, steps, timeout, grace)
+		return err
+	})
+	if err != nil {
+		if attempts > 1 {
+			return nil, fmt.Errorf("%w (gave up after %d attempts)", err, attempts)
+		}
+		return nil, err
+	}
+	stdout, stderr := &res.stdout, &res.stderr
+	exitCode, timedOut, cancelled := res.exitCode, res.timedOut, res.cancelled
+	duration := res.duration
+
+	var failure string
+	switch {
+	case timedOut:
+		failure = fmt.Sprintf("command timed out after %s", timeout)
+	case cancelled:
+		failure = "command cancelled by client"
+	case res.expectFailed:
+		failure = res.expectErr.Error()
+	case exitCode != 0:
+		failure = fmt.Sprintf("command exited with code %d", exitCode)
+	}
+	conn.RecordCommandResult(histCmd, duration, failure)
+
+	// Only save a sudo password that was just proven to work.
+	var saveErr error
+	if input.SaveSudoPassword && failure == "" {
+		saveErr = credentials.Update(deps.Credentials, credentialKey(ctx, input.SessionID), func(c *credentials.Credential) {
+			c.SudoPassword = sudoPassword
+		})
+	}
+
+	stdoutStr, stderrStr, enc := decodeStreams(dec, stdout.String(), stderr.String())
+
+	// Strip escape sequences and collapse overwritten progress output,
+	// unless the call or server config asks for raw output.
+	strip := deps.Config.StripANSI
+	if input.StripANSI != nil {
+		strip = *input.StripANSI
+	}
+	if strip {
+		stdoutStr = sanitizeOutput(stdoutStr)
+		stderrStr = sanitizeOutput(stderrStr)
+	}
+
+	// Parse stdout before truncation, which would break the JSON; output
+	// too large to return whole isn't parsed either.
+	var parsed json.RawMessage
+	var parseErr error
+	if input.ParseJSON {
+		if deps.MaxOutputSize > 0 && len(stdoutStr) > deps.MaxOutputSize {
+			parseErr = fmt.Errorf("stdout is larger than --max-output-size (%d bytes)", deps.MaxOutputSize)
+		} else {
+			parsed, parseErr = parseJSONOutput(stdoutStr)
+		}
+	}
+
+	// Truncate output if configured.
+	stdoutStr = TruncateOutput(stdoutStr, deps.MaxOutputSize)
+	stderrStr = TruncateOutput(stderrStr, deps.MaxOutputSize)
+
+	if timedOut || cancelled {
+		stopMsg := fmt.Sprintf("[TIMEOUT] Command timed out after %s", timeout)
+		if cancelled {
+			stopMsg = fmt.Sprintf("[CANCELLED] Command cancelled by client after %s", duration.Round(time.Millisecond))
+		}
+		if stderrStr != "" {
+			stderrStr = stderrStr + "\n" + stopMsg
+		} else {
+			stderrStr = stopMsg
+		}
+		if exitCode == 0 {
+			exitCode = -1
+		}
+	}
+
+	if len(steps) > 0 {
+		var note string
+		switch {
+		case res.expectFailed:
+			note = fmt.Sprintf("[EXPECT] %v; command stopped", res.expectErr)
+			if exitCode == 0 {
+				exitCode = -1
+			}
+		case res.expectMatched < len(steps):
+			note = fmt.Sprintf("[EXPECT] command ended after %d of %d expect steps matched", res.expectMatched, len(steps))
+		}
+		if note != "" && stderrStr != "" {
+			stderrStr = stderrStr + "\n" + note
+		} else if note != "" {
+			stderrStr = note
+		}
+	}
+
+	if saveErr != nil {
+		warning := fmt.Sprintf("[WARNING] sudo password not saved: %v", saveErr)
+		if stderrStr != "" {
+			stderrStr = stderrStr + "\n" + warning
+		} else {
+			stderrStr = warning
+		}
+	}
+
+	out := &SSHExecuteOutput{
+		Stdout:     stdoutStr,
+		Stderr:     stderrStr,
+		ExitCode:   exitCode,
+		DurationMs: duration.Milliseconds(),
+		TimedOut:   timedOut,
+		Cancelled:  cancelled,
+		Encoding:   enc,
+	}
+	if attempts > 1 {
+		out.Attempts = attempts
+	}
+	if len(steps) > 0 {
+		out.ExpectMatched = res.expectMatched
+	}
+	out.JSON = parsed
+	if parseErr != nil {
+		out.JSONError = parseErr.Error()
+	}
+	return out, nil
+}
+
+// parseJSONOutput parses stdout for parse_json: one JSON value, or JSON
+// Lines (journalctl -o json, docker ps --format '{{json .}}'), which
+// become an array.
+func parseJSONOutput(stdout string) (json.RawMessage, error) {
+	s := strings.TrimSpace(stdout)
+	if s == "" {
+		return nil, errors.New("stdout is empty")
+	}
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s), nil
+	}
+	if lines := strings.Split(s, "\n"); len(lines) > 1 {
+		items := make([]string, 0, len(lines))
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if !json.Valid([]byte(line)) {
+				items = nil
+				break
+			}
+			items = append(items, line)
+		}
+		if items != nil {
+			return json.RawMessage("[" + strings.Join(items, ",") + "]"), nil
+		}
+	}
+	var v any
+	err := json.Unmarshal([]byte(s), &v)
+	return nil, fmt.Errorf("stdout is not JSON: %w", err)
+}
+
+// execResult is the outcome of executeOnce.
+type execResult struct {
+	stdout, stderr      bytes.Buffer
+	exitCode            int
+	timedOut, cancelled bool
+	duration            time.Duration
+	// expectMatched is the number of expect steps whose pattern matched;
+	// expectErr is why the next one did not, and expectFailed is set when
+	// that stopped the command.
+	expectMatched int
+	expectErr     error
+	expectFailed  bool
+}
+
+// executeOnce runs cmd in a new session on conn, feeding it stdin, and stops
+// it after timeout, escalating to SIGKILL after grace. Unlike runRemoteCommand, a cancelled command is a result,
+// not an error: an error means it could not be started or the connection was
+// lost before it reported an exit status. With expect steps, stdin stays
+// open for their responses (see runExpect), and a step that times out stops
+// the command like a timeout does. A lost connection is recorded in the
+// session history as histCmd.
+func executeOnce(ctx context.Context, conn *connection.Connection, cmd, histCmd, stdin string, steps []expectStep, timeout, grace time.Duration) (*execResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -89,15 +467,24 @@ func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput
 
 	conn.IncrementCommandCount()
 
-	// Set up stdin for sudo password.
-	if input.Sudo && input.SudoPassword != "" {
-		session.Stdin = strings.NewReader(input.SudoPassword + "\n")
+	res := &execResult{}
+	session.Stdout = &res.stdout
+	session.Stderr = &res.stderr
+
+	var stream *expectStream
+	var stdinPipe io.WriteCloser
+	if len(steps) > 0 {
+		stream = newExpectStream()
+		session.Stdout = io.MultiWriter(&res.stdout, stream)
+		session.Stderr = io.MultiWriter(&res.stderr, stream)
+		if stdinPipe, err = session.StdinPipe(); err != nil {
+			return nil, fmt.Errorf("stdin pipe: %w", err)
+		}
+	} else if stdin != "" {
+		// Set up stdin for sudo password.
+		session.Stdin = strings.NewReader(stdin)
 	}
 
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
-
 	start := time.Now()
 
 	// Run the command with context timeout.
@@ -106,80 +493,135 @@ func HandleExecute(ctx context.Context, deps *ExecuteDeps, input SSHExecuteInput
 		done <- session.Run(cmd)
 	}()
 
-	var exitCode int
-	var timedOut bool
+	expectCtx, stopExpect := context.WithCancel(ctx)
+	defer stopExpect()
+	expectFailed := make(chan struct{})
+	expectFinished := make(chan struct{})
+	if stream != nil {
+		go func() {
+			defer close(expectFinished)
+			res.expectMatched, res.expectErr = runExpect(expectCtx, stream, stdinPipe, stdin, steps)
+			if res.expectErr != nil && expectCtx.Err() == nil {
+				close(expectFailed)
+			}
+		}()
+	} else {
+		close(expectFinished)
+	}
 
 	select {
-	case <-ctx.Done():
-		timedOut = true
-		// Graceful: SIGTERM first (may not be supported, ignore errors).
-		_ = session.Signal(ssh.SIGTERM)
+	case <-expectFailed:
+		res.expectFailed = true
+		res.exitCode = stopRemoteCommand(session, done, stopStages(grace))
 
-		// Wait grace period for command to exit.
-		graceTimer := time.NewTimer(killGracePeriod)
-		select {
-		case err := <-done:
-			graceTimer.Stop()
-			// Command exited after SIGTERM.
-			if exitErr, ok := err.(interface{ ExitStatus() int }); ok {
-				exitCode = exitErr.ExitStatus()
-			}
-		case <-graceTimer.C:
-			// Grace period expired, SIGKILL.
-			_ = session.Signal(ssh.SIGKILL)
-			killTimer := time.NewTimer(1 * time.Second)
-			select {
-			case <-done:
-				killTimer.Stop()
-			case <-killTimer.C:
-			}
-			exitCode = -1
-		}
+	case <-ctx.Done():
+		// The client cancelling the call stops the remote command too, the
+		// same way as a timeout.
+		res.cancelled = errors.Is(ctx.Err(), context.Canceled)
+		res.timedOut = !res.cancelled
+		res.exitCode = stopRemoteCommand(session, done, stopStages(grace))
 
 	case err := <-done:
 		// Normal completion.
 		if err != nil {
 			if exitErr, ok := err.(interface{ ExitStatus() int }); ok {
-				exitCode = exitErr.ExitStatus()
+				res.exitCode = exitErr.ExitStatus()
 			} else {
+				conn.RecordCommandResult(histCmd, time.Since(start), err.Error())
+				stopExpect()
+				<-expectFinished
 				return nil, fmt.Errorf("execute command: %w", err)
 			}
 		}
 	}
+	stopExpect()
+	<-expectFinished
+	res.duration = time.Since(start)
+	return res, nil
+}
 
-	duration := time.Since(start)
-
-	stdoutStr := stdout.String()
-	stderrStr := stderr.String()
-
-	// Strip ANSI escape codes if enabled.
-	if deps.Config.StripANSI {
-		stdoutStr = stripansi.Strip(stdoutStr)
-		stderrStr = stripansi.Strip(stderrStr)
+// retryConnection calls attempt until it succeeds, up to retries more times,
+// sleeping backoff before the first retry and doubling it for each next one.
+// It stops early when ctx is done and returns the number of attempts made.
+func retryConnection(ctx context.Context, retries int, backoff time.Duration, attempt func(n int) error) (int, error) {
+	for n := 1; ; n++ {
+		err := attempt(n)
+		if err == nil || n > retries || ctx.Err() != nil {
+			return n, err
+		}
+		log.Printf("ssh_execute attempt %d failed, retrying in %s: %v", n, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return n, err
+		}
+		backoff *= 2
 	}
+}
 
-	// Truncate output if configured.
-	stdoutStr = TruncateOutput(stdoutStr, deps.MaxOutputSize)
-	stderrStr = TruncateOutput(stderrStr, deps.MaxOutputSize)
+// run_as methods.
+const (
+	runAsSudo = "sudo"
+	runAsSu   = "su"
+)
 
-	if timedOut {
-		timeoutMsg := fmt.Sprintf("[TIMEOUT] Command timed out after %s", timeout)
-		if stderrStr != "" {
-			stderrStr = stderrStr + "\n" + timeoutMsg
-		} else {
-			stderrStr = timeoutMsg
+// wrapRunAs runs cmd as user with sudo -u (the default method) or su,
+// after checking user against the allowed run-as users. The sudo method
+// also needs --enable-sudo.
+func wrapRunAs(cmd, user, method string, cfg *config.SSHConfig) (string, error) {
+	allowed := cfg.RunAsUsers
+	if !config.UserNamePattern.MatchString(user) {
+		return "", fmt.Errorf("invalid run_as user %q", user)
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("run_as is disabled; start server with --run-as-users to allow")
+	}
+	if !slices.Contains(allowed, user) && !slices.Contains(allowed, "*") {
+		return "", fmt.Errorf("run_as user %q is not allowed (allowed: %s)", user, strings.Join(allowed, ", "))
+	}
+	switch method {
+	case "", runAsSudo:
+		if !cfg.AllowSudo {
+			return "", fmt.Errorf("run_as via sudo is disabled; start server with --enable-sudo to allow, or use run_as_method=su")
 		}
-		if exitCode == 0 {
-			exitCode = -1
+		return fmt.Sprintf("sudo -S -u %s sh -c %s", user, shellQuote(cmd)), nil
+	case runAsSu:
+		return fmt.Sprintf("su - %s -c %s", user, shellQuote(cmd)), nil
+	}
+	return "", fmt.Errorf("invalid run_as_method %q (must be %s or %s)", method, runAsSudo, runAsSu)
+}
+
+// resolveShell returns the shell to run a command with. "detected" (or
+// an empty shell with login set) means the shell detected on connect,
+// falling back to bash when detection found none. Only POSIX shells are
+// accepted: the command filter checks the command as shell code, so any
+// other interpreter (perl, python, awk) would run unchecked code.
+func resolveShell(shell, detected string, login bool) (string, error) {
+	if shell == "detected" || (shell == "" && login) {
+		if detected == "" {
+			return "bash", nil
 		}
+		shell = detected
+	}
+	if !config.ShellNamePattern.MatchString(shell) {
+		return "", fmt.Errorf("invalid shell %q: must be a shell name or absolute path", shell)
 	}
+	if !security.IsShell(shell) {
+		return "", fmt.Errorf("unsupported shell %q: must be sh, bash, dash, zsh, ksh, mksh or ash", shell)
+	}
+	return shell, nil
+}
 
-	return &SSHExecuteOutput{
-		Stdout:     stdoutStr,
-		Stderr:     stderrStr,
-		ExitCode:   exitCode,
-		DurationMs: duration.Milliseconds(),
-	}, nil
+// wrapShell runs cmd with shell -c, or shell -lc for a login shell so
+// profile files are read before the command.
+func wrapShell(cmd, shell string, login bool) string {
+	flag := "-c"
+	if login {
+		flag = "-lc"
+	}
+	return fmt.Sprintf("%s %s %s", shell, flag, shellQuote(cmd))
 }
 
 func shellQuote(s string) string {
@@ -1,9 +1,17 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
 )
 
 func TestKillGracePeriod(t *testing.T) {
@@ -12,18 +20,152 @@ func TestKillGracePeriod(t *testing.T) {
 	}
 }
 
+// fakeRemoteSession records signals and exits on exitOn, if set.
+type fakeRemoteSession struct {
+	mu      sync.Mutex
+	signals []ssh.Signal
+	exitOn  ssh.Signal
+	done    chan error
+	closed  bool
+}
+
+func (f *fakeRemoteSession) Signal(sig ssh.Signal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signals = append(f.signals, sig)
+	if sig == f.exitOn {
+		f.done <- &exitStatusError{status: 130}
+	}
+	return nil
+}
+
+func (f *fakeRemoteSession) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.done <- nil
+	return nil
+}
+
+type exitStatusError struct{ status int }
+
+func (e *exitStatusError) Error() string   { return "exited" }
+func (e *exitStatusError) ExitStatus() int { return e.status }
+
+var testStopStages = []stopStage{
+	{ssh.SIGINT, 50 * time.Millisecond},
+	{ssh.SIGTERM, 50 * time.Millisecond},
+	{ssh.SIGKILL, 50 * time.Millisecond},
+}
+
+func TestStopStages(t *testing.T) {
+	tests := []struct {
+		grace time.Duration
+		want  []stopStage
+	}{
+		{7 * time.Second, []stopStage{{ssh.SIGINT, 2 * time.Second}, {ssh.SIGTERM, 5 * time.Second}, {ssh.SIGKILL, time.Second}}},
+		{30 * time.Second, []stopStage{{ssh.SIGINT, 2 * time.Second}, {ssh.SIGTERM, 28 * time.Second}, {ssh.SIGKILL, time.Second}}},
+		{time.Second, []stopStage{{ssh.SIGINT, 500 * time.Millisecond}, {ssh.SIGTERM, 500 * time.Millisecond}, {ssh.SIGKILL, time.Second}}},
+		{0, []stopStage{{ssh.SIGKILL, time.Second}}},
+	}
+	for _, tt := range tests {
+		got := stopStages(tt.grace)
+		if len(got) != len(tt.want) {
+			t.Errorf("stopStages(%s) = %v, want %v", tt.grace, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("stopStages(%s)[%d] = %v, want %v", tt.grace, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestKillGrace(t *testing.T) {
+	cfg := &config.SSHConfig{KillGrace: 7 * time.Second}
+	intp := func(n int) *int { return &n }
+	tests := []struct {
+		sec     *int
+		want    time.Duration
+		wantErr bool
+	}{
+		{nil, 7 * time.Second, false},
+		{intp(0), 0, false},
+		{intp(60), time.Minute, false},
+		{intp(300), 5 * time.Minute, false},
+		{intp(301), 0, true},
+		{intp(-1), 0, true},
+	}
+	for _, tt := range tests {
+		got, err := killGrace(cfg, tt.sec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("killGrace(%v) = %s, %v; want %s, error %v", tt.sec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestStopRemoteCommand_KillAtOnce(t *testing.T) {
+	f := &fakeRemoteSession{exitOn: ssh.SIGKILL, done: make(chan error, 1)}
+	stopRemoteCommand(f, f.done, stopStages(0))
+	if len(f.signals) != 1 || f.signals[0] != ssh.SIGKILL {
+		t.Errorf("signals = %v, want [KILL]", f.signals)
+	}
+}
+
+func TestStopRemoteCommand_ExitsOnInterrupt(t *testing.T) {
+	f := &fakeRemoteSession{exitOn: ssh.SIGINT, done: make(chan error, 1)}
+	if code := stopRemoteCommand(f, f.done, testStopStages); code != 130 {
+		t.Errorf("exit code = %d, want 130", code)
+	}
+	if len(f.signals) != 1 || f.signals[0] != ssh.SIGINT {
+		t.Errorf("signals = %v, want [INT]", f.signals)
+	}
+	if f.closed {
+		t.Error("session should not be closed when the command exits")
+	}
+}
+
+func TestStopRemoteCommand_Escalates(t *testing.T) {
+	f := &fakeRemoteSession{exitOn: ssh.SIGKILL, done: make(chan error, 1)}
+	stopRemoteCommand(f, f.done, testStopStages)
+	want := []ssh.Signal{ssh.SIGINT, ssh.SIGTERM, ssh.SIGKILL}
+	if len(f.signals) != len(want) {
+		t.Fatalf("signals = %v, want %v", f.signals, want)
+	}
+	for i := range want {
+		if f.signals[i] != want[i] {
+			t.Errorf("signal %d = %s, want %s", i, f.signals[i], want[i])
+		}
+	}
+}
+
+func TestStopRemoteCommand_ClosesWhenSignalsIgnored(t *testing.T) {
+	f := &fakeRemoteSession{done: make(chan error, 1)}
+	if code := stopRemoteCommand(f, f.done, testStopStages); code != -1 {
+		t.Errorf("exit code = %d, want -1", code)
+	}
+	if !f.closed {
+		t.Error("expected session to be closed")
+	}
+}
+
 func TestSSHExecuteOutputText_Timeout(t *testing.T) {
 	out := SSHExecuteOutput{
 		Stdout:     "partial output",
 		Stderr:     "[TIMEOUT] Command timed out after 10s",
 		ExitCode:   -1,
 		DurationMs: 10000,
+		TimedOut:   true,
 	}
 
 	result := out.Text()
 	if !strings.Contains(result, "partial output") {
 		t.Error("expected Text() to contain stdout")
 	}
+	if !strings.Contains(result, "stopped after 10000ms, output is partial") {
+		t.Errorf("expected Text() to flag partial output, got %q", result)
+	}
 	if !strings.Contains(result, "[TIMEOUT]") {
 		t.Error("expected Text() to contain [TIMEOUT]")
 	}
@@ -89,3 +231,194 @@ func TestSSHExecuteOutputText_EmptyOutput(t *testing.T) {
 		t.Errorf("expected 'Completed' in empty output, got %q", result)
 	}
 }
+
+func TestResolveShell(t *testing.T) {
+	tests := []struct {
+		shell, detected string
+		login           bool
+		want            string
+		wantErr         bool
+	}{
+		{shell: "zsh", want: "zsh"},
+		{shell: "/usr/local/bin/zsh", login: true, want: "/usr/local/bin/zsh"},
+		{shell: "/usr/local/bin/fish", login: true, wantErr: true},
+		{shell: "perl", wantErr: true},
+		{shell: "detected", detected: "/usr/bin/python3", wantErr: true},
+		{shell: "detected", detected: "/bin/zsh", want: "/bin/zsh"},
+		{login: true, detected: "/bin/bash", want: "/bin/bash"},
+		{login: true, want: "bash"},
+		{shell: "bash; rm -rf /", wantErr: true},
+		{shell: "../bash", wantErr: true},
+		{shell: "detected", detected: "/bin/sh -x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveShell(tt.shell, tt.detected, tt.login)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("resolveShell(%q, %q, %v) = %q, want error", tt.shell, tt.detected, tt.login, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveShell(%q, %q, %v) = %q, %v, want %q", tt.shell, tt.detected, tt.login, got, err, tt.want)
+		}
+	}
+}
+
+func TestWrapShell(t *testing.T) {
+	if got, want := wrapShell("echo 'hi'", "bash", true), `bash -lc 'echo '\''hi'\'''`; got != want {
+		t.Errorf("wrapShell login = %q, want %q", got, want)
+	}
+	if got, want := wrapShell("node -v", "/bin/zsh", false), `/bin/zsh -c 'node -v'`; got != want {
+		t.Errorf("wrapShell = %q, want %q", got, want)
+	}
+}
+
+func TestWrapRunAs(t *testing.T) {
+	got, err := wrapRunAs("whoami", "deploy", "", &config.SSHConfig{RunAsUsers: []string{"deploy"}, AllowSudo: true})
+	if want := `sudo -S -u deploy sh -c 'whoami'`; err != nil || got != want {
+		t.Errorf("sudo: got %q, %v, want %q", got, err, want)
+	}
+	got, err = wrapRunAs("whoami", "postgres", "su", &config.SSHConfig{RunAsUsers: []string{"*"}})
+	if want := `su - postgres -c 'whoami'`; err != nil || got != want {
+		t.Errorf("su: got %q, %v, want %q", got, err, want)
+	}
+
+	for _, tt := range []struct {
+		user, method string
+		allowed      []string
+		allowSudo    bool
+	}{
+		{"deploy", "", nil, true},
+		{"root", "", []string{"deploy"}, true},
+		{"-u root", "", []string{"*"}, true},
+		{"deploy", "doas", []string{"deploy"}, true},
+		{"deploy", "", []string{"deploy"}, false},
+		{"deploy", "sudo", []string{"*"}, false},
+	} {
+		cfg := &config.SSHConfig{RunAsUsers: tt.allowed, AllowSudo: tt.allowSudo}
+		if got, err := wrapRunAs("id", tt.user, tt.method, cfg); err == nil {
+			t.Errorf("wrapRunAs(%q, %q, %v, sudo=%v) = %q, want error", tt.user, tt.method, tt.allowed, tt.allowSudo, got)
+		}
+	}
+}
+
+func TestExecOutput_TimedOut(t *testing.T) {
+	res := &remoteResult{
+		Stdout:   "step 1\nstep 2\n",
+		ExitCode: -1,
+		TimedOut: true,
+		Duration: 3 * time.Second,
+	}
+	out := execOutput(res, 2*time.Second, nil, 0)
+	if !out.TimedOut {
+		t.Error("expected TimedOut to be set")
+	}
+	if out.Stdout != res.Stdout {
+		t.Errorf("expected partial stdout to be kept, got %q", out.Stdout)
+	}
+	if out.DurationMs != 3000 {
+		t.Errorf("DurationMs = %d, want 3000", out.DurationMs)
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"timed_out":true`) {
+		t.Errorf("expected timed_out in JSON, got %s", data)
+	}
+}
+
+func TestRetryConnection(t *testing.T) {
+	ctx := context.Background()
+	lost := errors.New("connection lost")
+
+	// Succeeds on the third attempt, with doubling backoff in between.
+	var calls []time.Time
+	n, err := retryConnection(ctx, 3, 10*time.Millisecond, func(attempt int) error {
+		calls = append(calls, time.Now())
+		if attempt < 3 {
+			return lost
+		}
+		return nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("retryConnection = %d, %v; want 3, nil", n, err)
+	}
+	if d := calls[2].Sub(calls[1]); d < 20*time.Millisecond {
+		t.Errorf("second backoff %v, want at least 20ms", d)
+	}
+
+	// Gives up after retries+1 attempts.
+	n, err = retryConnection(ctx, 2, time.Millisecond, func(int) error { return lost })
+	if !errors.Is(err, lost) || n != 3 {
+		t.Errorf("retryConnection = %d, %v; want 3, %v", n, err, lost)
+	}
+
+	// Without retries the first error is returned as is.
+	n, err = retryConnection(ctx, 0, time.Hour, func(int) error { return lost })
+	if !errors.Is(err, lost) || n != 1 {
+		t.Errorf("retryConnection = %d, %v; want 1, %v", n, err, lost)
+	}
+
+	// Cancellation stops the backoff wait.
+	cctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	n, err = retryConnection(cctx, 5, time.Hour, func(int) error { return lost })
+	if !errors.Is(err, lost) || n != 1 || time.Since(start) > time.Second {
+		t.Errorf("retryConnection after cancel = %d, %v in %v", n, err, time.Since(start))
+	}
+}
+
+func TestSSHExecuteOutputText_Attempts(t *testing.T) {
+	out := SSHExecuteOutput{Stdout: "ok", Attempts: 2}
+	if got := out.Text(); !strings.Contains(got, "after 2 attempts") {
+		t.Errorf("Text() = %q, want attempts note", got)
+	}
+}
+
+func TestParseJSONOutput(t *testing.T) {
+	tests := []struct {
+		stdout, want, err string
+	}{
+		{`[{"Id": "abc", "State": {"Running": true}}]` + "\n", `[{"Id": "abc", "State": {"Running": true}}]`, ""},
+		{"  42  ", "42", ""},
+		{"{\"a\":1}\n\n{\"a\":2}\n", `[{"a":1},{"a":2}]`, ""},
+		{"", "", "stdout is empty"},
+		{"Error: no such container", "", "stdout is not JSON: invalid character"},
+		{"{\"a\":1}\nnot json", "", "stdout is not JSON"},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONOutput(tt.stdout)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: err = %v, want %q", tt.stdout, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%q = %s, %v; want %s", tt.stdout, got, err, tt.want)
+		}
+	}
+}
+
+func TestSSHExecuteOutput_ParseJSON(t *testing.T) {
+	out := SSHExecuteOutput{Stdout: `{"kind":"Pod"}`, JSON: json.RawMessage(`{"kind":"Pod"}`)}
+	data, err := json.Marshal(out.Structured())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `"stdout":""`) || !strings.Contains(got, `"json":{"kind":"Pod"}`) {
+		t.Errorf("structured = %s", got)
+	}
+	if out.Text() != `{"kind":"Pod"}` {
+		t.Errorf("Text() = %q, want stdout unchanged", out.Text())
+	}
+
+	failed := SSHExecuteOutput{Stdout: "oops", JSONError: "stdout is not JSON: invalid character 'o'"}
+	if got := failed.Text(); got != "oops\n[parse_json] stdout is not JSON: invalid character 'o'" {
+		t.Errorf("Text() = %q", got)
+	}
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+const (
+	maxExpectSteps    = 20
+	maxExpectBuffered = 64 << 10 // output kept for matching since the last match
+)
+
+// expectStep is a compiled ExpectStep.
+type expectStep struct {
+	re      *regexp.Regexp
+	send    string
+	timeout time.Duration // 0: until the command's timeout
+}
+
+// compileExpect validates and compiles the expect steps of ssh_execute.
+// Every line a step sends is input for the program, so it goes through
+// the command filter like a command would.
+func compileExpect(filter *security.Filter, steps []ExpectStep) ([]expectStep, error) {
+	if len(steps) > maxExpectSteps {
+		return nil, fmt.Errorf("at most %d expect steps", maxExpectSteps)
+	}
+	compiled := make([]expectStep, 0, len(steps))
+	for i, st := range steps {
+		if st.Pattern == "" {
+			return nil, fmt.Errorf("expect step %d: pattern is required", i+1)
+		}
+		re, err := regexp.Compile(st.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("expect step %d: invalid pattern: %w", i+1, err)
+		}
+		if st.Timeout < 0 {
+			return nil, fmt.Errorf("expect step %d: timeout must not be negative", i+1)
+		}
+		send := escapeReplacer.Replace(st.Send)
+		for _, line := range strings.FieldsFunc(send, func(r rune) bool { return r == '\n' || r == '\r' }) {
+			if err := filter.AllowCommand(line); err != nil {
+				return nil, fmt.Errorf("expect step %d: %w", i+1, err)
+			}
+		}
+		compiled = append(compiled, expectStep{re: re, send: send, timeout: time.Duration(st.Timeout) * time.Second})
+	}
+	return compiled, nil
+}
+
+// expectStream receives a command's stdout and stderr in the order they
+// arrive and keeps what has not been matched yet.
+type expectStream struct {
+	mu      sync.Mutex
+	buf     []byte
+	changed chan struct{} // closed and replaced on every write
+}
+
+func newExpectStream() *expectStream {
+	return &expectStream{changed: make(chan struct{})}
+}
+
+// Write implements io.Writer.
+func (s *expectStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, p...)
+	if over := len(s.buf) - maxExpectBuffered; over > 0 {
+		s.buf = s.buf[over:]
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return len(p), nil
+}
+
+// next waits for re to match the unmatched output and drops the output up
+// to the end of the match. It fails when timeout (if non-zero) passes or
+// ctx is done first; once ctx is done, output that already arrived is
+// still matched, since a command may print its last prompt and exit.
+func (s *expectStream) next(ctx context.Context, re *regexp.Regexp, timeout time.Duration) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		matched, changed := s.match(re)
+		if matched {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			return fmt.Errorf("pattern %q not seen within %s", re.String(), timeout)
+		case <-ctx.Done():
+			if matched, _ := s.match(re); matched {
+				return nil
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// match looks for re in the unmatched output, dropping the output up to
+// the end of a match. It also returns the channel closed on the next write.
+func (s *expectStream) match(re *regexp.Regexp) (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loc := re.FindIndex(s.buf)
+	if loc != nil {
+		s.buf = s.buf[loc[1]:]
+	}
+	return loc != nil, s.changed
+}
+
+// runExpect writes prefix (e.g. a sudo password) to stdin, then for each
+// step waits for its pattern and writes its response. stdin is closed at
+// the end, so later reads see end of file instead of hanging. It returns
+// the number of steps whose pattern matched, and the error of the step
+// that failed.
+func runExpect(ctx context.Context, stream *expectStream, stdin io.WriteCloser, prefix string, steps []expectStep) (int, error) {
+	defer stdin.Close()
+	if prefix != "" {
+		if _, err := io.WriteString(stdin, prefix); err != nil {
+			return 0, fmt.Errorf("write stdin: %w", err)
+		}
+	}
+	for i, st := range steps {
+		if err := stream.next(ctx, st.re, st.timeout); err != nil {
+			return i, fmt.Errorf("expect step %d: %w", i+1, err)
+		}
+		if st.send != "" {
+			if _, err := io.WriteString(stdin, st.send); err != nil {
+				return i + 1, fmt.Errorf("expect step %d: write response: %w", i+1, err)
+			}
+		}
+	}
+	return len(steps), nil
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func TestCompileExpect(t *testing.T) {
+	filter, err := security.NewFilter(nil, nil, nil, []string{`rm\s.*`})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	steps, err := compileExpect(filter, []ExpectStep{{Pattern: `\[y/N\]`, Send: `y\n`, Timeout: 5}, {Pattern: "done"}})
+	if err != nil {
+		t.Fatalf("compileExpect: %v", err)
+	}
+	if len(steps) != 2 || steps[0].send != "y\n" || steps[0].timeout != 5*time.Second || steps[1].timeout != 0 {
+		t.Errorf("steps = %+v", steps)
+	}
+
+	tests := []struct {
+		steps []ExpectStep
+		want  string
+	}{
+		{[]ExpectStep{{Send: "y\n"}}, "step 1: pattern is required"},
+		{[]ExpectStep{{Pattern: "ok"}, {Pattern: "(", Send: "y"}}, "step 2: invalid pattern"},
+		{[]ExpectStep{{Pattern: "ok", Timeout: -1}}, "must not be negative"},
+		{[]ExpectStep{{Pattern: `\$ $`, Send: `ls\nrm -rf /\n`}}, "denied"},
+		{make([]ExpectStep, maxExpectSteps+1), "at most"},
+	}
+	for _, tt := range tests {
+		if _, err := compileExpect(filter, tt.steps); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.steps, err, tt.want)
+		}
+	}
+}
+
+// pipeStdin records what runExpect writes and whether it closed stdin.
+type pipeStdin struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+	wrote  chan struct{}
+}
+
+func (p *pipeStdin) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p.buf.Write(b)
+	p.wrote <- struct{}{}
+	return len(b), nil
+}
+
+func (p *pipeStdin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func TestRunExpect(t *testing.T) {
+	steps, err := compileExpect(&security.Filter{}, []ExpectStep{
+		{Pattern: `Continue\? \[y/N\]`, Send: "y\n"},
+		{Pattern: `Name: $`, Send: "web\n"},
+	})
+	if err != nil {
+		t.Fatalf("compileExpect: %v", err)
+	}
+	stream := newExpectStream()
+	stdin := &pipeStdin{wrote: make(chan struct{}, 10)}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := runExpect(context.Background(), stream, stdin, "secret\n", steps)
+		done <- result{n, err}
+	}()
+
+	<-stdin.wrote // the prefix goes first
+	// A prompt split across writes still matches.
+	stream.Write([]byte("Removing 3 packages. Contin"))
+	stream.Write([]byte("ue? [y/N] "))
+	<-stdin.wrote
+	stream.Write([]byte("Name: "))
+	r := <-done
+	if r.err != nil || r.n != 2 {
+		t.Fatalf("runExpect = %d, %v", r.n, r.err)
+	}
+	if got := stdin.buf.String(); got != "secret\ny\nweb\n" {
+		t.Errorf("stdin = %q", got)
+	}
+	if !stdin.closed {
+		t.Error("stdin not closed after the last step")
+	}
+}
+
+func TestRunExpect_StepTimeout(t *testing.T) {
+	steps, _ := compileExpect(&security.Filter{}, []ExpectStep{{Pattern: "ok", Send: "1\n"}, {Pattern: "never", Timeout: 1}})
+	steps[1].timeout = 20 * time.Millisecond
+	stream := newExpectStream()
+	stream.Write([]byte("ok\n"))
+	stdin := &pipeStdin{wrote: make(chan struct{}, 10)}
+	n, err := runExpect(context.Background(), stream, stdin, "", steps)
+	if n != 1 || err == nil || !strings.Contains(err.Error(), `expect step 2: pattern "never" not seen within 20ms`) {
+		t.Errorf("runExpect = %d, %v", n, err)
+	}
+	if !stdin.closed {
+		t.Error("stdin not closed after a failed step")
+	}
+}
+
+func TestRunExpect_CancelledMatchesArrivedOutput(t *testing.T) {
+	steps, _ := compileExpect(&security.Filter{}, []ExpectStep{{Pattern: "first"}, {Pattern: "second"}, {Pattern: "third"}})
+	stream := newExpectStream()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		n, _ := runExpect(ctx, stream, &pipeStdin{wrote: make(chan struct{}, 10)}, "", steps)
+		done <- n
+	}()
+	// The command prints two prompts and exits before the third.
+	stream.Write([]byte("first second"))
+	cancel()
+	if n := <-done; n != 2 {
+		t.Errorf("matched %d steps, want 2", n)
+	}
+}
+
+func TestExpectStream_Cap(t *testing.T) {
+	stream := newExpectStream()
+	stream.Write([]byte("marker"))
+	stream.Write(bytes.Repeat([]byte("x"), maxExpectBuffered))
+	if len(stream.buf) != maxExpectBuffered {
+		t.Errorf("buffered %d bytes, want %d", len(stream.buf), maxExpectBuffered)
+	}
+	if matched, _ := stream.match(regexp.MustCompile("marker")); matched {
+		t.Error("output dropped by the cap still matched")
+	}
+}
@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// FetchURLDeps holds dependencies for the ssh_fetch_url tool handler.
+type FetchURLDeps struct {
+	Pool        *connection.Pool
+	LocalDirs   []config.LocalDir
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+	Domains     []string     // --fetch-allow-domain
+	MaxSize     int64        // --fetch-max-size, 0 = unlimited
+	Client      *http.Client // nil = a client with fetchHeaderTimeout
+}
+
+// fetchHeaderTimeout bounds the wait for the response headers; the body
+// itself may take as long as the call runs.
+const fetchHeaderTimeout = 30 * time.Second
+
+// maxFetchRedirects is how many redirects ssh_fetch_url follows.
+const maxFetchRedirects = 5
+
+// errFetchTooLarge is returned once a download exceeds --fetch-max-size.
+var errFetchTooLarge = errors.New("download exceeds --fetch-max-size")
+
+// HandleFetchURL implements the ssh_fetch_url tool. It downloads an HTTPS
+// URL on an allowed domain and writes it either to remote_path on a session,
+// streamed over SFTP, or to local_path on the MCP host. The target is only
+// replaced once the whole body arrived within the size cap and, if given,
+// matched sha256, so a failed fetch leaves no partial file.
+func HandleFetchURL(ctx context.Context, deps *FetchURLDeps, input SSHFetchURLInput) (*SSHFetchURLOutput, error) {
+	u, err := security.ValidateFetchURL(input.URL, deps.Domains)
+	if err != nil {
+		return nil, err
+	}
+	wantSum := strings.ToLower(strings.TrimPrefix(input.SHA256, "sha256:"))
+	if wantSum != "" {
+		if b, err := hex.DecodeString(wantSum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("sha256 must be 64 hex digits")
+		}
+	}
+	switch {
+	case (input.RemotePath == "") == (input.LocalPath == ""):
+		return nil, fmt.Errorf("set exactly one of remote_path and local_path")
+	case input.RemotePath != "" && input.SessionID == "":
+		return nil, fmt.Errorf("remote_path needs session_id")
+	case input.RemotePath != "":
+		if err := security.ValidatePath(input.RemotePath); err != nil {
+			return nil, fmt.Errorf("invalid remote path: %w", err)
+		}
+	default:
+		if err := security.ValidateLocalAccess(input.LocalPath, deps.LocalDirs, true); err != nil {
+			return nil, fmt.Errorf("invalid local path: %w", err)
+		}
+	}
+
+	// Connect before the download starts so a bad session fails fast.
+	var conn *connection.Connection
+	var write func(io.Reader) (int64, error)
+	dest := input.LocalPath
+	if input.RemotePath != "" {
+		c, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+		sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+		if err != nil {
+			return nil, err
+		}
+		defer sc.Close()
+		dest = expandRemotePath(conn, sc, input.RemotePath)
+		mode := uploadModes(deps.Config).FileMode(0o644)
+		write = func(r io.Reader) (int64, error) {
+			return sshclient.WriteFileAtomicFrom(sc, dest, r, mode)
+		}
+	} else {
+		write = func(r io.Reader) (int64, error) { return writeLocalAtomic(dest, r) }
+	}
+
+	resp, err := fetchGet(ctx, deps, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if deps.MaxSize > 0 && resp.ContentLength > deps.MaxSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes, limit %d", errFetchTooLarge, u.Redacted(), resp.ContentLength, deps.MaxSize)
+	}
+
+	body := &fetchBody{r: sshclient.NewContextReader(ctx, resp.Body), max: deps.MaxSize, want: wantSum, hash: sha256.New()}
+	n, err := write(body)
+	if err != nil {
+		if conn != nil {
+			conn.SetLastError(err)
+		}
+		return nil, fmt.Errorf("fetch %s: %w", u.Redacted(), err)
+	}
+	if conn != nil {
+		conn.AddBytesUploaded(n)
+	}
+
+	out := &SSHFetchURLOutput{
+		URL:         u.Redacted(),
+		FinalURL:    resp.Request.URL.Redacted(),
+		Bytes:       n,
+		SHA256:      body.sum,
+		Verified:    wantSum != "",
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if out.FinalURL == out.URL {
+		out.FinalURL = ""
+	}
+	if input.RemotePath != "" {
+		out.RemotePath = dest
+		out.Message = fmt.Sprintf("Fetched %d bytes to %s on %s", n, dest, input.SessionID)
+	} else {
+		out.LocalPath = dest
+		out.Message = fmt.Sprintf("Fetched %d bytes to %s", n, dest)
+	}
+	return out, nil
+}
+
+// fetchGet sends the GET request. Every redirect must stay on https and
+// an allowed domain.
+func fetchGet(ctx context.Context, deps *FetchURLDeps, u *url.URL) (*http.Response, error) {
+	var client http.Client
+	if deps.Client != nil {
+		client = *deps.Client
+	} else {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = fetchHeaderTimeout
+		client.Transport = transport
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxFetchRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+		}
+		if _, err := security.ValidateFetchURL(req.URL.String(), deps.Domains); err != nil {
+			return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), err)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ssh-mcp/"+config.Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u.Redacted(), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: %s", u.Redacted(), resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchBody hashes the response body as it is read. It fails the read
+// that goes past max, and at EOF one whose SHA-256 differs from want, so
+// the atomic writers drop the download instead of committing it.
+type fetchBody struct {
+	r    io.Reader
+	max  int64
+	want string
+	hash hash.Hash
+	n    int64
+	sum  string // hex SHA-256, set at EOF
+}
+
+func (b *fetchBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if b.max > 0 && b.n > b.max {
+		return 0, fmt.Errorf("%w (%d bytes)", errFetchTooLarge, b.max)
+	}
+	b.hash.Write(p[:n])
+	if err == io.EOF {
+		b.sum = hex.EncodeToString(b.hash.Sum(nil))
+		if b.want != "" && b.sum != b.want {
+			return n, fmt.Errorf("sha256 mismatch: got %s, want %s", b.sum, b.want)
+		}
+	}
+	return n, err
+}
+
+// writeLocalAtomic writes r to a temporary file next to localPath and
+// renames it into place, so a failed read leaves localPath untouched.
+func writeLocalAtomic(localPath string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".ssh-mcp-*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), localPath); err != nil {
+		return 0, fmt.Errorf("rename into place: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func fetchTestServer(t *testing.T) (*httptest.Server, *FetchURLDeps) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write([]byte("release contents"))
+	})
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/app.tar.gz", http.StatusFound)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://evil.example/x", http.StatusFound)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		// No Content-Length: the cap must hold while streaming.
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return srv, &FetchURLDeps{Domains: []string{u.Hostname()}, MaxSize: 50, Client: srv.Client()}
+}
+
+func TestHandleFetchURL_Local(t *testing.T) {
+	srv, deps := fetchTestServer(t)
+	dir := t.TempDir()
+	deps.LocalDirs = []config.LocalDir{{Path: dir}}
+	sum := sha256.Sum256([]byte("release contents"))
+	local := filepath.Join(dir, "app.tar.gz")
+
+	out, err := HandleFetchURL(context.Background(), deps, SSHFetchURLInput{
+		URL: srv.URL + "/latest", LocalPath: local, SHA256: hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "release contents" {
+		t.Errorf("file = %q", data)
+	}
+	if out.Bytes != 16 || !out.Verified || out.FinalURL != srv.URL+"/app.tar.gz" || out.ContentType != "application/gzip" {
+		t.Errorf("output = %+v", out)
+	}
+	if text := out.Text(); !strings.Contains(text, "(verified)") || !strings.Contains(text, "Redirected to:") {
+		t.Errorf("Text():\n%s", text)
+	}
+}
+
+func TestHandleFetchURL_Rejected(t *testing.T) {
+	srv, deps := fetchTestServer(t)
+	dir := t.TempDir()
+	deps.LocalDirs = []config.LocalDir{{Path: dir}}
+	local := filepath.Join(dir, "out")
+	if err := os.WriteFile(local, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range map[string]struct {
+		input SSHFetchURLInput
+		want  string
+	}{
+		"domain":      {SSHFetchURLInput{URL: "https://example.com/x", LocalPath: local}, "not in the fetch allowlist"},
+		"http":        {SSHFetchURLInput{URL: strings.Replace(srv.URL, "https", "http", 1) + "/x", LocalPath: local}, "only https"},
+		"redirect":    {SSHFetchURLInput{URL: srv.URL + "/elsewhere", LocalPath: local}, "not in the fetch allowlist"},
+		"status":      {SSHFetchURLInput{URL: srv.URL + "/missing", LocalPath: local}, "404"},
+		"size":        {SSHFetchURLInput{URL: srv.URL + "/big", LocalPath: local}, "--fetch-max-size"},
+		"checksum":    {SSHFetchURLInput{URL: srv.URL + "/app.tar.gz", LocalPath: local, SHA256: strings.Repeat("0", 64)}, "sha256 mismatch"},
+		"bad sum":     {SSHFetchURLInput{URL: srv.URL + "/app.tar.gz", LocalPath: local, SHA256: "abc"}, "64 hex digits"},
+		"both":        {SSHFetchURLInput{URL: srv.URL + "/app.tar.gz", LocalPath: local, RemotePath: "/tmp/x", SessionID: "s"}, "exactly one"},
+		"no session":  {SSHFetchURLInput{URL: srv.URL + "/app.tar.gz", RemotePath: "/tmp/x"}, "needs session_id"},
+		"outside dir": {SSHFetchURLInput{URL: srv.URL + "/app.tar.gz", LocalPath: "/etc/app.tar.gz"}, "invalid local path"},
+	} {
+		_, err := HandleFetchURL(context.Background(), deps, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", name, err, tt.want)
+		}
+	}
+	if data, _ := os.ReadFile(local); string(data) != "old" {
+		t.Errorf("failed fetches changed the target: %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestFetchBody_Size(t *testing.T) {
+	b := &fetchBody{r: strings.NewReader("0123456789"), max: 5, hash: sha256.New()}
+	buf := make([]byte, 4)
+	var err error
+	for err == nil {
+		_, err = b.Read(buf)
+	}
+	if !errors.Is(err, errFetchTooLarge) {
+		t.Errorf("err = %v, want errFetchTooLarge", err)
+	}
+}
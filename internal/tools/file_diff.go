@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// defaultDiffContext is the number of context lines around each change.
+const defaultDiffContext = 3
+
+// FileDiffDeps holds dependencies for the ssh_diff tool handler.
+type FileDiffDeps struct {
+	Pool          *connection.Pool
+	RateLimiter   *security.RateLimiter
+	LocalDirs     []config.LocalDir
+	MaxFileSize   int64
+	MaxOutputSize int
+}
+
+// HandleDiff implements the ssh_diff tool. remote_path is the old side of the
+// diff; the new side is a local file, a second remote file, or inline content.
+// Nothing is modified.
+func HandleDiff(ctx context.Context, deps *FileDiffDeps, input SSHDiffInput) (*SSHDiffOutput, error) {
+	sources := 0
+	for _, set := range []bool{input.LocalPath != "", input.OtherRemotePath != "", input.Content != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("exactly one of local_path, other_remote_path, or content must be provided")
+	}
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	if input.OtherRemotePath != "" {
+		if err := security.ValidatePath(input.OtherRemotePath); err != nil {
+			return nil, fmt.Errorf("invalid other remote path: %w", err)
+		}
+	}
+	if input.LocalPath != "" {
+		if err := security.ValidateLocalAccess(input.LocalPath, deps.LocalDirs, false); err != nil {
+			return nil, fmt.Errorf("invalid local path: %w", err)
+		}
+	}
+	contextLines := defaultDiffContext
+	if input.ContextLines != nil {
+		if *input.ContextLines < 0 {
+			return nil, fmt.Errorf("invalid context_lines: %d (must be non-negative)", *input.ContextLines)
+		}
+		contextLines = *input.ContextLines
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	// A missing remote_path diffs as an empty file, so the tool can preview
+	// creating a file too.
+	oldPath := expandRemotePath(conn, sc, input.RemotePath)
+	oldName := oldPath
+	oldData, err := sshclient.ReadFile(ctx, sc, oldPath, deps.MaxFileSize)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err):
+		oldData, oldName = nil, "/dev/null"
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", oldPath, err)
+	}
+	conn.AddBytesDownloaded(int64(len(oldData)))
+
+	var newName string
+	var newData []byte
+	switch {
+	case input.OtherRemotePath != "":
+		newName = expandRemotePath(conn, sc, input.OtherRemotePath)
+		if newData, err = sshclient.ReadFile(ctx, sc, newName, deps.MaxFileSize); err != nil {
+			return nil, fmt.Errorf("read %s: %w", newName, err)
+		}
+		conn.AddBytesDownloaded(int64(len(newData)))
+	case input.LocalPath != "":
+		newName = input.LocalPath
+		info, err := os.Stat(input.LocalPath)
+		if err != nil {
+			return nil, fmt.Errorf("stat local file: %w", err)
+		}
+		if deps.MaxFileSize > 0 && info.Size() > deps.MaxFileSize {
+			return nil, fmt.Errorf("local file %s is %d bytes, exceeds max file size %d", input.LocalPath, info.Size(), deps.MaxFileSize)
+		}
+		if newData, err = os.ReadFile(input.LocalPath); err != nil {
+			return nil, fmt.Errorf("read local file: %w", err)
+		}
+	default:
+		newName = oldPath + " (proposed)"
+		newData = []byte(*input.Content)
+	}
+
+	out := &SSHDiffOutput{OldPath: oldName, NewPath: newName}
+	if bytes.IndexByte(oldData, 0) >= 0 || bytes.IndexByte(newData, 0) >= 0 {
+		out.Binary = true
+		out.Identical = bytes.Equal(oldData, newData)
+		return out, nil
+	}
+	diff, stats, err := unifiedDiff(oldName, newName, string(oldData), string(newData), contextLines)
+	if err != nil {
+		return nil, err
+	}
+	out.Identical = diff == ""
+	out.Added, out.Removed = stats.Added, stats.Removed
+	out.Diff = TruncateOutput(diff, deps.MaxOutputSize)
+	return out, nil
+}
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -10,41 +12,91 @@ import (
 
 	"github.com/pkg/sftp"
 
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
 	"github.com/n0madic/ssh-mcp/internal/sshclient"
 )
 
+// errEditConflict marks an edit refused because the file changed since the
+// caller read it (expected_hash).
+var errEditConflict = errors.New("edit conflict")
+
 // FileEditDeps holds dependencies for the ssh_edit_file tool handler.
 type FileEditDeps struct {
 	Pool        *connection.Pool
 	RateLimiter *security.RateLimiter
 	MaxFileSize int64
+	Backup      config.BackupConfig
+	Locks       *EditLocks // per-file edit locks shared by all sessions; nil = none
 }
 
 // HandleEditFile implements the ssh_edit_file tool.
 func HandleEditFile(ctx context.Context, deps *FileEditDeps, input SSHEditFileInput) (*SSHEditFileOutput, error) {
+	return editFile(ctx, deps, input, nil)
+}
+
+// editFile runs an edit. beforeEdit, if set, is called with the expanded
+// path once the file's edit lock is held and before anything is written;
+// an error from it cancels the edit.
+func editFile(ctx context.Context, deps *FileEditDeps, input SSHEditFileInput, beforeEdit func(sc *sftp.Client, remotePath string) error) (*SSHEditFileOutput, error) {
 	if err := security.ValidatePath(input.RemotePath); err != nil {
 		return nil, fmt.Errorf("invalid remote path: %w", err)
 	}
 
-	_, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	sc, err := sshclient.NewSFTPClient(client)
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer sc.Close()
 
-	input.RemotePath = sshclient.ExpandRemotePath(sc, input.RemotePath)
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
 
 	mode := input.Mode
 	if mode == "" {
 		mode = "replace"
 	}
+	if input.ExpectedHash != "" {
+		input.ExpectedHash = strings.ToLower(input.ExpectedHash)
+		if b, err := hex.DecodeString(input.ExpectedHash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("expected_hash must be a hex SHA-256 (64 characters), as returned by ssh_read_file")
+		}
+	}
+
+	// Hold the file from the expected_hash check (or read) to the write.
+	unlock, err := deps.Locks.Lock(ctx, conn, input.RemotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if input.LockFile {
+		release, err := acquireRemoteLock(sc, input.RemotePath, "ssh-mcp session "+input.SessionID)
+		if err != nil {
+			conn.SetLastError(err)
+			return nil, err
+		}
+		defer release()
+	}
+	if beforeEdit != nil {
+		if err := beforeEdit(sc, input.RemotePath); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.ExpectedHash != "" {
+		// patch and lines check the content they read instead.
+		if mode != "patch" && mode != "lines" {
+			if err := checkExpectedHash(sc, input.RemotePath, input.ExpectedHash, nil); err != nil {
+				conn.SetLastError(err)
+				return nil, err
+			}
+		}
+	}
 
 	// Default backup to true.
 	doBackup := true
@@ -52,25 +104,40 @@ func HandleEditFile(ctx context.Context, deps *FileEditDeps, input SSHEditFileIn
 		doBackup = *input.Backup
 	}
 
+	var out *SSHEditFileOutput
 	switch mode {
 	case "replace":
-		return editReplace(sc, input, doBackup, deps.MaxFileSize)
+		out, err = editReplace(ctx, sc, deps, input, doBackup)
 	case "patch":
-		return editPatch(sc, deps, input, doBackup)
+		out, err = editPatch(ctx, sc, deps, input, doBackup)
+	case "lines":
+		out, err = editLines(ctx, sc, deps, input, doBackup)
+	case "append":
+		out, err = editAppend(sc, input)
+	case "write_at":
+		out, err = editWriteAt(sc, input)
 	default:
-		return nil, fmt.Errorf("unknown edit mode: %q (must be 'replace' or 'patch')", mode)
+		return nil, fmt.Errorf("unknown edit mode: %q (must be 'replace', 'patch', 'lines', 'append', or 'write_at')", mode)
 	}
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	conn.AddBytesUploaded(out.BytesWritten)
+	return out, nil
 }
 
-func editReplace(sc *sftp.Client, input SSHEditFileInput, doBackup bool, maxFileSize int64) (*SSHEditFileOutput, error) {
+func editReplace(ctx context.Context, sc *sftp.Client, deps *FileEditDeps, input SSHEditFileInput, doBackup bool) (*SSHEditFileOutput, error) {
 	_, statErr := sc.Stat(input.RemotePath)
 	if statErr != nil && !os.IsNotExist(statErr) {
 		return nil, fmt.Errorf("stat remote file: %w", statErr)
 	}
 	isNewFile := os.IsNotExist(statErr)
 
+	var backupPath string
 	if doBackup {
-		if err := createBackup(sc, input.RemotePath, maxFileSize); err != nil {
+		var err error
+		if backupPath, err = createBackup(ctx, sc, deps, input.RemotePath); err != nil {
 			return nil, fmt.Errorf("create backup: %w", err)
 		}
 	}
@@ -78,7 +145,7 @@ func editReplace(sc *sftp.Client, input SSHEditFileInput, doBackup bool, maxFile
 	// Preserve existing permissions or default to 0644.
 	var perms = defaultPerms(sc, input.RemotePath)
 
-	n, err := sshclient.WriteFile(sc, input.RemotePath, []byte(input.Content), perms)
+	n, err := sshclient.WriteFileAtomic(sc, input.RemotePath, []byte(input.Content), perms)
 	if err != nil {
 		return nil, fmt.Errorf("write file: %w", err)
 	}
@@ -90,62 +157,300 @@ func editReplace(sc *sftp.Client, input SSHEditFileInput, doBackup bool, maxFile
 
 	return &SSHEditFileOutput{
 		BytesWritten: n,
+		BackupPath:   backupPath,
+		SHA256:       contentHash([]byte(input.Content)),
 		Message:      message,
 	}, nil
 }
 
-func editPatch(sc *sftp.Client, deps *FileEditDeps, input SSHEditFileInput, doBackup bool) (*SSHEditFileOutput, error) {
-	if input.OldString == "" {
-		return nil, fmt.Errorf("old_string is required for patch mode")
+func editPatch(ctx context.Context, sc *sftp.Client, deps *FileEditDeps, input SSHEditFileInput, doBackup bool) (*SSHEditFileOutput, error) {
+	edits := input.Edits
+	switch {
+	case len(edits) > 0 && (input.OldString != "" || input.NewString != "" || input.ReplaceAll || input.ExpectedCount != nil):
+		return nil, fmt.Errorf("use either edits or old_string/new_string, not both (set replace_all/expected_count per edit)")
+	case len(edits) == 0 && input.OldString == "":
+		return nil, fmt.Errorf("old_string (or edits) is required for patch mode")
+	case len(edits) == 0:
+		edits = []FileEdit{{
+			OldString:     input.OldString,
+			NewString:     input.NewString,
+			ReplaceAll:    input.ReplaceAll,
+			ExpectedCount: input.ExpectedCount,
+		}}
+	}
+	for i, e := range edits {
+		if e.OldString == "" {
+			return nil, fmt.Errorf("edit %d: old_string is required", i+1)
+		}
 	}
 
-	data, err := sshclient.ReadFile(sc, input.RemotePath, deps.MaxFileSize)
+	data, err := sshclient.ReadFile(ctx, sc, input.RemotePath, deps.MaxFileSize)
 	if err != nil {
 		return nil, fmt.Errorf("read file for patch: %w", err)
 	}
-
-	content := string(data)
-	if !strings.Contains(content, input.OldString) {
-		return nil, fmt.Errorf("old_string not found in %s", input.RemotePath)
+	if err := checkExpectedHash(sc, input.RemotePath, input.ExpectedHash, data); err != nil {
+		return nil, err
 	}
 
-	newContent := strings.Replace(content, input.OldString, input.NewString, 1)
+	newContent, replacements, err := applyEdits(string(data), edits)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, input.RemotePath)
+	}
 
+	perms := defaultPerms(sc, input.RemotePath)
+	var backupPath string
 	if doBackup {
-		perms := defaultPerms(sc, input.RemotePath)
-		if _, err := sshclient.WriteFile(sc, input.RemotePath+".bak", data, perms); err != nil {
+		if backupPath, err = writeBackup(sc, deps.Backup, input.RemotePath, data, perms); err != nil {
 			return nil, fmt.Errorf("create backup: %w", err)
 		}
 	}
 
+	n, err := sshclient.WriteFileAtomic(sc, input.RemotePath, []byte(newContent), perms)
+	if err != nil {
+		return nil, fmt.Errorf("write patched file: %w", err)
+	}
+
+	message := fmt.Sprintf("Patched %s: %d replacement(s) (%d bytes)", input.RemotePath, replacements, n)
+	if len(edits) > 1 {
+		message = fmt.Sprintf("Patched %s with %d edits: %d replacement(s) (%d bytes)", input.RemotePath, len(edits), replacements, n)
+	}
+	return &SSHEditFileOutput{
+		BytesWritten: n,
+		Replacements: replacements,
+		BackupPath:   backupPath,
+		SHA256:       contentHash([]byte(newContent)),
+		Message:      message,
+	}, nil
+}
+
+// applyEdits applies find-and-replace edits in order, each to the result of
+// the previous one, and returns the total number of replacements. An edit
+// replaces the first occurrence unless ReplaceAll is set. It fails without
+// partial results if any old_string is not found or its ExpectedCount does
+// not match.
+func applyEdits(content string, edits []FileEdit) (string, int, error) {
+	total := 0
+	for i, e := range edits {
+		prefix := ""
+		if len(edits) > 1 {
+			prefix = fmt.Sprintf("edit %d: ", i+1)
+		}
+		suffix := ""
+		if len(edits) > 1 {
+			suffix = " (no edits applied)"
+		}
+		count := strings.Count(content, e.OldString)
+		switch {
+		case e.ExpectedCount != nil && count != *e.ExpectedCount:
+			return "", 0, fmt.Errorf("%sold_string found %d time(s), expected %d%s", prefix, count, *e.ExpectedCount, suffix)
+		case count == 0:
+			return "", 0, fmt.Errorf("%sold_string not found%s", prefix, suffix)
+		}
+		if e.ReplaceAll {
+			content = strings.ReplaceAll(content, e.OldString, e.NewString)
+			total += count
+		} else {
+			content = strings.Replace(content, e.OldString, e.NewString, 1)
+			total++
+		}
+	}
+	return content, total, nil
+}
+
+func editLines(ctx context.Context, sc *sftp.Client, deps *FileEditDeps, input SSHEditFileInput, doBackup bool) (*SSHEditFileOutput, error) {
+	data, err := sshclient.ReadFile(ctx, sc, input.RemotePath, deps.MaxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("read file for line edit: %w", err)
+	}
+	if err := checkExpectedHash(sc, input.RemotePath, input.ExpectedHash, data); err != nil {
+		return nil, err
+	}
+
+	newContent, summary, err := applyLineEdit(string(data), input.Operation, input.StartLine, input.EndLine, input.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	perms := defaultPerms(sc, input.RemotePath)
+	var backupPath string
+	if doBackup {
+		if backupPath, err = writeBackup(sc, deps.Backup, input.RemotePath, data, perms); err != nil {
+			return nil, fmt.Errorf("create backup: %w", err)
+		}
+	}
 
-	n, err := sshclient.WriteFile(sc, input.RemotePath, []byte(newContent), perms)
+	n, err := sshclient.WriteFileAtomic(sc, input.RemotePath, []byte(newContent), perms)
 	if err != nil {
-		return nil, fmt.Errorf("write patched file: %w", err)
+		return nil, fmt.Errorf("write edited file: %w", err)
+	}
+
+	return &SSHEditFileOutput{
+		BytesWritten: n,
+		BackupPath:   backupPath,
+		SHA256:       contentHash([]byte(newContent)),
+		Message:      fmt.Sprintf("%s in %s (%d bytes)", summary, input.RemotePath, n),
+	}, nil
+}
+
+// editAppend adds content to the end of the file in place, creating the file
+// if needed. Existing bytes are never rewritten, so no backup is made.
+func editAppend(sc *sftp.Client, input SSHEditFileInput) (*SSHEditFileOutput, error) {
+	if input.Content == "" {
+		return nil, fmt.Errorf("content is required for append mode")
 	}
+	n, err := sshclient.AppendFile(sc, input.RemotePath, []byte(input.Content), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("append to file: %w", err)
+	}
+	return &SSHEditFileOutput{
+		BytesWritten: n,
+		Message:      fmt.Sprintf("Appended %d bytes to %s", n, input.RemotePath),
+	}, nil
+}
 
+// editWriteAt overwrites bytes at an offset in place, for patching large
+// files without rewriting them. It makes no backup, since that would mean
+// copying the whole file.
+func editWriteAt(sc *sftp.Client, input SSHEditFileInput) (*SSHEditFileOutput, error) {
+	if input.Offset == nil {
+		return nil, fmt.Errorf("offset is required for write_at mode")
+	}
+	offset := *input.Offset
+	if input.Content == "" {
+		return nil, fmt.Errorf("content is required for write_at mode")
+	}
+	stat, err := sc.Stat(input.RemotePath)
+	if err != nil {
+		return nil, fmt.Errorf("stat remote file: %w", err)
+	}
+	if offset < 0 || offset > stat.Size() {
+		return nil, fmt.Errorf("offset %d out of range (file is %d bytes)", offset, stat.Size())
+	}
+	n, err := sshclient.WriteFileAt(sc, input.RemotePath, []byte(input.Content), offset)
+	if err != nil {
+		return nil, fmt.Errorf("write file: %w", err)
+	}
 	return &SSHEditFileOutput{
 		BytesWritten: n,
-		Message:      fmt.Sprintf("Patched %s (%d bytes)", input.RemotePath, n),
+		Message:      fmt.Sprintf("Wrote %d bytes at offset %d in %s", n, offset, input.RemotePath),
 	}, nil
 }
 
-func createBackup(sc *sftp.Client, remotePath string, maxFileSize int64) error {
-	data, err := sshclient.ReadFile(sc, remotePath, maxFileSize)
+// applyLineEdit applies a line-addressed edit (lines mode) to content and
+// returns the new content with a short summary. Inserted text always ends up
+// as whole lines, using the file's line ending (CRLF if the file has any).
+func applyLineEdit(content, op string, start, end int, text string) (string, string, error) {
+	lines := splitLines(content)
+	nl := "\n"
+	if strings.Contains(content, "\r\n") {
+		nl = "\r\n"
+	}
+	var insert []string
+	if text != "" {
+		if !strings.HasSuffix(text, "\n") {
+			text += nl
+		}
+		insert = splitLines(text)
+	}
+	// Terminate a final line without newline before adding lines after it.
+	terminateLast := func() {
+		if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+			lines[n-1] += nl
+		}
+	}
+	if end == 0 {
+		end = start
+	}
+
+	var summary string
+	switch op {
+	case "append":
+		if len(insert) == 0 {
+			return "", "", fmt.Errorf("content is required for append")
+		}
+		terminateLast()
+		lines = append(lines, insert...)
+		summary = fmt.Sprintf("Appended %d line(s)", len(insert))
+	case "insert":
+		if len(insert) == 0 {
+			return "", "", fmt.Errorf("content is required for insert")
+		}
+		if start < 0 || start > len(lines) {
+			return "", "", fmt.Errorf("start_line %d out of range (file has %d lines; use 0 to insert at the top)", start, len(lines))
+		}
+		if start == len(lines) {
+			terminateLast()
+		}
+		lines = append(lines[:start], append(insert, lines[start:]...)...)
+		summary = fmt.Sprintf("Inserted %d line(s) after line %d", len(insert), start)
+	case "replace", "delete":
+		if start < 1 || end < start || end > len(lines) {
+			return "", "", fmt.Errorf("line range %d-%d out of range (file has %d lines)", start, end, len(lines))
+		}
+		if op == "delete" {
+			insert = nil
+			summary = fmt.Sprintf("Deleted lines %d-%d", start, end)
+		} else {
+			summary = fmt.Sprintf("Replaced lines %d-%d with %d line(s)", start, end, len(insert))
+		}
+		lines = append(lines[:start-1], append(insert, lines[end:]...)...)
+	case "":
+		return "", "", fmt.Errorf("operation is required for lines mode (insert, replace, delete, or append)")
+	default:
+		return "", "", fmt.Errorf("unknown operation: %q (must be 'insert', 'replace', 'delete', or 'append')", op)
+	}
+	return strings.Join(lines, ""), summary, nil
+}
+
+// createBackup backs up remotePath if it exists and returns the backup path
+// ("" for a new file).
+func createBackup(ctx context.Context, sc *sftp.Client, deps *FileEditDeps, remotePath string) (string, error) {
+	data, err := sshclient.ReadFile(ctx, sc, remotePath, deps.MaxFileSize)
 	if err != nil {
 		// Use errors.Is to traverse fmt.Errorf("%w") wrapping from ReadFile.
 		// os.IsNotExist only unwraps *os.PathError, not arbitrary wrappers.
 		if errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err) {
 			// File doesn't exist yet, no backup needed.
-			return nil
+			return "", nil
 		}
-		return fmt.Errorf("backup failed, cannot read %s: %w", remotePath, err)
+		return "", fmt.Errorf("backup failed, cannot read %s: %w", remotePath, err)
 	}
 
-	perms := defaultPerms(sc, remotePath)
-	_, err = sshclient.WriteFile(sc, remotePath+".bak", data, perms)
-	return err
+	return writeBackup(sc, deps.Backup, remotePath, data, defaultPerms(sc, remotePath))
+}
+
+// checkExpectedHash fails with errEditConflict unless the file's current
+// content has the SHA-256 want, i.e. nobody changed it since the caller
+// read it. data is that content if already read; nil hashes the file over
+// SFTP. An empty want skips the check. This narrows the window for lost
+// updates to the edit itself; it is not a lock.
+func checkExpectedHash(sc *sftp.Client, remotePath, want string, data []byte) error {
+	if want == "" {
+		return nil
+	}
+	var got string
+	if data != nil {
+		got = contentHash(data)
+	} else {
+		var err error
+		if got, err = sshclient.RemoteSHA256(sc, remotePath); err != nil {
+			if errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err) {
+				return fmt.Errorf("%w: %s no longer exists", errEditConflict, remotePath)
+			}
+			return fmt.Errorf("hash %s: %w", remotePath, err)
+		}
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s changed since it was read (sha256 %s, expected %s); read it again and redo the edit", errEditConflict, remotePath, got, want)
+	}
+	return nil
+}
+
+// contentHash returns the hex SHA-256 reported as sha256 by ssh_read_file
+// and ssh_edit_file.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 func defaultPerms(sc *sftp.Client, remotePath string) os.FileMode {
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyEdits(t *testing.T) {
+	content := "listen 80;\nserver_name old.example.com;\nroot /var/www/old;\n"
+	got, n, err := applyEdits(content, []FileEdit{
+		{OldString: "listen 80;", NewString: "listen 443 ssl;"},
+		{OldString: "old.example.com", NewString: "new.example.com"},
+		{OldString: "/var/www/old", NewString: "/var/www/new"},
+	})
+	if err != nil {
+		t.Fatalf("applyEdits: %v", err)
+	}
+	want := "listen 443 ssl;\nserver_name new.example.com;\nroot /var/www/new;\n"
+	if got != want || n != 3 {
+		t.Errorf("applyEdits = %q, %d; want %q, 3", got, n, want)
+	}
+}
+
+func TestApplyEdits_Sequential(t *testing.T) {
+	// Later edits see the result of earlier ones.
+	got, _, err := applyEdits("a", []FileEdit{{OldString: "a", NewString: "b"}, {OldString: "b", NewString: "c"}})
+	if err != nil || got != "c" {
+		t.Errorf("applyEdits = %q, %v; want %q", got, err, "c")
+	}
+}
+
+func TestApplyEdits_MissingOldString(t *testing.T) {
+	_, _, err := applyEdits("alpha beta", []FileEdit{
+		{OldString: "alpha", NewString: "ALPHA"},
+		{OldString: "gamma", NewString: "GAMMA"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "edit 2") || !strings.Contains(err.Error(), "no edits applied") {
+		t.Errorf("expected error naming edit 2, got %v", err)
+	}
+
+	_, _, err = applyEdits("alpha", []FileEdit{{OldString: "gamma"}})
+	if err == nil || err.Error() != "old_string not found" {
+		t.Errorf("expected single-edit error, got %v", err)
+	}
+}
+
+func TestApplyEdits_ReplaceAll(t *testing.T) {
+	got, n, err := applyEdits("a-b-a-c-a", []FileEdit{{OldString: "a", NewString: "x", ReplaceAll: true}})
+	if err != nil || got != "x-b-x-c-x" || n != 3 {
+		t.Errorf("applyEdits = %q, %d, %v; want %q, 3", got, n, err, "x-b-x-c-x")
+	}
+
+	got, n, err = applyEdits("a-b-a", []FileEdit{{OldString: "a", NewString: "x"}})
+	if err != nil || got != "x-b-a" || n != 1 {
+		t.Errorf("applyEdits = %q, %d, %v; want first occurrence only", got, n, err)
+	}
+}
+
+func TestApplyEdits_ExpectedCount(t *testing.T) {
+	one, two := 1, 2
+	_, _, err := applyEdits("a-b-a", []FileEdit{{OldString: "a", NewString: "x", ExpectedCount: &one}})
+	if err == nil || !strings.Contains(err.Error(), "found 2 time(s), expected 1") {
+		t.Errorf("expected count mismatch error, got %v", err)
+	}
+
+	got, n, err := applyEdits("a-b-a", []FileEdit{{OldString: "a", NewString: "x", ExpectedCount: &two, ReplaceAll: true}})
+	if err != nil || got != "x-b-x" || n != 2 {
+		t.Errorf("applyEdits = %q, %d, %v; want %q, 2", got, n, err, "x-b-x")
+	}
+
+	zero := 0
+	_, _, err = applyEdits("abc", []FileEdit{
+		{OldString: "a", NewString: "x"},
+		{OldString: "b", NewString: "y", ExpectedCount: &zero},
+	})
+	if err == nil || !strings.Contains(err.Error(), "edit 2: old_string found 1 time(s), expected 0") {
+		t.Errorf("expected batch count error, got %v", err)
+	}
+}
+
+func TestApplyLineEdit(t *testing.T) {
+	const file = "one\ntwo\nthree\n"
+	tests := []struct {
+		name       string
+		content    string
+		op         string
+		start, end int
+		text       string
+		want       string
+	}{
+		{"insert top", file, "insert", 0, 0, "zero", "zero\none\ntwo\nthree\n"},
+		{"insert middle", file, "insert", 2, 0, "2a\n2b\n", "one\ntwo\n2a\n2b\nthree\n"},
+		{"insert after last", "one\ntwo", "insert", 2, 0, "three", "one\ntwo\nthree\n"},
+		{"replace one", file, "replace", 2, 0, "TWO", "one\nTWO\nthree\n"},
+		{"replace range", file, "replace", 1, 2, "x", "x\nthree\n"},
+		{"replace with nothing", file, "replace", 3, 3, "", "one\ntwo\n"},
+		{"delete range", file, "delete", 2, 3, "", "one\n"},
+		{"append", file, "append", 0, 0, "four", "one\ntwo\nthree\nfour\n"},
+		{"append no final newline", "one", "append", 0, 0, "two", "one\ntwo\n"},
+		{"append empty file", "", "append", 0, 0, "first", "first\n"},
+		{"crlf", "a\r\nb\r\n", "insert", 1, 0, "x", "a\r\nx\r\nb\r\n"},
+	}
+	for _, tt := range tests {
+		got, _, err := applyLineEdit(tt.content, tt.op, tt.start, tt.end, tt.text)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyLineEdit_Errors(t *testing.T) {
+	const file = "one\ntwo\nthree\n"
+	tests := []struct {
+		op         string
+		start, end int
+		text       string
+		want       string
+	}{
+		{"", 1, 0, "x", "operation is required"},
+		{"swap", 1, 0, "x", "unknown operation"},
+		{"insert", 4, 0, "x", "out of range"},
+		{"insert", -1, 0, "x", "out of range"},
+		{"insert", 1, 0, "", "content is required"},
+		{"append", 0, 0, "", "content is required"},
+		{"replace", 0, 0, "x", "out of range"},
+		{"delete", 2, 4, "", "out of range"},
+		{"delete", 3, 2, "", "out of range"},
+	}
+	for _, tt := range tests {
+		_, _, err := applyLineEdit(file, tt.op, tt.start, tt.end, tt.text)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %d-%d: expected error containing %q, got %v", tt.op, tt.start, tt.end, tt.want, err)
+		}
+	}
+}
+
+func TestCheckExpectedHash(t *testing.T) {
+	data := []byte("key: value\n")
+	sum := contentHash(data)
+	if len(sum) != 64 {
+		t.Fatalf("contentHash = %q", sum)
+	}
+	if err := checkExpectedHash(nil, "/etc/app.yaml", sum, data); err != nil {
+		t.Errorf("matching hash: %v", err)
+	}
+	if err := checkExpectedHash(nil, "/etc/app.yaml", "", data); err != nil {
+		t.Errorf("empty hash should skip the check: %v", err)
+	}
+	err := checkExpectedHash(nil, "/etc/app.yaml", sum, []byte("key: changed\n"))
+	if !errors.Is(err, errEditConflict) || !strings.Contains(err.Error(), "changed since it was read") {
+		t.Errorf("changed file: err = %v, want edit conflict", err)
+	}
+}
+
+func TestSSHEditFileOutput_Text(t *testing.T) {
+	out := SSHEditFileOutput{Message: "Patched /etc/app.yaml", SHA256: "abc", BackupPath: "/etc/app.yaml.bak"}
+	if got, want := out.Text(), "Patched /etc/app.yaml\nSHA-256: abc\nBackup: /etc/app.yaml.bak"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/n0madic/ssh-mcp/internal/charset"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+const (
+	defaultHeadTailLines = 10
+	maxHeadTailLines     = 10000
+	// maxHeadTailBytes caps what ssh_file_head/ssh_file_tail return and scan.
+	maxHeadTailBytes = 1 << 20
+	headTailChunk    = 64 << 10
+)
+
+// HandleFileHead implements the ssh_file_head tool.
+func HandleFileHead(ctx context.Context, deps *FileReadDeps, input SSHFileHeadTailInput) (*SSHFileHeadTailOutput, error) {
+	return handleHeadTail(ctx, deps, input, false)
+}
+
+// HandleFileTail implements the ssh_file_tail tool.
+func HandleFileTail(ctx context.Context, deps *FileReadDeps, input SSHFileHeadTailInput) (*SSHFileHeadTailOutput, error) {
+	return handleHeadTail(ctx, deps, input, true)
+}
+
+// handleHeadTail reads only the start (or, with fromEnd, the end) of a
+// remote file, seeking instead of downloading the whole file. The server's
+// max file size does not apply since at most maxHeadTailBytes are read.
+func handleHeadTail(ctx context.Context, deps *FileReadDeps, input SSHFileHeadTailInput, fromEnd bool) (*SSHFileHeadTailOutput, error) {
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	switch {
+	case input.Lines != 0 && input.Bytes != 0:
+		return nil, fmt.Errorf("use either lines or bytes, not both")
+	case input.Lines < 0 || input.Bytes < 0:
+		return nil, fmt.Errorf("lines and bytes must be non-negative")
+	case input.Lines > maxHeadTailLines:
+		return nil, fmt.Errorf("lines must be at most %d", maxHeadTailLines)
+	}
+	dec, err := outputDecoder(deps.Config, input.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+
+	file, err := sc.Open(input.RemotePath)
+	if err != nil {
+		return nil, fmt.Errorf("open remote file: %w", err)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat remote file: %w", err)
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", input.RemotePath)
+	}
+	size := stat.Size()
+
+	var data []byte
+	var truncated bool
+	if input.Bytes > 0 {
+		n := input.Bytes
+		if n > maxHeadTailBytes {
+			n, truncated = maxHeadTailBytes, true
+		}
+		data, err = readByteRange(file, size, n, fromEnd)
+	} else {
+		lines := input.Lines
+		if lines == 0 {
+			lines = defaultHeadTailLines
+		}
+		if fromEnd {
+			data, truncated, err = tailLines(file, size, lines, maxHeadTailBytes)
+		} else {
+			data, truncated, err = headLines(file, lines, maxHeadTailBytes)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	conn.AddBytesDownloaded(int64(len(data)))
+
+	content, enc := dec.Decode(data)
+	if enc == charset.UTF8 {
+		enc = ""
+	}
+	out := &SSHFileHeadTailOutput{
+		Path:      input.RemotePath,
+		Content:   content,
+		Encoding:  enc,
+		FileSize:  size,
+		Lines:     countLines(data),
+		Bytes:     len(data),
+		Truncated: truncated,
+	}
+	which := "first"
+	if fromEnd {
+		which = "last"
+	}
+	out.Message = fmt.Sprintf("%s: %s %d line(s), %d bytes of %d", input.RemotePath, which, out.Lines, out.Bytes, size)
+	if truncated {
+		out.Message += fmt.Sprintf(" (capped at %d bytes)", maxHeadTailBytes)
+	}
+	return out, nil
+}
+
+// readByteRange reads the first or last n bytes of a file of the given size.
+func readByteRange(r io.ReaderAt, size, n int64, fromEnd bool) ([]byte, error) {
+	if n > size {
+		n = size
+	}
+	off := int64(0)
+	if fromEnd {
+		off = size - n
+	}
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// headLines reads up to n lines from the start of r. It stops after
+// maxBytes and reports truncation if the lines did not fit.
+func headLines(r io.Reader, n int, maxBytes int) ([]byte, bool, error) {
+	var buf []byte
+	chunk := make([]byte, headTailChunk)
+	count := 0
+	for {
+		m, err := r.Read(chunk)
+		for i, c := range chunk[:m] {
+			if c != '\n' {
+				continue
+			}
+			if count++; count == n {
+				buf = append(buf, chunk[:i+1]...)
+				if len(buf) > maxBytes {
+					return buf[:maxBytes], true, nil
+				}
+				return buf, false, nil
+			}
+		}
+		buf = append(buf, chunk[:m]...)
+		if len(buf) > maxBytes {
+			return buf[:maxBytes], true, nil
+		}
+		if err == io.EOF {
+			return buf, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// tailLines reads the last n lines of a file of the given size, reading
+// backwards in chunks. A final newline does not count as an extra line. It
+// scans at most about maxBytes and reports truncation if the lines did not
+// fit.
+func tailLines(r io.ReaderAt, size int64, n int, maxBytes int) ([]byte, bool, error) {
+	var buf []byte
+	pos := size
+	for pos > 0 {
+		chunk := min(int64(headTailChunk), pos)
+		pos -= chunk
+		b := make([]byte, chunk)
+		if _, err := r.ReadAt(b, pos); err != nil && err != io.EOF {
+			return nil, false, err
+		}
+		buf = append(b, buf...)
+		if start := lastLinesStart(buf, n); start >= 0 {
+			buf = buf[start:]
+			break
+		}
+		if len(buf) > maxBytes {
+			break
+		}
+	}
+	if len(buf) > maxBytes {
+		return buf[len(buf)-maxBytes:], true, nil
+	}
+	return buf, false, nil
+}
+
+// lastLinesStart returns the index in buf where its last n lines begin, or
+// -1 if buf holds fewer than n complete line breaks before them.
+func lastLinesStart(buf []byte, n int) int {
+	body := bytes.TrimSuffix(buf, []byte("\n"))
+	count := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		if body[i] == '\n' {
+			if count++; count == n {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// countLines counts lines in data, including a final unterminated line.
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func numberedLines(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	return sb.String()
+}
+
+func TestHeadLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{"fewer lines than file", "a\nb\nc\n", 2, "a\nb\n"},
+		{"more lines than file", "a\nb\n", 5, "a\nb\n"},
+		{"no final newline", "a\nb", 5, "a\nb"},
+		{"empty", "", 3, ""},
+	}
+	for _, tt := range tests {
+		got, truncated, err := headLines(strings.NewReader(tt.content), tt.n, maxHeadTailBytes)
+		if err != nil || truncated || string(got) != tt.want {
+			t.Errorf("%s: headLines = %q, %v, %v; want %q", tt.name, got, truncated, err, tt.want)
+		}
+	}
+
+	// Lines spanning several chunks.
+	content := numberedLines(20000)
+	got, _, err := headLines(strings.NewReader(content), 15000, maxHeadTailBytes)
+	if err != nil || string(got) != numberedLines(15000) {
+		t.Errorf("multi-chunk headLines returned %d bytes, %v", len(got), err)
+	}
+
+	got, truncated, _ := headLines(strings.NewReader(strings.Repeat("x", 100)), 1, 10)
+	if !truncated || len(got) != 10 {
+		t.Errorf("expected truncation to 10 bytes, got %d (truncated=%v)", len(got), truncated)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{"last two", "a\nb\nc\n", 2, "b\nc\n"},
+		{"no final newline", "a\nb\nc", 2, "b\nc"},
+		{"whole file", "a\nb\n", 5, "a\nb\n"},
+		{"single line", "only\n", 1, "only\n"},
+		{"empty", "", 3, ""},
+	}
+	for _, tt := range tests {
+		r := strings.NewReader(tt.content)
+		got, truncated, err := tailLines(r, int64(len(tt.content)), tt.n, maxHeadTailBytes)
+		if err != nil || truncated || string(got) != tt.want {
+			t.Errorf("%s: tailLines = %q, %v, %v; want %q", tt.name, got, truncated, err, tt.want)
+		}
+	}
+
+	// Lines spanning several chunks read backwards.
+	content := numberedLines(30000)
+	got, _, err := tailLines(strings.NewReader(content), int64(len(content)), 12000, maxHeadTailBytes)
+	want := strings.TrimPrefix(content, numberedLines(18000))
+	if err != nil || string(got) != want {
+		t.Errorf("multi-chunk tailLines returned %d bytes (want %d), %v", len(got), len(want), err)
+	}
+
+	long := strings.Repeat("x", 200000) + "\n"
+	got, truncated, _ := tailLines(strings.NewReader(long), int64(len(long)), 1, 1000)
+	if !truncated || len(got) != 1000 || !strings.HasSuffix(string(got), "x\n") {
+		t.Errorf("expected last 1000 bytes, got %d (truncated=%v)", len(got), truncated)
+	}
+}
+
+func TestReadByteRange(t *testing.T) {
+	r := strings.NewReader("0123456789")
+	if got, _ := readByteRange(r, 10, 3, false); string(got) != "012" {
+		t.Errorf("head bytes = %q", got)
+	}
+	if got, _ := readByteRange(r, 10, 3, true); string(got) != "789" {
+		t.Errorf("tail bytes = %q", got)
+	}
+	if got, _ := readByteRange(r, 10, 50, true); string(got) != "0123456789" {
+		t.Errorf("oversized tail bytes = %q", got)
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for content, want := range map[string]int{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "a\nb\n": 2} {
+		if got := countLines([]byte(content)); got != want {
+			t.Errorf("countLines(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
+
+func TestHandleFileHeadTail_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHFileHeadTailInput
+		want  string
+	}{
+		{SSHFileHeadTailInput{RemotePath: "/var/log/syslog", Lines: 5, Bytes: 10}, "not both"},
+		{SSHFileHeadTailInput{RemotePath: "/var/log/syslog", Lines: -1}, "non-negative"},
+		{SSHFileHeadTailInput{RemotePath: "/var/log/syslog", Lines: maxHeadTailLines + 1}, "at most"},
+		{SSHFileHeadTailInput{RemotePath: "/var/../etc/shadow"}, "invalid remote path"},
+	}
+	for _, tt := range tests {
+		_, err := HandleFileTail(context.Background(), &FileReadDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
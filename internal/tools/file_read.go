@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/n0madic/ssh-mcp/internal/charset"
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
 	"github.com/n0madic/ssh-mcp/internal/sshclient"
@@ -15,6 +17,40 @@ type FileReadDeps struct {
 	Pool        *connection.Pool
 	RateLimiter *security.RateLimiter
 	MaxFileSize int64
+	Config      *config.SSHConfig // output encoding; nil decodes as charset.Auto
+}
+
+// ReadFileContent returns the raw content of a remote file, for the file
+// resource. It applies the same checks and size limit as ssh_read_file and
+// returns the expanded path.
+func ReadFileContent(ctx context.Context, deps *FileReadDeps, sessionID, remotePath string) ([]byte, string, error) {
+	if err := security.ValidatePath(remotePath); err != nil {
+		return nil, "", fmt.Errorf("invalid remote path: %w", err)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+	defer sc.Close()
+
+	remotePath = expandRemotePath(conn, sc, remotePath)
+	var data []byte
+	if deps.MaxFileSize > 0 {
+		data, err = sshclient.ReadFile(ctx, sc, remotePath, deps.MaxFileSize)
+	} else {
+		data, err = sshclient.ReadFile(ctx, sc, remotePath)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("read file: %w", err)
+	}
+	conn.AddBytesDownloaded(int64(len(data)))
+	return data, remotePath, nil
 }
 
 // HandleReadFile implements the ssh_read_file tool.
@@ -22,19 +58,23 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 	if err := security.ValidatePath(input.RemotePath); err != nil {
 		return nil, fmt.Errorf("invalid remote path: %w", err)
 	}
+	dec, err := outputDecoder(deps.Config, input.Encoding)
+	if err != nil {
+		return nil, err
+	}
 
-	_, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	sc, err := sshclient.NewSFTPClient(client)
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer sc.Close()
 
-	input.RemotePath = sshclient.ExpandRemotePath(sc, input.RemotePath)
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
 
 	// Determine max file size: use input override if set, otherwise server default.
 	maxSize := deps.MaxFileSize
@@ -45,9 +85,9 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 	// Read file content.
 	var data []byte
 	if maxSize > 0 {
-		data, err = sshclient.ReadFile(sc, input.RemotePath, maxSize)
+		data, err = sshclient.ReadFile(ctx, sc, input.RemotePath, maxSize)
 	} else {
-		data, err = sshclient.ReadFile(sc, input.RemotePath)
+		data, err = sshclient.ReadFile(ctx, sc, input.RemotePath)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
@@ -56,8 +96,13 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 	// File size equals len(data): ReadFile returns the full content on success
 	// (rejects files exceeding maxSize with an error before reading).
 	fileSize := int64(len(data))
+	conn.AddBytesDownloaded(fileSize)
+	sum := contentHash(data)
 
-	content := string(data)
+	content, enc := dec.Decode(data)
+	if enc == charset.UTF8 {
+		enc = ""
+	}
 
 	// Split into lines.
 	lines := strings.Split(content, "\n")
@@ -74,6 +119,8 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 			FileSize:   fileSize,
 			FromLine:   0,
 			ToLine:     0,
+			Encoding:   enc,
+			SHA256:     sum,
 			Message:    fmt.Sprintf("%s: 0 lines, %d bytes", input.RemotePath, fileSize),
 		}, nil
 	}
@@ -91,6 +138,8 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 			FileSize:   fileSize,
 			FromLine:   offset,
 			ToLine:     offset - 1,
+			Encoding:   enc,
+			SHA256:     sum,
 			Message:    fmt.Sprintf("%s: offset %d is beyond end of file (%d lines, %d bytes)", input.RemotePath, offset, totalLines, fileSize),
 		}, nil
 	}
@@ -117,6 +166,8 @@ func HandleReadFile(ctx context.Context, deps *FileReadDeps, input SSHReadFileIn
 		FileSize:   fileSize,
 		FromLine:   fromLine,
 		ToLine:     toLine,
+		Encoding:   enc,
+		SHA256:     sum,
 		Message:    fmt.Sprintf("%s: showing lines %d-%d of %d (%d bytes)", input.RemotePath, fromLine, toLine, totalLines, fileSize),
 	}, nil
 }
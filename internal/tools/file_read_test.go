@@ -48,3 +48,15 @@ func TestSSHReadFileOutputText_OffsetBeyondEOF(t *testing.T) {
 		t.Errorf("Text() = %q, want %q", result, out.Message)
 	}
 }
+
+func TestSSHReadFileOutputText_SHA256(t *testing.T) {
+	out := SSHReadFileOutput{
+		Content: "     1\thello\n",
+		SHA256:  "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		Message: "/tmp/test.txt: showing lines 1-1 of 1 (6 bytes)",
+	}
+	want := "/tmp/test.txt: showing lines 1-1 of 1 (6 bytes)\nSHA-256: 5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03\n     1\thello\n"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// statLookupTimeout bounds the remote stat(1) call that resolves owner
+// names and block counts.
+const statLookupTimeout = 10 * time.Second
+
+// FileStatDeps holds dependencies for the ssh_file_stat tool handler.
+type FileStatDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// HandleFileStat implements the ssh_file_stat tool. Mode, size, ownership
+// IDs and times come from SFTP (without following symlinks); user/group
+// names and the block count, which SFTP does not carry, come from one
+// best-effort stat(1) call on POSIX hosts.
+func HandleFileStat(ctx context.Context, deps *FileStatDeps, input SSHFileStatInput) (*SSHFileStatOutput, error) {
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+
+	fi, err := sc.Lstat(input.RemotePath)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("stat %s: %w", input.RemotePath, err)
+	}
+
+	out := &SSHFileStatOutput{
+		Path:        input.RemotePath,
+		Type:        fileType(fi.Mode()),
+		Mode:        fi.Mode().String(),
+		Permissions: fmt.Sprintf("%04o", fi.Mode().Perm()),
+		Size:        fi.Size(),
+		ModTime:     fi.ModTime().UTC().Format(time.RFC3339),
+	}
+	if st, ok := fi.Sys().(*sftp.FileStat); ok {
+		out.UID = st.UID
+		out.GID = st.GID
+		out.AccessTime = time.Unix(int64(st.Atime), 0).UTC().Format(time.RFC3339)
+	}
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		if target, err := sc.ReadLink(input.RemotePath); err == nil {
+			out.LinkTarget = target
+		}
+		if tfi, err := sc.Stat(input.RemotePath); err == nil {
+			out.TargetType = fileType(tfi.Mode())
+		} else {
+			out.TargetType = "broken"
+		}
+	}
+
+	osName := conn.GetRemoteInfo().OS
+	if osName == "Windows" {
+		return out, nil
+	}
+	format := "%b|%B|%U|%G"
+	flag := "-c"
+	if osName == "Darwin" || strings.HasSuffix(osName, "BSD") {
+		// BSD stat: st_blocks is always in 512-byte units.
+		format, flag = "%b|512|%Su|%Sg", "-f"
+	}
+	cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "stat", flag, format, "--", input.RemotePath)
+	if err != nil {
+		// The command filter may not allow stat; the SFTP fields still stand.
+		return out, nil
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, statLookupTimeout)
+	if err != nil || res.TimedOut || res.ExitCode != 0 {
+		return out, nil
+	}
+	applyStatLookup(out, res.Stdout)
+	return out, nil
+}
+
+// fileType names the type of a file mode.
+func fileType(m fs.FileMode) string {
+	switch {
+	case m.IsRegular():
+		return "file"
+	case m.IsDir():
+		return "directory"
+	case m&fs.ModeSymlink != 0:
+		return "symlink"
+	case m&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case m&fs.ModeSocket != 0:
+		return "socket"
+	case m&fs.ModeCharDevice != 0:
+		return "char_device"
+	case m&fs.ModeDevice != 0:
+		return "block_device"
+	}
+	return "other"
+}
+
+// applyStatLookup fills block count and owner names from stat(1) output in
+// the form "blocks|blocksize|user|group". Names stat could not resolve
+// ("UNKNOWN" in GNU stat, a bare number in BSD stat) are left empty.
+func applyStatLookup(out *SSHFileStatOutput, s string) {
+	fields := strings.Split(strings.TrimSpace(s), "|")
+	if len(fields) != 4 {
+		return
+	}
+	blocks, err1 := strconv.ParseInt(fields[0], 10, 64)
+	blockSize, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 == nil && err2 == nil {
+		out.Blocks = &blocks
+		out.BlockSize = blockSize
+	}
+	out.User = ownerName(fields[2], out.UID)
+	out.Group = ownerName(fields[3], out.GID)
+}
+
+// ownerName returns name unless it is stat's placeholder for an ID without
+// a name.
+func ownerName(name string, id uint32) string {
+	if name == "" || name == "UNKNOWN" || name == strconv.FormatUint(uint64(id), 10) {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestFileType(t *testing.T) {
+	tests := []struct {
+		mode fs.FileMode
+		want string
+	}{
+		{0644, "file"},
+		{fs.ModeDir | 0755, "directory"},
+		{fs.ModeSymlink | 0777, "symlink"},
+		{fs.ModeNamedPipe, "fifo"},
+		{fs.ModeSocket, "socket"},
+		{fs.ModeDevice | fs.ModeCharDevice, "char_device"},
+		{fs.ModeDevice, "block_device"},
+		{fs.ModeIrregular, "other"},
+	}
+	for _, tt := range tests {
+		if got := fileType(tt.mode); got != tt.want {
+			t.Errorf("fileType(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestApplyStatLookup(t *testing.T) {
+	out := &SSHFileStatOutput{UID: 1000, GID: 1000}
+	applyStatLookup(out, "8|512|deploy|www-data\n")
+	if out.Blocks == nil || *out.Blocks != 8 || out.BlockSize != 512 {
+		t.Errorf("blocks = %v/%d, want 8/512", out.Blocks, out.BlockSize)
+	}
+	if out.User != "deploy" || out.Group != "www-data" {
+		t.Errorf("names = %q/%q", out.User, out.Group)
+	}
+
+	// GNU stat prints UNKNOWN and BSD stat the bare ID for unnamed owners.
+	out = &SSHFileStatOutput{UID: 4242, GID: 4343}
+	applyStatLookup(out, "0|512|UNKNOWN|4343")
+	if out.User != "" || out.Group != "" {
+		t.Errorf("expected no names, got %q/%q", out.User, out.Group)
+	}
+
+	out = &SSHFileStatOutput{}
+	applyStatLookup(out, "stat: cannot stat")
+	if out.Blocks != nil || out.User != "" {
+		t.Errorf("expected malformed output to be ignored, got %+v", out)
+	}
+}
+
+func TestSSHFileStatOutput_Text(t *testing.T) {
+	blocks := int64(8)
+	out := SSHFileStatOutput{
+		Path:        "/etc/nginx/sites-enabled/app",
+		Type:        "symlink",
+		Mode:        "Lrwxrwxrwx",
+		Permissions: "0777",
+		Size:        34,
+		Blocks:      &blocks,
+		BlockSize:   512,
+		UID:         0,
+		GID:         33,
+		User:        "root",
+		LinkTarget:  "../sites-available/app",
+		TargetType:  "file",
+		ModTime:     "2026-03-14T14:09:26Z",
+		AccessTime:  "2026-03-15T08:00:00Z",
+	}
+	text := out.Text()
+	for _, want := range []string{
+		"File: /etc/nginx/sites-enabled/app -> ../sites-available/app",
+		"Type: symlink (target: file)",
+		"Blocks: 8 (512-byte)",
+		"Mode: Lrwxrwxrwx (0777)",
+		"Owner: root (0)  Group: 33",
+		"Accessed: 2026-03-15T08:00:00Z",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHandleFileStat_InvalidPath(t *testing.T) {
+	_, err := HandleFileStat(context.Background(), &FileStatDeps{}, SSHFileStatInput{
+		SessionID:  "user@host:22",
+		RemotePath: "/etc/../etc/passwd",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid remote path") {
+		t.Errorf("expected invalid path error, got %v", err)
+	}
+}
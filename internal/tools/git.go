@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// GitDeps holds dependencies for the ssh_git tool handler.
+type GitDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// Actions of ssh_git.
+const (
+	gitActionClone    = "clone"
+	gitActionPull     = "pull"
+	gitActionStatus   = "status"
+	gitActionCheckout = "checkout"
+	gitActionLog      = "log"
+)
+
+const (
+	defaultGitLogLimit = 20
+	maxGitLogLimit     = 500
+	maxGitStatusFiles  = 1000 // files listed by status; the rest are counted
+)
+
+// gitRefPattern matches branch, tag and commit names, including revision
+// suffixes such as HEAD~2; the first character rules out options.
+var gitRefPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./@{}^~+-]*$`)
+
+// gitLogFormat separates the fields of a commit with US and commits with RS.
+const gitLogFormat = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"
+
+// HandleGit implements the ssh_git tool. Every git command runs with
+// GIT_TERMINAL_PROMPT=0, so a missing credential fails instead of hanging,
+// and goes through the command filter. clone, pull and checkout return the
+// status of the working tree afterwards; pull only fast-forwards. With
+// forward_agent (--enable-agent-forwarding) clone and pull can use the
+// local ssh-agent for private repositories.
+func HandleGit(ctx context.Context, deps *GitDeps, input SSHGitInput) (*SSHGitOutput, error) {
+	if err := validateGitInput(deps.Config, input); err != nil {
+		return nil, err
+	}
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_git is not supported on Windows hosts")
+	}
+	var prepare func(*ssh.Session) error
+	if input.ForwardAgent {
+		if err := conn.ForwardAgent(client); err != nil {
+			return nil, err
+		}
+		prepare = agent.RequestAgentForwarding
+	}
+	timeout := deps.Config.CommandTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+	run := func(args ...string) (*remoteResult, error) {
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "git", args...)
+		if err != nil {
+			return nil, err
+		}
+		res, err := runRemoteCommandPrepared(ctx, conn, client, "GIT_TERMINAL_PROMPT=0 "+cmd, nil, timeout, interruptGracePeriod+killGracePeriod, prepare)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return res, remoteFailure("git "+gitSubcommand(args), res)
+		}
+		return res, nil
+	}
+	dir := gitDir(input.Path)
+	status := func() (*GitStatus, error) {
+		res, err := run("-C", dir, "status", "--porcelain=v2", "--branch", "-z")
+		if err != nil {
+			return nil, err
+		}
+		return parseGitStatus(res.Stdout), nil
+	}
+	head := func() (string, error) {
+		res, err := run("-C", dir, "rev-parse", "--verify", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(res.Stdout), nil
+	}
+
+	out := &SSHGitOutput{Action: input.Action, Path: input.Path}
+	fail := func(err error) (*SSHGitOutput, error) {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	switch input.Action {
+	case gitActionStatus:
+		if out.Status, err = status(); err != nil {
+			return nil, err
+		}
+		out.Message = "Status of " + input.Path
+	case gitActionLog:
+		res, err := run(gitActionArgs(input, dir)...)
+		if err != nil {
+			return nil, err
+		}
+		out.Commits = parseGitLog(decodeLogs(deps.Config, res.Stdout))
+		out.Message = fmt.Sprintf("%d commit(s)", len(out.Commits))
+	case gitActionPull:
+		before, err := head()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := run(gitActionArgs(input, dir)...); err != nil {
+			return fail(err)
+		}
+		after, err := head()
+		if err != nil {
+			return nil, err
+		}
+		if out.Status, err = status(); err != nil {
+			return nil, err
+		}
+		if before == after {
+			out.Message = fmt.Sprintf("Already up to date at %s", shortHash(after))
+			break
+		}
+		out.Message = fmt.Sprintf("Updated %s: %s..%s", out.Status.Branch, shortHash(before), shortHash(after))
+		res, err := run("-C", dir, "log", "-n", strconv.Itoa(gitLogLimit(input.Limit)), gitLogFormat, before+".."+after, "--")
+		if err != nil {
+			return nil, err
+		}
+		out.Commits = parseGitLog(decodeLogs(deps.Config, res.Stdout))
+	case gitActionClone, gitActionCheckout:
+		if _, err := run(gitActionArgs(input, dir)...); err != nil {
+			return fail(err)
+		}
+		if out.Status, err = status(); err != nil {
+			return nil, err
+		}
+		at := fmt.Sprintf("%s at %s", out.Status.Branch, shortHash(out.Status.Commit))
+		switch {
+		case input.Action == gitActionClone:
+			out.Message = fmt.Sprintf("Cloned %s into %s (%s)", input.URL, input.Path, at)
+		case input.Create:
+			out.Message = "Created and switched to " + at
+		default:
+			out.Message = "Switched to " + at
+		}
+	}
+	return out, nil
+}
+
+// validateGitInput checks the arguments of each action.
+func validateGitInput(cfg *config.SSHConfig, input SSHGitInput) error {
+	switch input.Action {
+	case gitActionClone, gitActionPull, gitActionStatus, gitActionCheckout, gitActionLog:
+	default:
+		return fmt.Errorf("invalid action %q (must be clone, pull, status, checkout or log)", input.Action)
+	}
+	if input.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if err := security.ValidatePath(input.Path); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	if input.Action == gitActionClone {
+		if input.URL == "" {
+			return fmt.Errorf("url is required for clone")
+		}
+		if strings.HasPrefix(input.URL, "-") || strings.Contains(input.URL, "::") || strings.ContainsAny(input.URL, "\n\r\x00") {
+			return fmt.Errorf("invalid url %q", input.URL)
+		}
+	} else if input.URL != "" {
+		return fmt.Errorf("url only applies to clone")
+	}
+	if input.Ref != "" && !gitRefPattern.MatchString(input.Ref) {
+		return fmt.Errorf("invalid ref %q", input.Ref)
+	}
+	switch {
+	case input.Action == gitActionCheckout && input.Ref == "":
+		return fmt.Errorf("ref is required for checkout")
+	case input.Ref != "" && input.Action != gitActionCheckout && input.Action != gitActionClone && input.Action != gitActionLog:
+		return fmt.Errorf("ref only applies to clone, checkout and log")
+	case input.Create && input.Action != gitActionCheckout:
+		return fmt.Errorf("create only applies to checkout")
+	case input.Depth < 0 || input.Depth > 0 && input.Action != gitActionClone:
+		return fmt.Errorf("depth only applies to clone and must be positive")
+	case input.Limit < 0 || input.Limit > maxGitLogLimit:
+		return fmt.Errorf("limit must be between 1 and %d", maxGitLogLimit)
+	case input.Limit > 0 && input.Action != gitActionLog && input.Action != gitActionPull:
+		return fmt.Errorf("limit only applies to log and pull")
+	case input.Timeout < 0:
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if input.ForwardAgent {
+		if input.Action != gitActionClone && input.Action != gitActionPull {
+			return fmt.Errorf("forward_agent only applies to clone and pull")
+		}
+		if cfg == nil || !cfg.AllowAgentForward {
+			return fmt.Errorf("agent forwarding is disabled; start server with --enable-agent-forwarding to allow")
+		}
+	}
+	return nil
+}
+
+// gitActionArgs returns the git arguments of clone, pull, checkout and log
+// for the repository at dir.
+func gitActionArgs(input SSHGitInput, dir string) []string {
+	switch input.Action {
+	case gitActionClone:
+		args := []string{"clone"}
+		if input.Ref != "" {
+			args = append(args, "--branch", input.Ref)
+		}
+		if input.Depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(input.Depth))
+		}
+		return append(args, "--", input.URL, dir)
+	case gitActionPull:
+		return []string{"-C", dir, "pull", "--ff-only"}
+	case gitActionCheckout:
+		if input.Create {
+			return []string{"-C", dir, "checkout", "-b", input.Ref}
+		}
+		return []string{"-C", dir, "checkout", input.Ref, "--"}
+	default:
+		args := []string{"-C", dir, "log", "-n", strconv.Itoa(gitLogLimit(input.Limit)), gitLogFormat}
+		if input.Ref != "" {
+			args = append(args, input.Ref)
+		}
+		return append(args, "--")
+	}
+}
+
+// gitSubcommand returns the git subcommand in args, skipping -C DIR.
+func gitSubcommand(args []string) string {
+	if len(args) > 2 && args[0] == "-C" {
+		return args[2]
+	}
+	return args[0]
+}
+
+// gitDir makes a leading ~ relative, since commands start in the home
+// directory and a quoted ~ is not expanded.
+func gitDir(p string) string {
+	if p == "~" {
+		return "."
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok && rest != "" {
+		return rest
+	}
+	return p
+}
+
+// gitLogLimit returns limit, or the default if it is unset.
+func gitLogLimit(limit int) int {
+	if limit <= 0 {
+		return defaultGitLogLimit
+	}
+	return limit
+}
+
+// shortHash abbreviates a commit hash for messages.
+func shortHash(h string) string {
+	if len(h) > 7 && !strings.HasPrefix(h, "(") {
+		return h[:7]
+	}
+	return h
+}
+
+// parseGitStatus reads git status --porcelain=v2 --branch -z output.
+func parseGitStatus(out string) *GitStatus {
+	st := &GitStatus{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
+		if line == "" {
+			continue
+		}
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			key, value, _ := strings.Cut(header, " ")
+			switch key {
+			case "branch.oid":
+				st.Commit = value
+			case "branch.head":
+				st.Branch = value
+			case "branch.upstream":
+				st.Upstream = value
+			case "branch.ab":
+				for _, n := range strings.Fields(value) {
+					v, _ := strconv.Atoi(n[1:])
+					if n[0] == '+' {
+						st.Ahead = v
+					} else {
+						st.Behind = v
+					}
+				}
+			}
+			continue
+		}
+		var f GitFileStatus
+		switch line[0] {
+		case '1': // 1 XY sub mH mI mW hH hI path
+			p := strings.SplitN(line, " ", 9)
+			if len(p) != 9 {
+				continue
+			}
+			f = GitFileStatus{Path: p[8], State: "changed"}
+			f.Staged, f.Unstaged = gitStatusCode(p[1])
+		case '2': // 2 XY sub mH mI mW hH hI Xscore path, then the original path
+			p := strings.SplitN(line, " ", 10)
+			if len(p) != 10 {
+				continue
+			}
+			f = GitFileStatus{Path: p[9], State: "renamed"}
+			if strings.HasPrefix(p[8], "C") {
+				f.State = "copied"
+			}
+			f.Staged, f.Unstaged = gitStatusCode(p[1])
+			if i+1 < len(fields) {
+				i++
+				f.OrigPath = fields[i]
+			}
+		case 'u': // u XY sub m1 m2 m3 mW h1 h2 h3 path
+			p := strings.SplitN(line, " ", 11)
+			if len(p) != 11 {
+				continue
+			}
+			f = GitFileStatus{Path: p[10], State: "unmerged"}
+			f.Staged, f.Unstaged = gitStatusCode(p[1])
+		case '?':
+			f = GitFileStatus{Path: line[2:], State: "untracked"}
+		default: // ! ignored
+			continue
+		}
+		if len(st.Files) < maxGitStatusFiles {
+			st.Files = append(st.Files, f)
+		} else {
+			st.MoreFiles++
+		}
+	}
+	st.Clean = len(st.Files) == 0 && st.MoreFiles == 0
+	return st
+}
+
+// gitStatusCode splits a porcelain XY code into its index and work tree
+// letters, with "." (unchanged) as "".
+func gitStatusCode(xy string) (staged, unstaged string) {
+	if len(xy) != 2 {
+		return "", ""
+	}
+	staged, unstaged = xy[:1], xy[1:]
+	if staged == "." {
+		staged = ""
+	}
+	if unstaged == "." {
+		unstaged = ""
+	}
+	return staged, unstaged
+}
+
+// parseGitLog reads git log output in gitLogFormat.
+func parseGitLog(out string) []GitCommit {
+	var commits []GitCommit
+	for _, rec := range strings.Split(out, "\x1e") {
+		f := strings.Split(strings.TrimLeft(rec, "\n"), "\x1f")
+		if len(f) != 5 {
+			continue
+		}
+		commits = append(commits, GitCommit{Hash: f[0], Author: f[1], Email: f[2], Date: f[3], Subject: f[4]})
+	}
+	return commits
+}
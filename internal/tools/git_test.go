@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestHandleGit_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHGitInput
+		want  string
+	}{
+		{SSHGitInput{Action: "push", Path: "app"}, "invalid action"},
+		{SSHGitInput{Action: "status"}, "path is required"},
+		{SSHGitInput{Action: "status", Path: "../etc"}, "invalid path"},
+		{SSHGitInput{Action: "clone", Path: "app"}, "url is required"},
+		{SSHGitInput{Action: "clone", Path: "app", URL: "--upload-pack=touch /tmp/x"}, "invalid url"},
+		{SSHGitInput{Action: "clone", Path: "app", URL: "ext::sh -c touch% /tmp/x"}, "invalid url"},
+		{SSHGitInput{Action: "pull", Path: "app", URL: "https://example.com/a.git"}, "url only applies"},
+		{SSHGitInput{Action: "checkout", Path: "app"}, "ref is required"},
+		{SSHGitInput{Action: "checkout", Path: "app", Ref: "-f"}, "invalid ref"},
+		{SSHGitInput{Action: "checkout", Path: "app", Ref: "main; id"}, "invalid ref"},
+		{SSHGitInput{Action: "pull", Path: "app", Ref: "main"}, "ref only applies"},
+		{SSHGitInput{Action: "log", Path: "app", Create: true}, "create only applies"},
+		{SSHGitInput{Action: "pull", Path: "app", Depth: 1}, "depth only applies"},
+		{SSHGitInput{Action: "log", Path: "app", Limit: maxGitLogLimit + 1}, "limit must be"},
+		{SSHGitInput{Action: "status", Path: "app", Limit: 5}, "limit only applies"},
+		{SSHGitInput{Action: "status", Path: "app", ForwardAgent: true}, "only applies to clone and pull"},
+		{SSHGitInput{Action: "pull", Path: "app", ForwardAgent: true}, "--enable-agent-forwarding"},
+	}
+	for _, tt := range tests {
+		_, err := HandleGit(context.Background(), &GitDeps{Config: &config.SSHConfig{}}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestGitActionArgs(t *testing.T) {
+	tests := []struct {
+		input SSHGitInput
+		want  []string
+	}{
+		{SSHGitInput{Action: "clone", URL: "git@github.com:org/app.git", Ref: "v1.2", Depth: 1},
+			[]string{"clone", "--branch", "v1.2", "--depth", "1", "--", "git@github.com:org/app.git", "app"}},
+		{SSHGitInput{Action: "pull"}, []string{"-C", "app", "pull", "--ff-only"}},
+		{SSHGitInput{Action: "checkout", Ref: "release"}, []string{"-C", "app", "checkout", "release", "--"}},
+		{SSHGitInput{Action: "checkout", Ref: "fix", Create: true}, []string{"-C", "app", "checkout", "-b", "fix"}},
+		{SSHGitInput{Action: "log", Ref: "origin/main", Limit: 5}, []string{"-C", "app", "log", "-n", "5", gitLogFormat, "origin/main", "--"}},
+		{SSHGitInput{Action: "log"}, []string{"-C", "app", "log", "-n", "20", gitLogFormat, "--"}},
+	}
+	for _, tt := range tests {
+		if got := gitActionArgs(tt.input, "app"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.input.Action, got, tt.want)
+		}
+	}
+}
+
+func TestGitDir(t *testing.T) {
+	for in, want := range map[string]string{"~": ".", "~/src/app": "src/app", "/srv/app": "/srv/app", "app": "app", "~other/x": "~other/x"} {
+		if got := gitDir(in); got != want {
+			t.Errorf("gitDir(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	out := "# branch.oid 48498a75a8f35b8218448f161244a8fd280a6f33\x00# branch.head main\x00" +
+		"# branch.upstream origin/main\x00# branch.ab +1 -2\x00" +
+		"1 .M N... 100644 100644 100644 6178079 6178079 b c\x00" +
+		"2 R. N... 100644 100644 100644 7898192 7898192 R100 z\x00a\x00" +
+		"u UU N... 100644 100644 100644 100644 1111111 2222222 3333333 conflict.go\x00" +
+		"? new\x00! ignored.log\x00"
+	want := &GitStatus{
+		Branch: "main", Commit: "48498a75a8f35b8218448f161244a8fd280a6f33", Upstream: "origin/main", Ahead: 1, Behind: 2,
+		Files: []GitFileStatus{
+			{Path: "b c", State: "changed", Unstaged: "M"},
+			{Path: "z", OrigPath: "a", State: "renamed", Staged: "R"},
+			{Path: "conflict.go", State: "unmerged", Staged: "U", Unstaged: "U"},
+			{Path: "new", State: "untracked"},
+		},
+	}
+	if got := parseGitStatus(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitStatus =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := parseGitStatus("# branch.oid (initial)\x00# branch.head main\x00"); !got.Clean || got.Commit != "(initial)" {
+		t.Errorf("empty repository: %+v", got)
+	}
+}
+
+func TestParseGitLog(t *testing.T) {
+	out := "48498a75a8f35b8218448f161244a8fd280a6f33\x1fAlice\x1falice@example.com\x1f2024-10-16T08:54:36+00:00\x1fFix login\x1e\n" +
+		"1111111111111111111111111111111111111111\x1fBob\x1fbob@example.com\x1f2024-10-15T08:00:00+02:00\x1fInitial commit\x1e\n"
+	want := []GitCommit{
+		{Hash: "48498a75a8f35b8218448f161244a8fd280a6f33", Author: "Alice", Email: "alice@example.com", Date: "2024-10-16T08:54:36+00:00", Subject: "Fix login"},
+		{Hash: "1111111111111111111111111111111111111111", Author: "Bob", Email: "bob@example.com", Date: "2024-10-15T08:00:00+02:00", Subject: "Initial commit"},
+	}
+	if got := parseGitLog(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitLog = %+v", got)
+	}
+}
+
+func TestSSHGitOutput_Text(t *testing.T) {
+	out := SSHGitOutput{
+		Action:  "pull",
+		Message: "Updated main: 1111111..48498a7",
+		Status: &GitStatus{Branch: "main", Commit: "48498a75a8f35b8218448f161244a8fd280a6f33", Upstream: "origin/main", Behind: 1,
+			Files: []GitFileStatus{{Path: "z", OrigPath: "a", State: "renamed", Staged: "R"}, {Path: "new", State: "untracked"}}},
+		Commits: []GitCommit{{Hash: "48498a75a8f35b8218448f161244a8fd280a6f33", Author: "Alice", Date: "2024-10-16T08:54:36+00:00", Subject: "Fix login"}},
+	}
+	want := "Updated main: 1111111..48498a7\nBranch main at 48498a7, tracking origin/main (ahead 0, behind 1)\n  R. z (from a)\n  ?? new\n48498a7 2024-10-16T08:54:36+00:00 Alice: Fix login"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// GlobDeps holds dependencies for the ssh_glob tool handler.
+type GlobDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// maxGlobEntries caps the directory entries one expansion reads, so a
+// ** over / fails instead of walking the whole filesystem.
+const maxGlobEntries = 50000
+
+// errGlobStop ends a glob walk early without an error.
+var errGlobStop = errors.New("stop glob")
+
+// HandleGlob implements the ssh_glob tool: it expands pattern on the
+// remote host over SFTP, without a shell, and returns the matches with
+// their type, size, mode and modification time, up to limit of them.
+func HandleGlob(ctx context.Context, deps *GlobDeps, input SSHGlobInput) (*SSHGlobOutput, error) {
+	if err := security.ValidatePath(input.Pattern); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if err := checkGlobPattern(input.Pattern); err != nil {
+		return nil, err
+	}
+	limit := input.Limit
+	switch {
+	case limit < 0 || limit > maxListLimit:
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+	case limit == 0:
+		limit = defaultListLimit
+	}
+	switch input.Type {
+	case "", "file", "directory", "symlink", "other":
+	default:
+		return nil, fmt.Errorf("type must be file, directory, symlink or other")
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	pattern := input.Pattern
+	if conn.GetRemoteInfo().OS == "Windows" {
+		pattern = sshclient.WindowsPath(pattern)
+	}
+	out := &SSHGlobOutput{Pattern: input.Pattern}
+	err = walkRemoteGlob(ctx, sc, pattern, func(p string, fi os.FileInfo) error {
+		if input.Type != "" && entryType(fi.Mode()) != input.Type {
+			return nil
+		}
+		if len(out.Matches) == limit {
+			out.HasMore = true
+			return errGlobStop
+		}
+		m := GlobMatch{
+			Path:    p,
+			Type:    entryType(fi.Mode()),
+			Size:    fi.Size(),
+			Mode:    fi.Mode().String(),
+			ModTime: fi.ModTime().UTC().Format(time.RFC3339),
+		}
+		if m.Type == "symlink" {
+			m.LinkTarget, _ = sc.ReadLink(p)
+		}
+		out.Matches = append(out.Matches, m)
+		return nil
+	})
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	return out, nil
+}
+
+// expandRemoteGlob returns the remote paths matching pattern, in walk
+// order (by name within each directory).
+func expandRemoteGlob(ctx context.Context, sc *sftp.Client, pattern string) ([]string, error) {
+	if err := checkGlobPattern(pattern); err != nil {
+		return nil, err
+	}
+	var matches []string
+	err := walkRemoteGlob(ctx, sc, pattern, func(p string, _ os.FileInfo) error {
+		matches = append(matches, p)
+		return nil
+	})
+	return matches, err
+}
+
+// hasGlobMeta reports whether p contains path.Match metacharacters.
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// checkGlobPattern rejects malformed character classes, which path.Match
+// would only report on the first name it tries.
+func checkGlobPattern(pattern string) error {
+	for _, part := range strings.Split(pattern, "/") {
+		if _, err := path.Match(part, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// walkRemoteGlob calls fn for every path matching pattern, each once.
+// Components are path.Match patterns, and a ** component matches any
+// number of directories, not following symlinks. The part before the
+// first component with a metacharacter is resolved with RealPath, so
+// relative and ~ patterns work as plain paths do. Unreadable directories
+// are skipped, as a shell does; reading more than maxGlobEntries entries
+// fails. fn returning errGlobStop ends the walk without an error.
+func walkRemoteGlob(ctx context.Context, sc *sftp.Client, pattern string, fn func(p string, fi os.FileInfo) error) error {
+	parts := slices.DeleteFunc(strings.Split(pattern, "/"), func(s string) bool { return s == "" || s == "." })
+	i := slices.IndexFunc(parts, hasGlobMeta)
+	if i < 0 {
+		i = len(parts)
+	}
+	base := strings.Join(parts[:i], "/")
+	switch {
+	case strings.HasPrefix(pattern, "/"):
+		base = "/" + base
+	case base == "":
+		base = "."
+	}
+	base = sshclient.ExpandRemotePath(sc, base)
+	info, err := sc.Lstat(base)
+	if i < len(parts) {
+		info, err = sc.Stat(base) // a directory to search, symlinks followed
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("stat %s: %w", base, err)
+	}
+	w := &globWalk{ctx: ctx, sc: sc, fn: fn, dirs: map[string][]os.FileInfo{}, seen: map[string]bool{}}
+	if err := w.match(base, info, parts[i:]); err != nil && err != errGlobStop {
+		return err
+	}
+	return nil
+}
+
+// globWalk is the state of one walkRemoteGlob.
+type globWalk struct {
+	ctx     context.Context
+	sc      *sftp.Client
+	fn      func(string, os.FileInfo) error
+	dirs    map[string][]os.FileInfo // ReadDir results, sorted by name
+	seen    map[string]bool          // matches passed to fn
+	entries int
+}
+
+// match calls fn for the paths under p (with info) that match parts.
+func (w *globWalk) match(p string, info os.FileInfo, parts []string) error {
+	if len(parts) == 0 {
+		if w.seen[p] {
+			return nil
+		}
+		w.seen[p] = true
+		return w.fn(p, info)
+	}
+	if !info.IsDir() {
+		return nil
+	}
+	part := parts[0]
+	if part == "**" {
+		if err := w.match(p, info, parts[1:]); err != nil {
+			return err
+		}
+		children, err := w.readDir(p)
+		if err != nil {
+			return err
+		}
+		for _, fi := range children {
+			if fi.IsDir() { // symlinks to directories are not descended into
+				if err := w.match(path.Join(p, fi.Name()), fi, parts); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if !hasGlobMeta(part) {
+		child := path.Join(p, part)
+		stat := w.sc.Stat
+		if len(parts) == 1 {
+			stat = w.sc.Lstat
+		}
+		fi, err := stat(child)
+		if err != nil {
+			return nil
+		}
+		return w.match(child, fi, parts[1:])
+	}
+	children, err := w.readDir(p)
+	if err != nil {
+		return err
+	}
+	for _, fi := range children {
+		if ok, _ := path.Match(part, fi.Name()); !ok {
+			continue
+		}
+		child := path.Join(p, fi.Name())
+		if len(parts) > 1 && fi.Mode()&os.ModeSymlink != 0 {
+			if target, err := w.sc.Stat(child); err == nil {
+				fi = target
+			}
+		}
+		if err := w.match(child, fi, parts[1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDir returns the entries of dir sorted by name, reading it once per
+// walk. An unreadable directory has no entries.
+func (w *globWalk) readDir(dir string) ([]os.FileInfo, error) {
+	if infos, ok := w.dirs[dir]; ok {
+		return infos, nil
+	}
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	infos, _ := w.sc.ReadDir(dir)
+	w.entries += len(infos)
+	if w.entries > maxGlobEntries {
+		return nil, fmt.Errorf("glob reads more than %d directory entries; narrow the pattern", maxGlobEntries)
+	}
+	slices.SortFunc(infos, func(a, b os.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+	w.dirs[dir] = infos
+	return infos, nil
+}
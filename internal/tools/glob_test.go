@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/sshclient/sftptest"
+)
+
+func TestHandleGlob_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHGlobInput
+		want  string
+	}{
+		{SSHGlobInput{SessionID: "s"}, "invalid pattern"},
+		{SSHGlobInput{SessionID: "s", Pattern: "/var/../etc/*"}, "invalid pattern"},
+		{SSHGlobInput{SessionID: "s", Pattern: "/etc/[a-"}, "invalid glob"},
+		{SSHGlobInput{SessionID: "s", Pattern: "/etc/*", Limit: maxListLimit + 1}, "limit must be"},
+		{SSHGlobInput{SessionID: "s", Pattern: "/etc/*", Type: "socket"}, "type must be"},
+	}
+	for _, tt := range tests {
+		_, err := HandleGlob(context.Background(), &GlobDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestWalkRemoteGlob(t *testing.T) {
+	sc := sftptest.NewClient(t)
+	root := remoteTree(t, map[string]string{
+		"app/main.py":            "",
+		"app/.hidden.py":         "",
+		"app/pkg/util.py":        "",
+		"app/pkg/deep/x.py":      "",
+		"app/pkg/deep/notes.txt": "",
+		"etc/sites-a/one.conf":   "",
+		"etc/sites-b/two.conf":   "",
+		"etc/sites-b/skip.bak":   "",
+	})
+	if err := os.Symlink(filepath.Join(root, "etc"), filepath.Join(root, "app", "etc-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	glob := func(pattern string) string {
+		t.Helper()
+		got, err := expandRemoteGlob(context.Background(), sc, pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", pattern, err)
+		}
+		return strings.ReplaceAll(strings.Join(got, ","), root, "")
+	}
+	tests := []struct{ pattern, want string }{
+		{root + "/etc/sites-*/*.conf", "/etc/sites-a/one.conf,/etc/sites-b/two.conf"},
+		{root + "/app/*.py", "/app/.hidden.py,/app/main.py"},
+		// ** matches zero or more directories but doesn't follow etc-link.
+		{root + "/app/**/*.py", "/app/.hidden.py,/app/main.py,/app/pkg/util.py,/app/pkg/deep/x.py"},
+		{root + "/app/**/**/x.py", "/app/pkg/deep/x.py"},
+		// A named symlink is followed to look inside it.
+		{root + "/app/etc-link/sites-?/*.bak", "/app/etc-link/sites-b/skip.bak"},
+		{root + "/app/etc-*/sites-a", "/app/etc-link/sites-a"},
+		{root + "/app/main.py", "/app/main.py"},
+		{root + "/app/none/*.py", ""},
+		{root + "/missing/*", ""},
+	}
+	for _, tt := range tests {
+		if got := glob(tt.pattern); got != tt.want {
+			t.Errorf("%s = %q, want %q", strings.TrimPrefix(tt.pattern, root), got, tt.want)
+		}
+	}
+
+	// The walk stops early when asked to.
+	var n int
+	err := walkRemoteGlob(context.Background(), sc, root+"/**", func(string, os.FileInfo) error {
+		n++
+		if n == 2 {
+			return errGlobStop
+		}
+		return nil
+	})
+	if err != nil || n != 2 {
+		t.Errorf("stopped walk: n = %d, err = %v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := expandRemoteGlob(ctx, sc, root+"/app/*"); err == nil {
+		t.Error("cancelled walk succeeded")
+	}
+}
+
+func TestSSHGlobOutput_Text(t *testing.T) {
+	if got := (SSHGlobOutput{Pattern: "/etc/*.conf"}).Text(); got != "/etc/*.conf: no matches" {
+		t.Errorf("empty Text() = %q", got)
+	}
+	out := SSHGlobOutput{Pattern: "/etc/nginx/*", HasMore: true, Matches: []GlobMatch{
+		{Path: "/etc/nginx/conf.d", Type: "directory", Mode: "drwxr-xr-x", ModTime: "2026-01-02T03:04:05Z", Size: 4096},
+		{Path: "/etc/nginx/default", Type: "symlink", Mode: "Lrwxrwxrwx", ModTime: "2026-01-02T03:04:05Z", Size: 30, LinkTarget: "sites-available/default"},
+	}}
+	text := out.Text()
+	for _, want := range []string{
+		"/etc/nginx/*: 2 matches (limit reached",
+		"drwxr-xr-x       4096 2026-01-02T03:04:05Z /etc/nginx/conf.d/",
+		"/etc/nginx/default -> sites-available/default",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
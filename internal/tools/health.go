@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// HealthDeps holds dependencies for the ssh_health tool handler.
+type HealthDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+const (
+	defaultHealthTop = 5  // processes per list when top is unset
+	maxHealthTop     = 20 // top limit
+	maxHealthDisks   = 10 // filesystems listed, fullest first
+)
+
+// Thresholds above which ssh_health adds a warning.
+const (
+	healthLoadPerCPU  = 1.0
+	healthMemPercent  = 90
+	healthSwapPercent = 50
+	healthDiskPercent = 90
+)
+
+// healthScript prints one section per marker line. Every command is
+// optional: a host without /proc or systemd leaves its sections empty,
+// and the failed section only appears where systemctl exists.
+const healthScript = `echo @@uptime; cat /proc/uptime 2>/dev/null
+echo @@load; cat /proc/loadavg 2>/dev/null || sysctl -n vm.loadavg 2>/dev/null
+echo @@cpus; nproc 2>/dev/null || getconf _NPROCESSORS_ONLN 2>/dev/null
+echo @@mem; grep -E '^(MemTotal|MemFree|MemAvailable|SwapTotal|SwapFree):' /proc/meminfo 2>/dev/null
+echo @@df; df -P -k 2>/dev/null
+echo @@ps; ps -eo pid=,user=,pcpu=,pmem=,rss=,comm= 2>/dev/null
+if command -v systemctl >/dev/null 2>&1; then echo @@failed; systemctl list-units --state=failed --no-legend --plain --no-pager 2>/dev/null; fi
+true`
+
+// pseudoFilesystems are df sources that hold no disk space worth watching.
+var pseudoFilesystems = map[string]bool{
+	"tmpfs": true, "devtmpfs": true, "udev": true, "overlay": true, "shm": true,
+	"none": true, "squashfs": true, "efivarfs": true, "devfs": true, "map": true,
+}
+
+// HandleHealth implements the ssh_health tool: one fixed, read-only probe
+// (so, like ssh_host_info, it bypasses the command filter) summarized into
+// uptime, load, memory and swap, the fullest filesystems, the top CPU and
+// memory processes, failed systemd units and warnings for values past the
+// thresholds above.
+func HandleHealth(ctx context.Context, deps *HealthDeps, input SSHHealthInput) (*SSHHealthOutput, error) {
+	top := input.Top
+	if top < 0 || top > maxHealthTop {
+		return nil, fmt.Errorf("top must be between 0 and %d", maxHealthTop)
+	}
+	if top == 0 {
+		top = defaultHealthTop
+	}
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_health needs a POSIX host")
+	}
+	res, err := runRemoteCommand(ctx, conn, client, healthScript, nil, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if res.TimedOut || res.ExitCode != 0 {
+		return nil, remoteFailure("health probe", res)
+	}
+	out := parseHealth(decodeLogs(deps.Config, res.Stdout), input.Mounts, top)
+	out.SessionID = input.SessionID
+	out.Warnings = healthWarnings(out)
+	return out, nil
+}
+
+// parseHealth reads healthScript output. mounts, if set, picks the
+// filesystems to report; top is the length of the process lists.
+func parseHealth(script string, mounts []string, top int) *SSHHealthOutput {
+	sections := map[string][]string{}
+	var cur string
+	for _, line := range strings.Split(script, "\n") {
+		if name, ok := strings.CutPrefix(line, "@@"); ok {
+			cur = name
+			sections[cur] = []string{}
+			continue
+		}
+		if cur != "" && strings.TrimSpace(line) != "" {
+			sections[cur] = append(sections[cur], line)
+		}
+	}
+	first := func(name string) []string {
+		if lines := sections[name]; len(lines) > 0 {
+			return strings.Fields(strings.Trim(lines[0], "{} "))
+		}
+		return nil
+	}
+
+	out := &SSHHealthOutput{}
+	if f := first("uptime"); len(f) > 0 {
+		if sec, err := strconv.ParseFloat(f[0], 64); err == nil {
+			out.UptimeSec = int64(sec)
+		}
+	}
+	if f := first("load"); len(f) >= 3 {
+		out.Load1, _ = strconv.ParseFloat(f[0], 64)
+		out.Load5, _ = strconv.ParseFloat(f[1], 64)
+		out.Load15, _ = strconv.ParseFloat(f[2], 64)
+	}
+	if f := first("cpus"); len(f) > 0 {
+		out.CPUs, _ = strconv.Atoi(f[0])
+	}
+	out.Memory = parseMeminfo(sections["mem"])
+	out.Disks, out.MoreDisks = parseDF(sections["df"], mounts)
+	procs := parseHealthProcesses(sections["ps"])
+	out.TopCPU = topProcesses(procs, top, func(a, b HealthProcess) int { return cmp.Compare(b.CPU, a.CPU) })
+	out.TopMemory = topProcesses(procs, top, func(a, b HealthProcess) int { return cmp.Compare(b.RSSKB, a.RSSKB) })
+	if lines, ok := sections["failed"]; ok {
+		out.Systemd = true
+		for _, line := range lines {
+			if f := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "●")); len(f) > 0 {
+				out.FailedUnits = append(out.FailedUnits, f[0])
+			}
+		}
+	}
+	return out
+}
+
+// parseMeminfo reads the /proc/meminfo lines of healthScript, or returns
+// nil without MemTotal.
+func parseMeminfo(lines []string) *HealthMemory {
+	kb := map[string]int64{}
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) >= 2 {
+			kb[strings.TrimSuffix(f[0], ":")], _ = strconv.ParseInt(f[1], 10, 64)
+		}
+	}
+	if kb["MemTotal"] == 0 {
+		return nil
+	}
+	avail, ok := kb["MemAvailable"] // since Linux 3.14
+	if !ok {
+		avail = kb["MemFree"]
+	}
+	return &HealthMemory{
+		TotalMB:     kb["MemTotal"] / 1024,
+		AvailableMB: avail / 1024,
+		UsedPercent: percent(kb["MemTotal"]-avail, kb["MemTotal"]),
+		SwapTotalMB: kb["SwapTotal"] / 1024,
+		SwapUsedMB:  (kb["SwapTotal"] - kb["SwapFree"]) / 1024,
+	}
+}
+
+// parseDF reads df -P -k output: the requested mounts, or the real
+// filesystems, fullest first and at most maxHealthDisks of them. It also
+// returns how many were left out.
+func parseDF(lines []string, mounts []string) ([]HealthDisk, int) {
+	var disks []HealthDisk
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 6 || f[0] == "Filesystem" {
+			continue
+		}
+		mount := strings.Join(f[5:], " ")
+		if len(mounts) > 0 {
+			if !slices.Contains(mounts, mount) {
+				continue
+			}
+		} else if pseudoFilesystems[f[0]] || strings.HasPrefix(f[0], "/dev/loop") || isPseudoMount(mount) {
+			continue
+		}
+		size, err1 := strconv.ParseInt(f[1], 10, 64)
+		used, err2 := strconv.ParseInt(f[2], 10, 64)
+		avail, err3 := strconv.ParseInt(f[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || size == 0 {
+			continue
+		}
+		disks = append(disks, HealthDisk{
+			Mount:       mount,
+			Filesystem:  f[0],
+			SizeMB:      size / 1024,
+			UsedMB:      used / 1024,
+			AvailMB:     avail / 1024,
+			UsedPercent: percent(used, used+avail), // as df computes Capacity
+		})
+	}
+	slices.SortStableFunc(disks, func(a, b HealthDisk) int { return cmp.Compare(b.UsedPercent, a.UsedPercent) })
+	if len(disks) > maxHealthDisks {
+		return disks[:maxHealthDisks], len(disks) - maxHealthDisks
+	}
+	return disks, 0
+}
+
+// isPseudoMount reports whether mount is under a kernel or runtime tree.
+func isPseudoMount(mount string) bool {
+	for _, p := range []string{"/proc", "/sys", "/dev", "/run", "/snap", "/System/Volumes/VM", "/private/var/vm"} {
+		if mount == p || strings.HasPrefix(mount, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHealthProcesses reads ps -eo pid=,user=,pcpu=,pmem=,rss=,comm= output.
+func parseHealthProcesses(lines []string) []HealthProcess {
+	var procs []HealthProcess
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) < 6 {
+			continue
+		}
+		p := HealthProcess{User: f[1], Command: strings.Join(f[5:], " ")}
+		var err error
+		if p.PID, err = strconv.Atoi(f[0]); err != nil {
+			continue
+		}
+		p.CPU, _ = strconv.ParseFloat(f[2], 64)
+		p.Mem, _ = strconv.ParseFloat(f[3], 64)
+		p.RSSKB, _ = strconv.ParseInt(f[4], 10, 64)
+		procs = append(procs, p)
+	}
+	return procs
+}
+
+// topProcesses returns the first n of procs in the order of cmp.
+func topProcesses(procs []HealthProcess, n int, cmp func(a, b HealthProcess) int) []HealthProcess {
+	sorted := slices.Clone(procs)
+	slices.SortStableFunc(sorted, cmp)
+	return sorted[:min(n, len(sorted))]
+}
+
+// healthWarnings lists the values of out past the health thresholds.
+func healthWarnings(out *SSHHealthOutput) []string {
+	var warnings []string
+	if out.CPUs > 0 && out.Load1/float64(out.CPUs) >= healthLoadPerCPU {
+		warnings = append(warnings, fmt.Sprintf("load %.2f on %d CPU(s)", out.Load1, out.CPUs))
+	}
+	if m := out.Memory; m != nil {
+		if m.UsedPercent >= healthMemPercent {
+			warnings = append(warnings, fmt.Sprintf("memory %d%% used", m.UsedPercent))
+		}
+		if m.SwapTotalMB > 0 && percent(m.SwapUsedMB, m.SwapTotalMB) >= healthSwapPercent {
+			warnings = append(warnings, fmt.Sprintf("swap %d%% used", percent(m.SwapUsedMB, m.SwapTotalMB)))
+		}
+	}
+	for _, d := range out.Disks {
+		if d.UsedPercent >= healthDiskPercent {
+			warnings = append(warnings, fmt.Sprintf("%s %d%% full", d.Mount, d.UsedPercent))
+		}
+	}
+	if len(out.FailedUnits) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d failed unit(s)", len(out.FailedUnits)))
+	}
+	return warnings
+}
+
+// percent returns part/whole as a whole percentage, rounded up like df.
+func percent(part, whole int64) int {
+	if whole <= 0 {
+		return 0
+	}
+	return int((part*100 + whole - 1) / whole)
+}
+
+// formatMB renders a size in MiB compactly, e.g. 512M or 7.7G.
+func formatMB(mb int64) string {
+	switch {
+	case mb >= 1<<20:
+		return fmt.Sprintf("%.1fT", float64(mb)/(1<<20))
+	case mb >= 1<<10:
+		return fmt.Sprintf("%.1fG", float64(mb)/(1<<10))
+	default:
+		return fmt.Sprintf("%dM", mb)
+	}
+}
+
+// formatUptime renders seconds as days, hours and minutes.
+func formatUptime(sec int64) string {
+	d, h, m := sec/86400, sec%86400/3600, sec%3600/60
+	switch {
+	case d > 0:
+		return fmt.Sprintf("%dd %dh", d, h)
+	case h > 0:
+		return fmt.Sprintf("%dh %dm", h, m)
+	default:
+		return fmt.Sprintf("%dm", m)
+	}
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const healthSample = `@@uptime
+273645.12 1041234.55
+@@load
+5.10 3.02 1.50 3/412 12345
+@@cpus
+4
+@@mem
+MemTotal:        8000000 kB
+MemFree:          300000 kB
+MemAvailable:     600000 kB
+SwapTotal:       2097152 kB
+SwapFree:        2097152 kB
+@@df
+Filesystem     1024-blocks      Used Available Capacity Mounted on
+/dev/sda1         51474912  47000000   1843552      97% /
+tmpfs              4000000         0   4000000       0% /dev/shm
+/dev/sdb1        103081248  10000000  87817220      11% /srv/my data
+/dev/loop0           56704     56704         0     100% /snap/core/1
+@@ps
+      1 root       0.0  0.1  12000 systemd
+   2201 postgres  35.5 12.0 980000 postgres
+   3302 www-data  80.1  2.0 160000 php-fpm: pool www
+    912 root       1.0 20.5 1700000 java
+@@failed
+nginx.service loaded failed failed A high performance web server
+● cron.service loaded failed failed Regular background program
+`
+
+func TestParseHealth(t *testing.T) {
+	out := parseHealth(healthSample, nil, 2)
+	if out.UptimeSec != 273645 || out.Load1 != 5.10 || out.Load15 != 1.50 || out.CPUs != 4 {
+		t.Errorf("uptime/load = %d %v %v %d", out.UptimeSec, out.Load1, out.Load15, out.CPUs)
+	}
+	m := out.Memory
+	if m == nil || m.TotalMB != 7812 || m.AvailableMB != 585 || m.UsedPercent != 93 || m.SwapTotalMB != 2048 || m.SwapUsedMB != 0 {
+		t.Errorf("memory = %+v", m)
+	}
+	if len(out.Disks) != 2 || out.Disks[0].Mount != "/" || out.Disks[0].UsedPercent != 97 || out.Disks[1].Mount != "/srv/my data" {
+		t.Errorf("disks = %+v", out.Disks)
+	}
+	if len(out.TopCPU) != 2 || out.TopCPU[0].Command != "php-fpm: pool www" || out.TopCPU[1].PID != 2201 {
+		t.Errorf("top cpu = %+v", out.TopCPU)
+	}
+	if len(out.TopMemory) != 2 || out.TopMemory[0].Command != "java" || out.TopMemory[0].RSSKB != 1700000 {
+		t.Errorf("top memory = %+v", out.TopMemory)
+	}
+	if !out.Systemd || strings.Join(out.FailedUnits, ",") != "nginx.service,cron.service" {
+		t.Errorf("failed units = %v (systemd %v)", out.FailedUnits, out.Systemd)
+	}
+
+	warnings := strings.Join(healthWarnings(out), "; ")
+	want := "load 5.10 on 4 CPU(s); memory 93% used; / 97% full; 2 failed unit(s)"
+	if warnings != want {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestParseHealth_MountsAndMissingSections(t *testing.T) {
+	out := parseHealth(healthSample, []string{"/dev/shm", "/nope"}, 5)
+	if len(out.Disks) != 1 || out.Disks[0].Mount != "/dev/shm" {
+		t.Errorf("disks = %+v", out.Disks)
+	}
+
+	// A BSD host: no /proc, sysctl load in braces, no systemd.
+	out = parseHealth("@@uptime\n@@load\n{ 0.25 0.20 0.15 }\n@@cpus\n2\n@@mem\n@@df\n@@ps\n", nil, 5)
+	if out.Load1 != 0.25 || out.Load15 != 0.15 || out.Memory != nil || out.Systemd {
+		t.Errorf("out = %+v", out)
+	}
+	if w := healthWarnings(out); len(w) != 0 {
+		t.Errorf("warnings = %v", w)
+	}
+}
+
+func TestParseDF_Cap(t *testing.T) {
+	var lines []string
+	for i := range maxHealthDisks + 3 {
+		lines = append(lines, fmt.Sprintf("/dev/vd%c 1000 100 900 10%% /mnt/%d", 'a'+i, i))
+	}
+	disks, more := parseDF(lines, nil)
+	if len(disks) != maxHealthDisks || more != 3 {
+		t.Errorf("got %d disks, %d more", len(disks), more)
+	}
+}
+
+func TestSSHHealthOutput_Text(t *testing.T) {
+	out := parseHealth(healthSample, nil, 1)
+	out.SessionID = "s1"
+	out.Warnings = healthWarnings(out)
+	text := out.Text()
+	for _, want := range []string{
+		"Health of session s1: up 3d 4h",
+		"Load: 5.10 3.02 1.50 on 4 CPU(s)",
+		"Memory: 93% used, 585M available of 7.6G; swap 0M of 2.0G used",
+		"97% of 49.1G (1.8G free)",
+		"php-fpm: pool www",
+		"Failed units: nginx.service, cron.service",
+		"Warnings: load 5.10",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatHelpers(t *testing.T) {
+	if got := formatMB(512); got != "512M" {
+		t.Errorf("formatMB(512) = %q", got)
+	}
+	if got := formatMB(3 << 20); got != "3.0T" {
+		t.Errorf("formatMB(3T) = %q", got)
+	}
+	if got := formatUptime(5400); got != "1h 30m" {
+		t.Errorf("formatUptime(5400) = %q", got)
+	}
+	if got := percent(1, 3); got != 34 {
+		t.Errorf("percent(1, 3) = %d", got)
+	}
+}
@@ -1,14 +1,24 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/n0madic/ssh-mcp/internal/charset"
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
 )
 
 // TruncateOutput truncates s to maxBytes and appends a truncation marker.
@@ -25,6 +35,16 @@ func TruncateOutput(s string, maxBytes int) string {
 	return s[:end] + fmt.Sprintf("\n[OUTPUT TRUNCATED: showing first %d of %d bytes]", end, len(s))
 }
 
+// credentialKey returns the credential store key of id (user@host:port)
+// for the caller. Keys of a non-shared owner (an HTTP client or a tenant)
+// are prefixed with it, so nobody can use a password someone else saved.
+func credentialKey(ctx context.Context, id string) string {
+	if owner := connection.OwnerFrom(ctx); owner != "" {
+		return owner + "|" + id
+	}
+	return id
+}
+
 // getConnectionWithRateLimit retrieves a connection and its SSH client, optionally applying rate limiting.
 // If rateLimiter is nil, rate limiting is skipped.
 func getConnectionWithRateLimit(ctx context.Context, pool *connection.Pool, rateLimiter *security.RateLimiter, sessionID string) (*connection.Connection, *ssh.Client, error) {
@@ -34,7 +54,7 @@ func getConnectionWithRateLimit(ctx context.Context, pool *connection.Pool, rate
 	}
 
 	if rateLimiter != nil {
-		if err := rateLimiter.Allow(conn.Host); err != nil {
+		if err := rateLimiter.Wait(ctx, conn.Host); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -46,3 +66,241 @@ func getConnectionWithRateLimit(ctx context.Context, pool *connection.Pool, rate
 
 	return conn, client, nil
 }
+
+// remoteResult is the outcome of runRemoteCommand.
+type remoteResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+}
+
+// runRemoteCommand runs cmd in a new session on client, feeding stdin if
+// non-nil, and records the result in the connection statistics. On timeout
+// the command is stopped and the partial output returned with TimedOut set;
+// if ctx is cancelled it is stopped and an error returned. A non-zero exit
+// status is not an error.
+func runRemoteCommand(ctx context.Context, conn *connection.Connection, client *ssh.Client, cmd string, stdin io.Reader, timeout time.Duration) (*remoteResult, error) {
+	return runRemoteCommandGrace(ctx, conn, client, cmd, stdin, timeout, interruptGracePeriod+killGracePeriod)
+}
+
+// runRemoteCommandGrace is runRemoteCommand for user commands, which are
+// stopped with SIGKILL grace after SIGINT (see stopStages).
+func runRemoteCommandGrace(ctx context.Context, conn *connection.Connection, client *ssh.Client, cmd string, stdin io.Reader, timeout, grace time.Duration) (*remoteResult, error) {
+	return runRemoteCommandPrepared(ctx, conn, client, cmd, stdin, timeout, grace, nil)
+}
+
+// runRemoteCommandPrepared is runRemoteCommandGrace with a prepare hook,
+// if non-nil, that sets up the session (e.g. requests agent forwarding)
+// before cmd starts.
+func runRemoteCommandPrepared(ctx context.Context, conn *connection.Connection, client *ssh.Client, cmd string, stdin io.Reader, timeout, grace time.Duration, prepare func(*ssh.Session) error) (*remoteResult, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+	if prepare != nil {
+		if err := prepare(session); err != nil {
+			return nil, err
+		}
+	}
+
+	conn.IncrementCommandCount()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if stdin != nil {
+		session.Stdin = stdin
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+
+	res := &remoteResult{}
+	select {
+	case <-ctx.Done():
+		stopRemoteCommand(session, done, stopStages(grace))
+		if errors.Is(ctx.Err(), context.Canceled) {
+			conn.RecordCommandResult(cmd, time.Since(start), "command cancelled by client")
+			return nil, fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+		res.TimedOut = true
+		res.ExitCode = -1
+	case err := <-done:
+		if err != nil {
+			exitErr, ok := err.(interface{ ExitStatus() int })
+			if !ok {
+				conn.RecordCommandResult(cmd, time.Since(start), err.Error())
+				return nil, fmt.Errorf("execute command: %w", err)
+			}
+			res.ExitCode = exitErr.ExitStatus()
+		}
+	}
+	res.Duration = time.Since(start)
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+
+	var failure string
+	switch {
+	case res.TimedOut:
+		failure = fmt.Sprintf("command timed out after %s", timeout)
+	case res.ExitCode != 0:
+		failure = fmt.Sprintf("command exited with code %d", res.ExitCode)
+	}
+	conn.RecordCommandResult(cmd, res.Duration, failure)
+	return res, nil
+}
+
+// buildCLICommand builds an invocation of program with every argument
+// shell-quoted, checks the full command line against the command filter, and
+// prefixes non-interactive sudo if requested (and allowed).
+func buildCLICommand(filter *security.Filter, cfg *config.SSHConfig, sudo bool, program string, args ...string) (string, error) {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, program)
+	for _, a := range args {
+		quoted = append(quoted, shellQuote(a))
+	}
+	cmd := strings.Join(quoted, " ")
+
+	if err := filter.AllowCommand(cmd); err != nil {
+		return "", err
+	}
+	if sudo {
+		if !cfg.AllowSudo {
+			return "", fmt.Errorf("sudo is disabled; start server with --enable-sudo to allow")
+		}
+		cmd = "sudo -n " + cmd
+	}
+	return cmd, nil
+}
+
+// runCLICommand runs cmd on the session and returns its result; failures to
+// run the command, timeouts, and non-zero exits are returned as errors.
+func runCLICommand(ctx context.Context, pool *connection.Pool, rateLimiter *security.RateLimiter, sessionID, what, cmd string, timeout time.Duration) (*remoteResult, error) {
+	conn, client, err := getConnectionWithRateLimit(ctx, pool, rateLimiter, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if res.TimedOut || res.ExitCode != 0 {
+		return nil, remoteFailure(what, res)
+	}
+	return res, nil
+}
+
+// transferClient opens the SFTP client for ssh_upload and ssh_download per
+// --transfer-protocol. A nil client means the transfer should use scp:
+// always with scp, and with auto when the server refuses SFTP.
+func transferClient(cfg *config.SSHConfig, client *ssh.Client) (*sftp.Client, error) {
+	proto := config.TransferAuto
+	var opTimeout time.Duration
+	if cfg != nil {
+		proto, opTimeout = cfg.TransferProtocol, cfg.SFTPTimeout
+	}
+	if proto == config.TransferSCP {
+		return nil, nil
+	}
+	sc, err := sshclient.NewSFTPClient(client, opTimeout)
+	if err != nil && proto != config.TransferSFTP {
+		log.Printf("transfer: %v; falling back to scp", err)
+		return nil, nil
+	}
+	return sc, err
+}
+
+// outputDecoder returns the decoder for remote output: encoding if set,
+// otherwise the server's --output-encoding, with its fallback.
+func outputDecoder(cfg *config.SSHConfig, encoding string) (*charset.Decoder, error) {
+	var fallback string
+	if cfg != nil {
+		if encoding == "" {
+			encoding = cfg.OutputEncoding
+		}
+		fallback = cfg.FallbackEncoding
+	}
+	return charset.NewDecoder(encoding, fallback)
+}
+
+// decodeStreams converts stdout and stderr to UTF-8 and returns the encoding
+// that was converted from, or "" if both were UTF-8 already.
+func decodeStreams(dec *charset.Decoder, stdout, stderr string) (string, string, string) {
+	stdout, outEnc := dec.Decode([]byte(stdout))
+	stderr, errEnc := dec.Decode([]byte(stderr))
+	switch {
+	case outEnc != charset.UTF8:
+		return stdout, stderr, outEnc
+	case errEnc != charset.UTF8:
+		return stdout, stderr, errEnc
+	}
+	return stdout, stderr, ""
+}
+
+// decodeLogs converts log output to UTF-8 per the server's encoding settings.
+func decodeLogs(cfg *config.SSHConfig, logs string) string {
+	dec, _ := outputDecoder(cfg, "") // config is validated at startup; nil decodes as auto
+	logs, _ = dec.Decode([]byte(logs))
+	return logs
+}
+
+// execOutput converts a command result into ssh_execute-style output:
+// conversion to UTF-8, ANSI stripping, per-stream truncation, and a
+// [TIMEOUT] marker on stderr.
+func execOutput(res *remoteResult, timeout time.Duration, cfg *config.SSHConfig, maxOutputSize int) *SSHExecuteOutput {
+	dec, _ := outputDecoder(cfg, "") // config is validated at startup; nil decodes as auto
+	stdout, stderr, enc := decodeStreams(dec, res.Stdout, res.Stderr)
+	if cfg != nil && cfg.StripANSI {
+		stdout = sanitizeOutput(stdout)
+		stderr = sanitizeOutput(stderr)
+	}
+	stdout = TruncateOutput(stdout, maxOutputSize)
+	stderr = TruncateOutput(stderr, maxOutputSize)
+	if res.TimedOut {
+		timeoutMsg := fmt.Sprintf("[TIMEOUT] Command timed out after %s", timeout)
+		if stderr != "" {
+			stderr += "\n" + timeoutMsg
+		} else {
+			stderr = timeoutMsg
+		}
+	}
+	return &SSHExecuteOutput{
+		Stdout:     stdout,
+		Stderr:     stderr,
+		ExitCode:   res.ExitCode,
+		DurationMs: res.Duration.Milliseconds(),
+		TimedOut:   res.TimedOut,
+		Encoding:   enc,
+	}
+}
+
+// remoteFailure formats a failed remote command as an error, preferring its
+// stderr (which usually explains the failure) over the bare exit code.
+func remoteFailure(what string, res *remoteResult) error {
+	if res.TimedOut {
+		return fmt.Errorf("%s timed out after %s", what, res.Duration.Round(time.Second))
+	}
+	if msg := bytes.TrimSpace([]byte(res.Stderr)); len(msg) > 0 {
+		return fmt.Errorf("%s failed (exit code %d): %s", what, res.ExitCode, msg)
+	}
+	return fmt.Errorf("%s failed with exit code %d", what, res.ExitCode)
+}
+
+// expandRemotePath resolves p on the session's host with
+// sshclient.ExpandRemotePath, first converting a Windows path such as
+// C:\Users\me to the SFTP form /C:/Users/me when the host runs Windows.
+func expandRemotePath(conn *connection.Connection, sc *sftp.Client, p string) string {
+	if conn.GetRemoteInfo().OS == "Windows" {
+		p = sshclient.WindowsPath(p)
+	}
+	return sshclient.ExpandRemotePath(sc, p)
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"strings"
 	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
 )
 
 func TestTruncateOutput_Unlimited(t *testing.T) {
@@ -69,3 +71,37 @@ func TestTruncateOutput_UTF8Boundary(t *testing.T) {
 		t.Errorf("expected truncation marker, got %q", result)
 	}
 }
+
+func TestOutputDecoder(t *testing.T) {
+	cfg := &config.SSHConfig{OutputEncoding: "auto", FallbackEncoding: "windows-1251"}
+
+	dec, err := outputDecoder(cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, enc := decodeStreams(dec, "\xcf\xf0\xe8\xe2\xe5\xf2", "ok")
+	if stdout != "Привет" || stderr != "ok" || enc != "windows-1251" {
+		t.Errorf("auto with fallback: got %q, %q, %q", stdout, stderr, enc)
+	}
+
+	stdout, _, enc = decodeStreams(dec, "plain", "")
+	if stdout != "plain" || enc != "" {
+		t.Errorf("UTF-8 output should be unchanged with no encoding reported, got %q, %q", stdout, enc)
+	}
+
+	// A per-call encoding overrides the server default.
+	dec, err = outputDecoder(cfg, "latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout, _, enc = decodeStreams(dec, "caf\xe9", ""); stdout != "café" || enc != "windows-1252" {
+		t.Errorf("per-call encoding: got %q, %q", stdout, enc)
+	}
+
+	if _, err := outputDecoder(cfg, "klingon"); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+	if _, err := outputDecoder(nil, ""); err != nil {
+		t.Errorf("nil config should decode as auto: %v", err)
+	}
+}
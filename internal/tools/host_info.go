@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// HostInfoDeps holds dependencies for the ssh_host_info tool handler.
+type HostInfoDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// HandleHostInfo implements the ssh_host_info tool. It returns what was
+// detected about the host on connect, or probes it again with refresh. The
+// probe is a fixed set of read-only commands, so it bypasses the command
+// filter like the detection on connect does.
+func HandleHostInfo(ctx context.Context, deps *HostInfoDeps, input SSHHostInfoInput) (*SSHHostInfoOutput, error) {
+	conn, err := deps.Pool.GetConnection(ctx, connection.SessionID(input.SessionID))
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+
+	info := conn.GetRemoteInfo()
+	if input.Refresh {
+		if err := deps.RateLimiter.Wait(ctx, conn.Host); err != nil {
+			return nil, err
+		}
+		if info, err = conn.RefreshRemoteInfo(ctx); err != nil {
+			return nil, fmt.Errorf("refresh host info: %w", err)
+		}
+	}
+	return hostInfoOutput(input.SessionID, info, input.Refresh), nil
+}
+
+func hostInfoOutput(sessionID string, info connection.RemoteInfo, refreshed bool) *SSHHostInfoOutput {
+	return &SSHHostInfoOutput{
+		SessionID:          sessionID,
+		OS:                 info.OS,
+		Arch:               info.Arch,
+		Shell:              info.Shell,
+		Distro:             info.Distro,
+		DistroLike:         info.DistroLike,
+		DistroVersion:      info.DistroVersion,
+		DistroName:         info.DistroName,
+		PackageManager:     info.PackageManager,
+		InitSystem:         info.InitSystem,
+		Sudo:               info.Sudo,
+		SudoNoninteractive: info.SudoNoninteractive,
+		Doas:               info.Doas,
+		Refreshed:          refreshed,
+	}
+}
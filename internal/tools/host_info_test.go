@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+func TestHandleHostInfo_UnknownSession(t *testing.T) {
+	cfg := &config.SSHConfig{ConfigPath: "/nonexistent/ssh/config"}
+	deps := &HostInfoDeps{Pool: connection.NewPool(cfg, connection.NewAuthDiscovery(cfg))}
+	_, err := HandleHostInfo(context.Background(), deps, SSHHostInfoInput{SessionID: "nobody@nowhere:22", Refresh: true})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected session not found, got %v", err)
+	}
+}
+
+func TestSSHHostInfoOutput_Text(t *testing.T) {
+	out := hostInfoOutput("root@web:22", connection.RemoteInfo{
+		OS: "Linux", Arch: "x86_64", Shell: "/bin/bash",
+		Distro: "ubuntu", DistroLike: "debian", DistroVersion: "22.04", DistroName: "Ubuntu 22.04.4 LTS",
+		PackageManager: "apt", InitSystem: "systemd", Sudo: true, SudoNoninteractive: true, Doas: true,
+	}, true)
+	text := out.Text()
+	for _, want := range []string{
+		"root@web:22 (refreshed)", "OS: Linux x86_64", "Distro: Ubuntu 22.04.4 LTS",
+		"Distro ID: ubuntu 22.04 (like debian)", "Package manager: apt", "Init system: systemd",
+		"Privilege tools: sudo (passwordless), doas",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	text = hostInfoOutput("root@web:22", connection.RemoteInfo{}, false).Text()
+	if !strings.Contains(text, "could not be detected") || strings.Contains(text, "refreshed") {
+		t.Errorf("Text() for undetected host:\n%s", text)
+	}
+}
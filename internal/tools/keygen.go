@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// KeygenDeps holds dependencies for the ssh_keygen tool handler.
+type KeygenDeps struct {
+	LocalDirs []config.LocalDir
+	SSHDir    string
+}
+
+// HandleKeygen implements the ssh_keygen tool.
+// Keys are written under the first writable --local-base-dir when one is
+// configured, otherwise under ~/.ssh.
+func HandleKeygen(_ context.Context, deps *KeygenDeps, input SSHKeygenInput) (*SSHKeygenOutput, error) {
+	keyType := strings.ToLower(input.KeyType)
+	if keyType == "" {
+		keyType = connection.KeyTypeEd25519
+	}
+
+	baseDir := deps.SSHDir
+	if len(deps.LocalDirs) > 0 {
+		baseDir = ""
+		for _, d := range deps.LocalDirs {
+			if !d.ReadOnly {
+				baseDir = d.Path
+				break
+			}
+		}
+	}
+
+	keyPath := input.Path
+	if keyPath == "" {
+		keyPath = "mcp_id_" + keyType
+	}
+	if !filepath.IsAbs(keyPath) {
+		if baseDir == "" {
+			return nil, fmt.Errorf("cannot determine key directory (no writable local base dir); pass an absolute path")
+		}
+		if err := os.MkdirAll(baseDir, 0700); err != nil {
+			return nil, fmt.Errorf("create key directory: %w", err)
+		}
+		keyPath = filepath.Join(baseDir, keyPath)
+	}
+
+	if err := security.ValidateFilename(filepath.Base(keyPath)); err != nil {
+		return nil, fmt.Errorf("invalid key path: %w", err)
+	}
+	if err := security.ValidateLocalAccess(keyPath, deps.LocalDirs, true); err != nil {
+		return nil, fmt.Errorf("invalid key path: %w", err)
+	}
+	if err := security.ValidateLocalAccess(keyPath+".pub", deps.LocalDirs, true); err != nil {
+		return nil, fmt.Errorf("invalid key path: %w", err)
+	}
+
+	comment := input.Comment
+	if comment == "" {
+		comment = "ssh-mcp"
+	}
+
+	key, err := connection.GenerateKeyPair(keyType, input.Bits, comment, keyPath, input.Overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSHKeygenOutput{
+		PrivateKeyPath: key.PrivateKeyPath,
+		PublicKeyPath:  key.PublicKeyPath,
+		PublicKey:      key.PublicKey,
+		Fingerprint:    key.Fingerprint,
+		Message: fmt.Sprintf("Generated %s %d-bit key %s (%s); public key saved to %s",
+			key.Type, key.Bits, key.PrivateKeyPath, key.Fingerprint, key.PublicKeyPath),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestHandleKeygen_DefaultPathUnderBaseDir(t *testing.T) {
+	base := t.TempDir()
+	deps := &KeygenDeps{LocalDirs: []config.LocalDir{{Path: base}}, SSHDir: "/nonexistent/.ssh"}
+
+	out, err := HandleKeygen(context.Background(), deps, SSHKeygenInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolvedBase, _ := filepath.EvalSymlinks(base)
+	if filepath.Dir(out.PrivateKeyPath) != base && filepath.Dir(out.PrivateKeyPath) != resolvedBase {
+		t.Errorf("key written to %s, want under %s", out.PrivateKeyPath, base)
+	}
+	if filepath.Base(out.PrivateKeyPath) != "mcp_id_ed25519" {
+		t.Errorf("unexpected default key name %s", filepath.Base(out.PrivateKeyPath))
+	}
+	if !strings.HasPrefix(out.PublicKey, "ssh-ed25519 ") {
+		t.Errorf("unexpected public key %q", out.PublicKey)
+	}
+	if !strings.Contains(out.Text(), out.PublicKey) {
+		t.Error("Text() should include the public key")
+	}
+}
+
+func TestHandleKeygen_RejectsPathOutsideBaseDir(t *testing.T) {
+	base := t.TempDir()
+	other := t.TempDir()
+	deps := &KeygenDeps{LocalDirs: []config.LocalDir{{Path: base}}}
+
+	_, err := HandleKeygen(context.Background(), deps, SSHKeygenInput{Path: filepath.Join(other, "id_test")})
+	if err == nil {
+		t.Fatal("expected error for key path outside local base dir")
+	}
+}
+
+func TestHandleKeygen_ReadOnlyBaseDirs(t *testing.T) {
+	ro, rw := t.TempDir(), t.TempDir()
+	deps := &KeygenDeps{LocalDirs: []config.LocalDir{{Path: ro, ReadOnly: true}, {Path: rw}}}
+
+	out, err := HandleKeygen(context.Background(), deps, SSHKeygenInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(rw)
+	if dir := filepath.Dir(out.PrivateKeyPath); dir != rw && dir != resolved {
+		t.Errorf("key written to %s, want under the writable dir %s", out.PrivateKeyPath, rw)
+	}
+
+	_, err = HandleKeygen(context.Background(), deps, SSHKeygenInput{Path: filepath.Join(ro, "id_test")})
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("expected read-only error, got %v", err)
+	}
+
+	deps.LocalDirs = deps.LocalDirs[:1]
+	if _, err := HandleKeygen(context.Background(), deps, SSHKeygenInput{}); err == nil || !strings.Contains(err.Error(), "no writable local base dir") {
+		t.Errorf("expected missing writable dir error, got %v", err)
+	}
+}
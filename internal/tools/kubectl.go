@@ -0,0 +1,323 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// defaultKubectlLogTail is the number of log lines returned when tail is unset.
+const defaultKubectlLogTail = 100
+
+var (
+	// k8sNamePattern matches Kubernetes object names (DNS subdomain names).
+	k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// k8sKindPattern matches resource kinds such as pod, deployment.apps, svc.
+	k8sKindPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.-]*$`)
+)
+
+// KubectlDeps holds dependencies for the ssh_kubectl_* tool handlers.
+type KubectlDeps struct {
+	Pool          *connection.Pool
+	Filter        *security.Filter
+	RateLimiter   *security.RateLimiter
+	Config        *config.SSHConfig
+	MaxOutputSize int
+}
+
+// kubectlTarget holds the cluster selection flags shared by all kubectl tools.
+type kubectlTarget struct {
+	Context   string
+	Namespace string
+}
+
+// args returns the global kubectl flags for the target.
+func (t kubectlTarget) args() ([]string, error) {
+	var args []string
+	if t.Context != "" {
+		if strings.HasPrefix(t.Context, "-") {
+			return nil, fmt.Errorf("invalid context %q", t.Context)
+		}
+		args = append(args, "--context", t.Context)
+	}
+	if t.Namespace != "" {
+		if !k8sNamePattern.MatchString(t.Namespace) {
+			return nil, fmt.Errorf("invalid namespace %q", t.Namespace)
+		}
+		args = append(args, "--namespace", t.Namespace)
+	}
+	return args, nil
+}
+
+// kubectlCommand builds a filtered kubectl invocation for target.
+func kubectlCommand(deps *KubectlDeps, target kubectlTarget, args ...string) (string, error) {
+	global, err := target.args()
+	if err != nil {
+		return "", err
+	}
+	return buildCLICommand(deps.Filter, deps.Config, false, "kubectl", append(global, args...)...)
+}
+
+func validateK8sName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s is required", kind)
+	}
+	if !k8sNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s %q", kind, name)
+	}
+	return nil
+}
+
+// HandleKubectlGetPods implements the ssh_kubectl_get_pods tool.
+func HandleKubectlGetPods(ctx context.Context, deps *KubectlDeps, input SSHKubectlGetPodsInput) (*SSHKubectlGetPodsOutput, error) {
+	args := []string{"get", "pods", "--output", "json"}
+	if input.AllNamespaces {
+		args = append(args, "--all-namespaces")
+	}
+	if input.Selector != "" {
+		if strings.HasPrefix(input.Selector, "-") {
+			return nil, fmt.Errorf("invalid selector %q", input.Selector)
+		}
+		args = append(args, "--selector", input.Selector)
+	}
+	target := kubectlTarget{Context: input.Context, Namespace: input.Namespace}
+	if input.AllNamespaces {
+		target.Namespace = ""
+	}
+	cmd, err := kubectlCommand(deps, target, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runCLICommand(ctx, deps.Pool, deps.RateLimiter, input.SessionID, "kubectl get pods", cmd, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := parsePodList(res.Stdout, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("parse kubectl output: %w", err)
+	}
+	return &SSHKubectlGetPodsOutput{Pods: pods}, nil
+}
+
+// podList is the subset of `kubectl get pods -o json` that is reported.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string    `json:"name"`
+			Namespace         string    `json:"namespace"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+			DeletionTimestamp *string   `json:"deletionTimestamp"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName   string     `json:"nodeName"`
+			Containers []struct{} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			Phase             string `json:"phase"`
+			Reason            string `json:"reason"`
+			PodIP             string `json:"podIP"`
+			ContainerStatuses []struct {
+				Ready        bool `json:"ready"`
+				RestartCount int  `json:"restartCount"`
+				State        struct {
+					Waiting *struct {
+						Reason string `json:"reason"`
+					} `json:"waiting"`
+					Terminated *struct {
+						Reason string `json:"reason"`
+					} `json:"terminated"`
+				} `json:"state"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parsePodList converts kubectl JSON into KubePod entries, deriving a status
+// like the STATUS column of `kubectl get pods` (waiting/terminated reasons
+// such as CrashLoopBackOff take precedence over the phase).
+func parsePodList(data string, now time.Time) ([]KubePod, error) {
+	var list podList
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, err
+	}
+	pods := make([]KubePod, 0, len(list.Items))
+	for _, item := range list.Items {
+		p := KubePod{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Node:      item.Spec.NodeName,
+			IP:        item.Status.PodIP,
+			Status:    item.Status.Phase,
+		}
+		if item.Status.Reason != "" {
+			p.Status = item.Status.Reason
+		}
+		ready := 0
+		for _, cs := range item.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			p.Restarts += cs.RestartCount
+			if w := cs.State.Waiting; w != nil && w.Reason != "" {
+				p.Status = w.Reason
+			} else if t := cs.State.Terminated; t != nil && t.Reason != "" {
+				p.Status = t.Reason
+			}
+		}
+		if item.Metadata.DeletionTimestamp != nil {
+			p.Status = "Terminating"
+		}
+		p.Ready = fmt.Sprintf("%d/%d", ready, len(item.Spec.Containers))
+		if !item.Metadata.CreationTimestamp.IsZero() {
+			p.Age = formatAge(now.Sub(item.Metadata.CreationTimestamp))
+		}
+		pods = append(pods, p)
+	}
+	return pods, nil
+}
+
+// formatAge formats a duration like kubectl's AGE column (e.g. 45s, 12m, 3h, 5d).
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// HandleKubectlLogs implements the ssh_kubectl_logs tool.
+func HandleKubectlLogs(ctx context.Context, deps *KubectlDeps, input SSHKubectlLogsInput) (*SSHKubectlLogsOutput, error) {
+	if err := validateK8sName("pod", input.Pod); err != nil {
+		return nil, err
+	}
+	if input.Tail < 0 {
+		return nil, fmt.Errorf("invalid tail: %d (must be non-negative)", input.Tail)
+	}
+	tail := input.Tail
+	if tail == 0 {
+		tail = defaultKubectlLogTail
+	}
+	args := []string{"logs", input.Pod, "--tail", fmt.Sprint(tail)}
+	if input.Container != "" {
+		if err := validateK8sName("container", input.Container); err != nil {
+			return nil, err
+		}
+		args = append(args, "--container", input.Container)
+	}
+	if input.Since != "" {
+		if strings.HasPrefix(input.Since, "-") {
+			return nil, fmt.Errorf("invalid since %q", input.Since)
+		}
+		args = append(args, "--since", input.Since)
+	}
+	if input.Previous {
+		args = append(args, "--previous")
+	}
+	if input.Timestamps {
+		args = append(args, "--timestamps")
+	}
+
+	cmd, err := kubectlCommand(deps, kubectlTarget{Context: input.Context, Namespace: input.Namespace}, args...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runCLICommand(ctx, deps.Pool, deps.RateLimiter, input.SessionID, "kubectl logs", cmd, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := decodeLogs(deps.Config, res.Stdout)
+	if deps.Config.StripANSI {
+		logs = sanitizeOutput(logs)
+	}
+	lines := strings.Count(logs, "\n")
+	if logs != "" && !strings.HasSuffix(logs, "\n") {
+		lines++
+	}
+	return &SSHKubectlLogsOutput{
+		Pod:   input.Pod,
+		Lines: lines,
+		Logs:  TruncateOutput(logs, deps.MaxOutputSize),
+	}, nil
+}
+
+// HandleKubectlDescribe implements the ssh_kubectl_describe tool.
+func HandleKubectlDescribe(ctx context.Context, deps *KubectlDeps, input SSHKubectlDescribeInput) (*SSHKubectlDescribeOutput, error) {
+	kind := input.Kind
+	if kind == "" {
+		kind = "pod"
+	}
+	if !k8sKindPattern.MatchString(kind) {
+		return nil, fmt.Errorf("invalid kind %q", kind)
+	}
+	if err := validateK8sName("name", input.Name); err != nil {
+		return nil, err
+	}
+	cmd, err := kubectlCommand(deps, kubectlTarget{Context: input.Context, Namespace: input.Namespace}, "describe", kind, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runCLICommand(ctx, deps.Pool, deps.RateLimiter, input.SessionID, "kubectl describe", cmd, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHKubectlDescribeOutput{
+		Kind:        kind,
+		Name:        input.Name,
+		Description: TruncateOutput(strings.TrimRight(res.Stdout, "\n"), deps.MaxOutputSize),
+	}, nil
+}
+
+// HandleKubectlExec implements the ssh_kubectl_exec tool.
+func HandleKubectlExec(ctx context.Context, deps *KubectlDeps, input SSHKubectlExecInput) (*SSHExecuteOutput, error) {
+	if err := validateK8sName("pod", input.Pod); err != nil {
+		return nil, err
+	}
+	if input.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	// The in-pod command is subject to the same filter as ssh_execute.
+	if err := deps.Filter.AllowCommand(input.Command); err != nil {
+		return nil, err
+	}
+	args := []string{"exec", input.Pod}
+	if input.Container != "" {
+		if err := validateK8sName("container", input.Container); err != nil {
+			return nil, err
+		}
+		args = append(args, "--container", input.Container)
+	}
+	args = append(args, "--", "sh", "-c", input.Command)
+
+	cmd, err := kubectlCommand(deps, kubectlTarget{Context: input.Context, Namespace: input.Namespace}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	timeout := deps.Config.CommandTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return execOutput(res, timeout, deps.Config, deps.MaxOutputSize), nil
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func newTestKubectlDeps(t *testing.T, commandDenylist ...string) *KubectlDeps {
+	t.Helper()
+	filter, err := security.NewFilter(nil, nil, nil, commandDenylist)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	return &KubectlDeps{Filter: filter, Config: &config.SSHConfig{}}
+}
+
+func TestKubectlCommand(t *testing.T) {
+	deps := newTestKubectlDeps(t)
+
+	cmd, err := kubectlCommand(deps, kubectlTarget{Context: "prod", Namespace: "web"}, "logs", "api-0")
+	if err != nil {
+		t.Fatalf("kubectlCommand: %v", err)
+	}
+	if want := `kubectl '--context' 'prod' '--namespace' 'web' 'logs' 'api-0'`; cmd != want {
+		t.Errorf("kubectlCommand = %q, want %q", cmd, want)
+	}
+
+	if _, err := kubectlCommand(deps, kubectlTarget{Context: "--kubeconfig=/tmp/x"}, "get", "pods"); err == nil {
+		t.Error("expected error for option-like context")
+	}
+	if _, err := kubectlCommand(deps, kubectlTarget{Namespace: "Bad_NS"}, "get", "pods"); err == nil {
+		t.Error("expected error for invalid namespace")
+	}
+	if _, err := kubectlCommand(newTestKubectlDeps(t, "kubectl .*delete.*"), kubectlTarget{}, "delete", "pod", "x"); err == nil {
+		t.Error("expected command filter to block kubectl delete")
+	}
+}
+
+func TestParsePodList(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	data := `{"items":[
+  {"metadata":{"name":"api-0","namespace":"web","creationTimestamp":"2025-01-07T12:00:00Z"},
+   "spec":{"nodeName":"node-1","containers":[{},{}]},
+   "status":{"phase":"Running","podIP":"10.0.0.5","containerStatuses":[
+     {"ready":true,"restartCount":1,"state":{"running":{}}},
+     {"ready":false,"restartCount":4,"state":{"waiting":{"reason":"CrashLoopBackOff"}}}]}},
+  {"metadata":{"name":"job-x","namespace":"batch","creationTimestamp":"2025-01-10T11:58:30Z","deletionTimestamp":"2025-01-10T11:59:00Z"},
+   "spec":{"containers":[{}]},
+   "status":{"phase":"Pending"}}
+]}`
+	pods, err := parsePodList(data, now)
+	if err != nil {
+		t.Fatalf("parsePodList: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("expected 2 pods, got %d", len(pods))
+	}
+	want := KubePod{Name: "api-0", Namespace: "web", Ready: "1/2", Status: "CrashLoopBackOff", Restarts: 5, Age: "3d", Node: "node-1", IP: "10.0.0.5"}
+	if pods[0] != want {
+		t.Errorf("pod 0 = %+v, want %+v", pods[0], want)
+	}
+	if pods[1].Status != "Terminating" || pods[1].Ready != "0/1" || pods[1].Age != "1m" {
+		t.Errorf("unexpected pod 1 %+v", pods[1])
+	}
+
+	if _, err := parsePodList("error: no context", now); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		45 * time.Second: "45s",
+		12 * time.Minute: "12m",
+		30 * time.Hour:   "30h",
+		72 * time.Hour:   "3d",
+	}
+	for d, want := range tests {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestSSHKubectlGetPodsOutput_Text(t *testing.T) {
+	out := SSHKubectlGetPodsOutput{Pods: []KubePod{{Name: "api-0", Namespace: "web", Ready: "1/1", Status: "Running", Age: "2h", Node: "node-1"}}}
+	text := out.Text()
+	for _, want := range []string{"1 pod(s)", "web/api-0", "1/1", "Running", "age=2h", "node=node-1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+	if got := (SSHKubectlGetPodsOutput{}).Text(); got != "No pods" {
+		t.Errorf("empty Text() = %q", got)
+	}
+}
+
+func TestHandleKubectl_Validation(t *testing.T) {
+	deps := newTestKubectlDeps(t, "rm -rf.*")
+	if _, err := HandleKubectlLogs(t.Context(), deps, SSHKubectlLogsInput{SessionID: "s"}); err == nil {
+		t.Error("expected error for missing pod")
+	}
+	if _, err := HandleKubectlLogs(t.Context(), deps, SSHKubectlLogsInput{SessionID: "s", Pod: "api-0", Tail: -1}); err == nil {
+		t.Error("expected error for negative tail")
+	}
+	if _, err := HandleKubectlDescribe(t.Context(), deps, SSHKubectlDescribeInput{SessionID: "s", Kind: "-o", Name: "api-0"}); err == nil {
+		t.Error("expected error for option-like kind")
+	}
+	if _, err := HandleKubectlExec(t.Context(), deps, SSHKubectlExecInput{SessionID: "s", Pod: "api-0"}); err == nil {
+		t.Error("expected error for empty command")
+	}
+	if _, err := HandleKubectlExec(t.Context(), deps, SSHKubectlExecInput{SessionID: "s", Pod: "api-0", Command: "rm -rf /data"}); err == nil {
+		t.Error("expected command filter to block in-pod command")
+	}
+}
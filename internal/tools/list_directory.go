@@ -0,0 +1,355 @@
+package tools
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+const (
+	defaultListLimit = 200
+	maxListLimit     = 5000
+)
+
+// ListDirectoryDeps holds dependencies for the ssh_list_directory tool handler.
+type ListDirectoryDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// HandleListDirectory implements the ssh_list_directory tool. Entries are
+// streamed from the server and only the requested page is kept, so huge
+// directories list in bounded memory.
+func HandleListDirectory(ctx context.Context, deps *ListDirectoryDeps, input SSHListDirectoryInput) (*SSHListDirectoryOutput, error) {
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	// Validate the listing options before touching the connection.
+	sel, err := newEntrySelector(input)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+
+	err = sshclient.ReadDirStream(ctx, client, conn.SFTPTimeout, input.RemotePath, func(fi os.FileInfo) error {
+		if !sel.add(fi) {
+			return sshclient.ErrStopListing
+		}
+		return nil
+	})
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("list %s: %w", input.RemotePath, err)
+	}
+
+	out := sel.result()
+	out.Path = input.RemotePath
+	for i, e := range out.Entries {
+		if e.Type == "symlink" {
+			if target, err := sc.ReadLink(path.Join(input.RemotePath, e.Name)); err == nil {
+				out.Entries[i].LinkTarget = target
+			}
+		}
+	}
+	return out, nil
+}
+
+// selectEntries filters, sorts and pages directory entries according to
+// input, as HandleListDirectory does for a streamed directory.
+func selectEntries(infos []os.FileInfo, input SSHListDirectoryInput) (*SSHListDirectoryOutput, error) {
+	sel, err := newEntrySelector(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range infos {
+		if !sel.add(fi) {
+			break
+		}
+	}
+	return sel.result(), nil
+}
+
+// listCursor is the decoded continuation token of a listing: the sort it
+// was issued for and the position of the last entry returned, so the next
+// page starts right after it without keeping any state on the server.
+type listCursor struct {
+	Sort    string `json:"s"`
+	Reverse bool   `json:"r,omitempty"`
+	Name    string `json:"n,omitempty"`
+	Size    int64  `json:"z,omitempty"`
+	MTime   int64  `json:"t,omitempty"` // Unix nanoseconds
+	Pos     int    `json:"p,omitempty"` // matching entries already returned, for sort=none
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(s string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: use next_cursor from a previous listing")
+	}
+	return c, nil
+}
+
+// cursorEntry stands in for the last entry of the previous page when
+// comparing positions.
+type cursorEntry struct{ c listCursor }
+
+func (e cursorEntry) Name() string       { return e.c.Name }
+func (e cursorEntry) Size() int64        { return e.c.Size }
+func (e cursorEntry) Mode() os.FileMode  { return 0 }
+func (e cursorEntry) ModTime() time.Time { return time.Unix(0, e.c.MTime) }
+func (e cursorEntry) IsDir() bool        { return false }
+func (e cursorEntry) Sys() any           { return nil }
+
+// entrySelector picks one page of a directory from entries fed one at a
+// time. Sorted listings keep only the best offset+limit+1 entries in a heap;
+// sort=none keeps entries in server order and stops reading once the page
+// is full, unless count asks for the total.
+type entrySelector struct {
+	input   SSHListDirectoryInput
+	re      *regexp.Regexp
+	less    func(a, b os.FileInfo) bool
+	limit   int
+	after   os.FileInfo // entries up to this one were returned already
+	skip    int         // entries to pass over before the page: offset, plus the cursor position for sort=none
+	kept    []os.FileInfo
+	total   int
+	passed  int  // matching entries at or before the cursor
+	counts  bool // read every entry, so total is complete
+	stopped bool // reading ended early; total is partial
+}
+
+func newEntrySelector(input SSHListDirectoryInput) (*entrySelector, error) {
+	s := &entrySelector{input: input}
+	if input.Pattern != "" {
+		if _, err := path.Match(input.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", input.Pattern, err)
+		}
+	}
+	if input.Regex != "" {
+		var err error
+		if s.re, err = regexp.Compile(input.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", input.Regex, err)
+		}
+	}
+	switch input.Type {
+	case "", "file", "directory", "symlink", "other":
+	default:
+		return nil, fmt.Errorf("unknown type filter: %q (must be 'file', 'directory', 'symlink', or 'other')", input.Type)
+	}
+	sortBy := input.Sort
+	switch sortBy {
+	case "", "name":
+		sortBy = "name"
+		s.less = func(a, b os.FileInfo) bool { return a.Name() < b.Name() }
+	case "size":
+		s.less = func(a, b os.FileInfo) bool { return a.Size() < b.Size() }
+	case "mtime":
+		s.less = func(a, b os.FileInfo) bool { return a.ModTime().Before(b.ModTime()) }
+	case "none":
+		if input.Reverse {
+			return nil, fmt.Errorf("reverse cannot be used with sort=none")
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort: %q (must be 'name', 'size', 'mtime', or 'none')", input.Sort)
+	}
+	if input.Offset < 0 {
+		return nil, fmt.Errorf("offset must be non-negative")
+	}
+	s.limit = input.Limit
+	switch {
+	case s.limit < 0:
+		return nil, fmt.Errorf("limit must be non-negative")
+	case s.limit == 0:
+		s.limit = defaultListLimit
+	case s.limit > maxListLimit:
+		s.limit = maxListLimit
+	}
+	s.counts = sortBy != "none" || input.Count
+	s.skip = input.Offset
+
+	if input.Cursor != "" {
+		c, err := decodeListCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortBy || c.Reverse != input.Reverse {
+			return nil, fmt.Errorf("cursor was issued for a different sort order; repeat the sort and reverse of the first page")
+		}
+		if sortBy == "none" {
+			s.skip += c.Pos
+		} else {
+			s.after = cursorEntry{c}
+		}
+	}
+	return s, nil
+}
+
+// before reports whether a is listed before b.
+func (s *entrySelector) before(a, b os.FileInfo) bool {
+	if s.input.Reverse {
+		a, b = b, a
+	}
+	if s.less(a, b) {
+		return true
+	}
+	if s.less(b, a) {
+		return false
+	}
+	// Ties (equal size or mtime) fall back to name order.
+	return a.Name() < b.Name()
+}
+
+func (s *entrySelector) matches(fi os.FileInfo) bool {
+	name := fi.Name()
+	if s.input.Pattern != "" {
+		if ok, _ := path.Match(s.input.Pattern, name); !ok {
+			return false
+		}
+	}
+	if s.re != nil && !s.re.MatchString(name) {
+		return false
+	}
+	return s.input.Type == "" || entryType(fi.Mode()) == s.input.Type
+}
+
+// add offers one directory entry and reports whether more are wanted.
+func (s *entrySelector) add(fi os.FileInfo) bool {
+	if !s.matches(fi) {
+		return true
+	}
+	s.total++
+
+	if s.less == nil {
+		// sort=none: the server's order, positions counted from the start.
+		if s.total > s.skip && len(s.kept) <= s.limit {
+			s.kept = append(s.kept, fi)
+		}
+		s.stopped = !s.counts && len(s.kept) > s.limit
+		return !s.stopped
+	}
+
+	if s.after != nil && !s.before(s.after, fi) {
+		s.passed++
+		return true
+	}
+	// A heap with the entry listed last on top, holding the page plus the
+	// offset before it and one entry to tell whether more follow.
+	h := &entryHeap{s.kept, s.before}
+	heap.Push(h, fi)
+	if h.Len() > s.skip+s.limit+1 {
+		heap.Pop(h)
+	}
+	s.kept = h.items
+	return true
+}
+
+// result returns the selected page.
+func (s *entrySelector) result() *SSHListDirectoryOutput {
+	page := s.kept
+	if s.less != nil {
+		slices.SortFunc(page, func(a, b os.FileInfo) int {
+			if s.before(a, b) {
+				return -1
+			}
+			return 1
+		})
+		page = page[min(s.skip, len(page)):]
+	}
+
+	out := &SSHListDirectoryOutput{Total: s.total, Offset: s.passed + s.skip}
+	if s.stopped {
+		out.Total = -1
+	}
+	if len(page) > s.limit {
+		page = page[:s.limit]
+		out.HasMore = true
+	}
+	out.Entries = make([]DirEntry, len(page))
+	for i, fi := range page {
+		out.Entries[i] = DirEntry{
+			Name:    fi.Name(),
+			Type:    entryType(fi.Mode()),
+			Size:    fi.Size(),
+			Mode:    fi.Mode().String(),
+			ModTime: fi.ModTime().UTC().Format(time.RFC3339),
+		}
+	}
+	if out.HasMore {
+		out.NextCursor = s.nextCursor(page[len(page)-1])
+	}
+	return out
+}
+
+func (s *entrySelector) nextCursor(last os.FileInfo) string {
+	if s.less == nil {
+		return listCursor{Sort: "none", Pos: s.skip + s.limit}.encode()
+	}
+	sortBy := s.input.Sort
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	return listCursor{
+		Sort:    sortBy,
+		Reverse: s.input.Reverse,
+		Name:    last.Name(),
+		Size:    last.Size(),
+		MTime:   last.ModTime().UnixNano(),
+	}.encode()
+}
+
+// entryHeap is a container/heap of entries with the one listed last on top.
+type entryHeap struct {
+	items  []os.FileInfo
+	before func(a, b os.FileInfo) bool
+}
+
+func (h *entryHeap) Len() int           { return len(h.items) }
+func (h *entryHeap) Less(i, j int) bool { return h.before(h.items[j], h.items[i]) }
+func (h *entryHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *entryHeap) Push(x any)         { h.items = append(h.items, x.(os.FileInfo)) }
+func (h *entryHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// entryType collapses fileType into the listing's type filter values.
+func entryType(m os.FileMode) string {
+	switch t := fileType(m); t {
+	case "file", "directory", "symlink":
+		return t
+	}
+	return "other"
+}
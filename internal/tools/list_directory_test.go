@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f fakeFileInfo) Name() string       { return f.name }
+func (f fakeFileInfo) Size() int64        { return f.size }
+func (f fakeFileInfo) Mode() fs.FileMode  { return f.mode }
+func (f fakeFileInfo) ModTime() time.Time { return f.modTime }
+func (f fakeFileInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f fakeFileInfo) Sys() any           { return nil }
+
+func testDirInfos() []os.FileInfo {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []os.FileInfo{
+		fakeFileInfo{"syslog", 300, 0644, base.Add(3 * time.Hour)},
+		fakeFileInfo{"auth.log", 100, 0640, base.Add(1 * time.Hour)},
+		fakeFileInfo{"nginx", 4096, fs.ModeDir | 0755, base.Add(2 * time.Hour)},
+		fakeFileInfo{"kern.log", 300, 0640, base},
+		fakeFileInfo{"current", 10, fs.ModeSymlink | 0777, base},
+		fakeFileInfo{"syslog.1.gz", 50, 0644, base.Add(-time.Hour)},
+	}
+}
+
+func entryNames(out *SSHListDirectoryOutput) string {
+	names := make([]string, len(out.Entries))
+	for i, e := range out.Entries {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSelectEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		input SSHListDirectoryInput
+		want  string
+		total int
+	}{
+		{"default name order", SSHListDirectoryInput{}, "auth.log,current,kern.log,nginx,syslog,syslog.1.gz", 6},
+		{"glob", SSHListDirectoryInput{Pattern: "*.log"}, "auth.log,kern.log", 2},
+		{"regex", SSHListDirectoryInput{Regex: `^sys`}, "syslog,syslog.1.gz", 2},
+		{"type directory", SSHListDirectoryInput{Type: "directory"}, "nginx", 1},
+		{"type symlink", SSHListDirectoryInput{Type: "symlink"}, "current", 1},
+		{"size with name tie-break", SSHListDirectoryInput{Sort: "size", Type: "file"}, "syslog.1.gz,auth.log,kern.log,syslog", 4},
+		{"largest first", SSHListDirectoryInput{Sort: "size", Reverse: true, Limit: 3}, "nginx,syslog,kern.log", 6},
+		{"newest first", SSHListDirectoryInput{Sort: "mtime", Reverse: true, Limit: 2}, "syslog,nginx", 6},
+		{"second page", SSHListDirectoryInput{Limit: 2, Offset: 2}, "kern.log,nginx", 6},
+		{"offset past end", SSHListDirectoryInput{Offset: 10}, "", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := selectEntries(testDirInfos(), tt.input)
+			if err != nil {
+				t.Fatalf("selectEntries: %v", err)
+			}
+			if got := entryNames(out); got != tt.want || out.Total != tt.total {
+				t.Errorf("got %q (total %d), want %q (total %d)", got, out.Total, tt.want, tt.total)
+			}
+		})
+	}
+}
+
+func TestSelectEntries_HasMore(t *testing.T) {
+	out, err := selectEntries(testDirInfos(), SSHListDirectoryInput{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.HasMore || len(out.Entries) != 5 {
+		t.Errorf("expected 5 entries with more, got %d (has_more=%v)", len(out.Entries), out.HasMore)
+	}
+	out, _ = selectEntries(testDirInfos(), SSHListDirectoryInput{Limit: 5, Offset: 5})
+	if out.HasMore || len(out.Entries) != 1 {
+		t.Errorf("expected last page of 1 entry, got %d (has_more=%v)", len(out.Entries), out.HasMore)
+	}
+}
+
+func TestSelectEntries_InvalidOptions(t *testing.T) {
+	tests := []SSHListDirectoryInput{
+		{Pattern: "[a-"},
+		{Regex: "("},
+		{Type: "device"},
+		{Sort: "owner"},
+		{Limit: -1},
+		{Offset: -1},
+	}
+	for _, input := range tests {
+		if _, err := selectEntries(nil, input); err == nil {
+			t.Errorf("expected error for %+v", input)
+		}
+	}
+}
+
+func TestSSHListDirectoryOutput_Text(t *testing.T) {
+	out := SSHListDirectoryOutput{
+		Path:    "/var/log",
+		Total:   3,
+		HasMore: true,
+		Entries: []DirEntry{
+			{Name: "nginx", Type: "directory", Mode: "drwxr-xr-x", ModTime: "2026-01-01T02:00:00Z", Size: 4096},
+			{Name: "current", Type: "symlink", Mode: "Lrwxrwxrwx", ModTime: "2026-01-01T00:00:00Z", LinkTarget: "syslog"},
+		},
+	}
+	text := out.Text()
+	for _, want := range []string{"entries 1-2 of 3", "nginx/", "current -> syslog", "use offset=2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	empty := SSHListDirectoryOutput{Path: "/var/log"}
+	if got := empty.Text(); got != "/var/log: no matching entries" {
+		t.Errorf("empty Text() = %q", got)
+	}
+}
+
+func TestSelectEntries_CursorPaging(t *testing.T) {
+	for _, input := range []SSHListDirectoryInput{
+		{},
+		{Sort: "size", Reverse: true},
+		{Sort: "mtime"},
+		{Sort: "none"},
+		{Pattern: "*.log", Sort: "size"},
+	} {
+		full, err := selectEntries(testDirInfos(), input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pages []string
+		page := input
+		page.Limit = 2
+		for i := 0; ; i++ {
+			out, err := selectEntries(testDirInfos(), page)
+			if err != nil {
+				t.Fatalf("%+v page %d: %v", input, i, err)
+			}
+			if out.Offset != 2*i {
+				t.Errorf("%+v page %d: offset %d", input, i, out.Offset)
+			}
+			if got := entryNames(out); got != "" {
+				pages = append(pages, got)
+			}
+			if !out.HasMore {
+				if out.NextCursor != "" {
+					t.Errorf("%+v: cursor on the last page", input)
+				}
+				break
+			}
+			page.Cursor = out.NextCursor
+		}
+		if got := strings.Join(pages, ","); got != entryNames(full) {
+			t.Errorf("%+v: paged %q, want %q", input, got, entryNames(full))
+		}
+	}
+}
+
+func TestSelectEntries_SortNone(t *testing.T) {
+	sel, err := newEntrySelector(SSHListDirectoryInput{Sort: "none", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed := 0
+	for _, fi := range testDirInfos() {
+		fed++
+		if !sel.add(fi) {
+			break
+		}
+	}
+	out := sel.result()
+	if fed != 3 || entryNames(out) != "syslog,auth.log" || !out.HasMore || out.Total != -1 {
+		t.Errorf("fed %d, got %q (has_more=%v, total %d)", fed, entryNames(out), out.HasMore, out.Total)
+	}
+	if text := out.Text(); !strings.Contains(text, "entries 1-2 (total not counted)") || !strings.Contains(text, "use cursor=") {
+		t.Errorf("Text() = %q", text)
+	}
+
+	out, _ = selectEntries(testDirInfos(), SSHListDirectoryInput{Sort: "none", Limit: 2, Count: true})
+	if out.Total != 6 || !out.HasMore {
+		t.Errorf("count: total %d, has_more %v", out.Total, out.HasMore)
+	}
+	// A directory that fits in one page is counted without asking.
+	out, _ = selectEntries(testDirInfos(), SSHListDirectoryInput{Sort: "none"})
+	if out.Total != 6 || out.HasMore {
+		t.Errorf("single page: total %d, has_more %v", out.Total, out.HasMore)
+	}
+}
+
+func TestSelectEntries_InvalidCursor(t *testing.T) {
+	out, err := selectEntries(testDirInfos(), SSHListDirectoryInput{Sort: "size", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []SSHListDirectoryInput{
+		{Cursor: out.NextCursor},                              // issued for sort=size
+		{Sort: "size", Reverse: true, Cursor: out.NextCursor}, // different direction
+		{Cursor: "not a cursor"},
+		{Sort: "none", Reverse: true},
+	} {
+		if _, err := selectEntries(nil, input); err == nil {
+			t.Errorf("expected error for %+v", input)
+		}
+	}
+}
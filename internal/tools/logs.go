@@ -0,0 +1,353 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// LogsDeps holds dependencies for the ssh_logs tool handler.
+type LogsDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+const (
+	defaultLogLimit = 100  // entries per page when limit is unset
+	maxLogLimit     = 1000 // upper bound for limit
+	maxLogMessage   = 4096 // bytes kept of a single message
+)
+
+// Log sources of ssh_logs.
+const (
+	logSourceAuto    = "auto"
+	logSourceJournal = "journal"
+	logSourceFile    = "file"
+)
+
+// defaultLogFiles are tried in order when the host has no journal and no
+// file is given.
+var defaultLogFiles = []string{"/var/log/syslog", "/var/log/messages"}
+
+// journalPriorities are the syslog priority names journalctl uses, by level.
+var journalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+var (
+	// logPriorityPattern matches journalctl -p values: a level or a range.
+	logPriorityPattern = regexp.MustCompile(`^(?:[0-7]|emerg|alert|crit|err|warning|notice|info|debug)(?:\.\.(?:[0-7]|emerg|alert|crit|err|warning|notice|info|debug))?$`)
+	// logUnitPattern matches systemd unit names and unit globs.
+	logUnitPattern = regexp.MustCompile(`^[A-Za-z0-9_@.:\\*?\[\]-]+$`)
+	// syslogLinePattern splits a classic or RFC 3339 syslog line into time,
+	// host, identifier, PID and message.
+	syslogLinePattern = regexp.MustCompile(`^([A-Z][a-z]{2} [ 0-9]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) (\S+) ([^\s\[:]+)(?:\[(\d+)\])?: ?(.*)$`)
+)
+
+// logFileProgram is the awk program for file sources. It keeps the last
+// limit lines before line "before" (0 = end of file) that match pat and,
+// for a unit, carry its syslog identifier; it prints the number of matching
+// lines, then the kept lines as "NR:line".
+const logFileProgram = `(before == 0 || NR < before) && (pat == "" || $0 ~ pat) && (ident == "" || index($0, " " ident "[") || index($0, " " ident ":")) { buf[n++ % limit] = NR ":" $0 }
+END { print n + 0; for (i = (n > limit ? n - limit : 0); i < n; i++) print buf[i % limit] }`
+
+// HandleLogs implements the ssh_logs tool. It reads the systemd journal
+// with journalctl -o json, or a plain log file when the host has no
+// journalctl or a file is asked for, and returns parsed entries oldest
+// first. Pages go back in time: next_cursor, passed as cursor, returns the
+// entries before the oldest one shown.
+func HandleLogs(ctx context.Context, deps *LogsDeps, input SSHLogsInput) (*SSHLogsOutput, error) {
+	limit := input.Limit
+	switch {
+	case limit < 0 || limit > maxLogLimit:
+		return nil, fmt.Errorf("limit must be between 0 and %d", maxLogLimit)
+	case limit == 0:
+		limit = defaultLogLimit
+	}
+	source := input.Source
+	if source == "" {
+		source = logSourceAuto
+	}
+	switch source {
+	case logSourceAuto, logSourceJournal, logSourceFile:
+	default:
+		return nil, fmt.Errorf("invalid source %q (must be auto, journal or file)", input.Source)
+	}
+	if input.File != "" {
+		if source == logSourceJournal {
+			return nil, fmt.Errorf("file cannot be used with source journal")
+		}
+		if err := security.ValidatePath(input.File); err != nil {
+			return nil, fmt.Errorf("invalid file: %w", err)
+		}
+		source = logSourceFile
+	}
+	if input.Unit != "" && (!logUnitPattern.MatchString(input.Unit) || strings.HasPrefix(input.Unit, "-")) {
+		return nil, fmt.Errorf("invalid unit %q", input.Unit)
+	}
+	if input.Priority != "" && !logPriorityPattern.MatchString(input.Priority) {
+		return nil, fmt.Errorf("invalid priority %q (a level 0-7 or name such as err or warning, or a range like warning..err)", input.Priority)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_logs needs a POSIX host")
+	}
+	run := func(what, cmd string) (*remoteResult, error) {
+		return runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+	}
+
+	file := input.File
+	if source == logSourceAuto {
+		// Server-side probe, like host detection; not a user command.
+		res, err := run("log source probe", logSourceProbe())
+		if err != nil {
+			return nil, err
+		}
+		source, file = parseLogSourceProbe(res.Stdout)
+		if source == "" {
+			return nil, fmt.Errorf("no journalctl and none of %s on the host; pass file", strings.Join(defaultLogFiles, ", "))
+		}
+	}
+
+	out := &SSHLogsOutput{Source: source, File: file}
+	if source == logSourceJournal {
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, "journalctl", journalArgs(input, limit)...)
+		if err != nil {
+			return nil, err
+		}
+		res, err := run("journalctl", cmd)
+		if err != nil {
+			return nil, err
+		}
+		// --grep without a match exits 1 with nothing on either stream.
+		if res.TimedOut || (res.ExitCode != 0 && (res.ExitCode != 1 || strings.TrimSpace(res.Stdout+res.Stderr) != "")) {
+			return nil, remoteFailure("journalctl", res)
+		}
+		if out.Entries, out.NextCursor, err = parseJournal(res.Stdout, limit); err != nil {
+			return nil, err
+		}
+	} else {
+		if input.Priority != "" || input.Since != "" || input.Until != "" {
+			return nil, fmt.Errorf("priority, since and until need the journal; %s is a plain log file", file)
+		}
+		args, err := logFileArgs(input, file, limit)
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, "awk", args...)
+		if err != nil {
+			return nil, err
+		}
+		res, err := run("awk", cmd)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return nil, remoteFailure("read "+file, res)
+		}
+		logs := decodeLogs(deps.Config, res.Stdout)
+		if deps.Config.StripANSI {
+			logs = sanitizeOutput(logs)
+		}
+		if out.Entries, out.NextCursor, err = parseLogFile(logs, limit); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// logSourceProbe returns a shell snippet that prints "journal" if
+// journalctl is installed, then the first of defaultLogFiles that exists.
+func logSourceProbe() string {
+	files := make([]string, len(defaultLogFiles))
+	for i, f := range defaultLogFiles {
+		files[i] = shellQuote(f)
+	}
+	return "command -v journalctl >/dev/null 2>&1 && echo journal; for f in " + strings.Join(files, " ") +
+		`; do if [ -f "$f" ]; then echo "$f"; break; fi; done; true`
+}
+
+// parseLogSourceProbe picks the source from logSourceProbe output: the
+// journal if present, otherwise the file it found ("" if neither).
+func parseLogSourceProbe(out string) (source, file string) {
+	for _, line := range strings.Split(out, "\n") {
+		switch line = strings.TrimSpace(line); {
+		case line == "journal":
+			return logSourceJournal, ""
+		case line != "" && file == "":
+			file = line
+		}
+	}
+	if file == "" {
+		return "", ""
+	}
+	return logSourceFile, file
+}
+
+// journalArgs builds the journalctl arguments. Entries are read newest
+// first (-r) and one more than limit is asked for to tell whether there
+// is another page. Values use the --opt=value form so none can be read as
+// an option, including relative times such as -1h.
+func journalArgs(input SSHLogsInput, limit int) []string {
+	args := []string{"--no-pager", "--output=json", "--reverse", "--lines=" + strconv.Itoa(limit+1)}
+	if input.Unit != "" {
+		args = append(args, "--unit="+input.Unit)
+	}
+	if input.Priority != "" {
+		args = append(args, "--priority="+input.Priority)
+	}
+	if input.Since != "" {
+		args = append(args, "--since="+input.Since)
+	}
+	if input.Until != "" {
+		args = append(args, "--until="+input.Until)
+	}
+	if input.Grep != "" {
+		args = append(args, "--grep="+input.Grep)
+	}
+	if input.Cursor != "" {
+		args = append(args, "--after-cursor="+input.Cursor)
+	}
+	return args
+}
+
+// parseJournal reads journalctl -o json --reverse output (at most limit+1
+// entries) into entries oldest first. If there were more than limit, the
+// extra one is dropped and the cursor of the oldest entry kept is returned
+// for the next page.
+func parseJournal(out string, limit int) ([]LogEntry, string, error) {
+	var raw []map[string]json.RawMessage
+	if err := decodeJSONLines(out, &raw); err != nil {
+		return nil, "", fmt.Errorf("parse journalctl output: %w", err)
+	}
+	next := ""
+	if len(raw) > limit {
+		raw = raw[:limit]
+		next = journalString(raw[limit-1]["__CURSOR"])
+	}
+	entries := make([]LogEntry, len(raw))
+	for i, r := range raw {
+		e := LogEntry{
+			Host:       journalString(r["_HOSTNAME"]),
+			Unit:       journalString(r["_SYSTEMD_UNIT"]),
+			Identifier: journalString(r["SYSLOG_IDENTIFIER"]),
+			Message:    truncateLogMessage(journalString(r["MESSAGE"])),
+		}
+		if usec, err := strconv.ParseInt(journalString(r["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+			e.Time = time.UnixMicro(usec).UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+		}
+		if p, err := strconv.Atoi(journalString(r["PRIORITY"])); err == nil && p >= 0 && p < len(journalPriorities) {
+			e.Priority = journalPriorities[p]
+		}
+		e.PID, _ = strconv.Atoi(journalString(r["_PID"]))
+		entries[len(raw)-1-i] = e // reverse to oldest first
+	}
+	return entries, next, nil
+}
+
+// journalString returns a journal JSON field as a string. journalctl
+// writes fields that are not valid UTF-8 as arrays of bytes, and null for
+// ones too large to show.
+func journalString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, v := range ints {
+			b = append(b, byte(v))
+		}
+		return strings.ToValidUTF8(string(b), "�")
+	}
+	return ""
+}
+
+// logFileArgs builds the awk arguments that read a page of file. The
+// cursor is "line:N", the first line of the previous page; a unit is
+// matched by its syslog identifier (nginx for nginx.service).
+func logFileArgs(input SSHLogsInput, file string, limit int) ([]string, error) {
+	before := 0
+	if input.Cursor != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(input.Cursor, "line:"))
+		if err != nil || !strings.HasPrefix(input.Cursor, "line:") || n < 1 {
+			return nil, fmt.Errorf("invalid cursor %q for a log file (expected line:N)", input.Cursor)
+		}
+		before = n
+	}
+	ident := strings.TrimSuffix(input.Unit, ".service")
+	if strings.ContainsAny(ident, `*?[]\`) {
+		return nil, fmt.Errorf("unit globs need the journal; %s is a plain log file", file)
+	}
+	// awk -v processes escapes, so backslashes are doubled to keep them.
+	pat := strings.ReplaceAll(input.Grep, `\`, `\\`)
+	return []string{
+		"-v", "limit=" + strconv.Itoa(limit),
+		"-v", "before=" + strconv.Itoa(before),
+		"-v", "pat=" + pat,
+		"-v", "ident=" + ident,
+		logFileProgram, file,
+	}, nil
+}
+
+// parseLogFile reads logFileProgram output into entries. If more lines
+// matched than were printed, the cursor of the first one is returned for
+// the next page.
+func parseLogFile(out string, limit int) ([]LogEntry, string, error) {
+	out = strings.TrimSuffix(out, "\n")
+	first, rest, _ := strings.Cut(out, "\n")
+	total, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return nil, "", fmt.Errorf("unexpected log file output %q", first)
+	}
+	var entries []LogEntry
+	if rest != "" {
+		for _, line := range strings.Split(rest, "\n") {
+			num, text, ok := strings.Cut(line, ":")
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil {
+				return nil, "", fmt.Errorf("unexpected log file line %q", line)
+			}
+			e := parseSyslogLine(text)
+			e.Line = n
+			entries = append(entries, e)
+		}
+	}
+	next := ""
+	if total > limit && len(entries) > 0 && entries[0].Line > 1 {
+		next = "line:" + strconv.Itoa(entries[0].Line)
+	}
+	return entries, next, nil
+}
+
+// parseSyslogLine splits a syslog line into its fields; lines in another
+// format are returned whole as the message.
+func parseSyslogLine(line string) LogEntry {
+	m := syslogLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return LogEntry{Message: truncateLogMessage(line)}
+	}
+	pid, _ := strconv.Atoi(m[4])
+	return LogEntry{Time: m[1], Host: m[2], Identifier: m[3], PID: pid, Message: truncateLogMessage(m[5])}
+}
+
+// truncateLogMessage caps a message at maxLogMessage bytes.
+func truncateLogMessage(s string) string {
+	if len(s) <= maxLogMessage {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxLogMessage], "") + " [truncated]"
+}
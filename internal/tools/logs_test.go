@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHandleLogs_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHLogsInput
+		want  string
+	}{
+		{SSHLogsInput{Limit: maxLogLimit + 1}, "limit"},
+		{SSHLogsInput{Limit: -1}, "limit"},
+		{SSHLogsInput{Source: "dmesg"}, "invalid source"},
+		{SSHLogsInput{Source: "journal", File: "/var/log/syslog"}, "source journal"},
+		{SSHLogsInput{File: "/var/log/\x00syslog"}, "invalid file"},
+		{SSHLogsInput{Unit: "--all"}, "invalid unit"},
+		{SSHLogsInput{Unit: "ssh; id"}, "invalid unit"},
+		{SSHLogsInput{Priority: "loud"}, "invalid priority"},
+		{SSHLogsInput{Priority: "warning..8"}, "invalid priority"},
+	}
+	for _, tt := range tests {
+		_, err := HandleLogs(context.Background(), &LogsDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestJournalArgs(t *testing.T) {
+	args := journalArgs(SSHLogsInput{Unit: "nginx.service", Priority: "warning..err", Since: "-1h", Grep: "timeout", Cursor: "s=abc;i=1"}, 50)
+	want := []string{"--no-pager", "--output=json", "--reverse", "--lines=51", "--unit=nginx.service",
+		"--priority=warning..err", "--since=-1h", "--grep=timeout", "--after-cursor=s=abc;i=1"}
+	if !slices.Equal(args, want) {
+		t.Errorf("journalArgs = %q, want %q", args, want)
+	}
+}
+
+func TestParseJournal(t *testing.T) {
+	// journalctl --reverse: newest first.
+	out := `{"__CURSOR":"c3","__REALTIME_TIMESTAMP":"1704207845123456","_HOSTNAME":"web1","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx","_PID":"812","PRIORITY":"3","MESSAGE":"upstream timed out"}
+{"__CURSOR":"c2","__REALTIME_TIMESTAMP":"1704207844000000","SYSLOG_IDENTIFIER":"kernel","PRIORITY":"6","MESSAGE":[104,105,255]}
+{"__CURSOR":"c1","__REALTIME_TIMESTAMP":"1704207843000000","MESSAGE":null}
+`
+	entries, next, err := parseJournal(out, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "c2" {
+		t.Errorf("next cursor = %q, want c2", next)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	old, last := entries[0], entries[1]
+	if old.Identifier != "kernel" || old.Message != "hi�" || old.Priority != "info" {
+		t.Errorf("oldest entry = %+v", old)
+	}
+	if last.Time != "2024-01-02T15:04:05.123456Z" || last.Host != "web1" || last.Unit != "nginx.service" ||
+		last.PID != 812 || last.Priority != "err" || last.Message != "upstream timed out" {
+		t.Errorf("newest entry = %+v", last)
+	}
+
+	if entries, next, err = parseJournal(out, 3); err != nil || next != "" || len(entries) != 3 || entries[0].Message != "" {
+		t.Errorf("last page: %d entries, next %q, err %v", len(entries), next, err)
+	}
+	if _, _, err := parseJournal("not json", 10); err == nil {
+		t.Error("expected error for invalid output")
+	}
+}
+
+func TestLogFileArgs(t *testing.T) {
+	args, err := logFileArgs(SSHLogsInput{Unit: "sshd.service", Grep: `fail\w+`, Cursor: "line:40"}, "/var/log/syslog", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"-v", "limit=20", "-v", "before=40", "-v", `pat=fail\\w+`, "-v", "ident=sshd", logFileProgram, "/var/log/syslog"}
+	if !slices.Equal(args, want) {
+		t.Errorf("logFileArgs = %q, want %q", args, want)
+	}
+	for _, cursor := range []string{"s=abc", "line:0", "line:x", "40"} {
+		if _, err := logFileArgs(SSHLogsInput{Cursor: cursor}, "/var/log/syslog", 20); err == nil {
+			t.Errorf("cursor %q: expected error", cursor)
+		}
+	}
+	if _, err := logFileArgs(SSHLogsInput{Unit: "ssh*"}, "/var/log/syslog", 20); err == nil {
+		t.Error("expected error for a unit glob")
+	}
+}
+
+func TestLogFileProgram(t *testing.T) {
+	awk, err := exec.LookPath("awk")
+	if err != nil {
+		t.Skip("awk not installed")
+	}
+	path := filepath.Join(t.TempDir(), "syslog")
+	lines := []string{
+		"Jan  2 15:04:01 web1 sshd[100]: Failed password for root",
+		"Jan  2 15:04:02 web1 cron[7]: job started",
+		"Jan  2 15:04:03 web1 sshd[101]: Accepted publickey for me",
+		"Jan  2 15:04:04 web1 sshd[102]: Failed password for admin",
+		"Jan  2 15:04:05 web1 sshd-keygen: done",
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func(input SSHLogsInput, limit int) ([]LogEntry, string) {
+		t.Helper()
+		args, err := logFileArgs(input, path, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(awk, args...).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, next, err := parseLogFile(string(out), limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries, next
+	}
+
+	entries, next := read(SSHLogsInput{Unit: "sshd.service"}, 2)
+	if len(entries) != 2 || entries[0].Line != 3 || entries[1].Line != 4 || next != "line:3" {
+		t.Fatalf("first page: %+v, next %q", entries, next)
+	}
+	entries, next = read(SSHLogsInput{Unit: "sshd.service", Cursor: next}, 2)
+	if len(entries) != 1 || entries[0].Line != 1 || next != "" {
+		t.Fatalf("second page: %+v, next %q", entries, next)
+	}
+	entries, _ = read(SSHLogsInput{Grep: `F[a-z]+ed\ password`}, 10)
+	if len(entries) != 2 || entries[0].PID != 100 || entries[1].PID != 102 {
+		t.Errorf("grep: %+v", entries)
+	}
+	if entries, next = read(SSHLogsInput{Grep: "nothing"}, 10); len(entries) != 0 || next != "" {
+		t.Errorf("no match: %+v, next %q", entries, next)
+	}
+}
+
+func TestParseSyslogLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want LogEntry
+	}{
+		{"Jan  2 15:04:05 web1 sshd[812]: Accepted publickey", LogEntry{Time: "Jan  2 15:04:05", Host: "web1", Identifier: "sshd", PID: 812, Message: "Accepted publickey"}},
+		{"2024-01-02T15:04:05.123456+00:00 web1 kernel: eth0: link up", LogEntry{Time: "2024-01-02T15:04:05.123456+00:00", Host: "web1", Identifier: "kernel", Message: "eth0: link up"}},
+		{"free-form line", LogEntry{Message: "free-form line"}},
+	}
+	for _, tt := range tests {
+		if got := parseSyslogLine(tt.line); got != tt.want {
+			t.Errorf("parseSyslogLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+	if got := parseSyslogLine(strings.Repeat("x", maxLogMessage+10)).Message; !strings.HasSuffix(got, " [truncated]") {
+		t.Error("long message not truncated")
+	}
+}
+
+func TestParseLogSourceProbe(t *testing.T) {
+	tests := []struct{ out, source, file string }{
+		{"journal\n/var/log/syslog\n", logSourceJournal, ""},
+		{"/var/log/messages\n", logSourceFile, "/var/log/messages"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if source, file := parseLogSourceProbe(tt.out); source != tt.source || file != tt.file {
+			t.Errorf("parseLogSourceProbe(%q) = %q, %q", tt.out, source, file)
+		}
+	}
+}
+
+func TestSSHLogsOutput_Text(t *testing.T) {
+	out := SSHLogsOutput{
+		Source: logSourceJournal,
+		Entries: []LogEntry{
+			{Time: "2024-01-02T15:04:05.000000Z", Identifier: "nginx", PID: 812, Priority: "err", Message: "upstream timed out"},
+			{Priority: "info", Message: "bare"},
+		},
+		NextCursor: "s=abc",
+	}
+	want := "journal: 2 entries, oldest first\n" +
+		"2024-01-02T15:04:05.000000Z nginx[812] <err>: upstream timed out\n" +
+		"<info> bare\n" +
+		`Older entries: call again with cursor "s=abc"`
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+	empty := SSHLogsOutput{Source: logSourceFile, File: "/var/log/syslog"}
+	if got := empty.Text(); got != "/var/log/syslog: no matching entries" {
+		t.Errorf("empty Text() = %q", got)
+	}
+}
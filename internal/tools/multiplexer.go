@@ -0,0 +1,470 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// MultiplexerDeps holds dependencies for the ssh_multiplexer tool handler.
+type MultiplexerDeps struct {
+	Pool          *connection.Pool
+	Filter        *security.Filter
+	RateLimiter   *security.RateLimiter
+	Config        *config.SSHConfig
+	MaxOutputSize int
+}
+
+// Actions of ssh_multiplexer.
+const (
+	muxActionList     = "list"
+	muxActionCreate   = "create"
+	muxActionSendKeys = "send-keys"
+	muxActionCapture  = "capture"
+	muxActionKill     = "kill"
+)
+
+// Programs of ssh_multiplexer.
+const (
+	muxTmux   = "tmux"
+	muxScreen = "screen"
+)
+
+const (
+	defaultMuxWaitMs = 500   // send-keys: wait before capturing the screen
+	maxMuxWaitMs     = 30000 // wait_ms limit
+	maxMuxHistory    = 10000 // history_lines limit
+)
+
+// muxNamePattern matches session names. tmux rewrites '.' and ':' in
+// names and screen prefixes them with "PID.", so both are left out.
+var muxNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]{0,63}$`)
+
+// tmuxKeys maps the special_key names of ssh_send_input to tmux key names.
+var tmuxKeys = map[string]string{
+	"CTRL_C":      "C-c",
+	"CTRL_D":      "C-d",
+	"CTRL_Z":      "C-z",
+	"ESC":         "Escape",
+	"TAB":         "Tab",
+	"BACKSPACE":   "BSpace",
+	"ENTER":       "Enter",
+	"ARROW_UP":    "Up",
+	"ARROW_DOWN":  "Down",
+	"ARROW_RIGHT": "Right",
+	"ARROW_LEFT":  "Left",
+}
+
+// tmuxListFormat is the list-sessions format parsed by parseTmuxSessions.
+const tmuxListFormat = "#{session_name}\t#{session_windows}\t#{session_attached}\t#{session_created}\t#{pane_current_command}"
+
+// HandleMultiplexer implements the ssh_multiplexer tool. It drives tmux or
+// screen on the remote host: the sessions it creates run on the host, so
+// they outlive this server and its SSH connections, and a person can
+// attach to them (tmux attach -t NAME, screen -r NAME). send-keys types
+// into the session's active pane or window and returns the screen after
+// wait_ms; capture returns the screen without typing. Command lines go
+// through the command filter, and so do the command of create and each
+// line of text of send-keys.
+func HandleMultiplexer(ctx context.Context, deps *MultiplexerDeps, input SSHMultiplexerInput) (*SSHMultiplexerOutput, error) {
+	if err := validateMultiplexerInput(input); err != nil {
+		return nil, err
+	}
+	program := muxProgram(input.Program)
+	if input.Command != "" {
+		if err := deps.Filter.AllowCommand(input.Command); err != nil {
+			return nil, err
+		}
+	}
+	// Each typed line is a command line for whatever runs in the pane.
+	text := escapeReplacer.Replace(input.Text)
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if err := deps.Filter.AllowCommand(line); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_multiplexer needs a POSIX host")
+	}
+	run := func(what, cmd string) (*remoteResult, error) {
+		res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return res, remoteFailure(what, res)
+		}
+		return res, nil
+	}
+	cli := func(args ...string) (string, error) {
+		return buildCLICommand(deps.Filter, deps.Config, false, program, args...)
+	}
+	list := func() ([]MultiplexerSession, error) {
+		var cmd string
+		var err error
+		if program == muxTmux {
+			cmd, err = cli("list-sessions", "-F", tmuxListFormat)
+		} else {
+			cmd, err = cli("-ls")
+		}
+		if err != nil {
+			return nil, err
+		}
+		res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if program == muxTmux {
+			if res.ExitCode != 0 && !res.TimedOut && tmuxNoServer(res.Stderr) {
+				return nil, nil
+			}
+			if res.TimedOut || res.ExitCode != 0 {
+				return nil, remoteFailure("tmux list-sessions", res)
+			}
+			return parseTmuxSessions(decodeLogs(deps.Config, res.Stdout)), nil
+		}
+		// screen -ls exits 1 when there are no sessions, and some versions
+		// whenever there are only detached ones.
+		if res.TimedOut || res.ExitCode > 1 {
+			return nil, remoteFailure("screen -ls", res)
+		}
+		return parseScreenSessions(decodeLogs(deps.Config, res.Stdout)), nil
+	}
+	capture := func() (string, error) {
+		var res *remoteResult
+		if program == muxTmux {
+			args := []string{"capture-pane", "-p", "-J", "-t", "=" + input.Name + ":"}
+			if input.HistoryLines > 0 {
+				args = append(args, "-S", strconv.Itoa(-input.HistoryLines))
+			}
+			cmd, err := cli(args...)
+			if err != nil {
+				return "", err
+			}
+			if res, err = run("tmux capture-pane", cmd); err != nil {
+				return "", err
+			}
+		} else {
+			cmd, err := cli("-S", input.Name, "-p", "0", "-X", "hardcopy")
+			if err != nil {
+				return "", err
+			}
+			if res, err = run("screen hardcopy", screenCaptureScript(cmd, input.HistoryLines)); err != nil {
+				return "", err
+			}
+		}
+		return TruncateOutput(trimTrailingBlankLines(decodeLogs(deps.Config, res.Stdout)), deps.MaxOutputSize), nil
+	}
+
+	out := &SSHMultiplexerOutput{Action: input.Action, Program: program, Name: input.Name}
+	switch input.Action {
+	case muxActionList:
+		if out.Sessions, err = list(); err != nil {
+			return nil, err
+		}
+		out.Message = fmt.Sprintf("%d %s session(s)", len(out.Sessions), program)
+		return out, nil
+
+	case muxActionCreate:
+		var cmd string
+		if program == muxTmux {
+			args := []string{"new-session", "-d", "-s", input.Name}
+			if input.Dir != "" {
+				args = append(args, "-c", gitDir(input.Dir))
+			}
+			if input.Command != "" {
+				args = append(args, input.Command)
+			}
+			cmd, err = cli(args...)
+		} else {
+			// screen allows duplicate names, which -S can't tell apart.
+			sessions, lerr := list()
+			if lerr != nil {
+				return nil, lerr
+			}
+			if slices.ContainsFunc(sessions, func(s MultiplexerSession) bool { return s.Name == input.Name }) {
+				return nil, fmt.Errorf("screen session %q already exists", input.Name)
+			}
+			args := []string{"-dmS", input.Name}
+			if input.Command != "" {
+				args = append(args, "sh", "-c", input.Command)
+			}
+			cmd, err = cli(args...)
+			if err == nil && input.Dir != "" {
+				cmd = "cd " + shellQuote(gitDir(input.Dir)) + " && " + cmd
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := run(program+" "+input.Action, cmd); err != nil {
+			conn.SetLastError(err)
+			return nil, err
+		}
+		attach := "tmux attach -t " + input.Name
+		if program == muxScreen {
+			attach = "screen -r " + input.Name
+		}
+		out.Message = fmt.Sprintf("Created %s session %s (attach with: %s)", program, input.Name, attach)
+		return out, nil
+
+	case muxActionSendKeys:
+		for _, argv := range muxSendCommands(program, input.Name, text, input.SpecialKey, input.Enter) {
+			cmd, err := cli(argv...)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := run(program+" "+input.Action, cmd); err != nil {
+				conn.SetLastError(err)
+				return nil, err
+			}
+		}
+		wait := input.WaitMs
+		if wait == 0 {
+			wait = defaultMuxWaitMs
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(wait) * time.Millisecond):
+		}
+		if out.Screen, err = capture(); err != nil {
+			return nil, err
+		}
+		out.Message = fmt.Sprintf("Sent keys to %s session %s", program, input.Name)
+		return out, nil
+
+	case muxActionCapture:
+		if out.Screen, err = capture(); err != nil {
+			return nil, err
+		}
+		out.Message = fmt.Sprintf("Screen of %s session %s", program, input.Name)
+		return out, nil
+
+	default: // muxActionKill
+		var cmd string
+		if program == muxTmux {
+			cmd, err = cli("kill-session", "-t", "="+input.Name)
+		} else {
+			cmd, err = cli("-S", input.Name, "-X", "quit")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := run(program+" "+input.Action, cmd); err != nil {
+			conn.SetLastError(err)
+			return nil, err
+		}
+		out.Message = fmt.Sprintf("Killed %s session %s", program, input.Name)
+		return out, nil
+	}
+}
+
+// validateMultiplexerInput checks the action and which fields it takes.
+func validateMultiplexerInput(input SSHMultiplexerInput) error {
+	switch input.Action {
+	case muxActionList, muxActionCreate, muxActionSendKeys, muxActionCapture, muxActionKill:
+	default:
+		return fmt.Errorf("invalid action %q (must be list, create, send-keys, capture or kill)", input.Action)
+	}
+	if input.Program != "" && input.Program != muxTmux && input.Program != muxScreen {
+		return fmt.Errorf("invalid program %q (must be tmux or screen)", input.Program)
+	}
+	if input.Action == muxActionList {
+		if input.Name != "" {
+			return fmt.Errorf("name does not apply to list")
+		}
+	} else {
+		if input.Name == "" {
+			return fmt.Errorf("name is required for %s", input.Action)
+		}
+		if !muxNamePattern.MatchString(input.Name) {
+			return fmt.Errorf("invalid session name %q (letters, digits, '_' and '-', at most 64)", input.Name)
+		}
+	}
+	if input.Action != muxActionCreate && (input.Command != "" || input.Dir != "") {
+		return fmt.Errorf("command and dir only apply to create")
+	}
+	if input.Dir != "" {
+		if err := security.ValidatePath(input.Dir); err != nil {
+			return fmt.Errorf("invalid dir: %w", err)
+		}
+	}
+	if input.Action != muxActionSendKeys && (input.Text != "" || input.SpecialKey != "" || input.Enter || input.WaitMs != 0) {
+		return fmt.Errorf("text, special_key, enter and wait_ms only apply to send-keys")
+	}
+	if input.Action == muxActionSendKeys {
+		if input.Text != "" && input.SpecialKey != "" {
+			return fmt.Errorf("only one of text or special_key can be provided, not both")
+		}
+		if input.Text == "" && input.SpecialKey == "" && !input.Enter {
+			return fmt.Errorf("send-keys needs text, special_key or enter")
+		}
+		if _, ok := tmuxKeys[input.SpecialKey]; input.SpecialKey != "" && !ok {
+			return fmt.Errorf("unknown special key %q; valid keys: CTRL_C, CTRL_D, CTRL_Z, ESC, TAB, BACKSPACE, ENTER, ARROW_UP, ARROW_DOWN, ARROW_LEFT, ARROW_RIGHT", input.SpecialKey)
+		}
+		if input.WaitMs < 0 || input.WaitMs > maxMuxWaitMs {
+			return fmt.Errorf("wait_ms must be between 0 and %d", maxMuxWaitMs)
+		}
+	}
+	if input.HistoryLines != 0 && input.Action != muxActionCapture && input.Action != muxActionSendKeys {
+		return fmt.Errorf("history_lines only applies to capture and send-keys")
+	}
+	if input.HistoryLines < 0 || input.HistoryLines > maxMuxHistory {
+		return fmt.Errorf("history_lines must be between 0 and %d", maxMuxHistory)
+	}
+	return nil
+}
+
+// muxProgram returns program, or tmux if it is unset.
+func muxProgram(program string) string {
+	if program == "" {
+		return muxTmux
+	}
+	return program
+}
+
+// muxSendCommands returns the argument lists that type text or a special
+// key, then Enter if enter is set. tmux gets text with -l so key names in
+// it are not looked up; screen's stuff command gets it escaped by
+// screenStuff.
+func muxSendCommands(program, name, text, specialKey string, enter bool) [][]string {
+	var cmds [][]string
+	if program == muxTmux {
+		target := "=" + name + ":"
+		if text != "" {
+			cmds = append(cmds, []string{"send-keys", "-t", target, "-l", text})
+		}
+		if specialKey != "" {
+			cmds = append(cmds, []string{"send-keys", "-t", target, tmuxKeys[specialKey]})
+		}
+		if enter {
+			cmds = append(cmds, []string{"send-keys", "-t", target, "Enter"})
+		}
+		return cmds
+	}
+	keys := []byte(text)
+	if specialKey != "" {
+		keys = specialKeys[specialKey]
+	}
+	if enter {
+		keys = append(slices.Clip(keys), '\r')
+	}
+	return [][]string{{"-S", name, "-p", "0", "-X", "stuff", screenStuff(keys)}}
+}
+
+// screenStuff escapes keys for screen's stuff command, whose argument is
+// parsed like a .screenrc line: backslash, caret, '$' and quotes are
+// escaped and control bytes are written as octal escapes.
+func screenStuff(keys []byte) string {
+	var b strings.Builder
+	for _, c := range keys {
+		switch {
+		case c == '\\' || c == '^' || c == '$' || c == '\'' || c == '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// screenCaptureScript returns a script that runs cmd, a screen hardcopy
+// command without its file argument, into a temporary file and prints it.
+// screen -X returns before the session writes the file, so the script
+// waits up to two seconds for it. With history, the scrollback is written
+// too and the output keeps the last history lines above the screen.
+func screenCaptureScript(cmd string, history int) string {
+	wait := func(f string) string {
+		return fmt.Sprintf(`i=0; while [ ! -s "%s" ] && [ $i -lt 20 ]; do sleep 0.1; i=$((i+1)); done; `, f)
+	}
+	var b strings.Builder
+	b.WriteString(`f=$(mktemp) && g=$(mktemp) || exit 1; trap 'rm -f "$f" "$g"' EXIT; `)
+	if history == 0 {
+		fmt.Fprintf(&b, `%s "$f" || exit 1; %scat "$f"`, cmd, wait("$f"))
+		return b.String()
+	}
+	fmt.Fprintf(&b, `%s "$g" || exit 1; %s`, cmd, wait("$g"))
+	fmt.Fprintf(&b, `%s -h "$f" || exit 1; %s`, cmd, wait("$f"))
+	fmt.Fprintf(&b, `tail -n $((%d + $(wc -l < "$g"))) "$f"`, history)
+	return b.String()
+}
+
+// tmuxNoServer reports whether tmux failed because no server is running,
+// i.e. there are no sessions.
+func tmuxNoServer(stderr string) bool {
+	return strings.Contains(stderr, "no server running") || strings.Contains(stderr, "error connecting to")
+}
+
+// parseTmuxSessions reads list-sessions output in tmuxListFormat.
+func parseTmuxSessions(out string) []MultiplexerSession {
+	var sessions []MultiplexerSession
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 5 {
+			continue
+		}
+		s := MultiplexerSession{Name: f[0], Command: f[4]}
+		s.Windows, _ = strconv.Atoi(f[1])
+		s.Clients, _ = strconv.Atoi(f[2])
+		s.Attached = s.Clients > 0
+		if sec, err := strconv.ParseInt(f[3], 10, 64); err == nil {
+			s.Created = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// parseScreenSessions reads screen -ls output, whose session lines are
+// "<TAB>PID.NAME<TAB>(DATE)<TAB>(STATE)" with the date missing on some
+// versions.
+func parseScreenSessions(out string) []MultiplexerSession {
+	var sessions []MultiplexerSession
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "\t") {
+			continue
+		}
+		f := strings.Split(strings.TrimSpace(line), "\t")
+		pid, name, ok := strings.Cut(f[0], ".")
+		if !ok || len(f) < 2 {
+			continue
+		}
+		s := MultiplexerSession{Name: name}
+		s.PID, _ = strconv.Atoi(pid)
+		state := strings.ToLower(strings.Trim(f[len(f)-1], "()"))
+		s.Attached = strings.Contains(state, "attached") // also "multi, attached"
+		if len(f) > 2 {
+			s.Created = strings.Trim(f[1], "()")
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// trimTrailingBlankLines drops the empty rows below the last line of text
+// on a captured screen.
+func trimTrailingBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	last := len(lines)
+	for last > 0 && strings.TrimSpace(lines[last-1]) == "" {
+		last--
+	}
+	return strings.Join(lines[:last], "\n")
+}
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func TestHandleMultiplexer_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHMultiplexerInput
+		want  string
+	}{
+		{SSHMultiplexerInput{Action: "attach", Name: "w"}, "invalid action"},
+		{SSHMultiplexerInput{Action: "list", Program: "zellij"}, "invalid program"},
+		{SSHMultiplexerInput{Action: "list", Name: "w"}, "name does not apply"},
+		{SSHMultiplexerInput{Action: "capture"}, "name is required"},
+		{SSHMultiplexerInput{Action: "kill", Name: "-t"}, "invalid session name"},
+		{SSHMultiplexerInput{Action: "kill", Name: "a.b"}, "invalid session name"},
+		{SSHMultiplexerInput{Action: "capture", Name: "w", Command: "top"}, "only apply to create"},
+		{SSHMultiplexerInput{Action: "create", Name: "w", Dir: "../etc"}, "invalid dir"},
+		{SSHMultiplexerInput{Action: "capture", Name: "w", Text: "ls"}, "only apply to send-keys"},
+		{SSHMultiplexerInput{Action: "send-keys", Name: "w"}, "needs text, special_key or enter"},
+		{SSHMultiplexerInput{Action: "send-keys", Name: "w", Text: "q", SpecialKey: "ESC"}, "not both"},
+		{SSHMultiplexerInput{Action: "send-keys", Name: "w", SpecialKey: "F1"}, "unknown special key"},
+		{SSHMultiplexerInput{Action: "send-keys", Name: "w", Enter: true, WaitMs: -1}, "wait_ms must be"},
+		{SSHMultiplexerInput{Action: "kill", Name: "w", HistoryLines: 10}, "history_lines only applies"},
+		{SSHMultiplexerInput{Action: "capture", Name: "w", HistoryLines: maxMuxHistory + 1}, "history_lines must be"},
+	}
+	for _, tt := range tests {
+		_, err := HandleMultiplexer(context.Background(), &MultiplexerDeps{Config: &config.SSHConfig{}}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestHandleMultiplexer_Filter(t *testing.T) {
+	filter, err := security.NewFilter(nil, nil, nil, []string{`rm\s+-rf.*`})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	deps := &MultiplexerDeps{Filter: filter, Config: &config.SSHConfig{}}
+	for _, input := range []SSHMultiplexerInput{
+		{Action: "create", Name: "w", Command: "rm -rf /tmp/x"},
+		{Action: "send-keys", Name: "w", Text: `ls\nrm -rf /\n`},
+	} {
+		if _, err := HandleMultiplexer(context.Background(), deps, input); err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Errorf("%s: err = %v, want a filter denial", input.Action, err)
+		}
+	}
+}
+
+func TestMuxSendCommands(t *testing.T) {
+	tests := []struct {
+		program, text, key string
+		enter              bool
+		want               [][]string
+	}{
+		{"tmux", "ls -l", "", true, [][]string{
+			{"send-keys", "-t", "=w:", "-l", "ls -l"},
+			{"send-keys", "-t", "=w:", "Enter"},
+		}},
+		{"tmux", "", "CTRL_C", false, [][]string{{"send-keys", "-t", "=w:", "C-c"}}},
+		{"screen", "echo $HOME", "", true, [][]string{{"-S", "w", "-p", "0", "-X", "stuff", `echo \$HOME\015`}}},
+		{"screen", "", "ARROW_UP", false, [][]string{{"-S", "w", "-p", "0", "-X", "stuff", `\033[A`}}},
+	}
+	for _, tt := range tests {
+		if got := muxSendCommands(tt.program, "w", tt.text, tt.key, tt.enter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q %q: %q, want %q", tt.program, tt.text, tt.key, got, tt.want)
+		}
+	}
+	// Enter must not write into the specialKeys table.
+	muxSendCommands("screen", "w", "", "ESC", true)
+	if got := specialKeys["ESC"]; len(got) != 1 {
+		t.Errorf("specialKeys[ESC] = %q after send", got)
+	}
+}
+
+func TestScreenStuff(t *testing.T) {
+	if got, want := screenStuff([]byte("a\\b^c'd\"e\t")), `a\\b\^c\'d\"e\011`; got != want {
+		t.Errorf("screenStuff = %q, want %q", got, want)
+	}
+}
+
+func TestScreenCaptureScript(t *testing.T) {
+	got := screenCaptureScript("screen 'hardcopy'", 0)
+	if !strings.Contains(got, `screen 'hardcopy' "$f"`) || !strings.HasSuffix(got, `cat "$f"`) {
+		t.Errorf("script = %q", got)
+	}
+	got = screenCaptureScript("screen 'hardcopy'", 50)
+	for _, want := range []string{`screen 'hardcopy' "$g"`, `screen 'hardcopy' -h "$f"`, `tail -n $((50 + $(wc -l < "$g"))) "$f"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("script %q lacks %q", got, want)
+		}
+	}
+}
+
+func TestParseTmuxSessions(t *testing.T) {
+	out := "build\t2\t1\t1700000000\tmake\nscratch\t1\t0\t1700000100\tbash\nbad line\n"
+	got := parseTmuxSessions(out)
+	want := []MultiplexerSession{
+		{Name: "build", Windows: 2, Attached: true, Clients: 1, Created: "2023-11-14T22:13:20Z", Command: "make"},
+		{Name: "scratch", Windows: 1, Created: "2023-11-14T22:15:00Z", Command: "bash"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTmuxSessions = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseScreenSessions(t *testing.T) {
+	out := "There are screens on:\n" +
+		"\t4242.build\t(11/14/2023 10:13:20 PM)\t(Detached)\n" +
+		"\t4300.shared\t(Multi, attached)\n" +
+		"2 Sockets in /run/screen/S-deploy.\n"
+	got := parseScreenSessions(out)
+	want := []MultiplexerSession{
+		{Name: "build", PID: 4242, Created: "11/14/2023 10:13:20 PM"},
+		{Name: "shared", PID: 4300, Attached: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseScreenSessions = %+v, want %+v", got, want)
+	}
+	if got := parseScreenSessions("No Sockets found in /run/screen/S-deploy.\n"); got != nil {
+		t.Errorf("no sockets: %+v", got)
+	}
+}
+
+func TestSSHMultiplexerOutput_Text(t *testing.T) {
+	out := SSHMultiplexerOutput{
+		Message: "2 tmux session(s)",
+		Sessions: []MultiplexerSession{
+			{Name: "build", Windows: 2, Attached: true, Clients: 1, Created: "2023-11-14T22:13:20Z", Command: "make"},
+			{Name: "scratch", Windows: 1},
+		},
+	}
+	want := "2 tmux session(s)\n" +
+		"  build: 2 window(s), attached (1 client(s)), created 2023-11-14T22:13:20Z, running make\n" +
+		"  scratch: 1 window(s), detached"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	out = SSHMultiplexerOutput{Message: "Screen of tmux session w", Screen: "$ uptime\n up 3 days"}
+	if got := out.Text(); got != "Screen of tmux session w\n$ uptime\n up 3 days" {
+		t.Errorf("Text() = %q", got)
+	}
+	if got := trimTrailingBlankLines("a\n\nb\n  \n\n"); got != "a\n\nb" {
+		t.Errorf("trimTrailingBlankLines = %q", got)
+	}
+}
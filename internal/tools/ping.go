@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+const (
+	defaultPingTimeout = 10 * time.Second
+	// pingCommand is the exec probe; it is valid in POSIX shells, cmd.exe and
+	// PowerShell alike.
+	pingCommand = "exit 0"
+)
+
+// PingDeps holds dependencies for the ssh_ping tool handler.
+type PingDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+}
+
+// HandlePing implements the ssh_ping tool. It gets the session (reconnecting
+// it if it was dead), times a keepalive request, then times a trivial exec
+// in a new session. The probe is not counted in the session's command
+// statistics. A failed probe is reported as unhealthy, not as an error.
+func HandlePing(ctx context.Context, deps *PingDeps, input SSHPingInput) (*SSHPingOutput, error) {
+	if input.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must be non-negative")
+	}
+	timeout := defaultPingTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+
+	conn, reconnected, err := deps.Pool.GetConnectionStatus(ctx, connection.SessionID(input.SessionID))
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+	if err := deps.RateLimiter.Wait(ctx, conn.Host); err != nil {
+		return nil, err
+	}
+	client, err := conn.GetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SSHPingOutput{
+		SessionID:     input.SessionID,
+		Reconnected:   reconnected,
+		ServerVersion: string(client.ServerVersion()),
+	}
+
+	// Servers answer unknown global requests with a failure reply, which
+	// still proves the transport is alive.
+	rtt, err := timed(ctx, timeout, func() error {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		return err
+	})
+	out.KeepaliveMs = millis(rtt)
+	if err != nil {
+		out.Error = fmt.Sprintf("keepalive: %v", err)
+		conn.SetLastError(err)
+		return out, nil
+	}
+
+	if err := deps.Filter.AllowCommand(pingCommand); err != nil {
+		out.ExecSkipped = "not allowed by the command filter"
+		out.Healthy = true
+		return out, nil
+	}
+	rtt, err = timed(ctx, timeout, func() error { return runProbe(client) })
+	out.ExecMs = millis(rtt)
+	if err != nil {
+		out.Error = fmt.Sprintf("exec probe: %v", err)
+		conn.SetLastError(err)
+		return out, nil
+	}
+	out.Healthy = true
+	return out, nil
+}
+
+// runProbe runs pingCommand in a new session.
+func runProbe(client *ssh.Client) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	defer session.Close()
+	return session.Run(pingCommand)
+}
+
+// timed runs f and returns how long it took. If f does not finish within
+// timeout (or ctx ends first) it is abandoned and an error returned.
+func timed(ctx context.Context, timeout time.Duration, f func() error) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	select {
+	case err := <-done:
+		return time.Since(start), err
+	case <-ctx.Done():
+		return time.Since(start), fmt.Errorf("no response after %s: %w", timeout, ctx.Err())
+	}
+}
+
+// millis converts d to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
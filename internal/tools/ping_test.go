@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+func TestTimed(t *testing.T) {
+	d, err := timed(context.Background(), time.Second, func() error { return nil })
+	if err != nil || d <= 0 {
+		t.Errorf("timed = %v, %v", d, err)
+	}
+
+	probeErr := errors.New("boom")
+	if _, err := timed(context.Background(), time.Second, func() error { return probeErr }); !errors.Is(err, probeErr) {
+		t.Errorf("timed error = %v, want %v", err, probeErr)
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	_, err = timed(context.Background(), 20*time.Millisecond, func() error { <-block; return nil })
+	if err == nil || !strings.Contains(err.Error(), "no response after") {
+		t.Errorf("timed on a hung probe = %v", err)
+	}
+}
+
+func TestMillis(t *testing.T) {
+	if got := millis(1500 * time.Microsecond); got != 1.5 {
+		t.Errorf("millis = %v, want 1.5", got)
+	}
+}
+
+func TestHandlePing_Validation(t *testing.T) {
+	cfg := &config.SSHConfig{ConfigPath: "/nonexistent/ssh/config"}
+	deps := &PingDeps{Pool: connection.NewPool(cfg, connection.NewAuthDiscovery(cfg))}
+
+	if _, err := HandlePing(context.Background(), deps, SSHPingInput{SessionID: "a@b:22", Timeout: -1}); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+	_, err := HandlePing(context.Background(), deps, SSHPingInput{SessionID: "nobody@nowhere:22"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected session not found, got %v", err)
+	}
+}
+
+func TestSSHPingOutput_Text(t *testing.T) {
+	out := SSHPingOutput{
+		SessionID:     "root@web:22",
+		Healthy:       true,
+		Reconnected:   true,
+		ServerVersion: "SSH-2.0-OpenSSH_9.6",
+		KeepaliveMs:   1.25,
+		ExecMs:        12.5,
+	}
+	text := out.Text()
+	for _, want := range []string{"root@web:22: healthy", "keepalive 1.2ms", "exec 12.5ms", "OpenSSH_9.6", "re-established"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	out = SSHPingOutput{SessionID: "root@web:22", ExecSkipped: "not allowed by the command filter", Error: "keepalive: EOF"}
+	text = out.Text()
+	for _, want := range []string{"UNHEALTHY", "exec probe skipped", "error: keepalive: EOF"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// maxPlanSteps bounds the steps of one ssh_plan_execute call.
+const maxPlanSteps = 50
+
+// planRollbackTimeout bounds a rollback, which runs even after the call is
+// cancelled.
+const planRollbackTimeout = 2 * time.Minute
+
+// Step failure policies.
+const (
+	planAbort    = "abort"    // stop and roll back completed edits (default)
+	planStop     = "stop"     // stop, keep what was done
+	planContinue = "continue" // record the failure and run the next step
+)
+
+// Step statuses reported in PlanStepResult.
+const (
+	planOK      = "ok"
+	planFailed  = "failed"
+	planSkipped = "skipped"
+)
+
+// PlanDeps holds dependencies for the ssh_plan_execute tool handler. A nil
+// field means that tool is disabled, and plans using it are rejected.
+type PlanDeps struct {
+	Edit    *FileEditDeps
+	Upload  *UploadDeps
+	Execute *ExecuteDeps
+}
+
+// HandlePlanExecute implements the ssh_plan_execute tool. Steps run in
+// order through the same handlers as ssh_edit_file, ssh_upload and
+// ssh_execute. Before a file is first edited its content is saved, under
+// the same edit lock as the edit, so when a step with the abort policy fails
+// every file the plan edited is put back as it was before the plan (or
+// removed, if the plan created it). Changes made to those files by others
+// since are overwritten too. Uploads and commands are not undone.
+func HandlePlanExecute(ctx context.Context, deps *PlanDeps, input SSHPlanExecuteInput) (*SSHPlanExecuteOutput, error) {
+	if len(input.Steps) == 0 {
+		return nil, fmt.Errorf("steps is required")
+	}
+	if len(input.Steps) > maxPlanSteps {
+		return nil, fmt.Errorf("too many steps: %d (max %d)", len(input.Steps), maxPlanSteps)
+	}
+	// Validate the whole plan before running any of it.
+	for i, st := range input.Steps {
+		if err := deps.checkStep(st); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	p := &planRun{deps: deps}
+	out := &SSHPlanExecuteOutput{Succeeded: true}
+	failedAt := -1
+	for i, st := range input.Steps {
+		res := PlanStepResult{Step: i + 1, Name: st.Name, Kind: st.kind()}
+		if failedAt >= 0 {
+			res.Status = planSkipped
+			out.Steps = append(out.Steps, res)
+			continue
+		}
+		msg, err := p.run(ctx, st)
+		res.Message = msg
+		if err == nil {
+			res.Status = planOK
+			out.Steps = append(out.Steps, res)
+			continue
+		}
+		res.Status, res.Error = planFailed, err.Error()
+		out.Steps = append(out.Steps, res)
+		out.Succeeded = false
+		switch st.OnFailure {
+		case planContinue:
+		case planStop:
+			failedAt = i
+		default:
+			failedAt = i
+			// Put files back even if the client gave up on the call.
+			rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), planRollbackTimeout)
+			out.RolledBack, out.RollbackErrors = p.rollback(rctx)
+			cancel()
+			out.RollbackAttempted = true
+		}
+	}
+	return out, nil
+}
+
+// checkStep validates a step without running it.
+func (deps *PlanDeps) checkStep(st PlanStep) error {
+	n := 0
+	for _, set := range []bool{st.Edit != nil, st.Upload != nil, st.Execute != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("set exactly one of edit, upload or execute")
+	}
+	switch st.OnFailure {
+	case "", planAbort, planStop, planContinue:
+	default:
+		return fmt.Errorf("invalid on_failure %q (must be %s, %s or %s)", st.OnFailure, planAbort, planStop, planContinue)
+	}
+	switch {
+	case st.Edit != nil && deps.Edit == nil:
+		return fmt.Errorf("ssh_edit_file is disabled on this server")
+	case st.Edit != nil:
+		if err := security.ValidatePath(st.Edit.RemotePath); err != nil {
+			return fmt.Errorf("invalid remote path: %w", err)
+		}
+	case st.Upload != nil && deps.Upload == nil:
+		return fmt.Errorf("ssh_upload is disabled on this server")
+	case st.Execute != nil && deps.Execute == nil:
+		return fmt.Errorf("ssh_execute is disabled on this server")
+	}
+	return nil
+}
+
+// kind names the tool a step runs.
+func (st PlanStep) kind() string {
+	switch {
+	case st.Edit != nil:
+		return "edit"
+	case st.Upload != nil:
+		return "upload"
+	}
+	return "execute"
+}
+
+// planRun carries the file snapshots of one plan.
+type planRun struct {
+	deps      *PlanDeps
+	snapshots []*fileSnapshot // in the order first edited
+	seen      map[string]bool
+}
+
+// fileSnapshot is a file's content before the plan first edited it.
+type fileSnapshot struct {
+	sessionID string
+	path      string // expanded
+	existed   bool
+	data      []byte
+	perms     os.FileMode
+}
+
+func (p *planRun) run(ctx context.Context, st PlanStep) (string, error) {
+	switch {
+	case st.Edit != nil:
+		out, err := editFile(ctx, p.deps.Edit, *st.Edit, func(sc *sftp.Client, remotePath string) error {
+			if err := p.snapshot(ctx, sc, st.Edit.SessionID, remotePath); err != nil {
+				return fmt.Errorf("save %s for rollback: %w", remotePath, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return out.Message, nil
+	case st.Upload != nil:
+		out, err := HandleUpload(ctx, p.deps.Upload, *st.Upload)
+		if err != nil {
+			return "", err
+		}
+		if len(out.Mismatches) > 0 {
+			return out.Message, fmt.Errorf("verification failed for %d file(s)", len(out.Mismatches))
+		}
+		return out.Message, nil
+	}
+	out, err := HandleExecute(ctx, p.deps.Execute, *st.Execute)
+	if err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("exit code %d", out.ExitCode)
+	switch {
+	case out.TimedOut:
+		return msg, fmt.Errorf("command timed out")
+	case out.Cancelled:
+		return msg, fmt.Errorf("command cancelled")
+	case out.ExitCode != 0:
+		return msg, fmt.Errorf("command exited with code %d: %s", out.ExitCode, TruncateOutput(strings.TrimSpace(out.Stderr), 500))
+	}
+	return msg, nil
+}
+
+// snapshot saves remotePath (already expanded) the first time the plan
+// edits it. It runs while the edit holds the file's lock, so no other edit
+// through this server can land between the snapshot and the plan's edit.
+func (p *planRun) snapshot(ctx context.Context, sc *sftp.Client, sessionID, remotePath string) error {
+	s := &fileSnapshot{sessionID: sessionID, path: remotePath}
+	key := sessionID + "\x00" + s.path
+	if p.seen[key] {
+		return nil
+	}
+	info, err := sc.Stat(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("not a regular file")
+		}
+		if s.data, err = sshclient.ReadFile(ctx, sc, s.path, p.deps.Edit.MaxFileSize); err != nil {
+			return err
+		}
+		s.existed, s.perms = true, info.Mode().Perm()
+	}
+	if p.seen == nil {
+		p.seen = make(map[string]bool)
+	}
+	p.seen[key] = true
+	p.snapshots = append(p.snapshots, s)
+	return nil
+}
+
+// rollback restores every snapshot, most recent first, and returns the
+// paths restored and the errors met.
+func (p *planRun) rollback(ctx context.Context) (restored, failures []string) {
+	for i := len(p.snapshots) - 1; i >= 0; i-- {
+		s := p.snapshots[i]
+		if err := p.restore(ctx, s); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", s.path, err))
+			continue
+		}
+		restored = append(restored, s.path)
+	}
+	return restored, failures
+}
+
+func (p *planRun) restore(ctx context.Context, s *fileSnapshot) error {
+	conn, client, err := getConnectionWithRateLimit(ctx, p.deps.Edit.Pool, nil, s.sessionID)
+	if err != nil {
+		return err
+	}
+	unlock, err := p.deps.Edit.Locks.Lock(ctx, conn, s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	if !s.existed {
+		if err := sc.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+			return fmt.Errorf("remove created file: %w", err)
+		}
+		return nil
+	}
+	n, err := sshclient.WriteFileAtomic(sc, s.path, s.data, s.perms)
+	if err != nil {
+		return err
+	}
+	conn.AddBytesUploaded(n)
+	return nil
+}
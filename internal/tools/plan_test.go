@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+)
+
+func TestHandlePlanExecute_Validation(t *testing.T) {
+	pool := connection.NewPool(&config.SSHConfig{}, nil)
+	deps := &PlanDeps{Edit: &FileEditDeps{Pool: pool}, Execute: &ExecuteDeps{Pool: pool}}
+	cmd := &SSHExecuteInput{SessionID: "s", Command: "true"}
+	tests := []struct {
+		name  string
+		steps []PlanStep
+		want  string
+	}{
+		{"empty", nil, "steps is required"},
+		{"too many", make([]PlanStep, maxPlanSteps+1), "too many steps"},
+		{"no action", []PlanStep{{Name: "x"}}, "step 1: set exactly one"},
+		{"two actions", []PlanStep{{Execute: cmd, Edit: &SSHEditFileInput{RemotePath: "/etc/x"}}}, "set exactly one"},
+		{"bad policy", []PlanStep{{Execute: cmd, OnFailure: "retry"}}, `invalid on_failure "retry"`},
+		{"disabled tool", []PlanStep{{Execute: cmd}, {Upload: &SSHUploadInput{}}}, "step 2: ssh_upload is disabled"},
+		{"bad path", []PlanStep{{Edit: &SSHEditFileInput{RemotePath: "/etc/\x00x"}}}, "invalid remote path"},
+	}
+	for _, tt := range tests {
+		_, err := HandlePlanExecute(t.Context(), deps, SSHPlanExecuteInput{Steps: tt.steps})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestHandlePlanExecute_FailurePolicies(t *testing.T) {
+	pool := connection.NewPool(&config.SSHConfig{}, nil)
+	deps := &PlanDeps{Execute: &ExecuteDeps{Pool: pool}}
+	// No session exists, so every step fails.
+	step := func(policy string) PlanStep {
+		return PlanStep{Execute: &SSHExecuteInput{SessionID: "missing", Command: "true"}, OnFailure: policy}
+	}
+	statuses := func(out *SSHPlanExecuteOutput) string {
+		var s []string
+		for _, r := range out.Steps {
+			s = append(s, r.Status)
+		}
+		return strings.Join(s, ",")
+	}
+
+	for _, tt := range []struct {
+		first    string
+		want     string
+		rollback bool
+	}{
+		{planContinue, "failed,failed,skipped", true},
+		{planStop, "failed,skipped,skipped", false},
+		{"", "failed,skipped,skipped", true},
+	} {
+		out, err := HandlePlanExecute(t.Context(), deps, SSHPlanExecuteInput{Steps: []PlanStep{step(tt.first), step(""), step("")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Succeeded || statuses(out) != tt.want || out.RollbackAttempted != tt.rollback {
+			t.Errorf("on_failure=%q: succeeded=%v statuses=%s rollback=%v, want %s rollback=%v",
+				tt.first, out.Succeeded, statuses(out), out.RollbackAttempted, tt.want, tt.rollback)
+		}
+		if out.Steps[0].Error == "" || out.Steps[0].Kind != "execute" {
+			t.Errorf("step 1 = %+v, want an execute error", out.Steps[0])
+		}
+	}
+}
+
+func TestSSHPlanExecuteOutput_Text(t *testing.T) {
+	out := SSHPlanExecuteOutput{
+		Steps: []PlanStepResult{
+			{Step: 1, Name: "config", Kind: "edit", Status: planOK, Message: "Patched /etc/app.conf"},
+			{Step: 2, Kind: "execute", Status: planFailed, Message: "exit code 1", Error: "command exited with code 1: bad"},
+			{Step: 3, Kind: "execute", Status: planSkipped},
+		},
+		RollbackAttempted: true,
+		RolledBack:        []string{"/etc/app.conf"},
+	}
+	want := "Plan FAILED: 1 of 3 step(s) succeeded\n" +
+		"  1. config (edit): ok - Patched /etc/app.conf\n" +
+		"  2. execute: failed - exit code 1: command exited with code 1: bad\n" +
+		"  3. execute: skipped\n" +
+		"Rolled back 1 file(s): /etc/app.conf"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+
+	out = SSHPlanExecuteOutput{Succeeded: true, Steps: []PlanStepResult{{Step: 1, Kind: "upload", Status: planOK}}}
+	if got := out.Text(); got != "Plan succeeded: 1 step(s)\n  1. upload: ok" {
+		t.Errorf("Text() = %q", got)
+	}
+	out = SSHPlanExecuteOutput{RollbackAttempted: true, RollbackErrors: []string{"/etc/a: permission denied"}}
+	if got := out.Text(); !strings.Contains(got, "ROLLBACK FAILED for 1 file(s):\n  /etc/a: permission denied") {
+		t.Errorf("Text() = %q", got)
+	}
+}
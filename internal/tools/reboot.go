@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// RebootDeps holds dependencies for the ssh_reboot tool handler.
+type RebootDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+const (
+	defaultRebootTimeout  = 10 * time.Minute
+	maxRebootTimeout      = time.Hour
+	defaultRebootInterval = 5 * time.Second
+	maxRebootInterval     = time.Minute
+	// rebootCommandWait is how long the reboot command may run; one that
+	// hangs is taken as the host going down.
+	rebootCommandWait = 30 * time.Second
+	// bootProbeTimeout bounds each boot identity probe.
+	bootProbeTimeout = 10 * time.Second
+)
+
+// bootProbeCommand prints the kernel release, then an identity that
+// changes on every boot: the Linux boot ID, or the BSD/macOS boot time.
+const bootProbeCommand = "uname -r; cat /proc/sys/kernel/random/boot_id 2>/dev/null || sysctl -n kern.boottime 2>/dev/null"
+
+// HandleReboot implements the ssh_reboot tool. It records the host's boot
+// identity, marks the session as rebooting (other tools fail fast on it
+// meanwhile), runs shutdown -r now through the command filter, and polls
+// with Pool.Revive every poll_interval until an SSH connection succeeds
+// and the boot identity has changed. The session is then reconnected;
+// the result reports how long the host was unreachable.
+func HandleReboot(ctx context.Context, deps *RebootDeps, input SSHRebootInput) (*SSHRebootOutput, error) {
+	timeout, interval, err := rebootTimings(input)
+	if err != nil {
+		return nil, err
+	}
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_reboot needs a POSIX host")
+	}
+	if !input.Sudo && conn.User != "root" {
+		return nil, fmt.Errorf("reboot needs root: set sudo: true (requires --enable-sudo)")
+	}
+	cmd, err := buildCLICommand(deps.Filter, deps.Config, input.Sudo, "shutdown", "-r", "now")
+	if err != nil {
+		return nil, err
+	}
+
+	probe := func(c *connection.Connection) (bootIdentity, error) {
+		cl, err := c.GetClient()
+		if err != nil {
+			return bootIdentity{}, err
+		}
+		res, err := runRemoteCommand(ctx, c, cl, bootProbeCommand, nil, bootProbeTimeout)
+		if err != nil {
+			return bootIdentity{}, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return bootIdentity{}, remoteFailure("boot probe", res)
+		}
+		return parseBootProbe(res.Stdout), nil
+	}
+	before, err := probe(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.BeginReboot()
+	defer conn.EndReboot()
+	start := time.Now()
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, rebootCommandWait)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, err
+	case err == nil && !res.TimedOut && res.ExitCode > 0:
+		// A lost connection, a signal or a hang all mean the host is
+		// going down; only a real exit status is a failure.
+		err = remoteFailure("shutdown", res)
+		conn.SetLastError(err)
+		return nil, err
+	}
+
+	out := &SSHRebootOutput{SessionID: input.SessionID, KernelBefore: before.kernel}
+	var downAt time.Time
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			if downAt.IsZero() {
+				return nil, fmt.Errorf("host did not go down within %s (boot ID unchanged); the reboot may be delayed or blocked", timeout)
+			}
+			return nil, fmt.Errorf("host did not come back within %s (unreachable since %s)", timeout, downAt.Format(time.RFC3339))
+		case <-time.After(interval):
+		}
+		out.Polls++
+		c, _, err := deps.Pool.Revive(ctx, connection.SessionID(input.SessionID))
+		var after bootIdentity
+		if err == nil {
+			after, err = probe(c)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if downAt.IsZero() {
+				downAt = time.Now()
+			}
+			continue
+		}
+		// Without a boot identity, coming back after being down counts.
+		if after.id != before.id || (before.id == "" && !downAt.IsZero()) {
+			up := time.Now()
+			if downAt.IsZero() {
+				downAt = start // back before any poll saw it down
+			}
+			out.KernelAfter = after.kernel
+			out.DowntimeMs = up.Sub(downAt).Milliseconds()
+			out.TotalMs = up.Sub(start).Milliseconds()
+			return out, nil
+		}
+	}
+}
+
+// rebootTimings returns the timeout and poll interval of input, or the
+// defaults.
+func rebootTimings(input SSHRebootInput) (time.Duration, time.Duration, error) {
+	timeout := time.Duration(input.Timeout) * time.Second
+	if input.Timeout < 0 || timeout > maxRebootTimeout {
+		return 0, 0, fmt.Errorf("timeout must be between 0 and %d seconds", int(maxRebootTimeout.Seconds()))
+	}
+	if timeout == 0 {
+		timeout = defaultRebootTimeout
+	}
+	interval := time.Duration(input.PollInterval) * time.Second
+	if input.PollInterval < 0 || interval > maxRebootInterval {
+		return 0, 0, fmt.Errorf("poll_interval must be between 0 and %d seconds", int(maxRebootInterval.Seconds()))
+	}
+	if interval == 0 {
+		interval = defaultRebootInterval
+	}
+	return timeout, interval, nil
+}
+
+// bootIdentity is the output of bootProbeCommand.
+type bootIdentity struct {
+	kernel string
+	id     string // empty if the host has neither source
+}
+
+// parseBootProbe reads bootProbeCommand output.
+func parseBootProbe(out string) bootIdentity {
+	kernel, id, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	return bootIdentity{kernel: strings.TrimSpace(kernel), id: strings.TrimSpace(id)}
+}
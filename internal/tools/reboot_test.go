@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebootTimings(t *testing.T) {
+	timeout, interval, err := rebootTimings(SSHRebootInput{})
+	if err != nil || timeout != defaultRebootTimeout || interval != defaultRebootInterval {
+		t.Errorf("defaults = %s, %s, %v", timeout, interval, err)
+	}
+	timeout, interval, err = rebootTimings(SSHRebootInput{Timeout: 120, PollInterval: 2})
+	if err != nil || timeout != 2*time.Minute || interval != 2*time.Second {
+		t.Errorf("explicit = %s, %s, %v", timeout, interval, err)
+	}
+	for _, in := range []SSHRebootInput{{Timeout: -1}, {Timeout: 3601}, {PollInterval: -1}, {PollInterval: 61}} {
+		if _, _, err := rebootTimings(in); err == nil {
+			t.Errorf("%+v: expected an error", in)
+		}
+	}
+}
+
+func TestHandleReboot_Validation(t *testing.T) {
+	_, err := HandleReboot(context.Background(), &RebootDeps{}, SSHRebootInput{SessionID: "root@host:22", Timeout: 7200})
+	if err == nil || !strings.Contains(err.Error(), "timeout must be") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParseBootProbe(t *testing.T) {
+	got := parseBootProbe("6.1.0-18-amd64\n0f6c1a8e-3a42-4c5e-9f0b-1c2d3e4f5a6b\n")
+	if got.kernel != "6.1.0-18-amd64" || got.id != "0f6c1a8e-3a42-4c5e-9f0b-1c2d3e4f5a6b" {
+		t.Errorf("linux = %+v", got)
+	}
+	got = parseBootProbe("23.4.0\n{ sec = 1718000000, usec = 0 } Mon Jun 10 06:13:20 2024\n")
+	if got.kernel != "23.4.0" || !strings.HasPrefix(got.id, "{ sec = 1718000000") {
+		t.Errorf("macOS = %+v", got)
+	}
+	if got := parseBootProbe("5.10.0\n"); got.kernel != "5.10.0" || got.id != "" {
+		t.Errorf("no boot id = %+v", got)
+	}
+}
+
+func TestSSHRebootOutput_Text(t *testing.T) {
+	out := SSHRebootOutput{SessionID: "root@db1:22", DowntimeMs: 83000, TotalMs: 91000, Polls: 18,
+		KernelBefore: "6.1.0-17-amd64", KernelAfter: "6.1.0-18-amd64"}
+	want := "Host rebooted; session root@db1:22 reconnected after 1m23s down (1m31s after the reboot command, 18 poll(s))\n" +
+		"Kernel 6.1.0-17-amd64 -> 6.1.0-18-amd64"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	out.KernelBefore = out.KernelAfter
+	if got := out.Text(); !strings.HasSuffix(got, "Kernel 6.1.0-18-amd64 (unchanged)") {
+		t.Errorf("Text() = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// RenameDeps holds dependencies for the ssh_rename tool handler.
+type RenameDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// HandleRename implements the ssh_rename tool. It moves a file or directory
+// on the remote host over SFTP; with overwrite, an existing destination
+// file is replaced atomically when the server supports posix-rename.
+func HandleRename(ctx context.Context, deps *RenameDeps, input SSHRenameInput) (*SSHRenameOutput, error) {
+	if input.SourcePath == "" || input.DestPath == "" {
+		return nil, fmt.Errorf("source_path and dest_path are required")
+	}
+	if err := security.ValidatePath(input.SourcePath); err != nil {
+		return nil, fmt.Errorf("invalid source path: %w", err)
+	}
+	if err := security.ValidatePath(input.DestPath); err != nil {
+		return nil, fmt.Errorf("invalid destination path: %w", err)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	src := path.Clean(expandRemotePath(conn, sc, input.SourcePath))
+	dst := path.Clean(expandRemotePath(conn, sc, input.DestPath))
+	if src == dst {
+		return nil, fmt.Errorf("source and destination are the same path: %s", src)
+	}
+	if strings.HasPrefix(dst, strings.TrimSuffix(src, "/")+"/") {
+		return nil, fmt.Errorf("cannot move %s into itself (%s)", src, dst)
+	}
+
+	srcInfo, err := sc.Lstat(src)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("stat source %s: %w", src, err)
+	}
+	out := &SSHRenameOutput{Source: src, Destination: dst}
+	if dstInfo, err := sc.Lstat(dst); err == nil {
+		switch {
+		case dstInfo.IsDir():
+			return nil, fmt.Errorf("destination %s is an existing directory; give the full new path", dst)
+		case srcInfo.IsDir():
+			return nil, fmt.Errorf("cannot overwrite file %s with a directory", dst)
+		case !input.Overwrite:
+			return nil, fmt.Errorf("destination %s already exists (set overwrite to replace it)", dst)
+		}
+		out.Replaced = true
+	} else if !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("stat destination %s: %w", dst, err)
+	}
+
+	out.Atomic, err = sshclient.Rename(sc, src, dst, input.Overwrite)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("rename %s to %s: %w", src, dst, err)
+	}
+	return out, nil
+}
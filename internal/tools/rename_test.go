@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandleRename_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input SSHRenameInput
+		want  string
+	}{
+		{"missing source", SSHRenameInput{SessionID: "s", DestPath: "/tmp/b"}, "required"},
+		{"missing dest", SSHRenameInput{SessionID: "s", SourcePath: "/tmp/a"}, "required"},
+		{"source traversal", SSHRenameInput{SessionID: "s", SourcePath: "/tmp/../etc/a", DestPath: "/tmp/b"}, "invalid source path"},
+		{"dest traversal", SSHRenameInput{SessionID: "s", SourcePath: "/tmp/a", DestPath: "/tmp/../etc/b"}, "invalid destination path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandleRename(context.Background(), &RenameDeps{}, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSSHRenameOutput_Text(t *testing.T) {
+	tests := []struct {
+		out  SSHRenameOutput
+		want string
+	}{
+		{SSHRenameOutput{Source: "/a", Destination: "/b", Atomic: true}, "Renamed /a to /b"},
+		{SSHRenameOutput{Source: "/a", Destination: "/b", Replaced: true, Atomic: true}, "Renamed /a to /b (replaced the existing file atomically)"},
+		{SSHRenameOutput{Source: "/a", Destination: "/b", Replaced: true}, "Renamed /a to /b (removed the existing file first; the server has no posix-rename)"},
+	}
+	for _, tt := range tests {
+		if got := tt.out.Text(); got != tt.want {
+			t.Errorf("Text() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// HandleRestoreBackup implements the ssh_restore_backup tool. It lists the
+// backups ssh_edit_file made of a file, or restores one of them (the newest
+// by default) over the file.
+func HandleRestoreBackup(ctx context.Context, deps *FileEditDeps, input SSHRestoreBackupInput) (*SSHRestoreBackupOutput, error) {
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	if input.Backup != "" && path.Base(input.Backup) != input.Backup {
+		return nil, fmt.Errorf("backup must be a backup name as listed, not a path: %q", input.Backup)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+
+	backups, err := listBackups(sc, deps.Backup, input.RemotePath)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	out := &SSHRestoreBackupOutput{RemotePath: input.RemotePath, Backups: backups}
+	if input.List {
+		return out, nil
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups of %s found", input.RemotePath)
+	}
+
+	chosen := backups[0]
+	if input.Backup != "" {
+		found := false
+		for _, b := range backups {
+			if b.Name == input.Backup {
+				chosen, found = b, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("backup %q not found for %s (use list to see available backups)", input.Backup, input.RemotePath)
+		}
+	}
+
+	data, err := sshclient.ReadFile(ctx, sc, chosen.Path, deps.MaxFileSize)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+
+	// Don't interleave with an ssh_edit_file of the same file.
+	unlock, err := deps.Locks.Lock(ctx, conn, input.RemotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Default backup of the current content to true, so a restore can itself
+	// be undone.
+	backupCurrent := true
+	if input.BackupCurrent != nil {
+		backupCurrent = *input.BackupCurrent
+	}
+	perms := defaultPerms(sc, input.RemotePath)
+	if _, err := sc.Stat(input.RemotePath); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("stat remote file: %w", err)
+		}
+		// The file is gone; restore it with the backup's permissions.
+		perms = defaultPerms(sc, chosen.Path)
+	} else if backupCurrent {
+		if out.BackupPath, err = createBackup(ctx, sc, deps, input.RemotePath); err != nil {
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("back up current file: %w", err)
+		}
+	}
+
+	n, err := sshclient.WriteFileAtomic(sc, input.RemotePath, data, perms)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, fmt.Errorf("restore file: %w", err)
+	}
+	conn.AddBytesUploaded(n)
+
+	out.Backups = nil
+	out.RestoredFrom = chosen.Path
+	out.BytesWritten = n
+	return out, nil
+}
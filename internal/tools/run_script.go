@@ -0,0 +1,258 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// RunScriptDeps holds dependencies for the ssh_run_script tool handler.
+type RunScriptDeps struct {
+	Pool          *connection.Pool
+	Filter        *security.Filter
+	RateLimiter   *security.RateLimiter
+	Config        *config.SSHConfig
+	MaxOutputSize int
+}
+
+// scriptInterpreter describes how a script file is run.
+type scriptInterpreter struct {
+	ext     string   // file extension (PowerShell refuses -File without .ps1)
+	posix   []string // argv prefix on POSIX hosts
+	windows []string // argv prefix on Windows hosts (nil = unsupported)
+}
+
+var scriptInterpreters = map[string]scriptInterpreter{
+	"bash":   {ext: ".sh", posix: []string{"bash"}},
+	"sh":     {ext: ".sh", posix: []string{"sh"}},
+	"python": {ext: ".py", posix: []string{"python3"}, windows: []string{"python"}},
+	"powershell": {
+		ext:     ".ps1",
+		posix:   []string{"pwsh", "-NoProfile", "-NonInteractive", "-File"},
+		windows: []string{"powershell", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"},
+	},
+}
+
+// scriptInterpreterAliases maps alternative names to scriptInterpreters keys.
+var scriptInterpreterAliases = map[string]string{
+	"python3": "python",
+	"pwsh":    "powershell",
+}
+
+// HandleRunScript implements the ssh_run_script tool: the script is uploaded
+// over SFTP to a temp file readable only by the SSH user, run with the chosen
+// interpreter, and removed afterwards (also on failure or timeout).
+func HandleRunScript(ctx context.Context, deps *RunScriptDeps, input SSHRunScriptInput) (*SSHExecuteOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if strings.TrimSpace(input.Script) == "" {
+		return nil, fmt.Errorf("script is required")
+	}
+	if err := checkScriptLines(deps.Filter, input.Script, input.Interpreter); err != nil {
+		return nil, err
+	}
+	if input.Timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout: %d (must be non-negative)", input.Timeout)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	windows := conn.GetRemoteInfo().OS == "Windows"
+
+	name := strings.ToLower(input.Interpreter)
+	if name == "" {
+		name = "bash"
+		if windows {
+			name = "powershell"
+		}
+	}
+	if alias, ok := scriptInterpreterAliases[name]; ok {
+		name = alias
+	}
+	interp, ok := scriptInterpreters[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interpreter %q (must be bash, sh, python, or powershell)", input.Interpreter)
+	}
+	argv := interp.posix
+	if windows {
+		argv = interp.windows
+		if argv == nil {
+			return nil, fmt.Errorf("interpreter %q is not available on Windows hosts", name)
+		}
+		if input.Sudo {
+			return nil, fmt.Errorf("sudo is not supported on Windows hosts")
+		}
+	}
+	if input.Sudo && !deps.Config.AllowSudo {
+		return nil, fmt.Errorf("sudo is disabled; start server with --enable-sudo to allow")
+	}
+	env, err := resolveCommandEnv(deps.Config, input.Locale, input.Term, windows)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	dir := "/tmp"
+	if windows {
+		// No portable temp directory over SFTP; use the SFTP start
+		// directory (the user's profile).
+		if dir, err = sc.Getwd(); err != nil {
+			return nil, fmt.Errorf("resolve remote home: %w", err)
+		}
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("generate script name: %w", err)
+	}
+	remotePath := path.Join(dir, "ssh-mcp-script-"+hex.EncodeToString(suffix)+interp.ext)
+
+	// O_EXCL guards against a pre-planted file or symlink in the shared
+	// temp directory; permissions are tightened before any content is written.
+	f, err := sc.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return nil, fmt.Errorf("create remote script: %w", err)
+	}
+	defer func() {
+		if err := sc.Remove(remotePath); err != nil {
+			conn.SetLastError(fmt.Errorf("remove remote script %s: %w", remotePath, err))
+		}
+	}()
+	if !windows {
+		if err := sc.Chmod(remotePath, 0o700); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("chmod remote script: %w", err)
+		}
+	}
+	script := input.Script
+	if windows {
+		script = strings.ReplaceAll(strings.ReplaceAll(script, "\r\n", "\n"), "\n", "\r\n")
+	}
+	if _, err := f.Write([]byte(script)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write remote script: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("write remote script: %w", err)
+	}
+	conn.AddBytesUploaded(int64(len(script)))
+
+	// env goes after sudo, which would reset the environment.
+	cmd := buildScriptCommand(append(env.envArgs(), argv...), remotePath, input.Args, input.WorkingDir, input.Sudo, windows)
+
+	timeout := deps.Config.CommandTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+	grace, err := killGrace(deps.Config, input.KillGrace)
+	if err != nil {
+		return nil, err
+	}
+	var stdin io.Reader
+	if input.Sudo && input.SudoPassword != "" {
+		stdin = strings.NewReader(input.SudoPassword + "\n")
+	}
+	res, err := runRemoteCommandGrace(ctx, conn, client, cmd, stdin, timeout, grace)
+	if err != nil {
+		return nil, err
+	}
+	return execOutput(res, timeout, deps.Config, deps.MaxOutputSize), nil
+}
+
+// checkScriptLines runs every non-blank, non-comment line of the script
+// through the command filter, so a script cannot smuggle in a command that
+// ssh_execute would reject. With an allowlist, every line must be allowed.
+// When the filter parses shell code, a bash or sh script is checked as a
+// whole instead, since its commands may span lines, and other interpreters
+// are rejected because their code can't be checked.
+func checkScriptLines(filter *security.Filter, script, interpreter string) error {
+	if filter.ShellParsing() {
+		switch strings.ToLower(interpreter) {
+		case "", "bash", "sh":
+		default:
+			return fmt.Errorf("interpreter %q is not allowed: with --parse-commands only bash and sh scripts can be checked", interpreter)
+		}
+		if err := filter.AllowCommand(script); err != nil {
+			return fmt.Errorf("script: %w", err)
+		}
+		return nil
+	}
+	for i, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := filter.AllowCommand(line); err != nil {
+			return fmt.Errorf("script line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// buildScriptCommand builds the remote command line that runs the script file.
+func buildScriptCommand(argv []string, remotePath string, args []string, workingDir string, sudo, windows bool) string {
+	quote := shellQuote
+	if windows {
+		quote = windowsQuote
+	}
+	parts := make([]string, 0, len(argv)+len(args)+1)
+	parts = append(parts, argv...)
+	parts = append(parts, quote(remotePath))
+	for _, a := range args {
+		parts = append(parts, quote(a))
+	}
+	cmd := strings.Join(parts, " ")
+	if sudo {
+		cmd = "sudo -S " + cmd
+	}
+	if workingDir != "" {
+		if windows {
+			cmd = fmt.Sprintf("cd /d %s && %s", quote(workingDir), cmd)
+		} else {
+			cmd = fmt.Sprintf("cd %s && %s", quote(workingDir), cmd)
+		}
+	}
+	return cmd
+}
+
+// windowsQuote quotes s as a single argument using the MSVC command-line
+// rules: embedded double quotes and the backslashes preceding them are escaped.
+func windowsQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for _, r := range s {
+		switch r {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteRune(r)
+	}
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func TestCheckScriptLines(t *testing.T) {
+	filter, err := security.NewFilter(nil, nil, nil, []string{"rm -rf.*", "shutdown.*"})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	script := "#!/bin/bash\n# rm -rf / is mentioned in a comment\n\nset -e\necho hi\n"
+	if err := checkScriptLines(filter, script, ""); err != nil {
+		t.Errorf("expected script to pass, got %v", err)
+	}
+
+	err = checkScriptLines(filter, "echo start\n  shutdown -h now\n", "")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected denial on line 2, got %v", err)
+	}
+
+	allow, err := security.NewFilter(nil, nil, []string{"echo .*"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	if err := checkScriptLines(allow, "echo a\ncurl example.com\n", ""); err == nil {
+		t.Error("expected allowlist to reject a line not in it")
+	}
+}
+
+func TestCheckScriptLines_ShellParsing(t *testing.T) {
+	filter, err := security.NewFilter(nil, nil, nil, []string{"shutdown.*"})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	filter.EnableShellParsing()
+	// A multi-line construct is checked as a whole, not line by line.
+	script := "for h in a b; do\n  echo \"$h\"\ndone\n"
+	if err := checkScriptLines(filter, script, "bash"); err != nil {
+		t.Errorf("expected script to pass, got %v", err)
+	}
+	if err := checkScriptLines(filter, "if true; then\n  echo x && shutdown -h now\nfi\n", ""); err == nil {
+		t.Error("expected shutdown inside if to be denied")
+	}
+	// Other interpreters can't be parsed, so they are refused.
+	if err := checkScriptLines(filter, "import os\nprint(1)\n", "python"); err == nil {
+		t.Error("expected python script to be rejected")
+	}
+}
+
+func TestBuildScriptCommand(t *testing.T) {
+	got := buildScriptCommand([]string{"bash"}, "/tmp/ssh-mcp-script-1.sh", []string{"a b", "it's"}, "/srv/app", true, false)
+	want := `cd '/srv/app' && sudo -S bash '/tmp/ssh-mcp-script-1.sh' 'a b' 'it'\''s'`
+	if got != want {
+		t.Errorf("POSIX command:\n got %s\nwant %s", got, want)
+	}
+
+	got = buildScriptCommand(scriptInterpreters["powershell"].windows, "C:/Users/me/ssh-mcp-script-1.ps1", []string{"x y"}, `C:\work`, false, true)
+	want = `cd /d "C:\work" && powershell -NoProfile -NonInteractive -ExecutionPolicy Bypass -File "C:/Users/me/ssh-mcp-script-1.ps1" "x y"`
+	if got != want {
+		t.Errorf("Windows command:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestWindowsQuote(t *testing.T) {
+	tests := map[string]string{
+		`plain`:        `"plain"`,
+		`with space`:   `"with space"`,
+		`say "hi"`:     `"say \"hi\""`,
+		`C:\dir\`:      `"C:\dir\\"`,
+		`a\"b`:         `"a\\\"b"`,
+		`C:\Program F`: `"C:\Program F"`,
+	}
+	for in, want := range tests {
+		if got := windowsQuote(in); got != want {
+			t.Errorf("windowsQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestHandleRunScript_Validation(t *testing.T) {
+	filter, err := security.NewFilter(nil, nil, nil, []string{"reboot"})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	deps := &RunScriptDeps{Filter: filter}
+	for _, tc := range []struct {
+		input SSHRunScriptInput
+		want  string
+	}{
+		{SSHRunScriptInput{Script: "echo hi"}, "session_id"},
+		{SSHRunScriptInput{SessionID: "s", Script: " \n "}, "script is required"},
+		{SSHRunScriptInput{SessionID: "s", Script: "echo hi\nreboot"}, "denied"},
+		{SSHRunScriptInput{SessionID: "s", Script: "echo hi", Timeout: -1}, "timeout"},
+	} {
+		_, err := HandleRunScript(t.Context(), deps, tc.input)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("input %+v: expected error containing %q, got %v", tc.input, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSanitizeColumn caps cursor movement and padding in sanitizeOutput so a
+// stray "ESC[99999C" cannot blow up a line.
+const maxSanitizeColumn = 4096
+
+// sanitizeOutput returns the text a terminal would end up showing for s.
+// Escape sequences (colours, cursor movement, OSC titles and hyperlinks,
+// DCS strings) are removed, and carriage returns, backspaces, line erases
+// and cursor-up redraws overwrite earlier text instead of piling up, so the
+// progress bars of apt, pip or docker pull collapse to their final state.
+// Other control characters except tab and newline are dropped. Absolute
+// cursor positioning and screen clears are ignored, so full-screen programs
+// are not rendered faithfully.
+func sanitizeOutput(s string) string {
+	if strings.IndexFunc(s, isSanitizedControl) < 0 {
+		return s
+	}
+	sc := &screen{lines: [][]rune{nil}}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\x1b':
+			i += sc.escape(s[i:])
+			continue
+		case r == '\n':
+			sc.newline()
+		case r == '\r':
+			sc.col = 0
+		case r == '\b':
+			if sc.col > 0 {
+				sc.col--
+			}
+		case r == '\t':
+			sc.put(r)
+		case isSanitizedControl(r):
+			// Bell and other control characters have no visible effect.
+		default:
+			sc.put(r)
+		}
+		i += size
+	}
+	return sc.String()
+}
+
+// isSanitizedControl reports whether r is a C0/C1 control character that
+// sanitizeOutput interprets or drops.
+func isSanitizedControl(r rune) bool {
+	return (r < 0x20 && r != '\n' && r != '\t') || (r >= 0x7f && r < 0xa0)
+}
+
+// screen is a minimal line-oriented terminal: a growing list of lines and a
+// cursor that can move within them.
+type screen struct {
+	lines    [][]rune
+	row, col int
+}
+
+func (sc *screen) put(r rune) {
+	line := sc.lines[sc.row]
+	if sc.col < len(line) {
+		line[sc.col] = r
+	} else {
+		for len(line) < sc.col {
+			line = append(line, ' ')
+		}
+		line = append(line, r)
+	}
+	sc.lines[sc.row] = line
+	sc.col++
+}
+
+func (sc *screen) newline() {
+	sc.row++
+	if sc.row == len(sc.lines) {
+		sc.lines = append(sc.lines, nil)
+	}
+	sc.col = 0
+}
+
+// escape consumes the escape sequence at the start of s (which begins with
+// ESC), applies its effect on the cursor or line, and returns its length.
+func (sc *screen) escape(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		return sc.csi(s)
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM and APC strings end with BEL or ST (ESC \).
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1
+			case s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\':
+				return i + 2
+			case s[i] == '\n':
+				// Unterminated: don't swallow the rest of the output.
+				return i
+			}
+		}
+		return len(s)
+	case '(', ')', '*', '+', '-', '.', '/', '#', '%', ' ':
+		// Character set designations and similar three-byte sequences.
+		return min(3, len(s))
+	}
+	return 2
+}
+
+// csi applies a Control Sequence Introducer sequence (ESC [ params final)
+// and returns its length.
+func (sc *screen) csi(s string) int {
+	i := 2
+	for i < len(s) && s[i] >= 0x30 && s[i] <= 0x3f {
+		i++
+	}
+	params := s[2:i]
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+		i++
+	}
+	if i >= len(s) {
+		return len(s)
+	}
+	final := s[i]
+	if params != "" && strings.ContainsAny(params[:1], "<=>?") {
+		return i + 1 // private modes (cursor visibility etc.)
+	}
+
+	arg := func(def int) int {
+		first, _, _ := strings.Cut(params, ";")
+		n, err := strconv.Atoi(first)
+		if err != nil || n <= 0 {
+			return def
+		}
+		return min(n, maxSanitizeColumn)
+	}
+
+	switch final {
+	case 'A': // cursor up
+		sc.row = max(sc.row-arg(1), 0)
+	case 'B': // cursor down, within the lines written so far
+		sc.row = min(sc.row+arg(1), len(sc.lines)-1)
+	case 'C': // cursor forward
+		sc.col = min(sc.col+arg(1), maxSanitizeColumn)
+	case 'D': // cursor back
+		sc.col = max(sc.col-arg(1), 0)
+	case 'E': // cursor to start of a following line
+		sc.row = min(sc.row+arg(1), len(sc.lines)-1)
+		sc.col = 0
+	case 'F': // cursor to start of a preceding line
+		sc.row = max(sc.row-arg(1), 0)
+		sc.col = 0
+	case 'G': // cursor to column
+		sc.col = arg(1) - 1
+	case 'K': // erase in line
+		sc.eraseLine(arg(0))
+	case 'J': // erase below; erasing the whole screen is ignored
+		if arg(0) == 0 {
+			sc.eraseLine(0)
+			sc.lines = sc.lines[:sc.row+1]
+		}
+	}
+	return i + 1
+}
+
+// eraseLine implements ESC[nK: 0 erases to the end of the line, 1 to the
+// cursor, 2 the whole line.
+func (sc *screen) eraseLine(mode int) {
+	line := sc.lines[sc.row]
+	switch mode {
+	case 0:
+		if sc.col < len(line) {
+			sc.lines[sc.row] = line[:sc.col]
+		}
+	case 1:
+		for i := 0; i <= sc.col && i < len(line); i++ {
+			line[i] = ' '
+		}
+	case 2:
+		sc.lines[sc.row] = line[:0]
+	}
+}
+
+func (sc *screen) String() string {
+	var b strings.Builder
+	for i, line := range sc.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(string(line))
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello\nworld\n", "hello\nworld\n"},
+		{"tabs kept", "a\tb\n", "a\tb\n"},
+		{"colours", "\x1b[1;31merror\x1b[0m: bad\n", "error: bad\n"},
+		{"crlf", "line1\r\nline2\r\n", "line1\nline2\n"},
+		{"cr progress", "  0%\r 50%\r100%\n", "100%\n"},
+		{"cr shorter overwrite", "downloading\rdone\n", "doneloading\n"},
+		{"cr with erase line", "downloading\r\x1b[Kdone\n", "done\n"},
+		{"erase whole line", "old text\x1b[2K\rnew\n", "new\n"},
+		{"backspace", "spinner: |\b/\b-\b\\\bdone\n", "spinner: done\n"},
+		{"osc title bel", "\x1b]0;my title\x07prompt$ ", "prompt$ "},
+		{"osc hyperlink st", "see \x1b]8;;https://example.com/a?b=1\x1b\\docs\x1b]8;;\x1b\\ now", "see docs now"},
+		{"unterminated osc stops at newline", "\x1b]0;title\nnext", "\nnext"},
+		{"cursor visibility", "\x1b[?25lworking\x1b[?25h\n", "working\n"},
+		{"charset designation", "\x1b(Bplain\n", "plain\n"},
+		{"bell dropped", "ding\a\n", "ding\n"},
+		{
+			"docker pull redraw",
+			"layer1: Downloading\nlayer2: Downloading\n" +
+				"\x1b[2A\x1b[2Klayer1: Pull complete\n\x1b[2Klayer2: Pull complete\n",
+			"layer1: Pull complete\nlayer2: Pull complete\n",
+		},
+		{"cursor up clamped", "\x1b[5Atop\n", "top\n"},
+		{"erase below", "a\nb\nc\x1b[2A\x1b[J", "a"},
+		{"cursor column", "abcdef\x1b[3GX\n", "abXdef\n"},
+		{"huge forward move capped", "a\x1b[99999Cb", "a" + strings.Repeat(" ", maxSanitizeColumn-1) + "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeOutput(tt.in); got != tt.want {
+				t.Errorf("sanitizeOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
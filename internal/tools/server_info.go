@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+// ToolInfo describes a tool registered on the server.
+type ToolInfo struct {
+	Name     string
+	ReadOnly bool // the tool's ReadOnlyHint annotation
+}
+
+// ServerInfoDeps holds dependencies for the ssh_server_info tool handler.
+type ServerInfoDeps struct {
+	Config *config.Config
+	Tools  []ToolInfo // every registered tool, in registration order
+	Tenant string     // name of the tenant served ("" for the main server)
+}
+
+// HandleServerInfo implements the ssh_server_info tool. It reports the
+// configuration an agent can plan around; secrets (tokens, passwords,
+// webhook URLs) and the allowlist/denylist patterns themselves are never
+// included, only whether they are set.
+func HandleServerInfo(_ context.Context, deps *ServerInfoDeps, _ SSHServerInfoInput) (*SSHServerInfoOutput, error) {
+	cfg := deps.Config
+	out := &SSHServerInfoOutput{
+		Name:          "ssh-mcp",
+		Version:       config.Version,
+		Tenant:        deps.Tenant,
+		DisabledTools: cfg.DisabledTools,
+		Categories:    cfg.DisabledCategories,
+		ReadOnly:      true,
+		Security: SecurityPosture{
+			SudoAllowed:       cfg.SSH.AllowSudo,
+			AgentForwarding:   cfg.SSH.AllowAgentForward,
+			RunAsUsers:        cfg.SSH.RunAsUsers,
+			TerminalAllowed:   cfg.SSH.AllowTerminal,
+			TunnelsAllowed:    cfg.SSH.AllowTunnels,
+			HostKeyVerified:   cfg.SSH.VerifyHostKey,
+			HostAllowlist:     len(cfg.Security.HostAllowlist) > 0,
+			HostDenylist:      len(cfg.Security.HostDenylist) > 0,
+			CommandAllowlist:  len(cfg.Security.CommandAllowlist) > 0,
+			CommandDenylist:   len(cfg.Security.CommandDenylist) > 0,
+			ParseCommands:     cfg.Security.ParseCommands,
+			PolicyWebhook:     cfg.Security.PolicyWebhook != "",
+			RateLimit:         cfg.Security.RateLimit,
+			MaxFileSize:       cfg.Security.MaxFileSize,
+			MaxOutputSize:     cfg.SSH.MaxOutputSize,
+			LocalDirs:         localDirNames(cfg.Security.LocalDirs),
+			UploadModes:       uploadModes(&cfg.SSH).String(),
+			CommandTimeoutSec: int(cfg.SSH.CommandTimeout.Seconds()),
+		},
+		Transport: TransportInfo{
+			Stdio:          cfg.Transport.StdioEnabled,
+			HTTP:           cfg.Transport.HTTPEnabled,
+			HTTPAuth:       cfg.Transport.HTTPToken != "",
+			SharedSessions: cfg.Transport.SharedSessions,
+		},
+	}
+	if cfg.Preset != config.PresetFull {
+		out.Preset = cfg.Preset
+	}
+	if cfg.Transport.HTTPEnabled {
+		out.Transport.HTTPEndpoint = fmt.Sprintf("http://%s:%d%s", cfg.Transport.HTTPHost, cfg.Transport.HTTPPort, cfg.Transport.HTTPPath)
+	}
+	for _, t := range deps.Tools {
+		out.Tools = append(out.Tools, t.Name)
+		if !t.ReadOnly {
+			out.ReadOnly = false
+		}
+	}
+	return out, nil
+}
+
+// localDirNames formats the --local-base-dir entries like the flag.
+func localDirNames(dirs []config.LocalDir) []string {
+	var names []string
+	for _, d := range dirs {
+		names = append(names, d.String())
+	}
+	return names
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func TestHandleServerInfo(t *testing.T) {
+	cfg := &config.Config{
+		SSH: config.SSHConfig{
+			AllowSudo:         true,
+			AllowAgentForward: true,
+			RunAsUsers:        []string{"postgres"},
+			VerifyHostKey:     true,
+			CommandTimeout:    60 * time.Second,
+			UploadFileMode:    0o640,
+			UploadUmask:       0o022,
+		},
+		Security: config.SecurityConfig{
+			HostAllowlist:   []string{"10.0.0.0/8"},
+			CommandDenylist: []string{"rm -rf.*"},
+			RateLimit:       60,
+			MaxFileSize:     1024,
+			LocalDirs:       []config.LocalDir{{Path: "/srv/mcp"}, {Path: "/src", ReadOnly: true}},
+			PolicyWebhook:   "https://policy.example/check?token=secret",
+		},
+		Transport: config.TransportConfig{
+			HTTPEnabled: true, HTTPHost: "localhost", HTTPPort: 8081, HTTPPath: "/mcp", HTTPToken: "t0ken",
+		},
+		DisabledTools: []string{"ssh_upload"},
+	}
+	deps := &ServerInfoDeps{Config: cfg, Tools: []ToolInfo{
+		{Name: "ssh_list_sessions", ReadOnly: true},
+		{Name: "ssh_execute"},
+	}}
+
+	out, err := HandleServerInfo(context.Background(), deps, SSHServerInfoInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ReadOnly {
+		t.Error("read_only set although ssh_execute is enabled")
+	}
+	if len(out.Tools) != 2 || out.DisabledTools[0] != "ssh_upload" {
+		t.Errorf("tools = %v, disabled = %v", out.Tools, out.DisabledTools)
+	}
+	sec := out.Security
+	if !sec.SudoAllowed || !sec.AgentForwarding || !sec.HostAllowlist || sec.HostDenylist || !sec.CommandDenylist || !sec.PolicyWebhook {
+		t.Errorf("security posture = %+v", sec)
+	}
+	if out.Transport.HTTPEndpoint != "http://localhost:8081/mcp" || !out.Transport.HTTPAuth {
+		t.Errorf("transport = %+v", out.Transport)
+	}
+
+	text := out.Text()
+	for _, want := range []string{
+		"Tools (2): ssh_list_sessions, ssh_execute",
+		"Disabled: ssh_upload",
+		"sudo allowed, terminal disabled, tunnels disabled, agent forwarding allowed, run_as: postgres",
+		"Filters: host allowlist, command denylist, policy webhook",
+		"max file size 1024 bytes, max output unlimited",
+		"restricted to /srv/mcp, /src:ro",
+		"Upload modes: files 0640, umask 0022",
+		"http://localhost:8081/mcp (bearer token)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+	for _, secret := range []string{"10.0.0.0/8", "rm -rf", "token=secret", "t0ken"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("Text() leaks %q", secret)
+		}
+	}
+}
+
+func TestHandleServerInfo_ReadOnly(t *testing.T) {
+	cfg := &config.Config{Preset: config.PresetReadOnly, DisabledCategories: config.PresetCategories[config.PresetReadOnly]}
+	deps := &ServerInfoDeps{Config: cfg, Tools: []ToolInfo{{Name: "ssh_list_sessions", ReadOnly: true}}}
+	out, err := HandleServerInfo(context.Background(), deps, SSHServerInfoInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := out.Text()
+	if !out.ReadOnly || !strings.Contains(text, "Read-only") {
+		t.Errorf("expected read-only server, got %+v", out)
+	}
+	if !strings.Contains(text, "Preset: readonly") || !strings.Contains(text, "Disabled categories: exec, file-write, tunnels") {
+		t.Errorf("Text() missing preset:\n%s", text)
+	}
+}
@@ -2,12 +2,17 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/tunnel"
 )
 
+// expiryWarningWindow is how long before its max lifetime a session is
+// flagged in ssh_list_sessions.
+const expiryWarningWindow = 10 * time.Minute
+
 // SessionsDeps holds dependencies for the ssh_list_sessions tool handler.
 type SessionsDeps struct {
 	Pool       *connection.Pool
@@ -20,8 +25,10 @@ type SSHListSessionsInput struct{}
 
 // HandleListSessions implements the ssh_list_sessions tool.
 // Access control: when HTTP transport is used, access is gated by the --http-token bearer auth middleware.
-func HandleListSessions(_ context.Context, deps *SessionsDeps, _ SSHListSessionsInput) (*SSHListSessionsOutput, error) {
-	conns := deps.Pool.ListConnections()
+func HandleListSessions(ctx context.Context, deps *SessionsDeps, _ SSHListSessionsInput) (*SSHListSessionsOutput, error) {
+	conns := deps.Pool.ListConnections(ctx)
+	owner := connection.OwnerFrom(ctx)
+	now := time.Now()
 
 	sessions := make([]SessionInfo, len(conns))
 	for i, c := range conns {
@@ -33,17 +40,35 @@ func HandleListSessions(_ context.Context, deps *SessionsDeps, _ SSHListSessions
 			ConnectedAt:        c.ConnectedAt.Format(time.RFC3339),
 			LastUsed:           c.LastUsed.Format(time.RFC3339),
 			CommandCount:       c.CommandCount,
+			FailedCommands:     c.FailedCommands,
+			CommandTimeMs:      c.CommandTime.Milliseconds(),
+			BytesUploaded:      c.BytesUploaded,
+			BytesDownloaded:    c.BytesDownloaded,
+			LastError:          c.LastError,
+			IdleTimeoutSec:     int64(c.IdleTimeout.Seconds()),
 			Connected:          c.Connected,
 			OS:                 c.OS,
 			Arch:               c.Arch,
 			Shell:              c.Shell,
 			PackageManager:     c.PackageManager,
 			SudoNoninteractive: c.SudoNoninteractive,
+			Distro:             c.Distro,
+			DistroVersion:      c.DistroVersion,
+			InitSystem:         c.InitSystem,
+			ViaSession:         string(c.ViaSession),
+			HostKeyType:        c.HostKeyType,
+			HostKeyFingerprint: c.HostKeyFingerprint,
+			Rebooting:          c.Rebooting,
+			Defaults:           defaultsOutput(c.Defaults),
+		}
+		if !c.ExpiresAt.IsZero() {
+			sessions[i].ExpiresAt = c.ExpiresAt.Format(time.RFC3339)
+			sessions[i].Warning = expiryWarning(c.ExpiresAt.Sub(now))
 		}
 
 		// Include terminal sessions for this connection.
 		if deps.TermPool != nil {
-			infos := deps.TermPool.List(c.SessionID)
+			infos := deps.TermPool.List(owner, c.SessionID)
 			if len(infos) > 0 {
 				terminals := make([]TerminalInfoOutput, len(infos))
 				for j, info := range infos {
@@ -60,7 +85,7 @@ func HandleListSessions(_ context.Context, deps *SessionsDeps, _ SSHListSessions
 
 		// Include tunnel sessions for this connection.
 		if deps.TunnelPool != nil {
-			tInfos := deps.TunnelPool.List(string(c.SessionID))
+			tInfos := deps.TunnelPool.List(owner, string(c.SessionID))
 			if len(tInfos) > 0 {
 				tunnels := make([]TunnelInfoOutput, len(tInfos))
 				for j, info := range tInfos {
@@ -76,3 +101,15 @@ func HandleListSessions(_ context.Context, deps *SessionsDeps, _ SSHListSessions
 		Count:    len(sessions),
 	}, nil
 }
+
+// expiryWarning returns a warning for a session with remaining time left
+// before its max lifetime, or "" if that is outside expiryWarningWindow.
+func expiryWarning(remaining time.Duration) string {
+	if remaining > expiryWarningWindow {
+		return ""
+	}
+	if remaining <= 0 {
+		return "session has reached its max lifetime and is being closed; connect again with ssh_connect"
+	}
+	return fmt.Sprintf("session expires in %s (max lifetime); it will be closed even if in use, so connect again with ssh_connect before starting long work", remaining.Round(time.Second))
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// SignalDeps holds dependencies for the ssh_signal tool handler.
+type SignalDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+	Protected   []string // --protect-process name patterns
+}
+
+// signalNames are the signals ssh_signal sends.
+var signalNames = []string{"TERM", "INT", "HUP", "QUIT", "KILL", "USR1", "USR2", "STOP", "CONT"}
+
+// processUserPattern matches user names for pgrep -u.
+var processUserPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// HandleSignal implements the ssh_signal tool. A pid is signaled at once;
+// a name is matched like pkill (pgrep) and, unless confirm is set, only the
+// matching processes are returned. Either way the processes are looked up
+// first, and PID 1 and names matching --protect-process are refused. Every
+// command goes through the command filter.
+func HandleSignal(ctx context.Context, deps *SignalDeps, input SSHSignalInput) (*SSHSignalOutput, error) {
+	sig, err := parseSignal(input.Signal)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case (input.PID == 0) == (input.Name == ""):
+		return nil, fmt.Errorf("set exactly one of pid and name")
+	case input.PID < 0:
+		return nil, fmt.Errorf("pid must be positive (process groups are not supported)")
+	case input.PID == 1:
+		return nil, fmt.Errorf("refusing to signal PID 1")
+	case input.PID != 0 && (input.FullCommand || input.Exact || input.User != ""):
+		return nil, fmt.Errorf("full_command, exact and user only apply to name")
+	case input.User != "" && !processUserPattern.MatchString(input.User):
+		return nil, fmt.Errorf("invalid user %q", input.User)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_signal needs a POSIX host")
+	}
+	run := func(what, cmd string) (*remoteResult, error) {
+		res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return res, remoteFailure(what, res)
+		}
+		return res, nil
+	}
+
+	pids := []int{input.PID}
+	if input.Name != "" {
+		args := []string{}
+		if input.FullCommand {
+			args = append(args, "-f")
+		}
+		if input.Exact {
+			args = append(args, "-x")
+		}
+		if input.User != "" {
+			args = append(args, "-u", input.User)
+		}
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "pgrep", append(args, "--", input.Name)...)
+		if err != nil {
+			return nil, err
+		}
+		res, err := run("pgrep", cmd)
+		if res != nil && res.ExitCode == 1 && !res.TimedOut {
+			return nil, fmt.Errorf("no process matches %q", input.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pids, err = parsePIDs(res.Stdout); err != nil {
+			return nil, err
+		}
+	}
+
+	pidList := make([]string, len(pids))
+	for i, p := range pids {
+		pidList[i] = strconv.Itoa(p)
+	}
+	cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "ps", "-o", "pid=", "-o", "user=", "-o", "args=", "-p", strings.Join(pidList, ","))
+	if err != nil {
+		return nil, err
+	}
+	res, err := run("ps", cmd)
+	if res != nil && res.ExitCode == 1 && !res.TimedOut && strings.TrimSpace(res.Stdout) == "" {
+		return nil, fmt.Errorf("no process with PID %s", strings.Join(pidList, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	procs := parseProcesses(res.Stdout)
+	if len(procs) == 0 {
+		return nil, fmt.Errorf("no process with PID %s", strings.Join(pidList, ", "))
+	}
+	for _, p := range procs {
+		if err := checkProtected(p, deps.Protected); err != nil {
+			return nil, err
+		}
+	}
+
+	out := &SSHSignalOutput{Signal: sig, Processes: procs}
+	if input.Name != "" && !input.Confirm {
+		out.Message = fmt.Sprintf("%d process(es) match %q; call again with confirm: true to send SIG%s", len(procs), input.Name, sig)
+		return out, nil
+	}
+
+	// Signal exactly the processes shown, not whatever matches by now.
+	pidList = pidList[:0]
+	for _, p := range procs {
+		pidList = append(pidList, strconv.Itoa(p.PID))
+	}
+	cmd, err = buildCLICommand(deps.Filter, deps.Config, input.Sudo, "kill", append([]string{"-s", sig}, pidList...)...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := run("kill", cmd); err != nil {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	out.Signaled = true
+	out.Message = fmt.Sprintf("Sent SIG%s to %d process(es)", sig, len(procs))
+	return out, nil
+}
+
+// parseSignal normalizes a signal name ("term", "SIGTERM") to one of
+// signalNames; empty means TERM.
+func parseSignal(s string) (string, error) {
+	if s == "" {
+		return "TERM", nil
+	}
+	sig := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if !slices.Contains(signalNames, sig) {
+		return "", fmt.Errorf("unsupported signal %q (must be one of %s)", s, strings.Join(signalNames, ", "))
+	}
+	return sig, nil
+}
+
+// parsePIDs reads the PIDs pgrep prints, one per line.
+func parsePIDs(out string) ([]int, error) {
+	var pids []int
+	for _, f := range strings.Fields(out) {
+		pid, err := strconv.Atoi(f)
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("unexpected pgrep output %q", f)
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("pgrep returned no PIDs")
+	}
+	return pids, nil
+}
+
+// parseProcesses reads `ps -o pid= -o user= -o args=` output. The name is
+// the base name of the first word of the command line.
+func parseProcesses(out string) []ProcessInfo {
+	var procs []ProcessInfo
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, ProcessInfo{
+			PID:     pid,
+			User:    fields[1],
+			Name:    path.Base(fields[2]),
+			Command: strings.Join(fields[2:], " "),
+		})
+	}
+	return procs
+}
+
+// checkProtected refuses PID 1 and processes whose name matches one of the
+// --protect-process patterns (anchored regular expressions).
+func checkProtected(p ProcessInfo, patterns []string) error {
+	if p.PID == 1 {
+		return fmt.Errorf("refusing to signal PID 1 (%s)", p.Name)
+	}
+	name := strings.Trim(p.Name, "[]:")
+	for _, pat := range patterns {
+		re, err := regexp.Compile("^(?:" + pat + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid protected process pattern %q: %w", pat, err)
+		}
+		if re.MatchString(name) {
+			return fmt.Errorf("process %d (%s) is protected by --protect-process", p.PID, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	for in, want := range map[string]string{"": "TERM", "term": "TERM", "SIGKILL": "KILL", "hup": "HUP", "sigusr1": "USR1"} {
+		if got, err := parseSignal(in); err != nil || got != want {
+			t.Errorf("parseSignal(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"9", "SEGV", "TERM; reboot"} {
+		if _, err := parseSignal(bad); err == nil {
+			t.Errorf("parseSignal(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseProcesses(t *testing.T) {
+	out := "  812 www-data /usr/sbin/nginx -g daemon off;\n" +
+		"    2 root     [kthreadd]\n" +
+		"garbage\n"
+	procs := parseProcesses(out)
+	if len(procs) != 2 {
+		t.Fatalf("procs = %+v", procs)
+	}
+	if p := procs[0]; p.PID != 812 || p.User != "www-data" || p.Name != "nginx" || p.Command != "/usr/sbin/nginx -g daemon off;" {
+		t.Errorf("nginx = %+v", p)
+	}
+	if procs[1].Name != "[kthreadd]" {
+		t.Errorf("kthreadd name = %q", procs[1].Name)
+	}
+
+	if pids, err := parsePIDs("812\n913\n"); err != nil || len(pids) != 2 || pids[1] != 913 {
+		t.Errorf("parsePIDs = %v, %v", pids, err)
+	}
+	if _, err := parsePIDs("812\nx\n"); err == nil {
+		t.Error("parsePIDs should reject non-numeric output")
+	}
+}
+
+func TestCheckProtected(t *testing.T) {
+	patterns := []string{"sshd", "postgres.*"}
+	for _, tt := range []struct {
+		p    ProcessInfo
+		deny bool
+	}{
+		{ProcessInfo{PID: 1, Name: "systemd"}, true},
+		{ProcessInfo{PID: 400, Name: "sshd:"}, true},
+		{ProcessInfo{PID: 401, Name: "postgres"}, true},
+		{ProcessInfo{PID: 402, Name: "nginx"}, false},
+		{ProcessInfo{PID: 403, Name: "mysshd"}, false},
+	} {
+		if err := checkProtected(tt.p, patterns); (err != nil) != tt.deny {
+			t.Errorf("%+v: err = %v, want deny %v", tt.p, err, tt.deny)
+		}
+	}
+}
+
+func TestHandleSignal_Args(t *testing.T) {
+	for _, tt := range []struct {
+		input SSHSignalInput
+		want  string
+	}{
+		{SSHSignalInput{SessionID: "s"}, "exactly one"},
+		{SSHSignalInput{SessionID: "s", PID: 10, Name: "nginx"}, "exactly one"},
+		{SSHSignalInput{SessionID: "s", PID: -5}, "process groups"},
+		{SSHSignalInput{SessionID: "s", PID: 1}, "PID 1"},
+		{SSHSignalInput{SessionID: "s", PID: 10, Exact: true}, "only apply to name"},
+		{SSHSignalInput{SessionID: "s", Name: "nginx", User: "-root"}, "invalid user"},
+		{SSHSignalInput{SessionID: "s", PID: 10, Signal: "SEGV"}, "unsupported signal"},
+	} {
+		_, err := HandleSignal(context.Background(), &SignalDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestSSHSignalOutput_Text(t *testing.T) {
+	out := SSHSignalOutput{Signal: "TERM", Message: "2 process(es) match", Processes: []ProcessInfo{
+		{PID: 812, User: "www-data", Command: "nginx: master"},
+		{PID: 813, User: "www-data", Command: "nginx: worker"},
+	}}
+	if text := out.Text(); !strings.Contains(text, "\n  812 www-data nginx: master\n  813 www-data nginx: worker") {
+		t.Errorf("Text():\n%s", text)
+	}
+}
@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// CommandTemplateDeps holds dependencies for a --command-templates tool.
+type CommandTemplateDeps struct {
+	Execute   *ExecuteDeps
+	RunScript *RunScriptDeps
+	Hosts     *security.Filter // the template's hosts; nil = any host
+}
+
+// HandleCommandTemplate runs a --command-templates entry: args (the tool
+// input) are checked against the template's parameters. A command
+// template's command is rendered and goes through HandleExecute like any
+// ssh_execute command, command filter included; a script template goes
+// through HandleRunScript with the parameters as its arguments. The
+// session is the template's session_id, or else the one in args, and must
+// be on one of the template's hosts.
+func HandleCommandTemplate(ctx context.Context, deps *CommandTemplateDeps, tpl config.CommandTemplate, args map[string]any) (*SSHExecuteOutput, error) {
+	sessionID, _ := args["session_id"].(string)
+	switch {
+	case tpl.SessionID != "" && sessionID != "":
+		return nil, fmt.Errorf("%s always runs on %s; session_id is not accepted", tpl.Name, tpl.SessionID)
+	case tpl.SessionID != "":
+		sessionID = tpl.SessionID
+	case sessionID == "":
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if deps.Hosts != nil {
+		conn, err := deps.Execute.Pool.GetConnection(ctx, connection.SessionID(sessionID))
+		if err != nil {
+			return nil, err
+		}
+		if err := deps.Hosts.AllowHost(conn.Host); err != nil {
+			return nil, fmt.Errorf("%s cannot run on %s: %w", tpl.Name, conn.Host, err)
+		}
+	}
+	if tpl.Script != "" {
+		scriptArgs, err := templateArgs(tpl, args)
+		if err != nil {
+			return nil, err
+		}
+		return HandleRunScript(ctx, deps.RunScript, SSHRunScriptInput{
+			SessionID:   sessionID,
+			Script:      tpl.Script,
+			Interpreter: tpl.Interpreter,
+			Args:        scriptArgs,
+			Sudo:        tpl.Sudo,
+			Timeout:     tpl.Timeout,
+		})
+	}
+	command, err := RenderCommandTemplate(tpl, args)
+	if err != nil {
+		return nil, err
+	}
+	return HandleExecute(ctx, deps.Execute, SSHExecuteInput{
+		SessionID: sessionID,
+		Command:   command,
+		Sudo:      tpl.Sudo,
+		Timeout:   tpl.Timeout,
+	})
+}
+
+// RenderCommandTemplate replaces every {{param}} of tpl's command with the
+// value from args, or the parameter's default. Strings are checked against
+// pattern and enum and shell-quoted; integers against min and max; a
+// boolean becomes its flag when true and nothing when false ("true" or
+// "false" without a flag). Unknown arguments are rejected.
+func RenderCommandTemplate(tpl config.CommandTemplate, args map[string]any) (string, error) {
+	values, err := templateValues(tpl, args, true)
+	if err != nil {
+		return "", err
+	}
+	return config.TemplatePlaceholder.ReplaceAllStringFunc(tpl.Command, func(m string) string {
+		return values[config.TemplatePlaceholder.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+// templateArgs returns the script arguments of tpl: the parameter values
+// in declaration order, unquoted, leaving out booleans that render as
+// nothing.
+func templateArgs(tpl config.CommandTemplate, args map[string]any) ([]string, error) {
+	values, err := templateValues(tpl, args, false)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range tpl.Params {
+		if p.ParamType() == config.ParamBoolean && values[p.Name] == "" {
+			continue
+		}
+		out = append(out, values[p.Name])
+	}
+	return out, nil
+}
+
+// templateValues checks args against tpl's parameters and renders each,
+// by name; strings are shell-quoted if quote is set.
+func templateValues(tpl config.CommandTemplate, args map[string]any, quote bool) (map[string]string, error) {
+	for name := range args {
+		if name != "session_id" && !slices.ContainsFunc(tpl.Params, func(p config.TemplateParam) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	values := make(map[string]string, len(tpl.Params))
+	for _, p := range tpl.Params {
+		v, ok := args[p.Name]
+		if !ok || v == nil {
+			if p.Default == nil {
+				return nil, fmt.Errorf("%s is required", p.Name)
+			}
+			v = p.Default
+		}
+		s, err := renderTemplateParam(p, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		if quote && p.ParamType() == config.ParamString {
+			s = shellQuote(s)
+		}
+		values[p.Name] = s
+	}
+	return values, nil
+}
+
+// renderTemplateParam returns v, the JSON value of p, as text.
+func renderTemplateParam(p config.TemplateParam, v any) (string, error) {
+	switch p.ParamType() {
+	case config.ParamInteger:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return "", fmt.Errorf("must be an integer")
+		}
+		n := int64(f)
+		if p.Min != nil && n < *p.Min {
+			return "", fmt.Errorf("must be at least %d", *p.Min)
+		}
+		if p.Max != nil && n > *p.Max {
+			return "", fmt.Errorf("must be at most %d", *p.Max)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case config.ParamBoolean:
+		b, ok := v.(bool)
+		switch {
+		case !ok:
+			return "", fmt.Errorf("must be true or false")
+		case p.Flag == "":
+			return strconv.FormatBool(b), nil
+		case b:
+			return p.Flag, nil
+		default:
+			return "", nil
+		}
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("must be a string")
+		}
+		if strings.ContainsAny(s, "\x00\n\r") {
+			return "", fmt.Errorf("must not contain NUL or line breaks")
+		}
+		if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+			return "", fmt.Errorf("must be one of %s", strings.Join(p.Enum, ", "))
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+			if err != nil {
+				return "", err
+			}
+			if !re.MatchString(s) {
+				return "", fmt.Errorf("%q does not match %s", s, p.Pattern)
+			}
+		}
+		return s, nil
+	}
+}
+
+// CommandTemplateSchema returns the JSON schema of tpl's tool input:
+// session_id (unless the template fixes it) and one property per
+// parameter, required unless it has a default.
+func CommandTemplateSchema(tpl config.CommandTemplate) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	if tpl.SessionID == "" {
+		props["session_id"] = map[string]any{"type": "string", "description": "Session ID from ssh_connect"}
+		required = append(required, "session_id")
+	}
+	for _, p := range tpl.Params {
+		prop := map[string]any{"type": p.ParamType()}
+		if p.Description != "" {
+			prop["description"] = p.Description
+		}
+		if len(p.Enum) > 0 {
+			prop["enum"] = p.Enum
+		}
+		if p.Pattern != "" {
+			prop["pattern"] = `^(?:` + p.Pattern + `)$`
+		}
+		if p.Min != nil {
+			prop["minimum"] = *p.Min
+		}
+		if p.Max != nil {
+			prop["maximum"] = *p.Max
+		}
+		if p.Default != nil {
+			prop["default"] = p.Default
+		} else {
+			required = append(required, p.Name)
+		}
+		props[p.Name] = prop
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+)
+
+func int64Ptr(n int64) *int64 { return &n }
+
+var tailLog = config.CommandTemplate{
+	Name:    "tail_log",
+	Command: "tail -n {{ lines }} {{follow}} /var/log/{{file}} | grep {{match}}",
+	Params: []config.TemplateParam{
+		{Name: "file", Enum: []string{"syslog", "auth.log"}},
+		{Name: "lines", Type: config.ParamInteger, Min: int64Ptr(1), Max: int64Ptr(1000), Default: 100.0},
+		{Name: "follow", Type: config.ParamBoolean, Flag: "-F", Default: false},
+		{Name: "match", Pattern: `[^;]*`, Default: ""},
+	},
+}
+
+func TestRenderCommandTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"session_id": "s", "file": "syslog"}, "tail -n 100  /var/log/'syslog' | grep ''"},
+		{map[string]any{"file": "auth.log", "lines": 5.0, "follow": true, "match": "it's"}, `tail -n 5 -F /var/log/'auth.log' | grep 'it'\''s'`},
+		{map[string]any{"file": "syslog", "match": "$(reboot)"}, "tail -n 100  /var/log/'syslog' | grep '$(reboot)'"},
+	} {
+		got, err := RenderCommandTemplate(tailLog, tt.args)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	for name, args := range map[string]map[string]any{
+		"missing required": {"lines": 5.0},
+		"not in enum":      {"file": "../../etc/shadow"},
+		"fractional":       {"file": "syslog", "lines": 2.5},
+		"below min":        {"file": "syslog", "lines": 0.0},
+		"above max":        {"file": "syslog", "lines": 5000.0},
+		"integer string":   {"file": "syslog", "lines": "5"},
+		"boolean string":   {"file": "syslog", "follow": "yes"},
+		"pattern mismatch": {"file": "syslog", "match": "a; reboot"},
+		"newline":          {"file": "syslog", "match": "a\nreboot"},
+		"unknown param":    {"file": "syslog", "sudo": true},
+	} {
+		if _, err := RenderCommandTemplate(tailLog, args); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	noFlag := config.CommandTemplate{Command: "set-debug {{on}}", Params: []config.TemplateParam{{Name: "on", Type: config.ParamBoolean}}}
+	if got, _ := RenderCommandTemplate(noFlag, map[string]any{"on": true}); got != "set-debug true" {
+		t.Errorf("boolean without flag = %q", got)
+	}
+}
+
+func TestCommandTemplateSchema(t *testing.T) {
+	s := CommandTemplateSchema(tailLog)
+	if !slices.Equal(s["required"].([]string), []string{"session_id", "file"}) {
+		t.Errorf("required = %v", s["required"])
+	}
+	props := s["properties"].(map[string]any)
+	lines := props["lines"].(map[string]any)
+	if lines["type"] != "integer" || lines["minimum"] != int64(1) || lines["maximum"] != int64(1000) || lines["default"] != 100.0 {
+		t.Errorf("lines = %v", lines)
+	}
+	if p := props["match"].(map[string]any)["pattern"]; !strings.HasPrefix(p.(string), "^(?:") {
+		t.Errorf("match pattern = %v", p)
+	}
+	if !slices.Equal(props["file"].(map[string]any)["enum"].([]string), []string{"syslog", "auth.log"}) || s["additionalProperties"] != false {
+		t.Errorf("schema = %v", s)
+	}
+}
+
+func TestTemplateArgs(t *testing.T) {
+	got, err := templateArgs(tailLog, map[string]any{"file": "syslog", "match": "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"syslog", "100", "it's"}; !slices.Equal(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+	got, _ = templateArgs(tailLog, map[string]any{"file": "syslog", "follow": true})
+	if want := []string{"syslog", "100", "-F", ""}; !slices.Equal(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestHandleCommandTemplate_Session(t *testing.T) {
+	deps := &CommandTemplateDeps{Execute: &ExecuteDeps{}}
+	if _, err := HandleCommandTemplate(context.Background(), deps, tailLog, map[string]any{"file": "syslog"}); err == nil || !strings.Contains(err.Error(), "session_id is required") {
+		t.Errorf("missing session_id: %v", err)
+	}
+	fixed := tailLog
+	fixed.SessionID = "root@web1:22"
+	_, err := HandleCommandTemplate(context.Background(), deps, fixed, map[string]any{"session_id": "root@db1:22", "file": "syslog"})
+	if err == nil || !strings.Contains(err.Error(), "always runs on root@web1:22") {
+		t.Errorf("session_id on a fixed session: %v", err)
+	}
+	if s := CommandTemplateSchema(fixed); len(s["required"].([]string)) != 1 || s["properties"].(map[string]any)["session_id"] != nil {
+		t.Errorf("fixed session schema = %v", s)
+	}
+}
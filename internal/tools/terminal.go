@@ -49,7 +49,7 @@ func HandleOpenTerminal(ctx context.Context, deps *TerminalDeps, input SSHOpenTe
 
 	// Rate limit terminal open operations.
 	if deps.RateLimiter != nil {
-		if err := deps.RateLimiter.Allow(conn.Host); err != nil {
+		if err := deps.RateLimiter.Wait(ctx, conn.Host); err != nil {
 			return nil, err
 		}
 	}
@@ -83,7 +83,7 @@ func HandleOpenTerminal(ctx context.Context, deps *TerminalDeps, input SSHOpenTe
 		protectExit = false
 	}
 
-	ts, err := deps.TermPool.Open(connection.SessionID(input.SessionID), client, cols, rows, input.TermType, protectExit)
+	ts, err := deps.TermPool.Open(connection.OwnerFrom(ctx), connection.SessionID(input.SessionID), client, cols, rows, input.TermType, protectExit)
 	if err != nil {
 		return nil, fmt.Errorf("open terminal: %w", err)
 	}
@@ -110,7 +110,7 @@ func HandleSendInput(ctx context.Context, deps *TerminalDeps, input SSHSendInput
 		return nil, fmt.Errorf("either text or special_key must be provided")
 	}
 
-	ts, err := deps.TermPool.Get(connection.TerminalID(input.TerminalID))
+	ts, err := deps.TermPool.Get(connection.OwnerFrom(ctx), connection.TerminalID(input.TerminalID))
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +121,7 @@ func HandleSendInput(ctx context.Context, deps *TerminalDeps, input SSHSendInput
 		if connErr != nil {
 			return nil, fmt.Errorf("get connection for rate limit: %w", connErr)
 		}
-		if err := deps.RateLimiter.Allow(conn.Host); err != nil {
+		if err := deps.RateLimiter.Wait(ctx, conn.Host); err != nil {
 			return nil, err
 		}
 	}
@@ -166,7 +166,7 @@ func HandleReadOutput(ctx context.Context, deps *TerminalDeps, input SSHReadOutp
 		return nil, fmt.Errorf("terminal_id is required")
 	}
 
-	ts, err := deps.TermPool.Get(connection.TerminalID(input.TerminalID))
+	ts, err := deps.TermPool.Get(connection.OwnerFrom(ctx), connection.TerminalID(input.TerminalID))
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +189,7 @@ func HandleCloseTerminal(ctx context.Context, deps *TerminalDeps, input SSHClose
 		return nil, fmt.Errorf("terminal_id is required")
 	}
 
-	if err := deps.TermPool.Close(connection.TerminalID(input.TerminalID)); err != nil {
+	if err := deps.TermPool.Close(connection.OwnerFrom(ctx), connection.TerminalID(input.TerminalID)); err != nil {
 		return nil, err
 	}
 
@@ -197,3 +197,43 @@ func HandleCloseTerminal(ctx context.Context, deps *TerminalDeps, input SSHClose
 		Message: fmt.Sprintf("Terminal %s closed", input.TerminalID),
 	}, nil
 }
+
+// HandleTerminalScreenshot returns the current screen of a terminal as a
+// VT100/xterm terminal would show it, rendered from everything the PTY has
+// printed (see vt.Screen). Reading it does not consume buffered output.
+// With wait_ms it first waits that long, so a program can finish drawing.
+func HandleTerminalScreenshot(ctx context.Context, deps *TerminalDeps, input SSHTerminalScreenshotInput) (*SSHTerminalScreenshotOutput, error) {
+	if input.TerminalID == "" {
+		return nil, fmt.Errorf("terminal_id is required")
+	}
+	if input.WaitMs < 0 {
+		return nil, fmt.Errorf("wait_ms must not be negative")
+	}
+
+	ts, err := deps.TermPool.Get(connection.OwnerFrom(ctx), connection.TerminalID(input.TerminalID))
+	if err != nil {
+		return nil, err
+	}
+	if input.WaitMs > 0 {
+		timer := time.NewTimer(time.Duration(input.WaitMs) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	snap := ts.Screen()
+	return &SSHTerminalScreenshotOutput{
+		TerminalID:    input.TerminalID,
+		Cols:          snap.Cols,
+		Rows:          snap.Rows,
+		Lines:         snap.Lines,
+		CursorRow:     snap.CursorRow + 1,
+		CursorCol:     snap.CursorCol + 1,
+		CursorVisible: snap.CursorVisible,
+		AltScreen:     snap.AltScreen,
+		Title:         snap.Title,
+	}, nil
+}
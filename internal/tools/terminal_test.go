@@ -198,3 +198,40 @@ func TestHandleCloseTerminalMissing(t *testing.T) {
 		t.Fatal("expected error for unknown terminal, got nil")
 	}
 }
+
+// TestHandleTerminalScreenshot verifies the screenshot of a fresh terminal and
+// errors for unknown terminals and negative waits.
+func TestHandleTerminalScreenshot(t *testing.T) {
+	deps := &TerminalDeps{
+		Pool:     connection.NewPool(&config.SSHConfig{}, nil),
+		TermPool: connection.NewTerminalPool(0),
+	}
+	deps.TermPool.InsertForTest(&connection.TerminalSession{ID: "term-1"})
+
+	out, err := HandleTerminalScreenshot(context.Background(), deps, SSHTerminalScreenshotInput{TerminalID: "term-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Cols != 120 || out.Rows != 50 || len(out.Lines) != 50 || out.CursorRow != 1 || out.CursorCol != 1 {
+		t.Errorf("screenshot = %+v", out)
+	}
+	if _, err := HandleTerminalScreenshot(context.Background(), deps, SSHTerminalScreenshotInput{TerminalID: "term-999"}); err == nil {
+		t.Error("expected error for unknown terminal, got nil")
+	}
+	if _, err := HandleTerminalScreenshot(context.Background(), deps, SSHTerminalScreenshotInput{TerminalID: "term-1", WaitMs: -1}); err == nil {
+		t.Error("expected error for negative wait_ms, got nil")
+	}
+}
+
+// TestSSHTerminalScreenshotOutput_Text verifies the header and that trailing
+// blank rows are dropped.
+func TestSSHTerminalScreenshotOutput_Text(t *testing.T) {
+	out := SSHTerminalScreenshotOutput{
+		Cols: 20, Rows: 4, Lines: []string{"  PID USER", "    1 root", "", ""},
+		CursorRow: 4, CursorCol: 1, AltScreen: true, Title: "htop",
+	}
+	want := "Screen 20x4, alternate screen, cursor at row 4, col 1 (hidden), title \"htop\"\n  PID USER\n    1 root"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/transcript"
+)
+
+// Bounds on ssh_get_transcript's limit.
+const (
+	defaultTranscriptLimit = 20
+	maxTranscriptLimit     = 500
+)
+
+// TranscriptDeps holds dependencies for the ssh_get_transcript tool handler.
+type TranscriptDeps struct {
+	Recorder *transcript.Recorder
+}
+
+// HandleGetTranscript implements the ssh_get_transcript tool: the latest
+// recorded tool calls of a session, as made by the calling client. The
+// session need not be connected any more.
+func HandleGetTranscript(ctx context.Context, deps *TranscriptDeps, input SSHGetTranscriptInput) (*SSHGetTranscriptOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	limit := input.Limit
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("invalid limit: %d (must be non-negative)", limit)
+	case limit == 0:
+		limit = defaultTranscriptLimit
+	case limit > maxTranscriptLimit:
+		limit = maxTranscriptLimit
+	}
+
+	entries, total, err := deps.Recorder.Read(connection.OwnerFrom(ctx), input.SessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return &SSHGetTranscriptOutput{
+		SessionID: input.SessionID,
+		File:      deps.Recorder.Path(input.SessionID),
+		Total:     total,
+		Entries:   entries,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// TransferDeps holds dependencies for the ssh_transfer tool handler.
+type TransferDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// HandleTransfer implements the ssh_transfer tool. The file is streamed from
+// the source session's SFTP connection into an atomic write on the
+// destination session, so it never touches the MCP host's disk and is never
+// held in memory as a whole.
+func HandleTransfer(ctx context.Context, deps *TransferDeps, input SSHTransferInput) (*SSHTransferOutput, error) {
+	switch {
+	case input.SourceSessionID == "" || input.DestSessionID == "":
+		return nil, fmt.Errorf("source_session_id and dest_session_id are required")
+	case input.SourcePath == "" || input.DestPath == "":
+		return nil, fmt.Errorf("source_path and dest_path are required")
+	case input.SourceSessionID == input.DestSessionID:
+		return nil, fmt.Errorf("source and destination are the same session; use ssh_copy instead")
+	}
+	if err := security.ValidatePath(input.SourcePath); err != nil {
+		return nil, fmt.Errorf("invalid source path: %w", err)
+	}
+	if err := security.ValidatePath(input.DestPath); err != nil {
+		return nil, fmt.Errorf("invalid destination path: %w", err)
+	}
+
+	// Each session passes its own host's rate limit.
+	srcConn, srcClient, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SourceSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("source session: %w", err)
+	}
+	dstConn, dstClient, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.DestSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("destination session: %w", err)
+	}
+
+	srcSC, err := sshclient.NewSFTPClient(srcClient, srcConn.SFTPTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("source session: %w", err)
+	}
+	defer srcSC.Close()
+	dstSC, err := sshclient.NewSFTPClient(dstClient, dstConn.SFTPTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("destination session: %w", err)
+	}
+	defer dstSC.Close()
+
+	src := expandRemotePath(srcConn, srcSC, input.SourcePath)
+	dst := expandRemotePath(dstConn, dstSC, input.DestPath)
+
+	srcInfo, err := srcSC.Stat(src)
+	if err != nil {
+		srcConn.SetLastError(err)
+		return nil, fmt.Errorf("stat source %s: %w", src, err)
+	}
+	if srcInfo.IsDir() {
+		return nil, fmt.Errorf("source %s is a directory; bundle it with ssh_archive and transfer the archive", src)
+	}
+	if dstInfo, err := dstSC.Stat(dst); err == nil {
+		if dstInfo.IsDir() {
+			return nil, fmt.Errorf("destination %s is an existing directory; give the full path of the file", dst)
+		}
+		if !input.Overwrite {
+			return nil, fmt.Errorf("destination %s already exists (set overwrite to replace it)", dst)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) && !os.IsNotExist(err) {
+		dstConn.SetLastError(err)
+		return nil, fmt.Errorf("stat destination %s: %w", dst, err)
+	}
+
+	file, err := srcSC.Open(src)
+	if err != nil {
+		srcConn.SetLastError(err)
+		return nil, fmt.Errorf("open source %s: %w", src, err)
+	}
+	defer file.Close()
+
+	r := &readCounter{r: sshclient.NewContextReader(ctx, file)}
+	n, err := sshclient.WriteFileAtomicFrom(dstSC, dst, r, srcInfo.Mode().Perm())
+	srcConn.AddBytesDownloaded(r.n)
+	if err != nil {
+		dstConn.SetLastError(err)
+		return nil, fmt.Errorf("transfer %s: %w", src, err)
+	}
+	dstConn.AddBytesUploaded(n)
+
+	return &SSHTransferOutput{
+		SourceSessionID: input.SourceSessionID,
+		SourcePath:      src,
+		DestSessionID:   input.DestSessionID,
+		DestPath:        dst,
+		BytesCopied:     n,
+	}, nil
+}
+
+// readCounter counts the bytes read through it.
+type readCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
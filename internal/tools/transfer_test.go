@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+func TestHandleTransfer_Validation(t *testing.T) {
+	valid := SSHTransferInput{
+		SourceSessionID: "a@one:22", SourcePath: "/tmp/f",
+		DestSessionID: "b@two:22", DestPath: "/tmp/f",
+	}
+	tests := []struct {
+		name   string
+		modify func(*SSHTransferInput)
+		want   string
+	}{
+		{"missing source session", func(in *SSHTransferInput) { in.SourceSessionID = "" }, "required"},
+		{"missing dest path", func(in *SSHTransferInput) { in.DestPath = "" }, "required"},
+		{"same session", func(in *SSHTransferInput) { in.DestSessionID = in.SourceSessionID }, "use ssh_copy"},
+		{"source traversal", func(in *SSHTransferInput) { in.SourcePath = "/tmp/../etc/shadow" }, "invalid source path"},
+		{"dest traversal", func(in *SSHTransferInput) { in.DestPath = "/tmp/../etc/passwd" }, "invalid destination path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.modify(&input)
+			_, err := HandleTransfer(context.Background(), &TransferDeps{}, input)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &readCounter{r: sshclient.NewContextReader(ctx, strings.NewReader("hello world"))}
+	buf := make([]byte, 5)
+	if n, err := r.Read(buf); n != 5 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	cancel()
+	if _, err := io.ReadAll(r); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled after cancel, got %v", err)
+	}
+	if r.n != 5 {
+		t.Errorf("counted %d bytes, want 5", r.n)
+	}
+}
+
+func TestSSHTransferOutput_Text(t *testing.T) {
+	out := SSHTransferOutput{
+		SourceSessionID: "a@one:22", SourcePath: "/var/dump.sql",
+		DestSessionID: "b@two:22", DestPath: "/srv/dump.sql", BytesCopied: 42,
+	}
+	if got := out.Text(); got != "Transferred a@one:22:/var/dump.sql to b@two:22:/srv/dump.sql (42 bytes)" {
+		t.Errorf("Text() = %q", got)
+	}
+}
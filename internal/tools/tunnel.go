@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
@@ -34,7 +38,7 @@ func HandleTunnelCreate(ctx context.Context, deps *TunnelDeps, input SSHTunnelCr
 		return nil, err
 	}
 
-	ts, err := deps.TunnelPool.Open(input.SessionID, client, input.LocalPort, input.RemoteAddr)
+	ts, err := deps.TunnelPool.Open(connection.OwnerFrom(ctx), input.SessionID, client, input.LocalPort, input.RemoteAddr)
 	if err != nil {
 		return nil, fmt.Errorf("create tunnel: %w", err)
 	}
@@ -48,9 +52,136 @@ func HandleTunnelCreate(ctx context.Context, deps *TunnelDeps, input SSHTunnelCr
 	}, nil
 }
 
+// dbEngine describes how to reach and address a database engine.
+type dbEngine struct {
+	scheme      string // URL scheme of the connection string
+	defaultPort int
+}
+
+// dbEngines maps the engine names accepted by ssh_db_tunnel (and aliases).
+var dbEngines = map[string]dbEngine{
+	"postgres":   {scheme: "postgres", defaultPort: 5432},
+	"mysql":      {scheme: "mysql", defaultPort: 3306},
+	"mariadb":    {scheme: "mysql", defaultPort: 3306},
+	"redis":      {scheme: "redis", defaultPort: 6379},
+	"mongodb":    {scheme: "mongodb", defaultPort: 27017},
+	"sqlserver":  {scheme: "sqlserver", defaultPort: 1433},
+	"clickhouse": {scheme: "clickhouse", defaultPort: 9000},
+}
+
+// dbEngineAliases maps common alternative spellings to dbEngines keys.
+var dbEngineAliases = map[string]string{
+	"postgresql": "postgres",
+	"pg":         "postgres",
+	"mongo":      "mongodb",
+	"mssql":      "sqlserver",
+}
+
+// lookupDBEngine resolves an engine name (case-insensitive, aliases allowed).
+func lookupDBEngine(name string) (string, dbEngine, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := dbEngineAliases[name]; ok {
+		name = alias
+	}
+	engine, ok := dbEngines[name]
+	if !ok {
+		names := make([]string, 0, len(dbEngines))
+		for n := range dbEngines {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return "", dbEngine{}, fmt.Errorf("unsupported engine %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return name, engine, nil
+}
+
+// dbConnectionString builds a URL-style DSN for engine pointing at localAddr.
+// Credentials are percent-encoded; the database goes in the path except for
+// SQL Server, which takes it as a query parameter.
+func dbConnectionString(engine dbEngine, localAddr, user, password, database string) string {
+	u := &url.URL{Scheme: engine.scheme, Host: localAddr}
+	switch {
+	case user != "" && password != "":
+		u.User = url.UserPassword(user, password)
+	case user != "":
+		u.User = url.User(user)
+	case password != "":
+		// Redis AUTH with only a password: redis://:password@host:port.
+		u.User = url.UserPassword("", password)
+	}
+	query := url.Values{}
+	switch engine.scheme {
+	case "sqlserver":
+		if database != "" {
+			query.Set("database", database)
+		}
+	case "mongodb":
+		// Replica set discovery would try to reach members directly,
+		// bypassing the tunnel.
+		query.Set("directConnection", "true")
+		fallthrough
+	default:
+		if database != "" {
+			u.Path = "/" + database
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// HandleDBTunnel opens a local forward to a database port on (or reachable
+// from) the remote host and returns a ready-to-use connection string.
+// The tunnel is closed together with the session by ssh_disconnect.
+func HandleDBTunnel(ctx context.Context, deps *TunnelDeps, input SSHDBTunnelInput) (*SSHDBTunnelOutput, error) {
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if input.Engine == "" {
+		return nil, fmt.Errorf("engine is required")
+	}
+	name, engine, err := lookupDBEngine(input.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if input.RemotePort < 0 || input.RemotePort > 65535 {
+		return nil, fmt.Errorf("invalid remote_port: %d (must be 1-65535)", input.RemotePort)
+	}
+	if input.LocalPort < 0 || input.LocalPort > 65535 {
+		return nil, fmt.Errorf("invalid local_port: %d (must be 0-65535)", input.LocalPort)
+	}
+	remoteHost := input.RemoteHost
+	if remoteHost == "" {
+		remoteHost = "127.0.0.1"
+	}
+	remotePort := input.RemotePort
+	if remotePort == 0 {
+		remotePort = engine.defaultPort
+	}
+	remoteAddr := net.JoinHostPort(remoteHost, strconv.Itoa(remotePort))
+
+	_, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := deps.TunnelPool.Open(connection.OwnerFrom(ctx), input.SessionID, client, input.LocalPort, remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("create tunnel: %w", err)
+	}
+
+	return &SSHDBTunnelOutput{
+		TunnelID:         string(ts.ID),
+		Engine:           name,
+		LocalAddr:        ts.LocalAddr,
+		LocalPort:        ts.LocalPort,
+		RemoteAddr:       ts.RemoteAddr,
+		ConnectionString: dbConnectionString(engine, ts.LocalAddr, input.User, input.Password, input.Database),
+	}, nil
+}
+
 // HandleTunnelList lists active tunnels, optionally filtered by session ID.
-func HandleTunnelList(_ context.Context, deps *TunnelDeps, input SSHTunnelListInput) (*SSHTunnelListOutput, error) {
-	infos := deps.TunnelPool.List(input.SessionID)
+func HandleTunnelList(ctx context.Context, deps *TunnelDeps, input SSHTunnelListInput) (*SSHTunnelListOutput, error) {
+	infos := deps.TunnelPool.List(connection.OwnerFrom(ctx), input.SessionID)
 
 	tunnels := make([]TunnelInfoOutput, len(infos))
 	for i, info := range infos {
@@ -64,12 +195,12 @@ func HandleTunnelList(_ context.Context, deps *TunnelDeps, input SSHTunnelListIn
 }
 
 // HandleTunnelClose closes an active tunnel.
-func HandleTunnelClose(_ context.Context, deps *TunnelDeps, input SSHTunnelCloseInput) (*SSHTunnelCloseOutput, error) {
+func HandleTunnelClose(ctx context.Context, deps *TunnelDeps, input SSHTunnelCloseInput) (*SSHTunnelCloseOutput, error) {
 	if input.TunnelID == "" {
 		return nil, fmt.Errorf("tunnel_id is required")
 	}
 
-	if err := deps.TunnelPool.Close(tunnel.TunnelID(input.TunnelID)); err != nil {
+	if err := deps.TunnelPool.Close(connection.OwnerFrom(ctx), tunnel.TunnelID(input.TunnelID)); err != nil {
 		return nil, err
 	}
 
@@ -1,31 +1,50 @@
 package tools
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/transcript"
 )
 
 // SSHConnectInput is the input for the ssh_connect tool.
 type SSHConnectInput struct {
-	Host     string `json:"host" jsonschema:"Required. SSH host — hostname, host:port, user@host, or user:password@host:port. This is the only required field, all others are optional and auto-discovered."`
-	Port     int    `json:"port,omitempty" jsonschema:"Optional. SSH port override (default 22)"`
-	User     string `json:"user,omitempty" jsonschema:"Optional. SSH username override (default: current OS user)"`
-	Password string `json:"password,omitempty" jsonschema:"Optional. SSH password override"`
-	KeyPath  string `json:"key_path,omitempty" jsonschema:"Optional. Path to SSH private key (default: auto-discovered from ~/.ssh/)"`
+	Host            string            `json:"host" jsonschema:"Required. SSH host — hostname, host:port, user@host, or user:password@host:port. This is the only required field, all others are optional and auto-discovered."`
+	Port            int               `json:"port,omitempty" jsonschema:"Optional. SSH port override (default 22)"`
+	User            string            `json:"user,omitempty" jsonschema:"Optional. SSH username override (default: current OS user)"`
+	Password        string            `json:"password,omitempty" jsonschema:"Optional. SSH password override"`
+	KeyPath         string            `json:"key_path,omitempty" jsonschema:"Optional. Path to SSH private key (default: auto-discovered from ~/.ssh/)"`
+	IdleTimeout     int               `json:"idle_timeout,omitempty" jsonschema:"Optional. Seconds the session may sit idle before the connection is closed (it is transparently reconnected on next use). Default from server config."`
+	MaxLifetime     int               `json:"max_lifetime,omitempty" jsonschema:"Optional. Seconds after connecting at which the session is closed for good, regardless of activity. Can only shorten the server's --max-session-lifetime"`
+	SaveCredentials bool              `json:"save_credentials,omitempty" jsonschema:"Optional. Save the password in the server's credential store after a successful connect so later connects to this user@host:port can omit it (requires --credential-store)"`
+	ViaSession      string            `json:"via_session,omitempty" jsonschema:"Optional. Session ID of an existing session to use as a jump host: the connection is opened from that host, like ProxyJump, so the target only needs to be reachable from there"`
+	Env             map[string]string `json:"env,omitempty" jsonschema:"Optional. Environment variables exported for every ssh_execute on this session, e.g. {\"RAILS_ENV\": \"production\"}. Added to those of an earlier connect and of the server's --host-defaults. Variables that change how programs load or find code (PATH, LD_PRELOAD, BASH_ENV and the like) are refused. POSIX hosts only"`
+	WorkingDir      string            `json:"working_dir,omitempty" jsonschema:"Optional. Working directory for every ssh_execute on this session that doesn't set its own"`
+	Shell           string            `json:"shell,omitempty" jsonschema:"Optional. Shell for every ssh_execute on this session that doesn't set its own (sh, bash, dash, zsh, ksh, mksh or ash, by name or path, or 'detected'). POSIX hosts only"`
 }
 
 // SSHConnectOutput is the output for the ssh_connect tool.
 type SSHConnectOutput struct {
-	SessionID          string `json:"session_id"`
-	Host               string `json:"host"`
-	Port               int    `json:"port"`
-	User               string `json:"user"`
-	Message            string `json:"message"`
-	OS                 string `json:"os,omitempty"`
-	Arch               string `json:"arch,omitempty"`
-	Shell              string `json:"shell,omitempty"`
-	PackageManager     string `json:"package_manager,omitempty"`
-	SudoNoninteractive bool   `json:"sudo_noninteractive,omitempty"`
+	SessionID          string                 `json:"session_id"`
+	Host               string                 `json:"host"`
+	Port               int                    `json:"port"`
+	User               string                 `json:"user"`
+	Message            string                 `json:"message"`
+	OS                 string                 `json:"os,omitempty"`
+	Arch               string                 `json:"arch,omitempty"`
+	Shell              string                 `json:"shell,omitempty"`
+	PackageManager     string                 `json:"package_manager,omitempty"`
+	SudoNoninteractive bool                   `json:"sudo_noninteractive,omitempty"`
+	Distro             string                 `json:"distro,omitempty"`
+	DistroVersion      string                 `json:"distro_version,omitempty"`
+	InitSystem         string                 `json:"init_system,omitempty"`
+	ExpiresAt          string                 `json:"expires_at,omitempty"`
+	HostKeyType        string                 `json:"host_key_type,omitempty"`
+	HostKeyFingerprint string                 `json:"host_key_fingerprint,omitempty"`
+	Defaults           *SessionDefaultsOutput `json:"defaults,omitempty"`
 }
 
 // Text returns a human-readable representation of the connect result.
@@ -35,12 +54,32 @@ func (o SSHConnectOutput) Text() string {
 
 // SSHExecuteInput is the input for the ssh_execute tool.
 type SSHExecuteInput struct {
-	SessionID    string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
-	Command      string `json:"command" jsonschema:"Command to execute"`
-	Timeout      int    `json:"timeout,omitempty" jsonschema:"Command timeout in seconds (default from config)"`
-	Sudo         bool   `json:"sudo,omitempty" jsonschema:"Execute with sudo"`
-	SudoPassword string `json:"sudo_password,omitempty" jsonschema:"Password for sudo (command is executed via 'sudo -S sh -c ...')"`
-	WorkingDir   string `json:"working_dir,omitempty" jsonschema:"Working directory for command execution"`
+	SessionID        string       `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Command          string       `json:"command" jsonschema:"Command to execute"`
+	Timeout          int          `json:"timeout,omitempty" jsonschema:"Command timeout in seconds (default from config)"`
+	Sudo             bool         `json:"sudo,omitempty" jsonschema:"Execute with sudo"`
+	SudoPassword     string       `json:"sudo_password,omitempty" jsonschema:"Password for sudo (command is executed via 'sudo -S sh -c ...')"`
+	WorkingDir       string       `json:"working_dir,omitempty" jsonschema:"Working directory for command execution (default: the session's working_dir from ssh_connect, if any)"`
+	Shell            string       `json:"shell,omitempty" jsonschema:"Shell to run the command with, by name or path (sh, bash, dash, zsh, ksh, mksh or ash, e.g. /bin/zsh), or 'detected' for the user's login shell as detected on connect (default: the session's shell from ssh_connect, if any). POSIX hosts only"`
+	RunAs            string       `json:"run_as,omitempty" jsonschema:"Run the command as this user (must be allowed by --run-as-users). Uses 'sudo -u' (sudo_password is passed to sudo) or 'su - <user> -c' per run_as_method. POSIX hosts only"`
+	RunAsMethod      string       `json:"run_as_method,omitempty" jsonschema:"How run_as switches user: sudo (default) or su. su cannot prompt for a password, so it only works where su needs none (e.g. when connected as root)"`
+	LoginShell       bool         `json:"login_shell,omitempty" jsonschema:"Run the command through a login shell (e.g. 'bash -lc') so profile files set up PATH and tools like nvm, pyenv or environment modules. Uses shell if set, otherwise the detected shell. POSIX hosts only"`
+	SaveSudoPassword bool         `json:"save_sudo_password,omitempty" jsonschema:"Save sudo_password in the server's credential store after the command succeeds so later sudo calls on this host can omit it (requires --credential-store)"`
+	StripANSI        *bool        `json:"strip_ansi,omitempty" jsonschema:"Remove colours, cursor movement and other escape sequences, and collapse carriage-return/erase-line redraws so progress bars (apt, pip, docker pull) show only their final state (default true). Set false to get the raw bytes"`
+	Locale           string       `json:"locale,omitempty" jsonschema:"Set LANG and LC_ALL to this locale (e.g. C.UTF-8) so messages, dates and numbers come out in a predictable form. POSIX hosts only (default from --locale)"`
+	Term             string       `json:"term,omitempty" jsonschema:"Set TERM (e.g. dumb to discourage colours and pagers). POSIX hosts only (default from --term)"`
+	Encoding         string       `json:"encoding,omitempty" jsonschema:"Encoding of the command's output, converted to UTF-8: auto (UTF-8, else the server's fallback encoding) or a name like latin1, windows-1251, cp932 (default from server config)"`
+	KillGrace        *int         `json:"kill_grace,omitempty" jsonschema:"Seconds the command gets after SIGINT and SIGTERM before SIGKILL on timeout or cancellation, for cleanup such as removing lock files (0-300, 0 = SIGKILL at once; default from --kill-grace, 7)"`
+	Retries          *int         `json:"retries,omitempty" jsonschema:"Retry up to this many times (0-10) when the connection fails before the command reports an exit status, reconnecting with exponential backoff. A lost connection may have run the command partly, so use it for idempotent commands. Non-zero exit codes, timeouts and cancellations are never retried (default from --execute-retries, 0)"`
+	Expect           []ExpectStep `json:"expect,omitempty" jsonschema:"Answer prompts: steps run in order, each waiting for its pattern in the output and then writing its response to stdin. stdin is closed after the last step. A step that times out stops the command. At most 20 steps"`
+	ParseJSON        bool         `json:"parse_json,omitempty" jsonschema:"Parse stdout as JSON (one value, or JSON Lines as an array) and return it as structured content in 'json', for commands like docker inspect or kubectl -o json. If stdout isn't valid JSON the result is plain text with json_error saying why"`
+}
+
+// ExpectStep is a prompt ssh_execute waits for and the response it sends.
+type ExpectStep struct {
+	Pattern string `json:"pattern" jsonschema:"Regular expression (Go RE2 syntax) matched against stdout and stderr printed since the previous step, e.g. '\\[y/N\\]'"`
+	Send    string `json:"send,omitempty" jsonschema:"Response written to stdin when the pattern matches; include \\n to press Enter (\\n, \\r and \\t escapes are expanded)"`
+	Timeout int    `json:"timeout,omitempty" jsonschema:"Seconds to wait for the pattern (default: until the command times out)"`
 }
 
 // SSHExecuteOutput is the output for the ssh_execute tool.
@@ -49,6 +88,29 @@ type SSHExecuteOutput struct {
 	Stderr     string `json:"stderr"`
 	ExitCode   int    `json:"exit_code"`
 	DurationMs int64  `json:"duration_ms"`
+	// TimedOut and Cancelled mean the command was stopped before it
+	// finished; Stdout and Stderr hold what it printed until then.
+	TimedOut  bool `json:"timed_out"`
+	Cancelled bool `json:"cancelled,omitempty"`
+	// Encoding is the charset the output was converted from to UTF-8;
+	// empty if it was UTF-8 already.
+	Encoding string `json:"encoding,omitempty"`
+	// Attempts is how many times the command was run, when retried.
+	Attempts int `json:"attempts,omitempty"`
+	// ExpectMatched is how many expect steps matched, when there were any.
+	ExpectMatched int `json:"expect_matched,omitempty"`
+	// JSON is stdout parsed for parse_json; JSONError says why it could
+	// not be.
+	JSON      json.RawMessage `json:"json,omitempty"`
+	JSONError string          `json:"json_error,omitempty"`
+}
+
+// Structured returns the structured content of a parse_json result: the
+// output with the parsed JSON in place of stdout, which the text content
+// still carries.
+func (o SSHExecuteOutput) Structured() SSHExecuteOutput {
+	o.Stdout = ""
+	return o
 }
 
 // Text returns a human-readable representation of the execute result.
@@ -69,10 +131,19 @@ func (o SSHExecuteOutput) Text() string {
 			b.WriteString("\n")
 		}
 		fmt.Fprintf(&b, "Exit code: %d", o.ExitCode)
+		if o.TimedOut || o.Cancelled {
+			fmt.Fprintf(&b, " (stopped after %dms, output is partial)", o.DurationMs)
+		}
 	}
 	if b.Len() == 0 {
 		fmt.Fprintf(&b, "Completed (exit code %d, %dms)", o.ExitCode, o.DurationMs)
 	}
+	if o.Attempts > 1 {
+		fmt.Fprintf(&b, "\n[after %d attempts; earlier ones lost the connection]", o.Attempts)
+	}
+	if o.JSONError != "" {
+		fmt.Fprintf(&b, "\n[parse_json] %s", o.JSONError)
+	}
 	return b.String()
 }
 
@@ -91,6 +162,14 @@ func (o SSHDisconnectOutput) Text() string {
 	return o.Message
 }
 
+// SessionDefaultsOutput describes the ssh_execute defaults of a session.
+// Env holds only the variable names; their values are never reported.
+type SessionDefaultsOutput struct {
+	WorkingDir string   `json:"working_dir,omitempty"`
+	Shell      string   `json:"shell,omitempty"`
+	Env        []string `json:"env,omitempty"`
+}
+
 // SSHListSessionsOutput is the output for the ssh_list_sessions tool.
 type SSHListSessionsOutput struct {
 	Sessions []SessionInfo `json:"sessions"`
@@ -99,21 +178,37 @@ type SSHListSessionsOutput struct {
 
 // SessionInfo provides information about an active session.
 type SessionInfo struct {
-	SessionID          string               `json:"session_id"`
-	Host               string               `json:"host"`
-	Port               int                  `json:"port"`
-	User               string               `json:"user"`
-	ConnectedAt        string               `json:"connected_at"`
-	LastUsed           string               `json:"last_used"`
-	CommandCount       int                  `json:"command_count"`
-	Connected          bool                 `json:"connected"`
-	OS                 string               `json:"os,omitempty"`
-	Arch               string               `json:"arch,omitempty"`
-	Shell              string               `json:"shell,omitempty"`
-	PackageManager     string               `json:"package_manager,omitempty"`
-	SudoNoninteractive bool                 `json:"sudo_noninteractive,omitempty"`
-	Terminals          []TerminalInfoOutput `json:"terminals,omitempty"`
-	Tunnels            []TunnelInfoOutput   `json:"tunnels,omitempty"`
+	SessionID          string                 `json:"session_id"`
+	Host               string                 `json:"host"`
+	Port               int                    `json:"port"`
+	User               string                 `json:"user"`
+	ConnectedAt        string                 `json:"connected_at"`
+	LastUsed           string                 `json:"last_used"`
+	CommandCount       int                    `json:"command_count"`
+	FailedCommands     int                    `json:"failed_commands"`
+	CommandTimeMs      int64                  `json:"command_time_ms"`
+	BytesUploaded      int64                  `json:"bytes_uploaded"`
+	BytesDownloaded    int64                  `json:"bytes_downloaded"`
+	LastError          string                 `json:"last_error,omitempty"`
+	IdleTimeoutSec     int64                  `json:"idle_timeout_sec,omitempty"`
+	ExpiresAt          string                 `json:"expires_at,omitempty"`
+	Warning            string                 `json:"warning,omitempty"`
+	Connected          bool                   `json:"connected"`
+	OS                 string                 `json:"os,omitempty"`
+	Arch               string                 `json:"arch,omitempty"`
+	Shell              string                 `json:"shell,omitempty"`
+	PackageManager     string                 `json:"package_manager,omitempty"`
+	SudoNoninteractive bool                   `json:"sudo_noninteractive,omitempty"`
+	Distro             string                 `json:"distro,omitempty"`
+	DistroVersion      string                 `json:"distro_version,omitempty"`
+	InitSystem         string                 `json:"init_system,omitempty"`
+	ViaSession         string                 `json:"via_session,omitempty"`
+	HostKeyType        string                 `json:"host_key_type,omitempty"`
+	HostKeyFingerprint string                 `json:"host_key_fingerprint,omitempty"`
+	Rebooting          bool                   `json:"rebooting,omitempty"`
+	Terminals          []TerminalInfoOutput   `json:"terminals,omitempty"`
+	Tunnels            []TunnelInfoOutput     `json:"tunnels,omitempty"`
+	Defaults           *SessionDefaultsOutput `json:"defaults,omitempty"`
 }
 
 // Text returns a human-readable representation of the sessions list.
@@ -125,27 +220,66 @@ func (o SSHListSessionsOutput) Text() string {
 	fmt.Fprintf(&b, "Active sessions (%d):\n", o.Count)
 	for _, s := range o.Sessions {
 		status := "connected"
-		if !s.Connected {
+		if s.Rebooting {
+			status = "rebooting"
+		} else if !s.Connected {
 			status = "disconnected"
 		}
-		line := fmt.Sprintf("  %s — %s, %d commands, last used %s", s.SessionID, status, s.CommandCount, s.LastUsed)
+		line := fmt.Sprintf("  %s — %s, %d commands", s.SessionID, status, s.CommandCount)
+		if s.FailedCommands > 0 {
+			line += fmt.Sprintf(" (%d failed)", s.FailedCommands)
+		}
+		if s.CommandTimeMs > 0 {
+			line += fmt.Sprintf(", %dms command time", s.CommandTimeMs)
+		}
+		if s.BytesUploaded > 0 || s.BytesDownloaded > 0 {
+			line += fmt.Sprintf(", %d bytes up / %d bytes down", s.BytesUploaded, s.BytesDownloaded)
+		}
+		if s.ViaSession != "" {
+			line += ", via " + s.ViaSession
+		}
+		line += ", last used " + s.LastUsed
+		if s.IdleTimeoutSec > 0 {
+			line += fmt.Sprintf(", idle timeout %ds", s.IdleTimeoutSec)
+		}
+		if s.ExpiresAt != "" {
+			line += ", expires " + s.ExpiresAt
+		}
 		if s.OS != "" {
 			detail := s.OS
 			if s.Arch != "" {
 				detail += " " + s.Arch
 			}
+			if s.Distro != "" {
+				detail += ", " + strings.TrimSpace(s.Distro+" "+s.DistroVersion)
+			}
 			if s.Shell != "" {
 				detail += ", " + s.Shell
 			}
 			if s.PackageManager != "" {
 				detail += ", pkg=" + s.PackageManager
 			}
+			if s.InitSystem != "" {
+				detail += ", init=" + s.InitSystem
+			}
 			if s.SudoNoninteractive {
 				detail += ", sudo-n"
 			}
 			line += fmt.Sprintf(" [%s]", detail)
 		}
 		b.WriteString(line + "\n")
+		if s.HostKeyFingerprint != "" {
+			fmt.Fprintf(&b, "    host key: %s %s\n", s.HostKeyType, s.HostKeyFingerprint)
+		}
+		if s.Defaults != nil {
+			fmt.Fprintf(&b, "    defaults: %s\n", describeDefaults(s.Defaults))
+		}
+		if s.LastError != "" {
+			fmt.Fprintf(&b, "    last error: %s\n", s.LastError)
+		}
+		if s.Warning != "" {
+			fmt.Fprintf(&b, "    warning: %s\n", s.Warning)
+		}
 		for _, t := range s.Terminals {
 			fmt.Fprintf(&b, "    terminal %s — created %s, last used %s\n", t.TerminalID, t.CreatedAt, t.LastUsed)
 		}
@@ -156,64 +290,433 @@ func (o SSHListSessionsOutput) Text() string {
 	return strings.TrimRight(b.String(), "\n")
 }
 
+// SSHUsageInput is the input for the ssh_usage tool.
+type SSHUsageInput struct {
+	SessionID string `json:"session_id,omitempty" jsonschema:"Only report this session (default: all of your sessions)"`
+}
+
+// SSHUsageOutput is the output for the ssh_usage tool.
+type SSHUsageOutput struct {
+	RateLimit        int             `json:"rate_limit"` // requests per minute per host
+	RateLimitFileOps bool            `json:"rate_limit_file_ops"`
+	RateLimitWaitMs  int64           `json:"rate_limit_wait_ms,omitempty"`
+	MaxConcurrent    int             `json:"max_concurrent,omitempty"`
+	Hosts            []HostRateLimit `json:"hosts"`
+	Sessions         []SessionUsage  `json:"sessions"`
+}
+
+// HostRateLimit is the rate limit budget left on one host.
+type HostRateLimit struct {
+	Host      string `json:"host"`
+	Burst     int    `json:"burst"`
+	Remaining int    `json:"remaining"`
+	NextInMs  int64  `json:"next_in_ms,omitempty"`
+}
+
+// SessionUsage holds the usage counters of one session.
+type SessionUsage struct {
+	SessionID       string `json:"session_id"`
+	Host            string `json:"host"`
+	CommandCount    int    `json:"command_count"`
+	FailedCommands  int    `json:"failed_commands"`
+	ActiveCommands  int    `json:"active_commands"`
+	BytesUploaded   int64  `json:"bytes_uploaded"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+}
+
+// Text returns a human-readable representation of the usage report.
+func (o SSHUsageOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rate limit: %d requests/min per host", o.RateLimit)
+	if o.RateLimitFileOps {
+		b.WriteString(", file operations included")
+	} else {
+		b.WriteString(", file operations not counted")
+	}
+	if o.RateLimitWaitMs > 0 {
+		fmt.Fprintf(&b, ", calls over the limit wait up to %s", time.Duration(o.RateLimitWaitMs)*time.Millisecond)
+	}
+	b.WriteString("\n")
+	if o.MaxConcurrent > 0 {
+		fmt.Fprintf(&b, "Concurrent tool calls: at most %d\n", o.MaxConcurrent)
+	}
+	if len(o.Sessions) == 0 {
+		b.WriteString("No active sessions")
+		return b.String()
+	}
+	b.WriteString("Hosts:\n")
+	for _, h := range o.Hosts {
+		fmt.Fprintf(&b, "  %s — %d of %d requests available", h.Host, h.Remaining, h.Burst)
+		if h.Remaining == 0 {
+			fmt.Fprintf(&b, ", next in %dms", h.NextInMs)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Sessions (%d):\n", len(o.Sessions))
+	for _, s := range o.Sessions {
+		fmt.Fprintf(&b, "  %s — %d commands", s.SessionID, s.CommandCount)
+		if s.FailedCommands > 0 {
+			fmt.Fprintf(&b, " (%d failed)", s.FailedCommands)
+		}
+		if s.ActiveCommands > 0 {
+			fmt.Fprintf(&b, ", %d running", s.ActiveCommands)
+		}
+		fmt.Fprintf(&b, ", %d bytes up / %d bytes down\n", s.BytesUploaded, s.BytesDownloaded)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// SSHServerInfoInput is the input for ssh_server_info (empty, no parameters needed).
+type SSHServerInfoInput struct{}
+
+// SSHServerInfoOutput is the output for the ssh_server_info tool.
+type SSHServerInfoOutput struct {
+	Name          string          `json:"name"`
+	Version       string          `json:"version"`
+	Tenant        string          `json:"tenant,omitempty"` // set when serving a --tenants entry
+	Tools         []string        `json:"tools"`
+	DisabledTools []string        `json:"disabled_tools,omitempty"`
+	Preset        string          `json:"preset,omitempty"` // omitted for the full preset
+	Categories    []string        `json:"disabled_categories,omitempty"`
+	ReadOnly      bool            `json:"read_only"` // every enabled tool is read-only
+	Security      SecurityPosture `json:"security"`
+	Transport     TransportInfo   `json:"transport"`
+}
+
+// SecurityPosture summarizes the server's security settings.
+type SecurityPosture struct {
+	SudoAllowed       bool     `json:"sudo_allowed"`
+	AgentForwarding   bool     `json:"agent_forwarding_allowed"`
+	RunAsUsers        []string `json:"run_as_users,omitempty"`
+	TerminalAllowed   bool     `json:"terminal_allowed"`
+	TunnelsAllowed    bool     `json:"tunnels_allowed"`
+	HostKeyVerified   bool     `json:"host_key_verified"`
+	HostAllowlist     bool     `json:"host_allowlist"`
+	HostDenylist      bool     `json:"host_denylist"`
+	CommandAllowlist  bool     `json:"command_allowlist"`
+	CommandDenylist   bool     `json:"command_denylist"`
+	ParseCommands     bool     `json:"parse_commands"`
+	PolicyWebhook     bool     `json:"policy_webhook"`
+	RateLimit         int      `json:"rate_limit"`
+	MaxFileSize       int64    `json:"max_file_size"`          // 0 = unlimited
+	MaxOutputSize     int      `json:"max_output_size"`        // 0 = unlimited
+	LocalDirs         []string `json:"local_dirs,omitempty"`   // read-only ones end in ":ro"
+	UploadModes       string   `json:"upload_modes,omitempty"` // e.g. "files 0640, umask 0022"; empty = local modes
+	CommandTimeoutSec int      `json:"command_timeout_sec"`
+}
+
+// TransportInfo describes how clients reach the server.
+type TransportInfo struct {
+	Stdio          bool   `json:"stdio"`
+	HTTP           bool   `json:"http"`
+	HTTPEndpoint   string `json:"http_endpoint,omitempty"`
+	HTTPAuth       bool   `json:"http_auth"`
+	SharedSessions bool   `json:"shared_sessions"`
+}
+
+// Text returns a human-readable representation of the server info.
+func (o SSHServerInfoOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", o.Name, o.Version)
+	if o.Tenant != "" {
+		fmt.Fprintf(&b, "Tenant: %s\n", o.Tenant)
+	}
+	fmt.Fprintf(&b, "Tools (%d): %s\n", len(o.Tools), strings.Join(o.Tools, ", "))
+	if len(o.DisabledTools) > 0 {
+		fmt.Fprintf(&b, "Disabled: %s\n", strings.Join(o.DisabledTools, ", "))
+	}
+	if o.Preset != "" {
+		fmt.Fprintf(&b, "Preset: %s\n", o.Preset)
+	}
+	if len(o.Categories) > 0 {
+		fmt.Fprintf(&b, "Disabled categories: %s\n", strings.Join(o.Categories, ", "))
+	}
+	if o.ReadOnly {
+		b.WriteString("Read-only: every enabled tool only reads\n")
+	}
+
+	sec := o.Security
+	onOff := func(on bool, what string) string {
+		if on {
+			return what + " allowed"
+		}
+		return what + " disabled"
+	}
+	posture := []string{onOff(sec.SudoAllowed, "sudo"), onOff(sec.TerminalAllowed, "terminal"), onOff(sec.TunnelsAllowed, "tunnels")}
+	if sec.AgentForwarding {
+		posture = append(posture, "agent forwarding allowed")
+	}
+	if len(sec.RunAsUsers) > 0 {
+		posture = append(posture, "run_as: "+strings.Join(sec.RunAsUsers, ", "))
+	}
+	if !sec.HostKeyVerified {
+		posture = append(posture, "host keys not verified")
+	}
+	fmt.Fprintf(&b, "Security: %s\n", strings.Join(posture, ", "))
+
+	var filters []string
+	for _, f := range []struct {
+		on   bool
+		name string
+	}{
+		{sec.HostAllowlist, "host allowlist"},
+		{sec.HostDenylist, "host denylist"},
+		{sec.CommandAllowlist, "command allowlist"},
+		{sec.CommandDenylist, "command denylist"},
+		{sec.ParseCommands, "shell parsing"},
+		{sec.PolicyWebhook, "policy webhook"},
+	} {
+		if f.on {
+			filters = append(filters, f.name)
+		}
+	}
+	if len(filters) == 0 {
+		filters = []string{"none"}
+	}
+	fmt.Fprintf(&b, "Filters: %s\n", strings.Join(filters, ", "))
+
+	limit := func(n int64) string {
+		if n == 0 {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%d bytes", n)
+	}
+	fmt.Fprintf(&b, "Limits: %d requests/min per host, command timeout %ds, max file size %s, max output %s\n",
+		sec.RateLimit, sec.CommandTimeoutSec, limit(sec.MaxFileSize), limit(int64(sec.MaxOutputSize)))
+	if len(sec.LocalDirs) > 0 {
+		fmt.Fprintf(&b, "Local files: restricted to %s\n", strings.Join(sec.LocalDirs, ", "))
+	}
+	if sec.UploadModes != "" {
+		fmt.Fprintf(&b, "Upload modes: %s\n", sec.UploadModes)
+	}
+
+	var transports []string
+	if o.Transport.Stdio {
+		transports = append(transports, "stdio")
+	}
+	if o.Transport.HTTP {
+		t := o.Transport.HTTPEndpoint
+		if o.Transport.HTTPAuth {
+			t += " (bearer token)"
+		}
+		if o.Transport.SharedSessions {
+			t += " (shared sessions)"
+		}
+		transports = append(transports, t)
+	}
+	fmt.Fprintf(&b, "Transport: %s", strings.Join(transports, ", "))
+	return b.String()
+}
+
+// SSHKeygenInput is the input for the ssh_keygen tool.
+type SSHKeygenInput struct {
+	KeyType   string `json:"key_type,omitempty" jsonschema:"Key type: ed25519 (default), ecdsa, or rsa"`
+	Bits      int    `json:"bits,omitempty" jsonschema:"Key size: RSA 2048-8192 (default 4096), ECDSA 256/384/521 (default 256); ignored for ed25519"`
+	Path      string `json:"path,omitempty" jsonschema:"Private key file path; relative paths are placed under the local base dir (or ~/.ssh). Default: mcp_id_<key_type>. The public key is written to <path>.pub"`
+	Comment   string `json:"comment,omitempty" jsonschema:"Public key comment (default ssh-mcp)"`
+	Overwrite bool   `json:"overwrite,omitempty" jsonschema:"Replace existing key files (default false)"`
+}
+
+// SSHKeygenOutput is the output for the ssh_keygen tool.
+type SSHKeygenOutput struct {
+	PrivateKeyPath string `json:"private_key_path"`
+	PublicKeyPath  string `json:"public_key_path"`
+	PublicKey      string `json:"public_key"`
+	Fingerprint    string `json:"fingerprint"`
+	Message        string `json:"message"`
+}
+
+// Text returns a human-readable representation of the keygen result.
+func (o SSHKeygenOutput) Text() string {
+	return o.Message + "\n" + o.PublicKey
+}
+
+// SSHDeployKeyInput is the input for the ssh_deploy_key tool.
+type SSHDeployKeyInput struct {
+	SessionID     string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	PublicKey     string `json:"public_key,omitempty" jsonschema:"Public key in authorized_keys format (e.g. 'ssh-ed25519 AAAA... comment')"`
+	PublicKeyPath string `json:"public_key_path,omitempty" jsonschema:"Local path to a .pub file (alternative to public_key)"`
+}
+
+// SSHDeployKeyOutput is the output for the ssh_deploy_key tool.
+type SSHDeployKeyOutput struct {
+	AuthorizedKeysPath string `json:"authorized_keys_path"`
+	Fingerprint        string `json:"fingerprint"`
+	AlreadyPresent     bool   `json:"already_present"`
+	Message            string `json:"message"`
+}
+
+// Text returns a human-readable representation of the deploy key result.
+func (o SSHDeployKeyOutput) Text() string {
+	return o.Message
+}
+
 // SSHUploadInput is the input for the ssh_upload tool.
 type SSHUploadInput struct {
-	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
-	LocalPath  string `json:"local_path" jsonschema:"Local file or directory path to upload"`
-	RemotePath string `json:"remote_path" jsonschema:"Remote destination path"`
+	SessionID     string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	LocalPath     string `json:"local_path" jsonschema:"Local file or directory path to upload"`
+	RemotePath    string `json:"remote_path" jsonschema:"Remote destination path"`
+	PreserveOwner bool   `json:"preserve_owner,omitempty" jsonschema:"Give remote files and directories the same numeric UID/GID as the local ones (needs root or CAP_CHOWN on the remote host; not available over scp)"`
+	Symlinks      string `json:"symlinks,omitempty" jsonschema:"Symlinks inside an uploaded directory: skip (default), follow (upload what they point to; loops are skipped), or preserve (recreate the links)"`
+	Verify        bool   `json:"verify,omitempty" jsonschema:"After the copy, compare SHA-256 checksums of every file on both ends (remote sha256sum, or reading the files back over SFTP) and report mismatches; not available over scp"`
+	CheckSpace    bool   `json:"check_space,omitempty" jsonschema:"Before writing anything, check that the remote filesystem has room for the upload (statvfs@openssh.com, or df) and fail early if not"`
+	Parallel      int    `json:"parallel,omitempty" jsonschema:"Files of a directory upload copied at once, 1-32 (default: the server's --transfer-workers); 1 copies them one at a time; not available over scp"`
 }
 
 // SSHUploadOutput is the output for the ssh_upload tool.
 type SSHUploadOutput struct {
-	FilesUploaded int    `json:"files_uploaded"`
-	BytesWritten  int64  `json:"bytes_written"`
-	Message       string `json:"message"`
+	FilesUploaded  int      `json:"files_uploaded"`
+	BytesWritten   int64    `json:"bytes_written"`
+	Protocol       string   `json:"protocol,omitempty"` // "scp" when SFTP was unavailable or disabled
+	OwnerPreserved bool     `json:"owner_preserved,omitempty"`
+	Skipped        []string `json:"skipped,omitempty"` // "path (reason)" for entries left out
+	Verified       int      `json:"verified,omitempty"`
+	VerifyMethod   string   `json:"verify_method,omitempty"` // set when verify was requested
+	Mismatches     []string `json:"mismatches,omitempty"`    // files whose checksums differ
+	SpaceCheck     string   `json:"space_check,omitempty"`   // free-space preflight summary
+	Message        string   `json:"message"`
 }
 
 // Text returns a human-readable representation of the upload result.
 func (o SSHUploadOutput) Text() string {
-	return o.Message
+	msg := o.Message
+	if o.OwnerPreserved {
+		msg += " (local ownership preserved)"
+	}
+	if o.SpaceCheck != "" {
+		msg += "\nSpace check: " + o.SpaceCheck
+	}
+	return msg + skippedText(o.Skipped) + verifyText(o.VerifyMethod, o.Verified, o.Mismatches)
 }
 
 // SSHDownloadInput is the input for the ssh_download tool.
 type SSHDownloadInput struct {
 	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
 	RemotePath string `json:"remote_path" jsonschema:"Remote file or directory path to download"`
-	LocalPath  string `json:"local_path" jsonschema:"Local destination path"`
+	LocalPath  string `json:"local_path,omitempty" jsonschema:"Local destination path (required unless inline is set)"`
+	Inline     bool   `json:"inline,omitempty" jsonschema:"Return the content of a single small file (up to 1 MiB, or --max-file-size if smaller) in the result instead of writing local_path: as text if it is UTF-8, else base64. For clients that can't reach the MCP host's disk"`
+	Symlinks   string `json:"symlinks,omitempty" jsonschema:"Symlinks inside a downloaded directory: skip (default), follow (download what they point to; loops are skipped), or preserve (recreate the links locally)"`
+	Verify     bool   `json:"verify,omitempty" jsonschema:"After the copy, compare SHA-256 checksums of every file on both ends (remote sha256sum, or reading the files back over SFTP) and report mismatches; not available over scp"`
+	Parallel   int    `json:"parallel,omitempty" jsonschema:"Files of a directory download copied at once, 1-32 (default: the server's --transfer-workers); 1 copies them one at a time; not available over scp"`
 }
 
 // SSHDownloadOutput is the output for the ssh_download tool.
 type SSHDownloadOutput struct {
-	FilesDownloaded int    `json:"files_downloaded"`
-	BytesRead       int64  `json:"bytes_read"`
-	Message         string `json:"message"`
+	FilesDownloaded int      `json:"files_downloaded"`
+	BytesRead       int64    `json:"bytes_read"`
+	Protocol        string   `json:"protocol,omitempty"` // "scp" when SFTP was unavailable or disabled
+	Skipped         []string `json:"skipped,omitempty"`  // "path (reason)" for entries left out
+	Verified        int      `json:"verified,omitempty"`
+	VerifyMethod    string   `json:"verify_method,omitempty"`    // set when verify was requested
+	Mismatches      []string `json:"mismatches,omitempty"`       // files whose checksums differ
+	Content         string   `json:"content,omitempty"`          // inline downloads only
+	ContentEncoding string   `json:"content_encoding,omitempty"` // InlineText or InlineBase64
+	SHA256          string   `json:"sha256,omitempty"`           // of the inline content
+	Message         string   `json:"message"`
 }
 
 // Text returns a human-readable representation of the download result.
 func (o SSHDownloadOutput) Text() string {
-	return o.Message
+	if o.ContentEncoding != "" {
+		return fmt.Sprintf("%s\nSHA-256: %s\n\n%s", o.Message, o.SHA256, o.Content)
+	}
+	return o.Message + skippedText(o.Skipped) + verifyText(o.VerifyMethod, o.Verified, o.Mismatches)
+}
+
+// skippedText lists the entries a directory transfer left out.
+func skippedText(skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nSkipped %d entries:\n  %s", len(skipped), strings.Join(skipped, "\n  "))
+}
+
+// SSHFetchURLInput is the input for the ssh_fetch_url tool.
+type SSHFetchURLInput struct {
+	URL        string `json:"url" jsonschema:"HTTPS URL to download; its domain (and that of every redirect) must be allowed by --fetch-allow-domain"`
+	SessionID  string `json:"session_id,omitempty" jsonschema:"Session ID from ssh_connect; required with remote_path"`
+	RemotePath string `json:"remote_path,omitempty" jsonschema:"Write the download to this path on the remote host, streamed over SFTP"`
+	LocalPath  string `json:"local_path,omitempty" jsonschema:"Write the download to this path on the MCP host instead (within --local-base-dir when set)"`
+	SHA256     string `json:"sha256,omitempty" jsonschema:"Expected SHA-256 of the download (hex); on a mismatch nothing is written"`
+}
+
+// SSHFetchURLOutput is the output for the ssh_fetch_url tool.
+type SSHFetchURLOutput struct {
+	URL         string `json:"url"`
+	FinalURL    string `json:"final_url,omitempty"` // after redirects, if different
+	RemotePath  string `json:"remote_path,omitempty"`
+	LocalPath   string `json:"local_path,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	SHA256      string `json:"sha256"`
+	Verified    bool   `json:"verified,omitempty"` // sha256 was given and matched
+	ContentType string `json:"content_type,omitempty"`
+	Message     string `json:"message"`
+}
+
+// Text returns a human-readable representation of the fetch result.
+func (o SSHFetchURLOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	if o.FinalURL != "" {
+		fmt.Fprintf(&b, "\nRedirected to: %s", o.FinalURL)
+	}
+	fmt.Fprintf(&b, "\nSHA-256: %s", o.SHA256)
+	if o.Verified {
+		b.WriteString(" (verified)")
+	}
+	if o.ContentType != "" {
+		fmt.Fprintf(&b, "\nContent-Type: %s", o.ContentType)
+	}
+	return b.String()
 }
 
 // SSHEditFileInput is the input for the ssh_edit_file tool.
 type SSHEditFileInput struct {
-	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
-	RemotePath string `json:"remote_path" jsonschema:"Remote file path to edit"`
-	Mode       string `json:"mode,omitempty" jsonschema:"Edit mode: replace (full content) or patch (find and replace)"`
-	Content    string `json:"content,omitempty" jsonschema:"Full file content (for replace mode)"`
-	OldString  string `json:"old_string,omitempty" jsonschema:"String to find (for patch mode)"`
-	NewString  string `json:"new_string,omitempty" jsonschema:"String to replace with (for patch mode)"`
-	Backup     *bool  `json:"backup,omitempty" jsonschema:"Create .bak backup before editing (default true)"`
+	SessionID     string     `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath    string     `json:"remote_path" jsonschema:"Remote file path to edit"`
+	Mode          string     `json:"mode,omitempty" jsonschema:"Edit mode: replace (full content), patch (find and replace), lines (edit by line number), append (add bytes to the end in place), or write_at (overwrite bytes at offset in place)"`
+	Content       string     `json:"content,omitempty" jsonschema:"Full file content (for replace mode), the lines to insert/append/replace with (for lines mode), or the bytes to write (for append and write_at modes)"`
+	OldString     string     `json:"old_string,omitempty" jsonschema:"String to find (for patch mode)"`
+	NewString     string     `json:"new_string,omitempty" jsonschema:"String to replace with (for patch mode)"`
+	ReplaceAll    bool       `json:"replace_all,omitempty" jsonschema:"Replace every occurrence of old_string instead of only the first (for patch mode)"`
+	ExpectedCount *int       `json:"expected_count,omitempty" jsonschema:"Fail unless old_string occurs exactly this many times (for patch mode), e.g. 1 to require a unique match"`
+	Edits         []FileEdit `json:"edits,omitempty" jsonschema:"Multiple find-and-replace edits for patch mode, applied in order in one write; the file is left unchanged if any old_string is not found. Use instead of old_string/new_string"`
+	Operation     string     `json:"operation,omitempty" jsonschema:"Lines mode operation: insert (after start_line; 0 = top of file), replace (lines start_line..end_line with content), delete (lines start_line..end_line), or append (to end of file)"`
+	StartLine     int        `json:"start_line,omitempty" jsonschema:"First line (1-based) for lines mode; for insert, the line to insert after"`
+	EndLine       int        `json:"end_line,omitempty" jsonschema:"Last line (inclusive) for lines mode replace/delete (default start_line)"`
+	Offset        *int64     `json:"offset,omitempty" jsonschema:"Byte offset for write_at mode (0-based, at most the file size)"`
+	Backup        *bool      `json:"backup,omitempty" jsonschema:"Back up the file before editing (default true); naming and location follow the server's backup settings, see ssh_restore_backup. Not used by the in-place append and write_at modes"`
+	ExpectedHash  string     `json:"expected_hash,omitempty" jsonschema:"SHA-256 of the file as last read (sha256 from ssh_read_file or a previous ssh_edit_file); the edit fails with a conflict instead of overwriting if the file has changed since"`
+	LockFile      bool       `json:"lock_file,omitempty" jsonschema:"Also hold a lock file (.<name>.ssh-mcp.lock next to the file) on the remote host during the edit, for editors outside this server; fails if another editor holds a fresh one"`
+}
+
+// FileEdit is a single find-and-replace edit for ssh_edit_file patch mode.
+type FileEdit struct {
+	OldString     string `json:"old_string" jsonschema:"String to find"`
+	NewString     string `json:"new_string" jsonschema:"String to replace with"`
+	ReplaceAll    bool   `json:"replace_all,omitempty" jsonschema:"Replace every occurrence instead of only the first"`
+	ExpectedCount *int   `json:"expected_count,omitempty" jsonschema:"Fail unless old_string occurs exactly this many times"`
 }
 
 // SSHEditFileOutput is the output for the ssh_edit_file tool.
 type SSHEditFileOutput struct {
 	BytesWritten int64  `json:"bytes_written"`
+	Replacements int    `json:"replacements,omitempty"` // patch mode only
+	BackupPath   string `json:"backup_path,omitempty"`
+	SHA256       string `json:"sha256,omitempty"` // new content; not set by append and write_at
 	Message      string `json:"message"`
 }
 
 // Text returns a human-readable representation of the edit result.
 func (o SSHEditFileOutput) Text() string {
-	return o.Message
+	msg := o.Message
+	if o.SHA256 != "" {
+		msg += "\nSHA-256: " + o.SHA256
+	}
+	if o.BackupPath != "" {
+		msg += "\nBackup: " + o.BackupPath
+	}
+	return msg
 }
 
 // SSHReadFileInput is the input for the ssh_read_file tool.
@@ -223,6 +726,7 @@ type SSHReadFileInput struct {
 	Offset     int    `json:"offset,omitempty" jsonschema:"Line offset to start reading from (1-based, default 1)"`
 	Limit      int    `json:"limit,omitempty" jsonschema:"Maximum number of lines to return (default 0 = all lines)"`
 	MaxSize    int64  `json:"max_size,omitempty" jsonschema:"Maximum file size in bytes (default from server config, 0=unlimited)"`
+	Encoding   string `json:"encoding,omitempty" jsonschema:"Encoding of the file, converted to UTF-8: auto (UTF-8, UTF-16 with BOM, else the server's fallback encoding) or a name like latin1, windows-1251, cp932 (default from server config)"`
 }
 
 // SSHReadFileOutput is the output for the ssh_read_file tool.
@@ -232,11 +736,46 @@ type SSHReadFileOutput struct {
 	FileSize   int64  `json:"file_size"`
 	FromLine   int    `json:"from_line"`
 	ToLine     int    `json:"to_line"`
+	Encoding   string `json:"encoding,omitempty"` // source charset if not UTF-8
+	SHA256     string `json:"sha256"`             // of the whole file, for ssh_edit_file expected_hash
 	Message    string `json:"message"`
 }
 
 // Text returns a human-readable representation of the read file result.
 func (o SSHReadFileOutput) Text() string {
+	msg := o.Message
+	if o.SHA256 != "" {
+		msg += "\nSHA-256: " + o.SHA256
+	}
+	if o.Content == "" {
+		return msg
+	}
+	return msg + "\n" + o.Content
+}
+
+// SSHFileHeadTailInput is the input for the ssh_file_head and ssh_file_tail tools.
+type SSHFileHeadTailInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath string `json:"remote_path" jsonschema:"Remote file path to read"`
+	Lines      int    `json:"lines,omitempty" jsonschema:"Number of lines to return (default 10, max 10000)"`
+	Bytes      int64  `json:"bytes,omitempty" jsonschema:"Return this many bytes instead of lines (max 1 MiB)"`
+	Encoding   string `json:"encoding,omitempty" jsonschema:"Encoding of the file, converted to UTF-8: auto (default from server config) or a name like latin1, windows-1251, cp932"`
+}
+
+// SSHFileHeadTailOutput is the output for the ssh_file_head and ssh_file_tail tools.
+type SSHFileHeadTailOutput struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	FileSize  int64  `json:"file_size"`
+	Lines     int    `json:"lines"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"` // output capped at 1 MiB
+	Encoding  string `json:"encoding,omitempty"`  // source charset if not UTF-8
+	Message   string `json:"message"`
+}
+
+// Text returns a human-readable representation of the head/tail result.
+func (o SSHFileHeadTailOutput) Text() string {
 	if o.Content == "" {
 		return o.Message
 	}
@@ -417,3 +956,1629 @@ type SSHTunnelCloseOutput struct {
 func (o SSHTunnelCloseOutput) Text() string {
 	return o.Message
 }
+
+// SSHDBTunnelInput is the input for the ssh_db_tunnel tool.
+type SSHDBTunnelInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Engine     string `json:"engine" jsonschema:"Database engine: postgres, mysql, mariadb, redis, mongodb, sqlserver, clickhouse"`
+	RemoteHost string `json:"remote_host,omitempty" jsonschema:"Database host as seen from the SSH server (default 127.0.0.1)"`
+	RemotePort int    `json:"remote_port,omitempty" jsonschema:"Database port (default: the engine's standard port)"`
+	LocalPort  int    `json:"local_port,omitempty" jsonschema:"Local port to listen on (0 = auto-assign a free port)"`
+	Database   string `json:"database,omitempty" jsonschema:"Database name (Redis: database number) to put in the connection string"`
+	User       string `json:"user,omitempty" jsonschema:"Database user to put in the connection string"`
+	Password   string `json:"password,omitempty" jsonschema:"Database password to put in the connection string (returned in the output)"`
+}
+
+// SSHDBTunnelOutput is the output for the ssh_db_tunnel tool.
+type SSHDBTunnelOutput struct {
+	TunnelID         string `json:"tunnel_id"`
+	Engine           string `json:"engine"`
+	LocalAddr        string `json:"local_addr"`
+	LocalPort        int    `json:"local_port"`
+	RemoteAddr       string `json:"remote_addr"`
+	ConnectionString string `json:"connection_string"`
+}
+
+// Text returns a human-readable representation of the database tunnel result.
+func (o SSHDBTunnelOutput) Text() string {
+	return fmt.Sprintf("%s tunnel %s: %s → %s\nConnection string: %s\nClose with ssh_tunnel_close (closed automatically on ssh_disconnect).",
+		o.Engine, o.TunnelID, o.LocalAddr, o.RemoteAddr, o.ConnectionString)
+}
+
+// SSHDockerPsInput is the input for the ssh_docker_ps tool.
+type SSHDockerPsInput struct {
+	SessionID string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	All       bool     `json:"all,omitempty" jsonschema:"Include stopped containers"`
+	Filters   []string `json:"filters,omitempty" jsonschema:"docker ps filters as key=value (e.g. status=running, name=web, label=app=api)"`
+	Sudo      bool     `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// DockerContainer describes a container in ssh_docker_ps output.
+type DockerContainer struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Image     string `json:"image"`
+	Command   string `json:"command"`
+	State     string `json:"state"`
+	Status    string `json:"status"`
+	Ports     string `json:"ports,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SSHDockerPsOutput is the output for the ssh_docker_ps tool.
+type SSHDockerPsOutput struct {
+	Containers []DockerContainer `json:"containers"`
+}
+
+// Text returns a human-readable representation of the container list.
+func (o SSHDockerPsOutput) Text() string {
+	if len(o.Containers) == 0 {
+		return "No containers"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d container(s):\n", len(o.Containers))
+	for _, c := range o.Containers {
+		fmt.Fprintf(&b, "  %s  %s  %s  [%s] %s", c.ID, c.Name, c.Image, c.State, c.Status)
+		if c.Ports != "" {
+			fmt.Fprintf(&b, "  ports: %s", c.Ports)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SSHDockerImagesInput is the input for the ssh_docker_images tool.
+type SSHDockerImagesInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Reference string `json:"reference,omitempty" jsonschema:"Only list images matching this repository[:tag]"`
+	All       bool   `json:"all,omitempty" jsonschema:"Include intermediate images"`
+	Sudo      bool   `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// DockerImage describes an image in ssh_docker_images output.
+type DockerImage struct {
+	ID         string `json:"id"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Size       string `json:"size"`
+	Created    string `json:"created"`
+}
+
+// SSHDockerImagesOutput is the output for the ssh_docker_images tool.
+type SSHDockerImagesOutput struct {
+	Images []DockerImage `json:"images"`
+}
+
+// Text returns a human-readable representation of the image list.
+func (o SSHDockerImagesOutput) Text() string {
+	if len(o.Images) == 0 {
+		return "No images"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d image(s):\n", len(o.Images))
+	for _, img := range o.Images {
+		fmt.Fprintf(&b, "  %s  %s:%s  %s  (created %s)\n", img.ID, img.Repository, img.Tag, img.Size, img.Created)
+	}
+	return b.String()
+}
+
+// SSHDockerInspectInput is the input for the ssh_docker_inspect tool.
+type SSHDockerInspectInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Target    string `json:"target" jsonschema:"Container or image name/ID"`
+	Sudo      bool   `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// SSHDockerInspectOutput is the output for the ssh_docker_inspect tool.
+type SSHDockerInspectOutput struct {
+	Target string `json:"target"`
+	JSON   string `json:"json"`
+}
+
+// Text returns the raw docker inspect JSON.
+func (o SSHDockerInspectOutput) Text() string {
+	return o.JSON
+}
+
+// SSHDockerLogsInput is the input for the ssh_docker_logs tool.
+type SSHDockerLogsInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Container  string `json:"container" jsonschema:"Container name or ID"`
+	Tail       int    `json:"tail,omitempty" jsonschema:"Number of lines from the end of the log (default 100)"`
+	Since      string `json:"since,omitempty" jsonschema:"Only logs since this time (e.g. 10m, 2h, 2024-01-02T15:04:05)"`
+	Timestamps bool   `json:"timestamps,omitempty" jsonschema:"Prefix each line with its timestamp"`
+	Sudo       bool   `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// SSHDockerLogsOutput is the output for the ssh_docker_logs tool.
+type SSHDockerLogsOutput struct {
+	Container string `json:"container"`
+	Lines     int    `json:"lines"`
+	Logs      string `json:"logs"`
+}
+
+// Text returns a human-readable representation of the container logs.
+func (o SSHDockerLogsOutput) Text() string {
+	if o.Logs == "" {
+		return fmt.Sprintf("%s: no log output", o.Container)
+	}
+	return fmt.Sprintf("%s: %d line(s)\n%s", o.Container, o.Lines, o.Logs)
+}
+
+// SSHDockerExecInput is the input for the ssh_docker_exec tool.
+type SSHDockerExecInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Container  string `json:"container" jsonschema:"Container name or ID"`
+	Command    string `json:"command" jsonschema:"Command to run inside the container (via sh -c)"`
+	User       string `json:"user,omitempty" jsonschema:"User (name or uid[:gid]) inside the container"`
+	WorkingDir string `json:"working_dir,omitempty" jsonschema:"Working directory inside the container"`
+	Timeout    int    `json:"timeout,omitempty" jsonschema:"Command timeout in seconds (default from config)"`
+	Sudo       bool   `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// SSHDockerRestartInput is the input for the ssh_docker_restart tool.
+type SSHDockerRestartInput struct {
+	SessionID   string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Containers  []string `json:"containers" jsonschema:"Container names or IDs to restart"`
+	StopTimeout int      `json:"stop_timeout,omitempty" jsonschema:"Seconds to wait for stop before killing (docker default 10)"`
+	Sudo        bool     `json:"sudo,omitempty" jsonschema:"Run docker via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// SSHDockerRestartOutput is the output for the ssh_docker_restart tool.
+type SSHDockerRestartOutput struct {
+	Restarted []string `json:"restarted"`
+	Message   string   `json:"message"`
+}
+
+// Text returns a human-readable representation of the restart result.
+func (o SSHDockerRestartOutput) Text() string {
+	return o.Message
+}
+
+// SSHKubectlGetPodsInput is the input for the ssh_kubectl_get_pods tool.
+type SSHKubectlGetPodsInput struct {
+	SessionID     string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Context       string `json:"context,omitempty" jsonschema:"kubeconfig context (default: current context on the remote host)"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"Namespace (default: the context's namespace)"`
+	AllNamespaces bool   `json:"all_namespaces,omitempty" jsonschema:"List pods in all namespaces"`
+	Selector      string `json:"selector,omitempty" jsonschema:"Label selector (e.g. app=web,tier!=cache)"`
+}
+
+// KubePod describes a pod in ssh_kubectl_get_pods output.
+type KubePod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Ready     string `json:"ready"`
+	Status    string `json:"status"`
+	Restarts  int    `json:"restarts"`
+	Age       string `json:"age,omitempty"`
+	Node      string `json:"node,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// SSHKubectlGetPodsOutput is the output for the ssh_kubectl_get_pods tool.
+type SSHKubectlGetPodsOutput struct {
+	Pods []KubePod `json:"pods"`
+}
+
+// Text returns a human-readable representation of the pod list.
+func (o SSHKubectlGetPodsOutput) Text() string {
+	if len(o.Pods) == 0 {
+		return "No pods"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d pod(s):\n", len(o.Pods))
+	for _, p := range o.Pods {
+		fmt.Fprintf(&b, "  %s/%s  %s  %s  restarts=%d", p.Namespace, p.Name, p.Ready, p.Status, p.Restarts)
+		if p.Age != "" {
+			fmt.Fprintf(&b, "  age=%s", p.Age)
+		}
+		if p.Node != "" {
+			fmt.Fprintf(&b, "  node=%s", p.Node)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SSHKubectlLogsInput is the input for the ssh_kubectl_logs tool.
+type SSHKubectlLogsInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Context    string `json:"context,omitempty" jsonschema:"kubeconfig context (default: current context on the remote host)"`
+	Namespace  string `json:"namespace,omitempty" jsonschema:"Namespace (default: the context's namespace)"`
+	Pod        string `json:"pod" jsonschema:"Pod name"`
+	Container  string `json:"container,omitempty" jsonschema:"Container name (required for multi-container pods)"`
+	Tail       int    `json:"tail,omitempty" jsonschema:"Number of lines from the end of the log (default 100)"`
+	Since      string `json:"since,omitempty" jsonschema:"Only logs newer than this duration (e.g. 10m, 2h)"`
+	Previous   bool   `json:"previous,omitempty" jsonschema:"Logs of the previous (crashed) container instance"`
+	Timestamps bool   `json:"timestamps,omitempty" jsonschema:"Prefix each line with its timestamp"`
+}
+
+// SSHKubectlLogsOutput is the output for the ssh_kubectl_logs tool.
+type SSHKubectlLogsOutput struct {
+	Pod   string `json:"pod"`
+	Lines int    `json:"lines"`
+	Logs  string `json:"logs"`
+}
+
+// Text returns a human-readable representation of the pod logs.
+func (o SSHKubectlLogsOutput) Text() string {
+	if o.Logs == "" {
+		return fmt.Sprintf("%s: no log output", o.Pod)
+	}
+	return fmt.Sprintf("%s: %d line(s)\n%s", o.Pod, o.Lines, o.Logs)
+}
+
+// SSHKubectlDescribeInput is the input for the ssh_kubectl_describe tool.
+type SSHKubectlDescribeInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Context   string `json:"context,omitempty" jsonschema:"kubeconfig context (default: current context on the remote host)"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace (default: the context's namespace)"`
+	Kind      string `json:"kind,omitempty" jsonschema:"Resource kind (default pod), e.g. deployment, service, node"`
+	Name      string `json:"name" jsonschema:"Resource name"`
+}
+
+// SSHKubectlDescribeOutput is the output for the ssh_kubectl_describe tool.
+type SSHKubectlDescribeOutput struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Text returns the kubectl describe output.
+func (o SSHKubectlDescribeOutput) Text() string {
+	return o.Description
+}
+
+// SSHKubectlExecInput is the input for the ssh_kubectl_exec tool.
+type SSHKubectlExecInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Context   string `json:"context,omitempty" jsonschema:"kubeconfig context (default: current context on the remote host)"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Namespace (default: the context's namespace)"`
+	Pod       string `json:"pod" jsonschema:"Pod name"`
+	Container string `json:"container,omitempty" jsonschema:"Container name (default: the pod's default container)"`
+	Command   string `json:"command" jsonschema:"Command to run inside the container (via sh -c)"`
+	Timeout   int    `json:"timeout,omitempty" jsonschema:"Command timeout in seconds (default from config)"`
+}
+
+// SSHRunScriptInput is the input for the ssh_run_script tool.
+type SSHRunScriptInput struct {
+	SessionID    string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Script       string   `json:"script" jsonschema:"Script content (multi-line, no shell quoting needed)"`
+	Interpreter  string   `json:"interpreter,omitempty" jsonschema:"bash, sh, python, or powershell (default bash; powershell on Windows hosts)"`
+	Args         []string `json:"args,omitempty" jsonschema:"Arguments passed to the script"`
+	WorkingDir   string   `json:"working_dir,omitempty" jsonschema:"Working directory for the script"`
+	Timeout      int      `json:"timeout,omitempty" jsonschema:"Script timeout in seconds (default from config)"`
+	Locale       string   `json:"locale,omitempty" jsonschema:"Set LANG and LC_ALL to this locale (e.g. C.UTF-8). POSIX hosts only (default from --locale)"`
+	Term         string   `json:"term,omitempty" jsonschema:"Set TERM (e.g. dumb). POSIX hosts only (default from --term)"`
+	KillGrace    *int     `json:"kill_grace,omitempty" jsonschema:"Seconds the script gets after SIGINT and SIGTERM before SIGKILL on timeout or cancellation (0-300, 0 = SIGKILL at once; default from --kill-grace, 7)"`
+	Sudo         bool     `json:"sudo,omitempty" jsonschema:"Run the interpreter with sudo"`
+	SudoPassword string   `json:"sudo_password,omitempty" jsonschema:"Password for sudo (passed via 'sudo -S')"`
+}
+
+// SSHArchiveInput is the input for the ssh_archive tool.
+type SSHArchiveInput struct {
+	SessionID   string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	ArchivePath string   `json:"archive_path" jsonschema:"Remote path of the archive to create (.tar.gz, .tgz, or .zip)"`
+	Paths       []string `json:"paths" jsonschema:"Files and directories to include, relative to base_dir"`
+	BaseDir     string   `json:"base_dir,omitempty" jsonschema:"Directory the paths are relative to (default: home directory)"`
+	Format      string   `json:"format,omitempty" jsonschema:"tar.gz or zip (default: inferred from archive_path)"`
+	Sudo        bool     `json:"sudo,omitempty" jsonschema:"Run the archiver with non-interactive sudo (sudo -n; requires --enable-sudo)"`
+}
+
+// SSHArchiveOutput is the output for the ssh_archive tool.
+type SSHArchiveOutput struct {
+	ArchivePath string `json:"archive_path"`
+	Format      string `json:"format"`
+	Tool        string `json:"tool"`
+	Paths       int    `json:"paths"`
+	Size        int64  `json:"size"` // -1 if the archive could not be stat'ed
+}
+
+// Text returns a human-readable representation of the archive result.
+func (o SSHArchiveOutput) Text() string {
+	msg := fmt.Sprintf("Created %s archive %s from %d path(s) using %s", o.Format, o.ArchivePath, o.Paths, o.Tool)
+	if o.Size >= 0 {
+		msg += fmt.Sprintf(" (%d bytes)", o.Size)
+	}
+	return msg
+}
+
+// SSHExtractInput is the input for the ssh_extract tool.
+type SSHExtractInput struct {
+	SessionID   string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	ArchivePath string `json:"archive_path" jsonschema:"Remote path of the archive to unpack (.tar.gz, .tgz, or .zip)"`
+	DestDir     string `json:"dest_dir" jsonschema:"Remote directory to extract into (created if missing)"`
+	Format      string `json:"format,omitempty" jsonschema:"tar.gz or zip (default: inferred from archive_path)"`
+	Sudo        bool   `json:"sudo,omitempty" jsonschema:"Run the archiver with non-interactive sudo (sudo -n; requires --enable-sudo)"`
+}
+
+// SSHExtractOutput is the output for the ssh_extract tool.
+type SSHExtractOutput struct {
+	ArchivePath string `json:"archive_path"`
+	DestDir     string `json:"dest_dir"`
+	Format      string `json:"format"`
+	Tool        string `json:"tool"`
+	Entries     int    `json:"entries"`
+}
+
+// Text returns a human-readable representation of the extract result.
+func (o SSHExtractOutput) Text() string {
+	return fmt.Sprintf("Extracted %d entries from %s into %s using %s", o.Entries, o.ArchivePath, o.DestDir, o.Tool)
+}
+
+// SSHDiffInput is the input for the ssh_diff tool.
+type SSHDiffInput struct {
+	SessionID       string  `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath      string  `json:"remote_path" jsonschema:"Remote file (old side of the diff; a missing file counts as empty)"`
+	LocalPath       string  `json:"local_path,omitempty" jsonschema:"Local file to compare against (new side)"`
+	OtherRemotePath string  `json:"other_remote_path,omitempty" jsonschema:"Second remote file on the same session to compare against (new side)"`
+	Content         *string `json:"content,omitempty" jsonschema:"Proposed file content to compare against (new side), e.g. before ssh_edit_file"`
+	ContextLines    *int    `json:"context_lines,omitempty" jsonschema:"Context lines around each change (default 3)"`
+}
+
+// SSHDiffOutput is the output for the ssh_diff tool.
+type SSHDiffOutput struct {
+	OldPath   string `json:"old_path"`
+	NewPath   string `json:"new_path"`
+	Identical bool   `json:"identical"`
+	Binary    bool   `json:"binary,omitempty"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Diff      string `json:"diff,omitempty"`
+}
+
+// Text returns a human-readable representation of the diff result.
+func (o SSHDiffOutput) Text() string {
+	switch {
+	case o.Identical:
+		return fmt.Sprintf("No differences between %s and %s", o.OldPath, o.NewPath)
+	case o.Binary:
+		return fmt.Sprintf("Binary files %s and %s differ", o.OldPath, o.NewPath)
+	}
+	return fmt.Sprintf("%d line(s) added, %d removed\n%s", o.Added, o.Removed, o.Diff)
+}
+
+// BackupInfo describes one backup of a remote file.
+type BackupInfo struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	Created     string `json:"created"` // RFC 3339; the file mtime for a simple .bak
+	Timestamped bool   `json:"timestamped"`
+}
+
+// SSHRestoreBackupInput is the input for the ssh_restore_backup tool.
+type SSHRestoreBackupInput struct {
+	SessionID     string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath    string `json:"remote_path" jsonschema:"File whose backup to restore (the edited file, not the backup)"`
+	Backup        string `json:"backup,omitempty" jsonschema:"Backup name as listed (default: the newest backup)"`
+	List          bool   `json:"list,omitempty" jsonschema:"Only list the available backups, newest first, without restoring"`
+	BackupCurrent *bool  `json:"backup_current,omitempty" jsonschema:"Back up the current file before restoring so the restore can be undone (default true)"`
+}
+
+// SSHRestoreBackupOutput is the output for the ssh_restore_backup tool.
+type SSHRestoreBackupOutput struct {
+	RemotePath   string       `json:"remote_path"`
+	Backups      []BackupInfo `json:"backups,omitempty"`
+	RestoredFrom string       `json:"restored_from,omitempty"`
+	BytesWritten int64        `json:"bytes_written,omitempty"`
+	BackupPath   string       `json:"backup_path,omitempty"`
+}
+
+// Text returns a human-readable representation of the restore result.
+func (o SSHRestoreBackupOutput) Text() string {
+	if o.RestoredFrom != "" {
+		msg := fmt.Sprintf("Restored %s from %s (%d bytes)", o.RemotePath, o.RestoredFrom, o.BytesWritten)
+		if o.BackupPath != "" {
+			msg += fmt.Sprintf("\nPrevious content backed up to %s", o.BackupPath)
+		}
+		return msg
+	}
+	if len(o.Backups) == 0 {
+		return fmt.Sprintf("No backups of %s", o.RemotePath)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d backup(s) of %s, newest first:\n", len(o.Backups), o.RemotePath)
+	for _, b := range o.Backups {
+		fmt.Fprintf(&sb, "  %s  %s  %d bytes\n", b.Name, b.Created, b.Size)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// SSHFileStatInput is the input for the ssh_file_stat tool.
+type SSHFileStatInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath string `json:"remote_path" jsonschema:"Remote path to inspect (symlinks are not followed; the target is reported)"`
+}
+
+// SSHFileStatOutput is the output for the ssh_file_stat tool.
+type SSHFileStatOutput struct {
+	Path        string `json:"path"`
+	Type        string `json:"type"` // file, directory, symlink, fifo, socket, char_device, block_device, other
+	Mode        string `json:"mode"`
+	Permissions string `json:"permissions"` // octal, e.g. "0644"
+	Size        int64  `json:"size"`
+	Blocks      *int64 `json:"blocks,omitempty"`
+	BlockSize   int64  `json:"block_size,omitempty"` // unit of Blocks in bytes
+	UID         uint32 `json:"uid"`
+	GID         uint32 `json:"gid"`
+	User        string `json:"user,omitempty"`
+	Group       string `json:"group,omitempty"`
+	LinkTarget  string `json:"link_target,omitempty"`
+	TargetType  string `json:"target_type,omitempty"` // type of the symlink target, or "broken"
+	ModTime     string `json:"mod_time"`
+	AccessTime  string `json:"access_time,omitempty"`
+}
+
+// Text returns a human-readable representation of the stat result.
+func (o SSHFileStatOutput) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "File: %s", o.Path)
+	if o.LinkTarget != "" {
+		fmt.Fprintf(&sb, " -> %s", o.LinkTarget)
+	}
+	fmt.Fprintf(&sb, "\nType: %s", o.Type)
+	if o.TargetType != "" {
+		fmt.Fprintf(&sb, " (target: %s)", o.TargetType)
+	}
+	fmt.Fprintf(&sb, "\nSize: %d", o.Size)
+	if o.Blocks != nil {
+		fmt.Fprintf(&sb, "  Blocks: %d (%d-byte)", *o.Blocks, o.BlockSize)
+	}
+	fmt.Fprintf(&sb, "\nMode: %s (%s)", o.Mode, o.Permissions)
+	fmt.Fprintf(&sb, "\nOwner: %s  Group: %s", idName(o.UID, o.User), idName(o.GID, o.Group))
+	fmt.Fprintf(&sb, "\nModified: %s", o.ModTime)
+	if o.AccessTime != "" {
+		fmt.Fprintf(&sb, "\nAccessed: %s", o.AccessTime)
+	}
+	return sb.String()
+}
+
+// idName formats a numeric owner ID with its name when known.
+func idName(id uint32, name string) string {
+	if name == "" {
+		return fmt.Sprintf("%d", id)
+	}
+	return fmt.Sprintf("%s (%d)", name, id)
+}
+
+// SSHListDirectoryInput is the input for the ssh_list_directory tool.
+type SSHListDirectoryInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath string `json:"remote_path" jsonschema:"Remote directory to list"`
+	Sort       string `json:"sort,omitempty" jsonschema:"Sort by 'name' (default), 'size', 'mtime', or 'none' (the server's order: fastest for huge directories, stops reading once the page is full)"`
+	Reverse    bool   `json:"reverse,omitempty" jsonschema:"Reverse the sort order, e.g. largest or newest first"`
+	Pattern    string `json:"pattern,omitempty" jsonschema:"Only entries whose name matches this glob, e.g. '*.log'"`
+	Regex      string `json:"regex,omitempty" jsonschema:"Only entries whose name matches this regular expression (unanchored)"`
+	Type       string `json:"type,omitempty" jsonschema:"Only entries of this type: 'file', 'directory', 'symlink', or 'other'"`
+	Limit      int    `json:"limit,omitempty" jsonschema:"Maximum entries to return (default 200, max 5000)"`
+	Offset     int    `json:"offset,omitempty" jsonschema:"Number of matching entries to skip, for paging; prefer cursor for large directories"`
+	Cursor     string `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page, to continue the listing right after it (use the same sort and reverse)"`
+	Count      bool   `json:"count,omitempty" jsonschema:"With sort=none, read the whole directory to report total (other sorts always count)"`
+}
+
+// DirEntry is one entry of a directory listing.
+type DirEntry struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Size       int64  `json:"size"`
+	Mode       string `json:"mode"`
+	ModTime    string `json:"mod_time"`
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
+// SSHListDirectoryOutput is the output for the ssh_list_directory tool.
+type SSHListDirectoryOutput struct {
+	Path       string     `json:"path"`
+	Total      int        `json:"total"` // entries matching the filters, before paging; -1 if not counted (sort=none)
+	Offset     int        `json:"offset"`
+	HasMore    bool       `json:"has_more"`
+	NextCursor string     `json:"next_cursor,omitempty"` // pass as cursor for the next page
+	Entries    []DirEntry `json:"entries"`
+}
+
+// Text returns a human-readable representation of the listing.
+func (o SSHListDirectoryOutput) Text() string {
+	var sb strings.Builder
+	switch {
+	case o.Total == 0:
+		fmt.Fprintf(&sb, "%s: no matching entries", o.Path)
+	case len(o.Entries) == 0 && o.Total < 0:
+		fmt.Fprintf(&sb, "%s: no more matching entries", o.Path)
+	case len(o.Entries) == 0:
+		fmt.Fprintf(&sb, "%s: %d matching entries, none at offset %d", o.Path, o.Total, o.Offset)
+	case o.Total < 0:
+		fmt.Fprintf(&sb, "%s: entries %d-%d (total not counted)", o.Path, o.Offset+1, o.Offset+len(o.Entries))
+	default:
+		fmt.Fprintf(&sb, "%s: entries %d-%d of %d", o.Path, o.Offset+1, o.Offset+len(o.Entries), o.Total)
+	}
+	for _, e := range o.Entries {
+		fmt.Fprintf(&sb, "\n%s %10d %s %s", e.Mode, e.Size, e.ModTime, e.Name)
+		if e.Type == "directory" {
+			sb.WriteString("/")
+		}
+		if e.LinkTarget != "" {
+			fmt.Fprintf(&sb, " -> %s", e.LinkTarget)
+		}
+	}
+	switch {
+	case o.NextCursor != "":
+		fmt.Fprintf(&sb, "\n(more entries: use cursor=%s)", o.NextCursor)
+	case o.HasMore:
+		fmt.Fprintf(&sb, "\n(more entries: use offset=%d)", o.Offset+len(o.Entries))
+	}
+	return sb.String()
+}
+
+// SSHCopyInput is the input for the ssh_copy tool.
+type SSHCopyInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	SourcePath string `json:"source_path" jsonschema:"Remote file or directory to copy"`
+	DestPath   string `json:"dest_path" jsonschema:"Full remote path of the copy (not a directory to copy into); parent directories are created"`
+	Overwrite  bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing destination file (directories are never overwritten)"`
+}
+
+// SSHCopyOutput is the output for the ssh_copy tool.
+type SSHCopyOutput struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Directory   bool   `json:"directory"`
+	Method      string `json:"method"` // "cp" (server-side) or "sftp" (streamed)
+	Files       int    `json:"files,omitempty"`
+	Bytes       int64  `json:"bytes,omitempty"`
+}
+
+// Text returns a human-readable representation of the copy result.
+func (o SSHCopyOutput) Text() string {
+	if o.Method == "sftp" {
+		return fmt.Sprintf("Copied %s to %s via SFTP (%d file(s), %d bytes)", o.Source, o.Destination, o.Files, o.Bytes)
+	}
+	return fmt.Sprintf("Copied %s to %s on the remote host (cp -a)", o.Source, o.Destination)
+}
+
+// SSHRenameInput is the input for the ssh_rename tool.
+type SSHRenameInput struct {
+	SessionID  string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	SourcePath string `json:"source_path" jsonschema:"Remote file or directory to rename or move"`
+	DestPath   string `json:"dest_path" jsonschema:"Full new remote path (not a directory to move into)"`
+	Overwrite  bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing destination file (directories are never overwritten)"`
+}
+
+// SSHRenameOutput is the output for the ssh_rename tool.
+type SSHRenameOutput struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Replaced    bool   `json:"replaced"`         // an existing destination file was overwritten
+	Atomic      bool   `json:"atomic,omitempty"` // the replace used posix-rename@openssh.com
+}
+
+// Text returns a human-readable representation of the rename result.
+func (o SSHRenameOutput) Text() string {
+	msg := fmt.Sprintf("Renamed %s to %s", o.Source, o.Destination)
+	switch {
+	case o.Replaced && o.Atomic:
+		msg += " (replaced the existing file atomically)"
+	case o.Replaced:
+		msg += " (removed the existing file first; the server has no posix-rename)"
+	}
+	return msg
+}
+
+// SSHTransferInput is the input for the ssh_transfer tool.
+type SSHTransferInput struct {
+	SourceSessionID string `json:"source_session_id" jsonschema:"Session ID of the host to copy from"`
+	SourcePath      string `json:"source_path" jsonschema:"File on the source host"`
+	DestSessionID   string `json:"dest_session_id" jsonschema:"Session ID of the host to copy to"`
+	DestPath        string `json:"dest_path" jsonschema:"Full destination file path on the destination host; parent directories are created"`
+	Overwrite       bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing destination file"`
+}
+
+// SSHTransferOutput is the output for the ssh_transfer tool.
+type SSHTransferOutput struct {
+	SourceSessionID string `json:"source_session_id"`
+	SourcePath      string `json:"source_path"`
+	DestSessionID   string `json:"dest_session_id"`
+	DestPath        string `json:"dest_path"`
+	BytesCopied     int64  `json:"bytes_copied"`
+}
+
+// Text returns a human-readable representation of the transfer result.
+func (o SSHTransferOutput) Text() string {
+	return fmt.Sprintf("Transferred %s:%s to %s:%s (%d bytes)",
+		o.SourceSessionID, o.SourcePath, o.DestSessionID, o.DestPath, o.BytesCopied)
+}
+
+// SSHWatchPathInput is the input for the ssh_watch_path tool.
+type SSHWatchPathInput struct {
+	SessionID       string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePath      string `json:"remote_path" jsonschema:"File or directory to watch; it may not exist yet"`
+	DurationSeconds int    `json:"duration_seconds,omitempty" jsonschema:"How long to watch (default 30, max 600)"`
+	IntervalMs      int    `json:"interval_ms,omitempty" jsonschema:"Polling interval in milliseconds (default 1000, min 200)"`
+	Pattern         string `json:"pattern,omitempty" jsonschema:"For a directory, only watch entries whose name matches this glob, e.g. '*.tar.gz'"`
+	UntilChange     bool   `json:"until_change,omitempty" jsonschema:"Return as soon as the first change is seen instead of watching for the full duration"`
+	Contains        string `json:"contains,omitempty" jsonschema:"For a file, return as soon as newly written content contains this string (e.g. a log marker)"`
+}
+
+// WatchEvent is one change seen by ssh_watch_path.
+type WatchEvent struct {
+	Time  string `json:"time"`
+	Path  string `json:"path"`
+	Event string `json:"event"` // created, modified, deleted, or matched
+	Size  int64  `json:"size,omitempty"`
+	Line  string `json:"line,omitempty"` // for matched: the line containing the marker
+}
+
+// SSHWatchPathOutput is the output for the ssh_watch_path tool.
+type SSHWatchPathOutput struct {
+	Path      string       `json:"path"`
+	Events    []WatchEvent `json:"events"`
+	Matched   bool         `json:"matched,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"` // stopped at the event limit
+	Polls     int          `json:"polls"`
+	ElapsedMs int64        `json:"elapsed_ms"`
+}
+
+// Text returns a human-readable representation of the watch result.
+func (o SSHWatchPathOutput) Text() string {
+	elapsed := time.Duration(o.ElapsedMs) * time.Millisecond
+	if len(o.Events) == 0 {
+		return fmt.Sprintf("No changes to %s in %s", o.Path, elapsed.Round(time.Second))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d change(s) to %s in %s:", len(o.Events), o.Path, elapsed.Round(time.Second))
+	for _, e := range o.Events {
+		fmt.Fprintf(&sb, "\n%s %-8s %s", e.Time, e.Event, e.Path)
+		if e.Line != "" {
+			fmt.Fprintf(&sb, ": %s", e.Line)
+		}
+	}
+	if o.Truncated {
+		fmt.Fprintf(&sb, "\n(stopped after %d events)", len(o.Events))
+	}
+	return sb.String()
+}
+
+// SSHSignalInput is the input for the ssh_signal tool.
+type SSHSignalInput struct {
+	SessionID   string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	PID         int    `json:"pid,omitempty" jsonschema:"Process ID to signal"`
+	Name        string `json:"name,omitempty" jsonschema:"Process name pattern, matched like pkill (pgrep); instead of pid"`
+	FullCommand bool   `json:"full_command,omitempty" jsonschema:"Match name against the full command line (pgrep -f)"`
+	Exact       bool   `json:"exact,omitempty" jsonschema:"Require name to match exactly (pgrep -x)"`
+	User        string `json:"user,omitempty" jsonschema:"Only match processes of this user (pgrep -u)"`
+	Signal      string `json:"signal,omitempty" jsonschema:"Signal to send: TERM (default), INT, HUP, QUIT, KILL, USR1, USR2, STOP or CONT"`
+	Confirm     bool   `json:"confirm,omitempty" jsonschema:"With name: actually send the signal. Without it the matching processes are only listed"`
+	Sudo        bool   `json:"sudo,omitempty" jsonschema:"Send the signal via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// ProcessInfo describes a remote process.
+type ProcessInfo struct {
+	PID     int    `json:"pid"`
+	User    string `json:"user"`
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+// SSHSignalOutput is the output for the ssh_signal tool.
+type SSHSignalOutput struct {
+	Signal    string        `json:"signal"`
+	Signaled  bool          `json:"signaled"` // false when name matched without confirm
+	Processes []ProcessInfo `json:"processes"`
+	Message   string        `json:"message"`
+}
+
+// Text returns a human-readable representation of the signal result.
+func (o SSHSignalOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	for _, p := range o.Processes {
+		fmt.Fprintf(&b, "\n  %d %s %s", p.PID, p.User, p.Command)
+	}
+	return b.String()
+}
+
+// SSHHostInfoInput is the input for the ssh_host_info tool.
+type SSHHostInfoInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Refresh   bool   `json:"refresh,omitempty" jsonschema:"Probe the host again instead of returning what was detected on connect, e.g. after installing packages or upgrading the OS"`
+}
+
+// SSHHostInfoOutput is the output for the ssh_host_info tool.
+type SSHHostInfoOutput struct {
+	SessionID          string `json:"session_id"`
+	OS                 string `json:"os,omitempty"`
+	Arch               string `json:"arch,omitempty"`
+	Shell              string `json:"shell,omitempty"`
+	Distro             string `json:"distro,omitempty"`
+	DistroLike         string `json:"distro_like,omitempty"`
+	DistroVersion      string `json:"distro_version,omitempty"`
+	DistroName         string `json:"distro_name,omitempty"`
+	PackageManager     string `json:"package_manager,omitempty"`
+	InitSystem         string `json:"init_system,omitempty"`
+	Sudo               bool   `json:"sudo"`
+	SudoNoninteractive bool   `json:"sudo_noninteractive"`
+	Doas               bool   `json:"doas"`
+	Refreshed          bool   `json:"refreshed,omitempty"`
+}
+
+// Text returns a human-readable representation of the host information.
+func (o SSHHostInfoOutput) Text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s", o.SessionID)
+	if o.Refreshed {
+		sb.WriteString(" (refreshed)")
+	}
+	sb.WriteString("\n")
+	if o.OS == "" {
+		sb.WriteString("  host information could not be detected")
+		return sb.String()
+	}
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "  %s: %s\n", name, value)
+		}
+	}
+	field("OS", strings.TrimSpace(o.OS+" "+o.Arch))
+	field("Distro", o.DistroName)
+	if o.Distro != "" {
+		id := strings.TrimSpace(o.Distro + " " + o.DistroVersion)
+		if o.DistroLike != "" {
+			id += " (like " + o.DistroLike + ")"
+		}
+		field("Distro ID", id)
+	}
+	field("Shell", o.Shell)
+	field("Package manager", o.PackageManager)
+	field("Init system", o.InitSystem)
+	var priv []string
+	if o.Sudo {
+		if o.SudoNoninteractive {
+			priv = append(priv, "sudo (passwordless)")
+		} else {
+			priv = append(priv, "sudo")
+		}
+	}
+	if o.Doas {
+		priv = append(priv, "doas")
+	}
+	field("Privilege tools", strings.Join(priv, ", "))
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SSHPingInput is the input for the ssh_ping tool.
+type SSHPingInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Timeout   int    `json:"timeout,omitempty" jsonschema:"Timeout in seconds for each probe (default 10)"`
+}
+
+// SSHPingOutput is the output for the ssh_ping tool.
+type SSHPingOutput struct {
+	SessionID     string  `json:"session_id"`
+	Healthy       bool    `json:"healthy"`
+	Reconnected   bool    `json:"reconnected"`
+	ServerVersion string  `json:"server_version"`
+	KeepaliveMs   float64 `json:"keepalive_ms"`
+	ExecMs        float64 `json:"exec_ms,omitempty"`
+	ExecSkipped   string  `json:"exec_skipped,omitempty"` // why the exec probe did not run
+	Error         string  `json:"error,omitempty"`
+}
+
+// Text returns a human-readable representation of the ping result.
+func (o SSHPingOutput) Text() string {
+	status := "healthy"
+	if !o.Healthy {
+		status = "UNHEALTHY"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s, keepalive %.1fms", o.SessionID, status, o.KeepaliveMs)
+	switch {
+	case o.ExecSkipped != "":
+		fmt.Fprintf(&sb, ", exec probe skipped (%s)", o.ExecSkipped)
+	case o.ExecMs > 0:
+		fmt.Fprintf(&sb, ", exec %.1fms", o.ExecMs)
+	}
+	if o.ServerVersion != "" {
+		fmt.Fprintf(&sb, "\nserver: %s", o.ServerVersion)
+	}
+	if o.Reconnected {
+		sb.WriteString("\nthe connection was lost and has been re-established")
+	}
+	if o.Error != "" {
+		fmt.Fprintf(&sb, "\nerror: %s", o.Error)
+	}
+	return sb.String()
+}
+
+// SSHPlanExecuteInput is the input for the ssh_plan_execute tool.
+type SSHPlanExecuteInput struct {
+	Steps []PlanStep `json:"steps" jsonschema:"Ordered steps, at most 50. Each sets exactly one of edit, upload or execute, with the same fields as ssh_edit_file, ssh_upload or ssh_execute (including session_id, so one plan can span hosts)"`
+}
+
+// PlanStep is one step of an ssh_plan_execute plan.
+type PlanStep struct {
+	Name      string            `json:"name,omitempty" jsonschema:"Label for the step in the result"`
+	Edit      *SSHEditFileInput `json:"edit,omitempty" jsonschema:"Run ssh_edit_file with these arguments"`
+	Upload    *SSHUploadInput   `json:"upload,omitempty" jsonschema:"Run ssh_upload with these arguments"`
+	Execute   *SSHExecuteInput  `json:"execute,omitempty" jsonschema:"Run ssh_execute with these arguments; a non-zero exit code, timeout or cancellation is a failure"`
+	OnFailure string            `json:"on_failure,omitempty" jsonschema:"What a failure of this step does: abort (default; skip the remaining steps and restore every file the plan edited), stop (skip the remaining steps, keep changes), or continue (go on with the next step)"`
+}
+
+// PlanStepResult is the outcome of one plan step.
+type PlanStepResult struct {
+	Step    int    `json:"step"`
+	Name    string `json:"name,omitempty"`
+	Kind    string `json:"kind"`   // edit, upload or execute
+	Status  string `json:"status"` // ok, failed or skipped
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SSHPlanExecuteOutput is the output for the ssh_plan_execute tool.
+type SSHPlanExecuteOutput struct {
+	Succeeded         bool             `json:"succeeded"`
+	Steps             []PlanStepResult `json:"steps"`
+	RollbackAttempted bool             `json:"rollback_attempted,omitempty"`
+	RolledBack        []string         `json:"rolled_back,omitempty"`     // files restored to their state before the plan
+	RollbackErrors    []string         `json:"rollback_errors,omitempty"` // "path: error" for files that could not be restored
+}
+
+// Text returns a human-readable representation of the plan result.
+func (o SSHPlanExecuteOutput) Text() string {
+	var b strings.Builder
+	done := 0
+	for _, s := range o.Steps {
+		if s.Status == planOK {
+			done++
+		}
+	}
+	if o.Succeeded {
+		fmt.Fprintf(&b, "Plan succeeded: %d step(s)", len(o.Steps))
+	} else {
+		fmt.Fprintf(&b, "Plan FAILED: %d of %d step(s) succeeded", done, len(o.Steps))
+	}
+	for _, s := range o.Steps {
+		label := s.Kind
+		if s.Name != "" {
+			label = s.Name + " (" + s.Kind + ")"
+		}
+		fmt.Fprintf(&b, "\n  %d. %s: %s", s.Step, label, s.Status)
+		if s.Message != "" {
+			b.WriteString(" - " + s.Message)
+		}
+		if s.Error != "" {
+			b.WriteString(": " + s.Error)
+		}
+	}
+	if o.RollbackAttempted {
+		switch {
+		case len(o.RolledBack) == 0 && len(o.RollbackErrors) == 0:
+			b.WriteString("\nRollback: no files had been edited")
+		case len(o.RolledBack) > 0:
+			fmt.Fprintf(&b, "\nRolled back %d file(s): %s", len(o.RolledBack), strings.Join(o.RolledBack, ", "))
+		}
+		if len(o.RollbackErrors) > 0 {
+			fmt.Fprintf(&b, "\nROLLBACK FAILED for %d file(s):\n  %s", len(o.RollbackErrors), strings.Join(o.RollbackErrors, "\n  "))
+		}
+	}
+	return b.String()
+}
+
+// SSHGetTranscriptInput is the input for the ssh_get_transcript tool.
+type SSHGetTranscriptInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID whose transcript to read; it may already be disconnected"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Number of most recent tool calls to return (default 20, max 500)"`
+}
+
+// SSHGetTranscriptOutput is the output for the ssh_get_transcript tool.
+type SSHGetTranscriptOutput struct {
+	SessionID string             `json:"session_id"`
+	File      string             `json:"file"`
+	Total     int                `json:"total"`
+	Entries   []transcript.Entry `json:"entries"`
+}
+
+// Text returns a human-readable representation of the transcript.
+func (o SSHGetTranscriptOutput) Text() string {
+	if o.Total == 0 {
+		return fmt.Sprintf("No recorded tool calls for %s", o.SessionID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Transcript of %s (last %d of %d calls, file %s):\n", o.SessionID, len(o.Entries), o.Total, o.File)
+	for _, e := range o.Entries {
+		fmt.Fprintf(&b, "\n--- %s %s (%dms)\n", e.Time, e.Tool, e.DurationMs)
+		if len(e.Arguments) > 0 {
+			args, _ := json.Marshal(e.Arguments)
+			fmt.Fprintf(&b, "arguments: %s\n", args)
+		}
+		if e.Output != "" {
+			b.WriteString(strings.TrimRight(e.Output, "\n") + "\n")
+		}
+		if e.Error != "" {
+			fmt.Fprintf(&b, "error: %s\n", e.Error)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// SSHLogsInput is the input for the ssh_logs tool.
+type SSHLogsInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Source    string `json:"source,omitempty" jsonschema:"Where to read: auto (default: the journal if journalctl exists, else /var/log/syslog or /var/log/messages), journal or file"`
+	File      string `json:"file,omitempty" jsonschema:"Log file to read instead of the journal"`
+	Unit      string `json:"unit,omitempty" jsonschema:"Only entries of this systemd unit (journalctl -u); in a file, lines tagged with its name"`
+	Priority  string `json:"priority,omitempty" jsonschema:"Journal only: this priority and more severe (err, warning, 0-7) or a range like warning..err"`
+	Since     string `json:"since,omitempty" jsonschema:"Journal only: entries since this time (e.g. -1h, today, 2024-01-02 15:04:05)"`
+	Until     string `json:"until,omitempty" jsonschema:"Journal only: entries until this time"`
+	Grep      string `json:"grep,omitempty" jsonschema:"Only entries matching this regular expression (journalctl --grep; extended regex over the line for files)"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Entries per page, newest last (default 100, max 1000)"`
+	Cursor    string `json:"cursor,omitempty" jsonschema:"next_cursor from the previous call, for the page of older entries"`
+	Sudo      bool   `json:"sudo,omitempty" jsonschema:"Read via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// LogEntry is a log entry in ssh_logs output.
+type LogEntry struct {
+	Time       string `json:"time,omitempty"` // RFC 3339 (journal) or as written in the file
+	Host       string `json:"host,omitempty"`
+	Unit       string `json:"unit,omitempty"`
+	Identifier string `json:"identifier,omitempty"` // syslog identifier, e.g. sshd
+	PID        int    `json:"pid,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	Message    string `json:"message"`
+	Line       int    `json:"line,omitempty"` // line number in the file
+}
+
+// SSHLogsOutput is the output for the ssh_logs tool.
+type SSHLogsOutput struct {
+	Source     string     `json:"source"`
+	File       string     `json:"file,omitempty"`
+	Entries    []LogEntry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// Text returns a human-readable representation of the log entries.
+func (o SSHLogsOutput) Text() string {
+	from := "journal"
+	if o.Source == "file" {
+		from = o.File
+	}
+	if len(o.Entries) == 0 {
+		return fmt.Sprintf("%s: no matching entries", from)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d entries, oldest first", from, len(o.Entries))
+	for _, e := range o.Entries {
+		b.WriteString("\n")
+		if e.Time != "" {
+			b.WriteString(e.Time + " ")
+		}
+		if e.Identifier != "" {
+			b.WriteString(e.Identifier)
+			if e.PID != 0 {
+				fmt.Fprintf(&b, "[%d]", e.PID)
+			}
+			if e.Priority != "" {
+				fmt.Fprintf(&b, " <%s>", e.Priority)
+			}
+			b.WriteString(": ")
+		} else if e.Priority != "" {
+			fmt.Fprintf(&b, "<%s> ", e.Priority)
+		}
+		b.WriteString(e.Message)
+	}
+	if o.NextCursor != "" {
+		fmt.Fprintf(&b, "\nOlder entries: call again with cursor %q", o.NextCursor)
+	}
+	return b.String()
+}
+
+// SSHCollectDiagnosticsInput is the input for the ssh_collect_diagnostics tool.
+type SSHCollectDiagnosticsInput struct {
+	SessionID   string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	LocalPath   string   `json:"local_path" jsonschema:"Where to write the .tar.gz bundle on the MCP host"`
+	Items       []string `json:"items,omitempty" jsonschema:"Built-in items to collect: uname, uptime, df, free, ps, dmesg, journal, network, sockets, mounts. Default (with no files either): uname, uptime, df, free, ps, dmesg, journal, network and /etc/os-release"`
+	Files       []string `json:"files,omitempty" jsonschema:"Remote files to include, e.g. /var/log/syslog or /etc/nginx/nginx.conf (the end of each if larger than max_item_size)"`
+	MaxItemSize int      `json:"max_item_size,omitempty" jsonschema:"Byte cap per item (default 1048576, max 16777216)"`
+	Sudo        bool     `json:"sudo,omitempty" jsonschema:"Run the commands and read the files via non-interactive sudo (requires --enable-sudo)"`
+}
+
+// DiagnosticItem describes an item of a diagnostics bundle.
+type DiagnosticItem struct {
+	Name      string `json:"name"`
+	Command   string `json:"command,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Entry     string `json:"entry,omitempty"` // file in the bundle, empty if nothing was collected
+	Size      int    `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SSHCollectDiagnosticsOutput is the output for the ssh_collect_diagnostics tool.
+type SSHCollectDiagnosticsOutput struct {
+	LocalPath string           `json:"local_path"`
+	Bytes     int64            `json:"bytes"`
+	Items     []DiagnosticItem `json:"items"`
+	Message   string           `json:"message"`
+}
+
+// Text returns a human-readable representation of the bundle.
+func (o SSHCollectDiagnosticsOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	for _, it := range o.Items {
+		fmt.Fprintf(&b, "\n  %s: %d bytes", it.Name, it.Size)
+		if it.Truncated {
+			b.WriteString(" (truncated)")
+		}
+		if it.Error != "" {
+			fmt.Fprintf(&b, ", error: %s", it.Error)
+		}
+	}
+	return b.String()
+}
+
+// SSHUserInput is the input for the ssh_user tool.
+type SSHUserInput struct {
+	SessionID     string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Action        string   `json:"action" jsonschema:"list, create, disable or add-to-group"`
+	User          string   `json:"user,omitempty" jsonschema:"User name; required except for list, where it shows just that user"`
+	Groups        []string `json:"groups,omitempty" jsonschema:"Supplementary groups: for add-to-group, and optionally for create"`
+	Shell         string   `json:"shell,omitempty" jsonschema:"create: login shell, e.g. /bin/bash"`
+	Comment       string   `json:"comment,omitempty" jsonschema:"create: full name or comment (GECOS)"`
+	IncludeSystem bool     `json:"include_system,omitempty" jsonschema:"list: include system accounts (UID below 1000 on Linux, 500 on macOS)"`
+	Sudo          bool     `json:"sudo,omitempty" jsonschema:"Run via non-interactive sudo (requires --enable-sudo); needed for changes unless the session is root"`
+}
+
+// UserAccount describes a user account in ssh_user output.
+type UserAccount struct {
+	Name    string   `json:"name"`
+	UID     int      `json:"uid"`
+	GID     int      `json:"gid"`
+	Comment string   `json:"comment,omitempty"`
+	Home    string   `json:"home"`
+	Shell   string   `json:"shell"`
+	Groups  []string `json:"groups,omitempty"` // primary group first
+}
+
+// SSHUserOutput is the output for the ssh_user tool.
+type SSHUserOutput struct {
+	Action  string        `json:"action"`
+	Users   []UserAccount `json:"users"`
+	Message string        `json:"message"`
+}
+
+// Text returns a human-readable representation of the user accounts.
+func (o SSHUserOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	for _, u := range o.Users {
+		fmt.Fprintf(&b, "\n  %s (uid %d, gid %d) home %s, shell %s", u.Name, u.UID, u.GID, u.Home, u.Shell)
+		if u.Comment != "" {
+			fmt.Fprintf(&b, ", %q", u.Comment)
+		}
+		if len(u.Groups) > 0 {
+			fmt.Fprintf(&b, "\n    groups: %s", strings.Join(u.Groups, ", "))
+		}
+	}
+	return b.String()
+}
+
+// SSHWorkspaceInput is the input for the ssh_workspace tool.
+type SSHWorkspaceInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Action    string `json:"action" jsonschema:"create (a new private scratch directory), list, or remove"`
+	Path      string `json:"path,omitempty" jsonschema:"remove: the workspace to delete; all of the session's workspaces when omitted"`
+}
+
+// SSHWorkspaceOutput is the output for the ssh_workspace tool.
+type SSHWorkspaceOutput struct {
+	Action     string   `json:"action"`
+	Path       string   `json:"path,omitempty"`    // the workspace created
+	Removed    []string `json:"removed,omitempty"` // the workspaces removed
+	Workspaces []string `json:"workspaces"`        // the session's workspaces afterwards
+	Message    string   `json:"message"`
+}
+
+// Text returns a human-readable representation of the workspace result.
+func (o SSHWorkspaceOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	switch {
+	case o.Action == "list":
+		for _, w := range o.Workspaces {
+			fmt.Fprintf(&b, "\n  %s", w)
+		}
+	case o.Action == "remove" && len(o.Workspaces) > 0:
+		fmt.Fprintf(&b, "\nRemaining: %s", strings.Join(o.Workspaces, ", "))
+	}
+	return b.String()
+}
+
+// SSHWhichInput is the input for the ssh_which tool.
+type SSHWhichInput struct {
+	SessionID string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Commands  []string `json:"commands" jsonschema:"Program names to look up on PATH, e.g. [\"git\", \"jq\", \"docker\"] (at most 50)"`
+	NoVersion bool     `json:"no_version,omitempty" jsonschema:"Only check presence; skip running each program's version option"`
+}
+
+// CommandAvailability reports whether one program is available.
+type CommandAvailability struct {
+	Name          string `json:"name"`
+	Found         bool   `json:"found"`
+	Path          string `json:"path,omitempty"`
+	Builtin       bool   `json:"builtin,omitempty"`        // a shell builtin or function, not a file
+	Version       string `json:"version,omitempty"`        // the version number found in VersionOutput
+	VersionOutput string `json:"version_output,omitempty"` // the first line the version option printed
+}
+
+// SSHWhichOutput is the output for the ssh_which tool.
+type SSHWhichOutput struct {
+	Commands []CommandAvailability `json:"commands"`
+}
+
+// Text returns a human-readable representation of the command lookup.
+func (o SSHWhichOutput) Text() string {
+	var b strings.Builder
+	var missing []string
+	for _, c := range o.Commands {
+		switch {
+		case !c.Found:
+			missing = append(missing, c.Name)
+			fmt.Fprintf(&b, "%s: not found\n", c.Name)
+		case c.Builtin:
+			fmt.Fprintf(&b, "%s: shell builtin\n", c.Name)
+		default:
+			fmt.Fprintf(&b, "%s: %s", c.Name, c.Path)
+			if c.Version != "" {
+				fmt.Fprintf(&b, " (%s)", c.Version)
+			} else if c.VersionOutput != "" {
+				fmt.Fprintf(&b, " (%s)", c.VersionOutput)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(&b, "Missing: %s", strings.Join(missing, ", "))
+	} else {
+		fmt.Fprintf(&b, "All %d command(s) found", len(o.Commands))
+	}
+	return b.String()
+}
+
+// SSHGitInput is the input for the ssh_git tool.
+type SSHGitInput struct {
+	SessionID    string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Action       string `json:"action" jsonschema:"clone, pull (fast-forward only), status, checkout or log"`
+	Path         string `json:"path" jsonschema:"Repository directory on the remote host (clone: the directory to clone into); relative paths and ~ are relative to the home directory"`
+	URL          string `json:"url,omitempty" jsonschema:"clone: repository URL (https, ssh or scp-like user@host:repo)"`
+	Ref          string `json:"ref,omitempty" jsonschema:"checkout: branch, tag or commit to switch to; clone: branch or tag to check out; log: where to start (default HEAD)"`
+	Create       bool   `json:"create,omitempty" jsonschema:"checkout: create ref as a new branch at HEAD"`
+	Depth        int    `json:"depth,omitempty" jsonschema:"clone: shallow clone with this many commits"`
+	Limit        int    `json:"limit,omitempty" jsonschema:"log and pull: maximum commits to list (default 20, at most 500)"`
+	ForwardAgent bool   `json:"forward_agent,omitempty" jsonschema:"clone and pull: forward the local ssh-agent for private repositories (requires --enable-agent-forwarding)"`
+	Timeout      int    `json:"timeout,omitempty" jsonschema:"Timeout in seconds for each git command (default from config)"`
+}
+
+// GitStatus is the state of a working tree.
+type GitStatus struct {
+	Branch    string          `json:"branch"` // "(detached)" when HEAD is detached
+	Commit    string          `json:"commit"` // "(initial)" before the first commit
+	Upstream  string          `json:"upstream,omitempty"`
+	Ahead     int             `json:"ahead,omitempty"`
+	Behind    int             `json:"behind,omitempty"`
+	Clean     bool            `json:"clean"`
+	Files     []GitFileStatus `json:"files,omitempty"`
+	MoreFiles int             `json:"more_files,omitempty"` // changed files not listed
+}
+
+// GitFileStatus is a changed, untracked or unmerged file.
+type GitFileStatus struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"` // the source of a rename or copy
+	State    string `json:"state"`               // changed, renamed, copied, unmerged or untracked
+	Staged   string `json:"staged,omitempty"`    // index status letter: M, T, A, D, R, C or U
+	Unstaged string `json:"unstaged,omitempty"`  // work tree status letter
+}
+
+// GitCommit is a commit listed by log or pull.
+type GitCommit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Date    string `json:"date"` // author date, ISO 8601
+	Subject string `json:"subject"`
+}
+
+// SSHGitOutput is the output for the ssh_git tool.
+type SSHGitOutput struct {
+	Action  string      `json:"action"`
+	Path    string      `json:"path"`
+	Message string      `json:"message"`
+	Status  *GitStatus  `json:"status,omitempty"`
+	Commits []GitCommit `json:"commits,omitempty"`
+}
+
+// Text returns a human-readable representation of the git result.
+func (o SSHGitOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	if st := o.Status; st != nil {
+		fmt.Fprintf(&b, "\nBranch %s at %s", st.Branch, shortHash(st.Commit))
+		if st.Upstream != "" {
+			fmt.Fprintf(&b, ", tracking %s", st.Upstream)
+			if st.Ahead > 0 || st.Behind > 0 {
+				fmt.Fprintf(&b, " (ahead %d, behind %d)", st.Ahead, st.Behind)
+			}
+		}
+		if st.Clean {
+			b.WriteString("\nWorking tree clean")
+		}
+		for _, f := range st.Files {
+			code := "??"
+			if f.State != "untracked" {
+				code = cmp.Or(f.Staged, ".") + cmp.Or(f.Unstaged, ".")
+			}
+			fmt.Fprintf(&b, "\n  %s %s", code, f.Path)
+			if f.OrigPath != "" {
+				fmt.Fprintf(&b, " (from %s)", f.OrigPath)
+			}
+		}
+		if st.MoreFiles > 0 {
+			fmt.Fprintf(&b, "\n  ... and %d more", st.MoreFiles)
+		}
+	}
+	for _, c := range o.Commits {
+		fmt.Fprintf(&b, "\n%s %s %s: %s", shortHash(c.Hash), c.Date, c.Author, c.Subject)
+	}
+	return b.String()
+}
+
+// SSHTerminalScreenshotInput is the input for the ssh_terminal_screenshot tool.
+type SSHTerminalScreenshotInput struct {
+	TerminalID string `json:"terminal_id" jsonschema:"Terminal ID from ssh_open_terminal"`
+	WaitMs     int    `json:"wait_ms,omitempty" jsonschema:"Milliseconds to wait before capturing, e.g. for a program to finish drawing (default 0)"`
+}
+
+// SSHTerminalScreenshotOutput is the output for the ssh_terminal_screenshot tool.
+type SSHTerminalScreenshotOutput struct {
+	TerminalID    string   `json:"terminal_id"`
+	Cols          int      `json:"cols"`
+	Rows          int      `json:"rows"`
+	Lines         []string `json:"lines"`      // one per row, trailing blanks trimmed
+	CursorRow     int      `json:"cursor_row"` // 1-based
+	CursorCol     int      `json:"cursor_col"` // 1-based
+	CursorVisible bool     `json:"cursor_visible"`
+	AltScreen     bool     `json:"alt_screen"` // a full-screen program is running
+	Title         string   `json:"title,omitempty"`
+}
+
+// Text returns the screen: a header line, then the rows down to the last
+// non-blank one.
+func (o SSHTerminalScreenshotOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Screen %dx%d", o.Cols, o.Rows)
+	if o.AltScreen {
+		b.WriteString(", alternate screen")
+	}
+	fmt.Fprintf(&b, ", cursor at row %d, col %d", o.CursorRow, o.CursorCol)
+	if !o.CursorVisible {
+		b.WriteString(" (hidden)")
+	}
+	if o.Title != "" {
+		fmt.Fprintf(&b, ", title %q", o.Title)
+	}
+	last := len(o.Lines)
+	for last > 0 && o.Lines[last-1] == "" {
+		last--
+	}
+	for _, l := range o.Lines[:last] {
+		b.WriteString("\n")
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// SSHMultiplexerInput is the input for the ssh_multiplexer tool.
+type SSHMultiplexerInput struct {
+	SessionID    string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Action       string `json:"action" jsonschema:"list, create, send-keys, capture or kill"`
+	Program      string `json:"program,omitempty" jsonschema:"tmux (default) or screen"`
+	Name         string `json:"name,omitempty" jsonschema:"Session name (letters, digits, '_' and '-'); required except for list"`
+	Command      string `json:"command,omitempty" jsonschema:"create: command to run instead of a login shell; the session ends when it exits"`
+	Dir          string `json:"dir,omitempty" jsonschema:"create: starting directory; relative paths and ~ are relative to the home directory"`
+	Text         string `json:"text,omitempty" jsonschema:"send-keys: text to type; supports \\n, \\r and \\t escapes"`
+	SpecialKey   string `json:"special_key,omitempty" jsonschema:"send-keys: key to press instead of text: CTRL_C, CTRL_D, CTRL_Z, ESC, TAB, BACKSPACE, ENTER, ARROW_UP, ARROW_DOWN, ARROW_LEFT, ARROW_RIGHT"`
+	Enter        bool   `json:"enter,omitempty" jsonschema:"send-keys: press Enter after the text or key"`
+	WaitMs       int    `json:"wait_ms,omitempty" jsonschema:"send-keys: milliseconds to wait before capturing the screen (default 500)"`
+	HistoryLines int    `json:"history_lines,omitempty" jsonschema:"capture and send-keys: also return up to this many scrollback lines above the screen"`
+}
+
+// MultiplexerSession is a tmux or screen session.
+type MultiplexerSession struct {
+	Name     string `json:"name"`
+	PID      int    `json:"pid,omitempty"`     // screen
+	Windows  int    `json:"windows,omitempty"` // tmux
+	Attached bool   `json:"attached"`
+	Clients  int    `json:"clients,omitempty"` // tmux: attached clients
+	Created  string `json:"created,omitempty"` // tmux: RFC 3339; screen: as screen -ls prints it
+	Command  string `json:"command,omitempty"` // tmux: command in the active pane
+}
+
+// SSHMultiplexerOutput is the output for the ssh_multiplexer tool.
+type SSHMultiplexerOutput struct {
+	Action   string               `json:"action"`
+	Program  string               `json:"program"`
+	Name     string               `json:"name,omitempty"`
+	Message  string               `json:"message"`
+	Sessions []MultiplexerSession `json:"sessions,omitempty"`
+	Screen   string               `json:"screen,omitempty"` // capture and send-keys
+}
+
+// Text returns a human-readable representation of the multiplexer result.
+func (o SSHMultiplexerOutput) Text() string {
+	var b strings.Builder
+	b.WriteString(o.Message)
+	for _, s := range o.Sessions {
+		fmt.Fprintf(&b, "\n  %s:", s.Name)
+		if s.PID > 0 {
+			fmt.Fprintf(&b, " pid %d,", s.PID)
+		}
+		if s.Windows > 0 {
+			fmt.Fprintf(&b, " %d window(s),", s.Windows)
+		}
+		if s.Attached {
+			b.WriteString(" attached")
+			if s.Clients > 0 {
+				fmt.Fprintf(&b, " (%d client(s))", s.Clients)
+			}
+		} else {
+			b.WriteString(" detached")
+		}
+		if s.Created != "" {
+			fmt.Fprintf(&b, ", created %s", s.Created)
+		}
+		if s.Command != "" {
+			fmt.Fprintf(&b, ", running %s", s.Command)
+		}
+	}
+	if o.Screen != "" {
+		b.WriteString("\n")
+		b.WriteString(o.Screen)
+	}
+	return b.String()
+}
+
+// SSHRebootInput is the input for the ssh_reboot tool.
+type SSHRebootInput struct {
+	SessionID    string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Sudo         bool   `json:"sudo,omitempty" jsonschema:"Run shutdown with sudo (requires --enable-sudo); needed unless the session logs in as root"`
+	Timeout      int    `json:"timeout,omitempty" jsonschema:"Seconds to wait for the host to come back (default 600, at most 3600)"`
+	PollInterval int    `json:"poll_interval,omitempty" jsonschema:"Seconds between reconnect attempts (default 5, at most 60)"`
+}
+
+// SSHRebootOutput is the output for the ssh_reboot tool.
+type SSHRebootOutput struct {
+	SessionID    string `json:"session_id"`
+	DowntimeMs   int64  `json:"downtime_ms"` // from the first failed poll (or the reboot command) to reconnecting
+	TotalMs      int64  `json:"total_ms"`    // from the reboot command to reconnecting
+	Polls        int    `json:"polls"`
+	KernelBefore string `json:"kernel_before,omitempty"`
+	KernelAfter  string `json:"kernel_after,omitempty"`
+}
+
+// Text returns a human-readable representation of the reboot result.
+func (o SSHRebootOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host rebooted; session %s reconnected after %s down (%s after the reboot command, %d poll(s))",
+		o.SessionID, time.Duration(o.DowntimeMs)*time.Millisecond, time.Duration(o.TotalMs)*time.Millisecond, o.Polls)
+	if o.KernelBefore != "" && o.KernelAfter != "" {
+		if o.KernelBefore == o.KernelAfter {
+			fmt.Fprintf(&b, "\nKernel %s (unchanged)", o.KernelAfter)
+		} else {
+			fmt.Fprintf(&b, "\nKernel %s -> %s", o.KernelBefore, o.KernelAfter)
+		}
+	}
+	return b.String()
+}
+
+// SSHHealthInput is the input for the ssh_health tool.
+type SSHHealthInput struct {
+	SessionID string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Top       int      `json:"top,omitempty" jsonschema:"Processes listed by CPU and by memory (default 5, at most 20)"`
+	Mounts    []string `json:"mounts,omitempty" jsonschema:"Mount points to report disk usage for (default: the fullest real filesystems)"`
+}
+
+// HealthMemory is the memory and swap usage in an ssh_health result.
+type HealthMemory struct {
+	TotalMB     int64 `json:"total_mb"`
+	AvailableMB int64 `json:"available_mb"`
+	UsedPercent int   `json:"used_percent"`
+	SwapTotalMB int64 `json:"swap_total_mb"`
+	SwapUsedMB  int64 `json:"swap_used_mb"`
+}
+
+// HealthDisk is the usage of one filesystem in an ssh_health result.
+type HealthDisk struct {
+	Mount       string `json:"mount"`
+	Filesystem  string `json:"filesystem"`
+	SizeMB      int64  `json:"size_mb"`
+	UsedMB      int64  `json:"used_mb"`
+	AvailMB     int64  `json:"avail_mb"`
+	UsedPercent int    `json:"used_percent"`
+}
+
+// HealthProcess is one process in an ssh_health top list.
+type HealthProcess struct {
+	PID     int     `json:"pid"`
+	User    string  `json:"user"`
+	CPU     float64 `json:"cpu"` // percent of one CPU
+	Mem     float64 `json:"mem"` // percent of memory
+	RSSKB   int64   `json:"rss_kb"`
+	Command string  `json:"command"`
+}
+
+// SSHHealthOutput is the output for the ssh_health tool.
+type SSHHealthOutput struct {
+	SessionID   string          `json:"session_id"`
+	UptimeSec   int64           `json:"uptime_sec,omitempty"`
+	Load1       float64         `json:"load1"`
+	Load5       float64         `json:"load5"`
+	Load15      float64         `json:"load15"`
+	CPUs        int             `json:"cpus,omitempty"`
+	Memory      *HealthMemory   `json:"memory,omitempty"` // nil without /proc/meminfo
+	Disks       []HealthDisk    `json:"disks,omitempty"`
+	MoreDisks   int             `json:"more_disks,omitempty"` // filesystems left out of disks
+	TopCPU      []HealthProcess `json:"top_cpu,omitempty"`
+	TopMemory   []HealthProcess `json:"top_memory,omitempty"`
+	Systemd     bool            `json:"systemd"` // whether failed units were checked
+	FailedUnits []string        `json:"failed_units,omitempty"`
+	Warnings    []string        `json:"warnings,omitempty"`
+}
+
+// Text returns a human-readable representation of the health snapshot.
+func (o SSHHealthOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Health of session %s", o.SessionID)
+	if o.UptimeSec > 0 {
+		fmt.Fprintf(&b, ": up %s", formatUptime(o.UptimeSec))
+	}
+	fmt.Fprintf(&b, "\nLoad: %.2f %.2f %.2f", o.Load1, o.Load5, o.Load15)
+	if o.CPUs > 0 {
+		fmt.Fprintf(&b, " on %d CPU(s)", o.CPUs)
+	}
+	if m := o.Memory; m != nil {
+		fmt.Fprintf(&b, "\nMemory: %d%% used, %s available of %s", m.UsedPercent, formatMB(m.AvailableMB), formatMB(m.TotalMB))
+		if m.SwapTotalMB > 0 {
+			fmt.Fprintf(&b, "; swap %s of %s used", formatMB(m.SwapUsedMB), formatMB(m.SwapTotalMB))
+		} else {
+			b.WriteString("; no swap")
+		}
+	}
+	if len(o.Disks) > 0 {
+		b.WriteString("\nDisks:")
+		for _, d := range o.Disks {
+			fmt.Fprintf(&b, "\n  %-20s %3d%% of %s (%s free)", d.Mount, d.UsedPercent, formatMB(d.SizeMB), formatMB(d.AvailMB))
+		}
+		if o.MoreDisks > 0 {
+			fmt.Fprintf(&b, "\n  ... %d more", o.MoreDisks)
+		}
+	}
+	writeProcs := func(title string, procs []HealthProcess) {
+		if len(procs) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:", title)
+		for _, p := range procs {
+			fmt.Fprintf(&b, "\n  %7d %-10s %5.1f%% cpu %5.1f%% mem %8s  %s", p.PID, p.User, p.CPU, p.Mem, formatMB(p.RSSKB/1024), p.Command)
+		}
+	}
+	writeProcs("Top CPU", o.TopCPU)
+	writeProcs("Top memory", o.TopMemory)
+	if o.Systemd {
+		if len(o.FailedUnits) == 0 {
+			b.WriteString("\nFailed units: none")
+		} else {
+			fmt.Fprintf(&b, "\nFailed units: %s", strings.Join(o.FailedUnits, ", "))
+		}
+	}
+	if len(o.Warnings) > 0 {
+		fmt.Fprintf(&b, "\nWarnings: %s", strings.Join(o.Warnings, "; "))
+	} else {
+		b.WriteString("\nNo warnings")
+	}
+	return b.String()
+}
+
+// SSHDownloadFilesInput is the input for the ssh_download_files tool.
+type SSHDownloadFilesInput struct {
+	SessionID   string   `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	RemotePaths []string `json:"remote_paths" jsonschema:"Remote files to download, at most 100; entries with * ? or [ are globs expanded on the remote host (e.g. /var/log/nginx/*.log)"`
+	LocalDir    string   `json:"local_dir" jsonschema:"Local directory the files are written to under their base names; created if missing"`
+	Parallel    int      `json:"parallel,omitempty" jsonschema:"Files copied at once, 1-32 (default: the server's --transfer-workers)"`
+}
+
+// DownloadFileResult is the status of one file in an ssh_download_files result.
+type DownloadFileResult struct {
+	RemotePath string `json:"remote_path"`
+	LocalPath  string `json:"local_path,omitempty"`
+	Status     string `json:"status"` // DownloadStatusOK, DownloadStatusSkipped or DownloadStatusFailed
+	Bytes      int64  `json:"bytes,omitempty"`
+	Error      string `json:"error,omitempty"` // why the file was skipped or failed
+}
+
+// SSHDownloadFilesOutput is the output for the ssh_download_files tool.
+type SSHDownloadFilesOutput struct {
+	LocalDir   string               `json:"local_dir"`
+	Downloaded int                  `json:"downloaded"`
+	Skipped    int                  `json:"skipped"`
+	Failed     int                  `json:"failed"`
+	BytesRead  int64                `json:"bytes_read"`
+	Files      []DownloadFileResult `json:"files"`
+}
+
+// Text returns a human-readable representation of the batch download result.
+func (o SSHDownloadFilesOutput) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Downloaded %d of %d files (%d bytes) to %s", o.Downloaded, len(o.Files), o.BytesRead, o.LocalDir)
+	if o.Skipped > 0 {
+		fmt.Fprintf(&b, ", %d skipped", o.Skipped)
+	}
+	if o.Failed > 0 {
+		fmt.Fprintf(&b, ", %d failed", o.Failed)
+	}
+	for _, f := range o.Files {
+		if f.Status == DownloadStatusOK {
+			fmt.Fprintf(&b, "\n  ok      %s (%d bytes)", f.RemotePath, f.Bytes)
+		} else {
+			fmt.Fprintf(&b, "\n  %-7s %s: %s", f.Status, f.RemotePath, f.Error)
+		}
+	}
+	return b.String()
+}
+
+// SSHGlobInput is the input for the ssh_glob tool.
+type SSHGlobInput struct {
+	SessionID string `json:"session_id" jsonschema:"Session ID from ssh_connect"`
+	Pattern   string `json:"pattern" jsonschema:"Remote glob, e.g. /etc/nginx/sites-*/*.conf or ~/app/**/*.py; * ? and [...] match within one path component (dotfiles included), ** any number of directories"`
+	Type      string `json:"type,omitempty" jsonschema:"Only matches of this type: 'file', 'directory', 'symlink', or 'other'"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum matches to return (default 200, max 5000)"`
+}
+
+// GlobMatch is one path matched by ssh_glob.
+type GlobMatch struct {
+	Path       string `json:"path"`
+	Type       string `json:"type"`
+	Size       int64  `json:"size"`
+	Mode       string `json:"mode"`
+	ModTime    string `json:"mod_time"`
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
+// SSHGlobOutput is the output for the ssh_glob tool.
+type SSHGlobOutput struct {
+	Pattern string      `json:"pattern"`
+	HasMore bool        `json:"has_more"` // more matches than limit
+	Matches []GlobMatch `json:"matches"`
+}
+
+// Text returns a human-readable representation of the glob matches.
+func (o SSHGlobOutput) Text() string {
+	if len(o.Matches) == 0 {
+		return fmt.Sprintf("%s: no matches", o.Pattern)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d matches", o.Pattern, len(o.Matches))
+	if o.HasMore {
+		sb.WriteString(" (limit reached; narrow the pattern or raise limit)")
+	}
+	for _, m := range o.Matches {
+		fmt.Fprintf(&sb, "\n%s %10d %s %s", m.Mode, m.Size, m.ModTime, m.Path)
+		if m.Type == "directory" {
+			sb.WriteString("/")
+		}
+		if m.LinkTarget != "" {
+			fmt.Fprintf(&sb, " -> %s", m.LinkTarget)
+		}
+	}
+	return sb.String()
+}
@@ -2,7 +2,9 @@ package tools
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSSHConnectInput_NoUseSSHConfig(t *testing.T) {
@@ -71,3 +73,88 @@ func TestSSHReadOutputOutput_TextHasMoreInsertsNewline(t *testing.T) {
 		t.Errorf("Text() = %q, want %q", out.Text(), expected)
 	}
 }
+
+func TestSSHListSessionsOutput_TextStatistics(t *testing.T) {
+	out := SSHListSessionsOutput{
+		Count: 1,
+		Sessions: []SessionInfo{{
+			SessionID:       "user@host:22",
+			Connected:       true,
+			CommandCount:    4,
+			FailedCommands:  1,
+			CommandTimeMs:   1500,
+			BytesUploaded:   10,
+			BytesDownloaded: 20,
+			LastError:       "command exited with code 2",
+			LastUsed:        "2025-01-01T00:00:00Z",
+		}},
+	}
+	text := out.Text()
+	for _, want := range []string{"4 commands (1 failed)", "1500ms command time", "10 bytes up / 20 bytes down", "last error: command exited with code 2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestSSHListSessionsOutput_TextExpiry(t *testing.T) {
+	out := SSHListSessionsOutput{
+		Count: 1,
+		Sessions: []SessionInfo{{
+			SessionID: "user@host:22",
+			Connected: true,
+			LastUsed:  "2025-01-01T00:00:00Z",
+			ExpiresAt: "2025-01-01T08:00:00Z",
+			Warning:   expiryWarning(5 * time.Minute),
+		}},
+	}
+	text := out.Text()
+	for _, want := range []string{"expires 2025-01-01T08:00:00Z", "warning: session expires in 5m0s"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+	if w := expiryWarning(time.Hour); w != "" {
+		t.Errorf("expiryWarning(1h) = %q, want none", w)
+	}
+}
+
+func TestSSHListSessionsOutput_TextHostKey(t *testing.T) {
+	out := SSHListSessionsOutput{
+		Count: 1,
+		Sessions: []SessionInfo{{
+			SessionID:          "user@host:22",
+			Connected:          true,
+			LastUsed:           "2025-01-01T00:00:00Z",
+			HostKeyType:        "ssh-ed25519",
+			HostKeyFingerprint: "SHA256:abc",
+		}},
+	}
+	if text := out.Text(); !strings.Contains(text, "host key: ssh-ed25519 SHA256:abc") {
+		t.Errorf("Text() missing host key:\n%s", text)
+	}
+}
+
+func TestSSHUploadOutput_TextSkipped(t *testing.T) {
+	out := SSHUploadOutput{
+		Message:        "Uploaded 2 files (10 bytes) to /srv/app",
+		OwnerPreserved: true,
+		Skipped:        []string{"/src/app/loop (symlink loop)", "/src/app/fifo (special file)"},
+	}
+	want := "Uploaded 2 files (10 bytes) to /srv/app (local ownership preserved)\n" +
+		"Skipped 2 entries:\n  /src/app/loop (symlink loop)\n  /src/app/fifo (special file)"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if got := (SSHDownloadOutput{Message: "Downloaded"}).Text(); got != "Downloaded" {
+		t.Errorf("Text() without skipped entries = %q", got)
+	}
+}
+
+func TestSSHUploadOutput_TextSpaceCheck(t *testing.T) {
+	out := SSHUploadOutput{Message: "Uploaded 5 bytes to /srv/f", SpaceCheck: "5 bytes needed, 4096 free on /srv (df)"}
+	want := "Uploaded 5 bytes to /srv/f\nSpace check: 5 bytes needed, 4096 free on /srv (df)"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -2,9 +2,18 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
 	"github.com/n0madic/ssh-mcp/internal/connection"
 	"github.com/n0madic/ssh-mcp/internal/security"
 	"github.com/n0madic/ssh-mcp/internal/sshclient"
@@ -12,58 +21,256 @@ import (
 
 // UploadDeps holds dependencies for the ssh_upload tool handler.
 type UploadDeps struct {
-	Pool         *connection.Pool
-	LocalBaseDir string
-	RateLimiter  *security.RateLimiter
+	Pool        *connection.Pool
+	LocalDirs   []config.LocalDir
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+	Filter      *security.Filter // gates the remote sha256sum used by verify
 }
 
 // HandleUpload implements the ssh_upload tool.
 // It auto-detects whether local_path is a file or directory and delegates accordingly.
 func HandleUpload(ctx context.Context, deps *UploadDeps, input SSHUploadInput) (*SSHUploadOutput, error) {
-	if err := security.ValidateLocalPath(input.LocalPath, deps.LocalBaseDir); err != nil {
+	if err := security.ValidateLocalAccess(input.LocalPath, deps.LocalDirs, false); err != nil {
 		return nil, fmt.Errorf("invalid local path: %w", err)
 	}
 	if err := security.ValidatePath(input.RemotePath); err != nil {
 		return nil, fmt.Errorf("invalid remote path: %w", err)
 	}
 
+	symlinks, err := sshclient.ParseSymlinkPolicy(input.Symlinks)
+	if err != nil {
+		return nil, err
+	}
+	parallel, err := transferWorkers(deps.Config, input.Parallel)
+	if err != nil {
+		return nil, err
+	}
+
 	info, err := os.Stat(input.LocalPath)
 	if err != nil {
 		return nil, fmt.Errorf("stat local path: %w", err)
 	}
 
-	_, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	sftpClient, err := sshclient.NewSFTPClient(client)
+	sftpClient, err := transferClient(deps.Config, client)
 	if err != nil {
 		return nil, err
 	}
-	defer sftpClient.Close()
+	if sftpClient == nil {
+		if input.PreserveOwner {
+			return nil, fmt.Errorf("preserve_owner needs SFTP; the scp protocol cannot set ownership")
+		}
+		if symlinks != sshclient.SymlinkSkip {
+			return nil, fmt.Errorf("symlinks=%s needs SFTP; over scp symlinks are always skipped", symlinks)
+		}
+		if input.Verify {
+			return nil, fmt.Errorf("verify needs SFTP")
+		}
+		if input.Parallel > 1 {
+			return nil, fmt.Errorf("parallel needs SFTP; scp copies one file at a time")
+		}
+	} else {
+		defer sftpClient.Close()
+		input.RemotePath = expandRemotePath(conn, sftpClient, input.RemotePath)
+	}
 
-	input.RemotePath = sshclient.ExpandRemotePath(sftpClient, input.RemotePath)
+	modes := uploadModes(deps.Config)
+	var space string
+	if input.CheckSpace {
+		if space, err = checkUploadSpace(ctx, deps, conn, client, sftpClient, input.LocalPath, input.RemotePath); err != nil {
+			return nil, err
+		}
+	}
 
-	if info.IsDir() {
-		fileCount, totalBytes, err := sshclient.UploadDir(sftpClient, input.LocalPath, input.RemotePath)
+	if sftpClient == nil {
+		fileCount, totalBytes, err := sshclient.SCPUpload(ctx, client, input.LocalPath, input.RemotePath, modes)
 		if err != nil {
-			return nil, fmt.Errorf("upload directory: %w", err)
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("upload failed: %w", err)
 		}
+		conn.AddBytesUploaded(totalBytes)
 		return &SSHUploadOutput{
 			FilesUploaded: fileCount,
 			BytesWritten:  totalBytes,
-			Message:       fmt.Sprintf("Uploaded %d files (%d bytes) to %s", fileCount, totalBytes, input.RemotePath),
+			Protocol:      config.TransferSCP,
+			SpaceCheck:    space,
+			Message:       fmt.Sprintf("Uploaded %d files (%d bytes) to %s via scp", fileCount, totalBytes, input.RemotePath),
 		}, nil
 	}
 
-	n, err := sshclient.UploadFile(sftpClient, input.LocalPath, input.RemotePath, nil)
+	if info.IsDir() {
+		opts := sshclient.UploadOptions{PreserveOwner: input.PreserveOwner, Symlinks: symlinks, Parallel: parallel, Modes: modes}
+		if len(deps.LocalDirs) > 0 {
+			// Following a link must not escape --local-base-dir.
+			opts.AllowFollow = func(p string) error { return security.ValidateLocalAccess(p, deps.LocalDirs, false) }
+		}
+		stats, err := sshclient.UploadDir(ctx, sftpClient, input.LocalPath, input.RemotePath, opts)
+		if err != nil {
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("upload directory: %w", err)
+		}
+		conn.AddBytesUploaded(stats.Bytes)
+		out := &SSHUploadOutput{
+			FilesUploaded:  stats.Files,
+			BytesWritten:   stats.Bytes,
+			OwnerPreserved: input.PreserveOwner,
+			Skipped:        stats.Skipped,
+			SpaceCheck:     space,
+			Message:        fmt.Sprintf("Uploaded %d files (%d bytes) to %s", stats.Files, stats.Bytes, input.RemotePath),
+		}
+		if input.Verify {
+			v, err := verifyTransfer(ctx, deps.Filter, deps.Config, conn, client, sftpClient, stats.Copied)
+			if err != nil {
+				return nil, err
+			}
+			out.Verified, out.VerifyMethod, out.Mismatches = v.Verified, v.Method, v.Mismatches
+		}
+		return out, nil
+	}
+
+	mode := modes.FileMode(info.Mode())
+	n, err := sshclient.UploadFile(ctx, sftpClient, input.LocalPath, input.RemotePath, &mode)
 	if err != nil {
+		conn.SetLastError(err)
 		return nil, fmt.Errorf("upload failed: %w", err)
 	}
-	return &SSHUploadOutput{
-		FilesUploaded: 1,
-		BytesWritten:  n,
-		Message:       fmt.Sprintf("Uploaded %d bytes to %s", n, input.RemotePath),
-	}, nil
+	conn.AddBytesUploaded(n)
+	if input.PreserveOwner {
+		if err := sshclient.ChownLikeLocal(sftpClient, input.RemotePath, info); err != nil {
+			conn.SetLastError(err)
+			return nil, fmt.Errorf("uploaded %d bytes but could not preserve ownership: %w", n, err)
+		}
+	}
+	out := &SSHUploadOutput{
+		FilesUploaded:  1,
+		BytesWritten:   n,
+		OwnerPreserved: input.PreserveOwner,
+		SpaceCheck:     space,
+		Message:        fmt.Sprintf("Uploaded %d bytes to %s", n, input.RemotePath),
+	}
+	if input.Verify {
+		files := []sshclient.FilePair{{Local: input.LocalPath, Remote: input.RemotePath}}
+		v, err := verifyTransfer(ctx, deps.Filter, deps.Config, conn, client, sftpClient, files)
+		if err != nil {
+			return nil, err
+		}
+		out.Verified, out.VerifyMethod, out.Mismatches = v.Verified, v.Method, v.Mismatches
+	}
+	return out, nil
+}
+
+// uploadModes returns the --upload-file-mode, --upload-dir-mode and
+// --upload-umask policy.
+func uploadModes(cfg *config.SSHConfig) sshclient.ModePolicy {
+	if cfg == nil {
+		return sshclient.ModePolicy{}
+	}
+	return sshclient.ModePolicy{File: cfg.UploadFileMode, Dir: cfg.UploadDirMode, Umask: cfg.UploadUmask}
+}
+
+// transferWorkers returns how many files a directory transfer copies at
+// once: parallel if set, else the server's --transfer-workers.
+func transferWorkers(cfg *config.SSHConfig, parallel int) (int, error) {
+	switch {
+	case parallel < 0 || parallel > sshclient.MaxTransferWorkers:
+		return 0, fmt.Errorf("parallel must be between 1 and %d", sshclient.MaxTransferWorkers)
+	case parallel > 0:
+		return parallel, nil
+	case cfg != nil:
+		return cfg.TransferWorkers, nil
+	}
+	return 1, nil
+}
+
+// checkUploadSpace fails if the remote filesystem that will hold remotePath
+// has less room than the upload needs, so a full disk is reported before
+// anything is written rather than as ENOSPC halfway through. Free space comes
+// from statvfs@openssh.com, or from df where SFTP or the extension is
+// missing. If neither works the upload goes ahead; the returned summary
+// says so.
+func checkUploadSpace(ctx context.Context, deps *UploadDeps, conn *connection.Connection, client *ssh.Client, sc *sftp.Client, localPath, remotePath string) (string, error) {
+	need, err := sshclient.LocalSize(localPath)
+	if err != nil {
+		return "", fmt.Errorf("measure local path: %w", err)
+	}
+
+	// scp needs the parent to exist; over SFTP the upload creates missing
+	// directories, so the nearest existing one decides the filesystem.
+	dir := path.Dir(path.Clean(remotePath))
+	var free uint64
+	var method string
+	if sc != nil {
+		if dir, err = sshclient.NearestExistingDir(sc, remotePath); err != nil {
+			return "", err
+		}
+		// A file being replaced frees its own space.
+		if info, err := sc.Stat(remotePath); err == nil && info.Mode().IsRegular() {
+			need -= min(need, info.Size())
+		}
+		if free, err = sshclient.FreeSpace(sc, dir); err == nil {
+			method = "statvfs"
+		} else if !errors.Is(err, sshclient.ErrStatVFSUnsupported) {
+			log.Printf("upload: %v; trying df", err)
+		}
+	}
+	if method == "" {
+		var ok bool
+		if free, ok = remoteDfAvailable(ctx, deps, conn, client, dir); !ok {
+			return "free space not checked (no statvfs@openssh.com or usable df on the host)", nil
+		}
+		method = "df"
+	}
+
+	if uint64(need) > free {
+		return "", fmt.Errorf("not enough free space on the remote filesystem holding %s: upload needs %d bytes, %d available", dir, need, free)
+	}
+	return fmt.Sprintf("%d bytes needed, %d free on %s (%s)", need, free, dir, method), nil
+}
+
+// remoteDfAvailable returns the available bytes that POSIX df reports for
+// dir. ok is false on Windows hosts, if the command filter denies df, or if
+// its output can't be parsed.
+func remoteDfAvailable(ctx context.Context, deps *UploadDeps, conn *connection.Connection, client *ssh.Client, dir string) (uint64, bool) {
+	if deps.Filter == nil || deps.Config == nil || conn.GetRemoteInfo().OS == "Windows" {
+		return 0, false
+	}
+	// Commands start in the home directory, where ~ would not expand once quoted.
+	switch {
+	case dir == "~":
+		dir = "."
+	case strings.HasPrefix(dir, "~/"):
+		dir = strings.TrimPrefix(dir, "~/")
+	}
+	cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "df", "-Pk", "--", dir)
+	if err != nil {
+		return 0, false
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+	if err != nil || res.TimedOut || res.ExitCode != 0 {
+		return 0, false
+	}
+	return parseDfAvailable(res.Stdout)
+}
+
+// parseDfAvailable reads the Available column (1K blocks) of `df -Pk`
+// output for a single filesystem.
+func parseDfAvailable(out string) (uint64, bool) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		return 0, false
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 6 {
+		return 0, false
+	}
+	kb, err := strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return kb * 1024, true
 }
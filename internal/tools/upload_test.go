@@ -0,0 +1,36 @@
+package tools
+
+import "testing"
+
+func TestParseDfAvailable(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want uint64
+		ok   bool
+	}{
+		{
+			"linux",
+			"Filesystem     1024-blocks     Used Available Capacity Mounted on\n" +
+				"/dev/sda1         41152736 20123456  18912345      52% /\n",
+			18912345 * 1024, true,
+		},
+		{
+			"mount point with spaces",
+			"Filesystem 1024-blocks Used Available Capacity Mounted on\n" +
+				"/dev/sdb1 1000 600 400 60% /mnt/my disk\n",
+			400 * 1024, true,
+		},
+		{"header only", "Filesystem 1024-blocks Used Available Capacity Mounted on\n", 0, false},
+		{"garbage", "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/x a b c d /\n", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDfAvailable(tt.out)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseDfAvailable() = %d, %v; want %d, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// UsageDeps holds dependencies for the ssh_usage tool handler.
+type UsageDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+	Security    *config.SecurityConfig
+}
+
+// HandleUsage implements the ssh_usage tool. It reports the caller's
+// sessions with their counters and the rate limit budget left on each of
+// their hosts. Reading it consumes no rate limit.
+func HandleUsage(ctx context.Context, deps *UsageDeps, input SSHUsageInput) (*SSHUsageOutput, error) {
+	conns := deps.Pool.ListConnections(ctx)
+	if input.SessionID != "" {
+		conns = slices.DeleteFunc(conns, func(c connection.ConnectionInfo) bool {
+			return string(c.SessionID) != input.SessionID
+		})
+		if len(conns) == 0 {
+			return nil, fmt.Errorf("session %q not found", input.SessionID)
+		}
+	}
+	slices.SortFunc(conns, func(a, b connection.ConnectionInfo) int {
+		return strings.Compare(string(a.SessionID), string(b.SessionID))
+	})
+
+	out := &SSHUsageOutput{
+		RateLimit:        deps.Security.RateLimit,
+		RateLimitFileOps: deps.Security.RateLimitFileOps,
+		RateLimitWaitMs:  deps.RateLimiter.MaxWait().Milliseconds(),
+		MaxConcurrent:    deps.Security.MaxConcurrent,
+		Sessions:         make([]SessionUsage, len(conns)),
+	}
+	var hosts []string
+	for i, c := range conns {
+		out.Sessions[i] = SessionUsage{
+			SessionID:       string(c.SessionID),
+			Host:            c.Host,
+			CommandCount:    c.CommandCount,
+			FailedCommands:  c.FailedCommands,
+			ActiveCommands:  c.ActiveCommands,
+			BytesUploaded:   c.BytesUploaded,
+			BytesDownloaded: c.BytesDownloaded,
+		}
+		if !slices.Contains(hosts, c.Host) {
+			hosts = append(hosts, c.Host)
+		}
+	}
+	slices.Sort(hosts)
+	for _, h := range hosts {
+		u := deps.RateLimiter.Usage(h)
+		out.Hosts = append(out.Hosts, HostRateLimit{
+			Host:      h,
+			Burst:     u.Burst,
+			Remaining: u.Remaining,
+			NextInMs:  u.NextIn.Milliseconds(),
+		})
+	}
+	return out, nil
+}
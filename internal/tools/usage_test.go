@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+func TestHandleUsage_NoSessions(t *testing.T) {
+	cfg := &config.SSHConfig{ConfigPath: "/nonexistent/ssh/config"}
+	limiter := security.NewRateLimiter(60)
+	limiter.SetMaxWait(5 * time.Second)
+	deps := &UsageDeps{
+		Pool:        connection.NewPool(cfg, connection.NewAuthDiscovery(cfg)),
+		RateLimiter: limiter,
+		Security:    &config.SecurityConfig{RateLimit: 60, MaxConcurrent: 4},
+	}
+
+	out, err := HandleUsage(context.Background(), deps, SSHUsageInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.RateLimit != 60 || out.RateLimitWaitMs != 5000 || out.MaxConcurrent != 4 {
+		t.Errorf("limits = %+v", out)
+	}
+	text := out.Text()
+	for _, want := range []string{"60 requests/min per host", "wait up to 5s", "at most 4", "No active sessions"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	if _, err := HandleUsage(context.Background(), deps, SSHUsageInput{SessionID: "nobody@nowhere:22"}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected session not found, got %v", err)
+	}
+}
+
+func TestSSHUsageOutputText(t *testing.T) {
+	out := SSHUsageOutput{
+		RateLimit:        60,
+		RateLimitFileOps: true,
+		Hosts: []HostRateLimit{
+			{Host: "db", Burst: 6, Remaining: 0, NextInMs: 850},
+			{Host: "web", Burst: 6, Remaining: 4},
+		},
+		Sessions: []SessionUsage{
+			{SessionID: "root@db:22", Host: "db", CommandCount: 12, FailedCommands: 1, ActiveCommands: 2, BytesUploaded: 2048},
+			{SessionID: "root@web:22", Host: "web", CommandCount: 3},
+		},
+	}
+	text := out.Text()
+	for _, want := range []string{
+		"file operations included",
+		"db — 0 of 6 requests available, next in 850ms",
+		"web — 4 of 6 requests available\n",
+		"Sessions (2):",
+		"root@db:22 — 12 commands (1 failed), 2 running, 2048 bytes up / 0 bytes down",
+		"root@web:22 — 3 commands, 0 bytes up / 0 bytes down",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// UserDeps holds dependencies for the ssh_user tool handler.
+type UserDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// Actions of ssh_user.
+const (
+	userActionList       = "list"
+	userActionCreate     = "create"
+	userActionDisable    = "disable"
+	userActionAddToGroup = "add-to-group"
+)
+
+var (
+	// accountNamePattern matches user and group names both useradd and
+	// macOS accept; the first character rules out options.
+	accountNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,31}$`)
+	// shellPathPattern matches login shells.
+	shellPathPattern = regexp.MustCompile(`^/[A-Za-z0-9_./-]+$`)
+)
+
+// HandleUser implements the ssh_user tool. list reads the account
+// databases (getent on Linux, dscacheutil on macOS); create, disable and
+// add-to-group wrap useradd/usermod on Linux and sysadminctl, pwpolicy and
+// dscl on macOS. Changes need root: sudo must be set unless the session
+// logs in as root. Every command goes through the command filter, and
+// changes return the account as it is afterwards.
+func HandleUser(ctx context.Context, deps *UserDeps, input SSHUserInput) (*SSHUserOutput, error) {
+	if input.User != "" && !accountNamePattern.MatchString(input.User) {
+		return nil, fmt.Errorf("invalid user name %q", input.User)
+	}
+	for _, g := range input.Groups {
+		if !accountNamePattern.MatchString(g) {
+			return nil, fmt.Errorf("invalid group name %q", g)
+		}
+	}
+	if input.Shell != "" && !shellPathPattern.MatchString(input.Shell) {
+		return nil, fmt.Errorf("invalid shell %q (must be an absolute path)", input.Shell)
+	}
+	if strings.ContainsAny(input.Comment, ":\n\r") {
+		return nil, fmt.Errorf("comment must not contain ':' or line breaks")
+	}
+	switch input.Action {
+	case userActionList:
+		if len(input.Groups) > 0 || input.Shell != "" || input.Comment != "" {
+			return nil, fmt.Errorf("groups, shell and comment only apply to create and add-to-group")
+		}
+	case userActionCreate:
+		if input.User == "" {
+			return nil, fmt.Errorf("user is required for create")
+		}
+	case userActionDisable:
+		if input.User == "" {
+			return nil, fmt.Errorf("user is required for disable")
+		}
+		if len(input.Groups) > 0 || input.Shell != "" || input.Comment != "" {
+			return nil, fmt.Errorf("groups, shell and comment only apply to create and add-to-group")
+		}
+	case userActionAddToGroup:
+		if input.User == "" || len(input.Groups) == 0 {
+			return nil, fmt.Errorf("user and groups are required for add-to-group")
+		}
+		if input.Shell != "" || input.Comment != "" {
+			return nil, fmt.Errorf("shell and comment only apply to create")
+		}
+	default:
+		return nil, fmt.Errorf("invalid action %q (must be list, create, disable or add-to-group)", input.Action)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	osName := conn.GetRemoteInfo().OS
+	if osName != "Linux" && osName != "Darwin" {
+		return nil, fmt.Errorf("ssh_user supports Linux and macOS hosts, not %s", osNameOrUnknown(osName))
+	}
+	run := func(sudo bool, program string, args ...string) (*remoteResult, error) {
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, sudo, program, args...)
+		if err != nil {
+			return nil, err
+		}
+		res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return res, remoteFailure(program, res)
+		}
+		return res, nil
+	}
+
+	out := &SSHUserOutput{Action: input.Action}
+	if input.Action != userActionList {
+		if !input.Sudo && conn.User != "root" {
+			return nil, fmt.Errorf("%s needs root: set sudo: true (requires --enable-sudo)", input.Action)
+		}
+		for _, argv := range userChangeCommands(osName, input) {
+			if _, err := run(input.Sudo, argv[0], argv[1:]...); err != nil {
+				conn.SetLastError(err)
+				return nil, err
+			}
+		}
+	}
+
+	// Read the accounts back, with sudo if the change used it.
+	users, groups := []string{"getent", "passwd"}, []string{"getent", "group"}
+	if osName == "Darwin" {
+		users, groups = []string{"dscacheutil", "-q", "user"}, []string{"dscacheutil", "-q", "group"}
+	}
+	if input.User != "" {
+		users = append(users, input.User)
+		if osName == "Darwin" {
+			users = slices.Insert(users, 3, "-a", "name")
+		}
+	}
+	ures, err := run(input.Sudo, users[0], users[1:]...)
+	if ures != nil && ures.ExitCode == 2 && !ures.TimedOut && input.User != "" {
+		return nil, fmt.Errorf("no user %q", input.User) // getent: key not found
+	}
+	if err != nil {
+		return nil, err
+	}
+	gres, err := run(input.Sudo, groups[0], groups[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []UserAccount
+	var groupList []accountGroup
+	if osName == "Darwin" {
+		accounts, groupList = parseDSCacheUsers(ures.Stdout), parseDSCacheGroups(gres.Stdout)
+	} else {
+		accounts, groupList = parsePasswd(ures.Stdout), parseGroupFile(gres.Stdout)
+	}
+	if input.User != "" && len(accounts) == 0 {
+		return nil, fmt.Errorf("no user %q", input.User)
+	}
+	for _, a := range accounts {
+		if input.User == "" && !input.IncludeSystem && isSystemAccount(osName, a.UID) {
+			continue
+		}
+		a.Groups = accountGroups(a, groupList)
+		out.Users = append(out.Users, a)
+	}
+
+	switch input.Action {
+	case userActionList:
+		out.Message = fmt.Sprintf("%d user(s)", len(out.Users))
+	case userActionCreate:
+		out.Message = fmt.Sprintf("Created user %s", input.User)
+	case userActionDisable:
+		out.Message = fmt.Sprintf("Disabled user %s", input.User)
+	case userActionAddToGroup:
+		out.Message = fmt.Sprintf("Added %s to %s", input.User, strings.Join(input.Groups, ", "))
+	}
+	return out, nil
+}
+
+// userChangeCommands returns the commands that carry out a change action.
+// Disabling on Linux locks the password (as passwd -l does) and expires
+// the account, so key logins stop working too.
+func userChangeCommands(osName string, input SSHUserInput) [][]string {
+	if osName == "Darwin" {
+		switch input.Action {
+		case userActionCreate:
+			argv := []string{"sysadminctl", "-addUser", input.User}
+			if input.Comment != "" {
+				argv = append(argv, "-fullName", input.Comment)
+			}
+			if input.Shell != "" {
+				argv = append(argv, "-shell", input.Shell)
+			}
+			cmds := [][]string{argv}
+			for _, g := range input.Groups {
+				cmds = append(cmds, []string{"dscl", ".", "-append", "/Groups/" + g, "GroupMembership", input.User})
+			}
+			return cmds
+		case userActionDisable:
+			return [][]string{{"pwpolicy", "-u", input.User, "-disableuser"}}
+		default:
+			var cmds [][]string
+			for _, g := range input.Groups {
+				cmds = append(cmds, []string{"dscl", ".", "-append", "/Groups/" + g, "GroupMembership", input.User})
+			}
+			return cmds
+		}
+	}
+	switch input.Action {
+	case userActionCreate:
+		argv := []string{"useradd", "--create-home"}
+		if input.Shell != "" {
+			argv = append(argv, "--shell", input.Shell)
+		}
+		if input.Comment != "" {
+			argv = append(argv, "--comment", input.Comment)
+		}
+		if len(input.Groups) > 0 {
+			argv = append(argv, "--groups", strings.Join(input.Groups, ","))
+		}
+		return [][]string{append(argv, input.User)}
+	case userActionDisable:
+		return [][]string{{"usermod", "--lock", "--expiredate", "1", input.User}}
+	default:
+		return [][]string{{"usermod", "--append", "--groups", strings.Join(input.Groups, ","), input.User}}
+	}
+}
+
+// accountGroup is a group database entry.
+type accountGroup struct {
+	name    string
+	gid     int
+	members []string
+}
+
+// parsePasswd reads getent passwd output.
+func parsePasswd(out string) []UserAccount {
+	var users []UserAccount
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(strings.TrimSpace(line), ":")
+		if len(f) != 7 {
+			continue
+		}
+		uid, err1 := strconv.Atoi(f[2])
+		gid, err2 := strconv.Atoi(f[3])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		users = append(users, UserAccount{Name: f[0], UID: uid, GID: gid, Comment: strings.TrimRight(f[4], ","), Home: f[5], Shell: f[6]})
+	}
+	return users
+}
+
+// parseGroupFile reads getent group output.
+func parseGroupFile(out string) []accountGroup {
+	var groups []accountGroup
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Split(strings.TrimSpace(line), ":")
+		if len(f) != 4 {
+			continue
+		}
+		gid, err := strconv.Atoi(f[2])
+		if err != nil {
+			continue
+		}
+		g := accountGroup{name: f[0], gid: gid}
+		if f[3] != "" {
+			g.members = strings.Split(f[3], ",")
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// dsCacheRecords splits dscacheutil -q output into records of key: value
+// lines separated by blank lines.
+func dsCacheRecords(out string) []map[string]string {
+	var records []map[string]string
+	rec := map[string]string{}
+	for _, line := range strings.Split(out+"\n", "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(rec) > 0 {
+				records = append(records, rec)
+				rec = map[string]string{}
+			}
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			rec[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return records
+}
+
+// parseDSCacheUsers reads dscacheutil -q user output.
+func parseDSCacheUsers(out string) []UserAccount {
+	var users []UserAccount
+	for _, r := range dsCacheRecords(out) {
+		uid, err1 := strconv.Atoi(r["uid"])
+		gid, err2 := strconv.Atoi(r["gid"])
+		if r["name"] == "" || err1 != nil || err2 != nil {
+			continue
+		}
+		users = append(users, UserAccount{Name: r["name"], UID: uid, GID: gid, Comment: r["gecos"], Home: r["dir"], Shell: r["shell"]})
+	}
+	return users
+}
+
+// parseDSCacheGroups reads dscacheutil -q group output.
+func parseDSCacheGroups(out string) []accountGroup {
+	var groups []accountGroup
+	for _, r := range dsCacheRecords(out) {
+		gid, err := strconv.Atoi(r["gid"])
+		if r["name"] == "" || err != nil {
+			continue
+		}
+		groups = append(groups, accountGroup{name: r["name"], gid: gid, members: strings.Fields(r["users"])})
+	}
+	return groups
+}
+
+// accountGroups returns the primary group of a, then the groups that list
+// it as a member.
+func accountGroups(a UserAccount, groups []accountGroup) []string {
+	var names []string
+	for _, g := range groups {
+		if g.gid == a.GID && !slices.Contains(names, g.name) {
+			names = slices.Insert(names, 0, g.name)
+		}
+	}
+	for _, g := range groups {
+		if slices.Contains(g.members, a.Name) && !slices.Contains(names, g.name) {
+			names = append(names, g.name)
+		}
+	}
+	return names
+}
+
+// isSystemAccount reports whether uid belongs to a system account rather
+// than a person: below the first regular UID (1000 on Linux, 500 on macOS),
+// or nobody.
+func isSystemAccount(osName string, uid int) bool {
+	if osName == "Darwin" {
+		return uid < 500
+	}
+	return uid < 1000 || uid == 65534
+}
+
+// osNameOrUnknown returns osName, or "an unknown OS" if it is empty.
+func osNameOrUnknown(osName string) string {
+	if osName == "" {
+		return "an unknown OS"
+	}
+	return osName
+}
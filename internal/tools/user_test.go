@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandleUser_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHUserInput
+		want  string
+	}{
+		{SSHUserInput{Action: "delete", User: "alice"}, "invalid action"},
+		{SSHUserInput{Action: "create"}, "user is required"},
+		{SSHUserInput{Action: "create", User: "-o"}, "invalid user name"},
+		{SSHUserInput{Action: "create", User: "alice;id"}, "invalid user name"},
+		{SSHUserInput{Action: "create", User: "alice", Groups: []string{"wheel,root"}}, "invalid group name"},
+		{SSHUserInput{Action: "create", User: "alice", Shell: "bash"}, "invalid shell"},
+		{SSHUserInput{Action: "create", User: "alice", Comment: "a:b"}, "comment"},
+		{SSHUserInput{Action: "disable"}, "user is required"},
+		{SSHUserInput{Action: "disable", User: "alice", Groups: []string{"docker"}}, "only apply"},
+		{SSHUserInput{Action: "add-to-group", User: "alice"}, "user and groups are required"},
+		{SSHUserInput{Action: "add-to-group", User: "alice", Groups: []string{"docker"}, Shell: "/bin/sh"}, "only apply to create"},
+		{SSHUserInput{Action: "list", Comment: "x"}, "only apply"},
+	}
+	for _, tt := range tests {
+		_, err := HandleUser(context.Background(), &UserDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestUserChangeCommands(t *testing.T) {
+	create := SSHUserInput{Action: "create", User: "alice", Shell: "/bin/bash", Comment: "Alice Smith", Groups: []string{"docker", "adm"}}
+	tests := []struct {
+		os    string
+		input SSHUserInput
+		want  [][]string
+	}{
+		{"Linux", create, [][]string{{"useradd", "--create-home", "--shell", "/bin/bash", "--comment", "Alice Smith", "--groups", "docker,adm", "alice"}}},
+		{"Linux", SSHUserInput{Action: "disable", User: "alice"}, [][]string{{"usermod", "--lock", "--expiredate", "1", "alice"}}},
+		{"Linux", SSHUserInput{Action: "add-to-group", User: "alice", Groups: []string{"docker"}}, [][]string{{"usermod", "--append", "--groups", "docker", "alice"}}},
+		{"Darwin", create, [][]string{
+			{"sysadminctl", "-addUser", "alice", "-fullName", "Alice Smith", "-shell", "/bin/bash"},
+			{"dscl", ".", "-append", "/Groups/docker", "GroupMembership", "alice"},
+			{"dscl", ".", "-append", "/Groups/adm", "GroupMembership", "alice"},
+		}},
+		{"Darwin", SSHUserInput{Action: "disable", User: "alice"}, [][]string{{"pwpolicy", "-u", "alice", "-disableuser"}}},
+		{"Darwin", SSHUserInput{Action: "add-to-group", User: "alice", Groups: []string{"staff"}}, [][]string{{"dscl", ".", "-append", "/Groups/staff", "GroupMembership", "alice"}}},
+	}
+	for _, tt := range tests {
+		if got := userChangeCommands(tt.os, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: %q, want %q", tt.os, tt.input.Action, got, tt.want)
+		}
+	}
+}
+
+func TestParsePasswdAndGroups(t *testing.T) {
+	users := parsePasswd("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000:Alice Smith,,,:/home/alice:/bin/bash\nbroken line\n")
+	want := []UserAccount{
+		{Name: "root", UID: 0, GID: 0, Comment: "root", Home: "/root", Shell: "/bin/bash"},
+		{Name: "alice", UID: 1000, GID: 1000, Comment: "Alice Smith", Home: "/home/alice", Shell: "/bin/bash"},
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Fatalf("parsePasswd = %+v", users)
+	}
+	groups := parseGroupFile("root:x:0:\nalice:x:1000:\nsudo:x:27:alice,bob\ndocker:x:998:alice\n")
+	if got := accountGroups(users[1], groups); !reflect.DeepEqual(got, []string{"alice", "sudo", "docker"}) {
+		t.Errorf("groups of alice = %v", got)
+	}
+	if got := accountGroups(users[0], groups); !reflect.DeepEqual(got, []string{"root"}) {
+		t.Errorf("groups of root = %v", got)
+	}
+}
+
+func TestParseDSCache(t *testing.T) {
+	users := parseDSCacheUsers("name: alice\npassword: ********\nuid: 501\ngid: 20\ndir: /Users/alice\nshell: /bin/zsh\ngecos: Alice Smith\n\nname: _www\nuid: 70\ngid: 70\ndir: /Library/WebServer\nshell: /usr/bin/false\ngecos: World Wide Web Server\n")
+	if len(users) != 2 || !reflect.DeepEqual(users[0], UserAccount{Name: "alice", UID: 501, GID: 20, Comment: "Alice Smith", Home: "/Users/alice", Shell: "/bin/zsh"}) {
+		t.Fatalf("parseDSCacheUsers = %+v", users)
+	}
+	groups := parseDSCacheGroups("name: staff\npassword: *\ngid: 20\n\nname: admin\npassword: *\ngid: 80\nusers: root alice\n")
+	if got := accountGroups(users[0], groups); !reflect.DeepEqual(got, []string{"staff", "admin"}) {
+		t.Errorf("groups of alice = %v", got)
+	}
+}
+
+func TestIsSystemAccount(t *testing.T) {
+	tests := []struct {
+		os   string
+		uid  int
+		want bool
+	}{
+		{"Linux", 0, true},
+		{"Linux", 999, true},
+		{"Linux", 1000, false},
+		{"Linux", 65534, true},
+		{"Darwin", 70, true},
+		{"Darwin", 501, false},
+	}
+	for _, tt := range tests {
+		if got := isSystemAccount(tt.os, tt.uid); got != tt.want {
+			t.Errorf("isSystemAccount(%s, %d) = %v", tt.os, tt.uid, got)
+		}
+	}
+}
+
+func TestSSHUserOutput_Text(t *testing.T) {
+	out := SSHUserOutput{
+		Action:  "create",
+		Message: "Created user alice",
+		Users:   []UserAccount{{Name: "alice", UID: 1001, GID: 1001, Comment: "Alice", Home: "/home/alice", Shell: "/bin/bash", Groups: []string{"alice", "docker"}}},
+	}
+	want := "Created user alice\n  alice (uid 1001, gid 1001) home /home/alice, shell /bin/bash, \"Alice\"\n    groups: alice, docker"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+// verifyBatch is the number of files hashed per remote sha256sum call,
+// keeping the command line well below ARG_MAX.
+const verifyBatch = 200
+
+// Verification methods reported in transfer outputs.
+const (
+	verifySHA256Sum = "sha256sum" // remote sha256sum over the files on disk
+	verifySFTP      = "sftp"      // remote files read back over SFTP
+)
+
+// transferVerification is the result of comparing both ends of a transfer.
+type transferVerification struct {
+	Method     string
+	Verified   int
+	Mismatches []string
+}
+
+// verifyTransfer compares the SHA-256 of every copied file on both ends.
+// Remote hashes come from sha256sum on the host where it can run (not
+// Windows, allowed by the command filter, installed), so the data is not
+// sent again; files it could not hash are read back over SFTP instead.
+// Files that differ or cannot be hashed are reported as mismatches.
+func verifyTransfer(ctx context.Context, filter *security.Filter, cfg *config.SSHConfig, conn *connection.Connection, client *ssh.Client, sc *sftp.Client, files []sshclient.FilePair) (*transferVerification, error) {
+	remote := make(map[string]string, len(files))
+	usedCmd, usedSFTP := false, false
+	if filter != nil && cfg != nil && conn.GetRemoteInfo().OS != "Windows" {
+		for start := 0; start < len(files); start += verifyBatch {
+			paths := make([]string, 0, verifyBatch)
+			for _, f := range files[start:min(start+verifyBatch, len(files))] {
+				paths = append(paths, f.Remote)
+			}
+			sums, ok := remoteSHA256Sums(ctx, filter, cfg, conn, client, paths)
+			if !ok {
+				break
+			}
+			usedCmd = true
+			for p, sum := range sums {
+				remote[p] = sum
+			}
+		}
+	}
+
+	v := &transferVerification{}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("verification cancelled: %w", err)
+		}
+		local, err := sshclient.LocalSHA256(f.Local)
+		if err != nil {
+			v.Mismatches = append(v.Mismatches, fmt.Sprintf("%s: cannot hash local file: %v", f.Local, err))
+			continue
+		}
+		sum, ok := remote[f.Remote]
+		if !ok {
+			usedSFTP = true
+			if sum, err = sshclient.RemoteSHA256(sc, f.Remote); err != nil {
+				v.Mismatches = append(v.Mismatches, fmt.Sprintf("%s: cannot hash remote file: %v", f.Remote, err))
+				continue
+			}
+		}
+		if sum != local {
+			v.Mismatches = append(v.Mismatches, fmt.Sprintf("%s: sha256 %s locally, %s remotely", f.Remote, local, sum))
+			continue
+		}
+		v.Verified++
+	}
+
+	switch {
+	case usedCmd && usedSFTP:
+		v.Method = verifySHA256Sum + "+" + verifySFTP
+	case usedCmd:
+		v.Method = verifySHA256Sum
+	default:
+		v.Method = verifySFTP
+	}
+	return v, nil
+}
+
+// remoteSHA256Sums runs sha256sum on paths and returns the hashes it
+// printed, keyed by path. ok is false if the command could not be used at
+// all; files it could not read are simply missing from the map.
+func remoteSHA256Sums(ctx context.Context, filter *security.Filter, cfg *config.SSHConfig, conn *connection.Connection, client *ssh.Client, paths []string) (map[string]string, bool) {
+	cmd, err := buildCLICommand(filter, cfg, false, "sha256sum", append([]string{"--"}, paths...)...)
+	if err != nil {
+		return nil, false
+	}
+	res, err := runRemoteCommand(ctx, conn, client, cmd, nil, cfg.CommandTimeout)
+	if err != nil || res.TimedOut || res.ExitCode == exitCommandNotFound {
+		return nil, false
+	}
+	return parseSHA256Sums(res.Stdout), true
+}
+
+// parseSHA256Sums parses sha256sum output ("<hash>  <name>", or " *" in
+// binary mode). Lines for names sha256sum had to escape (starting with a
+// backslash) are skipped, so those files fall back to SFTP.
+func parseSHA256Sums(out string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 67 || line[64] != ' ' || (line[65] != ' ' && line[65] != '*') {
+			continue
+		}
+		sums[line[66:]] = line[:64]
+	}
+	return sums
+}
+
+// verifyText renders a verification summary for transfer outputs.
+func verifyText(method string, verified int, mismatches []string) string {
+	if method == "" {
+		return ""
+	}
+	if len(mismatches) == 0 {
+		return fmt.Sprintf("\nVerified %d file(s) by SHA-256 (%s)", verified, method)
+	}
+	return fmt.Sprintf("\nVERIFICATION FAILED for %d of %d file(s) (%s):\n  %s",
+		len(mismatches), verified+len(mismatches), method, strings.Join(mismatches, "\n  "))
+}
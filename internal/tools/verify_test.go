@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSHA256Sums(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("b", 64)
+	out := a + "  /srv/app/a.txt\n" +
+		b + " */srv/app/with  two spaces\n" +
+		"\\" + a + "  /srv/app/new\\nline\n" +
+		"sha256sum: /srv/app/gone: No such file or directory\n"
+	got := parseSHA256Sums(out)
+	want := map[string]string{"/srv/app/a.txt": a, "/srv/app/with  two spaces": b}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for name, sum := range want {
+		if got[name] != sum {
+			t.Errorf("%s = %q, want %q", name, got[name], sum)
+		}
+	}
+}
+
+func TestVerifyText(t *testing.T) {
+	if got := verifyText("", 0, nil); got != "" {
+		t.Errorf("without verify = %q", got)
+	}
+	if got := verifyText("sha256sum", 3, nil); got != "\nVerified 3 file(s) by SHA-256 (sha256sum)" {
+		t.Errorf("all verified = %q", got)
+	}
+	got := (SSHDownloadOutput{
+		Message:      "Downloaded 2 files (4 bytes) from /srv",
+		Verified:     1,
+		VerifyMethod: "sftp",
+		Mismatches:   []string{"/srv/b: sha256 x locally, y remotely"},
+	}).Text()
+	want := "Downloaded 2 files (4 bytes) from /srv\n" +
+		"VERIFICATION FAILED for 1 of 2 file(s) (sftp):\n  /srv/b: sha256 x locally, y remotely"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+	"github.com/n0madic/ssh-mcp/internal/sshclient"
+)
+
+const (
+	defaultWatchDuration = 30 * time.Second
+	maxWatchDuration     = 10 * time.Minute
+	defaultWatchInterval = time.Second
+	minWatchInterval     = 200 * time.Millisecond
+	maxWatchEvents       = 500
+	// maxWatchScan caps how much newly appended data one poll scans for
+	// a contains marker.
+	maxWatchScan = 1 << 20
+	// watchLineContext is how much already-seen data is re-read so a match
+	// can be reported with its whole line.
+	watchLineContext = 4 << 10
+)
+
+// WatchDeps holds dependencies for the ssh_watch_path tool handler.
+type WatchDeps struct {
+	Pool        *connection.Pool
+	RateLimiter *security.RateLimiter
+}
+
+// watchEntry is the polled state of one watched path.
+type watchEntry struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// HandleWatchPath implements the ssh_watch_path tool. It polls the path over
+// SFTP (no remote commands, so it works on any host) and reports entries
+// created, modified or deleted within the watch window. With contains set
+// on a file, it also scans appended data and stops at the first match.
+func HandleWatchPath(ctx context.Context, deps *WatchDeps, input SSHWatchPathInput) (*SSHWatchPathOutput, error) {
+	if err := security.ValidatePath(input.RemotePath); err != nil {
+		return nil, fmt.Errorf("invalid remote path: %w", err)
+	}
+	if input.Pattern != "" {
+		if _, err := path.Match(input.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", input.Pattern, err)
+		}
+	}
+	duration := defaultWatchDuration
+	if input.DurationSeconds < 0 {
+		return nil, fmt.Errorf("duration_seconds must be non-negative")
+	}
+	if input.DurationSeconds > 0 {
+		duration = time.Duration(input.DurationSeconds) * time.Second
+	}
+	if duration > maxWatchDuration {
+		return nil, fmt.Errorf("duration_seconds must be at most %d", int(maxWatchDuration.Seconds()))
+	}
+	interval := defaultWatchInterval
+	if input.IntervalMs > 0 {
+		interval = max(time.Duration(input.IntervalMs)*time.Millisecond, minWatchInterval)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sshclient.NewSFTPClient(client, conn.SFTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+
+	input.RemotePath = expandRemotePath(conn, sc, input.RemotePath)
+
+	prev, isDir, err := watchSnapshot(sc, input.RemotePath, input.Pattern)
+	if err != nil {
+		conn.SetLastError(err)
+		return nil, err
+	}
+	if input.Contains != "" && isDir {
+		return nil, fmt.Errorf("contains only applies to a file, %s is a directory", input.RemotePath)
+	}
+	// A contains marker is looked for only in data written after the watch
+	// starts.
+	var offset int64
+	if e, ok := prev[input.RemotePath]; ok && !isDir {
+		offset = e.size
+	}
+
+	out := &SSHWatchPathOutput{Path: input.RemotePath}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+poll:
+	for {
+		select {
+		case <-ctx.Done():
+			break poll
+		case <-ticker.C:
+		}
+		out.Polls++
+
+		cur, _, err := watchSnapshot(sc, input.RemotePath, input.Pattern)
+		if err != nil {
+			conn.SetLastError(err)
+			return nil, err
+		}
+		now := time.Now()
+		events := diffWatchSnapshots(prev, cur, now)
+		prev = cur
+
+		if input.Contains != "" {
+			if e, ok := cur[input.RemotePath]; ok {
+				line, next, err := scanAppended(sc, input.RemotePath, offset, e.size, input.Contains)
+				if err != nil {
+					conn.SetLastError(err)
+					return nil, err
+				}
+				offset = next
+				if line != "" {
+					events = append(events, WatchEvent{
+						Time: now.UTC().Format(time.RFC3339), Path: input.RemotePath,
+						Event: "matched", Size: e.size, Line: line,
+					})
+					out.Matched = true
+				}
+			}
+		}
+
+		out.Events = append(out.Events, events...)
+		switch {
+		case len(out.Events) >= maxWatchEvents:
+			out.Events = out.Events[:maxWatchEvents]
+			out.Truncated = true
+			break poll
+		case out.Matched:
+			break poll
+		case input.UntilChange && len(events) > 0:
+			break poll
+		}
+	}
+	out.ElapsedMs = time.Since(start).Milliseconds()
+	return out, nil
+}
+
+// watchSnapshot returns the state of remotePath, or of its entries (those
+// matching pattern) if it is a directory. A missing path yields an empty
+// snapshot, so a watch can wait for it to appear.
+func watchSnapshot(sc *sftp.Client, remotePath, pattern string) (map[string]watchEntry, bool, error) {
+	snap := make(map[string]watchEntry)
+	fi, err := sc.Stat(remotePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err) {
+			return snap, false, nil
+		}
+		return nil, false, fmt.Errorf("stat %s: %w", remotePath, err)
+	}
+	if !fi.IsDir() {
+		snap[remotePath] = watchEntry{size: fi.Size(), modTime: fi.ModTime(), mode: fi.Mode()}
+		return snap, false, nil
+	}
+	entries, err := sc.ReadDir(remotePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || os.IsNotExist(err) {
+			return snap, true, nil
+		}
+		return nil, true, fmt.Errorf("list %s: %w", remotePath, err)
+	}
+	for _, e := range entries {
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, e.Name()); !ok {
+				continue
+			}
+		}
+		snap[path.Join(remotePath, e.Name())] = watchEntry{size: e.Size(), modTime: e.ModTime(), mode: e.Mode()}
+	}
+	return snap, true, nil
+}
+
+// diffWatchSnapshots returns the changes from prev to cur, sorted by path.
+func diffWatchSnapshots(prev, cur map[string]watchEntry, now time.Time) []WatchEvent {
+	ts := now.UTC().Format(time.RFC3339)
+	var events []WatchEvent
+	for p, c := range cur {
+		switch old, ok := prev[p]; {
+		case !ok:
+			events = append(events, WatchEvent{Time: ts, Path: p, Event: "created", Size: c.size})
+		case old != c:
+			events = append(events, WatchEvent{Time: ts, Path: p, Event: "modified", Size: c.size})
+		}
+	}
+	for p := range prev {
+		if _, ok := cur[p]; !ok {
+			events = append(events, WatchEvent{Time: ts, Path: p, Event: "deleted"})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events
+}
+
+// scanAppended looks for marker in the bytes of remotePath between offset
+// and size, and returns the line containing it (empty if not found) and the
+// offset to continue from. A file that shrank was truncated or rotated and
+// is scanned from the start.
+func scanAppended(sc *sftp.Client, remotePath string, offset, size int64, marker string) (string, int64, error) {
+	if size < offset {
+		offset = 0
+	}
+	if size == offset {
+		return "", offset, nil
+	}
+	// Re-read some already-seen data so a marker split across two writes is
+	// still found and its line can be shown whole; only matches that end in
+	// new data count.
+	from := max(offset-watchLineContext, size-maxWatchScan, 0)
+	file, err := sc.Open(remotePath)
+	if err != nil {
+		return "", offset, fmt.Errorf("open %s: %w", remotePath, err)
+	}
+	defer file.Close()
+	buf := make([]byte, size-from)
+	n, err := file.ReadAt(buf, from)
+	if err != nil && err != io.EOF {
+		return "", offset, fmt.Errorf("read %s: %w", remotePath, err)
+	}
+	searchFrom := max(int(offset-from)-len(marker)+1, 0)
+	return markerLine(buf[:n], searchFrom, marker), size, nil
+}
+
+// markerLine returns the line of data containing the first occurrence of
+// marker at or after searchFrom, or "" if there is none.
+func markerLine(data []byte, searchFrom int, marker string) string {
+	if searchFrom > len(data) {
+		return ""
+	}
+	i := bytes.Index(data[searchFrom:], []byte(marker))
+	if i < 0 {
+		return ""
+	}
+	i += searchFrom
+	start := bytes.LastIndexByte(data[:i], '\n') + 1
+	end := len(data)
+	if j := bytes.IndexByte(data[i:], '\n'); j >= 0 {
+		end = i + j
+	}
+	return strings.TrimRight(string(data[start:end]), "\r")
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffWatchSnapshots(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := map[string]watchEntry{
+		"/build/app.log":  {size: 10, modTime: t0},
+		"/build/old.tmp":  {size: 5, modTime: t0},
+		"/build/same.txt": {size: 1, modTime: t0},
+	}
+	cur := map[string]watchEntry{
+		"/build/app.log":    {size: 20, modTime: t0.Add(time.Second)},
+		"/build/app.tar.gz": {size: 1024, modTime: t0.Add(time.Second)},
+		"/build/same.txt":   {size: 1, modTime: t0},
+	}
+	events := diffWatchSnapshots(prev, cur, t0.Add(2*time.Second))
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Event+" "+e.Path)
+	}
+	want := "modified /build/app.log,created /build/app.tar.gz,deleted /build/old.tmp"
+	if strings.Join(got, ",") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+	if events[0].Time != "2026-01-01T00:00:02Z" || events[1].Size != 1024 {
+		t.Errorf("unexpected event details: %+v", events)
+	}
+
+	if events := diffWatchSnapshots(cur, cur, t0); len(events) != 0 {
+		t.Errorf("expected no events for identical snapshots, got %v", events)
+	}
+}
+
+func TestMarkerLine(t *testing.T) {
+	tests := []struct {
+		data       string
+		searchFrom int
+		marker     string
+		want       string
+	}{
+		{"starting\nserver READY on :8080\nmore\n", 0, "READY", "server READY on :8080"},
+		{"no newline READY", 0, "READY", "no newline READY"},
+		{"READY\r\n", 0, "READY", "READY"},
+		{"partial REA", 0, "READY", ""},
+		// Only matches at or after searchFrom count, but the whole line is shown.
+		{"READY old\nserver READY new\n", 5, "READY", "server READY new"},
+		{"READY old\n", 5, "READY", ""},
+		{"short", 10, "READY", ""},
+	}
+	for _, tt := range tests {
+		if got := markerLine([]byte(tt.data), tt.searchFrom, tt.marker); got != tt.want {
+			t.Errorf("markerLine(%q, %d, %q) = %q, want %q", tt.data, tt.searchFrom, tt.marker, got, tt.want)
+		}
+	}
+}
+
+func TestHandleWatchPath_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHWatchPathInput
+		want  string
+	}{
+		{SSHWatchPathInput{RemotePath: "/tmp/../etc"}, "invalid remote path"},
+		{SSHWatchPathInput{RemotePath: "/tmp", Pattern: "[a-"}, "invalid pattern"},
+		{SSHWatchPathInput{RemotePath: "/tmp", DurationSeconds: -1}, "non-negative"},
+		{SSHWatchPathInput{RemotePath: "/tmp", DurationSeconds: 3600}, "at most 600"},
+	}
+	for _, tt := range tests {
+		_, err := HandleWatchPath(context.Background(), &WatchDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestSSHWatchPathOutput_Text(t *testing.T) {
+	out := SSHWatchPathOutput{Path: "/build", ElapsedMs: 30000}
+	if got := out.Text(); got != "No changes to /build in 30s" {
+		t.Errorf("empty Text() = %q", got)
+	}
+	out.Events = []WatchEvent{
+		{Time: "2026-01-01T00:00:02Z", Path: "/build/app.tar.gz", Event: "created", Size: 1024},
+		{Time: "2026-01-01T00:00:03Z", Path: "/build/app.log", Event: "matched", Line: "BUILD OK"},
+	}
+	text := out.Text()
+	for _, want := range []string{"2 change(s) to /build", "created  /build/app.tar.gz", "matched  /build/app.log: BUILD OK"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+}
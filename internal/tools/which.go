@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// WhichDeps holds dependencies for the ssh_which tool handler.
+type WhichDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+const (
+	maxWhichCommands   = 50  // commands per call
+	whichVersionWait   = 5   // seconds a version command may run (with timeout(1))
+	maxWhichVersionLen = 200 // bytes kept of a version line
+)
+
+var (
+	// commandNamePattern matches program names looked up on PATH.
+	commandNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+-]*$`)
+	// versionNumberPattern finds a version number in a version line.
+	versionNumberPattern = regexp.MustCompile(`\d+(?:\.\d+)+(?:[-+~][0-9A-Za-z.]+)?`)
+)
+
+// versionArgs are the version options of programs that don't take
+// --version.
+var versionArgs = map[string][]string{
+	"go":      {"version"},
+	"java":    {"-version"},
+	"javac":   {"-version"},
+	"openssl": {"version"},
+	"ssh":     {"-V"},
+	"tmux":    {"-V"},
+	"screen":  {"-v"},
+	"kubectl": {"version", "--client"},
+	"helm":    {"version", "--short"},
+}
+
+// HandleWhich implements the ssh_which tool. One shell script looks every
+// command up with command -v and, with version, runs its version option
+// (--version unless versionArgs says otherwise) with stdin closed and, if
+// timeout(1) exists, a time limit. Version commands the command filter
+// denies are skipped; the lookups themselves only read PATH.
+func HandleWhich(ctx context.Context, deps *WhichDeps, input SSHWhichInput) (*SSHWhichOutput, error) {
+	if len(input.Commands) == 0 {
+		return nil, fmt.Errorf("commands is required")
+	}
+	if len(input.Commands) > maxWhichCommands {
+		return nil, fmt.Errorf("at most %d commands per call", maxWhichCommands)
+	}
+	for _, name := range input.Commands {
+		if !commandNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid command name %q (a program name, not a path or command line)", name)
+		}
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn.GetRemoteInfo().OS == "Windows" {
+		return nil, fmt.Errorf("ssh_which needs a POSIX host")
+	}
+	versions := map[string]bool{}
+	if !input.NoVersion {
+		for _, name := range input.Commands {
+			versions[name] = deps.Filter.AllowCommand(versionCommand(name)) == nil
+		}
+	}
+	res, err := runRemoteCommand(ctx, conn, client, whichScript(input.Commands, versions), nil, deps.Config.CommandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if res.TimedOut || res.ExitCode != 0 {
+		return nil, remoteFailure("command lookup", res)
+	}
+	out := &SSHWhichOutput{Commands: parseWhich(decodeLogs(deps.Config, res.Stdout))}
+	for i := range out.Commands {
+		if c := &out.Commands[i]; c.Found && !input.NoVersion && !versions[c.Name] {
+			c.VersionOutput = "(version not checked: denied by the command filter)"
+		}
+	}
+	return out, nil
+}
+
+// versionCommand returns the command that prints the version of name.
+func versionCommand(name string) string {
+	args := versionArgs[name]
+	if args == nil {
+		args = []string{"--version"}
+	}
+	quoted := []string{name}
+	for _, a := range args {
+		quoted = append(quoted, shellQuote(a))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// whichScript returns a script that prints "NAME<TAB>PATH<TAB>VERSION
+// LINE" for every name, with an empty path if it is not found and an
+// empty version unless versions[name]. Names match commandNamePattern, so
+// they need no quoting.
+func whichScript(names []string, versions map[string]bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "t=; command -v timeout >/dev/null 2>&1 && t='timeout %d'; ", whichVersionWait)
+	for _, name := range names {
+		fmt.Fprintf(&b, "p=$(command -v %s 2>/dev/null); v=; ", name)
+		if versions[name] {
+			fmt.Fprintf(&b, `[ -n "$p" ] && v=$($t %s </dev/null 2>&1 | head -n 1); `, versionCommand(name))
+		}
+		fmt.Fprintf(&b, `printf '%%s\t%%s\t%%s\n' %s "$p" "$v"; `, name)
+	}
+	b.WriteString("true")
+	return b.String()
+}
+
+// parseWhich reads whichScript output.
+func parseWhich(out string) []CommandAvailability {
+	var cmds []CommandAvailability
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.SplitN(line, "\t", 3)
+		if len(f) != 3 {
+			continue
+		}
+		c := CommandAvailability{Name: f[0], Found: f[1] != ""}
+		if strings.HasPrefix(f[1], "/") {
+			c.Path = f[1]
+		} else if c.Found {
+			c.Builtin = true // a shell builtin or function
+		}
+		if c.Found {
+			line := strings.TrimSpace(f[2])
+			if len(line) > maxWhichVersionLen {
+				line = strings.ToValidUTF8(line[:maxWhichVersionLen], "")
+			}
+			c.VersionOutput = line
+			c.Version = versionNumberPattern.FindString(line)
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandleWhich_Validation(t *testing.T) {
+	tests := []struct {
+		cmds []string
+		want string
+	}{
+		{nil, "commands is required"},
+		{[]string{"/usr/bin/git"}, "invalid command name"},
+		{[]string{"git --version"}, "invalid command name"},
+		{[]string{"-v"}, "invalid command name"},
+		{[]string{"git;id"}, "invalid command name"},
+		{make([]string, maxWhichCommands+1), "at most"},
+	}
+	for _, tt := range tests {
+		_, err := HandleWhich(context.Background(), &WhichDeps{}, SSHWhichInput{Commands: tt.cmds})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.cmds, err, tt.want)
+		}
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	tests := map[string]string{
+		"git":     "git '--version'",
+		"ssh":     "ssh '-V'",
+		"kubectl": "kubectl 'version' '--client'",
+	}
+	for name, want := range tests {
+		if got := versionCommand(name); got != want {
+			t.Errorf("versionCommand(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWhichScript(t *testing.T) {
+	got := whichScript([]string{"git", "jq"}, map[string]bool{"git": true})
+	want := "t=; command -v timeout >/dev/null 2>&1 && t='timeout 5'; " +
+		"p=$(command -v git 2>/dev/null); v=; [ -n \"$p\" ] && v=$($t git '--version' </dev/null 2>&1 | head -n 1); printf '%s\\t%s\\t%s\\n' git \"$p\" \"$v\"; " +
+		"p=$(command -v jq 2>/dev/null); v=; printf '%s\\t%s\\t%s\\n' jq \"$p\" \"$v\"; true"
+	if got != want {
+		t.Errorf("whichScript =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseWhich(t *testing.T) {
+	out := "git\t/usr/bin/git\tgit version 2.43.0\n" +
+		"jq\t\t\n" +
+		"cd\tcd\t\n" +
+		"ssh\t/usr/bin/ssh\tOpenSSH_9.6p1 Ubuntu-3ubuntu13, OpenSSL 3.0.13 30 Jan 2024\n" +
+		"node\t/usr/bin/node\tv20.11.1\n"
+	want := []CommandAvailability{
+		{Name: "git", Found: true, Path: "/usr/bin/git", Version: "2.43.0", VersionOutput: "git version 2.43.0"},
+		{Name: "jq"},
+		{Name: "cd", Found: true, Builtin: true},
+		{Name: "ssh", Found: true, Path: "/usr/bin/ssh", Version: "9.6", VersionOutput: "OpenSSH_9.6p1 Ubuntu-3ubuntu13, OpenSSL 3.0.13 30 Jan 2024"},
+		{Name: "node", Found: true, Path: "/usr/bin/node", Version: "20.11.1", VersionOutput: "v20.11.1"},
+	}
+	if got := parseWhich(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWhich =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSSHWhichOutput_Text(t *testing.T) {
+	out := SSHWhichOutput{Commands: []CommandAvailability{
+		{Name: "git", Found: true, Path: "/usr/bin/git", Version: "2.43.0"},
+		{Name: "jq"},
+	}}
+	want := "git: /usr/bin/git (2.43.0)\njq: not found\nMissing: jq"
+	if got := out.Text(); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
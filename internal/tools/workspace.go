@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/n0madic/ssh-mcp/internal/config"
+	"github.com/n0madic/ssh-mcp/internal/connection"
+	"github.com/n0madic/ssh-mcp/internal/security"
+)
+
+// WorkspaceDeps holds dependencies for the ssh_workspace tool handler.
+type WorkspaceDeps struct {
+	Pool        *connection.Pool
+	Filter      *security.Filter
+	RateLimiter *security.RateLimiter
+	Config      *config.SSHConfig
+}
+
+// Actions of ssh_workspace.
+const (
+	workspaceActionCreate = "create"
+	workspaceActionList   = "list"
+	workspaceActionRemove = "remove"
+)
+
+// workspaceTemplate is the mktemp template of a workspace; -t puts it in
+// $TMPDIR or /tmp with both GNU and BSD mktemp.
+const workspaceTemplate = "ssh-mcp.XXXXXXXXXX"
+
+// HandleWorkspace implements the ssh_workspace tool. create makes a
+// private directory with mktemp -d (through the command filter) and records
+// it on the session; the pool deletes a session's workspaces when it is
+// disconnected, expires or the server shuts down. remove deletes one
+// workspace, or all of them, early. Only directories the server created
+// can be removed, so the rm does not go through the filter.
+func HandleWorkspace(ctx context.Context, deps *WorkspaceDeps, input SSHWorkspaceInput) (*SSHWorkspaceOutput, error) {
+	switch input.Action {
+	case workspaceActionCreate, workspaceActionList:
+		if input.Path != "" {
+			return nil, fmt.Errorf("path only applies to remove")
+		}
+	case workspaceActionRemove:
+	default:
+		return nil, fmt.Errorf("invalid action %q (must be create, list or remove)", input.Action)
+	}
+
+	conn, client, err := getConnectionWithRateLimit(ctx, deps.Pool, deps.RateLimiter, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	out := &SSHWorkspaceOutput{Action: input.Action}
+
+	switch input.Action {
+	case workspaceActionCreate:
+		if conn.GetRemoteInfo().OS == "Windows" {
+			return nil, fmt.Errorf("workspaces need a POSIX host")
+		}
+		cmd, err := buildCLICommand(deps.Filter, deps.Config, false, "mktemp", "-d", "-t", workspaceTemplate)
+		if err != nil {
+			return nil, err
+		}
+		res, err := runRemoteCommand(ctx, conn, client, cmd, nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return nil, remoteFailure("mktemp", res)
+		}
+		dir := strings.TrimSpace(res.Stdout)
+		if !path.IsAbs(dir) || strings.ContainsAny(dir, "\n\x00") {
+			return nil, fmt.Errorf("unexpected mktemp output %q", res.Stdout)
+		}
+		conn.AddWorkspace(dir)
+		out.Path = dir
+		out.Message = fmt.Sprintf("Created workspace %s (removed when the session is disconnected)", dir)
+
+	case workspaceActionRemove:
+		dirs := conn.Workspaces()
+		if input.Path != "" {
+			if !slices.Contains(dirs, input.Path) {
+				return nil, fmt.Errorf("%s is not a workspace of this session", input.Path)
+			}
+			dirs = []string{input.Path}
+		}
+		if len(dirs) == 0 {
+			out.Message = "No workspaces to remove"
+			break
+		}
+		res, err := runRemoteCommand(ctx, conn, client, connection.RemoveWorkspacesCommand(dirs), nil, deps.Config.CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut || res.ExitCode != 0 {
+			return nil, remoteFailure("rm", res)
+		}
+		for _, d := range dirs {
+			conn.RemoveWorkspace(d)
+		}
+		out.Removed = dirs
+		out.Message = fmt.Sprintf("Removed %d workspace(s)", len(dirs))
+	}
+
+	out.Workspaces = conn.Workspaces()
+	if input.Action == workspaceActionList {
+		out.Message = fmt.Sprintf("%d workspace(s)", len(out.Workspaces))
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandleWorkspace_Validation(t *testing.T) {
+	tests := []struct {
+		input SSHWorkspaceInput
+		want  string
+	}{
+		{SSHWorkspaceInput{Action: "clean"}, "invalid action"},
+		{SSHWorkspaceInput{Action: "create", Path: "/tmp/x"}, "only applies to remove"},
+		{SSHWorkspaceInput{Action: "list", Path: "/tmp/x"}, "only applies to remove"},
+	}
+	for _, tt := range tests {
+		_, err := HandleWorkspace(context.Background(), &WorkspaceDeps{}, tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestSSHWorkspaceOutput_Text(t *testing.T) {
+	tests := []struct {
+		out  SSHWorkspaceOutput
+		want string
+	}{
+		{SSHWorkspaceOutput{Action: "create", Path: "/tmp/ssh-mcp.a", Workspaces: []string{"/tmp/ssh-mcp.a"}, Message: "Created workspace /tmp/ssh-mcp.a"}, "Created workspace /tmp/ssh-mcp.a"},
+		{SSHWorkspaceOutput{Action: "list", Workspaces: []string{"/tmp/ssh-mcp.a", "/tmp/ssh-mcp.b"}, Message: "2 workspace(s)"}, "2 workspace(s)\n  /tmp/ssh-mcp.a\n  /tmp/ssh-mcp.b"},
+		{SSHWorkspaceOutput{Action: "remove", Removed: []string{"/tmp/ssh-mcp.a"}, Workspaces: []string{"/tmp/ssh-mcp.b"}, Message: "Removed 1 workspace(s)"}, "Removed 1 workspace(s)\nRemaining: /tmp/ssh-mcp.b"},
+	}
+	for _, tt := range tests {
+		if got := tt.out.Text(); got != tt.want {
+			t.Errorf("Text() = %q, want %q", got, tt.want)
+		}
+	}
+}
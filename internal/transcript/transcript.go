@@ -0,0 +1,196 @@
+// Package transcript records every tool call made on an SSH session, with
+// its arguments and output, to one JSON-lines file per session, for review
+// after the fact.
+package transcript
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOutput bounds the output and error text kept per entry.
+const MaxOutput = 256 << 10
+
+// noSession names the file of calls not tied to an SSH session.
+const noSession = "no-session"
+
+// Entry is one recorded tool call.
+type Entry struct {
+	Time       string         `json:"time"`
+	Owner      string         `json:"owner,omitempty"` // MCP session that made the call, empty when shared
+	SessionID  string         `json:"session_id,omitempty"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments,omitempty"` // secrets redacted
+	Output     string         `json:"output,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// Recorder appends entries to per-session files in a directory.
+type Recorder struct {
+	dir       string
+	retention time.Duration // 0 = keep forever
+	mu        sync.Mutex
+}
+
+// New creates a Recorder writing to dir, creating it (0700) if needed.
+// Files not written to for longer than retention are deleted by
+// StartCleanup; 0 keeps them forever.
+func New(dir string, retention time.Duration) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create transcript directory: %w", err)
+	}
+	return &Recorder{dir: dir, retention: retention}, nil
+}
+
+// Path returns the transcript file of an SSH session.
+func (r *Recorder) Path(sessionID string) string {
+	if sessionID == "" {
+		sessionID = noSession
+	}
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '@':
+			return c
+		}
+		return '_'
+	}, sessionID)
+	return filepath.Join(r.dir, name+".jsonl")
+}
+
+// Record appends e to its session's file. Output and error are cut to
+// MaxOutput.
+func (r *Recorder) Record(e Entry) error {
+	e.Output = truncate(e.Output)
+	e.Error = truncate(e.Error)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.Path(e.SessionID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the last limit entries (all if limit <= 0) that owner
+// recorded on sessionID, oldest first, and how many it recorded in total.
+func (r *Recorder) Read(owner, sessionID string, limit int) ([]Entry, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.Open(r.Path(sessionID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	total := 0
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			// A line cut short by a crash is skipped.
+			if json.Unmarshal(line, &e) == nil && e.Owner == owner && e.SessionID == sessionID {
+				total++
+				entries = append(entries, e)
+				if limit > 0 && len(entries) > limit {
+					entries = entries[1:]
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, total, nil
+}
+
+// Cleanup deletes transcript files last written more than the retention
+// period ago and returns how many it removed.
+func (r *Recorder) Cleanup() int {
+	if r.retention <= 0 {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(r.dir, "*.jsonl"))
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) <= r.retention {
+			continue
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartCleanup applies the retention period now and then every interval
+// until ctx is done.
+func (r *Recorder) StartCleanup(ctx context.Context, interval time.Duration) {
+	if r.retention <= 0 {
+		return
+	}
+	clean := func() {
+		if removed := r.Cleanup(); removed > 0 {
+			log.Printf("Transcript cleanup: removed %d file(s) older than %s", removed, r.retention)
+		}
+	}
+	clean()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				clean()
+			}
+		}
+	}()
+}
+
+func truncate(s string) string {
+	if len(s) <= MaxOutput {
+		return s
+	}
+	cut := MaxOutput
+	for cut > 0 && cut < len(s) && s[cut]&0xC0 == 0x80 {
+		cut-- // don't split a UTF-8 sequence
+	}
+	return s[:cut] + "\n[transcript: output truncated]"
+}
@@ -0,0 +1,100 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder_RecordAndRead(t *testing.T) {
+	r, err := New(filepath.Join(t.TempDir(), "transcripts"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range []Entry{
+		{Owner: "a", SessionID: "root@web:22", Tool: "ssh_execute", Output: "one"},
+		{Owner: "b", SessionID: "root@web:22", Tool: "ssh_execute", Output: "other client"},
+		{Owner: "a", SessionID: "root@web:22", Tool: "ssh_read_file", Output: "two"},
+		{Owner: "a", SessionID: "root@db:22", Tool: "ssh_execute", Output: "other session"},
+		{Owner: "a", SessionID: "root@web:22", Tool: "ssh_execute", Error: "three"},
+	} {
+		if err := r.Record(e); err != nil {
+			t.Fatalf("Record %d: %v", i, err)
+		}
+	}
+
+	entries, total, err := r.Read("a", "root@web:22", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+	if len(entries) != 2 || entries[0].Output != "two" || entries[1].Error != "three" {
+		t.Errorf("entries = %+v, want the last two of owner a", entries)
+	}
+
+	entries, total, err = r.Read("a", "nobody@nowhere:22", 0)
+	if err != nil || total != 0 || len(entries) != 0 {
+		t.Errorf("unknown session: %v, %d, %v", entries, total, err)
+	}
+}
+
+func TestRecorder_Path(t *testing.T) {
+	r := &Recorder{dir: "/t"}
+	if got := r.Path("root@web:22"); got != "/t/root@web_22.jsonl" {
+		t.Errorf("Path = %q", got)
+	}
+	if got := r.Path("../../etc/passwd"); filepath.Dir(got) != "/t" {
+		t.Errorf("Path escaped the directory: %q", got)
+	}
+	if got := r.Path(""); got != "/t/no-session.jsonl" {
+		t.Errorf("Path(\"\") = %q", got)
+	}
+}
+
+func TestRecorder_TruncatesOutput(t *testing.T) {
+	r, err := New(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Record(Entry{SessionID: "s", Tool: "ssh_execute", Output: strings.Repeat("é", MaxOutput)}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := r.Read("", "s", 0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Read: %v, %v", entries, err)
+	}
+	if out := entries[0].Output; len(out) > MaxOutput+64 || !strings.HasSuffix(out, "output truncated]") {
+		t.Errorf("output not truncated: %d bytes", len(out))
+	}
+}
+
+func TestRecorder_Cleanup(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"old", "new"} {
+		if err := r.Record(Entry{SessionID: id, Tool: "ssh_execute"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(r.Path("old"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if removed := r.Cleanup(); removed != 1 {
+		t.Errorf("Cleanup removed %d files, want 1", removed)
+	}
+	if _, err := os.Stat(r.Path("old")); !os.IsNotExist(err) {
+		t.Errorf("old transcript still exists: %v", err)
+	}
+	if _, err := os.Stat(r.Path("new")); err != nil {
+		t.Errorf("new transcript removed: %v", err)
+	}
+}
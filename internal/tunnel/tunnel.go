@@ -19,6 +19,7 @@ type TunnelID string
 type TunnelSession struct {
 	ID         TunnelID
 	SessionID  string
+	Owner      string // client namespace of the SSH session ("" = shared)
 	LocalAddr  string
 	LocalPort  int
 	RemoteAddr string
@@ -69,8 +70,9 @@ func NewTunnelPool(maxTunnels int) *TunnelPool {
 
 // Open creates a new local port forwarding tunnel.
 // localPort of 0 means auto-assign a free port. remoteAddr is the address the
-// SSH server should dial (e.g. "localhost:5432").
-func (tp *TunnelPool) Open(sessionID string, client *ssh.Client, localPort int, remoteAddr string) (*TunnelSession, error) {
+// SSH server should dial (e.g. "localhost:5432"). The tunnel is only
+// visible to owner.
+func (tp *TunnelPool) Open(owner, sessionID string, client *ssh.Client, localPort int, remoteAddr string) (*TunnelSession, error) {
 	// Bind local listener.
 	listenAddr := fmt.Sprintf("127.0.0.1:%d", localPort)
 	listener, err := net.Listen("tcp", listenAddr)
@@ -89,6 +91,7 @@ func (tp *TunnelPool) Open(sessionID string, client *ssh.Client, localPort int,
 	ts := &TunnelSession{
 		ID:         id,
 		SessionID:  sessionID,
+		Owner:      owner,
 		LocalAddr:  listener.Addr().String(),
 		LocalPort:  actualPort,
 		RemoteAddr: remoteAddr,
@@ -206,26 +209,26 @@ func (ts *TunnelSession) forward(localConn net.Conn) {
 	remoteConn.Close()
 }
 
-// Get retrieves a TunnelSession by ID.
-func (tp *TunnelPool) Get(id TunnelID) (*TunnelSession, error) {
+// Get retrieves one of owner's TunnelSessions by ID.
+func (tp *TunnelPool) Get(owner string, id TunnelID) (*TunnelSession, error) {
 	tp.mu.RLock()
 	ts, ok := tp.tunnels[id]
 	tp.mu.RUnlock()
-	if !ok {
+	if !ok || ts.Owner != owner {
 		return nil, fmt.Errorf("tunnel %s not found", id)
 	}
 	return ts, nil
 }
 
-// List returns metadata for all active tunnels. If sessionID is non-empty,
-// only tunnels belonging to that session are included.
-func (tp *TunnelPool) List(sessionID string) []TunnelInfo {
+// List returns metadata for all active tunnels of owner. If sessionID is
+// non-empty, only tunnels belonging to that session are included.
+func (tp *TunnelPool) List(owner, sessionID string) []TunnelInfo {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
 	result := make([]TunnelInfo, 0, len(tp.tunnels))
 	for _, ts := range tp.tunnels {
-		if sessionID != "" && ts.SessionID != sessionID {
+		if ts.Owner != owner || (sessionID != "" && ts.SessionID != sessionID) {
 			continue
 		}
 		ts.mu.Lock()
@@ -267,10 +270,11 @@ func closeTunnel(ts *TunnelSession) {
 	ts.activeConnsMu.Unlock()
 }
 
-// Close terminates a tunnel and removes it from the pool.
-func (tp *TunnelPool) Close(id TunnelID) error {
+// Close terminates one of owner's tunnels and removes it from the pool.
+func (tp *TunnelPool) Close(owner string, id TunnelID) error {
 	tp.mu.Lock()
 	ts, ok := tp.tunnels[id]
+	ok = ok && ts.Owner == owner
 	if ok {
 		delete(tp.tunnels, id)
 	}
@@ -284,12 +288,13 @@ func (tp *TunnelPool) Close(id TunnelID) error {
 	return nil
 }
 
-// CloseBySession closes all tunnels associated with the given session ID.
-func (tp *TunnelPool) CloseBySession(sessionID string) {
+// CloseBySession closes all tunnels of owner associated with the given
+// session ID.
+func (tp *TunnelPool) CloseBySession(owner, sessionID string) {
 	tp.mu.Lock()
 	var toClose []*TunnelSession
 	for id, ts := range tp.tunnels {
-		if ts.SessionID == sessionID {
+		if ts.Owner == owner && ts.SessionID == sessionID {
 			toClose = append(toClose, ts)
 			delete(tp.tunnels, id)
 		}
@@ -21,7 +21,7 @@ func TestTunnelPool_OpenAndClose(t *testing.T) {
 	// We pass nil for the SSH client — the accept loop will run but
 	// we must NOT connect to the listener (that would trigger a nil dereference
 	// when trying to dial through the SSH client).
-	ts, err := tp.Open("user@host:22", nil, 0, "localhost:5432")
+	ts, err := tp.Open("", "user@host:22", nil, 0, "localhost:5432")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -45,7 +45,7 @@ func TestTunnelPool_OpenAndClose(t *testing.T) {
 	}
 
 	// Verify Get works.
-	got, err := tp.Get(ts.ID)
+	got, err := tp.Get("", ts.ID)
 	if err != nil {
 		t.Fatalf("unexpected error from Get: %v", err)
 	}
@@ -54,12 +54,12 @@ func TestTunnelPool_OpenAndClose(t *testing.T) {
 	}
 
 	// Close the tunnel.
-	if err := tp.Close(ts.ID); err != nil {
+	if err := tp.Close("", ts.ID); err != nil {
 		t.Fatalf("unexpected error closing tunnel: %v", err)
 	}
 
 	// Verify it's removed.
-	_, err = tp.Get(ts.ID)
+	_, err = tp.Get("", ts.ID)
 	if err == nil {
 		t.Error("expected error getting closed tunnel")
 	}
@@ -67,7 +67,7 @@ func TestTunnelPool_OpenAndClose(t *testing.T) {
 
 func TestTunnelPool_GetUnknown(t *testing.T) {
 	tp := NewTunnelPool(0)
-	_, err := tp.Get("nonexistent")
+	_, err := tp.Get("", "nonexistent")
 	if err == nil {
 		t.Error("expected error for unknown tunnel")
 	}
@@ -75,7 +75,7 @@ func TestTunnelPool_GetUnknown(t *testing.T) {
 
 func TestTunnelPool_CloseUnknown(t *testing.T) {
 	tp := NewTunnelPool(0)
-	err := tp.Close("nonexistent")
+	err := tp.Close("", "nonexistent")
 	if err == nil {
 		t.Error("expected error closing unknown tunnel")
 	}
@@ -84,20 +84,20 @@ func TestTunnelPool_CloseUnknown(t *testing.T) {
 func TestTunnelPool_MaxTunnels(t *testing.T) {
 	tp := NewTunnelPool(1)
 
-	ts1, err := tp.Open("user@host:22", nil, 0, "localhost:5432")
+	ts1, err := tp.Open("", "user@host:22", nil, 0, "localhost:5432")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	_, err = tp.Open("user@host:22", nil, 0, "localhost:3306")
+	_, err = tp.Open("", "user@host:22", nil, 0, "localhost:3306")
 	if err == nil {
 		t.Error("expected error when max tunnels reached")
 	}
 
 	// Close first tunnel, should allow a new one.
-	tp.Close(ts1.ID)
+	tp.Close("", ts1.ID)
 
-	_, err = tp.Open("user@host:22", nil, 0, "localhost:3306")
+	_, err = tp.Open("", "user@host:22", nil, 0, "localhost:3306")
 	if err != nil {
 		t.Fatalf("unexpected error after closing tunnel: %v", err)
 	}
@@ -106,17 +106,17 @@ func TestTunnelPool_MaxTunnels(t *testing.T) {
 func TestTunnelPool_List(t *testing.T) {
 	tp := NewTunnelPool(0)
 
-	ts1, _ := tp.Open("session-1", nil, 0, "localhost:5432")
-	ts2, _ := tp.Open("session-2", nil, 0, "localhost:3306")
+	ts1, _ := tp.Open("", "session-1", nil, 0, "localhost:5432")
+	ts2, _ := tp.Open("", "session-2", nil, 0, "localhost:3306")
 
 	// List all.
-	all := tp.List("")
+	all := tp.List("", "")
 	if len(all) != 2 {
 		t.Errorf("expected 2 tunnels, got %d", len(all))
 	}
 
 	// List filtered by session.
-	s1 := tp.List("session-1")
+	s1 := tp.List("", "session-1")
 	if len(s1) != 1 {
 		t.Errorf("expected 1 tunnel for session-1, got %d", len(s1))
 	}
@@ -124,7 +124,7 @@ func TestTunnelPool_List(t *testing.T) {
 		t.Errorf("expected tunnel ID %s, got %s", ts1.ID, s1[0].ID)
 	}
 
-	s2 := tp.List("session-2")
+	s2 := tp.List("", "session-2")
 	if len(s2) != 1 {
 		t.Errorf("expected 1 tunnel for session-2, got %d", len(s2))
 	}
@@ -133,7 +133,7 @@ func TestTunnelPool_List(t *testing.T) {
 	}
 
 	// List non-existent session.
-	empty := tp.List("session-3")
+	empty := tp.List("", "session-3")
 	if len(empty) != 0 {
 		t.Errorf("expected 0 tunnels for session-3, got %d", len(empty))
 	}
@@ -142,13 +142,13 @@ func TestTunnelPool_List(t *testing.T) {
 func TestTunnelPool_CloseBySession(t *testing.T) {
 	tp := NewTunnelPool(0)
 
-	tp.Open("session-1", nil, 0, "localhost:5432")
-	tp.Open("session-1", nil, 0, "localhost:3306")
-	tp.Open("session-2", nil, 0, "localhost:6379")
+	tp.Open("", "session-1", nil, 0, "localhost:5432")
+	tp.Open("", "session-1", nil, 0, "localhost:3306")
+	tp.Open("", "session-2", nil, 0, "localhost:6379")
 
-	tp.CloseBySession("session-1")
+	tp.CloseBySession("", "session-1")
 
-	remaining := tp.List("")
+	remaining := tp.List("", "")
 	if len(remaining) != 1 {
 		t.Errorf("expected 1 remaining tunnel, got %d", len(remaining))
 	}
@@ -160,12 +160,12 @@ func TestTunnelPool_CloseBySession(t *testing.T) {
 func TestTunnelPool_CloseAll(t *testing.T) {
 	tp := NewTunnelPool(0)
 
-	tp.Open("session-1", nil, 0, "localhost:5432")
-	tp.Open("session-2", nil, 0, "localhost:3306")
+	tp.Open("", "session-1", nil, 0, "localhost:5432")
+	tp.Open("", "session-2", nil, 0, "localhost:3306")
 
 	tp.CloseAll()
 
-	all := tp.List("")
+	all := tp.List("", "")
 	if len(all) != 0 {
 		t.Errorf("expected 0 tunnels after CloseAll, got %d", len(all))
 	}
@@ -174,15 +174,38 @@ func TestTunnelPool_CloseAll(t *testing.T) {
 func TestTunnelPool_DoubleClose(t *testing.T) {
 	tp := NewTunnelPool(0)
 
-	ts, _ := tp.Open("session-1", nil, 0, "localhost:5432")
+	ts, _ := tp.Open("", "session-1", nil, 0, "localhost:5432")
 
 	// First close should succeed.
-	if err := tp.Close(ts.ID); err != nil {
+	if err := tp.Close("", ts.ID); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	// Second close should return not found.
-	if err := tp.Close(ts.ID); err == nil {
+	if err := tp.Close("", ts.ID); err == nil {
 		t.Error("expected error on double close")
 	}
 }
+
+func TestTunnelPool_OwnersIsolated(t *testing.T) {
+	tp := NewTunnelPool(0)
+	defer tp.CloseAll()
+
+	ts, err := tp.Open("client-a", "session-1", nil, 0, "localhost:5432")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := tp.Get("client-b", ts.ID); err == nil {
+		t.Error("expected Get by another owner to fail")
+	}
+	if got := tp.List("client-b", ""); len(got) != 0 {
+		t.Errorf("List(client-b) = %+v, want none", got)
+	}
+	if err := tp.Close("client-b", ts.ID); err == nil {
+		t.Error("expected Close by another owner to fail")
+	}
+	tp.CloseBySession("client-b", "session-1")
+	if got := tp.List("client-a", "session-1"); len(got) != 1 {
+		t.Errorf("List(client-a) = %+v, want the tunnel to survive", got)
+	}
+}
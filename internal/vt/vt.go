@@ -0,0 +1,662 @@
+// Package vt renders a PTY output stream onto a virtual screen, the way a
+// VT100/xterm terminal would, so the current screen of a full-screen
+// program (htop, an installer, menuconfig) can be read as text.
+//
+// It covers what curses applications use to draw: cursor movement and
+// addressing, erasing, insert/delete of characters and lines, scroll
+// regions, the alternate screen, autowrap, DEC line-drawing characters and
+// wide (East Asian) runes. Colors and other attributes are parsed and
+// dropped; queries such as cursor position reports are not answered.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// wideTail marks the cell covered by the right half of a wide rune.
+const wideTail rune = -1
+
+// maxOSCLen caps the operating system command (window title) collected.
+const maxOSCLen = 4096
+
+// Parser states.
+const (
+	stateGround = iota
+	stateEscape
+	stateCharset // after ESC ( or ESC ): the charset designator
+	stateSkipOne // after ESC # and similar: one more byte to ignore
+	stateCSI
+	stateOSC
+	stateOSCEscape    // ESC inside an OSC: the start of ST
+	stateString       // DCS, SOS, PM or APC: ignored up to ST
+	stateStringEscape // ESC inside a string
+)
+
+// Snapshot is the rendered state of a Screen.
+type Snapshot struct {
+	Cols          int
+	Rows          int
+	Lines         []string // one per row, trailing blanks trimmed
+	CursorRow     int      // 0-based
+	CursorCol     int      // 0-based
+	CursorVisible bool
+	AltScreen     bool   // a full-screen program switched to the alternate screen
+	Title         string // set by OSC 0 or 2
+}
+
+// savedCursor is the state DECSC saves.
+type savedCursor struct {
+	x, y     int
+	wrapNext bool
+	origin   bool
+	charsets [2]bool
+	shift    int
+}
+
+// Screen is a virtual terminal screen. It is not safe for concurrent use.
+type Screen struct {
+	cols, rows int
+	grid       [][]rune // the active screen: main or alt
+	main, alt  [][]rune
+	altActive  bool
+
+	x, y        int
+	wrapNext    bool // the last column was written; the next rune wraps
+	top, bottom int  // scroll region, inclusive
+	autowrap    bool
+	origin      bool // cursor addressing relative to the scroll region
+	insert      bool
+	hidden      bool
+	charsets    [2]bool // G0/G1 designated as DEC line drawing
+	shift       int     // active charset: 0 (SI) or 1 (SO)
+	saved       savedCursor
+	altSaved    savedCursor // saved by mode 1049
+	title       string
+
+	state   int
+	params  []byte
+	private byte // CSI private marker: ?, >, = or <
+	inter   byte // CSI intermediate byte
+	osc     []byte
+	pending []byte // incomplete UTF-8 sequence
+}
+
+// New returns a blank screen of cols x rows (at least 1 x 1).
+func New(cols, rows int) *Screen {
+	cols, rows = max(cols, 1), max(rows, 1)
+	s := &Screen{cols: cols, rows: rows}
+	s.reset()
+	return s
+}
+
+// reset returns the screen to its power-on state (RIS).
+func (s *Screen) reset() {
+	s.main, s.alt = blankGrid(s.cols, s.rows), blankGrid(s.cols, s.rows)
+	s.grid, s.altActive = s.main, false
+	s.x, s.y, s.wrapNext = 0, 0, false
+	s.top, s.bottom = 0, s.rows-1
+	s.autowrap, s.origin, s.insert, s.hidden = true, false, false, false
+	s.charsets, s.shift = [2]bool{}, 0
+	s.saved, s.altSaved = savedCursor{}, savedCursor{}
+	s.title = ""
+}
+
+func blankGrid(cols, rows int) [][]rune {
+	g := make([][]rune, rows)
+	for i := range g {
+		g[i] = blankLine(cols)
+	}
+	return g
+}
+
+func blankLine(cols int) []rune {
+	l := make([]rune, cols)
+	for i := range l {
+		l[i] = ' '
+	}
+	return l
+}
+
+// Write feeds terminal output to the screen. Escape sequences and UTF-8
+// runes may be split across calls. It never fails.
+func (s *Screen) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.feed(b)
+	}
+	return len(p), nil
+}
+
+// Snapshot returns the current screen.
+func (s *Screen) Snapshot() Snapshot {
+	lines := make([]string, s.rows)
+	var b strings.Builder
+	for i, row := range s.grid {
+		b.Reset()
+		for _, r := range row {
+			if r != wideTail {
+				b.WriteRune(r)
+			}
+		}
+		lines[i] = strings.TrimRight(b.String(), " ")
+	}
+	return Snapshot{
+		Cols: s.cols, Rows: s.rows, Lines: lines,
+		CursorRow: s.y, CursorCol: s.x, CursorVisible: !s.hidden,
+		AltScreen: s.altActive, Title: s.title,
+	}
+}
+
+func (s *Screen) feed(b byte) {
+	switch s.state {
+	case stateGround:
+		s.ground(b)
+	case stateEscape:
+		s.escape(b)
+	case stateCharset:
+		s.charsets[s.inter] = b == '0'
+		s.state = stateGround
+	case stateSkipOne:
+		s.state = stateGround
+	case stateCSI:
+		s.csiByte(b)
+	case stateOSC:
+		switch b {
+		case 0x07:
+			s.endOSC()
+		case 0x1b:
+			s.state = stateOSCEscape
+		default:
+			if len(s.osc) < maxOSCLen {
+				s.osc = append(s.osc, b)
+			}
+		}
+	case stateOSCEscape:
+		s.endOSC()
+		if b != '\\' {
+			s.escape(b)
+		}
+	case stateString:
+		switch b {
+		case 0x07:
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateStringEscape
+		}
+	case stateStringEscape:
+		s.state = stateGround
+		if b != '\\' {
+			s.escape(b)
+		}
+	}
+}
+
+func (s *Screen) ground(b byte) {
+	if len(s.pending) > 0 && b < 0x80 {
+		s.pending = s.pending[:0] // a cut-off sequence
+		s.put(utf8.RuneError)
+	}
+	if b >= 0x80 {
+		s.pending = append(s.pending, b)
+		if !utf8.FullRune(s.pending) {
+			return
+		}
+		r, _ := utf8.DecodeRune(s.pending)
+		s.pending = s.pending[:0]
+		s.put(r)
+		return
+	}
+	if b >= 0x20 && b < 0x7f {
+		s.put(rune(b))
+		return
+	}
+	s.control(b)
+}
+
+// control executes a C0 control character.
+func (s *Screen) control(b byte) {
+	switch b {
+	case 0x1b:
+		s.state = stateEscape
+	case '\r':
+		s.x, s.wrapNext = 0, false
+	case '\n', '\v', '\f':
+		s.lineFeed()
+	case '\b':
+		if s.x > 0 {
+			s.x--
+		}
+		s.wrapNext = false
+	case '\t':
+		s.x = min((s.x/8+1)*8, s.cols-1)
+		s.wrapNext = false
+	case 0x0e: // SO
+		s.shift = 1
+	case 0x0f: // SI
+		s.shift = 0
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.state, s.params, s.private, s.inter = stateCSI, s.params[:0], 0, 0
+	case ']':
+		s.state, s.osc = stateOSC, s.osc[:0]
+	case 'P', 'X', '^', '_':
+		s.state = stateString
+	case '(', ')':
+		s.state, s.inter = stateCharset, b-'('
+	case '#', '%', '*', '+', ' ':
+		s.state = stateSkipOne
+	case '7':
+		s.saved = s.saveCursor()
+	case '8':
+		s.restoreCursor(s.saved)
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.x = 0
+		s.lineFeed()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset()
+	case 0x1b:
+		s.state = stateEscape
+	}
+}
+
+func (s *Screen) csiByte(b byte) {
+	switch {
+	case b >= '0' && b <= '9' || b == ';' || b == ':':
+		s.params = append(s.params, b)
+	case b >= '<' && b <= '?':
+		if len(s.params) == 0 {
+			s.private = b
+		}
+	case b >= 0x20 && b <= 0x2f:
+		s.inter = b
+	case b >= 0x40 && b <= 0x7e:
+		s.state = stateGround
+		s.csi(b)
+	case b == 0x1b:
+		s.state = stateEscape
+	case b < 0x20:
+		s.control(b)
+	}
+}
+
+// csiParams returns the numeric parameters; empty ones are 0.
+func (s *Screen) csiParams() []int {
+	if len(s.params) == 0 {
+		return nil
+	}
+	fields := strings.Split(string(s.params), ";")
+	ps := make([]int, len(fields))
+	for i, f := range fields {
+		f, _, _ = strings.Cut(f, ":") // sub-parameters only matter to SGR
+		ps[i], _ = strconv.Atoi(f)
+	}
+	return ps
+}
+
+// param returns parameter i, or def if it is missing or 0.
+func param(ps []int, i, def int) int {
+	if i < len(ps) && ps[i] > 0 {
+		return ps[i]
+	}
+	return def
+}
+
+func (s *Screen) csi(final byte) {
+	ps := s.csiParams()
+	if s.inter != 0 {
+		return // DECSCUSR, DECSTR and the like change nothing visible
+	}
+	if s.private != 0 {
+		if s.private == '?' && (final == 'h' || final == 'l') {
+			for _, p := range ps {
+				s.setPrivateMode(p, final == 'h')
+			}
+		}
+		return
+	}
+	n := param(ps, 0, 1)
+	switch final {
+	case 'A':
+		s.moveTo(s.x, max(s.y-n, s.regionTop()))
+	case 'B', 'e':
+		s.moveTo(s.x, min(s.y+n, s.regionBottom()))
+	case 'C', 'a':
+		s.moveTo(s.x+n, s.y)
+	case 'D':
+		s.moveTo(s.x-n, s.y)
+	case 'E':
+		s.moveTo(0, min(s.y+n, s.regionBottom()))
+	case 'F':
+		s.moveTo(0, max(s.y-n, s.regionTop()))
+	case 'G', '`':
+		s.moveTo(n-1, s.y)
+	case 'd':
+		s.moveTo(s.x, s.originRow(n-1))
+	case 'H', 'f':
+		s.moveTo(param(ps, 1, 1)-1, s.originRow(n-1))
+	case 'J':
+		s.eraseDisplay(param(ps, 0, 0))
+	case 'K':
+		s.eraseLine(param(ps, 0, 0))
+	case 'L':
+		s.insertLines(n)
+	case 'M':
+		s.deleteLines(n)
+	case '@':
+		s.insertChars(n)
+	case 'P':
+		s.deleteChars(n)
+	case 'X':
+		s.eraseChars(n)
+	case 'S':
+		s.scrollUp(s.top, s.bottom, n)
+	case 'T':
+		s.scrollDown(s.top, s.bottom, n)
+	case 'r':
+		top, bottom := param(ps, 0, 1)-1, param(ps, 1, s.rows)-1
+		if top < bottom && bottom < s.rows {
+			s.top, s.bottom = top, bottom
+			s.moveTo(0, s.originRow(0))
+		}
+	case 's':
+		s.saved = s.saveCursor()
+	case 'u':
+		s.restoreCursor(s.saved)
+	case 'h', 'l':
+		for _, p := range ps {
+			if p == 4 {
+				s.insert = final == 'h'
+			}
+		}
+	}
+}
+
+func (s *Screen) setPrivateMode(mode int, on bool) {
+	switch mode {
+	case 6:
+		s.origin = on
+		s.moveTo(0, s.originRow(0))
+	case 7:
+		s.autowrap = on
+	case 25:
+		s.hidden = !on
+	case 47, 1047:
+		s.switchScreen(on)
+	case 1049:
+		if on {
+			s.altSaved = s.saveCursor()
+			s.switchScreen(true)
+			s.eraseDisplay(2)
+		} else {
+			s.switchScreen(false)
+			s.restoreCursor(s.altSaved)
+		}
+	}
+}
+
+func (s *Screen) switchScreen(alt bool) {
+	if alt == s.altActive {
+		return
+	}
+	s.altActive = alt
+	if alt {
+		s.grid = s.alt
+	} else {
+		s.grid = s.main
+	}
+}
+
+func (s *Screen) saveCursor() savedCursor {
+	return savedCursor{x: s.x, y: s.y, wrapNext: s.wrapNext, origin: s.origin, charsets: s.charsets, shift: s.shift}
+}
+
+func (s *Screen) restoreCursor(c savedCursor) {
+	s.x, s.y = min(c.x, s.cols-1), min(c.y, s.rows-1)
+	s.wrapNext, s.origin, s.charsets, s.shift = c.wrapNext, c.origin, c.charsets, c.shift
+}
+
+func (s *Screen) endOSC() {
+	s.state = stateGround
+	kind, text, ok := strings.Cut(string(s.osc), ";")
+	if ok && (kind == "0" || kind == "2") {
+		s.title = strings.ToValidUTF8(text, "")
+	}
+}
+
+// regionTop and regionBottom bound vertical cursor movement: the scroll
+// region when the cursor is inside it, the screen otherwise.
+func (s *Screen) regionTop() int {
+	if s.y >= s.top {
+		return s.top
+	}
+	return 0
+}
+
+func (s *Screen) regionBottom() int {
+	if s.y <= s.bottom {
+		return s.bottom
+	}
+	return s.rows - 1
+}
+
+// originRow converts a row parameter for origin mode.
+func (s *Screen) originRow(row int) int {
+	if s.origin {
+		return min(s.top+row, s.bottom)
+	}
+	return row
+}
+
+func (s *Screen) moveTo(x, y int) {
+	s.x = min(max(x, 0), s.cols-1)
+	s.y = min(max(y, 0), s.rows-1)
+	s.wrapNext = false
+}
+
+// put writes a printable rune at the cursor.
+func (s *Screen) put(r rune) {
+	if s.charsets[s.shift] {
+		if g, ok := decGraphics[r]; ok {
+			r = g
+		}
+	}
+	w := runeWidth(r)
+	if w == 0 {
+		return // combining marks and format characters have no cell of their own
+	}
+	if s.wrapNext && s.autowrap {
+		s.x = 0
+		s.lineFeed()
+	}
+	s.wrapNext = false
+	if w == 2 && s.x == s.cols-1 {
+		if !s.autowrap {
+			return
+		}
+		s.setCell(s.x, ' ')
+		s.x = 0
+		s.lineFeed()
+	}
+	if s.insert {
+		s.insertChars(w)
+	}
+	s.setCell(s.x, r)
+	if w == 2 {
+		s.setCell(s.x+1, wideTail)
+	}
+	s.x += w
+	if s.x >= s.cols {
+		s.x, s.wrapNext = s.cols-1, true
+	}
+}
+
+// setCell writes one cell of the cursor row, blanking the other half of a
+// wide rune it overwrites.
+func (s *Screen) setCell(x int, r rune) {
+	row := s.grid[s.y]
+	if row[x] == wideTail && x > 0 && r != wideTail {
+		row[x-1] = ' '
+	}
+	if x+1 < s.cols && row[x+1] == wideTail {
+		row[x+1] = ' '
+	}
+	row[x] = r
+}
+
+func (s *Screen) lineFeed() {
+	switch {
+	case s.y == s.bottom:
+		s.scrollUp(s.top, s.bottom, 1)
+	case s.y < s.rows-1:
+		s.y++
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) reverseIndex() {
+	switch {
+	case s.y == s.top:
+		s.scrollDown(s.top, s.bottom, 1)
+	case s.y > 0:
+		s.y--
+	}
+	s.wrapNext = false
+}
+
+// scrollUp moves rows top..bottom up by n, blanking the rows at the bottom.
+func (s *Screen) scrollUp(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	copy(s.grid[top:bottom+1], s.grid[top+n:bottom+1])
+	for i := bottom - n + 1; i <= bottom; i++ {
+		s.grid[i] = blankLine(s.cols)
+	}
+}
+
+// scrollDown moves rows top..bottom down by n, blanking the rows at the top.
+func (s *Screen) scrollDown(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	copy(s.grid[top+n:bottom+1], s.grid[top:bottom+1-n])
+	for i := top; i < top+n; i++ {
+		s.grid[i] = blankLine(s.cols)
+	}
+}
+
+func (s *Screen) insertLines(n int) {
+	if s.y < s.top || s.y > s.bottom {
+		return
+	}
+	s.scrollDown(s.y, s.bottom, n)
+	s.x, s.wrapNext = 0, false
+}
+
+func (s *Screen) deleteLines(n int) {
+	if s.y < s.top || s.y > s.bottom {
+		return
+	}
+	s.scrollUp(s.y, s.bottom, n)
+	s.x, s.wrapNext = 0, false
+}
+
+func (s *Screen) insertChars(n int) {
+	row := s.grid[s.y]
+	n = min(n, s.cols-s.x)
+	copy(row[s.x+n:], row[s.x:s.cols-n])
+	s.blank(row, s.x, s.x+n)
+	s.wrapNext = false
+}
+
+func (s *Screen) deleteChars(n int) {
+	row := s.grid[s.y]
+	n = min(n, s.cols-s.x)
+	copy(row[s.x:], row[s.x+n:])
+	s.blank(row, s.cols-n, s.cols)
+	s.wrapNext = false
+}
+
+func (s *Screen) eraseChars(n int) {
+	s.blank(s.grid[s.y], s.x, min(s.x+n, s.cols))
+	s.wrapNext = false
+}
+
+func (s *Screen) eraseLine(mode int) {
+	row := s.grid[s.y]
+	switch mode {
+	case 0:
+		s.blank(row, s.x, s.cols)
+	case 1:
+		s.blank(row, 0, s.x+1)
+	case 2:
+		s.blank(row, 0, s.cols)
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for i := s.y + 1; i < s.rows; i++ {
+			s.grid[i] = blankLine(s.cols)
+		}
+	case 1:
+		s.eraseLine(1)
+		for i := 0; i < s.y; i++ {
+			s.grid[i] = blankLine(s.cols)
+		}
+	case 2, 3:
+		for i := range s.grid {
+			s.grid[i] = blankLine(s.cols)
+		}
+	}
+	s.wrapNext = false
+}
+
+// blank clears row[from:to], including halves of wide runes cut at either
+// end.
+func (s *Screen) blank(row []rune, from, to int) {
+	if from > 0 && from < s.cols && row[from] == wideTail {
+		row[from-1] = ' '
+	}
+	if to < s.cols && row[to] == wideTail {
+		row[to] = ' '
+	}
+	for i := from; i < to; i++ {
+		row[i] = ' '
+	}
+}
+
+// runeWidth returns the number of cells r takes: 2 for wide and fullwidth
+// East Asian runes, 0 for combining marks and format characters.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// decGraphics maps the DEC Special Graphics set (ESC ( 0), which curses
+// uses for boxes and borders, to Unicode.
+var decGraphics = map[rune]rune{
+	'`': '◆', 'a': '▒', 'f': '°', 'g': '±', 'h': '░', 'i': '␋',
+	'j': '┘', 'k': '┐', 'l': '┌', 'm': '└', 'n': '┼',
+	'o': '⎺', 'p': '⎻', 'q': '─', 'r': '⎼', 's': '⎽',
+	't': '├', 'u': '┤', 'v': '┴', 'w': '┬', 'x': '│',
+	'y': '≤', 'z': '≥', '{': 'π', '|': '≠', '}': '£', '~': '·',
+}
@@ -0,0 +1,103 @@
+package vt
+
+import (
+	"slices"
+	"testing"
+)
+
+// render feeds the chunks to a new cols x rows screen and returns it.
+func render(cols, rows int, chunks ...string) Snapshot {
+	s := New(cols, rows)
+	for _, c := range chunks {
+		s.Write([]byte(c))
+	}
+	return s.Snapshot()
+}
+
+func checkLines(t *testing.T, got Snapshot, want ...string) {
+	t.Helper()
+	if !slices.Equal(got.Lines, want) {
+		t.Errorf("lines =\n%q\nwant\n%q", got.Lines, want)
+	}
+}
+
+func TestScreen_TextAndWrap(t *testing.T) {
+	snap := render(5, 3, "ab\r\ncdefgh")
+	checkLines(t, snap, "ab", "cdefg", "h")
+	// The last line has scrolled: the cursor sits on a fresh bottom row.
+	snap = render(5, 3, "1\r\n2\r\n3\r\n4")
+	checkLines(t, snap, "2", "3", "4")
+	if snap.CursorRow != 2 || snap.CursorCol != 1 {
+		t.Errorf("cursor = %d,%d", snap.CursorRow, snap.CursorCol)
+	}
+	// Writing the last column only wraps with the next rune.
+	snap = render(3, 2, "abc\rX")
+	checkLines(t, snap, "Xbc", "")
+}
+
+func TestScreen_CursorAndErase(t *testing.T) {
+	snap := render(10, 4, "line one\r\nline two\r\nline three", "\x1b[2;6Hxx\x1b[K", "\x1b[1;1H\x1b[2P")
+	checkLines(t, snap, "ne one", "line xx", "line three", "")
+	snap = render(10, 3, "aaaaaaaaaa\r\nbbbbbbbbbb\r\ncccccccccc", "\x1b[3;4H\x1b[1J")
+	checkLines(t, snap, "", "", "    cccccc")
+	snap = render(10, 3, "aaaaaaaaaa\r\nbbbbbbbbbb\r\ncccccccccc", "\x1b[2;3H\x1b[J")
+	checkLines(t, snap, "aaaaaaaaaa", "bb", "")
+	snap = render(10, 1, "abcdef", "\x1b[1;3H\x1b[2@", "\x1b[1;1H\x1b[3X")
+	checkLines(t, snap, "    cdef")
+}
+
+func TestScreen_ScrollRegion(t *testing.T) {
+	// A status line at the bottom stays while the region above scrolls.
+	snap := render(10, 4, "\x1b[4;1Hstatus", "\x1b[1;3r\x1b[1;1H", "a\r\nb\r\nc\r\nd")
+	checkLines(t, snap, "b", "c", "d", "status")
+	snap = render(10, 4, "1\r\n2\r\n3\r\n4", "\x1b[2;1H\x1b[L")
+	checkLines(t, snap, "1", "", "2", "3")
+	snap = render(10, 4, "1\r\n2\r\n3\r\n4", "\x1b[2;1H\x1b[2M")
+	checkLines(t, snap, "1", "4", "", "")
+	snap = render(10, 3, "1\r\n2\r\n3", "\x1b[1;1H\x1bM")
+	checkLines(t, snap, "", "1", "2")
+}
+
+func TestScreen_AltScreen(t *testing.T) {
+	s := New(10, 3)
+	s.Write([]byte("$ htop\r\n"))
+	s.Write([]byte("\x1b[?1049h\x1b[?25l\x1b[1;1HCPU 12%"))
+	snap := s.Snapshot()
+	checkLines(t, snap, "CPU 12%", "", "")
+	if !snap.AltScreen || snap.CursorVisible {
+		t.Errorf("alt screen = %v, cursor visible = %v", snap.AltScreen, snap.CursorVisible)
+	}
+	s.Write([]byte("\x1b[?1049l\x1b[?25h"))
+	snap = s.Snapshot()
+	checkLines(t, snap, "$ htop", "", "")
+	if snap.AltScreen || !snap.CursorVisible || snap.CursorRow != 1 || snap.CursorCol != 0 {
+		t.Errorf("after leaving: %+v", snap)
+	}
+}
+
+func TestScreen_LineDrawingAndWide(t *testing.T) {
+	snap := render(10, 2, "\x1b(0lqqk\x1b(B ok\r\n", "\x0e\x1b)0x\x0fx")
+	checkLines(t, snap, "┌──┐ ok", "│x")
+	snap = render(6, 2, "日本語x")
+	checkLines(t, snap, "日本語", "x")
+	// Overwriting half of a wide rune blanks the other half.
+	snap = render(6, 1, "日本", "\x1b[1;2Hx")
+	checkLines(t, snap, " x本")
+}
+
+func TestScreen_SplitSequencesAndTitle(t *testing.T) {
+	// Escape sequences, UTF-8 runes and OSC strings cut across writes.
+	snap := render(10, 2, "\x1b", "[2", ";3H", "\xe2\x94", "\x80", "\x1b]0;my ti", "tle\x07", "\x1bP1$r\x1b\\", "\x1b[31mred\x1b[0m")
+	checkLines(t, snap, "", "  ─red")
+	if snap.Title != "my title" {
+		t.Errorf("title = %q", snap.Title)
+	}
+}
+
+func TestScreen_Reset(t *testing.T) {
+	snap := render(5, 2, "abc\x1b[?1049h\x1b]2;x\x1b\\", "\x1bc")
+	checkLines(t, snap, "", "")
+	if snap.AltScreen || snap.Title != "" || snap.CursorRow != 0 || snap.CursorCol != 0 {
+		t.Errorf("after reset: %+v", snap)
+	}
+}
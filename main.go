@@ -29,6 +29,9 @@ func main() {
 		sig := <-sigCh
 		log.Printf("Received signal %v, shutting down...", sig)
 		cancel()
+		sig = <-sigCh
+		log.Printf("Received signal %v again, exiting immediately", sig)
+		os.Exit(1)
 	}()
 
 	srv, err := server.New(ctx, cfg)
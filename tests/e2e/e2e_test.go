@@ -198,6 +198,40 @@ func TestE2E(t *testing.T) {
 		}
 	})
 
+	t.Run("PlanRollback", func(t *testing.T) {
+		sessionID := sshConnect(t, env)
+		existing := "/home/testuser/plan-existing.txt"
+		created := "/home/testuser/plan-created.txt"
+		callTool(t, env, "ssh_edit_file", map[string]any{
+			"session_id":  sessionID,
+			"remote_path": existing,
+			"content":     "version 1\n",
+			"backup":      false,
+		})
+
+		// The failing command aborts the plan and undoes both edits.
+		text := callTool(t, env, "ssh_plan_execute", map[string]any{
+			"steps": []map[string]any{
+				{"edit": map[string]any{"session_id": sessionID, "remote_path": existing, "mode": "patch", "old_string": "version 1", "new_string": "version 2"}},
+				{"edit": map[string]any{"session_id": sessionID, "remote_path": created, "content": "new\n"}},
+				{"name": "check", "execute": map[string]any{"session_id": sessionID, "command": "false"}},
+				{"execute": map[string]any{"session_id": sessionID, "command": "echo never"}},
+			},
+		})
+		t.Logf("Plan response: %s", text)
+		if !strings.Contains(text, "Plan FAILED: 2 of 4") || !strings.Contains(text, "4. execute: skipped") || !strings.Contains(text, "Rolled back 2 file(s)") {
+			t.Errorf("unexpected plan result: %s", text)
+		}
+
+		text = callTool(t, env, "ssh_execute", map[string]any{
+			"session_id": sessionID,
+			"command":    fmt.Sprintf("cat %s; test -e %s && echo created-left", existing, created),
+		})
+		if !strings.Contains(text, "version 1") || strings.Contains(text, "created-left") {
+			t.Errorf("files not rolled back: %s", text)
+		}
+	})
+
 	t.Run("SessionReuse", func(t *testing.T) {
 		sessionID1 := sshConnect(t, env)
 		sessionID2 := sshConnect(t, env)